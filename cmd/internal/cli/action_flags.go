@@ -10,18 +10,31 @@
 package cli
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/apptainer/apptainer/pkg/cmdline"
+	"github.com/spf13/pflag"
 )
 
 // actionflags.go contains flag variables for action-like commands to draw from
 var (
 	appName           string
 	bindPaths         []string
+	bindFiles         []string
+	envBindPaths      []string
+	noEnvBinds        bool
 	mounts            []string
+	overlayBinds      []string
+	mountFrom         []string
 	homePath          string
 	overlayPath       []string
+	imageMountOpts    []string
+	imageMountOpt     []string
+	overlayOpts       []string
+	imageDriver       string
 	scratchPath       []string
 	workdirPath       string
 	cwdPath           string
@@ -29,43 +42,61 @@ var (
 	hostname          string
 	network           string
 	networkArgs       []string
+	networkRetries    int
 	dns               string
+	dnsSearch         []string
+	dnsOptions        []string
 	security          []string
+	traceSyscalls     string
+	schedPolicy       string
+	niceValue         int
 	cgroupsTOMLFile   string
 	containLibsPath   []string
 	fuseMount         []string
 	apptainerEnv      map[string]string
 	apptainerEnvFiles []string
+	keepEnv           []string
 	noMount           []string
 	dmtcpLaunch       string
 	dmtcpRestart      string
+	passwdEntry       string
+	groupEntry        string
 
 	isBoot          bool
 	isFakeroot      bool
+	isKeepID        bool
 	isCleanEnv      bool
 	isCompat        bool
 	isContained     bool
 	isContainAll    bool
+	cwdReadOnly     bool
+	noTTYConsole    bool
+	overlayVerify   bool
 	isWritable      bool
 	isWritableTmpfs bool
+	useOCILayers    bool
 	nvidia          bool
 	nvCCLI          bool
 	rocm            bool
+	dri             bool
 	noEval          bool
 	noHome          bool
 	noInit          bool
 	noNvidia        bool
 	noRocm          bool
 	noUmask         bool
+	nsswitch        bool
 	disableCache    bool
 
-	netNamespace   bool
-	netnsPath      string
-	utsNamespace   bool
-	userNamespace  bool
-	pidNamespace   bool
-	noPidNamespace bool
-	ipcNamespace   bool
+	netNamespace    bool
+	netnsPath       string
+	utsNamespace    bool
+	userNamespace   bool
+	pidNamespace    namespaceFlag
+	noPidNamespace  bool
+	ipcNamespace    namespaceFlag
+	cgroupNamespace bool
+	bindCgroupfs    bool
 
 	allowSUID bool
 	keepPrivs bool
@@ -73,6 +104,8 @@ var (
 	addCaps   string
 	dropCaps  string
 
+	fakerootCaps string
+
 	blkioWeight       int
 	blkioWeightDevice []string
 	cpuShares         int
@@ -95,6 +128,29 @@ var (
 	shareNS bool // mode for launching container using shared namespace
 
 	runscriptTimeout string // runscript timeout
+	entrypoint       string // command overriding the runscript
+	wrap             string // wrapper command of the container process
+
+	noAutofsWorkaround bool // disable file descriptors kept open on autofs bind sources
+
+	dumpOciSpec string // path where the container OCI runtime spec is written
+	dryRun      bool   // prepare the container without starting it
+
+	dryRunMounts bool // print the container mount plan without starting it
+	printCaps    bool // print the container capabilities without starting it
+
+	loopMode          string // how squashfs image partitions are mounted
+	overlayPrecedence string // which read-only overlays take precedence
+
+	writableOverlaySize string // size of the temporary writable overlay image
+	overlayWarnFree     string // free space of the writable overlay below which a warning is displayed
+
+	sessionFs   string // memory filesystem type of the session directory
+	sessionSize string // size of the session directory
+
+	strictBinds bool // fail if a requested bind can't be mounted
+
+	homeKeepPath bool // keep the home path under fakeroot instead of /root
 )
 
 // --app
@@ -114,10 +170,49 @@ var actionBindFlag = cmdline.Flag{
 	DefaultValue: cmdline.StringArray{}, // to allow commas in bind path
 	Name:         "bind",
 	ShortHand:    "B",
-	Usage:        "a user-bind path specification.  spec has the format src[:dest[:opts]], where src and dest are outside and inside paths.  If dest is not given, it is set equal to src.  Mount options ('opts') may be specified as 'ro' (read-only) or 'rw' (read/write, which is the default). Multiple bind paths can be given by a comma separated list.",
+	Usage:        "a user-bind path specification.  spec has the format src[:dest[:opts]], where src and dest are outside and inside paths.  If dest is not given, it is set equal to src.  Mount options ('opts') may be specified as 'ro' (read-only) or 'rw' (read/write, which is the default), the mount propagation as 'slave', 'private' or 'unbindable', 'mkdir' creates the destination parent directory and 'mkfile' the destination file in the overlay or underlay layer. Multiple bind paths can be given by a comma separated list.",
 	EnvKeys:      []string{"BIND", "BINDPATH"},
 	Tag:          "<spec>",
-	EnvHandler:   cmdline.EnvAppendValue,
+	EnvHandler:   envBindHandler,
+}
+
+// envBindHandler collects binds set with APPTAINER_BIND/APPTAINER_BINDPATH
+// apart from the command line binds, so they can be overridden or ignored.
+func envBindHandler(_ *pflag.Flag, value string) error {
+	if strings.TrimSpace(value) != "" {
+		envBindPaths = append(envBindPaths, value)
+	}
+	return nil
+}
+
+// --no-env-binds
+var actionNoEnvBindsFlag = cmdline.Flag{
+	ID:           "actionNoEnvBindsFlag",
+	Value:        &noEnvBinds,
+	DefaultValue: false,
+	Name:         "no-env-binds",
+	Usage:        "ignore binds set with the APPTAINER_BIND and APPTAINER_BINDPATH environment variables",
+}
+
+// --bind-file
+var actionBindFileFlag = cmdline.Flag{
+	ID:           "actionBindFileFlag",
+	Value:        &bindFiles,
+	DefaultValue: cmdline.StringArray{},
+	Name:         "bind-file",
+	Usage:        "read user-bind path specifications from a file, one per line in the same format as --bind. Blank lines and lines starting with # are ignored.",
+	EnvKeys:      []string{"BIND_FILE"},
+	Tag:          "<path>",
+}
+
+// --strict-binds
+var actionStrictBindsFlag = cmdline.Flag{
+	ID:           "actionStrictBindsFlag",
+	Value:        &strictBinds,
+	DefaultValue: false,
+	Name:         "strict-binds",
+	Usage:        "fail instead of skipping with a warning any bind which can't be mounted, e.g. because its destination doesn't exist in the container",
+	EnvKeys:      []string{"STRICT_BINDS"},
 }
 
 // --mount
@@ -126,12 +221,36 @@ var actionMountFlag = cmdline.Flag{
 	Value:        &mounts,
 	DefaultValue: cmdline.StringArray{},
 	Name:         "mount",
-	Usage:        "a mount specification e.g. 'type=bind,source=/opt,destination=/hostopt'.",
+	Usage:        "a mount specification e.g. 'type=bind,source=/opt,destination=/hostopt', 'type=tmpfs,destination=/cache,size=256m,mode=1777' or 'type=kernelfs,name=tracefs' (debugfs, tracefs or cgroup2).",
 	EnvKeys:      []string{"MOUNT"},
 	Tag:          "<spec>",
 	EnvHandler:   cmdline.EnvAppendValue,
 }
 
+// --overlay-bind
+var actionOverlayBindFlag = cmdline.Flag{
+	ID:           "actionOverlayBindFlag",
+	Value:        &overlayBinds,
+	DefaultValue: cmdline.StringArray{},
+	Name:         "overlay-bind",
+	Usage:        "mount a host directory with a writable overlay discarded at exit e.g. 'lower=/opt,upper=tmpfs,target=/app'",
+	EnvKeys:      []string{"OVERLAY_BIND"},
+	Tag:          "<spec>",
+	EnvHandler:   cmdline.EnvAppendValue,
+}
+
+// --mount-from
+var actionMountFromFlag = cmdline.Flag{
+	ID:           "actionMountFromFlag",
+	Value:        &mountFrom,
+	DefaultValue: cmdline.StringArray{},
+	Name:         "mount-from",
+	Usage:        "mount a path of a running instance, which must outlive the container, e.g. 'instance://NAME:/src:/dest'",
+	EnvKeys:      []string{"MOUNT_FROM"},
+	Tag:          "<spec>",
+	EnvHandler:   cmdline.EnvAppendValue,
+}
+
 // -H|--home
 var actionHomeFlag = cmdline.Flag{
 	ID:           "actionHomeFlag",
@@ -144,6 +263,16 @@ var actionHomeFlag = cmdline.Flag{
 	Tag:          "<spec>",
 }
 
+// --home-keep-path
+var actionHomeKeepPathFlag = cmdline.Flag{
+	ID:           "actionHomeKeepPathFlag",
+	Value:        &homeKeepPath,
+	DefaultValue: false,
+	Name:         "home-keep-path",
+	Usage:        "with --fakeroot, mount the home directory at its original path instead of /root",
+	EnvKeys:      []string{"HOME_KEEP_PATH"},
+}
+
 // -o|--overlay
 var actionOverlayFlag = cmdline.Flag{
 	ID:           "actionOverlayFlag",
@@ -151,11 +280,98 @@ var actionOverlayFlag = cmdline.Flag{
 	DefaultValue: []string{},
 	Name:         "overlay",
 	ShortHand:    "o",
-	Usage:        "use an overlayFS image for persistent data storage or as read-only layer of container",
+	Usage:        "use an overlayFS image or a directory for persistent data storage or as read-only layer of container (with the :ro suffix)",
 	EnvKeys:      []string{"OVERLAY", "OVERLAYIMAGE"},
 	Tag:          "<path>",
 }
 
+// --overlay-verify
+var actionOverlayVerifyFlag = cmdline.Flag{
+	ID:           "actionOverlayVerifyFlag",
+	Value:        &overlayVerify,
+	DefaultValue: false,
+	Name:         "overlay-verify",
+	Usage:        "require overlay images to be signed by a key of the global keyring, with SIF signatures or a detached .sig/.asc signature file",
+	EnvKeys:      []string{"OVERLAY_VERIFY"},
+}
+
+// --overlay-warn-free
+var actionOverlayWarnFreeFlag = cmdline.Flag{
+	ID:           "actionOverlayWarnFreeFlag",
+	Value:        &overlayWarnFree,
+	DefaultValue: "",
+	Name:         "overlay-warn-free",
+	Usage:        "warn when the free space of the writable ext3 overlay image is below the given size (e.g. 100M), checked once mounted and when the container exits",
+	EnvKeys:      []string{"OVERLAY_WARN_FREE"},
+	Tag:          "<size>",
+}
+
+// --session-fs
+var actionSessionFsFlag = cmdline.Flag{
+	ID:           "actionSessionFsFlag",
+	Value:        &sessionFs,
+	DefaultValue: "",
+	Name:         "session-fs",
+	Usage:        "memory filesystem type (tmpfs or ramfs) of the session directory holding the writable tmpfs and overlay layers, instead of the 'memory fs type' set in apptainer.conf",
+	EnvKeys:      []string{"SESSION_FS"},
+	Tag:          "<type>",
+}
+
+// --session-size
+var actionSessionSizeFlag = cmdline.Flag{
+	ID:           "actionSessionSizeFlag",
+	Value:        &sessionSize,
+	DefaultValue: "",
+	Name:         "session-size",
+	Usage:        "size of the session directory (e.g. 256M), capped at the 'sessiondir max size' set in apptainer.conf in setuid mode",
+	EnvKeys:      []string{"SESSION_SIZE"},
+	Tag:          "<size>",
+}
+
+// --image-mount-opts
+var actionImageMountOptsFlag = cmdline.Flag{
+	ID:           "actionImageMountOptsFlag",
+	Value:        &imageMountOpts,
+	DefaultValue: []string{},
+	Name:         "image-mount-opts",
+	Usage:        "access time mount options (noatime, nodiratime, relatime, strictatime, lazytime) applied to ext3 and sandbox root filesystem and overlay images",
+	EnvKeys:      []string{"IMAGE_MOUNT_OPTS"},
+	Tag:          "<opts>",
+}
+
+// --image-mount-opt
+var actionImageMountOptFlag = cmdline.Flag{
+	ID:           "actionImageMountOptFlag",
+	Value:        &imageMountOpt,
+	DefaultValue: []string{},
+	Name:         "image-mount-opt",
+	Usage:        "key=value mount option passed to squashfs root filesystem and data image mounts (can be specified multiple times), only errors=continue and threads=<single|multi|percpu|N> are accepted in setuid mode",
+	EnvKeys:      []string{"IMAGE_MOUNT_OPT"},
+	Tag:          "<key=value>",
+}
+
+// --overlay-opts
+var actionOverlayOptsFlag = cmdline.Flag{
+	ID:           "actionOverlayOptsFlag",
+	Value:        &overlayOpts,
+	DefaultValue: []string{},
+	Name:         "overlay-opts",
+	Usage:        "kernel overlay features (metacopy, redirect_dir) enabled for the writable container overlay, metacopy may expose host inode numbers, in setuid mode only features enabled in apptainer.conf are accepted",
+	EnvKeys:      []string{"OVERLAY_OPTS"},
+	Tag:          "<opts>",
+}
+
+// --image-driver
+var actionImageDriverFlag = cmdline.Flag{
+	ID:           "actionImageDriverFlag",
+	Value:        &imageDriver,
+	DefaultValue: "",
+	Name:         "image-driver",
+	Usage:        "use the registered image driver <name> instead of the one set in apptainer.conf, restricted to 'allowed image drivers' in setuid mode",
+	EnvKeys:      []string{"IMAGE_DRIVER"},
+	Tag:          "<name>",
+}
+
 // -S|--scratch
 var actionScratchFlag = cmdline.Flag{
 	ID:           "actionScratchFlag",
@@ -163,7 +379,7 @@ var actionScratchFlag = cmdline.Flag{
 	DefaultValue: []string{},
 	Name:         "scratch",
 	ShortHand:    "S",
-	Usage:        "include a scratch directory within the container that is linked to a temporary dir (use -W to force location)",
+	Usage:        "include a scratch directory within the container that is linked to a temporary dir (use -W to force location), append :size=<size> (e.g. /work:size=512m) to back it with a tmpfs of this size when not using -W",
 	EnvKeys:      []string{"SCRATCH", "SCRATCHDIR"},
 	Tag:          "<path>",
 }
@@ -258,6 +474,17 @@ var actionNetworkArgsFlag = cmdline.Flag{
 	Tag:          "<args>",
 }
 
+// --net-retry
+var actionNetworkRetriesFlag = cmdline.Flag{
+	ID:           "actionNetworkRetriesFlag",
+	Value:        &networkRetries,
+	DefaultValue: -1,
+	Name:         "net-retry",
+	Usage:        "number of times network setup is retried on transient CNI plugin errors, 0 disables retries (default -1 uses the configuration value)",
+	EnvKeys:      []string{"NET_RETRY"},
+	Tag:          "<n>",
+}
+
 // --dns
 var actionDNSFlag = cmdline.Flag{
 	ID:           "actionDnsFlag",
@@ -268,16 +495,80 @@ var actionDNSFlag = cmdline.Flag{
 	EnvKeys:      []string{"DNS"},
 }
 
+// --dns-search
+var actionDNSSearchFlag = cmdline.Flag{
+	ID:           "actionDNSSearchFlag",
+	Value:        &dnsSearch,
+	DefaultValue: []string{},
+	Name:         "dns-search",
+	Usage:        "list of DNS search domains separated by commas to set in resolv.conf",
+	EnvKeys:      []string{"DNS_SEARCH"},
+	Tag:          "<domains>",
+}
+
+// --dns-option
+var actionDNSOptionFlag = cmdline.Flag{
+	ID:           "actionDNSOptionFlag",
+	Value:        &dnsOptions,
+	DefaultValue: []string{},
+	Name:         "dns-option",
+	Usage:        "list of resolver options separated by commas to set in resolv.conf (e.g. ndots:2)",
+	EnvKeys:      []string{"DNS_OPTION"},
+	Tag:          "<options>",
+}
+
+// --nsswitch
+var actionNsswitchFlag = cmdline.Flag{
+	ID:           "actionNsswitchFlag",
+	Value:        &nsswitch,
+	DefaultValue: false,
+	Name:         "nsswitch",
+	Usage:        "use a minimal /etc/nsswitch.conf resolving users, groups and hosts from local files",
+	EnvKeys:      []string{"NSSWITCH"},
+}
+
 // --security
 var actionSecurityFlag = cmdline.Flag{
 	ID:           "actionSecurityFlag",
 	Value:        &security,
 	DefaultValue: []string{},
 	Name:         "security",
-	Usage:        "enable security features (SELinux, Apparmor, Seccomp)",
+	Usage:        "enable security features (SELinux, Apparmor, Seccomp, Landlock)",
 	EnvKeys:      []string{"SECURITY"},
 }
 
+// --trace-syscalls
+var actionTraceSyscallsFlag = cmdline.Flag{
+	ID:           "actionTraceSyscallsFlag",
+	Value:        &traceSyscalls,
+	DefaultValue: "",
+	Name:         "trace-syscalls",
+	Usage:        "trace system calls of the container process to the given file, for debugging only as every system call is slowed down",
+	EnvKeys:      []string{"TRACE_SYSCALLS"},
+}
+
+// --sched-policy
+var actionSchedPolicyFlag = cmdline.Flag{
+	ID:           "actionSchedPolicyFlag",
+	Value:        &schedPolicy,
+	DefaultValue: "",
+	Name:         "sched-policy",
+	Usage:        "CPU scheduling policy of the container process and its children: other, batch, idle, fifo:<priority> or rr:<priority>",
+	EnvKeys:      []string{"SCHED_POLICY"},
+	Tag:          "<policy>",
+}
+
+// --nice
+var actionNiceFlag = cmdline.Flag{
+	ID:           "actionNiceFlag",
+	Value:        &niceValue,
+	DefaultValue: 0,
+	Name:         "nice",
+	Usage:        "nice value of the container process and its children, from -20 (highest priority) to 19 (lowest priority)",
+	EnvKeys:      []string{"NICE"},
+	Tag:          "<n>",
+}
+
 // --apply-cgroups
 var actionApplyCgroupsFlag = cmdline.Flag{
 	ID:           "actionApplyCgroupsFlag",
@@ -308,15 +599,15 @@ var actionFuseMountFlag = cmdline.Flag{
 	EnvKeys:      []string{"FUSESPEC"},
 }
 
-// hidden flag to handle APPTAINER_TMPDIR environment variable
+// --tmpdir
 var actionTmpDirFlag = cmdline.Flag{
 	ID:           "actionTmpDirFlag",
 	Value:        &tmpDir,
 	DefaultValue: os.TempDir(),
 	Name:         "tmpdir",
-	Usage:        "specify a temporary directory to use for build",
-	Hidden:       true,
+	Usage:        "directory where temporary files are created, like pulled images, images extracted to a sandbox and the startup profile",
 	EnvKeys:      []string{"TMPDIR"},
+	Tag:          "<path>",
 }
 
 // --boot
@@ -340,6 +631,36 @@ var actionFakerootFlag = cmdline.Flag{
 	EnvKeys:      []string{"FAKEROOT"},
 }
 
+// --keep-id
+var actionKeepIDFlag = cmdline.Flag{
+	ID:           "actionKeepIDFlag",
+	Value:        &isKeepID,
+	DefaultValue: false,
+	Name:         "keep-id",
+	Usage:        "run container in a user namespace as your own UID/GID, mapping the other IDs from subuid/subgid ranges",
+	EnvKeys:      []string{"KEEP_ID"},
+}
+
+// --passwd-entry
+var actionPasswdEntryFlag = cmdline.Flag{
+	ID:           "actionPasswdEntryFlag",
+	Value:        &passwdEntry,
+	DefaultValue: "",
+	Name:         "passwd-entry",
+	Usage:        "override the user entry generated in the container /etc/passwd (format is name:uid:gid:gecos:home:shell)",
+	EnvKeys:      []string{"PASSWD_ENTRY"},
+}
+
+// --group-entry
+var actionGroupEntryFlag = cmdline.Flag{
+	ID:           "actionGroupEntryFlag",
+	Value:        &groupEntry,
+	DefaultValue: "",
+	Name:         "group-entry",
+	Usage:        "override the group entry generated in the container /etc/group for the group ID (format is name:gid)",
+	EnvKeys:      []string{"GROUP_ENTRY"},
+}
+
 // -e|--cleanenv
 var actionCleanEnvFlag = cmdline.Flag{
 	ID:           "actionCleanEnvFlag",
@@ -413,6 +734,16 @@ var actionRocmFlag = cmdline.Flag{
 	EnvKeys:      []string{"ROCM"},
 }
 
+// --dri flag to bind DRM card and render nodes
+var actionDRIFlag = cmdline.Flag{
+	ID:           "actionDRIFlag",
+	Value:        &dri,
+	DefaultValue: false,
+	Name:         "dri",
+	Usage:        "enable GPU support through DRM card and render nodes in /dev/dri (e.g. Intel GPUs)",
+	EnvKeys:      []string{"DRI"},
+}
+
 // -w|--writable
 var actionWritableFlag = cmdline.Flag{
 	ID:           "actionWritableFlag",
@@ -434,6 +765,27 @@ var actionWritableTmpfsFlag = cmdline.Flag{
 	EnvKeys:      []string{"WRITABLE_TMPFS"},
 }
 
+// --writable-overlay-size
+var actionWritableOverlaySizeFlag = cmdline.Flag{
+	ID:           "actionWritableOverlaySizeFlag",
+	Value:        &writableOverlaySize,
+	DefaultValue: "",
+	Name:         "writable-overlay-size",
+	Usage:        "makes the file system accessible as read-write through a temporary sparse ext3 overlay image of the given size (e.g. 2G), created in the temporary directory and deleted on exit",
+	EnvKeys:      []string{"WRITABLE_OVERLAY_SIZE"},
+	Tag:          "<size>",
+}
+
+// --oci-layers
+var actionOCILayersFlag = cmdline.Flag{
+	ID:           "actionOCILayersFlag",
+	Value:        &useOCILayers,
+	DefaultValue: false,
+	Name:         "oci-layers",
+	Usage:        "mount the layers of OCI images (docker://, oci:// ...) as overlay lower directories instead of converting them to a single SIF image (with overlay support only)",
+	EnvKeys:      []string{"OCI_LAYERS"},
+}
+
 // --no-home
 var actionNoHomeFlag = cmdline.Flag{
 	ID:           "actionNoHomeFlag",
@@ -450,10 +802,30 @@ var actionNoMountFlag = cmdline.Flag{
 	Value:        &noMount,
 	DefaultValue: []string{},
 	Name:         "no-mount",
-	Usage:        "disable one or more 'mount xxx' options set in apptainer.conf and/or specify absolute destination path to disable a bind path entry, or 'bind-paths' to disable all bind path entries.",
+	Usage:        "disable one or more 'mount xxx' options set in apptainer.conf and/or specify absolute destination path to disable a bind path entry, 'bind-paths' to disable all bind path entries, or 'image-binds' to disable the binds provided by the image.",
 	EnvKeys:      []string{"NO_MOUNT"},
 }
 
+// --cwd-ro
+var actionCwdReadOnlyFlag = cmdline.Flag{
+	ID:           "actionCwdReadOnlyFlag",
+	Value:        &cwdReadOnly,
+	DefaultValue: false,
+	Name:         "cwd-ro",
+	Usage:        "mount the current working directory read-only into the container",
+	EnvKeys:      []string{"CWD_RO"},
+}
+
+// --no-tty-console
+var actionNoTTYConsoleFlag = cmdline.Flag{
+	ID:           "actionNoTTYConsoleFlag",
+	Value:        &noTTYConsole,
+	DefaultValue: false,
+	Name:         "no-tty-console",
+	Usage:        "do not bind the terminal at /dev/console with a minimal /dev (--contain), mostly useful for non-interactive jobs",
+	EnvKeys:      []string{"NO_TTY_CONSOLE"},
+}
+
 // --no-init
 var actionNoInitFlag = cmdline.Flag{
 	ID:           "actionNoInitFlag",
@@ -484,15 +856,60 @@ var actionNoRocmFlag = cmdline.Flag{
 	EnvKeys:      []string{"ROCM_OFF", "NO_ROCM"},
 }
 
+// namespaceFlag holds the value of a namespace flag which either
+// requests a new namespace (--pid) or to share the namespace of a
+// running instance (--pid=container:NAME).
+type namespaceFlag struct {
+	enabled  bool
+	instance string
+}
+
+const namespaceFlagContainerPrefix = "container:"
+
+func (n *namespaceFlag) Set(value string) error {
+	if strings.HasPrefix(value, namespaceFlagContainerPrefix) {
+		name := strings.TrimPrefix(value, namespaceFlagContainerPrefix)
+		if name == "" {
+			return fmt.Errorf("no instance name provided")
+		}
+		n.enabled = false
+		n.instance = name
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("value must be a boolean or %sNAME", namespaceFlagContainerPrefix)
+	}
+	n.enabled = b
+	n.instance = ""
+	return nil
+}
+
+func (n *namespaceFlag) String() string {
+	if n.instance != "" {
+		return namespaceFlagContainerPrefix + n.instance
+	}
+	return strconv.FormatBool(n.enabled)
+}
+
+// Type returns the value type shown in the usage, the flag
+// is a boolean which also accepts container:NAME.
+func (n *namespaceFlag) Type() string {
+	return "namespace"
+}
+
+func (n *namespaceFlag) IsBoolFlag() bool {
+	return true
+}
+
 // -p|--pid
 var actionPidNamespaceFlag = cmdline.Flag{
-	ID:           "actionPidNamespaceFlag",
-	Value:        &pidNamespace,
-	DefaultValue: false,
-	Name:         "pid",
-	ShortHand:    "p",
-	Usage:        "run container in a new PID namespace",
-	EnvKeys:      []string{"PID", "UNSHARE_PID"},
+	ID:        "actionPidNamespaceFlag",
+	Value:     &pidNamespace,
+	Name:      "pid",
+	ShortHand: "p",
+	Usage:     "run container in a new PID namespace, or join the PID namespace of a running instance with --pid=container:NAME",
+	EnvKeys:   []string{"PID", "UNSHARE_PID"},
 }
 
 // --no-pid
@@ -507,13 +924,12 @@ var actionNoPidNamespaceFlag = cmdline.Flag{
 
 // -i|--ipc
 var actionIpcNamespaceFlag = cmdline.Flag{
-	ID:           "actionIpcNamespaceFlag",
-	Value:        &ipcNamespace,
-	DefaultValue: false,
-	Name:         "ipc",
-	ShortHand:    "i",
-	Usage:        "run container in a new IPC namespace",
-	EnvKeys:      []string{"IPC", "UNSHARE_IPC"},
+	ID:        "actionIpcNamespaceFlag",
+	Value:     &ipcNamespace,
+	Name:      "ipc",
+	ShortHand: "i",
+	Usage:     "run container in a new IPC namespace, or join the IPC namespace of a running instance with --ipc=container:NAME",
+	EnvKeys:   []string{"IPC", "UNSHARE_IPC"},
 }
 
 // -n|--net
@@ -537,6 +953,26 @@ var actionUtsNamespaceFlag = cmdline.Flag{
 	EnvKeys:      []string{"UTS", "UNSHARE_UTS"},
 }
 
+// --cgroupns
+var actionCgroupNamespaceFlag = cmdline.Flag{
+	ID:           "actionCgroupNamespaceFlag",
+	Value:        &cgroupNamespace,
+	DefaultValue: false,
+	Name:         "cgroupns",
+	Usage:        "run container in a new cgroup namespace, with a read-only cgroup filesystem mounted on /sys/fs/cgroup",
+	EnvKeys:      []string{"CGROUPNS", "UNSHARE_CGROUPNS"},
+}
+
+// --bind-cgroupfs
+var actionBindCgroupfsFlag = cmdline.Flag{
+	ID:           "actionBindCgroupfsFlag",
+	Value:        &bindCgroupfs,
+	DefaultValue: false,
+	Name:         "bind-cgroupfs",
+	Usage:        "bind the host /sys/fs/cgroup read-only into the container",
+	EnvKeys:      []string{"BIND_CGROUPFS"},
+}
+
 // -u|--userns
 var actionUserNamespaceFlag = cmdline.Flag{
 	ID:           "actionUserNamespaceFlag",
@@ -588,6 +1024,17 @@ var actionDropCapsFlag = cmdline.Flag{
 	EnvKeys:      []string{"DROP_CAPS"},
 }
 
+// --fakeroot-caps
+var actionFakerootCapsFlag = cmdline.Flag{
+	ID:           "actionFakerootCapsFlag",
+	Value:        &fakerootCaps,
+	DefaultValue: "",
+	Name:         "fakeroot-caps",
+	Usage:        "a comma separated capability list set within the user namespace with --fakeroot, in place of all capabilities",
+	EnvKeys:      []string{"FAKEROOT_CAPS"},
+	Tag:          "<caps>",
+}
+
 // --allow-setuid
 var actionAllowSetuidFlag = cmdline.Flag{
 	ID:           "actionAllowSetuidFlag",
@@ -613,10 +1060,21 @@ var actionEnvFileFlag = cmdline.Flag{
 	Value:        &apptainerEnvFiles,
 	DefaultValue: []string{},
 	Name:         "env-file",
-	Usage:        "pass environment variables from file to contained process",
+	Usage:        "pass environment variables from file to contained process, may be repeated with later files taking precedence (--env takes precedence over files, files over APPTAINERENV_ variables)",
 	EnvKeys:      []string{"ENV_FILE"},
 }
 
+// --keep-env
+var actionKeepEnvFlag = cmdline.Flag{
+	ID:           "actionKeepEnvFlag",
+	Value:        &keepEnv,
+	DefaultValue: []string{},
+	Name:         "keep-env",
+	Usage:        "comma separated list of host environment variables to keep with a clean environment (--cleanenv/--containall), overridden by --env, --env-file and APPTAINERENV_ variables",
+	EnvKeys:      []string{"KEEP_ENV"},
+	Tag:          "<var,var...>",
+}
+
 // --no-umask
 var actionNoUmaskFlag = cmdline.Flag{
 	ID:           "actionNoUmask",
@@ -844,6 +1302,98 @@ var actionRunscriptTimeoutFlag = cmdline.Flag{
 	Hidden:       false,
 }
 
+// --entrypoint
+var actionEntrypointFlag = cmdline.Flag{
+	ID:           "actionEntrypointFlag",
+	Value:        &entrypoint,
+	DefaultValue: "",
+	Name:         "entrypoint",
+	Usage:        "run the given command with the provided arguments in place of the container runscript, the container environment is still sourced",
+	Tag:          "<command>",
+}
+
+// --wrap
+var actionWrapFlag = cmdline.Flag{
+	ID:           "actionWrapFlag",
+	Value:        &wrap,
+	DefaultValue: "",
+	Name:         "wrap",
+	Usage:        "prepend the given wrapper command, resolved inside the container, to the container process (e.g. 'numactl --cpunodebind=0')",
+	EnvKeys:      []string{"WRAP"},
+	Tag:          "<command>",
+}
+
+// --no-autofs-workaround
+var actionNoAutofsWorkaroundFlag = cmdline.Flag{
+	ID:           "actionNoAutofsWorkaroundFlag",
+	Value:        &noAutofsWorkaround,
+	DefaultValue: false,
+	Name:         "no-autofs-workaround",
+	Usage:        "do NOT keep file descriptors open on bind sources located under autofs mount points, binds from autofs mounts may fail",
+	EnvKeys:      []string{"NO_AUTOFS_WORKAROUND"},
+}
+
+// --dump-oci-spec
+var actionDumpOciSpecFlag = cmdline.Flag{
+	ID:           "actionDumpOciSpecFlag",
+	Value:        &dumpOciSpec,
+	DefaultValue: "",
+	Name:         "dump-oci-spec",
+	Usage:        "write the OCI runtime spec assembled for the container to the given file, or to the standard output with '-'",
+	EnvKeys:      []string{"DUMP_OCI_SPEC"},
+	Tag:          "<path>",
+}
+
+// --dry-run
+var actionDryRunFlag = cmdline.Flag{
+	ID:           "actionDryRunFlag",
+	Value:        &dryRun,
+	DefaultValue: false,
+	Name:         "dry-run",
+	Usage:        "prepare the container configuration without starting the container, requires --dump-oci-spec",
+}
+
+// --dry-run-mounts
+var actionDryRunMountsFlag = cmdline.Flag{
+	ID:           "actionDryRunMountsFlag",
+	Value:        &dryRunMounts,
+	DefaultValue: false,
+	Name:         "dry-run-mounts",
+	Usage:        "print the mount points of the container without mounting them and exit",
+	Hidden:       true,
+}
+
+// --print-caps
+var actionPrintCapsFlag = cmdline.Flag{
+	ID:           "actionPrintCapsFlag",
+	Value:        &printCaps,
+	DefaultValue: false,
+	Name:         "print-caps",
+	Usage:        "print the capability sets resolved for the container process and exit without starting the container",
+}
+
+// --loop
+var actionLoopModeFlag = cmdline.Flag{
+	ID:           "actionLoopModeFlag",
+	Value:        &loopMode,
+	DefaultValue: "kernel",
+	Name:         "loop",
+	Usage:        "mount squashfs image partitions with kernel loop devices ('kernel') or with the FUSE image driver when available ('fuse')",
+	EnvKeys:      []string{"LOOP"},
+	Tag:          "<mode>",
+}
+
+// --overlay-precedence
+var actionOverlayPrecedenceFlag = cmdline.Flag{
+	ID:           "actionOverlayPrecedenceFlag",
+	Value:        &overlayPrecedence,
+	DefaultValue: "last",
+	Name:         "overlay-precedence",
+	Usage:        "which read-only overlays take precedence when they provide the same file, the last ones ('last') or the first ones ('first')",
+	EnvKeys:      []string{"OVERLAY_PRECEDENCE"},
+	Tag:          "<last|first>",
+}
+
 // --netns-path
 var actionNetnsPathFlag = cmdline.Flag{
 	ID:           "actionNetnsPathFlag",
@@ -880,6 +1430,7 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&actionAppFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionApplyCgroupsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionBindFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionNoEnvBindsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionCleanEnvFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionCompatFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionContainAllFlag, actionsInstanceCmd...)
@@ -887,29 +1438,54 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&actionContainLibsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionDisableCacheFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionDNSFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionDNSSearchFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionDNSOptionFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionNsswitchFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionDropCapsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionFakerootCapsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionFakerootFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionKeepIDFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionPasswdEntryFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionGroupEntryFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionFuseMountFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionHomeFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionHomeKeepPathFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionHostnameFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionIpcNamespaceFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionKeepPrivsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionMountFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionBindFileFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionStrictBindsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionOverlayBindFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionMountFromFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNetNamespaceFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNetnsPathFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNetworkArgsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionNetworkRetriesFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNetworkFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoHomeFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoMountFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoInitFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionCwdReadOnlyFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionNoTTYConsoleFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoNvidiaFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoRocmFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoPrivsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNvidiaFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNvCCLIFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionRocmFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionDRIFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionOverlayFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionOverlayVerifyFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionOverlayWarnFreeFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionSessionFsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionSessionSizeFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionImageMountOptsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionImageMountOptFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionOverlayOptsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionImageDriverFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&commonPromptForPassphraseFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&commonPassphraseFdFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&commonPEMFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionPidNamespaceFlag, actionsCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoPidNamespaceFlag, actionsCmd...)
@@ -917,13 +1493,20 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&actionPwdFlag, actionsCmd...)
 		cmdManager.RegisterFlagForCmd(&actionScratchFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionSecurityFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionTraceSyscallsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionSchedPolicyFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionNiceFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionShellFlag, ShellCmd)
 		cmdManager.RegisterFlagForCmd(&actionTmpDirFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionUserNamespaceFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionUtsNamespaceFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionCgroupNamespaceFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionBindCgroupfsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionWorkdirFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionWritableFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionWritableTmpfsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionWritableOverlaySizeFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionOCILayersFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&commonNoHTTPSFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&commonOldNoHTTPSFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&dockerLoginFlag, actionsInstanceCmd...)
@@ -932,6 +1515,7 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&dockerUsernameFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionEnvFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionEnvFileFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionKeepEnvFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoUmaskFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoEvalFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionBlkioWeightFlag, actionsInstanceCmd...)
@@ -953,5 +1537,14 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&actionShareNSFlag, actionsCmd...)
 		cmdManager.RegisterFlagForCmd(&commonAuthFileFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionRunscriptTimeoutFlag, actionsRunscriptCmd...)
+		cmdManager.RegisterFlagForCmd(&actionEntrypointFlag, actionsRunscriptCmd...)
+		cmdManager.RegisterFlagForCmd(&actionWrapFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionNoAutofsWorkaroundFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionDumpOciSpecFlag, actionsCmd...)
+		cmdManager.RegisterFlagForCmd(&actionDryRunFlag, actionsCmd...)
+		cmdManager.RegisterFlagForCmd(&actionDryRunMountsFlag, actionsCmd...)
+		cmdManager.RegisterFlagForCmd(&actionPrintCapsFlag, actionsCmd...)
+		cmdManager.RegisterFlagForCmd(&actionLoopModeFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionOverlayPrecedenceFlag, actionsInstanceCmd...)
 	})
 }
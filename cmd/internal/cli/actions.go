@@ -32,6 +32,7 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/util/env"
 	"github.com/apptainer/apptainer/internal/pkg/util/uri"
 	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/apptainer/apptainer/pkg/util/apptainerconf"
 	"github.com/apptainer/apptainer/pkg/util/fs/lock"
 	"github.com/spf13/cobra"
 	"golang.org/x/sys/unix"
@@ -40,8 +41,14 @@ import (
 const (
 	defaultPath           = "/bin:/usr/bin:/sbin:/usr/sbin:/usr/local/bin:/usr/local/sbin"
 	shareNSInstancePrefix = "sharens_instance"
+	// maximum number of lower directories supported by the kernel overlay filesystem
+	maxKernelOverlayLayers = 500
 )
 
+// ociLayerDirs holds the unpacked layer directories stacked on top of the
+// image when OCI layers are used as overlay lower directories.
+var ociLayerDirs []string
+
 func getCacheHandle(cfg cache.Config) *cache.Handle {
 	envKey := env.TrimApptainerKey(cache.DirEnv)
 	h, err := cache.New(cache.Config{
@@ -94,9 +101,33 @@ func handleOCI(ctx context.Context, imgCache *cache.Handle, cmd *cobra.Command,
 		Platform:    getOCIPlatform(),
 	}
 
+	if useOCILayers {
+		layers, err := handleOCILayers(ctx, imgCache, pullFrom, pullOpts)
+		if err == nil {
+			ociLayerDirs = layers[1:]
+			return layers[0], nil
+		}
+		sylog.Warningf("Could not use OCI layers as overlay lower directories, falling back to a single image: %s", err)
+	}
+
 	return oci.Pull(ctx, imgCache, pullFrom, pullOpts)
 }
 
+// handleOCILayers unpacks the layers of the OCI image pullFrom, the first
+// returned directory is the root filesystem and the following ones are
+// overlay lower directories. It fails when the number of layers exceeds
+// the overlay lower directories limit, the image is flattened instead.
+func handleOCILayers(ctx context.Context, imgCache *cache.Handle, pullFrom string, pullOpts oci.PullOptions) ([]string, error) {
+	maxLayers := maxKernelOverlayLayers
+	if m := int(apptainerconf.GetCurrentConfig().MaxOverlayLayers); m < maxLayers {
+		maxLayers = m
+	}
+	// leave room for the overlay images
+	maxLayers -= len(overlayPath)
+
+	return oci.PullLayers(ctx, imgCache, pullFrom, pullOpts, maxLayers)
+}
+
 func handleOras(ctx context.Context, imgCache *cache.Handle, cmd *cobra.Command, pullFrom string) (string, error) {
 	ociAuth, err := makeOCICredentials(cmd)
 	if err != nil {
@@ -285,12 +316,16 @@ var TestCmd = &cobra.Command{
 
 func launchContainer(cmd *cobra.Command, image string, args []string, instanceName string, fd int) error {
 	ns := launch.Namespaces{
-		User:  userNamespace,
-		UTS:   utsNamespace,
-		PID:   pidNamespace,
-		IPC:   ipcNamespace,
-		Net:   netNamespace,
-		NoPID: noPidNamespace,
+		User:   userNamespace,
+		UTS:    utsNamespace,
+		PID:    pidNamespace.enabled,
+		IPC:    ipcNamespace.enabled,
+		Net:    netNamespace,
+		Cgroup: cgroupNamespace,
+		NoPID:  noPidNamespace,
+
+		PIDInstance: pidNamespace.instance,
+		IPCInstance: ipcNamespace.instance,
 	}
 
 	cgJSON, err := getCgroupsJSON()
@@ -310,7 +345,17 @@ func launchContainer(cmd *cobra.Command, image string, args []string, instanceNa
 	opts := []launch.Option{
 		launch.OptWritable(isWritable),
 		launch.OptWritableTmpfs(isWritableTmpfs),
+		launch.OptWritableOverlaySize(writableOverlaySize),
 		launch.OptOverlayPaths(overlayPath),
+		launch.OptOverlayVerify(overlayVerify),
+		launch.OptOverlayWarnFree(overlayWarnFree),
+		launch.OptSessionFs(sessionFs),
+		launch.OptSessionSize(sessionSize),
+		launch.OptOCILayers(ociLayerDirs),
+		launch.OptImageMountOpts(imageMountOpts),
+		launch.OptSquashfsMountOpts(imageMountOpt),
+		launch.OptOverlayOpts(overlayOpts),
+		launch.OptImageDriver(imageDriver),
 		launch.OptScratchDirs(scratchPath),
 		launch.OptWorkDir(workdirPath),
 		launch.OptHome(
@@ -318,31 +363,54 @@ func launchContainer(cmd *cobra.Command, image string, args []string, instanceNa
 			cmd.Flag(actionHomeFlag.Name).Changed,
 			noHome,
 		),
+		launch.OptHomeKeepPath(homeKeepPath),
 		launch.OptMounts(bindPaths, mounts, fuseMount),
+		launch.OptEnvBindPaths(envBindPaths, noEnvBinds),
+		launch.OptBindFiles(bindFiles),
+		launch.OptStrictBinds(strictBinds),
+		launch.OptOverlayBinds(overlayBinds),
+		launch.OptMountFrom(mountFrom),
 		launch.OptNoMount(noMount),
+		launch.OptCwdReadOnly(cwdReadOnly),
+		launch.OptNoTTYConsole(noTTYConsole),
 		launch.OptNvidia(nvidia, nvCCLI),
 		launch.OptNoNvidia(noNvidia),
 		launch.OptRocm(rocm),
 		launch.OptNoRocm(noRocm),
+		launch.OptDRI(dri),
+		launch.OptBindCgroupfs(bindCgroupfs),
 		launch.OptContainLibs(containLibsPath),
 		launch.OptEnv(apptainerEnv, apptainerEnvFiles, isCleanEnv),
+		launch.OptKeepEnv(keepEnv),
 		launch.OptNoEval(noEval),
 		launch.OptNamespaces(ns),
 		launch.OptNetnsPath(netnsPath),
 		launch.OptNetwork(network, networkArgs),
+		launch.OptNetworkRetries(networkRetries),
 		launch.OptHostname(hostname),
 		launch.OptDNS(dns),
+		launch.OptDNSSearch(dnsSearch, dnsOptions),
+		launch.OptNsswitch(nsswitch),
 		launch.OptCaps(addCaps, dropCaps),
+		launch.OptFakerootCaps(fakerootCaps),
 		launch.OptAllowSUID(allowSUID),
 		launch.OptKeepPrivs(keepPrivs),
 		launch.OptNoPrivs(noPrivs),
 		launch.OptSecurity(security),
+		launch.OptTraceSyscalls(traceSyscalls),
+		launch.OptScheduling(
+			schedPolicy,
+			niceValue,
+			cmd.Flag(actionNiceFlag.Name).Changed,
+		),
 		launch.OptNoUmask(noUmask),
 		launch.OptCgroupsJSON(cgJSON),
 		launch.OptConfigFile(configurationFile),
 		launch.OptShellPath(shellPath),
 		launch.OptCwdPath(cwdPath),
 		launch.OptFakeroot(isFakeroot),
+		launch.OptKeepID(isKeepID),
+		launch.OptIdentityEntries(passwdEntry, groupEntry),
 		launch.OptBoot(isBoot),
 		launch.OptNoInit(noInit),
 		launch.OptContain(isContained),
@@ -362,6 +430,14 @@ func launchContainer(cmd *cobra.Command, image string, args []string, instanceNa
 		launch.OptShareNSMode(shareNS),
 		launch.OptShareNSFd(fd),
 		launch.OptRunscriptTimeout(runscriptTimeout),
+		launch.OptEntrypoint(entrypoint),
+		launch.OptWrap(wrap),
+		launch.OptNoAutofsWorkaround(noAutofsWorkaround),
+		launch.OptDumpOciSpec(dumpOciSpec, dryRun),
+		launch.OptDryRunMounts(dryRunMounts),
+		launch.OptPrintCaps(printCaps),
+		launch.OptLoopMode(loopMode),
+		launch.OptOverlayPrecedence(overlayPrecedence),
 	}
 
 	l, err := launch.NewLauncher(opts...)
@@ -64,8 +64,10 @@ var (
 
 	encryptionPEMPath   string
 	promptForPassphrase bool
+	passphraseFd        int
 	forceOverwrite      bool
 	noHTTPS             bool
+	noResume            bool
 	useBuildConfig      bool
 	tmpDir              string
 	// Optional user requested authentication file for writing/reading OCI registry credentials
@@ -194,6 +196,16 @@ var commonPromptForPassphraseFlag = cmdline.Flag{
 	Usage:        "prompt for an encryption passphrase",
 }
 
+// --passphrase-fd
+var commonPassphraseFdFlag = cmdline.Flag{
+	ID:           "commonPassphraseFdFlag",
+	Value:        &passphraseFd,
+	DefaultValue: -1,
+	Name:         "passphrase-fd",
+	Usage:        "read the encryption passphrase from a file descriptor until EOF",
+	Tag:          "<fd>",
+}
+
 // --pem-path
 var commonPEMFlag = cmdline.Flag{
 	ID:           "actionEncryptionPEMPath",
@@ -224,6 +236,16 @@ var commonNoHTTPSFlag = cmdline.Flag{
 	EnvKeys:      []string{"NOHTTPS", "NO_HTTPS"},
 }
 
+// --no-resume
+var commonNoResumeFlag = cmdline.Flag{
+	ID:           "commonNoResumeFlag",
+	Value:        &noResume,
+	DefaultValue: false,
+	Name:         "no-resume",
+	Usage:        "restart interrupted oras:// transfers from the beginning instead of resuming them",
+	EnvKeys:      []string{"NO_RESUME"},
+}
+
 // --nohttps (deprecated)
 var commonOldNoHTTPSFlag = cmdline.Flag{
 	ID:           "commonOldNoHTTPSFlag",
@@ -37,9 +37,16 @@ var buildArgs struct {
 	encrypt             bool
 	fakeroot            bool
 	fakefakeroot        bool
+	fakerootCaps        string
 	fixPerms            bool
+	stripDocs           bool
+	stripLocales        bool
+	stripPaths          []string
+	jsonProgress        bool
+	jsonProgressFd      int
 	isJSON              bool
 	noCleanUp           bool
+	noSectionCache      bool
 	noTest              bool
 	sandbox             bool
 	update              bool
@@ -140,6 +147,16 @@ var buildNoCleanupFlag = cmdline.Flag{
 	EnvKeys:      []string{"NO_CLEANUP"},
 }
 
+// --no-section-cache
+var buildNoSectionCacheFlag = cmdline.Flag{
+	ID:           "buildNoSectionCacheFlag",
+	Value:        &buildArgs.noSectionCache,
+	DefaultValue: false,
+	Name:         "no-section-cache",
+	Usage:        "with --update --sandbox, execute all sections even if unchanged since the last build (the image cache is still used, see --disable-cache)",
+	EnvKeys:      []string{"NO_SECTION_CACHE"},
+}
+
 // --fakeroot
 var buildFakerootFlag = cmdline.Flag{
 	ID:           "buildFakerootFlag",
@@ -172,6 +189,70 @@ var buildFixPermsFlag = cmdline.Flag{
 	EnvKeys:      []string{"FIXPERMS"},
 }
 
+// --fakeroot-caps
+var buildFakerootCapsFlag = cmdline.Flag{
+	ID:           "buildFakerootCapsFlag",
+	Value:        &buildArgs.fakerootCaps,
+	DefaultValue: "",
+	Name:         "fakeroot-caps",
+	Usage:        "a comma separated capability list set during the %post section when building with --fakeroot, in place of all capabilities",
+	EnvKeys:      []string{"FAKEROOT_CAPS"},
+	Tag:          "<caps>",
+}
+
+// --strip-docs
+var buildStripDocsFlag = cmdline.Flag{
+	ID:           "buildStripDocsFlag",
+	Value:        &buildArgs.stripDocs,
+	DefaultValue: false,
+	Name:         "strip-docs",
+	Usage:        "remove documentation (/usr/share/{doc,man,info,gtk-doc}) from the image, license files are kept",
+	EnvKeys:      []string{"STRIP_DOCS"},
+}
+
+// --strip-locales
+var buildStripLocalesFlag = cmdline.Flag{
+	ID:           "buildStripLocalesFlag",
+	Value:        &buildArgs.stripLocales,
+	DefaultValue: false,
+	Name:         "strip-locales",
+	Usage:        "remove non english locales (/usr/share/locale) from the image",
+	EnvKeys:      []string{"STRIP_LOCALES"},
+}
+
+// --strip-path
+var buildStripPathFlag = cmdline.Flag{
+	ID:           "buildStripPathFlag",
+	Value:        &buildArgs.stripPaths,
+	DefaultValue: cmdline.StringArray{},
+	Name:         "strip-path",
+	Usage:        "remove the paths matching the given pattern from the image once all sections ran (can be specified multiple times)",
+	EnvKeys:      []string{"STRIP_PATH"},
+	Tag:          "<pattern>",
+	EnvHandler:   cmdline.EnvAppendValue,
+}
+
+// --json-progress
+var buildJSONProgressFlag = cmdline.Flag{
+	ID:           "buildJSONProgressFlag",
+	Value:        &buildArgs.jsonProgress,
+	DefaultValue: false,
+	Name:         "json-progress",
+	Usage:        "report build progress as newline-delimited JSON events (sections run with their exit code, final image size and digest)",
+	EnvKeys:      []string{"JSON_PROGRESS"},
+}
+
+// --json-progress-fd
+var buildJSONProgressFdFlag = cmdline.Flag{
+	ID:           "buildJSONProgressFdFlag",
+	Value:        &buildArgs.jsonProgressFd,
+	DefaultValue: 2,
+	Name:         "json-progress-fd",
+	Usage:        "file descriptor the --json-progress events are written to",
+	EnvKeys:      []string{"JSON_PROGRESS_FD"},
+	Tag:          "<fd>",
+}
+
 // --nv
 var buildNvFlag = cmdline.Flag{
 	ID:           "nvFlag",
@@ -324,12 +405,19 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&buildEncryptFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildFakerootFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildFixPermsFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildFakerootCapsFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildJSONFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildLibraryFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildNoCleanupFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNoSectionCacheFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildNoTestFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildSandboxFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildSectionFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildStripDocsFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildStripLocalesFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildStripPathFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildJSONProgressFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildJSONProgressFdFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildUpdateFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&commonForceFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&commonNoHTTPSFlag, buildCmd)
@@ -341,6 +429,7 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&dockerLoginFlag, buildCmd)
 
 		cmdManager.RegisterFlagForCmd(&commonPromptForPassphraseFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&commonPassphraseFdFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&commonPEMFlag, buildCmd)
 
 		cmdManager.RegisterFlagForCmd(&buildNvFlag, buildCmd)
@@ -447,15 +536,13 @@ func checkBuildTarget(path string) error {
 
 			question := fmt.Sprintf("Build target '%s' already exists and will be deleted during the build process. Do you want to continue? [y/N] ", f.Name())
 
-			img, err := image.Init(abspath, false)
+			_, err := image.Init(abspath, false, image.OptDetectOnly())
 			if err != nil {
 				if err != image.ErrUnknownFormat {
 					return fmt.Errorf("while determining '%s' format: %s", f.Name(), err)
 				}
 				// unknown image file format
 				question = fmt.Sprintf("Build target '%s' may be a definition file or a text/binary file that will be overwritten. Do you still want to overwrite it? [y/N] ", f.Name())
-			} else {
-				img.File.Close()
 			}
 
 			input, err := interactive.AskYNQuestion("n", question)
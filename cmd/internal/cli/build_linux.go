@@ -14,6 +14,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	osExec "os/exec"
 	"strconv"
@@ -41,6 +42,7 @@ import (
 	"github.com/apptainer/apptainer/pkg/util/namespaces"
 	keyClient "github.com/apptainer/container-key-client/client"
 	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
 )
 
 func fakerootExec(isDeffile, unprivEncrypt bool) {
@@ -248,7 +250,7 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string, fa
 		keyInfo = k
 
 		if keyInfo == nil && unprivilege {
-			sylog.Errorf("Missing encryption info, please add `--passphrase` or `--pem-path` or corresponding environment variable")
+			sylog.Errorf("Missing encryption info, please add `--passphrase`, `--passphrase-fd` or `--pem-path` or corresponding environment variable")
 			return
 		}
 	} else {
@@ -356,6 +358,11 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string, fa
 		sylog.Fatalf("%v", err)
 	}
 
+	progress, err := jsonProgressWriter()
+	if err != nil {
+		sylog.Fatalf("While setting up JSON progress: %v", err)
+	}
+
 	b, err := build.New(
 		defs,
 		build.Config{
@@ -366,6 +373,7 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string, fa
 				ImgCache:          imgCache,
 				TmpDir:            tmpDir,
 				NoCache:           disableCache,
+				NoSectionCache:    buildArgs.noSectionCache,
 				Update:            buildArgs.update,
 				Force:             forceOverwrite,
 				Sections:          buildArgs.sections,
@@ -374,16 +382,21 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string, fa
 				LibraryURL:        buildArgs.libraryURL,
 				LibraryAuthToken:  authToken,
 				FakerootPath:      fakerootPath,
+				FakerootCaps:      buildArgs.fakerootCaps,
 				KeyServerOpts:     ko,
 				OCIAuthConfig:     authConf,
 				DockerDaemonHost:  dockerHost,
 				EncryptionKeyInfo: keyInfo,
 				FixPerms:          buildArgs.fixPerms,
+				StripDocs:         buildArgs.stripDocs,
+				StripLocales:      buildArgs.stripLocales,
+				StripPaths:        buildArgs.stripPaths,
 				SandboxTarget:     sandboxTarget,
 				Binds:             buildArgs.bindPaths,
 				Unprivilege:       unprivilege,
 				ReqAuthFile:       reqAuthFile,
 				Platform:          *dp,
+				JSONProgress:      progress,
 			},
 		})
 	if err != nil {
@@ -400,6 +413,22 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string, fa
 	}
 }
 
+// jsonProgressWriter returns the writer of the build progress events
+// requested with --json-progress, or nil if they are not requested.
+func jsonProgressWriter() (io.Writer, error) {
+	if !buildArgs.jsonProgress {
+		return nil, nil
+	}
+	fd := buildArgs.jsonProgressFd
+	if fd < 0 {
+		return nil, fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_GETFD, 0); err != nil {
+		return nil, fmt.Errorf("file descriptor %d is not open: %s", fd, err)
+	}
+	return os.NewFile(uintptr(fd), "json-progress"), nil
+}
+
 func checkSections() error {
 	var all, none bool
 	for _, section := range buildArgs.sections {
@@ -422,10 +451,7 @@ func checkSections() error {
 }
 
 func isImage(spec string) bool {
-	i, err := image.Init(spec, false)
-	if i != nil {
-		_ = i.File.Close()
-	}
+	_, err := image.Init(spec, false, image.OptDetectOnly())
 	return err == nil
 }
 
@@ -435,23 +461,28 @@ func isImage(spec string) bool {
 // enforce the unique flag/env precedence for the encryption flow
 func getEncryptionMaterial(cmd *cobra.Command) (*cryptkey.KeyInfo, error) {
 	passphraseFlag := cmd.Flags().Lookup("passphrase")
+	passphraseFdFlag := cmd.Flags().Lookup("passphrase-fd")
 	PEMFlag := cmd.Flags().Lookup("pem-path")
 	passphraseEnv, passphraseEnvOK := os.LookupEnv("APPTAINER_ENCRYPTION_PASSPHRASE")
 	pemPathEnv, pemPathEnvOK := os.LookupEnv("APPTAINER_ENCRYPTION_PEM_PATH")
 	pemDataEnv, pemDataEnvOK := os.LookupEnv("APPTAINER_ENCRYPTION_PEM_DATA")
 
-	if PEMFlag == nil || passphraseFlag == nil {
+	if PEMFlag == nil || passphraseFlag == nil || passphraseFdFlag == nil {
 		return nil, nil
 	}
 
 	// checks for no flags/envvars being set
-	if !(PEMFlag.Changed || pemPathEnvOK || pemDataEnvOK || passphraseFlag.Changed || passphraseEnvOK) {
+	if !(PEMFlag.Changed || pemPathEnvOK || pemDataEnvOK || passphraseFlag.Changed || passphraseFdFlag.Changed || passphraseEnvOK) {
 		return nil, nil
 	}
 
+	if passphraseFlag.Changed && passphraseFdFlag.Changed {
+		return nil, fmt.Errorf("--passphrase and --passphrase-fd are mutually exclusive")
+	}
+
 	// order of precedence:
 	// 1. PEM flag
-	// 2. Passphrase flag
+	// 2. Passphrase flag or passphrase file descriptor flag
 	// 3. PEM PATH envvar
 	// 4. PEM DATA envvar
 	// 5. Passphrase envvar
@@ -495,6 +526,18 @@ func getEncryptionMaterial(cmd *cobra.Command) (*cryptkey.KeyInfo, error) {
 		return &cryptkey.KeyInfo{Format: cryptkey.Passphrase, Material: passphrase}, nil
 	}
 
+	if passphraseFdFlag.Changed {
+		sylog.Verbosef("Using passphrase from file descriptor %d for encrypted container", passphraseFd)
+		passphrase, err := interactive.ReadPassphraseFd(passphraseFd)
+		if err != nil {
+			return nil, err
+		}
+		if passphrase == "" {
+			sylog.Fatalf("Cannot encrypt container with empty passphrase")
+		}
+		return &cryptkey.KeyInfo{Format: cryptkey.Passphrase, Material: passphrase}, nil
+	}
+
 	if pemPathEnvOK {
 		exists, err := fs.PathExists(pemPathEnv)
 		if err != nil {
@@ -31,5 +31,6 @@ func init() {
 
 		cmdManager.RegisterSubCmd(configCmd, configFakerootCmd)
 		cmdManager.RegisterSubCmd(configCmd, configGlobalCmd)
+		cmdManager.RegisterSubCmd(configCmd, configLoopCmd)
 	})
 }
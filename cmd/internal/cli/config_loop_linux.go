@@ -0,0 +1,60 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/apptainer/apptainer/docs"
+	"github.com/apptainer/apptainer/internal/app/apptainer"
+	"github.com/apptainer/apptainer/pkg/cmdline"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/spf13/cobra"
+)
+
+// -c|--clean
+var loopConfigClean bool
+
+var loopConfigCleanFlag = cmdline.Flag{
+	ID:           "loopConfigCleanFlag",
+	Value:        &loopConfigClean,
+	DefaultValue: false,
+	Name:         "clean",
+	ShortHand:    "c",
+	Usage:        "detach the stale loop devices whose backing file was deleted and which are not mounted (root user only)",
+}
+
+// configLoopCmd apptainer config loop
+var configLoopCmd = &cobra.Command{
+	Args:                  cobra.ExactArgs(0),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if loopConfigClean {
+			CheckRoot(cmd, args)
+			if err := apptainer.CleanLoopDevices(); err != nil {
+				sylog.Fatalf("%s", err)
+			}
+			return
+		}
+		if err := apptainer.PrintLoopDevices(os.Stdout); err != nil {
+			sylog.Fatalf("%s", err)
+		}
+	},
+
+	Use:     docs.ConfigLoopUse,
+	Short:   docs.ConfigLoopShort,
+	Long:    docs.ConfigLoopLong,
+	Example: docs.ConfigLoopExample,
+}
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterFlagForCmd(&loopConfigCleanFlag, configLoopCmd)
+	})
+}
@@ -21,9 +21,12 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"syscall"
 
 	"github.com/apptainer/apptainer/docs"
+	"github.com/apptainer/apptainer/internal/pkg/image/unpacker"
 	"github.com/apptainer/apptainer/internal/pkg/util/env"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs"
 	"github.com/apptainer/apptainer/pkg/cmdline"
 	"github.com/apptainer/apptainer/pkg/image"
 	"github.com/apptainer/apptainer/pkg/inspect"
@@ -187,6 +190,25 @@ type command struct {
 	metadata    *inspect.Metadata
 	sifMetadata *inspect.Metadata
 	img         *image.Image
+	// rootfs is the directory where the metadata files were extracted
+	// from the image root filesystem, if any
+	rootfs string
+	// allData and metadataDir are used to read the extracted metadata
+	// files, metadataDir is relative to the extracted root filesystem
+	allData     bool
+	metadataDir string
+	// files lists the metadata files to read from the extracted root
+	// filesystem in the order they were requested
+	files []metadataFiles
+}
+
+// metadataFiles describes the metadata files read for a section, patterns
+// are file names or glob patterns relative to the metadata directories,
+// rootPatterns only apply to the /.singularity.d directory.
+type metadataFiles struct {
+	section      string
+	rootPatterns []string
+	patterns     []string
 }
 
 //nolint:dupword
@@ -215,9 +237,12 @@ func newCommand(allData bool, appName string, img *image.Image) *command {
 				sylog.Fatalf("Could not inspect %s: %s SIF descriptor not found", img.Path, metadataJSON)
 			}
 			sylog.Debugf("No %s SIF descriptor found", metadataJSON)
+			prefix = command.extractMetadataFiles()
 		}
 	} else if runtime.GOOS != "linux" {
 		sylog.Fatalf("Could not inspect image %s on this platform, only SIF and sandbox images are supported", img.Path)
+	} else {
+		prefix = command.extractMetadataFiles()
 	}
 
 	pathPrefix := filepath.Join(prefix, "/.singularity.d")
@@ -263,9 +288,169 @@ func newCommand(allData bool, appName string, img *image.Image) *command {
 	`
 
 	command.script = fmt.Sprintf(snippet, allVar, prefix, sectionDelim, pathPrefix)
+	if command.rootfs != "" {
+		command.allData = allData
+		command.metadataDir = strings.TrimPrefix(pathPrefix, prefix)
+	}
 	return command
 }
 
+// extractMetadataFiles extracts the metadata files from the squashfs root
+// filesystem of the image into a temporary directory, so they can be read
+// without running the container. It returns the directory where the root
+// filesystem files were extracted, or an empty string if extraction is not
+// possible, the container is then executed to read the metadata files.
+func (c *command) extractMetadataFiles() string {
+	part, err := c.img.GetRootFsPartition()
+	if err != nil || part.Type != image.SQUASHFS {
+		sylog.Debugf("Root filesystem of %s is not a squashfs partition, metadata will be read from the running container", c.img.Path)
+		return ""
+	}
+
+	s := unpacker.NewSquashfs()
+	if !s.HasUnsquashfs() {
+		sylog.Debugf("unsquashfs not found, metadata will be read from the running container")
+		return ""
+	}
+
+	dir, err := os.MkdirTemp("", "inspect-")
+	if err != nil {
+		sylog.Debugf("Could not create temporary directory: %s", err)
+		return ""
+	}
+	rootfs := filepath.Join(dir, "rootfs")
+
+	// app metadata are only present if the image contains SCIF apps
+	for _, files := range [][]string{
+		{"/.singularity.d", "/scif/apps/*/scif"},
+		{"/.singularity.d"},
+	} {
+		var reader io.Reader
+		reader, err = image.NewPartitionReader(c.img, image.RootFs, -1)
+		if err != nil {
+			break
+		}
+		if err = s.ExtractFiles(files, reader, rootfs); err == nil {
+			c.rootfs = dir
+			return rootfs
+		}
+	}
+
+	sylog.Debugf("Could not extract metadata files from %s, metadata will be read from the running container: %s", c.img.Path, err)
+	os.RemoveAll(dir)
+	return ""
+}
+
+// cleanup removes the metadata files extracted from the image, if any.
+func (c *command) cleanup() {
+	if c.rootfs != "" {
+		os.RemoveAll(c.rootfs)
+	}
+}
+
+// readExtractedMetadata reads the requested metadata files from the
+// extracted root filesystem. Paths are resolved as if the root filesystem
+// was the host root, and files are opened without following symlinks, so
+// an image can't make inspect read files outside of it.
+func (c *command) readExtractedMetadata() error {
+	rootfs := filepath.Join(c.rootfs, "rootfs")
+	dirs := []string{c.metadataDir}
+
+	apps, err := readExtractedDir(rootfs, "/scif/apps")
+	if err != nil {
+		return err
+	}
+	for _, app := range apps {
+		appDir := filepath.Join("/scif/apps", app, "scif")
+		fi, err := os.Stat(filepath.Join(rootfs, fs.EvalRelative(appDir, rootfs)))
+		if err != nil || !fi.IsDir() {
+			continue
+		}
+		if err := c.setAttribute("apps", app, ""); err != nil {
+			return err
+		}
+		if c.allData {
+			dirs = append(dirs, appDir)
+		}
+	}
+
+	for _, files := range c.files {
+		for _, dir := range dirs {
+			patterns := files.patterns
+			if filepath.Base(dir) == ".singularity.d" {
+				patterns = append(files.rootPatterns, patterns...)
+			}
+			for _, pattern := range patterns {
+				if err := c.readExtractedFiles(rootfs, files.section, filepath.Join(dir, pattern)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// readExtractedFiles sets the section attribute with the content of the
+// regular files matching pattern in the extracted root filesystem.
+func (c *command) readExtractedFiles(rootfs, section, pattern string) error {
+	dir := filepath.Dir(pattern)
+	names, err := readExtractedDir(rootfs, dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if ok, _ := filepath.Match(filepath.Base(pattern), name); !ok {
+			continue
+		}
+		file := filepath.Join(dir, name)
+		b, err := readExtractedFile(rootfs, file)
+		if err != nil {
+			return fmt.Errorf("could not inspect container: while reading %s: %s", file, err)
+		} else if b == nil {
+			continue
+		}
+		if err := c.setAttribute(section, string(b), file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readExtractedDir returns the sorted names of the non hidden entries of
+// the directory dir relative to the extracted root filesystem.
+func readExtractedDir(rootfs, dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(rootfs, fs.EvalRelative(dir, rootfs)))
+	if err != nil && !os.IsNotExist(err) && !errors.Is(err, syscall.ENOTDIR) {
+		return nil, fmt.Errorf("could not inspect container: while reading %s: %s", dir, err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+// readExtractedFile returns the content of file relative to the extracted
+// root filesystem, or nil if it's not a regular file.
+func readExtractedFile(rootfs, file string) ([]byte, error) {
+	path := filepath.Join(rootfs, fs.EvalRelative(file, rootfs))
+	f, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	} else if !fi.Mode().IsRegular() {
+		return nil, nil
+	}
+	return io.ReadAll(f)
+}
+
 func (c *command) setAttribute(section, value, file string) error {
 	sylog.Debugf("Section %s found", section)
 	value = strings.TrimRight(value, "\n")
@@ -336,12 +521,19 @@ func (c *command) getMetadata() (*inspect.Metadata, error) {
 	if c.sifMetadata != nil {
 		return c.metadata, nil
 	}
+	// metadata files extracted from the image are read directly
+	if c.rootfs != "" {
+		if err := c.readExtractedMetadata(); err != nil {
+			return nil, err
+		}
+		return c.metadata, nil
+	}
 
 	args := []string{"/bin/sh", "-c", c.script}
 	prefix := ""
 	outBuf := new(bytes.Buffer)
 
-	// Execute the compound script.
+	// Execute the compound script, directly on the host for sandbox images.
 	if c.img.Type == image.SANDBOX {
 		os.Setenv("PATH", env.DefaultPath)
 
@@ -418,6 +610,8 @@ func (c *command) getMetadata() (*inspect.Metadata, error) {
 }
 
 func (c *command) addSingleFileCommand(file string, label string) {
+	c.files = append(c.files, metadataFiles{section: label, patterns: []string{file}})
+
 	snippet := `
 	for prefix in ${ALL_PATH}; do
 		file="$prefix/%[1]s"
@@ -511,6 +705,11 @@ func (c *command) addHelpCommand() {
 
 func (c *command) addEnvironmentCommand() {
 	if c.sifMetadata == nil {
+		c.files = append(c.files, metadataFiles{
+			section:      "environment",
+			rootPatterns: []string{"env/10-docker*.sh"},
+			patterns:     []string{"env/9*-environment.sh"},
+		})
 		c.script += `
 		for prefix in ${ALL_PATH}; do
 			if [ "${prefix##*/}" = ".singularity.d" ]; then
@@ -699,6 +898,7 @@ var InspectCmd = &cobra.Command{
 		}
 
 		inspectData, err := inspectCmd.getMetadata()
+		inspectCmd.cleanup()
 		if err != nil {
 			sylog.Fatalf("%s", err)
 		}
@@ -730,16 +930,22 @@ var InspectCmd = &cobra.Command{
 				fmt.Printf("%s\n", inspectData.Data.Attributes.Runscript)
 			} else if appAttr != nil && appAttr.Runscript != "" {
 				fmt.Printf("%s\n", appAttr.Runscript)
+			} else if runscript {
+				sylog.Infof("No runscript found in %s", args[0])
 			}
 			if inspectData.Data.Attributes.Startscript != "" {
 				fmt.Printf("%s\n", inspectData.Data.Attributes.Startscript)
 			} else if appAttr != nil && appAttr.Startscript != "" {
 				fmt.Printf("%s\n", appAttr.Startscript)
+			} else if startscript {
+				sylog.Infof("No startscript found in %s", args[0])
 			}
 			if inspectData.Data.Attributes.Test != "" {
 				fmt.Printf("%s\n", inspectData.Data.Attributes.Test)
 			} else if appAttr != nil && appAttr.Test != "" {
 				fmt.Printf("%s\n", appAttr.Test)
+			} else if testfile {
+				sylog.Infof("No test script found in %s", args[0])
 			}
 			if inspectData.Data.Attributes.Helpfile != "" {
 				fmt.Printf("%s\n", inspectData.Data.Attributes.Helpfile)
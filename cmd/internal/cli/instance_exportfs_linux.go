@@ -0,0 +1,58 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/apptainer/apptainer/docs"
+	"github.com/apptainer/apptainer/internal/app/apptainer"
+	"github.com/apptainer/apptainer/pkg/cmdline"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterFlagForCmd(&instanceExportFsUserFlag, instanceExportFsCmd)
+	})
+}
+
+// -u|--user
+var instanceExportFsUser string
+
+var instanceExportFsUserFlag = cmdline.Flag{
+	ID:           "instanceExportFsUserFlag",
+	Value:        &instanceExportFsUser,
+	DefaultValue: "",
+	Name:         "user",
+	ShortHand:    "u",
+	Usage:        "export the filesystem of an instance belonging to a user (root only)",
+	Tag:          "<username>",
+	EnvKeys:      []string{"USER"},
+}
+
+// apptainer instance export-fs
+var instanceExportFsCmd = &cobra.Command{
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	Run: func(_ *cobra.Command, args []string) {
+		if instanceExportFsUser != "" && os.Getuid() != 0 {
+			sylog.Fatalf("Only the root user can export the filesystem of a user's instance")
+		}
+		if err := apptainer.InstanceExportFs(args[0], instanceExportFsUser, args[1]); err != nil {
+			sylog.Fatalf("Could not export filesystem of instance %s: %s", args[0], err)
+		}
+	},
+
+	Use:     docs.InstanceExportFsUse,
+	Short:   docs.InstanceExportFsShort,
+	Long:    docs.InstanceExportFsLong,
+	Example: docs.InstanceExportFsExample,
+}
@@ -25,6 +25,8 @@ func init() {
 		cmdManager.RegisterSubCmd(instanceCmd, instanceStopCmd)
 		cmdManager.RegisterSubCmd(instanceCmd, instanceListCmd)
 		cmdManager.RegisterSubCmd(instanceCmd, instanceStatsCmd)
+		cmdManager.RegisterSubCmd(instanceCmd, instanceSnapshotCmd)
+		cmdManager.RegisterSubCmd(instanceCmd, instanceExportFsCmd)
 	})
 }
 
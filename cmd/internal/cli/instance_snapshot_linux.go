@@ -0,0 +1,58 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/apptainer/apptainer/docs"
+	"github.com/apptainer/apptainer/internal/app/apptainer"
+	"github.com/apptainer/apptainer/pkg/cmdline"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterFlagForCmd(&instanceSnapshotUserFlag, instanceSnapshotCmd)
+	})
+}
+
+// -u|--user
+var instanceSnapshotUser string
+
+var instanceSnapshotUserFlag = cmdline.Flag{
+	ID:           "instanceSnapshotUserFlag",
+	Value:        &instanceSnapshotUser,
+	DefaultValue: "",
+	Name:         "user",
+	ShortHand:    "u",
+	Usage:        "snapshot an instance belonging to a user (root only)",
+	Tag:          "<username>",
+	EnvKeys:      []string{"USER"},
+}
+
+// apptainer instance snapshot
+var instanceSnapshotCmd = &cobra.Command{
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	Run: func(_ *cobra.Command, args []string) {
+		if instanceSnapshotUser != "" && os.Getuid() != 0 {
+			sylog.Fatalf("Only the root user can snapshot a user's instance")
+		}
+		if err := apptainer.InstanceSnapshot(args[0], instanceSnapshotUser, args[1]); err != nil {
+			sylog.Fatalf("Could not snapshot instance %s: %s", args[0], err)
+		}
+	},
+
+	Use:     docs.InstanceSnapshotUse,
+	Short:   docs.InstanceSnapshotShort,
+	Long:    docs.InstanceSnapshotLong,
+	Example: docs.InstanceSnapshotExample,
+}
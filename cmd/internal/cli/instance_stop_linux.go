@@ -96,7 +96,8 @@ var instanceStopTimeoutFlag = cmdline.Flag{
 	DefaultValue: 10,
 	Name:         "timeout",
 	ShortHand:    "t",
-	Usage:        "force kill non stopped instances after X seconds",
+	Usage:        "grace period in seconds given to each instance before it's killed with SIGKILL",
+	Tag:          "<seconds>",
 }
 
 // apptainer instance stop
@@ -121,6 +122,9 @@ var instanceStopCmd = &cobra.Command{
 				sylog.Fatalf("Could not convert stop signal: %s", err)
 			}
 		}
+		if instanceStopTimeout < 0 {
+			return errors.New("timeout must be a positive number of seconds")
+		}
 		if instanceStopForce {
 			sig = syscall.SIGKILL
 		}
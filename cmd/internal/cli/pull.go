@@ -166,6 +166,7 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&pullLibraryURIFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&pullNameFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&commonNoHTTPSFlag, PullCmd)
+		cmdManager.RegisterFlagForCmd(&commonNoResumeFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&commonTmpDirFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&pullDisableCacheFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&pullDirFlag, PullCmd)
@@ -289,7 +290,7 @@ func pullRun(cmd *cobra.Command, args []string) {
 			sylog.Fatalf("Unable to make docker oci credentials: %s", err)
 		}
 
-		_, err = oras.PullToFile(ctx, imgCache, pullTo, pullFrom, ociAuth, noHTTPS, reqAuthFile, pullSandbox)
+		_, err = oras.PullToFile(ctx, imgCache, pullTo, pullFrom, ociAuth, noHTTPS, reqAuthFile, pullSandbox, !noResume)
 		if err != nil {
 			sylog.Fatalf("While pulling image from oci registry: %v", err)
 		}
@@ -0,0 +1,88 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"errors"
+
+	"github.com/apptainer/apptainer/docs"
+	"github.com/apptainer/apptainer/internal/app/apptainer"
+	"github.com/apptainer/apptainer/pkg/cmdline"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/spf13/cobra"
+)
+
+// sandboxExcludes holds the paths excluded from a sandbox manifest
+var sandboxExcludes []string
+
+// --exclude
+var sandboxExcludeFlag = cmdline.Flag{
+	ID:           "sandboxExcludeFlag",
+	Value:        &sandboxExcludes,
+	DefaultValue: []string{},
+	Name:         "exclude",
+	Usage:        "path or shell pattern, relative to the sandbox root, to exclude from the manifest (can be repeated)",
+}
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterCmd(SandboxCmd)
+		cmdManager.RegisterSubCmd(SandboxCmd, SandboxSealCmd)
+		cmdManager.RegisterSubCmd(SandboxCmd, SandboxVerifyCmd)
+
+		cmdManager.RegisterFlagForCmd(&sandboxExcludeFlag, SandboxSealCmd, SandboxVerifyCmd)
+	})
+}
+
+// SandboxCmd is the 'sandbox' command that allows to manage sandbox images.
+var SandboxCmd = &cobra.Command{
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return errors.New("invalid command")
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.SandboxUse,
+	Short:   docs.SandboxShort,
+	Long:    docs.SandboxLong,
+	Example: docs.SandboxExample,
+}
+
+// SandboxSealCmd is the 'sandbox seal' command that records the integrity
+// manifest of a sandbox image.
+var SandboxSealCmd = &cobra.Command{
+	Args: cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		if err := apptainer.SandboxSeal(args[0], sandboxExcludes); err != nil {
+			sylog.Fatalf("%s", err)
+		}
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.SandboxSealUse,
+	Short:   docs.SandboxSealShort,
+	Long:    docs.SandboxSealLong,
+	Example: docs.SandboxSealExample,
+}
+
+// SandboxVerifyCmd is the 'sandbox verify' command that checks a sandbox
+// image against its integrity manifest.
+var SandboxVerifyCmd = &cobra.Command{
+	Args: cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		if err := apptainer.SandboxVerify(args[0], sandboxExcludes); err != nil {
+			sylog.Fatalf("%s", err)
+		}
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.SandboxVerifyUse,
+	Short:   docs.SandboxVerifyShort,
+	Long:    docs.SandboxVerifyLong,
+	Example: docs.SandboxVerifyExample,
+}
@@ -10,8 +10,12 @@
 package cli
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/apptainer/apptainer/docs"
 	"github.com/apptainer/apptainer/pkg/cmdline"
+	"github.com/apptainer/apptainer/pkg/image"
 	"github.com/apptainer/sif/v2/pkg/siftool"
 	"github.com/spf13/cobra"
 )
@@ -27,7 +31,39 @@ func init() {
 			DisableFlagsInUseLine: true,
 		}
 		siftool.AddCommands(cmd)
+		addInfoCompression(cmd)
 
 		cmdManager.RegisterCmd(cmd)
 	})
 }
+
+// addInfoCompression extends the info command to also display the
+// compression algorithm of squashfs partitions.
+func addInfoCompression(cmd *cobra.Command) {
+	info, _, err := cmd.Find([]string{"info"})
+	if err != nil || info == cmd || info.RunE == nil {
+		return
+	}
+
+	runE := info.RunE
+	info.RunE = func(c *cobra.Command, args []string) error {
+		if err := runE(c, args); err != nil {
+			return err
+		}
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return nil
+		}
+		partitions, err := image.InspectPartitions(args[1])
+		if err != nil {
+			return nil
+		}
+		for _, p := range partitions {
+			if p.ID == uint32(id) && p.Compression != "" {
+				// align with the info output columns
+				fmt.Fprintf(c.OutOrStdout(), "  %-16s  %s\n", "Compression:", p.Compression)
+			}
+		}
+		return nil
+	}
+}
@@ -162,6 +162,9 @@ struct starter {
 
     /* bounding capability set will include caps needed by nvidia-container-cli */
     bool nvCCLICaps;
+
+    /* stop once stage 1 prepared the configuration, the container is not started */
+    bool dryRun;
 };
 
 /* engine configuration */
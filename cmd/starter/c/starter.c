@@ -1312,6 +1312,11 @@ __attribute__((constructor)) static void init(void) {
     debugf("Wait completion of stage1\n");
     wait_child("stage 1", process, false);
 
+    if ( sconfig->starter.dryRun ) {
+        verbosef("Dry run requested, not starting container\n");
+        exit(0);
+    }
+
     /* change current working directory if requested by stage 1 */
     if ( sconfig->starter.workingDirectoryFd >= 0 ) {
         debugf("Applying stage 1 working directory\n");
@@ -1474,7 +1479,6 @@ __attribute__((constructor)) static void init(void) {
         network_namespace_init(&sconfig->container.namespace);
         uts_namespace_init(&sconfig->container.namespace);
         ipc_namespace_init(&sconfig->container.namespace);
-        cgroup_namespace_init(&sconfig->container.namespace);
 
         /*
          * depending of engines, the master process may require to propagate mount point
@@ -1534,6 +1538,13 @@ __attribute__((constructor)) static void init(void) {
             verbosef("Don't execute RPC server, joining instance\n");
         }
 
+        /*
+         * the cgroup namespace is initialized once the master process
+         * placed the container process in its cgroup during the RPC server
+         * lifetime, so the namespace root is the container cgroup
+         */
+        cgroup_namespace_init(&sconfig->container.namespace);
+
         debugf("Set container privileges\n");
         current = get_process_capabilities();
         apply_privileges(&sconfig->container.privileges, current);
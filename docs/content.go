@@ -628,6 +628,56 @@ Enterprise Performance Computing (EPC)`
   $ apptainer instance stats --no-stream mysql
   $ sudo apptainer instance stats --user <username> user-mysql`
 
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// instance snapshot
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	InstanceSnapshotUse   string = `snapshot [snapshot options...] <instance name> <overlay image path>`
+	InstanceSnapshotShort string = `Save the writable overlay of a running instance into a new overlay image`
+	InstanceSnapshotLong  string = `
+  The instance snapshot command copies the current state of the writable
+  overlay directory of a running instance into a new EXT3 overlay image,
+  without stopping the instance. The new image can then be used with --overlay.
+
+  Snapshots are supported for instances started with a writable overlay
+  directory. Writable EXT3 overlay images are mounted read-write by the
+  instance and can only be copied once it is stopped. Instances using
+  --writable-tmpfs or an overlay partition embedded in a SIF image can't be
+  snapshotted.
+
+  If the instance runs in a cgroup, its processes are frozen while the overlay
+  is copied so the snapshot is coherent. Otherwise writes in flight during the
+  copy may not be captured consistently.`
+	InstanceSnapshotExample string = `
+  $ apptainer instance start --overlay overlay/ my-sql.sif mysql
+  $ apptainer instance snapshot mysql mysql-snapshot.img
+  $ apptainer instance start --overlay mysql-snapshot.img my-sql.sif mysql2`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// instance export-fs
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	InstanceExportFsUse   string = `export-fs [export-fs options...] <instance name> <mount point>`
+	InstanceExportFsShort string = `Export the root filesystem of a running instance on a host mount point`
+	InstanceExportFsLong  string = `
+  The instance export-fs command mounts the root filesystem of a running
+  instance, as seen from inside the container, read-only on a host directory
+  with fuse-overlayfs. It allows to inspect the files of an instance from the
+  host, including its overlays and bind mounts, without entering its
+  namespaces.
+
+  The command runs in the foreground until it is interrupted, the mount point
+  is unmounted with fusermount or the instance exits. Only the owner of an
+  instance can export its filesystem.
+
+  The export is read-only and, as every file operation goes through the
+  fuse-overlayfs process, much slower than direct access, it is intended for
+  debugging and not for bulk transfers.`
+	InstanceExportFsExample string = `
+  $ apptainer instance start my-sql.sif mysql
+  $ mkdir /tmp/mysql-fs
+  $ apptainer instance export-fs mysql /tmp/mysql-fs &
+  $ ls /tmp/mysql-fs/var/lib/mysql
+  $ fusermount -u /tmp/mysql-fs`
+
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// instance stop
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -635,7 +685,12 @@ Enterprise Performance Computing (EPC)`
 	InstanceStopShort string = `Stop a named instance of a given container image`
 	InstanceStopLong  string = `
   The command apptainer instance stop allows you to stop and clean up a named,
-  running instance of a given container image.`
+  running instance of a given container image.
+
+  The stop signal (SIGINT by default) is sent to the instance, which is given
+  a grace period (10 seconds by default) to exit before being killed with
+  SIGKILL. When stopping several instances, e.g. with --all, each instance is
+  given its own grace period.`
 	InstanceStopExample string = `
   $ apptainer instance start my-sql.sif mysql1
   $ apptainer instance start my-sql.sif mysql2
@@ -651,7 +706,11 @@ Enterprise Performance Computing (EPC)`
   Send SIGTERM to the instance
   $ apptainer instance stop -s SIGTERM mysql1
   $ apptainer instance stop -s TERM mysql1
-  $ apptainer instance stop -s 15 mysql1`
+  $ apptainer instance stop -s 15 mysql1
+
+  Send SIGTERM and give the instance 30 seconds to exit before killing it
+  $ apptainer instance stop --signal SIGTERM --timeout 30 mysql1
+  $ apptainer instance stop --all --signal SIGTERM --timeout 30`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// pull
@@ -743,6 +802,13 @@ Enterprise Performance Computing (EPC)`
   automatically. All arguments following the container name will be passed
   directly to the runscript.
 
+  The --entrypoint option replaces the runscript with another command, which
+  receives the arguments following the container name, like the Docker
+  --entrypoint option. Unlike exec, run --entrypoint is handled as a run
+  action: the container environment is sourced and APPTAINER_COMMAND is set
+  to "run", but the runscript (including the ENTRYPOINT and CMD of images
+  converted from Docker/OCI) is ignored.
+
   apptainer run accepts the following container formats:` + formats
 	RunExamples string = `
   # Here we see that the runscript prints "Hello world: "
@@ -755,7 +821,11 @@ Enterprise Performance Computing (EPC)`
   Hello world: one two three
 
   # Note that this does the same thing
-  $ ./tmp/debian.sif one two three`
+  $ ./tmp/debian.sif one two three
+
+  # Run another command in place of the runscript
+  $ apptainer run --entrypoint /bin/echo /tmp/debian.sif one two three
+  one two three`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// shell
@@ -1127,6 +1197,21 @@ Enterprise Performance Computing (EPC)`
   To display the resulting configuration instead of writing it to file:
   $ apptainer config global --dry-run --set "bind path" /etc/resolv.conf`
 
+	ConfigLoopUse   string = `loop [option]`
+	ConfigLoopShort string = `List and clean up stale loop devices`
+	ConfigLoopLong  string = `
+  The config loop command lists the loop devices attached to a backing file,
+  and allows a root user to detach the stale loop devices left over by crashed
+  containers. Only the autoclear loop devices, as attached by Apptainer, whose
+  backing file was deleted and which are not mounted in any mount namespace
+  are detached.`
+	ConfigLoopExample string = `
+  To list the attached loop devices:
+  $ apptainer config loop
+
+  To detach the stale loop devices:
+  $ apptainer config loop --clean`
+
 	OverlayUse   string = `overlay`
 	OverlayShort string = `Manage an EXT3 writable overlay image`
 	OverlayLong  string = `
@@ -1155,6 +1240,47 @@ Enterprise Performance Computing (EPC)`
   To create an EXT3 writable overlay image for use with --fakeroot actions:
   $ apptainer overlay create --fakeroot --size 1024 /tmp/my_overlay.img`
 
+	SandboxUse   string = `sandbox`
+	SandboxShort string = `Manage the integrity of sandbox images`
+	SandboxLong  string = `
+  The sandbox command allows recording and verifying the integrity manifest
+  of sandbox (directory) images.`
+	SandboxExample string = `
+  All sandbox commands have their own help output:
+
+  $ apptainer help sandbox seal
+  $ apptainer sandbox verify --help`
+
+	SandboxSealUse   string = `seal <options> sandbox`
+	SandboxSealShort string = `Record the integrity manifest of a sandbox image`
+	SandboxSealLong  string = `
+  The sandbox seal command records the type, permissions and content digest of
+  every file of a sandbox image in the /.singularity.d/manifest.json manifest.
+  Volatile paths (/dev, /proc, /sys, /run, /tmp, /var/tmp, /var/cache and
+  /var/log) are excluded from the manifest, additional paths can be excluded
+  with --exclude. Sealing an already sealed sandbox replaces its manifest.`
+	SandboxSealExample string = `
+  To seal a sandbox image:
+  $ apptainer sandbox seal /tmp/my_sandbox
+
+  To seal a sandbox image excluding the /opt/app/data directory:
+  $ apptainer sandbox seal --exclude /opt/app/data /tmp/my_sandbox`
+
+	SandboxVerifyUse   string = `verify <options> sandbox`
+	SandboxVerifyShort string = `Verify a sandbox image against its integrity manifest`
+	SandboxVerifyLong  string = `
+  The sandbox verify command checks a sandbox image against the manifest
+  recorded by sandbox seal, it lists the files added, removed or modified
+  since the sandbox was sealed and exits with an error if any is found. Paths
+  excluded when sealing the sandbox are ignored, additional paths can be
+  ignored with --exclude.`
+	SandboxVerifyExample string = `
+  To verify a sandbox image:
+  $ apptainer sandbox verify /tmp/my_sandbox
+
+  To verify a sandbox image ignoring files under /etc matching *.conf:
+  $ apptainer sandbox verify --exclude '/etc/*.conf' /tmp/my_sandbox`
+
 	CheckpointUse   string = `checkpoint`
 	CheckpointShort string = `Manage container checkpoint state (experimental)`
 	CheckpointLong  string = `
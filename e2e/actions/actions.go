@@ -11,6 +11,7 @@ package actions
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
@@ -27,6 +28,7 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/test/tool/exec"
 	"github.com/apptainer/apptainer/internal/pkg/test/tool/require"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
+	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/pkg/errors"
 )
 
@@ -1975,6 +1977,26 @@ func (c actionTests) actionBinds(t *testing.T) {
 			}
 		})
 	}
+
+	// --mount relative sources are resolved against the current directory
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("MountRelativeSource"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithDir(workspace),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--mount", "type=bind,source=./canary/file,destination="+contCanaryFile, sandbox, "test", "-f", contCanaryFile),
+		e2e.ExpectExit(0),
+	)
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("MountNonExistentSource"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithDir(workspace),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--mount", "type=bind,source=./non-existent,destination=/mnt", sandbox, "true"),
+		e2e.ExpectExit(255),
+	)
 }
 
 func (c actionTests) actionLayerType(t *testing.T) {
@@ -3014,6 +3036,8 @@ func (c actionTests) actionCompat(t *testing.T) {
 func (c actionTests) actionFakerootHome(t *testing.T) {
 	e2e.EnsureImage(t, c.env)
 
+	u := e2e.CurrentUser(t)
+
 	type test struct {
 		name     string
 		args     []string
@@ -3034,6 +3058,17 @@ func (c actionTests) actionFakerootHome(t *testing.T) {
 			exitCode: 0,
 			expect:   e2e.ExpectOutput(e2e.ExactMatch, "/root"),
 		},
+		{
+			name:     "fakeroot --home-keep-path",
+			args:     []string{"--home-keep-path", c.env.ImagePath, "sh", "-c", "echo $HOME"},
+			exitCode: 0,
+			expect:   e2e.ExpectOutput(e2e.ExactMatch, u.Dir),
+		},
+		{
+			name:     "fakeroot --home-keep-path passwd",
+			args:     []string{"--home-keep-path", c.env.ImagePath, "grep", "^root:.*:" + u.Dir + ":", "/etc/passwd"},
+			exitCode: 0,
+		},
 	}
 
 	for _, tt := range tests {
@@ -3051,6 +3086,801 @@ func (c actionTests) actionFakerootHome(t *testing.T) {
 	}
 }
 
+// actionImageMountOpts checks that --image-mount-opts accepts access time
+// options for ext3 overlay images and rejects any other mount option.
+func (c actionTests) actionImageMountOpts(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	require.Filesystem(t, "overlay")
+	require.Command(t, "mkfs.ext3")
+	require.Command(t, "dd")
+
+	testdir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "image-mount-opts-", "")
+	t.Cleanup(func() {
+		if !t.Failed() {
+			e2e.Privileged(cleanup)(t)
+		}
+	})
+
+	ext3Img := c.ext3Create(t, testdir)
+
+	tests := []struct {
+		name   string
+		args   []string
+		exit   int
+		expect e2e.ApptainerCmdResultOp
+	}{
+		{
+			name: "noatime lazytime",
+			args: []string{"--image-mount-opts", "noatime,lazytime", "--overlay", ext3Img, c.env.ImagePath, "touch", "/noatime"},
+			exit: 0,
+		},
+		{
+			name:   "unsupported option",
+			args:   []string{"--image-mount-opts", "suid", "--overlay", ext3Img, c.env.ImagePath, "true"},
+			exit:   255,
+			expect: e2e.ExpectError(e2e.ContainMatch, `image mount option "suid" is not supported`),
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.RootProfile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(tt.args...),
+			e2e.ExpectExit(tt.exit, tt.expect),
+		)
+	}
+}
+
+// actionKeepID checks that --keep-id runs the container with the same
+// UID/GID as the host user, contrary to --fakeroot which maps the user to root.
+func (c actionTests) actionKeepID(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	// --keep-id has the same subuid/subgid requirements as --fakeroot
+	e2e.FakerootProfile.Requirements(t)
+
+	u := e2e.UserProfile.HostUser(t)
+
+	tests := []struct {
+		name   string
+		args   []string
+		expect string
+	}{
+		{
+			name:   "uid",
+			args:   []string{"--keep-id", c.env.ImagePath, "id", "-u"},
+			expect: strconv.Itoa(int(u.UID)),
+		},
+		{
+			name:   "gid",
+			args:   []string{"--keep-id", c.env.ImagePath, "id", "-g"},
+			expect: strconv.Itoa(int(u.GID)),
+		},
+		{
+			name:   "uid without suid",
+			args:   []string{"--keep-id", "--userns", c.env.ImagePath, "id", "-u"},
+			expect: strconv.Itoa(int(u.UID)),
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(tt.args...),
+			e2e.ExpectExit(
+				0,
+				e2e.ExpectOutput(e2e.ExactMatch, tt.expect),
+			),
+		)
+	}
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("with fakeroot"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--keep-id", "--fakeroot", c.env.ImagePath, "true"),
+		e2e.ExpectExit(
+			255,
+			e2e.ExpectError(e2e.ContainMatch, "--keep-id and --fakeroot are mutually exclusive"),
+		),
+	)
+}
+
+// actionEnvBinds checks that command line binds override binds from
+// APPTAINER_BIND with the same destination, and that --no-env-binds
+// ignores binds from APPTAINER_BIND.
+func (c actionTests) actionEnvBinds(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	envDir, cleanupEnv := e2e.MakeTempDir(t, c.env.TestDir, "env-binds-env-", "")
+	defer cleanupEnv(t)
+	cliDir, cleanupCli := e2e.MakeTempDir(t, c.env.TestDir, "env-binds-cli-", "")
+	defer cleanupCli(t)
+
+	for _, f := range []string{filepath.Join(envDir, "env-file"), filepath.Join(cliDir, "cli-file")} {
+		if err := os.WriteFile(f, []byte{}, 0o644); err != nil {
+			t.Fatalf("could not create %s: %s", f, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		envBind string
+		args    []string
+		exit    int
+	}{
+		{
+			name:    "env bind",
+			envBind: envDir + ":/srv",
+			args:    []string{c.env.ImagePath, "test", "-f", "/srv/env-file"},
+			exit:    0,
+		},
+		{
+			name:    "command line override",
+			envBind: envDir + ":/srv",
+			args:    []string{"--bind", cliDir + ":/srv", c.env.ImagePath, "test", "-f", "/srv/cli-file"},
+			exit:    0,
+		},
+		{
+			name:    "command line override hides env bind",
+			envBind: envDir + ":/srv",
+			args:    []string{"--bind", cliDir + ":/srv", c.env.ImagePath, "test", "-f", "/srv/env-file"},
+			exit:    1,
+		},
+		{
+			name:    "mount override",
+			envBind: envDir + ":/srv",
+			args:    []string{"--mount", "type=bind,source=" + cliDir + ",destination=/srv", c.env.ImagePath, "test", "-f", "/srv/cli-file"},
+			exit:    0,
+		},
+		{
+			name:    "no env binds",
+			envBind: envDir + ":/srv",
+			args:    []string{"--no-env-binds", c.env.ImagePath, "test", "-f", "/srv/env-file"},
+			exit:    1,
+		},
+		{
+			name:    "no env binds keeps command line binds",
+			envBind: envDir + ":/srv",
+			args:    []string{"--no-env-binds", "--bind", cliDir + ":/mnt", c.env.ImagePath, "test", "-f", "/mnt/cli-file"},
+			exit:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("exec"),
+			e2e.WithEnv(append(os.Environ(), "APPTAINER_BIND="+tt.envBind)),
+			e2e.WithArgs(tt.args...),
+			e2e.ExpectExit(tt.exit),
+		)
+	}
+}
+
+// actionOverlayBind checks that --overlay-bind gives a writable view of a
+// host directory without modifying it.
+func (c actionTests) actionOverlayBind(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	lowerDir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "overlay-bind-", "")
+	defer cleanup(t)
+
+	if err := os.WriteFile(filepath.Join(lowerDir, "file"), []byte("host"), 0o644); err != nil {
+		t.Fatalf("could not create file in %s: %s", lowerDir, err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		exit int
+	}{
+		{
+			name: "lower visible",
+			args: []string{"--overlay-bind", "lower=" + lowerDir + ",target=/app", c.env.ImagePath, "grep", "-q", "host", "/app/file"},
+			exit: 0,
+		},
+		{
+			name: "writable",
+			args: []string{"--overlay-bind", "lower=" + lowerDir + ",upper=tmpfs,target=/app", c.env.ImagePath, "sh", "-c", "echo container > /app/file && touch /app/new && grep -q container /app/file"},
+			exit: 0,
+		},
+		{
+			name: "multiple",
+			args: []string{"--overlay-bind", "lower=" + lowerDir + ",target=/app1", "--overlay-bind", "lower=" + lowerDir + ",target=/app2", c.env.ImagePath, "sh", "-c", "touch /app1/new && test ! -e /app2/new"},
+			exit: 0,
+		},
+		{
+			name: "unsupported upper",
+			args: []string{"--overlay-bind", "lower=" + lowerDir + ",upper=" + lowerDir + ",target=/app", c.env.ImagePath, "true"},
+			exit: 255,
+		},
+	}
+
+	for _, profile := range []e2e.Profile{e2e.UserProfile, e2e.RootProfile} {
+		t.Run(profile.String(), func(t *testing.T) {
+			for _, tt := range tests {
+				c.env.RunApptainer(
+					t,
+					e2e.AsSubtest(tt.name),
+					e2e.WithProfile(profile),
+					e2e.WithCommand("exec"),
+					e2e.WithArgs(tt.args...),
+					e2e.ExpectExit(tt.exit),
+				)
+			}
+		})
+	}
+
+	// modifications are discarded
+	b, err := os.ReadFile(filepath.Join(lowerDir, "file"))
+	if err != nil {
+		t.Fatalf("could not read file in %s: %s", lowerDir, err)
+	} else if string(b) != "host" {
+		t.Errorf("host file was modified: %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(lowerDir, "new")); !os.IsNotExist(err) {
+		t.Errorf("file created in container is present in host directory")
+	}
+}
+
+// actionScheduling checks that --sched-policy and --nice are applied to the
+// container process.
+func (c actionTests) actionScheduling(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	// fields 19 and 41 of /proc/<pid>/stat are the nice value and the policy
+	stat := []string{"cut", "-d", " ", "-f", "19,41", "/proc/self/stat"}
+
+	tests := []struct {
+		name    string
+		profile e2e.Profile
+		args    []string
+		exit    int
+		output  string
+	}{
+		{
+			name:    "nice",
+			profile: e2e.UserProfile,
+			args:    []string{"--nice", "5"},
+			output:  "5 0",
+		},
+		{
+			name:    "batch",
+			profile: e2e.UserProfile,
+			args:    []string{"--sched-policy", "batch", "--nice", "10"},
+			output:  "10 3",
+		},
+		{
+			name:    "idle",
+			profile: e2e.UserNamespaceProfile,
+			args:    []string{"--sched-policy", "idle"},
+			output:  "0 5",
+		},
+		{
+			name:    "fifo root",
+			profile: e2e.RootProfile,
+			args:    []string{"--sched-policy", "fifo:10"},
+			output:  "0 1",
+		},
+		{
+			name:    "negative nice root",
+			profile: e2e.RootProfile,
+			args:    []string{"--nice", "-5"},
+			output:  "-5 0",
+		},
+		{
+			name:    "nice out of range",
+			profile: e2e.UserProfile,
+			args:    []string{"--nice", "20"},
+			exit:    255,
+		},
+		{
+			name:    "bad policy",
+			profile: e2e.UserProfile,
+			args:    []string{"--sched-policy", "fifo"},
+			exit:    255,
+		},
+	}
+
+	for _, tt := range tests {
+		expects := []e2e.ApptainerCmdResultOp{}
+		if tt.output != "" {
+			expects = append(expects, e2e.ExpectOutput(e2e.ExactMatch, tt.output))
+		}
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(tt.profile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(append(append(tt.args, c.env.ImagePath), stat...)...),
+			e2e.ExpectExit(tt.exit, expects...),
+		)
+	}
+}
+
+// actionNsswitch checks that --nsswitch stages a minimal nsswitch.conf
+// resolving users and groups from local files.
+func (c actionTests) actionNsswitch(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	for _, profile := range e2e.Profiles {
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(profile.String()),
+			e2e.WithProfile(profile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs("--nsswitch", c.env.ImagePath, "grep", "^passwd:", "/etc/nsswitch.conf"),
+			e2e.ExpectExit(
+				0,
+				e2e.ExpectOutput(e2e.ExactMatch, "passwd:     files"),
+			),
+		)
+	}
+}
+
+// actionIdentityEntries checks that --passwd-entry and --group-entry
+// override the entries generated in the container passwd and group files.
+func (c actionTests) actionIdentityEntries(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	for _, profile := range []e2e.Profile{e2e.UserProfile, e2e.UserNamespaceProfile, e2e.FakerootProfile} {
+		u := profile.ContainerUser(t)
+		passwdEntry := fmt.Sprintf("e2euser:%d:%d:E2E User:/home/e2e:/bin/zsh", u.UID, u.GID)
+		groupEntry := fmt.Sprintf("e2egroup:%d", u.GID)
+
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(profile.String()+"/passwd"),
+			e2e.WithProfile(profile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs("--passwd-entry", passwdEntry, c.env.ImagePath, "grep", "^e2euser:", "/etc/passwd"),
+			e2e.ExpectExit(
+				0,
+				e2e.ExpectOutput(e2e.ExactMatch, fmt.Sprintf("e2euser:x:%d:%d:E2E User:/home/e2e:/bin/zsh", u.UID, u.GID)),
+			),
+		)
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(profile.String()+"/group"),
+			e2e.WithProfile(profile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs("--passwd-entry", passwdEntry, "--group-entry", groupEntry, c.env.ImagePath, "grep", "^e2egroup:", "/etc/group"),
+			e2e.ExpectExit(
+				0,
+				e2e.ExpectOutput(e2e.ExactMatch, fmt.Sprintf("e2egroup:x:%d:e2euser", u.GID)),
+			),
+		)
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(profile.String()+"/uid mismatch"),
+			e2e.WithProfile(profile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs("--passwd-entry", fmt.Sprintf("e2euser:%d:%d::/home/e2e:/bin/sh", u.UID+1, u.GID), c.env.ImagePath, "true"),
+			e2e.ExpectExit(
+				255,
+				e2e.ExpectError(e2e.ContainMatch, "doesn't match the container user ID"),
+			),
+		)
+	}
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("bad entry"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--passwd-entry", "e2euser:1000", c.env.ImagePath, "true"),
+		e2e.ExpectExit(255),
+	)
+}
+
+// actionWrap checks that --wrap prepends the wrapper command to the
+// container process.
+func (c actionTests) actionWrap(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	tests := []struct {
+		name    string
+		command string
+		wrap    string
+		args    []string
+		exit    int
+		expect  e2e.ApptainerCmdResultOp
+	}{
+		{
+			name:    "exec",
+			command: "exec",
+			wrap:    "env 'WRAPPED=wrapped value'",
+			args:    []string{"sh", "-c", "echo $WRAPPED"},
+			expect:  e2e.ExpectOutput(e2e.ExactMatch, "wrapped value"),
+		},
+		{
+			name:    "run",
+			command: "run",
+			wrap:    "env WRAPPED=1",
+			args:    []string{"env"},
+			expect:  e2e.ExpectOutput(e2e.ContainMatch, "WRAPPED=1"),
+		},
+		{
+			name:    "missing wrapper",
+			command: "exec",
+			wrap:    "/not/a/wrapper",
+			args:    []string{"true"},
+			exit:    255,
+			expect:  e2e.ExpectError(e2e.ContainMatch, "wrapper command /not/a/wrapper not found in container"),
+		},
+		{
+			name:    "bad wrapper",
+			command: "exec",
+			wrap:    "env 'WRAPPED",
+			args:    []string{"true"},
+			exit:    255,
+			expect:  e2e.ExpectError(e2e.ContainMatch, "invalid wrapper command"),
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand(tt.command),
+			e2e.WithArgs(append([]string{"--wrap", tt.wrap, c.env.ImagePath}, tt.args...)...),
+			e2e.ExpectExit(tt.exit, tt.expect),
+		)
+	}
+}
+
+// actionFakerootCaps checks that --fakeroot-caps restricts the capabilities
+// of the container process within the fakeroot user namespace.
+func (c actionTests) actionFakerootCaps(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	tests := []struct {
+		name   string
+		caps   string
+		exit   int
+		expect e2e.ApptainerCmdResultOp
+	}{
+		{
+			// CAP_CHOWN and CAP_FOWNER are capabilities 0 and 3
+			name:   "chown fowner",
+			caps:   "CAP_CHOWN,fowner",
+			expect: e2e.ExpectOutput(e2e.RegexMatch, `CapEff:\s+0000000000000009`),
+		},
+		{
+			name:   "ineffective",
+			caps:   "CAP_CHOWN,CAP_SYS_MODULE",
+			expect: e2e.ExpectError(e2e.ContainMatch, "Capabilities CAP_SYS_MODULE can't be effective within a user namespace"),
+		},
+		{
+			name:   "unknown",
+			caps:   "CAP_UNKNOWN",
+			exit:   255,
+			expect: e2e.ExpectError(e2e.ContainMatch, "unknown fakeroot capabilities: CAP_UNKNOWN"),
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.FakerootProfile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs("--fakeroot-caps", tt.caps, c.env.ImagePath, "grep", "CapEff", "/proc/self/status"),
+			e2e.ExpectExit(tt.exit, tt.expect),
+		)
+	}
+}
+
+// actionDumpOciSpec checks that --dump-oci-spec writes the container OCI
+// runtime spec, with or without starting the container with --dry-run.
+func (c actionTests) actionDumpOciSpec(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	dir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "dump-oci-spec-", "")
+	t.Cleanup(func() {
+		if !t.Failed() {
+			cleanup(t)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		dryRun bool
+		output string
+	}{
+		{
+			name:   "run",
+			output: "started",
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+			output: "",
+		},
+	}
+
+	for _, tt := range tests {
+		specPath := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".json")
+		args := []string{"--dump-oci-spec", specPath}
+		if tt.dryRun {
+			args = append(args, "--dry-run")
+		}
+		args = append(args, c.env.ImagePath, "echo", "started")
+
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(args...),
+			e2e.PostRun(func(t *testing.T) {
+				b, err := os.ReadFile(specPath)
+				if err != nil {
+					t.Fatalf("OCI spec not written: %s", err)
+				}
+				spec := new(specs.Spec)
+				if err := json.Unmarshal(b, spec); err != nil {
+					t.Fatalf("failed to decode OCI spec: %s", err)
+				}
+				if spec.Process == nil || spec.Linux == nil {
+					t.Errorf("incomplete OCI spec written: %s", b)
+				}
+			}),
+			e2e.ExpectExit(0, e2e.ExpectOutput(e2e.ExactMatch, tt.output)),
+		)
+	}
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("dry run without dump"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--dry-run", c.env.ImagePath, "true"),
+		e2e.ExpectExit(255, e2e.ExpectError(e2e.ContainMatch, "--dry-run requires --dump-oci-spec")),
+	)
+}
+
+// actionPrintCaps checks that --print-caps prints the resolved capability
+// sets of the container process without starting the container.
+func (c actionTests) actionPrintCaps(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	tests := []struct {
+		name    string
+		profile e2e.Profile
+		args    []string
+		expect  string
+	}{
+		{
+			name:    "user",
+			profile: e2e.UserProfile,
+			expect:  `(?m)^Permitted:\s+-$`,
+		},
+		{
+			name:    "root drop caps",
+			profile: e2e.RootProfile,
+			args:    []string{"--drop-caps", "CAP_NET_RAW"},
+			expect:  `(?m)^Bounding:\s+\S*CAP_CHOWN`,
+		},
+	}
+
+	for _, tt := range tests {
+		args := append([]string{"--print-caps"}, tt.args...)
+		args = append(args, c.env.ImagePath, "echo", "started")
+
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(tt.profile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(args...),
+			e2e.ExpectExit(0,
+				e2e.ExpectOutput(e2e.RegexMatch, tt.expect),
+				e2e.ExpectOutput(e2e.UnwantedContainMatch, "started"),
+				e2e.ExpectOutput(e2e.UnwantedContainMatch, "CAP_NET_RAW"),
+			),
+		)
+	}
+}
+
+// overlayPrecedence checks which overlay partition of a SIF image provides
+// a file present in two overlay partitions with --overlay-precedence.
+func (c actionTests) overlayPrecedence(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	require.Filesystem(t, "overlay")
+	require.Command(t, "mksquashfs")
+
+	testdir, err := os.MkdirTemp(c.env.TestDir, "overlay-precedence-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if !t.Failed() {
+			os.RemoveAll(testdir)
+		}
+	})
+
+	sifImage := filepath.Join(testdir, "overlays.sif")
+	if err := fs.CopyFile(c.env.ImagePath, sifImage, 0o755); err != nil {
+		t.Fatalf("failed to copy %s to %s: %s", c.env.ImagePath, sifImage, err)
+	}
+
+	// add two squashfs overlay partitions providing the same file
+	for _, name := range []string{"first", "second"} {
+		dir := filepath.Join(testdir, name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "precedence"), []byte(name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		squashfsImage := filepath.Join(testdir, name+".sqfs")
+		cmd := exec.Command("mksquashfs", dir, squashfsImage, "-noappend", "-all-root")
+		if res := cmd.Run(t); res.Error != nil {
+			t.Fatalf("Unexpected error while running command.\n%s", res)
+		}
+
+		c.env.RunApptainer(
+			t,
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("sif"),
+			e2e.WithArgs(
+				"add",
+				"--datatype", "4", "--partarch", "2",
+				"--partfs", "1", "--parttype", "4",
+				"--groupid", "1",
+				sifImage, squashfsImage,
+			),
+			e2e.ExpectExit(0),
+		)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		expect string
+	}{
+		{
+			name:   "default",
+			expect: "second",
+		},
+		{
+			name:   "last",
+			args:   []string{"--overlay-precedence", "last"},
+			expect: "second",
+		},
+		{
+			name:   "first",
+			args:   []string{"--overlay-precedence", "first"},
+			expect: "first",
+		},
+	}
+
+	for _, tt := range tests {
+		args := append(tt.args, sifImage, "cat", "/precedence")
+
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(args...),
+			e2e.ExpectExit(0,
+				e2e.ExpectOutput(e2e.ExactMatch, tt.expect),
+			),
+		)
+	}
+}
+
+// actionEntrypoint checks that run --entrypoint executes the given command
+// in place of the runscript, with the run action environment.
+func (c actionTests) actionEntrypoint(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	tests := []struct {
+		name   string
+		args   []string
+		expect string
+	}{
+		{
+			name:   "absolute path",
+			args:   []string{"--entrypoint", "/bin/echo", c.env.ImagePath, "one", "two"},
+			expect: "one two",
+		},
+		{
+			name:   "command in PATH",
+			args:   []string{"--entrypoint", "echo", c.env.ImagePath, "one", "two"},
+			expect: "one two",
+		},
+		{
+			name:   "run action",
+			args:   []string{"--entrypoint", "/bin/sh", c.env.ImagePath, "-c", "echo $APPTAINER_COMMAND"},
+			expect: "run",
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("run"),
+			e2e.WithArgs(tt.args...),
+			e2e.ExpectExit(
+				0,
+				e2e.ExpectOutput(e2e.ExactMatch, tt.expect),
+			),
+		)
+	}
+}
+
+// actionCgroupfs checks that /sys/fs/cgroup is mounted read-only and
+// reflects the container cgroup with --cgroupns, and that the host
+// /sys/fs/cgroup is bound with --bind-cgroupfs.
+func (c actionTests) actionCgroupfs(t *testing.T) {
+	require.CgroupsV2Unified(t)
+	e2e.EnsureImage(t, c.env)
+
+	tests := []struct {
+		name    string
+		args    []string
+		exit    int
+		wantOut string
+	}{
+		{
+			name:    "cgroupns root",
+			args:    []string{"--cgroupns", c.env.ImagePath, "cat", "/proc/self/cgroup"},
+			wantOut: "0::/",
+		},
+		{
+			name: "cgroupns mount",
+			args: []string{"--cgroupns", c.env.ImagePath, "test", "-f", "/sys/fs/cgroup/cgroup.controllers"},
+		},
+		{
+			name: "cgroupns read-only",
+			args: []string{"--cgroupns", c.env.ImagePath, "mkdir", "/sys/fs/cgroup/e2e"},
+			exit: 1,
+		},
+		{
+			name: "bind cgroupfs",
+			args: []string{"--bind-cgroupfs", c.env.ImagePath, "test", "-f", "/sys/fs/cgroup/cgroup.controllers"},
+		},
+	}
+
+	for _, profile := range []e2e.Profile{e2e.UserProfile, e2e.UserNamespaceProfile} {
+		t.Run(profile.String(), func(t *testing.T) {
+			for _, tt := range tests {
+				var expect []e2e.ApptainerCmdResultOp
+				if tt.wantOut != "" {
+					expect = append(expect, e2e.ExpectOutput(e2e.ExactMatch, tt.wantOut))
+				}
+				c.env.RunApptainer(
+					t,
+					e2e.AsSubtest(tt.name),
+					e2e.WithProfile(profile),
+					e2e.WithCommand("exec"),
+					e2e.WithArgs(tt.args...),
+					e2e.ExpectExit(tt.exit, expect...),
+				)
+			}
+		})
+	}
+}
+
 // Make sure --workdir and --scratch work together nicely even when workdir is a
 // relative path. Test needs to be run in non-parallel mode, because it changes
 // the current working directory of the host.
@@ -3266,6 +4096,20 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 		"umask":                        np(c.actionUmask),       // test umask propagation
 		"invalidRemote":                np(c.invalidRemote),     // GHSA-5mv9-q7fq-9394
 		"fakeroot home":                c.actionFakerootHome,    // test home dir in fakeroot
+		"keep-id":                      c.actionKeepID,          // test --keep-id uid/gid mapping
+		"image mount opts":             c.actionImageMountOpts,  // test --image-mount-opts
+		"env binds":                    c.actionEnvBinds,        // test APPTAINER_BIND override and --no-env-binds
+		"entrypoint":                   c.actionEntrypoint,      // test run --entrypoint
+		"overlay bind":                 c.actionOverlayBind,     // test --overlay-bind
+		"scheduling":                   c.actionScheduling,      // test --sched-policy and --nice
+		"nsswitch":                     c.actionNsswitch,        // test --nsswitch
+		"identity entries":             c.actionIdentityEntries, // test --passwd-entry and --group-entry
+		"wrap":                         c.actionWrap,            // test --wrap
+		"fakeroot caps":                c.actionFakerootCaps,    // test --fakeroot-caps
+		"dump oci spec":                c.actionDumpOciSpec,     // test --dump-oci-spec and --dry-run
+		"print caps":                   c.actionPrintCaps,       // test --print-caps
+		"overlay precedence":           c.overlayPrecedence,     // test --overlay-precedence
+		"cgroupfs":                     c.actionCgroupfs,        // test --cgroupns and --bind-cgroupfs
 		"relWorkdirScratch":            np(c.relWorkdirScratch), // test relative --workdir with --scratch
 		"issue 1868":                   c.issue1868,             // https://github.com/apptainer/apptainer/issues/1868
 		"auth":                         np(c.actionAuth),        // tests action cmds w/authenticated pulls from OCI registries
@@ -723,6 +723,47 @@ func (c *ctx) instanceFlagsRootless(t *testing.T) {
 	}
 }
 
+// With --cgroupns and a resource limit, the cgroup namespace root and the
+// cgroup filesystem mounted on /sys/fs/cgroup must be the limited cgroup.
+func (c *ctx) actionCgroupNamespace(t *testing.T, profile e2e.Profile) {
+	e2e.EnsureImage(t, c.env)
+	require.CgroupsV2Unified(t)
+	require.CgroupsResourceExists(t, "", "memory.max")
+	if !profile.Privileged() {
+		require.CgroupsV2Delegated(t, "memory")
+	}
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("memory.max"),
+		e2e.WithProfile(profile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--memory", "500M", "--cgroupns", c.env.ImagePath, "cat", "/sys/fs/cgroup/memory.max"),
+		e2e.ExpectExit(0, e2e.ExpectOutput(e2e.ExactMatch, "524288000")),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("namespace root"),
+		e2e.WithProfile(profile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--memory", "500M", "--cgroupns", c.env.ImagePath, "cat", "/proc/self/cgroup"),
+		e2e.ExpectExit(0, e2e.ExpectOutput(e2e.ExactMatch, "0::/")),
+	)
+}
+
+func (c *ctx) actionCgroupNamespaceRoot(t *testing.T) {
+	c.actionCgroupNamespace(t, e2e.RootProfile)
+}
+
+func (c *ctx) actionCgroupNamespaceRootless(t *testing.T) {
+	for _, profile := range []e2e.Profile{e2e.UserProfile, e2e.UserNamespaceProfile} {
+		t.Run(profile.String(), func(t *testing.T) {
+			c.actionCgroupNamespace(t, profile)
+		})
+	}
+}
+
 // On cgroups v2 systems, can run rootless without resource limits with bad
 // XDG_RUNTIME_DIR / DBUS_SESSION_BUS_ADDRESS. Cannot run with resource limits
 // and bad env vars.
@@ -891,6 +932,8 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 		"action rootless cgroups":         np(env.WithRootlessManagers(c.actionApplyRootless)),
 		"action flags root cgroups":       np(env.WithRootManagers(c.actionFlagsRoot)),
 		"action flags rootless cgroups":   np(env.WithRootlessManagers(c.actionFlagsRootless)),
+		"action cgroupns root":            np(env.WithRootManagers(c.actionCgroupNamespaceRoot)),
+		"action cgroupns rootless":        np(env.WithRootlessManagers(c.actionCgroupNamespaceRootless)),
 		"action dbus xdg":                 np(c.actionDbusXDG),
 		"instance dbus xdg":               np(c.instanceDbusXdg),
 	}
@@ -430,6 +430,23 @@ func (c configTests) configGlobal(t *testing.T) {
 			directiveValue: "no",
 			exit:           1,
 		},
+		{
+			name:           "MaxOverlayLayersExceeded",
+			argv:           []string{"--overlay", c.squashfsImage, "--overlay", c.squashfsImage, c.env.ImagePath, "true"},
+			profile:        e2e.RootProfile,
+			directive:      "max overlay layers",
+			directiveValue: "1",
+			exit:           255,
+			resultOp:       e2e.ExpectError(e2e.ContainMatch, "too many overlay layers"),
+		},
+		{
+			name:           "MaxOverlayLayers",
+			argv:           []string{"--overlay", c.squashfsImage, "--overlay", c.squashfsImage, c.env.ImagePath, "true"},
+			profile:        e2e.RootProfile,
+			directive:      "max overlay layers",
+			directiveValue: "2",
+			exit:           0,
+		},
 		// test image is owned by root:root
 		{
 			name:           "LimitContainerOwnersUser",
@@ -479,6 +496,15 @@ func (c configTests) configGlobal(t *testing.T) {
 			directiveValue: c.env.TestDir,
 			exit:           0,
 		},
+		{
+			name:           "AllowedFusemountProgramsDenied",
+			argv:           []string{"--fusemount", "host:true /mnt", c.env.ImagePath, "true"},
+			profile:        e2e.UserProfile,
+			directive:      "allowed fusemount programs",
+			directiveValue: "sshfs, s3fs",
+			exit:           255,
+			resultOp:       e2e.ExpectError(e2e.ContainMatch, "fusemount program true not allowed"),
+		},
 		{
 			name:           "AllowContainerSifNo",
 			argv:           []string{c.sifImage, "true"},
@@ -547,6 +547,20 @@ func (c ctx) apptainerEnvEval(t *testing.T) {
 			noeval:       false,
 			expectOutput: "$(id -u)",
 		},
+		{
+			name:         "whoami subshell env",
+			args:         testArgs,
+			env:          []string{"APPTAINERENV_WHO=$(whoami)"},
+			noeval:       false,
+			expectOutput: e2e.CurrentUser(t).Name,
+		},
+		{
+			name:         "spaces and double quotes env",
+			args:         testArgs,
+			env:          []string{`APPTAINERENV_WHO=a b "c"`},
+			noeval:       false,
+			expectOutput: `a b "c"`,
+		},
 		// Docker/OCI behavior (with --no-eval)
 		{
 			name:         "no-eval/no env",
@@ -618,6 +632,20 @@ func (c ctx) apptainerEnvEval(t *testing.T) {
 			noeval:       true,
 			expectOutput: "\\$(id -u)",
 		},
+		{
+			name:         "no-eval/whoami subshell env",
+			args:         testArgs,
+			env:          []string{"APPTAINERENV_WHO=$(whoami)"},
+			noeval:       true,
+			expectOutput: "$(whoami)",
+		},
+		{
+			name:         "no-eval/spaces and double quotes env",
+			args:         testArgs,
+			env:          []string{`APPTAINERENV_WHO=a b "c"`},
+			noeval:       true,
+			expectOutput: `a b "c"`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -641,6 +669,65 @@ func (c ctx) apptainerEnvEval(t *testing.T) {
 	}
 }
 
+// apptainerEnvSpecialChars checks that environment variable values containing
+// newlines, quotes and shell special characters are preserved exactly, whether
+// forwarded from the host environment or set with --env and --no-eval.
+func (c ctx) apptainerEnvSpecialChars(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	testArgs := []string{"/bin/sh", "-c", `printf '%s' "$SPECIAL"`}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{
+			name:  "newlines",
+			value: "-----BEGIN CERTIFICATE-----\nMIIBszCCAVmgAwIBAgIU\n-----END CERTIFICATE-----",
+		},
+		{
+			name:  "escaped newline",
+			value: `line1\u000Aline2\nline3`,
+		},
+		{
+			name:  "quotes",
+			value: `single ' and double " quotes`,
+		},
+		{
+			name:  "dollar and backticks",
+			value: "$HOME $(id -u) `id -u` ${PATH}",
+		},
+		{
+			name:  "mixed",
+			value: "a '$x'\nb \"`y`\"\n\\",
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest("host/"+tt.name),
+			e2e.WithEnv([]string{"SPECIAL=" + tt.value}),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(append([]string{c.env.ImagePath}, testArgs...)...),
+			e2e.ExpectExit(0,
+				e2e.ExpectOutput(e2e.ExactMatch, tt.value),
+			),
+		)
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest("env/"+tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(append([]string{"--no-eval", "--env", "SPECIAL=" + tt.value, c.env.ImagePath}, testArgs...)...),
+			e2e.ExpectExit(0,
+				e2e.ExpectOutput(e2e.ExactMatch, tt.value),
+			),
+		)
+	}
+}
+
 // apptainerRuntimeTimetutEnv tests the new apptainer run/instance option `--runscript-timeout`
 func (c ctx) apptainerRuntimeTimeoutEnv(t *testing.T) {
 	e2e.EnsureImage(t, c.env)
@@ -673,6 +760,7 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 		"environment option":       c.apptainerEnvOption,
 		"environment file":         c.apptainerEnvFile,
 		"env eval":                 c.apptainerEnvEval,
+		"env special chars":        c.apptainerEnvSpecialChars,
 		"issue 5057":               c.issue5057,                  // https://github.com/apptainer/singularity/issues/5057
 		"issue 5426":               c.issue5426,                  // https://github.com/apptainer/singularity/issues/5426
 		"issue 43":                 c.issue43,                    // https://github.com/sylabs/singularity/issues/43
@@ -297,6 +297,56 @@ func (c ctx) testRocm(t *testing.T) {
 	}
 }
 
+// testDRI checks that DRM render nodes are available in the container
+// with --dri, including with a minimal /dev.
+func (c ctx) testDRI(t *testing.T) {
+	require.DRI(t)
+	e2e.EnsureImage(t, c.env)
+
+	nodes, err := filepath.Glob("/dev/dri/renderD*")
+	if err != nil {
+		t.Fatalf("while finding render nodes: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		profile e2e.Profile
+		args    []string
+	}{
+		{
+			name:    "User",
+			profile: e2e.UserProfile,
+			args:    []string{"--dri", c.env.ImagePath, "test", "-c", nodes[0]},
+		},
+		{
+			name:    "UserContain",
+			profile: e2e.UserProfile,
+			args:    []string{"--contain", "--dri", c.env.ImagePath, "test", "-c", nodes[0]},
+		},
+		{
+			name:    "UserNamespaceContain",
+			profile: e2e.UserNamespaceProfile,
+			args:    []string{"--contain", "--dri", c.env.ImagePath, "test", "-c", nodes[0]},
+		},
+		{
+			name:    "RootContain",
+			profile: e2e.RootProfile,
+			args:    []string{"--contain", "--dri", c.env.ImagePath, "test", "-c", nodes[0]},
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(tt.profile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(tt.args...),
+			e2e.ExpectExit(0),
+		)
+	}
+}
+
 //nolint:dupl
 func (c ctx) testBuildNvidiaLegacy(t *testing.T) {
 	require.Nvidia(t)
@@ -560,6 +610,7 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 		"nvidia":       c.testNvidiaLegacy,
 		"nvccli":       c.testNvCCLI,
 		"rocm":         c.testRocm,
+		"dri":          c.testDRI,
 		"build nvidia": c.testBuildNvidiaLegacy,
 		"build nvccli": c.testBuildNvCCLI,
 		"build rocm":   c.testBuildRocm,
@@ -95,6 +95,7 @@ func (c ctx) testCommands(t *testing.T) {
 		{"Run", "run"},
 		{"Run-help", "run-help"},
 		{"Remote", "remote"},
+		{"Sandbox", "sandbox"},
 		{"Search", "search"},
 		{"Shell", "shell"},
 		{"SIF", "sif"},
@@ -1867,6 +1867,111 @@ func (c imgBuildTests) testWritableTmpfs(t *testing.T) {
 	)
 }
 
+// buildStrip checks that --strip-docs, --strip-locales and --strip-path
+// remove files installed by the %post section.
+func (c imgBuildTests) buildStrip(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	tmpdir, cleanup := c.tempDir(t, "build-strip-test")
+	t.Cleanup(func() {
+		if !t.Failed() {
+			cleanup()
+		}
+	})
+
+	definition := fmt.Sprintf(`Bootstrap: localimage
+From: %s
+%%post
+mkdir -p /usr/share/doc/pkg /usr/share/locale/fr /opt/cache
+echo readme > /usr/share/doc/pkg/README
+echo copyright > /usr/share/doc/pkg/copyright
+echo french > /usr/share/locale/fr/tool.mo
+echo cache > /opt/cache/data
+`, c.env.ImagePath)
+
+	defFile := e2e.RawDefFile(t, tmpdir, strings.NewReader(definition))
+	sandbox := filepath.Join(tmpdir, "sandbox")
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(e2e.RootProfile),
+		e2e.WithCommand("build"),
+		e2e.WithArgs("-F", "--sandbox", "--strip-docs", "--strip-locales", "--strip-path", "/opt/cache/*", sandbox, defFile),
+		e2e.PostRun(func(t *testing.T) {
+			if t.Failed() {
+				return
+			}
+			for _, f := range []string{"/usr/share/doc/pkg/README", "/usr/share/locale/fr", "/opt/cache/data"} {
+				if _, err := os.Stat(filepath.Join(sandbox, f)); !os.IsNotExist(err) {
+					t.Errorf("%s was not stripped", f)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(sandbox, "/usr/share/doc/pkg/copyright")); err != nil {
+				t.Errorf("license file was stripped: %s", err)
+			}
+		}),
+		e2e.ExpectExit(0, e2e.ExpectError(e2e.ContainMatch, "Stripping root filesystem saved")),
+	)
+}
+
+// buildJSONProgress checks the build progress events reported with
+// --json-progress, for a successful build and for a failing %post section.
+func (c imgBuildTests) buildJSONProgress(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	tmpdir, cleanup := c.tempDir(t, "build-json-progress-test")
+	t.Cleanup(func() {
+		if !t.Failed() {
+			cleanup()
+		}
+	})
+
+	tests := []struct {
+		name     string
+		post     string
+		exit     int
+		expected []string
+	}{
+		{
+			name: "Success",
+			post: "true",
+			exit: 0,
+			expected: []string{
+				`"type":"section-end","section":"post","exitCode":0`,
+				`"type":"build-end","duration":`,
+				`"digest":"sha256:`,
+			},
+		},
+		{
+			name: "PostFailure",
+			post: "exit 3",
+			exit: 255,
+			expected: []string{
+				`"type":"section-end","section":"post","exitCode":3`,
+				`"type":"build-end","section":"post"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		definition := fmt.Sprintf("Bootstrap: localimage\nFrom: %s\n%%post\n%s\n", c.env.ImagePath, tt.post)
+		defFile := e2e.RawDefFile(t, tmpdir, strings.NewReader(definition))
+
+		matchers := make([]e2e.ApptainerCmdResultOp, 0, len(tt.expected))
+		for _, e := range tt.expected {
+			matchers = append(matchers, e2e.ExpectError(e2e.ContainMatch, e))
+		}
+
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.RootProfile),
+			e2e.WithCommand("build"),
+			e2e.WithArgs("-F", "--json-progress", filepath.Join(tmpdir, "image.sif"), defFile),
+			e2e.ExpectExit(tt.exit, matchers...),
+		)
+	}
+}
+
 // testBuildEnvironmentVariables tests the environment variables exposed by the build system when executing
 // definition sections. This includes APPTAINER_ROOTFS, APPTAINER_ENVIRONMENT, APPTAINER_LABELS and their
 // SINGULARITY_ prefixed counterparts.
@@ -2343,6 +2448,8 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 		"library host":                           c.buildLibraryHost,                     // build image with hostname in library URI
 		"customShebang":                          c.buildCustomShebang,                   // build image with custom #! in %test and %runscript
 		"test with writable tmpfs":               c.testWritableTmpfs,                    // build image, using writable tmpfs in the test step
+		"strip":                                  c.buildStrip,                           // build image, stripping documentation and locales
+		"json progress":                          c.buildJSONProgress,                    // build image, reporting progress as JSON events
 		"test build system environment":          c.testBuildEnvironmentVariables,        // build image with build system environment variables set in definition
 		"test build under fakeroot modes":        c.testContainerBuildUnderFakerootModes, // build image under different fakeroot modes
 		"issue 2347":                             c.issue2347,                            // https://github.com/apptainer/apptainer/issues/2347
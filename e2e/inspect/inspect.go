@@ -15,7 +15,9 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/apptainer/apptainer/e2e/internal/e2e"
@@ -24,6 +26,7 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/test/tool/require"
 	"github.com/apptainer/apptainer/pkg/image"
 	"github.com/apptainer/apptainer/pkg/inspect"
+	"github.com/apptainer/sif/v2/pkg/sif"
 )
 
 type ctx struct {
@@ -415,6 +418,92 @@ func (c ctx) apptainerInspect(t *testing.T) {
 		e2e.WithArgs("--all", sandboxImage),
 		e2e.ExpectExit(0, compareAll),
 	)
+
+	// test missing script
+	if err := os.Remove(filepath.Join(sandboxImage, ".singularity.d", "test")); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("could not remove test script: %s", err)
+	}
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("Sandbox/no test script"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("inspect"),
+		e2e.WithArgs("--test", sandboxImage),
+		e2e.ExpectExit(
+			0,
+			e2e.ExpectOutput(e2e.ExactMatch, ""),
+			e2e.ExpectError(e2e.ContainMatch, "No test script found"),
+		),
+	)
+
+	// metadata files extracted from SIF and squashfs images must not
+	// follow symlinks pointing outside of the image
+	require.Command(t, "mksquashfs")
+
+	const secret = "inspect symlink escape"
+	hostFile := filepath.Join(testDir, "host-file")
+	if err := os.WriteFile(hostFile, []byte(secret+"\n"), 0o644); err != nil {
+		t.Fatalf("could not create %s: %s", hostFile, err)
+	}
+	escapes := map[string]string{
+		"runscript":   hostFile,
+		"labels.json": strings.Repeat("../", 32) + strings.TrimPrefix(hostFile, "/"),
+	}
+	for file, target := range escapes {
+		path := filepath.Join(sandboxImage, ".singularity.d", file)
+		if err := os.Remove(path); err != nil {
+			t.Fatalf("could not remove %s: %s", path, err)
+		}
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatalf("could not create symlink %s: %s", path, err)
+		}
+	}
+
+	escapeSquash := filepath.Join(testDir, "escape.sqs")
+	cmd = exec.Command("mksquashfs", sandboxImage, escapeSquash, "-noappend", "-all-root")
+	if res := cmd.Run(t); res.Error != nil {
+		t.Fatalf("Unexpected error while running command.\n%s", res)
+	}
+	escapeSIF := filepath.Join(testDir, "escape.sif")
+	createRootfsSIF(t, escapeSquash, escapeSIF)
+
+	for name, img := range map[string]string{"SIF": escapeSIF, "Squash": escapeSquash} {
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(name+"/symlink escape"),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("inspect"),
+			e2e.WithArgs("--all", img),
+			e2e.ExpectExit(
+				0,
+				e2e.ExpectOutput(e2e.UnwantedContainMatch, secret),
+			),
+		)
+	}
+}
+
+// createRootfsSIF creates a SIF image at path with the squashfs image as
+// root filesystem and without the inspect metadata.
+func createRootfsSIF(t *testing.T, squashImage, path string) {
+	f, err := os.Open(squashImage)
+	if err != nil {
+		t.Fatalf("failed to open %s: %s", squashImage, err)
+	}
+	defer f.Close()
+
+	di, err := sif.NewDescriptorInput(sif.DataPartition, f,
+		sif.OptPartitionMetadata(sif.FsSquash, sif.PartPrimSys, runtime.GOARCH),
+	)
+	if err != nil {
+		t.Fatalf("failed to create descriptor input: %s", err)
+	}
+	fimg, err := sif.CreateContainerAtPath(path, sif.OptCreateWithDescriptors(di))
+	if err != nil {
+		t.Fatalf("failed to create %s: %s", path, err)
+	}
+	if err := fimg.UnloadContainer(); err != nil {
+		t.Fatalf("failed to unload %s: %s", path, err)
+	}
 }
 
 // E2ETests is the main func to trigger the test suite
@@ -177,6 +177,180 @@ func (c *ctx) testStopAll(t *testing.T) {
 	c.stopInstance(t, "", "--all")
 }
 
+// Test that instances ignoring the stop signal are killed once their
+// grace period expired.
+func (c *ctx) testStopTimeout(t *testing.T) {
+	const n = 2
+
+	for i := 0; i < n; i++ {
+		c.env.RunApptainer(
+			t,
+			e2e.WithProfile(c.profile),
+			e2e.WithCommand("instance start"),
+			e2e.WithArgs(c.env.ImagePath, "timeout"+strconv.Itoa(i+1), strconv.Itoa(instanceStartPort+i)),
+			e2e.ExpectExit(0),
+		)
+	}
+
+	// SIGCONT doesn't terminate the instances, they are killed after the timeout
+	_, stderr, success := c.stopInstance(t, "timeout*", "--signal", "SIGCONT", "--timeout", "1")
+	if success {
+		for i := 0; i < n; i++ {
+			killed := fmt.Sprintf("timeout%d instance killed", i+1)
+			if !strings.Contains(stderr, killed) {
+				t.Errorf("%q not found in stop output: %s", killed, stderr)
+			}
+		}
+	}
+}
+
+// Test mounting a path of a running instance with --mount-from.
+func (c *ctx) testMountFrom(t *testing.T) {
+	const instanceName = "mountfrom"
+
+	dir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "mount-from-", "")
+	defer cleanup(t)
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("shared"), 0o644); err != nil {
+		t.Fatalf("Failed to create file: %s", err)
+	}
+
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("instance start"),
+		e2e.WithArgs("--bind", dir+":/shared", c.env.ImagePath, instanceName, strconv.Itoa(instanceStartPort)),
+		e2e.ExpectExit(0),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("mount"),
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--mount-from", "instance://"+instanceName+":/shared:/data", c.env.ImagePath, "cat", "/data/file"),
+		e2e.ExpectExit(0, e2e.ExpectOutput(e2e.ExactMatch, "shared")),
+	)
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("missing source"),
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--mount-from", "instance://"+instanceName+":/not/exist", c.env.ImagePath, "true"),
+		e2e.ExpectExit(255),
+	)
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("missing instance"),
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--mount-from", "instance://notaninstance:/shared", c.env.ImagePath, "true"),
+		e2e.ExpectExit(255),
+	)
+
+	c.stopInstance(t, instanceName)
+}
+
+// Test the propagation of mounts between the host and a bind with the
+// slave propagation option: host mounts are visible in the container but
+// container mounts don't propagate back to the host, a shared bind is
+// rejected.
+func (c *ctx) testBindPropagation(t *testing.T) {
+	const instanceName = "propagation"
+
+	e2e.EnsureImage(t, c.env)
+
+	c.profile = e2e.RootProfile
+
+	dir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "bind-propagation-", "")
+	defer cleanup(t)
+	hostDir := filepath.Join(dir, "host")
+	containerDir := filepath.Join(dir, "container")
+	for _, d := range []string{hostDir, containerDir} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %s", err)
+		}
+	}
+
+	isMountPoint := func(t *testing.T, path string) bool {
+		entries, err := proc.GetMountInfoEntry("/proc/self/mountinfo")
+		if err != nil {
+			t.Fatalf("Failed to read mount information: %s", err)
+		}
+		for _, e := range entries {
+			if e.Point == path {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the bind source must be shared to receive host mounts
+	e2e.Privileged(func(t *testing.T) {
+		if err := syscall.Mount(dir, dir, "", syscall.MS_BIND, ""); err != nil {
+			t.Fatalf("Failed to bind %s: %s", dir, err)
+		}
+		if err := syscall.Mount("", dir, "", syscall.MS_SHARED, ""); err != nil {
+			t.Fatalf("Failed to set %s shared: %s", dir, err)
+		}
+	})(t)
+	defer e2e.Privileged(func(t *testing.T) {
+		syscall.Unmount(dir, syscall.MNT_DETACH)
+	})(t)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("shared"),
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--bind", dir+":/propagation:shared", c.env.ImagePath, "true"),
+		e2e.ExpectExit(
+			255,
+			e2e.ExpectError(e2e.ContainMatch, "shared bind option is not supported"),
+		),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("instance start"),
+		e2e.WithArgs("--bind", dir+":/propagation:slave", c.env.ImagePath, instanceName, strconv.Itoa(instanceStartPort)),
+		e2e.ExpectExit(0),
+	)
+
+	e2e.Privileged(func(t *testing.T) {
+		if err := syscall.Mount("tmpfs", hostDir, "tmpfs", 0, ""); err != nil {
+			t.Fatalf("Failed to mount tmpfs on %s: %s", hostDir, err)
+		}
+	})(t)
+	defer e2e.Privileged(func(t *testing.T) {
+		syscall.Unmount(hostDir, syscall.MNT_DETACH)
+	})(t)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("host to container"),
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("instance://"+instanceName, "grep", " /propagation/host ", "/proc/self/mountinfo"),
+		e2e.ExpectExit(0),
+	)
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("container to host"),
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("instance://"+instanceName, "mount", "-t", "tmpfs", "tmpfs", "/propagation/container"),
+		e2e.ExpectExit(0),
+		e2e.PostRun(func(t *testing.T) {
+			if isMountPoint(t, containerDir) {
+				t.Errorf("container mount propagated to host %s", containerDir)
+			}
+		}),
+	)
+
+	c.stopInstance(t, instanceName)
+}
+
 // Test basic options like mounting a custom home directory, changing the
 // hostname, etc.
 func (c *ctx) testBasicOptions(t *testing.T) {
@@ -295,6 +469,176 @@ func (c *ctx) testContain(t *testing.T) {
 	}
 }
 
+// Snapshot the writable overlay directory of a running instance, and check
+// the snapshot image holds the changes made in the instance, whiteouts
+// included. Writable EXT3 overlay images are refused.
+func (c *ctx) testInstanceSnapshot(t *testing.T) {
+	const (
+		fileName    = "snapshot-file"
+		removedFile = "/etc/alpine-release"
+	)
+	instanceName := randomName(t)
+
+	dir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "instance-snapshot-", "")
+	defer cleanup(t)
+
+	overlayDir := filepath.Join(dir, "overlay")
+	overlayImage := filepath.Join(dir, "overlay.img")
+	snapshotImage := filepath.Join(dir, "snapshot.img")
+
+	if err := os.Mkdir(overlayDir, 0o755); err != nil {
+		t.Fatalf("failed to create %s: %s", overlayDir, err)
+	}
+
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("instance start"),
+		e2e.WithArgs("--overlay", overlayDir, c.env.ImagePath, instanceName),
+		e2e.PostRun(func(t *testing.T) {
+			if t.Failed() {
+				return
+			}
+			defer c.stopInstance(t, instanceName)
+
+			if _, _, success := c.execInstance(t, instanceName, "touch", "/"+fileName); !success {
+				return
+			}
+			if _, _, success := c.execInstance(t, instanceName, "rm", removedFile); !success {
+				return
+			}
+
+			c.env.RunApptainer(
+				t,
+				e2e.WithProfile(c.profile),
+				e2e.WithCommand("instance snapshot"),
+				e2e.WithArgs(instanceName, snapshotImage),
+				e2e.ExpectExit(0),
+			)
+		}),
+		e2e.ExpectExit(0),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--overlay", snapshotImage+":ro", c.env.ImagePath, "test", "-f", "/"+fileName),
+		e2e.ExpectExit(0),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--overlay", snapshotImage+":ro", c.env.ImagePath, "test", "-e", removedFile),
+		e2e.ExpectExit(1),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("overlay create"),
+		e2e.WithArgs("--size", "64", overlayImage),
+		e2e.ExpectExit(0),
+	)
+
+	instanceName = randomName(t)
+
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("instance start"),
+		e2e.WithArgs("--overlay", overlayImage, c.env.ImagePath, instanceName),
+		e2e.PostRun(func(t *testing.T) {
+			if t.Failed() {
+				return
+			}
+			defer c.stopInstance(t, instanceName)
+
+			c.env.RunApptainer(
+				t,
+				e2e.WithProfile(c.profile),
+				e2e.WithCommand("instance snapshot"),
+				e2e.WithArgs(instanceName, filepath.Join(dir, "image-snapshot.img")),
+				e2e.ExpectExit(
+					255,
+					e2e.ExpectError(e2e.ContainMatch, "is mounted read-write by instance"),
+				),
+			)
+		}),
+		e2e.ExpectExit(0),
+	)
+}
+
+// Share the PID and IPC namespaces of a running instance with
+// --pid=container:NAME and --ipc=container:NAME.
+func (c *ctx) testShareInstanceNamespaces(t *testing.T) {
+	instanceName := randomName(t)
+
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("instance start"),
+		e2e.WithArgs("--ipc", c.env.ImagePath, instanceName),
+		e2e.PostRun(func(t *testing.T) {
+			if t.Failed() {
+				return
+			}
+			defer c.stopInstance(t, instanceName)
+
+			for _, ns := range []string{"pid", "ipc"} {
+				stdout, _, success := c.execInstance(t, instanceName, "readlink", "/proc/self/ns/"+ns)
+				if !success {
+					return
+				}
+				c.env.RunApptainer(
+					t,
+					e2e.AsSubtest(ns),
+					e2e.WithProfile(c.profile),
+					e2e.WithCommand("exec"),
+					e2e.WithArgs("--"+ns+"=container:"+instanceName, c.env.ImagePath, "readlink", "/proc/self/ns/"+ns),
+					e2e.ExpectExit(
+						0,
+						e2e.ExpectOutput(e2e.ExactMatch, strings.TrimSpace(stdout)),
+					),
+				)
+			}
+
+			// --pid without value still requests a new PID namespace
+			pidNs, _, success := c.execInstance(t, instanceName, "readlink", "/proc/self/ns/pid")
+			if !success {
+				return
+			}
+			var newPidNs, stderr string
+			c.env.RunApptainer(
+				t,
+				e2e.AsSubtest("new pid"),
+				e2e.WithProfile(c.profile),
+				e2e.WithCommand("exec"),
+				e2e.WithArgs("--pid", c.env.ImagePath, "readlink", "/proc/self/ns/pid"),
+				e2e.ExpectExit(0, e2e.GetStreams(&newPidNs, &stderr)),
+			)
+			if strings.TrimSpace(newPidNs) == strings.TrimSpace(pidNs) {
+				t.Errorf("--pid joined the instance PID namespace %s", strings.TrimSpace(pidNs))
+			}
+		}),
+		e2e.ExpectExit(0),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("missing instance"),
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--pid=container:"+instanceName, c.env.ImagePath, "true"),
+		e2e.ExpectExit(
+			255,
+			e2e.ExpectError(e2e.ContainMatch, "While sharing PID namespace"),
+		),
+	)
+}
+
 // Test by running directly from URI
 func (c *ctx) testInstanceFromURI(t *testing.T) {
 	e2e.EnsureORASImage(t, c.env)
@@ -594,11 +938,15 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 				{"InstanceFromURI", c.testInstanceFromURI},
 				{"CreateManyInstances", c.testCreateManyInstances},
 				{"InstanceRun", c.testInstanceRun},
+				{"StopTimeout", c.testStopTimeout},
+				{"MountFrom", c.testMountFrom},
 				{"StopAll", c.testStopAll},
 				{"GhostInstance", c.testGhostInstance},
 				{"CheckpointInstance", c.testCheckpointInstance},
 				{"InstanceWithConfigDir", c.testInstanceWithConfigDir},
 				{"ShareNSMode", c.testShareNSMode},
+				{"InstanceSnapshot", c.testInstanceSnapshot},
+				{"ShareInstanceNamespaces", c.testShareInstanceNamespaces},
 				{"issue 2189", c.issue2189},
 			}
 
@@ -616,7 +964,8 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 				})
 			}
 		},
-		"issue 5033": c.issue5033,                // https://github.com/apptainer/singularity/issues/4836
-		"auth":       np(c.testInstanceAuthFile), // custom --authfile with instance start command
+		"issue 5033":       c.issue5033,                // https://github.com/apptainer/singularity/issues/4836
+		"auth":             np(c.testInstanceAuthFile), // custom --authfile with instance start command
+		"bind propagation": np(c.testBindPropagation),  // bind mount propagation options
 	}
 }
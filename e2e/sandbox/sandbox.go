@@ -0,0 +1,134 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apptainer/apptainer/e2e/internal/e2e"
+	"github.com/apptainer/apptainer/e2e/internal/testhelper"
+)
+
+type ctx struct {
+	env e2e.TestEnv
+}
+
+func (c ctx) testSealVerify(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	tmpDir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "sandbox-", "")
+	defer cleanup(t)
+
+	sandbox := filepath.Join(tmpDir, "sandbox")
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("build"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("build"),
+		e2e.WithArgs("--sandbox", sandbox, c.env.ImagePath),
+		e2e.ExpectExit(0),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("verify unsealed"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("sandbox verify"),
+		e2e.WithArgs(sandbox),
+		e2e.ExpectExit(
+			255,
+			e2e.ExpectError(e2e.ContainMatch, "the sandbox is not sealed"),
+		),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("seal"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("sandbox seal"),
+		e2e.WithArgs("--exclude", "/ignored", sandbox),
+		e2e.ExpectExit(0),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("verify sealed"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("sandbox verify"),
+		e2e.WithArgs(sandbox),
+		e2e.ExpectExit(0),
+	)
+
+	// changes in volatile and excluded paths are ignored
+	for _, f := range []string{"/tmp/volatile", "/ignored"} {
+		if err := os.WriteFile(filepath.Join(sandbox, f), []byte("data\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("verify excluded changes"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("sandbox verify"),
+		e2e.WithArgs(sandbox),
+		e2e.ExpectExit(0),
+	)
+
+	if err := os.WriteFile(filepath.Join(sandbox, "/added"), []byte("data\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("verify tampered"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("sandbox verify"),
+		e2e.WithArgs(sandbox),
+		e2e.ExpectExit(
+			255,
+			e2e.ExpectOutput(e2e.ExactMatch, "added: /added"),
+		),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("verify tampered excluded"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("sandbox verify"),
+		e2e.WithArgs("--exclude", "/added", sandbox),
+		e2e.ExpectExit(0),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("seal SIF"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("sandbox seal"),
+		e2e.WithArgs(c.env.ImagePath),
+		e2e.ExpectExit(
+			255,
+			e2e.ExpectError(e2e.ContainMatch, "is not a sandbox image"),
+		),
+	)
+}
+
+// E2ETests is the main func to trigger the test suite
+func E2ETests(env e2e.TestEnv) testhelper.Tests {
+	c := ctx{
+		env: env,
+	}
+
+	return testhelper.Tests{
+		"seal verify": c.testSealVerify,
+	}
+}
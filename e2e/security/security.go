@@ -12,6 +12,8 @@ package security
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/apptainer/apptainer/e2e/internal/e2e"
@@ -79,6 +81,27 @@ func (c ctx) testSecurityUnpriv(t *testing.T) {
 			preFn:      require.Seccomp,
 			expectExit: 0,
 		},
+		// landlock rules
+		{
+			name:       "Landlock_write_allowed",
+			argv:       []string{"sh", "-c", "echo > /tmp/landlock-e2e-$$ && rm /tmp/landlock-e2e-$$"},
+			opts:       []string{"--security", "landlock:ro:/,landlock:rw:/tmp"},
+			preFn:      require.Landlock,
+			expectExit: 0,
+		},
+		{
+			name:       "Landlock_write_denied",
+			argv:       []string{"touch", "/var/tmp/landlock-e2e"},
+			opts:       []string{"--security", "landlock:ro:/,landlock:rw:/tmp"},
+			preFn:      require.Landlock,
+			expectExit: 1,
+		},
+		{
+			name:       "Landlock_bad_rule",
+			argv:       []string{"true"},
+			opts:       []string{"--security", "landlock:rx:/"},
+			expectExit: 255,
+		},
 		// capabilities
 		{
 			name:       "capabilities_keep_true",
@@ -221,6 +244,37 @@ func (c ctx) testSecurityPriv(t *testing.T) {
 	}
 }
 
+// testTraceSyscalls tests the system calls trace of the container process.
+func (c ctx) testTraceSyscalls(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	for _, profile := range []e2e.Profile{e2e.UserProfile, e2e.RootProfile, e2e.UserNamespaceProfile} {
+		trace := filepath.Join(t.TempDir(), "trace")
+
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(profile.String()),
+			e2e.WithProfile(profile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs("--trace-syscalls", trace, c.env.ImagePath, "mkdir", "/tmp/trace-syscalls-e2e"),
+			e2e.PreRun(require.SeccompNotify),
+			e2e.PostRun(func(t *testing.T) {
+				if t.Skipped() {
+					return
+				}
+				data, err := os.ReadFile(trace)
+				if err != nil {
+					t.Fatalf("could not read trace file: %s", err)
+				}
+				if !strings.Contains(string(data), " mkdir") {
+					t.Errorf("mkdir system call not found in trace file")
+				}
+			}),
+			e2e.ExpectExit(0),
+		)
+	}
+}
+
 // testSecurityConfOwnership tests checks on config files ownerships
 func (c ctx) testSecurityConfOwnership(t *testing.T) {
 	e2e.EnsureImage(t, c.env)
@@ -266,6 +320,7 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	return testhelper.Tests{
 		"apptainerSecurityUnpriv":   c.testSecurityUnpriv,
 		"apptainerSecurityPriv":     c.testSecurityPriv,
+		"apptainerTraceSyscalls":    c.testTraceSyscalls,
 		"testSecurityConfOwnership": np(c.testSecurityConfOwnership),
 	}
 }
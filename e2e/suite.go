@@ -51,6 +51,7 @@ import (
 	"github.com/apptainer/apptainer/e2e/remote"
 	"github.com/apptainer/apptainer/e2e/run"
 	"github.com/apptainer/apptainer/e2e/runhelp"
+	"github.com/apptainer/apptainer/e2e/sandbox"
 	"github.com/apptainer/apptainer/e2e/security"
 	"github.com/apptainer/apptainer/e2e/sign"
 	"github.com/apptainer/apptainer/e2e/verify"
@@ -234,6 +235,7 @@ func Run(t *testing.T) {
 	suite.AddGroup("REMOTE", remote.E2ETests)
 	suite.AddGroup("RUN", run.E2ETests)
 	suite.AddGroup("RUNHELP", runhelp.E2ETests)
+	suite.AddGroup("SANDBOX", sandbox.E2ETests)
 	suite.AddGroup("SECURITY", security.E2ETests)
 	suite.AddGroup("SIGN", sign.E2ETests)
 	suite.AddGroup("VERIFY", verify.E2ETests)
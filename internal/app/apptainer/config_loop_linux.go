@@ -0,0 +1,56 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/apptainer/apptainer/pkg/util/loop"
+)
+
+// PrintLoopDevices prints the loop devices attached to a backing file.
+func PrintLoopDevices(w io.Writer) error {
+	loops, err := loop.ListAttached()
+	if err != nil {
+		return fmt.Errorf("could not retrieve loop devices: %v", err)
+	}
+
+	tabWriter := tabwriter.NewWriter(w, 0, 8, 4, ' ', 0)
+	defer tabWriter.Flush()
+
+	if _, err := fmt.Fprintln(tabWriter, "DEVICE\tOFFSET\tAUTOCLEAR\tSTALE\tBACKING FILE"); err != nil {
+		return fmt.Errorf("could not write list header: %v", err)
+	}
+	for _, l := range loops {
+		_, err := fmt.Fprintf(tabWriter, "/dev/loop%d\t%d\t%t\t%t\t%s\n", l.Device, l.Offset, l.AutoClear, !l.BackingFileExists, l.BackingFile)
+		if err != nil {
+			return fmt.Errorf("could not write loop device info: %v", err)
+		}
+	}
+	return nil
+}
+
+// CleanLoopDevices detaches the stale loop devices left over by crashed
+// containers, whose backing file was deleted and which are not mounted.
+func CleanLoopDevices() error {
+	detached, err := loop.DetachStale()
+	for _, l := range detached {
+		sylog.Infof("Detached /dev/loop%d from deleted file %s", l.Device, l.BackingFile)
+	}
+	if err != nil {
+		return fmt.Errorf("while detaching stale loop devices: %v", err)
+	}
+	if len(detached) == 0 {
+		sylog.Infof("No stale loop device found")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/apptainer/apptainer/internal/pkg/instance"
+	"github.com/apptainer/apptainer/internal/pkg/util/bin"
+	"github.com/apptainer/apptainer/pkg/sylog"
+)
+
+// exportFsPollInterval is the interval at which the instance process is
+// checked while its root filesystem is exported.
+const exportFsPollInterval = 2 * time.Second
+
+// InstanceExportFs exports read-only on the host directory mountPoint the
+// root filesystem of the running instance name, as seen from inside the
+// container. File operations are served by a fuse-overlayfs process using
+// the instance root as its only lower layer, until the mount point is
+// unmounted, the instance exits or the command is interrupted.
+func InstanceExportFs(name, instanceUser, mountPoint string) error {
+	ii, err := instanceListOrError(instanceUser, name)
+	if err != nil {
+		return err
+	}
+	if len(ii) != 1 {
+		return fmt.Errorf("query returned more than one instance (%d)", len(ii))
+	}
+	i := ii[0]
+
+	if fi, err := os.Stat(mountPoint); err != nil {
+		return err
+	} else if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", mountPoint)
+	}
+
+	rootPath, err := instanceRootPath(i, os.Getuid())
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(rootPath); err != nil {
+		return fmt.Errorf("could not access root filesystem of instance %s: %s", i.Name, err)
+	}
+
+	fuseOverlayfs, err := bin.FindBin("fuse-overlayfs")
+	if err != nil {
+		return err
+	}
+
+	// without upper directory the overlay is read-only
+	errBuf := new(bytes.Buffer)
+	cmd := exec.Command(
+		fuseOverlayfs, "-f",
+		"-o", fmt.Sprintf("lowerdir=%s,fsname=apptainer-%s", rootPath, i.Name),
+		mountPoint,
+	)
+	cmd.Stderr = errBuf
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("while starting fuse-overlayfs: %s", err)
+	}
+
+	sylog.Infof("Exporting root filesystem of %s instance (PID=%d) on %s, interrupt or unmount to stop", i.Name, i.Pid, mountPoint)
+
+	// fuse-overlayfs unmounts the mount point and exits on SIGTERM
+	stop := func() {
+		if err := cmd.Process.Signal(syscall.SIGTERM); err != nil && err != os.ErrProcessDone {
+			sylog.Errorf("Could not stop fuse-overlayfs: %s", err)
+		}
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(exportFsPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-sig:
+				stop()
+				return
+			case <-ticker.C:
+				if err := syscall.Kill(i.Pid, 0); err == syscall.ESRCH {
+					sylog.Infof("Instance %s exited", i.Name)
+					stop()
+					return
+				}
+			}
+		}
+	}()
+
+	err = cmd.Wait()
+	close(done)
+	if err != nil {
+		return fmt.Errorf("fuse-overlayfs failed to export %s: %s\nCommand error: %s", mountPoint, err, errBuf)
+	}
+	return nil
+}
+
+// instanceRootPath returns the path to the root filesystem of the instance
+// process, restricted to the instance owner or root as identified by uid.
+// Access is also checked by the kernel with the process credentials, the
+// path can't be opened if the process isn't accessible to the caller.
+func instanceRootPath(i *instance.File, uid int) (string, error) {
+	if i.Pid <= 1 {
+		return "", fmt.Errorf("invalid PID %d for instance %s", i.Pid, i.Name)
+	}
+
+	procPath := fmt.Sprintf("/proc/%d", i.Pid)
+	var st syscall.Stat_t
+	if err := syscall.Stat(procPath, &st); err != nil {
+		return "", fmt.Errorf("instance %s is not running: %s", i.Name, err)
+	}
+	if uid != 0 && int(st.Uid) != uid {
+		return "", fmt.Errorf("instance %s doesn't belong to the current user", i.Name)
+	}
+
+	return procPath + "/root", nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/instance"
+)
+
+func TestInstanceRootPath(t *testing.T) {
+	// a PID which isn't running anymore
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("could not run true: %s", err)
+	}
+	exitedPid := cmd.Process.Pid
+
+	uid := os.Getuid()
+	otherUID := uid + 1
+	if uid == 0 {
+		otherUID = 1000
+	}
+
+	tests := []struct {
+		name    string
+		pid     int
+		uid     int
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "InvalidPid",
+			pid:     0,
+			uid:     uid,
+			wantErr: "invalid PID",
+		},
+		{
+			name:    "InitPid",
+			pid:     1,
+			uid:     uid,
+			wantErr: "invalid PID",
+		},
+		{
+			name:    "NotRunning",
+			pid:     exitedPid,
+			uid:     uid,
+			wantErr: "is not running",
+		},
+		{
+			name: "Owner",
+			pid:  os.Getpid(),
+			uid:  uid,
+			want: fmt.Sprintf("/proc/%d/root", os.Getpid()),
+		},
+		{
+			name: "Root",
+			pid:  os.Getpid(),
+			uid:  0,
+			want: fmt.Sprintf("/proc/%d/root", os.Getpid()),
+		},
+		{
+			name:    "OtherUser",
+			pid:     os.Getpid(),
+			uid:     otherUID,
+			wantErr: "doesn't belong to the current user",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &instance.File{Name: "test", Pid: tt.pid}
+
+			path, err := instanceRootPath(i, tt.uid)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if path != tt.want {
+				t.Errorf("got path %s, want %s", path, tt.want)
+			}
+		})
+	}
+}
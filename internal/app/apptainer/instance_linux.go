@@ -31,6 +31,7 @@ import (
 	"github.com/buger/goterm"
 	units "github.com/docker/go-units"
 	libcgroups "github.com/opencontainers/runc/libcontainer/cgroups"
+	"golang.org/x/sys/unix"
 )
 
 type instanceInfo struct {
@@ -302,57 +303,81 @@ func InstanceStats(ctx context.Context, name, instanceUser string, formatJSON bo
 // StopInstance fetches instance list, applying name and
 // user filters, and stops them by sending a signal sig. If an instance
 // is still running after a grace period defined by timeout is expired,
-// it will be forcibly killed.
+// it will be forcibly killed. Each instance is given its own grace period,
+// the outcome is reported for every instance.
 func StopInstance(name, user string, sig syscall.Signal, timeout time.Duration) error {
 	ii, err := instanceListOrError(user, name)
 	if err != nil {
 		return err
 	}
-	stoppedPID := make(chan int, 1)
-	stopped := make([]int, 0)
 
+	errs := make(chan error, len(ii))
 	for _, i := range ii {
-		go killInstance(i, sig, stoppedPID)
+		go func(i *instance.File) {
+			errs <- stopInstance(i, sig, timeout)
+		}(i)
 	}
 
-	for {
-		select {
-		case pid := <-stoppedPID:
-			stopped = append(stopped, pid)
-			if len(stopped) == len(ii) {
-				return nil
-			}
-		case <-time.After(timeout):
-		killNext:
-			for _, i := range ii {
-				for _, pid := range stopped {
-					if i.Pid == pid {
-						continue killNext
-					}
-				}
-
-				sylog.Infof("Killing %s instance of %s (PID=%d) (Timeout)\n", i.Name, i.Image, i.Pid)
-				syscall.Kill(i.Pid, syscall.SIGKILL)
-			}
-			return nil
+	failed := 0
+	for range ii {
+		if err := <-errs; err != nil {
+			sylog.Errorf("%s", err)
+			failed++
 		}
 	}
+	if failed > 0 {
+		return fmt.Errorf("failed to stop %d instance(s)", failed)
+	}
+	return nil
 }
 
-func killInstance(i *instance.File, sig syscall.Signal, stoppedPID chan<- int) {
+// stopInstance sends the signal sig to the instance i and waits up to
+// timeout for the instance to exit before killing it with SIGKILL.
+func stopInstance(i *instance.File, sig syscall.Signal, timeout time.Duration) error {
 	sylog.Infof("Stopping %s instance of %s (PID=%d)\n", i.Name, i.Image, i.Pid)
-	syscall.Kill(i.Pid, sig)
+	if err := syscall.Kill(i.Pid, sig); err != nil && err != syscall.ESRCH {
+		return fmt.Errorf("could not send %s to %s instance (PID=%d): %s", unix.SignalName(sig), i.Name, i.Pid, err)
+	}
 
+	start := time.Now()
+	if waitInstance(i, timeout) {
+		sylog.Infof("%s instance stopped after %s\n", i.Name, time.Since(start).Round(time.Millisecond))
+		return nil
+	}
+	if sig == syscall.SIGKILL {
+		return fmt.Errorf("%s instance (PID=%d) still running after %s", i.Name, i.Pid, timeout)
+	}
+
+	sylog.Infof("Killing %s instance of %s (PID=%d) (Timeout after %s)\n", i.Name, i.Image, i.Pid, timeout)
+	if err := syscall.Kill(i.Pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return fmt.Errorf("could not kill %s instance (PID=%d): %s", i.Name, i.Pid, err)
+	}
+	if !waitInstance(i, killTimeout) {
+		return fmt.Errorf("%s instance (PID=%d) still running after SIGKILL", i.Name, i.Pid)
+	}
+	sylog.Infof("%s instance killed\n", i.Name)
+	return nil
+}
+
+// killTimeout is the time given to an instance to exit after SIGKILL.
+const killTimeout = 10 * time.Second
+
+// waitInstance waits up to timeout for the instance i to exit and
+// returns whether it exited.
+func waitInstance(i *instance.File, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
 	for {
 		if err := syscall.Kill(i.PPid, 0); err == syscall.ESRCH {
-			stoppedPID <- i.Pid
-			break
+			return true
 		}
 		if childs, err := proc.CountChilds(i.Pid); childs == 0 {
 			if err == nil {
 				syscall.Kill(i.Pid, syscall.SIGKILL)
 			}
 		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
 		time.Sleep(10 * time.Millisecond)
 	}
 }
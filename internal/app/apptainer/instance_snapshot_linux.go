@@ -0,0 +1,343 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/apptainer/apptainer/internal/pkg/cgroups"
+	"github.com/apptainer/apptainer/internal/pkg/instance"
+	"github.com/apptainer/apptainer/internal/pkg/util/bin"
+	fsutil "github.com/apptainer/apptainer/internal/pkg/util/fs"
+	"github.com/apptainer/apptainer/pkg/image"
+	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
+	"github.com/apptainer/apptainer/pkg/runtime/engine/config"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	"golang.org/x/sys/unix"
+)
+
+// InstanceSnapshot copies the writable overlay directory of the running
+// instance name into a new EXT3 overlay image created at dst. If the
+// instance runs in a cgroup, it is frozen during the copy. Writable EXT3
+// overlay images are mounted read-write by the instance and can't be
+// copied consistently, they are refused.
+func InstanceSnapshot(name, instanceUser, dst string) error {
+	ii, err := instanceListOrError(instanceUser, name)
+	if err != nil {
+		return err
+	}
+	if len(ii) != 1 {
+		return fmt.Errorf("query returned more than one instance (%d)", len(ii))
+	}
+	i := ii[0]
+
+	if exists, err := fsutil.PathExists(dst); err != nil {
+		return err
+	} else if exists {
+		return fmt.Errorf("%s already exists", dst)
+	}
+
+	overlay, err := instanceWritableOverlay(i)
+	if err != nil {
+		return err
+	}
+
+	if overlay.Type == image.EXT3 {
+		return fmt.Errorf("overlay image %s is mounted read-write by instance %s and can't be copied consistently, stop the instance and copy the image instead", overlay.Path, i.Name)
+	}
+
+	sylog.Infof("Snapshotting overlay %s of %s instance (PID=%d)", overlay.Path, i.Name, i.Pid)
+
+	if i.Cgroup {
+		manager, err := cgroups.GetManagerForPid(i.Pid)
+		if err != nil {
+			return fmt.Errorf("while getting cgroup manager for pid: %v", err)
+		}
+		if err := manager.Freeze(); err != nil {
+			return fmt.Errorf("while freezing instance: %v", err)
+		}
+		defer func() {
+			if err := manager.Thaw(); err != nil {
+				sylog.Errorf("Could not resume %s instance: %v", i.Name, err)
+			}
+		}()
+	} else {
+		sylog.Warningf("Instance %s is not running in a cgroup and can't be frozen, writes in flight may not be captured consistently", i.Name)
+	}
+
+	// flush pending overlay writes to the underlying image or directory
+	unix.Sync()
+
+	return snapshotOverlayDir(filepath.Join(overlay.Path, "upper"), dst)
+}
+
+// instanceWritableOverlay returns the writable overlay image used by
+// the instance, as recorded in its configuration.
+func instanceWritableOverlay(i *instance.File) (*image.Image, error) {
+	engineConfig := apptainerConfig.NewConfig()
+	commonConfig := &config.Common{
+		EngineConfig: engineConfig,
+	}
+	if err := json.Unmarshal(i.Config, commonConfig); err != nil {
+		return nil, fmt.Errorf("while reading instance configuration: %s", err)
+	}
+
+	if engineConfig.GetWritableTmpfs() {
+		return nil, fmt.Errorf("instance %s uses --writable-tmpfs which can't be snapshotted", i.Name)
+	}
+
+	for _, img := range engineConfig.GetImageList() {
+		if !img.Writable || img.Usage&image.OverlayUsage == 0 {
+			continue
+		}
+		if img.Usage&image.RootFsUsage != 0 {
+			return nil, fmt.Errorf("instance %s uses an overlay partition embedded in %s which can't be snapshotted", i.Name, img.Path)
+		}
+		if img.Type != image.EXT3 && img.Type != image.SANDBOX {
+			return nil, fmt.Errorf("overlay %s of instance %s has an unsupported format", img.Path, i.Name)
+		}
+		return &img, nil
+	}
+
+	return nil, fmt.Errorf("instance %s doesn't use a writable overlay", i.Name)
+}
+
+// snapshotOverlayDir creates the EXT3 overlay image dst populated with
+// a copy of the overlay upper directory upperDir.
+func snapshotOverlayDir(upperDir, dst string) error {
+	mkfs, err := bin.FindBin(mkfsBinary)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	err = filepath.WalkDir(upperDir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("while computing size of %s: %s", upperDir, err)
+	}
+	// leave room for filesystem metadata and subsequent writes
+	sizeMiB := size/(1024*1024)*2 + 64
+
+	tmpDir, err := os.MkdirTemp("", "overlay-snapshot-")
+	if err != nil {
+		return fmt.Errorf("while creating temporary overlay directory: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := copyOverlayDir(upperDir, filepath.Join(tmpDir, "upper")); err != nil {
+		return err
+	}
+
+	if err := os.Mkdir(filepath.Join(tmpDir, "work"), 0o755); err != nil {
+		return fmt.Errorf("while creating overlay work directory: %s", err)
+	}
+
+	tmpFile := dst + ".ext3"
+	defer os.Remove(tmpFile)
+
+	f, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("while creating %s: %s", tmpFile, err)
+	}
+	err = f.Truncate(sizeMiB * 1024 * 1024)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("while resizing %s: %s", tmpFile, err)
+	}
+
+	errBuf := new(bytes.Buffer)
+	cmd := exec.Command(mkfs, "-d", tmpDir, tmpFile)
+	cmd.Stderr = errBuf
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("while creating ext3 partition in %s: %s\nCommand error: %s", tmpFile, err, errBuf)
+	}
+
+	if err := os.Rename(tmpFile, dst); err != nil {
+		return fmt.Errorf("while renaming %s to %s: %s", tmpFile, dst, err)
+	}
+	return nil
+}
+
+// overlayXattrPrefixes are the prefixes of the extended attributes used
+// by the kernel overlay and fuse-overlayfs to mark opaque directories in
+// the upper directory.
+var overlayXattrPrefixes = []string{"trusted.overlay.", "user.overlay.", "user.fuseoverlayfs."}
+
+// copyOverlayDir copies the overlay upper directory src to dst, preserving
+// permissions, times and, for root, ownership. Whiteouts are recreated as
+// whiteout devices and the overlay extended attributes are preserved. The
+// ".wh." prefixed whiteout files of fuse-overlayfs are regular files which
+// are copied as is.
+func copyOverlayDir(src, dst string) error {
+	var dirs []string
+
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		} else if fi.Mode()&fs.ModeSocket != 0 {
+			sylog.Debugf("Skipping socket %s", path)
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if err := copyOverlayEntry(path, target, fi); err != nil {
+			return fmt.Errorf("while copying %s: %s", path, err)
+		}
+		// directory times are restored once their content is copied
+		if fi.IsDir() {
+			dirs = append(dirs, path)
+		} else if err := copyTimes(fi, target); err != nil {
+			return fmt.Errorf("while setting times of %s: %s", target, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("while copying %s: %s", src, err)
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		fi, err := os.Lstat(dirs[i])
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, strings.TrimPrefix(dirs[i], src))
+		if err := copyTimes(fi, target); err != nil {
+			return fmt.Errorf("while setting times of %s: %s", target, err)
+		}
+	}
+	return nil
+}
+
+// copyOverlayEntry creates dst as a copy of the upper directory entry src
+// described by fi, without its content for directories.
+func copyOverlayEntry(src, dst string, fi fs.FileInfo) error {
+	st := fi.Sys().(*syscall.Stat_t)
+	mode := fi.Mode()
+
+	switch {
+	case mode.IsDir():
+		if err := os.Mkdir(dst, 0o700); err != nil {
+			return err
+		}
+	case mode.IsRegular():
+		if err := fsutil.CopyFile(src, dst, 0o600); err != nil {
+			return err
+		}
+	case mode&fs.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(target, dst); err != nil {
+			return err
+		}
+	case mode&fs.ModeCharDevice != 0 && st.Rdev == 0:
+		// a whiteout hiding the file from the lower layers, the 0/0
+		// character device doesn't require CAP_MKNOD
+		if err := unix.Mknod(dst, unix.S_IFCHR, 0); err != nil {
+			return fmt.Errorf("while creating whiteout: %s", err)
+		}
+	case mode&fs.ModeNamedPipe != 0:
+		if err := unix.Mkfifo(dst, 0o600); err != nil {
+			return err
+		}
+	default:
+		if err := unix.Mknod(dst, st.Mode, int(st.Rdev)); err != nil {
+			return err
+		}
+	}
+
+	if os.Geteuid() == 0 {
+		if err := os.Lchown(dst, int(st.Uid), int(st.Gid)); err != nil {
+			return err
+		}
+	}
+	if mode&fs.ModeSymlink != 0 {
+		return nil
+	}
+	// set after the ownership change which clears the setuid/setgid bits
+	if err := os.Chmod(dst, mode&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky)); err != nil {
+		return err
+	}
+	return copyOverlayXattrs(src, dst)
+}
+
+// copyOverlayXattrs copies the overlay extended attributes of src to dst.
+func copyOverlayXattrs(src, dst string) error {
+	size, err := unix.Llistxattr(src, nil)
+	if err == unix.ENOTSUP || size == 0 {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("while listing extended attributes: %s", err)
+	}
+	buf := make([]byte, size)
+	size, err = unix.Llistxattr(src, buf)
+	if err != nil {
+		return fmt.Errorf("while listing extended attributes: %s", err)
+	}
+
+	for _, name := range strings.Split(strings.TrimRight(string(buf[:size]), "\x00"), "\x00") {
+		overlay := false
+		for _, prefix := range overlayXattrPrefixes {
+			overlay = overlay || strings.HasPrefix(name, prefix)
+		}
+		if !overlay {
+			continue
+		}
+		size, err := unix.Lgetxattr(src, name, nil)
+		if err != nil {
+			return fmt.Errorf("while reading extended attribute %s: %s", name, err)
+		}
+		value := make([]byte, size)
+		size, err = unix.Lgetxattr(src, name, value)
+		if err != nil {
+			return fmt.Errorf("while reading extended attribute %s: %s", name, err)
+		}
+		if err := unix.Lsetxattr(dst, name, value[:size], 0); err != nil {
+			return fmt.Errorf("while setting extended attribute %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// copyTimes sets the access and modification times of path from fi.
+func copyTimes(fi fs.FileInfo, path string) error {
+	st := fi.Sys().(*syscall.Stat_t)
+	times := []unix.Timespec{
+		unix.NsecToTimespec(syscall.TimespecToNsec(st.Atim)),
+		unix.NsecToTimespec(syscall.TimespecToNsec(st.Mtim)),
+	}
+	return unix.UtimesNanoAt(unix.AT_FDCWD, path, times, unix.AT_SYMLINK_NOFOLLOW)
+}
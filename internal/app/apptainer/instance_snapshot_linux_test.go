@@ -0,0 +1,81 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCopyOverlayDir(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "upper")
+	dst := filepath.Join(t.TempDir(), "upper")
+
+	if err := os.MkdirAll(filepath.Join(src, "opaque"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "file"), []byte("content"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc/passwd", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+	// fuse-overlayfs whiteout file
+	if err := os.WriteFile(filepath.Join(src, ".wh.removed"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.Mknod(filepath.Join(src, "whiteout"), unix.S_IFCHR, 0); err != nil {
+		t.Skipf("could not create whiteout: %s", err)
+	}
+	opaque := "user.overlay.opaque"
+	if err := unix.Setxattr(filepath.Join(src, "opaque"), opaque, []byte("y"), 0); err != nil {
+		t.Skipf("could not set %s: %s", opaque, err)
+	}
+	// directory times must be restored after the copy of their content
+	mtime := unix.NsecToTimespec(1e18)
+	times := []unix.Timespec{mtime, mtime}
+	if err := unix.UtimesNanoAt(unix.AT_FDCWD, src, times, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyOverlayDir(src, dst); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if b, err := os.ReadFile(filepath.Join(dst, "file")); err != nil || string(b) != "content" {
+		t.Errorf("unexpected file content %q: %v", b, err)
+	}
+	if fi, err := os.Stat(filepath.Join(dst, "file")); err != nil || fi.Mode().Perm() != 0o640 {
+		t.Errorf("unexpected file mode: %v %v", fi.Mode(), err)
+	}
+	if target, err := os.Readlink(filepath.Join(dst, "link")); err != nil || target != "/etc/passwd" {
+		t.Errorf("unexpected symlink target %q: %v", target, err)
+	}
+	if fi, err := os.Lstat(filepath.Join(dst, ".wh.removed")); err != nil || !fi.Mode().IsRegular() {
+		t.Errorf("fuse-overlayfs whiteout file not copied: %v", err)
+	}
+	fi, err := os.Lstat(filepath.Join(dst, "whiteout"))
+	if err != nil {
+		t.Fatalf("whiteout not copied: %s", err)
+	}
+	if st := fi.Sys().(*syscall.Stat_t); fi.Mode()&os.ModeCharDevice == 0 || st.Rdev != 0 {
+		t.Errorf("whiteout copied as %v", fi.Mode())
+	}
+	value := make([]byte, 1)
+	if _, err := unix.Getxattr(filepath.Join(dst, "opaque"), opaque, value); err != nil || string(value) != "y" {
+		t.Errorf("opaque attribute not copied: %v", err)
+	}
+	if fi, err := os.Stat(dst); err != nil || fi.ModTime().UnixNano() != 1e18 {
+		t.Errorf("directory modification time not preserved: %v", err)
+	}
+}
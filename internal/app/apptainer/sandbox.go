@@ -0,0 +1,74 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apptainer/apptainer/internal/pkg/image/sandbox"
+	"github.com/apptainer/apptainer/pkg/image"
+	"github.com/apptainer/apptainer/pkg/sylog"
+)
+
+// checkSandbox returns an error if path is not a sandbox image.
+func checkSandbox(path string) error {
+	img, err := image.Init(path, false)
+	if err != nil {
+		return fmt.Errorf("could not open image %s: %s", path, err)
+	}
+	defer img.File.Close()
+
+	if img.Type != image.SANDBOX {
+		return fmt.Errorf("%s is not a sandbox image", path)
+	}
+	if _, err := os.Stat(filepath.Join(path, "/.singularity.d")); err != nil {
+		return fmt.Errorf("%s is not a sandbox image: %s", path, err)
+	}
+	return nil
+}
+
+// SandboxSeal records the integrity manifest of the sandbox image at path,
+// paths matching excludes are excluded in addition to the default volatile
+// paths.
+func SandboxSeal(path string, excludes []string) error {
+	if err := checkSandbox(path); err != nil {
+		return err
+	}
+
+	m, err := sandbox.Seal(path, excludes)
+	if err != nil {
+		return err
+	}
+	sylog.Infof("Sandbox %s sealed, %d entries recorded in %s", path, len(m.Entries), sandbox.ManifestPath)
+	return nil
+}
+
+// SandboxVerify checks the sandbox image at path against its integrity
+// manifest, paths matching excludes are ignored in addition to the paths
+// excluded when the sandbox was sealed.
+func SandboxVerify(path string, excludes []string) error {
+	if err := checkSandbox(path); err != nil {
+		return err
+	}
+
+	changes, err := sandbox.Verify(path, excludes)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("sandbox %s doesn't match its manifest, %d change(s) found", path, len(changes))
+	}
+	sylog.Infof("Sandbox %s matches its manifest", path)
+	return nil
+}
@@ -47,6 +47,8 @@ type Build struct {
 	stages []stage
 	// Conf contains cross stage build configuration.
 	Conf Config
+	// progress reports the build progress, nil if progress isn't reported.
+	progress *progress
 }
 
 // Config defines how build is executed, including things like where final image is written.
@@ -94,7 +96,8 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 	}
 
 	b := &Build{
-		Conf: conf,
+		Conf:     conf,
+		progress: newProgress(conf.Opts.JSONProgress),
 	}
 
 	// look if there is mount options set which could conflict
@@ -134,6 +137,7 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 		}
 		s.name = d.Header["stage"]
 		s.b.Recipe = d
+		s.progress = b.progress
 
 		if conf.Format == "sandbox" && lastStageIndex == i {
 			// rootfs path changed during bundle creation it means that chown
@@ -347,9 +351,14 @@ func haveBindFor(binds []string, destination string) bool {
 }
 
 // Full runs a standard build from start to finish.
-func (b *Build) Full(ctx context.Context) error {
+func (b *Build) Full(ctx context.Context) (err error) {
 	sylog.Infof("Starting build...")
 
+	b.progress.buildStart(b.Conf.Dest)
+	defer func() {
+		b.progress.buildEnd(b.Conf.Dest, err)
+	}()
+
 	// monitor build for termination signal and clean up
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -365,6 +374,8 @@ func (b *Build) Full(ctx context.Context) error {
 
 	// build each stage one after the other
 	for i, stage := range b.stages {
+		b.progress.stageStart(stage.name)
+
 		if err := stage.runHostScript("pre", stage.b.Recipe.BuildData.Pre); err != nil {
 			return err
 		}
@@ -430,14 +441,33 @@ func (b *Build) Full(ctx context.Context) error {
 			}
 		}
 
-		if err := stage.runHostScript("setup", stage.b.Recipe.BuildData.Setup); err != nil {
+		// track sections checksums to skip unchanged sections on update
+		cache := b.newSectionCache(i, update)
+
+		skip, err := cache.unchanged("setup", writeScript(stage.b.Recipe.BuildData.Setup))
+		if err != nil {
 			return err
 		}
+		if !skip {
+			if err := stage.runHostScript("setup", stage.b.Recipe.BuildData.Setup); err != nil {
+				return err
+			}
+		} else if stage.b.Recipe.BuildData.Setup.Script != "" {
+			b.progress.skipSection("setup")
+		}
 
 		// copy files from host
 		if stage.b.RunSection("files") {
-			if err := stage.copyFiles(); err != nil { //nolint:contextcheck
-				return fmt.Errorf("unable to copy files from host to container fs: %v", err)
+			skip, err := cache.unchanged("files", writeFiles(stage.b.Recipe.BuildData.Files))
+			if err != nil {
+				return err
+			}
+			if !skip {
+				if err := b.progress.runSection("files", stage.copyFiles); err != nil { //nolint:contextcheck
+					return fmt.Errorf("unable to copy files from host to container fs: %v", err)
+				}
+			} else {
+				b.progress.skipSection("files")
 			}
 		}
 
@@ -462,10 +492,16 @@ func (b *Build) Full(ctx context.Context) error {
 			}
 		}
 
-		if stage.b.Recipe.BuildData.Post.Script != "" {
+		skip, err = cache.unchanged("post", writeScript(stage.b.Recipe.BuildData.Post))
+		if err != nil {
+			return err
+		}
+		if stage.b.Recipe.BuildData.Post.Script != "" && !skip {
 			if err := stage.runPostScript(sessionResolv, sessionHosts); err != nil {
 				return fmt.Errorf("while running engine: %v", err)
 			}
+		} else if skip {
+			b.progress.skipSection("post")
 		}
 
 		sylog.Debugf("Inserting Metadata")
@@ -476,6 +512,20 @@ func (b *Build) Full(ctx context.Context) error {
 		if err := stage.runTestScript(sessionResolv, sessionHosts); err != nil {
 			return fmt.Errorf("failed to execute %%test script: %v", err)
 		}
+
+		if i == len(b.stages)-1 {
+			if err := stage.strip(); err != nil {
+				return err
+			}
+		}
+
+		if b.Conf.Format == "sandbox" && i == len(b.stages)-1 {
+			if err := cache.save(stage.b.RootfsPath); err != nil {
+				return fmt.Errorf("while saving build cache: %v", err)
+			}
+		}
+
+		b.progress.stageEnd()
 	}
 
 	syscall.Umask(oldumask)
@@ -497,7 +547,7 @@ func makeDef(spec string) (types.Definition, error) {
 	}
 
 	// Check if spec is an image/sandbox
-	if _, err := image.Init(spec, false); err == nil {
+	if _, err := image.Init(spec, false, image.OptDetectOnly()); err == nil {
 		return types.NewDefinitionFromURI("localimage" + "://" + spec)
 	}
 
@@ -525,8 +575,7 @@ func MakeAllDefs(spec string, buildArgsMap map[string]string) ([]types.Definitio
 	}
 
 	// check if spec is an image/sandbox
-	if i, err := image.Init(spec, false); err == nil {
-		_ = i.File.Close()
+	if _, err := image.Init(spec, false, image.OptDetectOnly()); err == nil {
 		d, err := types.NewDefinitionFromURI("localimage://" + spec)
 		return []types.Definition{d}, nil, err
 	}
@@ -0,0 +1,59 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package files
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// HashFromHost writes to w the content of the host files that CopyFromHost
+// would copy for src, along with their relative paths and modes, so that
+// the resulting checksum changes whenever one of the source files changes.
+// Like CopyFromHost, symlinks are dereferenced.
+func HashFromHost(w io.Writer, src string) error {
+	paths, err := expandPath(src)
+	if err != nil {
+		return fmt.Errorf("while expanding source path with bash: %s: %s", src, err)
+	}
+
+	for _, srcGlobbed := range paths {
+		err := filepath.Walk(srcGlobbed, func(path string, _ os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// dereference symlinks as cp -L does
+			fi, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(srcGlobbed, path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s\x00%s\x00%o\x00", srcGlobbed, rel, fi.Mode())
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(w, f)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("while hashing %s: %s", srcGlobbed, err)
+		}
+	}
+	return nil
+}
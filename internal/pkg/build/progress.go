@@ -0,0 +1,228 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/apptainer/apptainer/pkg/sylog"
+)
+
+// ProgressVersion is the version of the progress event schema, it's
+// incremented on incompatible changes only, new fields may be added
+// without changing it.
+const ProgressVersion = 1
+
+// Progress event types.
+const (
+	// EventBuildStart is emitted once before the first stage is built.
+	EventBuildStart = "build-start"
+	// EventStageStart is emitted before building a stage.
+	EventStageStart = "stage-start"
+	// EventSectionStart is emitted before running a definition section.
+	EventSectionStart = "section-start"
+	// EventSectionEnd is emitted once a definition section ran, along
+	// with its exit code.
+	EventSectionEnd = "section-end"
+	// EventSectionSkip is emitted for sections unchanged since the last
+	// build of the sandbox being updated.
+	EventSectionSkip = "section-skip"
+	// EventStageEnd is emitted once a stage was successfully built.
+	EventStageEnd = "stage-end"
+	// EventBuildEnd is emitted once the build completed or failed, in
+	// which case the stage and section being built are reported.
+	EventBuildEnd = "build-end"
+)
+
+// ProgressEvent describes a build progress event, one event is written
+// per line as a JSON object.
+type ProgressEvent struct {
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	// Stage is the name of the stage, empty for unnamed stages.
+	Stage string `json:"stage,omitempty"`
+	// Section is the name of the section without the leading %.
+	Section string `json:"section,omitempty"`
+	// ExitCode is the exit code of a section script, or -1 if the
+	// section failed without running a script.
+	ExitCode *int `json:"exitCode,omitempty"`
+	// Duration is the duration in seconds of a section or of the build.
+	Duration float64 `json:"duration,omitempty"`
+	// Bytes is the size of the final image file.
+	Bytes int64 `json:"bytes,omitempty"`
+	// Image is the path of the image being built.
+	Image string `json:"image,omitempty"`
+	// Digest is the sha256 digest of the final image file.
+	Digest string `json:"digest,omitempty"`
+	// Error is the error message of a failed section or build.
+	Error string `json:"error,omitempty"`
+}
+
+// progress writes build progress events, a nil progress doesn't
+// report anything.
+type progress struct {
+	sync.Mutex
+
+	w     io.Writer
+	start time.Time
+	// stage and section being built
+	stage   string
+	section string
+}
+
+func newProgress(w io.Writer) *progress {
+	if w == nil {
+		return nil
+	}
+	return &progress{w: w}
+}
+
+func (p *progress) emit(ev ProgressEvent) {
+	if p == nil {
+		return
+	}
+	ev.Version = ProgressVersion
+	ev.Time = time.Now().UTC()
+
+	b, err := json.Marshal(ev)
+	if err != nil {
+		sylog.Debugf("While encoding progress event: %s", err)
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if _, err := p.w.Write(append(b, '\n')); err != nil {
+		sylog.Debugf("While writing progress event: %s", err)
+	}
+}
+
+// exitCode returns the exit code of the process which returned err, or
+// -1 if err doesn't come from a process.
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+
+	if err == nil {
+		return 0
+	} else if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+func (p *progress) buildStart(image string) {
+	if p == nil {
+		return
+	}
+	p.start = time.Now()
+	p.emit(ProgressEvent{Type: EventBuildStart, Image: image})
+}
+
+func (p *progress) stageStart(stage string) {
+	if p == nil {
+		return
+	}
+	p.stage = stage
+	p.section = ""
+	p.emit(ProgressEvent{Type: EventStageStart, Stage: stage})
+}
+
+func (p *progress) stageEnd() {
+	if p == nil {
+		return
+	}
+	p.emit(ProgressEvent{Type: EventStageEnd, Stage: p.stage})
+	p.section = ""
+}
+
+// runSection runs fn as the section name of the current stage and
+// reports its start, its end and its exit code.
+func (p *progress) runSection(name string, fn func() error) error {
+	if p == nil {
+		return fn()
+	}
+	p.section = name
+	p.emit(ProgressEvent{Type: EventSectionStart, Stage: p.stage, Section: name})
+
+	start := time.Now()
+	err := fn()
+
+	code := exitCode(err)
+	ev := ProgressEvent{
+		Type:     EventSectionEnd,
+		Stage:    p.stage,
+		Section:  name,
+		ExitCode: &code,
+		Duration: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	} else {
+		p.section = ""
+	}
+	p.emit(ev)
+
+	return err
+}
+
+func (p *progress) skipSection(name string) {
+	if p == nil {
+		return
+	}
+	p.emit(ProgressEvent{Type: EventSectionSkip, Stage: p.stage, Section: name})
+}
+
+// buildEnd reports the build outcome, the size and digest of the image
+// are reported for successful builds of image files.
+func (p *progress) buildEnd(image string, err error) {
+	if p == nil {
+		return
+	}
+	ev := ProgressEvent{
+		Type:     EventBuildEnd,
+		Image:    image,
+		Duration: time.Since(p.start).Seconds(),
+	}
+	if err != nil {
+		ev.Stage = p.stage
+		ev.Section = p.section
+		ev.Error = err.Error()
+	} else if fi, err := os.Stat(image); err == nil && fi.Mode().IsRegular() {
+		ev.Bytes = fi.Size()
+		if ev.Digest, err = fileDigest(image); err != nil {
+			sylog.Warningf("While computing digest of %s: %s", image, err)
+		}
+	}
+	p.emit(ev)
+}
+
+// fileDigest returns the sha256 digest of the file at path.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
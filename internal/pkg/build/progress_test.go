@@ -0,0 +1,122 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func decodeEvents(t *testing.T, buf *bytes.Buffer) []ProgressEvent {
+	t.Helper()
+
+	events := make([]ProgressEvent, 0)
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var ev ProgressEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("failed to decode event %q: %s", scanner.Text(), err)
+		}
+		if ev.Version != ProgressVersion {
+			t.Errorf("unexpected event version %d", ev.Version)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestProgress(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "image.sif")
+	if err := os.WriteFile(image, []byte("image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	p := newProgress(buf)
+
+	p.buildStart(image)
+	p.stageStart("devel")
+	if err := p.runSection("setup", func() error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p.skipSection("files")
+	p.stageEnd()
+	p.stageStart("final")
+	err := p.runSection("post", exec.Command("/bin/sh", "-c", "exit 3").Run)
+	if err == nil {
+		t.Fatalf("unexpected success")
+	}
+	p.buildEnd(image, err)
+	p.buildEnd(image, nil)
+
+	expected := []ProgressEvent{
+		{Type: EventBuildStart, Image: image},
+		{Type: EventStageStart, Stage: "devel"},
+		{Type: EventSectionStart, Stage: "devel", Section: "setup"},
+		{Type: EventSectionEnd, Stage: "devel", Section: "setup"},
+		{Type: EventSectionSkip, Stage: "devel", Section: "files"},
+		{Type: EventStageEnd, Stage: "devel"},
+		{Type: EventStageStart, Stage: "final"},
+		{Type: EventSectionStart, Stage: "final", Section: "post"},
+		{Type: EventSectionEnd, Stage: "final", Section: "post"},
+		{Type: EventBuildEnd, Stage: "final", Section: "post", Image: image},
+		{Type: EventBuildEnd, Image: image},
+	}
+	events := decodeEvents(t, buf)
+	if len(events) != len(expected) {
+		t.Fatalf("got %d events, expected %d", len(events), len(expected))
+	}
+	for i, ev := range events {
+		e := expected[i]
+		if ev.Type != e.Type || ev.Stage != e.Stage || ev.Section != e.Section || ev.Image != e.Image {
+			t.Errorf("event %d: got %+v, expected %+v", i, ev, e)
+		}
+	}
+
+	if code := events[3].ExitCode; code == nil || *code != 0 {
+		t.Errorf("unexpected exit code for successful section: %v", code)
+	}
+	if code := events[8].ExitCode; code == nil || *code != 3 {
+		t.Errorf("unexpected exit code for failed section: %v", code)
+	}
+	if events[8].Error == "" || events[9].Error == "" {
+		t.Errorf("failure not reported")
+	}
+	if ev := events[10]; ev.Bytes != 5 || ev.Digest == "" || ev.Error != "" {
+		t.Errorf("unexpected successful build end event: %+v", ev)
+	}
+}
+
+func TestProgressDisabled(t *testing.T) {
+	p := newProgress(nil)
+	if p != nil {
+		t.Fatalf("unexpected progress without writer")
+	}
+
+	// a nil progress must run the sections without reporting
+	p.buildStart("image")
+	p.stageStart("stage")
+	ran := false
+	err := p.runSection("post", func() error {
+		ran = true
+		return errors.New("failed")
+	})
+	if !ran || err == nil {
+		t.Errorf("section not run or error not returned")
+	}
+	p.skipSection("setup")
+	p.stageEnd()
+	p.buildEnd("image", err)
+}
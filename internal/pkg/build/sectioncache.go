@@ -0,0 +1,157 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apptainer/apptainer/internal/pkg/build/files"
+	"github.com/apptainer/apptainer/pkg/build/types"
+	"github.com/apptainer/apptainer/pkg/sylog"
+)
+
+// sectionCachePath is the path, relative to the root filesystem, of the
+// file recording the checksums of the sections applied to a sandbox.
+const sectionCachePath = ".singularity.d/build-cache.json"
+
+// sectionCache tracks the checksums of the %setup, %files and %post
+// sections applied to a sandbox, so that sections left unchanged since
+// the last build can be skipped when updating it. Like a layer cache,
+// each checksum also covers the preceding sections, once a section has
+// changed all the following ones are executed again.
+type sectionCache struct {
+	enabled  bool
+	changed  bool
+	sum      []byte
+	previous map[string]string
+	current  map[string]string
+}
+
+// newSectionCache returns the section cache for the stage at index i.
+// Checksums are only recorded for the last stage of a sandbox build and
+// only compared against the ones stored in the root filesystem when
+// update is set.
+func (b *Build) newSectionCache(i int, update bool) *sectionCache {
+	c := &sectionCache{
+		previous: make(map[string]string),
+		current:  make(map[string]string),
+	}
+	s := b.stages[i]
+	if b.Conf.Format != "sandbox" || i != len(b.stages)-1 || s.b.Opts.NoSectionCache {
+		return c
+	}
+	// partial builds don't apply every section
+	if len(s.b.Opts.Sections) != 1 || s.b.Opts.Sections[0] != "all" {
+		return c
+	}
+	// content copied from previous stages is not tracked
+	for _, f := range s.b.Recipe.BuildData.Files {
+		if strings.TrimSpace(strings.Split(f.Args, "#")[0]) != "" {
+			return c
+		}
+	}
+	c.enabled = true
+
+	if !update {
+		return c
+	}
+	data, err := os.ReadFile(filepath.Join(s.b.RootfsPath, sectionCachePath))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			sylog.Warningf("Could not read build cache, all sections will be executed: %s", err)
+		}
+		return c
+	}
+	if err := json.Unmarshal(data, &c.previous); err != nil {
+		sylog.Warningf("Could not parse build cache, all sections will be executed: %s", err)
+	}
+	return c
+}
+
+// unchanged computes the checksum of the section name from the content
+// written by write and returns true if it matches the checksum recorded
+// by the previous build.
+func (c *sectionCache) unchanged(name string, write func(io.Writer) error) (bool, error) {
+	if !c.enabled {
+		return false, nil
+	}
+
+	h := sha256.New()
+	h.Write(c.sum)
+	fmt.Fprintf(h, "%s\x00", name)
+	if err := write(h); err != nil {
+		return false, fmt.Errorf("while computing %%%s checksum: %s", name, err)
+	}
+	c.sum = h.Sum(nil)
+
+	sum := hex.EncodeToString(c.sum)
+	c.current[name] = sum
+	if !c.changed && c.previous[name] == sum {
+		sylog.Infof("Skipping unchanged %%%s section", name)
+		return true, nil
+	}
+	c.changed = true
+	return false, nil
+}
+
+// save records the section checksums in the root filesystem rootfs. When
+// section checksums are not tracked, the record of a previous build is
+// removed as it doesn't reflect the content anymore.
+func (c *sectionCache) save(rootfs string) error {
+	path := filepath.Join(rootfs, sectionCachePath)
+	if !c.enabled {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(c.current)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// writeScript returns a function writing the arguments and content of
+// the section script to a hash.
+func writeScript(script types.Script) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "%s\x00%s", script.Args, script.Script)
+		return err
+	}
+}
+
+// writeFiles returns a function writing the transfers of the %files
+// sections copying from the host to a hash, including the content of
+// the source files.
+func writeFiles(sections []types.Files) func(io.Writer) error {
+	return func(w io.Writer) error {
+		for _, f := range sections {
+			for _, transfer := range f.Files {
+				if transfer.Src == "" {
+					continue
+				}
+				fmt.Fprintf(w, "%s\x00%s\x00", transfer.Src, transfer.Dst)
+				if err := files.HashFromHost(w, transfer.Src); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apptainer/apptainer/pkg/build/types"
+	"gotest.tools/v3/assert"
+)
+
+func TestSectionCache(t *testing.T) {
+	rootfs := t.TempDir()
+	assert.NilError(t, os.Mkdir(filepath.Join(rootfs, ".singularity.d"), 0o755))
+
+	src := filepath.Join(t.TempDir(), "file")
+	assert.NilError(t, os.WriteFile(src, []byte("first"), 0o644))
+
+	newBuild := func(sections []string, noCache bool) *Build {
+		def := types.Definition{}
+		def.BuildData.Setup.Script = "echo setup"
+		def.BuildData.Post.Script = "echo post"
+		def.BuildData.Files = []types.Files{
+			{Files: []types.FileTransport{{Src: src, Dst: "/file"}}},
+		}
+		return &Build{
+			Conf: Config{Format: "sandbox"},
+			stages: []stage{{
+				b: &types.Bundle{
+					RootfsPath: rootfs,
+					Recipe:     def,
+					Opts:       types.Options{Sections: sections, NoSectionCache: noCache},
+				},
+			}},
+		}
+	}
+
+	// run the cached sections and return those which were skipped
+	run := func(b *Build, update bool, post string) []string {
+		var skipped []string
+		c := b.newSectionCache(0, update)
+		data := b.stages[0].b.Recipe.BuildData
+		data.Post.Script = post
+		for _, s := range []struct {
+			name  string
+			write func(w io.Writer) error
+		}{
+			{"setup", writeScript(data.Setup)},
+			{"files", writeFiles(data.Files)},
+			{"post", writeScript(data.Post)},
+		} {
+			skip, err := c.unchanged(s.name, s.write)
+			assert.NilError(t, err)
+			if skip {
+				skipped = append(skipped, s.name)
+			}
+		}
+		assert.NilError(t, c.save(rootfs))
+		return skipped
+	}
+
+	all := []string{"all"}
+
+	// initial build executes all sections
+	assert.DeepEqual(t, run(newBuild(all, false), false, "echo post"), []string(nil))
+	// nothing changed
+	assert.DeepEqual(t, run(newBuild(all, false), true, "echo post"), []string{"setup", "files", "post"})
+	// %post changed
+	assert.DeepEqual(t, run(newBuild(all, false), true, "echo post 2"), []string{"setup", "files"})
+	assert.DeepEqual(t, run(newBuild(all, false), true, "echo post 2"), []string{"setup", "files", "post"})
+	// %files source content changed, following %post is executed again
+	assert.NilError(t, os.WriteFile(src, []byte("second"), 0o644))
+	assert.DeepEqual(t, run(newBuild(all, false), true, "echo post 2"), []string{"setup"})
+	// --no-section-cache executes all sections and discards checksums
+	assert.DeepEqual(t, run(newBuild(all, true), true, "echo post 2"), []string(nil))
+	_, err := os.Stat(filepath.Join(rootfs, sectionCachePath))
+	assert.Assert(t, os.IsNotExist(err))
+	assert.DeepEqual(t, run(newBuild(all, false), true, "echo post 2"), []string(nil))
+	// partial builds execute requested sections
+	assert.DeepEqual(t, run(newBuild([]string{"post"}, false), true, "echo post 2"), []string(nil))
+}
@@ -378,10 +378,12 @@ func (cp *OCIConveyorPacker) insertEnv() error {
 		if len(envParts) == 1 {
 			export = fmt.Sprintf("export %s=\"${%s:-}\"\n", envParts[0], envParts[0])
 		} else {
+			// values are shell escaped and double quoted, so they are
+			// set verbatim without any evaluation when sourced
 			if envParts[0] == "PATH" {
-				export = fmt.Sprintf("export %s=%q\n", envParts[0], shell.Escape(envParts[1]))
+				export = fmt.Sprintf("export %s=\"%s\"\n", envParts[0], shell.Escape(envParts[1]))
 			} else {
-				export = fmt.Sprintf("export %s=\"${%s:-%q}\"\n", envParts[0], envParts[0], shell.Escape(envParts[1]))
+				export = fmt.Sprintf("export %s=\"${%s:-\"%s\"}\"\n", envParts[0], envParts[0], shell.Escape(envParts[1]))
 			}
 		}
 		_, err = f.WriteString(export)
@@ -414,6 +416,36 @@ func (cp *OCIConveyorPacker) insertOCILabels() (err error) {
 	return err
 }
 
+// InsertOCIMetadata writes the Apptainer base environment, runscript,
+// environment and labels generated from the configuration of srcImage
+// into rootfs, as done for an image built from an OCI source.
+func InsertOCIMetadata(srcImage v1.Image, rootfs string) error {
+	cf, err := srcImage.ConfigFile()
+	if err != nil {
+		return err
+	}
+
+	cp := &OCIConveyorPacker{
+		srcImg:    srcImage,
+		b:         &sytypes.Bundle{RootfsPath: rootfs},
+		imgConfig: cf.Config,
+	}
+
+	if err := cp.insertBaseEnv(); err != nil {
+		return fmt.Errorf("while inserting base environment: %v", err)
+	}
+	if err := cp.insertRunScript(); err != nil {
+		return fmt.Errorf("while inserting runscript: %v", err)
+	}
+	if err := cp.insertEnv(); err != nil {
+		return fmt.Errorf("while inserting docker specific environment: %v", err)
+	}
+	if err := cp.insertOCILabels(); err != nil {
+		return fmt.Errorf("while inserting oci labels: %v", err)
+	}
+	return nil
+}
+
 // CleanUp removes any tmpfs owned by the conveyorPacker on the filesystem
 func (cp *OCIConveyorPacker) CleanUp() {
 	cp.b.Remove()
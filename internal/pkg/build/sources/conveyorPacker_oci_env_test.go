@@ -0,0 +1,67 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/util/shell/interpreter"
+	sytypes "github.com/apptainer/apptainer/pkg/build/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestInsertEnv(t *testing.T) {
+	rootfs := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(rootfs, ".singularity.d", "env"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cp := &OCIConveyorPacker{
+		b: &sytypes.Bundle{RootfsPath: rootfs},
+		imgConfig: v1.Config{
+			Env: []string{
+				"PATH=/opt/$(whoami)/bin:/usr/bin",
+				"FOO=$(whoami)",
+				`BAR=a b "c"`,
+				"BAZ=`id -u` ${HOME} back\\slash",
+				"EMPTY",
+			},
+		},
+	}
+	if err := cp.insertEnv(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	script, err := os.ReadFile(filepath.Join(rootfs, ".singularity.d", "env", "10-docker2singularity.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// command execution is disabled, the script evaluation fails if any
+	// value is evaluated
+	env, err := interpreter.EvaluateEnv(context.Background(), script, nil, []string{"HOME=/home/user", "EMPTY=host"})
+	if err != nil {
+		t.Fatalf("unexpected error while evaluating %s: %s", script, err)
+	}
+
+	for _, want := range []string{
+		"PATH=/opt/$(whoami)/bin:/usr/bin",
+		"FOO=$(whoami)",
+		`BAR=a b "c"`,
+		"BAZ=`id -u` ${HOME} back\\slash",
+		"EMPTY=host",
+	} {
+		if !slices.Contains(env, want) {
+			t.Errorf("%s not found in environment %v", want, env)
+		}
+	}
+}
@@ -61,6 +61,48 @@ func UnpackRootfs(_ context.Context, srcImage v1.Image, destDir string) (err err
 
 	flatTar := mutate.Extract(srcImage)
 
+	// Unpack root filesystem
+	unpackOptions, err := umociUnpackOptions()
+	if err != nil {
+		return err
+	}
+	err = umocilayer.UnpackLayer(destDir, flatTar, unpackOptions)
+	if err != nil {
+		return fmt.Errorf("error unpacking rootfs: %s", err)
+	}
+
+	// No `--fix-perms` and no sandbox... we are fine
+	return err
+}
+
+// UnpackLayer extracts a single layer into destDir. Whiteouts are not
+// applied but converted to overlay filesystem whiteouts, so destDir can be
+// used as an overlay lower directory on top of the directories of the
+// layers below it. Opaque directories are marked with a trusted extended
+// attribute which requires privileges, an error is returned otherwise.
+func UnpackLayer(_ context.Context, layer v1.Layer, destDir string) error {
+	unpackOptions, err := umociUnpackOptions()
+	if err != nil {
+		return err
+	}
+	unpackOptions.WhiteoutMode = umocilayer.OverlayFSWhiteout
+
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return fmt.Errorf("while reading layer: %s", err)
+	}
+	defer rc.Close()
+
+	if err := umocilayer.UnpackLayer(destDir, rc, unpackOptions); err != nil {
+		return fmt.Errorf("error unpacking layer: %s", err)
+	}
+	return nil
+}
+
+// umociUnpackOptions returns the umoci unpack options, the umoci log level
+// follows the Apptainer one and the rootless mode is set for unprivileged
+// users.
+func umociUnpackOptions() (*umocilayer.UnpackOptions, error) {
 	var mapOptions umocilayer.MapOptions
 
 	loggerLevel := sylog.GetLevel()
@@ -87,26 +129,18 @@ func UnpackRootfs(_ context.Context, srcImage v1.Image, destDir string) (err err
 
 		uidMap, err := idtools.ParseMapping(fmt.Sprintf("0:%d:1", os.Geteuid()))
 		if err != nil {
-			return fmt.Errorf("error parsing uidmap: %s", err)
+			return nil, fmt.Errorf("error parsing uidmap: %s", err)
 		}
 		mapOptions.UIDMappings = append(mapOptions.UIDMappings, uidMap)
 
 		gidMap, err := idtools.ParseMapping(fmt.Sprintf("0:%d:1", os.Getegid()))
 		if err != nil {
-			return fmt.Errorf("error parsing gidmap: %s", err)
+			return nil, fmt.Errorf("error parsing gidmap: %s", err)
 		}
 		mapOptions.GIDMappings = append(mapOptions.GIDMappings, gidMap)
 	}
 
-	// Unpack root filesystem
-	unpackOptions := umocilayer.UnpackOptions{MapOptions: mapOptions}
-	err = umocilayer.UnpackLayer(destDir, flatTar, &unpackOptions)
-	if err != nil {
-		return fmt.Errorf("error unpacking rootfs: %s", err)
-	}
-
-	// No `--fix-perms` and no sandbox... we are fine
-	return err
+	return &umocilayer.UnpackOptions{MapOptions: mapOptions}, nil
 }
 
 // FixPerms will work through the rootfs of this bundle, making sure that all
@@ -34,6 +34,8 @@ type stage struct {
 	a Assembler
 	// b is an intermediate structure that encapsulates all information for the container, e.g., metadata, filesystems.
 	b *types.Bundle
+	// progress reports the sections run, nil if progress isn't reported.
+	progress *progress
 }
 
 const (
@@ -74,7 +76,7 @@ func (s *stage) runHostScript(name string, script types.Script) error {
 		cmd.Env = append(cmd.Env, aEnvironment, sEnvironment, aRootfs, sRootfs)
 
 		sylog.Infof("Running %s scriptlet", name)
-		if err := cmd.Run(); err != nil {
+		if err := s.progress.runSection(name, cmd.Run); err != nil {
 			return fmt.Errorf("failed to run %%%s script: %v", name, err)
 		}
 	}
@@ -114,6 +116,9 @@ func (s *stage) runPostScript(sessionResolv, sessionHosts string) error {
 				cmdArgs = append(cmdArgs, "-B", bind)
 			}
 		}
+		if s.b.Opts.FakerootCaps != "" {
+			cmdArgs = append(cmdArgs, "--fakeroot-caps", s.b.Opts.FakerootCaps)
+		}
 		script := s.b.Recipe.BuildData.Post
 		scriptPath := filepath.Join(s.b.RootfsPath, ".post.script")
 		if err = createScript(scriptPath, []byte(script.Script)); err != nil {
@@ -138,7 +143,7 @@ func (s *stage) runPostScript(sessionResolv, sessionHosts string) error {
 		cmd.Env = env
 
 		sylog.Infof("Running post scriptlet")
-		err = cmd.Run()
+		err = s.progress.runSection("post", cmd.Run)
 		if len(fakerootBinds) > 0 {
 			s.cleanFakerootBindpoints(fakerootBinds)
 		}
@@ -176,7 +181,7 @@ func (s *stage) runTestScript(sessionResolv, sessionHosts string) error {
 		cmd.Env = currentEnvNoApptainer([]string{"DEBUG", "NV", "NVCCLI", "ROCM", "BINDPATH", "MOUNT", "WRITABLE_TMPFS"})
 
 		sylog.Infof("Running testscript")
-		return cmd.Run()
+		return s.progress.runSection("test", cmd.Run)
 	}
 	return nil
 }
@@ -0,0 +1,187 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/apptainer/apptainer/pkg/sylog"
+	units "github.com/docker/go-units"
+)
+
+// stripDocsPatterns lists the documentation paths removed by --strip-docs.
+var stripDocsPatterns = []string{
+	"/usr/share/doc/*",
+	"/usr/share/man/*",
+	"/usr/share/info/*",
+	"/usr/share/gtk-doc/*",
+}
+
+// stripLocalesPatterns lists the locale paths removed by --strip-locales,
+// the compiled locales in /usr/lib/locale are left untouched.
+var stripLocalesPatterns = []string{
+	"/usr/share/locale/*",
+}
+
+// stripKeepPatterns lists the base names of the files and directories
+// never removed when stripping the root filesystem: licenses and english
+// locales.
+var stripKeepPatterns = []string{
+	"copyright",
+	"COPYING*",
+	"LICENSE*",
+	"en",
+	"en_*",
+	"locale.alias",
+}
+
+// stripPatterns returns the patterns of the paths to remove from the
+// root filesystem of the stage.
+func (s *stage) stripPatterns() []string {
+	patterns := make([]string, 0)
+	if s.b.Opts.StripDocs {
+		patterns = append(patterns, stripDocsPatterns...)
+	}
+	if s.b.Opts.StripLocales {
+		patterns = append(patterns, stripLocalesPatterns...)
+	}
+	return append(patterns, s.b.Opts.StripPaths...)
+}
+
+// strip removes the paths matching the strip patterns from the root
+// filesystem of the stage and reports the space saved. It's executed
+// once all sections ran so files installed late are removed too.
+func (s *stage) strip() error {
+	patterns := s.stripPatterns()
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	saved, err := stripRootfs(s.b.RootfsPath, patterns)
+	if err != nil {
+		return fmt.Errorf("while stripping root filesystem: %w", err)
+	}
+	sylog.Infof("Stripping root filesystem saved %s", units.HumanSize(float64(saved)))
+	return nil
+}
+
+// stripRootfs removes the paths matching patterns, absolute paths relative
+// to rootfs which can contain shell patterns, and returns the size of the
+// removed files.
+func stripRootfs(rootfs string, patterns []string) (int64, error) {
+	root, err := filepath.EvalSymlinks(rootfs)
+	if err != nil {
+		return 0, err
+	}
+
+	var saved int64
+	for _, p := range patterns {
+		if !strings.HasPrefix(p, "/") {
+			return saved, fmt.Errorf("strip path %s must be absolute", p)
+		}
+		matches, err := filepath.Glob(filepath.Join(root, p))
+		if err != nil {
+			return saved, fmt.Errorf("bad strip pattern %s: %s", p, err)
+		}
+		for _, m := range matches {
+			// don't follow symlinks leading outside of the root filesystem
+			parent, err := filepath.EvalSymlinks(filepath.Dir(m))
+			if err != nil {
+				return saved, err
+			}
+			if parent != root && !strings.HasPrefix(parent, root+"/") {
+				sylog.Warningf("Not stripping %s: located outside of the root filesystem", m)
+				continue
+			}
+			m = filepath.Join(parent, filepath.Base(m))
+
+			n, err := removeExceptKept(m)
+			saved += n
+			if err != nil {
+				return saved, err
+			}
+		}
+	}
+	return saved, nil
+}
+
+// isKept returns whether the base name of path matches one of the
+// stripKeepPatterns.
+func isKept(path string) bool {
+	base := filepath.Base(path)
+	for _, k := range stripKeepPatterns {
+		if ok, _ := filepath.Match(k, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// removeExceptKept removes path recursively, except the kept files and
+// directories along with their parent directories, and returns the size
+// of the removed files.
+func removeExceptKept(path string) (int64, error) {
+	var saved int64
+	var dirs []string
+
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if isKept(p) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			dirs = append(dirs, p)
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			saved += fi.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return saved, fmt.Errorf("while removing %s: %w", path, err)
+	}
+
+	// remove directories deepest first, those still containing
+	// kept files are left in place
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Remove(dirs[i]); err != nil && !isNotEmpty(err) {
+			return saved, fmt.Errorf("while removing %s: %w", dirs[i], err)
+		}
+	}
+	return saved, nil
+}
+
+// isNotEmpty returns whether err was returned while removing a non empty
+// directory.
+func isNotEmpty(err error) bool {
+	var pe *os.PathError
+	if errors.As(err, &pe) {
+		return pe.Err == syscall.ENOTEMPTY || pe.Err == syscall.EEXIST
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestStripRootfs(t *testing.T) {
+	rootfs := t.TempDir()
+	outside := t.TempDir()
+
+	files := map[string]string{
+		"/usr/share/doc/pkg/README":                   "readme",
+		"/usr/share/doc/pkg/copyright":                "copyright",
+		"/usr/share/man/man1/tool.1":                  "manpage",
+		"/usr/share/locale/fr/LC_MESSAGES/tool.mo":    "french",
+		"/usr/share/locale/en_GB/LC_MESSAGES/tool.mo": "english",
+		"/usr/share/locale/locale.alias":              "alias",
+		"/usr/bin/tool":                               "binary",
+	}
+	for f, content := range files {
+		path := filepath.Join(rootfs, f)
+		assert.NilError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NilError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	assert.NilError(t, os.WriteFile(filepath.Join(outside, "host"), []byte("host"), 0o644))
+	assert.NilError(t, os.Symlink(outside, filepath.Join(rootfs, "/usr/share/info")))
+	assert.NilError(t, os.MkdirAll(filepath.Join(rootfs, "/opt/link"), 0o755))
+	assert.NilError(t, os.Symlink(outside, filepath.Join(rootfs, "/opt/link/outside")))
+
+	patterns := append(append([]string{}, stripDocsPatterns...), stripLocalesPatterns...)
+	patterns = append(patterns, "/opt/link/outside/*")
+	saved, err := stripRootfs(rootfs, patterns)
+	assert.NilError(t, err)
+	assert.Equal(t, saved, int64(len("readme")+len("manpage")+len("french")))
+
+	for _, f := range []string{
+		"/usr/share/doc/pkg/copyright",
+		"/usr/share/locale/en_GB/LC_MESSAGES/tool.mo",
+		"/usr/share/locale/locale.alias",
+		"/usr/bin/tool",
+	} {
+		_, err := os.Stat(filepath.Join(rootfs, f))
+		assert.NilError(t, err, "%s was removed", f)
+	}
+	for _, f := range []string{
+		"/usr/share/doc/pkg/README",
+		"/usr/share/man/man1",
+		"/usr/share/locale/fr",
+	} {
+		_, err := os.Lstat(filepath.Join(rootfs, f))
+		assert.Assert(t, os.IsNotExist(err), "%s was not removed", f)
+	}
+	// symlinks are removed but never followed outside of the root filesystem
+	_, err = os.Stat(filepath.Join(outside, "host"))
+	assert.NilError(t, err)
+
+	_, err = stripRootfs(rootfs, []string{"usr/share/doc"})
+	assert.ErrorContains(t, err, "must be absolute")
+}
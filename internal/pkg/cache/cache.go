@@ -51,6 +51,8 @@ const (
 	OrasCacheType = "oras"
 	// NetCacheType specifies the cache holds images pulled from http(s) internet sources
 	NetCacheType = "net"
+	// OciLayerCacheType specifies the cache holds unpacked OCI layers used as overlay lower directories
+	OciLayerCacheType = "oci-layer"
 )
 
 var (
@@ -66,6 +68,10 @@ var (
 	OciCacheTypes = []string{
 		OciBlobCacheType,
 	}
+	// DirCacheTypes specifies the directory cache types.
+	DirCacheTypes = []string{
+		OciLayerCacheType,
+	}
 )
 
 // Config describes the requested configuration requested when a new handle is created,
@@ -105,6 +111,13 @@ func (h *Handle) GetOciCacheDir(cacheType string) (cacheDir string, err error) {
 	return h.getCacheTypeDir(cacheType), nil
 }
 
+func (h *Handle) GetDirCacheDir(cacheType string) (cacheDir string, err error) {
+	if !stringInSlice(cacheType, DirCacheTypes) {
+		return "", errInvalidCacheType
+	}
+	return h.getCacheTypeDir(cacheType), nil
+}
+
 // GetEntry returns a cache Entry for a specified file cache type and hash
 func (h *Handle) GetEntry(cacheType string, hash string) (e *Entry, err error) {
 	if h.disabled {
@@ -190,7 +203,8 @@ func (h *Handle) CleanCache(cacheType string, dryRun bool, days int) (err error)
 		sylog.Infof("Removing %s cache entry: %s", cacheType, f.Name())
 		if !dryRun {
 			// We RemoveAll in case the entry is a directory from Singularity (prior to 3.6)
-			err := os.RemoveAll(path.Join(dir, f.Name()))
+			// or an unpacked layer which may contain directories without write permission
+			err := fs.ForceRemoveAll(path.Join(dir, f.Name()))
 			if err != nil {
 				sylog.Errorf("Could not remove cache entry '%s': %v", f.Name(), err)
 				errCount = errCount + 1
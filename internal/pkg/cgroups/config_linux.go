@@ -11,9 +11,13 @@ package cgroups
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/opencontainers/runc/libcontainer/cgroups"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/pelletier/go-toml/v2"
 )
@@ -61,6 +65,21 @@ type LinuxThrottleDevice struct {
 	Rate uint64 `toml:"rate" json:"rate"`
 }
 
+// LinuxIOMax struct holds the cgroups v2 io.max limits of a device, a limit
+// not set is not throttled
+type LinuxIOMax struct {
+	// Device is the device's major:minor number.
+	Device string `toml:"device" json:"device"`
+	// Rbps is the read rate limit, bytes per second
+	Rbps *uint64 `toml:"rbps" json:"rbps,omitempty"`
+	// Wbps is the write rate limit, bytes per second
+	Wbps *uint64 `toml:"wbps" json:"wbps,omitempty"`
+	// Riops is the read rate limit, IO per second
+	Riops *uint64 `toml:"riops" json:"riops,omitempty"`
+	// Wiops is the write rate limit, IO per second
+	Wiops *uint64 `toml:"wiops" json:"wiops,omitempty"`
+}
+
 // LinuxBlockIO for Linux cgroup 'blkio' resource management
 type LinuxBlockIO struct {
 	// Specifies per cgroup weight
@@ -77,6 +96,79 @@ type LinuxBlockIO struct {
 	ThrottleReadIOPSDevice []LinuxThrottleDevice `toml:"throttleReadIOPSDevice" json:"throttleReadIOPSDevice,omitempty"`
 	// IO write rate limit per cgroup per device, IO per second
 	ThrottleWriteIOPSDevice []LinuxThrottleDevice `toml:"throttleWriteIOPSDevice" json:"throttleWriteIOPSDevice,omitempty"`
+	// IO rate limits per cgroup per device in the io.max format, cgroups v2 only
+	IOMax []LinuxIOMax `toml:"ioMax" json:"ioMax,omitempty"`
+}
+
+// ParseDeviceNumber parses a device number in the major:minor format.
+func ParseDeviceNumber(device string) (major, minor int64, err error) {
+	ma, mi, ok := strings.Cut(device, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("device %q is not in major:minor format", device)
+	}
+	major, err = strconv.ParseInt(ma, 10, 64)
+	if err != nil || major < 0 {
+		return 0, 0, fmt.Errorf("invalid major number in device %q", device)
+	}
+	minor, err = strconv.ParseInt(mi, 10, 64)
+	if err != nil || minor < 0 {
+		return 0, 0, fmt.Errorf("invalid minor number in device %q", device)
+	}
+	return major, minor, nil
+}
+
+// setThrottleDevice sets the rate limit of the device major:minor in the
+// throttle devices list.
+func setThrottleDevice(devices []LinuxThrottleDevice, major, minor int64, rate uint64) []LinuxThrottleDevice {
+	for i := range devices {
+		if devices[i].Major == major && devices[i].Minor == minor {
+			devices[i].Rate = rate
+			return devices
+		}
+	}
+	return append(devices, LinuxThrottleDevice{Major: major, Minor: minor, Rate: rate})
+}
+
+// withIOMax returns a copy of the configuration with the io.max limits
+// merged into the throttle devices lists, which are applied as io.max
+// limits on cgroups v2 hosts. The io.max limits are rejected on cgroups v1
+// hosts, where throttling is done per blkio.throttle file with the throttle
+// devices lists.
+func (c *Config) withIOMax(unified bool) (*Config, error) {
+	if c.BlockIO == nil || len(c.BlockIO.IOMax) == 0 {
+		return c, nil
+	}
+	if !unified {
+		return nil, fmt.Errorf("blockIO ioMax limits require cgroups v2, use the blockIO throttle device limits on cgroups v1 hosts")
+	}
+
+	conf := *c
+	blkio := *c.BlockIO
+	blkio.ThrottleReadBpsDevice = append([]LinuxThrottleDevice{}, c.BlockIO.ThrottleReadBpsDevice...)
+	blkio.ThrottleWriteBpsDevice = append([]LinuxThrottleDevice{}, c.BlockIO.ThrottleWriteBpsDevice...)
+	blkio.ThrottleReadIOPSDevice = append([]LinuxThrottleDevice{}, c.BlockIO.ThrottleReadIOPSDevice...)
+	blkio.ThrottleWriteIOPSDevice = append([]LinuxThrottleDevice{}, c.BlockIO.ThrottleWriteIOPSDevice...)
+
+	for _, limit := range c.BlockIO.IOMax {
+		major, minor, err := ParseDeviceNumber(limit.Device)
+		if err != nil {
+			return nil, fmt.Errorf("while parsing blockIO ioMax limits: %w", err)
+		}
+		if limit.Rbps != nil {
+			blkio.ThrottleReadBpsDevice = setThrottleDevice(blkio.ThrottleReadBpsDevice, major, minor, *limit.Rbps)
+		}
+		if limit.Wbps != nil {
+			blkio.ThrottleWriteBpsDevice = setThrottleDevice(blkio.ThrottleWriteBpsDevice, major, minor, *limit.Wbps)
+		}
+		if limit.Riops != nil {
+			blkio.ThrottleReadIOPSDevice = setThrottleDevice(blkio.ThrottleReadIOPSDevice, major, minor, *limit.Riops)
+		}
+		if limit.Wiops != nil {
+			blkio.ThrottleWriteIOPSDevice = setThrottleDevice(blkio.ThrottleWriteIOPSDevice, major, minor, *limit.Wiops)
+		}
+	}
+	conf.BlockIO = &blkio
+	return &conf, nil
 }
 
 // LinuxMemory for Linux cgroup 'memory' resource management
@@ -175,9 +267,15 @@ type Config struct {
 	Unified map[string]string `toml:"unified" json:"unified,omitempty"`
 }
 
-// MarshalJSON marshals a cgroups.Config struct to a JSON string
+// MarshalJSON marshals a cgroups.Config struct to a JSON string, the
+// blockIO ioMax limits are merged into the throttle devices lists of
+// the OCI LinuxResources struct.
 func (c *Config) MarshalJSON() (string, error) {
-	data, err := json.Marshal(c)
+	conf, err := c.withIOMax(cgroups.IsCgroup2UnifiedMode())
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(conf)
 	if err != nil {
 		return "", err
 	}
@@ -230,12 +328,12 @@ func LoadResources(path string) (spec specs.LinuxResources, err error) {
 	}
 
 	// convert TOML structures to OCI JSON structures
-	data, err := json.Marshal(conf)
+	data, err := conf.MarshalJSON()
 	if err != nil {
 		return
 	}
 
-	if err = json.Unmarshal(data, &spec); err != nil {
+	if err = json.Unmarshal([]byte(data), &spec); err != nil {
 		return
 	}
 
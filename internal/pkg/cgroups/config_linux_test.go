@@ -0,0 +1,108 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cgroups
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestParseDeviceNumber(t *testing.T) {
+	tests := []struct {
+		device  string
+		major   int64
+		minor   int64
+		wantErr bool
+	}{
+		{device: "8:0", major: 8, minor: 0},
+		{device: "259:12", major: 259, minor: 12},
+		{device: "8", wantErr: true},
+		{device: "8:", wantErr: true},
+		{device: "a:0", wantErr: true},
+		{device: "-1:0", wantErr: true},
+		{device: "8:0:1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.device, func(t *testing.T) {
+			major, minor, err := ParseDeviceNumber(tt.device)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if major != tt.major || minor != tt.minor {
+				t.Errorf("got %d:%d, expected %d:%d", major, minor, tt.major, tt.minor)
+			}
+		})
+	}
+}
+
+func TestWithIOMax(t *testing.T) {
+	rate := func(r uint64) *uint64 { return &r }
+
+	conf := &Config{
+		BlockIO: &LinuxBlockIO{
+			ThrottleReadBpsDevice: []LinuxThrottleDevice{
+				{Major: 8, Minor: 0, Rate: 10},
+				{Major: 8, Minor: 16, Rate: 20},
+			},
+			IOMax: []LinuxIOMax{
+				{Device: "8:0", Rbps: rate(1048576), Wiops: rate(100)},
+			},
+		},
+	}
+
+	if _, err := conf.withIOMax(false); err == nil {
+		t.Errorf("unexpected success on cgroups v1")
+	}
+
+	got, err := conf.withIOMax(true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// merging again the limits must not duplicate devices
+	got, err = got.withIOMax(true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := specs.LinuxResources{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &specs.LinuxBlockIO{
+		ThrottleReadBpsDevice: []specs.LinuxThrottleDevice{
+			{LinuxBlockIODevice: specs.LinuxBlockIODevice{Major: 8, Minor: 0}, Rate: 1048576},
+			{LinuxBlockIODevice: specs.LinuxBlockIODevice{Major: 8, Minor: 16}, Rate: 20},
+		},
+		ThrottleWriteIOPSDevice: []specs.LinuxThrottleDevice{
+			{LinuxBlockIODevice: specs.LinuxBlockIODevice{Major: 8, Minor: 0}, Rate: 100},
+		},
+	}
+	if !reflect.DeepEqual(spec.BlockIO, want) {
+		t.Errorf("got %+v, expected %+v", spec.BlockIO, want)
+	}
+
+	// the original configuration is left untouched
+	if conf.BlockIO.ThrottleReadBpsDevice[0].Rate != 10 || len(conf.BlockIO.ThrottleWriteIOPSDevice) != 0 {
+		t.Errorf("original configuration modified: %+v", conf.BlockIO)
+	}
+
+	conf.BlockIO.IOMax[0].Device = "sda"
+	if _, err := conf.withIOMax(true); err == nil {
+		t.Errorf("unexpected success with an invalid device")
+	}
+}
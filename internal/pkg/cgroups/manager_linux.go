@@ -287,6 +287,10 @@ func newManager(resources *specs.LinuxResources, group string, systemd bool) (ma
 		if len(resources.Devices) > 0 {
 			sylog.Warningf("Device limits will not be applied with rootless cgroups")
 		}
+
+		if err := CheckDelegation(resources); err != nil {
+			return nil, err
+		}
 	}
 
 	spec := &specs.Spec{
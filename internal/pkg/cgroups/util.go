@@ -12,10 +12,12 @@ package cgroups
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/apptainer/apptainer/pkg/sylog"
 	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
 )
 
@@ -147,3 +149,85 @@ func CanUseCgroups(systemd bool, warn bool) bool {
 
 	return rootlessOK
 }
+
+// userDelegatePath returns the path of the cgroup that systemd delegates to
+// the user manager (user@<uid>.service) of uid.
+func userDelegatePath(uid int) string {
+	return filepath.Join(
+		unifiedMountPoint,
+		"user.slice",
+		fmt.Sprintf("user-%d.slice", uid),
+		fmt.Sprintf("user@%d.service", uid),
+	)
+}
+
+// requiredControllers returns the cgroups v2 controllers needed to apply
+// the limits set in resources.
+func requiredControllers(resources *specs.LinuxResources) []string {
+	controllers := []string{}
+	if resources.CPU != nil {
+		cpu := resources.CPU
+		if cpu.Shares != nil || cpu.Quota != nil || cpu.Period != nil || cpu.Idle != nil {
+			controllers = append(controllers, "cpu")
+		}
+		if cpu.Cpus != "" || cpu.Mems != "" {
+			controllers = append(controllers, "cpuset")
+		}
+	}
+	if resources.Memory != nil {
+		controllers = append(controllers, "memory")
+	}
+	if resources.BlockIO != nil {
+		controllers = append(controllers, "io")
+	}
+	if resources.Pids != nil {
+		controllers = append(controllers, "pids")
+	}
+	if len(resources.HugepageLimits) > 0 {
+		controllers = append(controllers, "hugetlb")
+	}
+	return controllers
+}
+
+// checkDelegation verifies that the required controllers are listed in the
+// cgroup.controllers file of the delegated cgroup at path.
+func checkDelegation(path string, required []string) error {
+	data, err := os.ReadFile(filepath.Join(path, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("cgroup v2 delegation not available for your user session (%s); "+
+			"ask your administrator to set Delegate=yes for user@.service", err)
+	}
+
+	available := make(map[string]bool)
+	for _, c := range strings.Fields(string(data)) {
+		available[c] = true
+	}
+
+	missing := []string{}
+	for _, c := range required {
+		if !available[c] {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("cgroup v2 delegation for %s not enabled for your user session; "+
+			"ask your administrator to set Delegate=yes for user@.service", strings.Join(missing, "/"))
+	}
+	return nil
+}
+
+// CheckDelegation verifies, for a non-root user on a cgroups v2 host, that
+// the controllers needed to apply resources are delegated by systemd to the
+// user session. It returns an actionable error if they are not, as creating
+// the cgroup through systemd would otherwise fail with an opaque D-Bus error.
+func CheckDelegation(resources *specs.LinuxResources) error {
+	uid := os.Geteuid()
+	if uid == 0 || resources == nil || !cgroups.IsCgroup2UnifiedMode() {
+		return nil
+	}
+	required := requiredControllers(resources)
+	if len(required) == 0 {
+		return nil
+	}
+	return checkDelegation(userDelegatePath(uid), required)
+}
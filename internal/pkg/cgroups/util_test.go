@@ -0,0 +1,110 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cgroups
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestRequiredControllers(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources specs.LinuxResources
+		expected  []string
+	}{
+		{
+			name:      "empty",
+			resources: specs.LinuxResources{},
+			expected:  []string{},
+		},
+		{
+			name: "cpu and memory",
+			resources: specs.LinuxResources{
+				CPU:    &specs.LinuxCPU{Quota: Int64ptr(10000)},
+				Memory: &specs.LinuxMemory{Limit: Int64ptr(1024)},
+			},
+			expected: []string{"cpu", "memory"},
+		},
+		{
+			name: "cpuset pids io",
+			resources: specs.LinuxResources{
+				CPU:     &specs.LinuxCPU{Cpus: "0-1"},
+				BlockIO: &specs.LinuxBlockIO{},
+				Pids:    &specs.LinuxPids{Limit: 10},
+			},
+			expected: []string{"cpuset", "io", "pids"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := requiredControllers(&tt.resources)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("unexpected controllers: got %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckDelegation(t *testing.T) {
+	delegated := t.TempDir()
+	if err := os.WriteFile(filepath.Join(delegated, "cgroup.controllers"), []byte("cpu io memory pids\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		required []string
+		errMatch string
+	}{
+		{
+			name:     "delegated",
+			path:     delegated,
+			required: []string{"cpu", "memory"},
+		},
+		{
+			name:     "missing controllers",
+			path:     delegated,
+			required: []string{"cpu", "cpuset", "hugetlb"},
+			errMatch: "delegation for cpuset/hugetlb not enabled",
+		},
+		{
+			name:     "no delegated cgroup",
+			path:     filepath.Join(delegated, "nonexistent"),
+			required: []string{"memory"},
+			errMatch: "delegation not available",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDelegation(tt.path, tt.required)
+			if tt.errMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("expected error matching %q", tt.errMatch)
+			} else if !strings.Contains(err.Error(), tt.errMatch) {
+				t.Errorf("error %q doesn't match %q", err, tt.errMatch)
+			} else if !strings.Contains(err.Error(), "Delegate=yes") {
+				t.Errorf("error %q doesn't suggest Delegate=yes", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apptainer/apptainer/internal/pkg/build/sources"
+	"github.com/apptainer/apptainer/internal/pkg/cache"
+	"github.com/apptainer/apptainer/internal/pkg/ociimage"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs"
+	"github.com/apptainer/apptainer/pkg/sylog"
+)
+
+// ErrTooManyLayers is returned by PullLayers when the image has more
+// layers than the maximum number of overlay lower directories.
+var ErrTooManyLayers = errors.New("too many layers")
+
+// PullLayers fetches the OCI image pullFrom into the cache and unpacks each
+// of its layers into a separate directory of the layer cache. It returns the
+// layer directories ordered from the bottom to the top layer, the top one
+// holding the Apptainer metadata generated from the image configuration,
+// so the first one can be used as the root filesystem and the others as
+// overlay lower directories. ErrTooManyLayers is returned when more than
+// maxLayers directories would be stacked on top of the root filesystem.
+func PullLayers(ctx context.Context, imgCache *cache.Handle, pullFrom string, opts PullOptions, maxLayers int) ([]string, error) {
+	if imgCache == nil || imgCache.IsDisabled() {
+		return nil, fmt.Errorf("OCI layers can't be used with the cache disabled")
+	}
+
+	cacheDir, err := imgCache.GetDirCacheDir(cache.OciLayerCacheType)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(cacheDir, 0o700); err != nil {
+		return nil, fmt.Errorf("while creating layer cache directory: %w", err)
+	}
+
+	img, err := ociimage.FetchToLayout(ctx, transportOptions(opts), imgCache, pullFrom, opts.TmpDir)
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+	// the metadata directory is stacked on top of the layers
+	if len(layers) > maxLayers {
+		return nil, fmt.Errorf("%w: image has %d layers, at most %d can be stacked", ErrTooManyLayers, len(layers), maxLayers)
+	}
+
+	dirs := make([]string, 0, len(layers)+1)
+	for _, layer := range layers {
+		mt, err := layer.MediaType()
+		if err != nil {
+			return nil, err
+		}
+		if !mt.IsLayer() {
+			continue
+		}
+		diffID, err := layer.DiffID()
+		if err != nil {
+			return nil, err
+		}
+		dir, err := cachedLayerDir(cacheDir, diffID.Hex, func(dir string) error {
+			sylog.Debugf("Unpacking layer %s", diffID)
+			return sources.UnpackLayer(ctx, layer, dir)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("while unpacking layer %s: %w", diffID, err)
+		}
+		dirs = append(dirs, dir)
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no extractable OCI/Docker tar layers found in this image")
+	}
+
+	digest, err := img.ConfigName()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := cachedLayerDir(cacheDir, "config-"+digest.Hex, func(dir string) error {
+		return sources.InsertOCIMetadata(img, dir)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("while generating image metadata: %w", err)
+	}
+
+	return append(dirs, dir), nil
+}
+
+// cachedLayerDir returns the directory name within cacheDir, populating it
+// with fn first if it doesn't exist. The directory is populated in a
+// temporary directory renamed once complete, so concurrent pulls of the
+// same layer never see a partial directory.
+func cachedLayerDir(cacheDir, name string, fn func(dir string) error) (string, error) {
+	dir := filepath.Join(cacheDir, name)
+	if fs.IsDir(dir) {
+		return dir, nil
+	}
+
+	tmpDir, err := os.MkdirTemp(cacheDir, "tmp_")
+	if err != nil {
+		return "", err
+	}
+	if err := fn(tmpDir); err != nil {
+		fs.ForceRemoveAll(tmpDir)
+		return "", err
+	}
+	// the temporary directory is created with a restrictive mode
+	if err := os.Chmod(tmpDir, 0o755); err != nil {
+		fs.ForceRemoveAll(tmpDir)
+		return "", err
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		fs.ForceRemoveAll(tmpDir)
+		// another process may have populated it concurrently
+		if fs.IsDir(dir) {
+			return dir, nil
+		}
+		return "", err
+	}
+	return dir, nil
+}
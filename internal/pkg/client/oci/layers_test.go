@@ -0,0 +1,54 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCachedLayerDir(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	calls := 0
+	populate := func(dir string) error {
+		calls++
+		return os.WriteFile(filepath.Join(dir, "file"), []byte("layer"), 0o644)
+	}
+
+	for i := 0; i < 2; i++ {
+		dir, err := cachedLayerDir(cacheDir, "layer", populate)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if dir != filepath.Join(cacheDir, "layer") {
+			t.Errorf("unexpected directory %s", dir)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "file")); err != nil {
+			t.Errorf("layer content not found: %s", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("layer populated %d times, expected once", calls)
+	}
+
+	errPopulate := errors.New("populate error")
+	if _, err := cachedLayerDir(cacheDir, "failed", func(string) error { return errPopulate }); !errors.Is(err, errPopulate) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary directory left in the cache: %v", entries)
+	}
+}
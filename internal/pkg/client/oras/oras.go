@@ -14,9 +14,11 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/apptainer/apptainer/internal/pkg/client"
@@ -27,80 +29,29 @@ import (
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
-	"github.com/google/go-containerregistry/pkg/v1/empty"
-	"github.com/google/go-containerregistry/pkg/v1/layout"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	"golang.org/x/term"
 )
 
-// DownloadImage downloads a SIF image specified by an oci reference to a file using the included credentials
-func DownloadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string) error {
-	rt := client.NewRoundTripper(ctx, nil)
-	im, err := remoteImage(ctx, ref, ociAuth, noHTTPS, rt, reqAuthFile)
+// DownloadImage downloads a SIF image specified by an oci reference to a file using the included credentials.
+// If resume is true, a partial download left by a previous interrupted pull of the same image is completed.
+func DownloadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string, resume bool) error {
+	ir, layer, err := sifLayer(ctx, ref, ociAuth, noHTTPS, reqAuthFile)
 	if err != nil {
-		rt.ProgressShutdown()
 		return err
 	}
+	return downloadImage(ctx, ir, layer, path, partialPath(path, layer.Digest), ociAuth, reqAuthFile, resume)
+}
 
-	// Check manifest to ensure we have a SIF as single layer
-	//
-	// We *don't* check the image config mediaType as prior versions of
-	// Apptainer have not been consistent in setting this, and really all we
-	// care about is that we are pulling a single SIF file.
-	//
-	manifest, err := im.Manifest()
-	if err != nil {
-		rt.ProgressShutdown()
-		return err
-	}
-	if len(manifest.Layers) != 1 {
-		return fmt.Errorf("ORAS SIF image should have a single layer, found %d", len(manifest.Layers))
-	}
-	layer := manifest.Layers[0]
-	if layer.MediaType != SifLayerMediaTypeV1 &&
-		layer.MediaType != SifLayerMediaTypeProto {
-		rt.ProgressShutdown()
-		return fmt.Errorf("invalid layer mediatype: %s", layer.MediaType)
-	}
-
-	// Retrieve image to a temporary OCI layout
-	tmpDir, err := os.MkdirTemp("", "oras-tmp-")
-	if err != nil {
-		rt.ProgressShutdown()
-		return err
-	}
-	defer func() {
-		if err := os.RemoveAll(tmpDir); err != nil {
-			sylog.Errorf("while removing %q: %v", tmpDir, err)
-		}
-	}()
-	tmpLayout, err := layout.Write(tmpDir, empty.Index)
-	if err != nil {
-		rt.ProgressShutdown()
-		return err
-	}
-	if err := tmpLayout.AppendImage(im); err != nil {
-		rt.ProgressShutdown()
-		return err
-	}
-
-	rt.ProgressComplete()
-	rt.ProgressWait()
-
-	// Copy SIF blob out from layout to final location
-	blob, err := tmpLayout.Blob(layer.Digest)
+// downloadImage downloads the SIF layer of the image ir to path, using partial
+// to store the data while the download is in progress.
+func downloadImage(ctx context.Context, ir name.Reference, layer v1.Descriptor, path, partial string, ociAuth *authn.AuthConfig, reqAuthFile string, resume bool) error {
+	rt, err := registryTransport(ctx, ir.Context(), ociAuth, reqAuthFile, transport.PullScope)
 	if err != nil {
 		return err
 	}
-	defer blob.Close()
-	outFile, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer outFile.Close()
-
-	_, err = io.Copy(outFile, blob)
-	if err != nil {
+	if err := downloadBlob(ctx, rt, ir.Context(), layer, path, partial, resume); err != nil {
 		return err
 	}
 
@@ -113,9 +64,19 @@ func DownloadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthCon
 	return nil
 }
 
+// partialPath returns the path of the partial download of the blob digest to path.
+func partialPath(path string, digest v1.Hash) string {
+	hex := digest.Hex
+	if len(hex) > 12 {
+		hex = hex[:12]
+	}
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+"."+hex+partialSuffix)
+}
+
 // UploadImage uploads the image specified by path and pushes it to the provided oci reference,
-// it will use credentials if supplied
-func UploadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string) error {
+// it will use credentials if supplied. If resume is true, the SIF is uploaded in chunks and an
+// interrupted upload of the same image is resumed where the registry supports it.
+func UploadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string, resume bool) error {
 	// ensure that are uploading a SIF
 	if err := ensureSIF(path); err != nil {
 		return err
@@ -139,6 +100,13 @@ func UploadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthConfi
 		return err
 	}
 
+	if resume {
+		// upload the SIF layer first, remote.Write skips existing layers
+		if err := uploadLayer(ctx, ir.Context(), im, path, ociAuth, reqAuthFile); err != nil {
+			return err
+		}
+	}
+
 	remoteOpts := []remote.Option{
 		ociauth.AuthOptn(ociAuth, reqAuthFile),
 		remote.WithUserAgent(useragent.Value()),
@@ -175,6 +143,28 @@ func UploadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthConfi
 	return remote.Write(ir, im, remoteOpts...)
 }
 
+// uploadLayer uploads the SIF layer of im with a resumable chunked upload. If
+// the registry doesn't support chunked uploads, the layer is left to be
+// uploaded by remote.Write.
+func uploadLayer(ctx context.Context, repo name.Repository, im *SifImage, path string, ociAuth *authn.AuthConfig, reqAuthFile string) error {
+	manifest, err := im.Manifest()
+	if err != nil {
+		return err
+	}
+	rt, err := registryTransport(ctx, repo, ociAuth, reqAuthFile, transport.PushScope)
+	if err != nil {
+		return err
+	}
+
+	err = uploadBlob(ctx, rt, repo, manifest.Layers[0], path)
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		sylog.Warningf("Registry doesn't support resumable uploads, falling back to a single upload: %s", err)
+		return nil
+	}
+	return err
+}
+
 // ensureSIF checks for a SIF image at filepath and returns an error if it is not, or an error is encountered
 func ensureSIF(filepath string) error {
 	img, err := image.Init(filepath, false)
@@ -192,27 +182,41 @@ func ensureSIF(filepath string) error {
 
 // RefHash returns the digest of the SIF layer of the OCI manifest for supplied ref
 func RefHash(ctx context.Context, ref string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string) (v1.Hash, error) {
-	im, err := remoteImage(ctx, ref, ociAuth, noHTTPS, nil, reqAuthFile)
+	_, layer, err := sifLayer(ctx, ref, ociAuth, noHTTPS, reqAuthFile)
 	if err != nil {
 		return v1.Hash{}, err
 	}
+	return layer.Digest, nil
+}
+
+// sifLayer returns the parsed reference and the descriptor of the SIF layer of
+// the OCI manifest for supplied ref.
+func sifLayer(ctx context.Context, ref string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string) (name.Reference, v1.Descriptor, error) {
+	ir, im, err := remoteImage(ctx, ref, ociAuth, noHTTPS, reqAuthFile)
+	if err != nil {
+		return nil, v1.Descriptor{}, err
+	}
 
 	// Check manifest to ensure we have a SIF as single layer
+	//
+	// We *don't* check the image config mediaType as prior versions of
+	// Apptainer have not been consistent in setting this, and really all we
+	// care about is that we are pulling a single SIF file.
+	//
 	manifest, err := im.Manifest()
 	if err != nil {
-		return v1.Hash{}, err
+		return nil, v1.Descriptor{}, err
 	}
 	if len(manifest.Layers) != 1 {
-		return v1.Hash{}, fmt.Errorf("ORAS SIF image should have a single layer, found %d", len(manifest.Layers))
+		return nil, v1.Descriptor{}, fmt.Errorf("ORAS SIF image should have a single layer, found %d", len(manifest.Layers))
 	}
 	layer := manifest.Layers[0]
 	if layer.MediaType != SifLayerMediaTypeV1 &&
 		layer.MediaType != SifLayerMediaTypeProto {
-		return v1.Hash{}, fmt.Errorf("invalid layer mediatype: %s", layer.MediaType)
+		return nil, v1.Descriptor{}, fmt.Errorf("invalid layer mediatype: %s", layer.MediaType)
 	}
 
-	hash := layer.Digest
-	return hash, nil
+	return ir, layer, nil
 }
 
 // ImageDigest returns the digest for a file
@@ -249,8 +253,8 @@ func sha256sum(r io.Reader) (result string, nBytes int64, err error) {
 	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nBytes, nil
 }
 
-// remoteImage returns a v1.Image for the provided remote ref.
-func remoteImage(ctx context.Context, ref string, ociAuth *authn.AuthConfig, noHTTPS bool, rt *client.RoundTripper, reqAuthFile string) (v1.Image, error) {
+// remoteImage returns the parsed reference and a v1.Image for the provided remote ref.
+func remoteImage(ctx context.Context, ref string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string) (name.Reference, v1.Image, error) {
 	ref = strings.TrimPrefix(ref, "oras://")
 	ref = strings.TrimPrefix(ref, "//")
 
@@ -261,18 +265,15 @@ func remoteImage(ctx context.Context, ref string, ociAuth *authn.AuthConfig, noH
 	}
 	ir, err := name.ParseReference(ref, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("invalid reference %q: %w", ref, err)
+		return nil, nil, fmt.Errorf("invalid reference %q: %w", ref, err)
 	}
 	remoteOpts := []remote.Option{
 		ociauth.AuthOptn(ociAuth, reqAuthFile),
 		remote.WithContext(ctx),
 	}
-	if rt != nil {
-		remoteOpts = append(remoteOpts, remote.WithTransport(rt))
-	}
 	im, err := remote.Image(ir, remoteOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return im, nil
+	return ir, im, nil
 }
@@ -22,15 +22,17 @@ import (
 )
 
 // pull will pull an oras image into the cache if directTo="", or a specific file if directTo is set.
-func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string) (imagePath string, err error) {
-	hash, err := RefHash(ctx, pullFrom, ociAuth, noHTTPS, reqAuthFile)
+// If resume is true, a partial download left by a previous interrupted pull of the same image is completed.
+func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string, resume bool) (imagePath string, err error) {
+	ir, layer, err := sifLayer(ctx, pullFrom, ociAuth, noHTTPS, reqAuthFile)
 	if err != nil {
 		return "", fmt.Errorf("failed to get checksum for %s: %s", pullFrom, err)
 	}
+	hash := layer.Digest
 
 	if directTo != "" {
 		sylog.Infof("Downloading oras image")
-		if err := DownloadImage(ctx, directTo, pullFrom, ociAuth, noHTTPS, reqAuthFile); err != nil {
+		if err := downloadImage(ctx, ir, layer, directTo, partialPath(directTo, hash), ociAuth, reqAuthFile, resume); err != nil {
 			return "", fmt.Errorf("unable to Download Image: %v", err)
 		}
 		imagePath = directTo
@@ -44,14 +46,10 @@ func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string
 		if !cacheEntry.Exists {
 			sylog.Infof("Downloading oras image")
 
-			if err := DownloadImage(ctx, cacheEntry.TmpPath, pullFrom, ociAuth, noHTTPS, reqAuthFile); err != nil {
+			// the downloaded data are verified against the layer digest
+			if err := downloadImage(ctx, ir, layer, cacheEntry.TmpPath, cacheEntry.Path+partialSuffix, ociAuth, reqAuthFile, resume); err != nil {
 				return "", fmt.Errorf("unable to Download Image: %v", err)
 			}
-			if cacheFileHash, err := ImageHash(cacheEntry.TmpPath); err != nil {
-				return "", fmt.Errorf("error getting ImageHash: %v", err)
-			} else if cacheFileHash != hash {
-				return "", fmt.Errorf("cached file hash(%s) and expected hash(%s) does not match", cacheFileHash, hash)
-			}
 
 			err = cacheEntry.Finalize()
 			if err != nil {
@@ -80,18 +78,19 @@ func Pull(ctx context.Context, imgCache *cache.Handle, pullFrom, tmpDir string,
 		sylog.Infof("Downloading oras image to tmp cache: %s", directTo)
 	}
 
-	return pull(ctx, imgCache, directTo, pullFrom, ociAuth, noHTTPS, reqAuthFile)
+	return pull(ctx, imgCache, directTo, pullFrom, ociAuth, noHTTPS, reqAuthFile, true)
 }
 
-// PullToFile will pull an oras image to the specified location, through the cache, or directly if cache is disabled
-func PullToFile(ctx context.Context, imgCache *cache.Handle, pullTo, pullFrom string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string, sandbox bool) (imagePath string, err error) {
+// PullToFile will pull an oras image to the specified location, through the cache, or directly if cache is disabled.
+// If resume is true, a partial download left by a previous interrupted pull of the same image is completed.
+func PullToFile(ctx context.Context, imgCache *cache.Handle, pullTo, pullFrom string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string, sandbox, resume bool) (imagePath string, err error) {
 	directTo := ""
 	if imgCache.IsDisabled() {
 		directTo = pullTo
 		sylog.Debugf("Cache disabled, pulling directly to: %s", directTo)
 	}
 
-	src, err := pull(ctx, imgCache, directTo, pullFrom, ociAuth, noHTTPS, reqAuthFile)
+	src, err := pull(ctx, imgCache, directTo, pullFrom, ociAuth, noHTTPS, reqAuthFile, resume)
 	if err != nil {
 		return "", fmt.Errorf("error fetching image to cache: %v", err)
 	}
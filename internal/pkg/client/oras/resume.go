@@ -0,0 +1,380 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oras
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/apptainer/apptainer/internal/pkg/client"
+	"github.com/apptainer/apptainer/internal/pkg/util/ociauth"
+	"github.com/apptainer/apptainer/pkg/syfs"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	useragent "github.com/apptainer/apptainer/pkg/util/user-agent"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// partialSuffix is appended to the path of an incomplete download.
+const partialSuffix = ".partial"
+
+// uploadChunkSize is the size of the chunks sent by resumable uploads.
+var uploadChunkSize = 64 << 20
+
+// uploadState is the state of an interrupted upload, saved to resume it.
+type uploadState struct {
+	Repository string `json:"repository"`
+	Digest     string `json:"digest"`
+	Location   string `json:"location"`
+}
+
+// registryTransport returns a transport to the registry of repo authenticated
+// for the scope action.
+func registryTransport(ctx context.Context, repo name.Repository, ociAuth *authn.AuthConfig, reqAuthFile, action string) (http.RoundTripper, error) {
+	auth, err := ociauth.Authenticator(ociAuth, reqAuthFile, repo)
+	if err != nil {
+		return nil, err
+	}
+	rt := transport.NewUserAgent(http.DefaultTransport, useragent.Value())
+	return transport.NewWithContext(ctx, repo.Registry, auth, rt, []string{repo.Scope(action)})
+}
+
+// registryURL returns the URL of the registry API endpoint path of repo.
+func registryURL(repo name.Repository, path string) string {
+	return fmt.Sprintf("%s://%s/v2/%s/%s", repo.Registry.Scheme(), repo.RegistryStr(), repo.RepositoryStr(), path)
+}
+
+// newHash returns a hash for the digest algorithm of h.
+func newHash(h v1.Hash) (hash.Hash, error) {
+	if h.Algorithm != "sha256" {
+		return nil, fmt.Errorf("unsupported digest algorithm %s", h.Algorithm)
+	}
+	return sha256.New(), nil
+}
+
+// downloadBlob downloads the blob described by desc from repo to path. Data
+// are first written to partial, when resume is true an existing partial file
+// is completed with a ranged request instead of downloading the blob again.
+// The downloaded data are verified against the blob digest before partial is
+// renamed to path.
+func downloadBlob(ctx context.Context, rt http.RoundTripper, repo name.Repository, desc v1.Descriptor, path, partial string, resume bool) error {
+	h, err := newHash(desc.Digest)
+	if err != nil {
+		return err
+	}
+
+	if !resume {
+		if err := os.Remove(partial); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("while removing partial download %s: %w", partial, err)
+		}
+	}
+
+	f, err := os.OpenFile(partial, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if offset > desc.Size {
+		offset = 0
+	}
+	if offset > 0 {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(h, f, offset); err != nil {
+			return fmt.Errorf("while reading partial download %s: %w", partial, err)
+		}
+	}
+
+	if offset < desc.Size {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, registryURL(repo, "blobs/"+desc.Digest.String()), nil)
+		if err != nil {
+			return err
+		}
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, desc.Size-1))
+		}
+
+		resp, err := (&http.Client{Transport: rt}).Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if err := transport.CheckError(resp, http.StatusOK, http.StatusPartialContent); err != nil {
+			return err
+		}
+		if offset > 0 && resp.StatusCode == http.StatusPartialContent {
+			sylog.Infof("Resuming download at %d/%d bytes", offset, desc.Size)
+		} else {
+			// the whole blob is sent
+			offset = 0
+			h.Reset()
+		}
+		if err := f.Truncate(offset); err != nil {
+			return err
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+
+		progress := client.ProgressBarCallback(ctx)
+		if err := progress(desc.Size-offset, resp.Body, io.MultiWriter(f, h)); err != nil {
+			return fmt.Errorf("download interrupted, partial data kept in %s: %w", partial, err)
+		}
+	}
+
+	digest := desc.Digest.Algorithm + ":" + hex.EncodeToString(h.Sum(nil))
+	if digest != desc.Digest.String() {
+		os.Remove(partial)
+		return fmt.Errorf("downloaded data digest %s doesn't match expected digest %s", digest, desc.Digest)
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partial, path)
+}
+
+// uploadStatePath returns the path of the file storing the state of the
+// upload of digest to repo.
+func uploadStatePath(repo name.Repository, digest v1.Hash) string {
+	sum := sha256.Sum256([]byte(repo.Name() + "@" + digest.String()))
+	return filepath.Join(syfs.ConfigDir(), "uploads", hex.EncodeToString(sum[:16])+".json")
+}
+
+// readUploadState returns the saved location of an interrupted upload of
+// digest to repo, or an empty string if there is none.
+func readUploadState(path string, repo name.Repository, digest v1.Hash) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var s uploadState
+	if err := json.Unmarshal(b, &s); err != nil || s.Repository != repo.Name() || s.Digest != digest.String() {
+		return ""
+	}
+	return s.Location
+}
+
+// writeUploadState saves the location of the upload of digest to repo.
+func writeUploadState(path string, repo name.Repository, digest v1.Hash, location string) error {
+	b, err := json.Marshal(uploadState{
+		Repository: repo.Name(),
+		Digest:     digest.String(),
+		Location:   location,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o600)
+}
+
+// resolveLocation returns the absolute URL of the Location header of resp.
+func resolveLocation(resp *http.Response) (string, error) {
+	loc, err := resp.Location()
+	if err != nil {
+		return "", fmt.Errorf("registry returned no upload location: %w", err)
+	}
+	return loc.String(), nil
+}
+
+// uploadOffset returns the number of bytes received by the registry for the
+// upload session at location.
+func uploadOffset(ctx context.Context, hc *http.Client, location string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if err := transport.CheckError(resp, http.StatusNoContent); err != nil {
+		return 0, err
+	}
+	// the Range header is inclusive, in 0-<last byte> format
+	r := resp.Header.Get("Range")
+	if r == "" {
+		return 0, nil
+	}
+	_, end, ok := strings.Cut(r, "-")
+	if !ok {
+		return 0, fmt.Errorf("bad upload range %q", r)
+	}
+	last, err := strconv.ParseInt(end, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad upload range %q: %w", r, err)
+	}
+	return last + 1, nil
+}
+
+// uploadBlob uploads the file at path as the blob described by desc to repo
+// with a chunked upload. The upload session of a previous interrupted upload
+// of the same blob is resumed from the last chunk received by the registry. The registry verifies the uploaded data against the blob
+// digest when the upload is completed.
+//
+//nolint:funlen
+func uploadBlob(ctx context.Context, rt http.RoundTripper, repo name.Repository, desc v1.Descriptor, path string) error {
+	hc := &http.Client{Transport: rt}
+
+	// nothing to do if the registry already has the blob
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, registryURL(repo, "blobs/"+desc.Digest.String()), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		sylog.Debugf("Blob %s already exists in %s", desc.Digest, repo)
+		return nil
+	}
+
+	statePath := uploadStatePath(repo, desc.Digest)
+	location := readUploadState(statePath, repo, desc.Digest)
+	offset := int64(0)
+
+	if location != "" {
+		offset, err = uploadOffset(ctx, hc, location)
+		if err != nil || offset > desc.Size {
+			sylog.Debugf("Could not resume upload session: %v", err)
+			location, offset = "", 0
+		} else {
+			sylog.Infof("Resuming upload at %d/%d bytes", offset, desc.Size)
+		}
+	}
+	if location == "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, registryURL(repo, "blobs/uploads/"), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := hc.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if err := transport.CheckError(resp, http.StatusAccepted); err != nil {
+			return err
+		}
+		if location, err = resolveLocation(resp); err != nil {
+			return err
+		}
+	}
+	if err := writeUploadState(statePath, repo, desc.Digest, location); err != nil {
+		sylog.Warningf("Could not save upload state, upload won't be resumable: %s", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	pb := &client.UploadProgressBar{}
+	pb.InitUpload(desc.Size-offset, f)
+	r := pb.GetReader()
+
+	buf := make([]byte, uploadChunkSize)
+	for offset < desc.Size {
+		n, err := io.ReadFull(r, buf)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			pb.Terminate()
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPatch, location, bytes.NewReader(buf[:n]))
+		if err != nil {
+			pb.Terminate()
+			return err
+		}
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set("Content-Range", fmt.Sprintf("%d-%d", offset, offset+int64(n)-1))
+		req.ContentLength = int64(n)
+
+		resp, err := hc.Do(req)
+		if err != nil {
+			pb.Terminate()
+			return fmt.Errorf("upload interrupted, run the command again to resume it: %w", err)
+		}
+		resp.Body.Close()
+		if err := transport.CheckError(resp, http.StatusAccepted, http.StatusNoContent); err != nil {
+			pb.Terminate()
+			return err
+		}
+		if location, err = resolveLocation(resp); err != nil {
+			pb.Terminate()
+			return err
+		}
+		offset += int64(n)
+
+		if err := writeUploadState(statePath, repo, desc.Digest, location); err != nil {
+			sylog.Debugf("Could not save upload state: %s", err)
+		}
+	}
+	pb.Finish()
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("digest", desc.Digest.String())
+	u.RawQuery = q.Encode()
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodPut, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err = hc.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusCreated); err != nil {
+		return err
+	}
+
+	if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+		sylog.Debugf("Could not remove upload state %s: %s", statePath, err)
+	}
+	return nil
+}
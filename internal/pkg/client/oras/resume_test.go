@@ -0,0 +1,141 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oras
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestResumableTransfer(t *testing.T) {
+	t.Setenv("APPTAINER_CONFIGDIR", t.TempDir())
+
+	s := httptest.NewServer(registry.New())
+	defer s.Close()
+
+	repo, err := name.NewRepository(strings.TrimPrefix(s.URL, "http://")+"/test/resume", name.Insecure)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// send the blob in several chunks
+	chunkSize := uploadChunkSize
+	uploadChunkSize = 1024
+	defer func() { uploadChunkSize = chunkSize }()
+
+	data := make([]byte, 3*uploadChunkSize+100)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.WriteFile(src, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	digest, size, err := v1.SHA256(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc := v1.Descriptor{Digest: digest, Size: size}
+
+	ctx := context.Background()
+	rt := http.DefaultTransport
+
+	if err := uploadBlob(ctx, rt, repo, desc, src); err != nil {
+		t.Fatalf("unexpected upload error: %s", err)
+	}
+	if _, err := os.Stat(uploadStatePath(repo, digest)); !os.IsNotExist(err) {
+		t.Errorf("upload state not removed after completed upload")
+	}
+	// the blob exists now
+	if err := uploadBlob(ctx, rt, repo, desc, src); err != nil {
+		t.Fatalf("unexpected upload error for existing blob: %s", err)
+	}
+
+	dst := filepath.Join(dir, "dst")
+	partial := partialPath(dst, digest)
+
+	tests := []struct {
+		name      string
+		partial   []byte
+		resume    bool
+		expectErr bool
+	}{
+		{
+			name:   "NoPartial",
+			resume: true,
+		},
+		{
+			name:    "ResumePartial",
+			partial: data[:len(data)/2],
+			resume:  true,
+		},
+		{
+			name:    "CompletePartial",
+			partial: data,
+			resume:  true,
+		},
+		{
+			name:      "ResumeCorruptedPartial",
+			partial:   bytes.Repeat([]byte{'x'}, 100),
+			resume:    true,
+			expectErr: true,
+		},
+		{
+			name:    "NoResumeCorruptedPartial",
+			partial: bytes.Repeat([]byte{'x'}, 100),
+			resume:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(dst)
+			if tt.partial != nil {
+				if err := os.WriteFile(partial, tt.partial, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := downloadBlob(ctx, rt, repo, desc, dst, partial, tt.resume)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if _, err := os.Stat(partial); !os.IsNotExist(err) {
+				t.Errorf("partial download %s not removed", partial)
+			}
+			if tt.expectErr {
+				return
+			}
+
+			b, err := os.ReadFile(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, data) {
+				t.Errorf("downloaded data doesn't match uploaded data")
+			}
+		})
+	}
+}
@@ -387,6 +387,39 @@ func GetIDRange(path string, uid uint32) (*specs.LinuxIDMapping, error) {
 	}, nil
 }
 
+// GetKeepIDMappings returns the ID mappings used to keep the identity
+// of the user inside the container: the given id is mapped onto itself
+// while container IDs below and above it are mapped onto the subordinate
+// ID range returned by GetIDRange.
+func GetKeepIDMappings(id uint32, idRange *specs.LinuxIDMapping) []specs.LinuxIDMapping {
+	below := id
+	if below > idRange.Size {
+		below = idRange.Size
+	}
+
+	mappings := make([]specs.LinuxIDMapping, 0, 3)
+	if below > 0 {
+		mappings = append(mappings, specs.LinuxIDMapping{
+			ContainerID: 0,
+			HostID:      idRange.HostID,
+			Size:        below,
+		})
+	}
+	mappings = append(mappings, specs.LinuxIDMapping{
+		ContainerID: id,
+		HostID:      id,
+		Size:        1,
+	})
+	if idRange.Size > below {
+		mappings = append(mappings, specs.LinuxIDMapping{
+			ContainerID: id + 1,
+			HostID:      idRange.HostID + below,
+			Size:        idRange.Size - below,
+		})
+	}
+	return mappings
+}
+
 // IsUIDMapped returns true if the given uid is mapped in SubUIDFile
 // and otherwise it returns false
 func IsUIDMapped(uid uint32) bool {
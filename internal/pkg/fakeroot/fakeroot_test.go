@@ -497,6 +497,58 @@ func testEditEntry(t *testing.T, config *Config) {
 	}
 }
 
+func TestGetKeepIDMappings(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       uint32
+		idRange  specs.LinuxIDMapping
+		expected []specs.LinuxIDMapping
+	}{
+		{
+			name:    "id within range",
+			id:      1000,
+			idRange: specs.LinuxIDMapping{ContainerID: 1, HostID: 100000, Size: 65536},
+			expected: []specs.LinuxIDMapping{
+				{ContainerID: 0, HostID: 100000, Size: 1000},
+				{ContainerID: 1000, HostID: 1000, Size: 1},
+				{ContainerID: 1001, HostID: 101000, Size: 64536},
+			},
+		},
+		{
+			name:    "id above range",
+			id:      100000,
+			idRange: specs.LinuxIDMapping{ContainerID: 1, HostID: 200000, Size: 65536},
+			expected: []specs.LinuxIDMapping{
+				{ContainerID: 0, HostID: 200000, Size: 65536},
+				{ContainerID: 100000, HostID: 100000, Size: 1},
+			},
+		},
+		{
+			name:    "id zero",
+			id:      0,
+			idRange: specs.LinuxIDMapping{ContainerID: 1, HostID: 100000, Size: 65536},
+			expected: []specs.LinuxIDMapping{
+				{ContainerID: 0, HostID: 0, Size: 1},
+				{ContainerID: 1, HostID: 100000, Size: 65536},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappings := GetKeepIDMappings(tt.id, &tt.idRange)
+			if len(mappings) != len(tt.expected) {
+				t.Fatalf("unexpected mappings: got %v, expected %v", mappings, tt.expected)
+			}
+			for i := range mappings {
+				if mappings[i] != tt.expected[i] {
+					t.Errorf("unexpected mapping %d: got %v, expected %v", i, mappings[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
 func TestConfig(t *testing.T) {
 	test.DropPrivilege(t)
 	defer test.ResetPrivilege(t)
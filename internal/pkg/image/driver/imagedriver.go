@@ -24,6 +24,7 @@ import (
 
 	"github.com/apptainer/apptainer/internal/pkg/buildcfg"
 	"github.com/apptainer/apptainer/internal/pkg/util/bin"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs/mount"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs/squashfs"
 	"github.com/apptainer/apptainer/pkg/image"
 	"github.com/apptainer/apptainer/pkg/sylog"
@@ -51,6 +52,7 @@ type fuseappsInstance struct {
 type fuseappsFeature struct {
 	binName   string
 	cmdPath   string
+	err       error
 	instances []*fuseappsInstance
 }
 
@@ -59,6 +61,7 @@ type fuseappsDriver struct {
 	ext3Feature    fuseappsFeature
 	overlayFeature fuseappsFeature
 	gocryptFeature fuseappsFeature
+	erofsFeature   fuseappsFeature
 	features       image.DriverFeature
 	cmdPrefix      []string
 	squashSetUID   bool
@@ -81,12 +84,26 @@ func (f *fuseappsFeature) init(binNames string, purpose string, desired image.Dr
 		sylog.Debugf("%v mounting not enabled because: %v", f.binName, err)
 		if desired != 0 {
 			sylog.Infof("%v not found, will not be able to %v", f.binName, purpose)
+			// keep track of the missing program to report it
+			// from Available
+			f.err = err
 		}
 		return false
 	}
 	return true
 }
 
+// preferFuseSquash is set when squashfs images must be mounted with
+// squashfuse even if kernel squashfs mounts are allowed.
+var preferFuseSquash bool
+
+// PreferFuseSquash makes the driver handle squashfs image mounts with
+// squashfuse in place of kernel loop mounts when available, it must be
+// called before InitImageDrivers.
+func PreferFuseSquash() {
+	preferFuseSquash = true
+}
+
 func InitImageDrivers(register, unprivileged bool, fileconf *apptainerconf.File, desiredFeatures image.DriverFeature) error {
 	if fileconf.ImageDriver != "" && fileconf.ImageDriver != DriverName {
 		sylog.Debugf("Skipping installing %v image driver because %v already configured", DriverName, fileconf.ImageDriver)
@@ -104,13 +121,14 @@ func InitImageDrivers(register, unprivileged bool, fileconf *apptainerconf.File,
 	var ext3Feature fuseappsFeature
 	var overlayFeature fuseappsFeature
 	var gocryptFeature fuseappsFeature
+	var erofsFeature fuseappsFeature
 	var features image.DriverFeature
 	// Always initialize the SquashFeature because it is needed by
 	// the GocryptFeature which can be used even in privileged mode.
 	// However, only indicate that it is available when it is needed
 	// for other reasons, because when it is marked as available it
 	// takes precedence over the kernel squashfs.
-	if unprivileged || !squashfs.SetuidMountAllowed(fileconf) {
+	if unprivileged || preferFuseSquash || !squashfs.SetuidMountAllowed(fileconf) {
 		if squashFeature.init("squashfuse_ll|squashfuse", "mount SIF or other squashfs files", desiredFeatures&image.SquashFeature) {
 			features |= image.SquashFeature
 		}
@@ -122,6 +140,12 @@ func InitImageDrivers(register, unprivileged bool, fileconf *apptainerconf.File,
 			features |= image.Ext3Feature
 		}
 	}
+	// The kernel EROFS mount follows the same setuid policy as squashfs
+	if unprivileged || !squashfs.SetuidMountAllowed(fileconf) {
+		if erofsFeature.init("erofsfuse", "mount EROFS files", desiredFeatures&image.ErofsFeature) {
+			features |= image.ErofsFeature
+		}
+	}
 	// Always initialize the OverlayFeature because the kernel overlay
 	// doesn't like using FUSE for lower or upper layers.
 	if overlayFeature.init("fuse-overlayfs", "use FUSE overlay", desiredFeatures&image.OverlayFeature) {
@@ -163,7 +187,7 @@ func InitImageDrivers(register, unprivileged bool, fileconf *apptainerconf.File,
 		_ = cmd.Wait()
 	}
 
-	if squashFeature.cmdPath != "" || ext3Feature.cmdPath != "" || overlayFeature.cmdPath != "" || gocryptFeature.cmdPath != "" {
+	if squashFeature.cmdPath != "" || ext3Feature.cmdPath != "" || overlayFeature.cmdPath != "" || gocryptFeature.cmdPath != "" || erofsFeature.cmdPath != "" {
 		sylog.Debugf("Setting ImageDriver to %v", DriverName)
 		fileconf.ImageDriver = DriverName
 		if register {
@@ -172,6 +196,7 @@ func InitImageDrivers(register, unprivileged bool, fileconf *apptainerconf.File,
 				ext3Feature:    ext3Feature,
 				overlayFeature: overlayFeature,
 				gocryptFeature: gocryptFeature,
+				erofsFeature:   erofsFeature,
 				features:       features,
 				cmdPrefix:      []string{},
 				squashSetUID:   squashSetUID,
@@ -189,6 +214,33 @@ func (d *fuseappsDriver) Features() image.DriverFeature {
 	return d.features
 }
 
+// Available checks that the programs of the features required by the
+// caller of InitImageDrivers were found and that the programs of the
+// features supported by the driver can still be executed.
+func (d *fuseappsDriver) Available() error {
+	for _, f := range []struct {
+		feature image.DriverFeature
+		fuseappsFeature
+	}{
+		{image.SquashFeature, d.squashFeature},
+		{image.Ext3Feature, d.ext3Feature},
+		{image.OverlayFeature, d.overlayFeature},
+		{image.GocryptFeature, d.gocryptFeature},
+		{image.ErofsFeature, d.erofsFeature},
+	} {
+		if f.err != nil {
+			return fmt.Errorf("%s program required by the %s image driver not found: %s", f.binName, DriverName, f.err)
+		}
+		if d.features&f.feature == 0 {
+			continue
+		}
+		if err := unix.Access(f.cmdPath, unix.X_OK); err != nil {
+			return fmt.Errorf("%s program %s is not usable: %s", f.binName, f.cmdPath, err)
+		}
+	}
+	return nil
+}
+
 //nolint:maintidx
 func (d *fuseappsDriver) Mount(params *image.MountParams, _ image.MountFunc) error {
 	extraFiles := 0
@@ -231,8 +283,11 @@ func (d *fuseappsDriver) Mount(params *image.MountParams, _ image.MountFunc) err
 		if len(params.FSOptions) > 0 {
 			optsStr += "," + strings.Join(params.FSOptions, ",")
 		}
-		// Ignore xino=on option with fuse-overlayfs
-		optsStr = strings.Replace(optsStr, ",xino=on", "", -1)
+		// Ignore xino=on, metacopy=on and redirect_dir=on options
+		// with fuse-overlayfs
+		for _, o := range mount.OverlayOptionalOptions {
+			optsStr = strings.Replace(optsStr, ","+o, "", -1)
+		}
 		// noacl is needed to avoid failures when the upper layer
 		// filesystem type (for example tmpfs) does not support it,
 		// when the fuse-overlayfs version is 1.8 or greater.
@@ -248,12 +303,23 @@ func (d *fuseappsDriver) Mount(params *image.MountParams, _ image.MountFunc) err
 		if params.Offset > 0 {
 			optsStr += ",offset=" + strconv.FormatUint(params.Offset, 10)
 		}
+		if len(params.FSOptions) > 0 {
+			optsStr += "," + strings.Join(params.FSOptions, ",")
+		}
 		cmdArgs = append(cmdArgs, f.cmdPath, "-f")
 		if optsStr != "" {
 			cmdArgs = append(cmdArgs, "-o", optsStr)
 		}
 		cmdArgs = append(cmdArgs, params.Source, params.Target)
 		cmd = exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	case "erofs":
+		f = &d.erofsFeature
+		cmdArgs = append(cmdArgs, f.cmdPath, "-f", "-o", optsStr)
+		if params.Offset > 0 {
+			cmdArgs = append(cmdArgs, "--offset="+strconv.FormatUint(params.Offset, 10))
+		}
+		cmdArgs = append(cmdArgs, params.Source, params.Target)
+		cmd = exec.Command(cmdArgs[0], cmdArgs[1:]...)
 	case "gocryptfs":
 		f = &d.gocryptFeature
 		cmdArgs = append(cmdArgs, f.cmdPath, "-fg", params.Source, params.Target)
@@ -574,7 +640,7 @@ func (f *fuseappsFeature) waitInstance(instance *fuseappsInstance) error {
 }
 
 func (d *fuseappsDriver) allFeatures() []fuseappsFeature {
-	return []fuseappsFeature{d.squashFeature, d.ext3Feature, d.overlayFeature, d.gocryptFeature}
+	return []fuseappsFeature{d.squashFeature, d.ext3Feature, d.overlayFeature, d.gocryptFeature, d.erofsFeature}
 }
 
 func (d *fuseappsDriver) Stop(target string) error {
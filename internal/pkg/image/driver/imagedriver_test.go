@@ -0,0 +1,94 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apptainer/apptainer/pkg/image"
+	"github.com/apptainer/apptainer/pkg/util/apptainerconf"
+)
+
+func TestAvailable(t *testing.T) {
+	dir := t.TempDir()
+	overlayPath := filepath.Join(dir, "fuse-overlayfs")
+	if err := os.WriteFile(overlayPath, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// only fuse-overlayfs can be found, gocryptfs is missing
+	t.Setenv("PATH", dir)
+	current := apptainerconf.GetCurrentConfig()
+	file, err := apptainerconf.GetConfig(nil)
+	if err != nil {
+		t.Fatalf("failed to get default configuration: %s", err)
+	}
+	file.BinaryPath = dir
+	file.SuidBinaryPath = dir
+	apptainerconf.SetCurrentConfig(file)
+	t.Cleanup(func() { apptainerconf.SetCurrentConfig(current) })
+
+	tests := []struct {
+		name       string
+		desired    image.DriverFeature
+		notExec    bool
+		wantErrStr string
+	}{
+		{
+			name: "MissingNotRequired",
+		},
+		{
+			name:       "MissingRequired",
+			desired:    image.GocryptFeature,
+			wantErrStr: "gocryptfs program required",
+		},
+		{
+			name:       "NotExecutable",
+			notExec:    true,
+			wantErrStr: "fuse-overlayfs program " + overlayPath + " is not usable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var overlayFeature, gocryptFeature fuseappsFeature
+			if !overlayFeature.init("fuse-overlayfs", "use FUSE overlay", tt.desired&image.OverlayFeature) {
+				t.Fatalf("fuse-overlayfs not found in %s", dir)
+			}
+			if gocryptFeature.init("gocryptfs", "use gocryptfs", tt.desired&image.GocryptFeature) {
+				t.Fatalf("unexpected gocryptfs found in %s", dir)
+			}
+			d := &fuseappsDriver{
+				overlayFeature: overlayFeature,
+				gocryptFeature: gocryptFeature,
+				features:       image.OverlayFeature,
+			}
+
+			mode := os.FileMode(0o755)
+			if tt.notExec {
+				mode = 0o644
+			}
+			if err := os.Chmod(overlayPath, mode); err != nil {
+				t.Fatal(err)
+			}
+
+			err := d.Available()
+			if tt.wantErrStr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErrStr) {
+				t.Errorf("got error %v, want %q", err, tt.wantErrStr)
+			}
+		})
+	}
+}
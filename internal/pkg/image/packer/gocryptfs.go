@@ -63,6 +63,9 @@ func (g *Gocryptfs) HasGocryptfs() bool {
 
 func (g *Gocryptfs) init(tmpDir string) (cryptInfo *cryptInfo, err error) {
 	if !g.HasGocryptfs() {
+		if err := image.DriverAvailable(g.driver); err != nil {
+			return nil, fmt.Errorf("imagedriver is not initialized: %s", err)
+		}
 		return nil, fmt.Errorf("imagedriver is not initialized")
 	}
 
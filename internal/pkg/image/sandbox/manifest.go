@@ -0,0 +1,328 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package sandbox provides integrity manifests for sandbox images. A manifest
+// records the type, permissions and content digest of every file of a sandbox
+// to detect files added, removed or modified afterwards.
+package sandbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// ManifestPath is the path of the integrity manifest in a sandbox,
+	// it's always excluded from the manifest itself.
+	ManifestPath = "/.singularity.d/manifest.json"
+
+	manifestVersion = 1
+)
+
+// DefaultExcludes lists the volatile paths excluded from manifests, their
+// content usually changes when running a container.
+var DefaultExcludes = []string{
+	"/dev",
+	"/proc",
+	"/sys",
+	"/run",
+	"/tmp",
+	"/var/tmp",
+	"/var/cache",
+	"/var/log",
+}
+
+// Entry types.
+const (
+	TypeFile    = "file"
+	TypeDir     = "dir"
+	TypeSymlink = "symlink"
+	TypeOther   = "other"
+)
+
+// Entry describes a sandbox file.
+type Entry struct {
+	Path   string      `json:"path"`
+	Type   string      `json:"type"`
+	Mode   fs.FileMode `json:"mode"`
+	Size   int64       `json:"size,omitempty"`
+	Digest string      `json:"digest,omitempty"`
+	Target string      `json:"target,omitempty"`
+}
+
+// Manifest is the list of sandbox files, sorted by path. Paths matching
+// one of the exclude patterns, and everything beneath them, are not listed.
+type Manifest struct {
+	Version  int      `json:"version"`
+	Excludes []string `json:"excludes"`
+	Entries  []Entry  `json:"entries"`
+}
+
+// Change kinds.
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Modified = "modified"
+)
+
+// Change describes a difference between a sandbox and its manifest.
+type Change struct {
+	Kind   string
+	Path   string
+	Reason string
+}
+
+func (c Change) String() string {
+	if c.Reason == "" {
+		return fmt.Sprintf("%s: %s", c.Kind, c.Path)
+	}
+	return fmt.Sprintf("%s: %s (%s)", c.Kind, c.Path, c.Reason)
+}
+
+// checkExcludes returns the cleaned exclude patterns, they must be absolute
+// paths relative to the sandbox root and can contain shell patterns.
+func checkExcludes(excludes []string) ([]string, error) {
+	cleaned := make([]string, 0, len(excludes))
+	for _, e := range excludes {
+		if !strings.HasPrefix(e, "/") {
+			return nil, fmt.Errorf("exclude path %s must be absolute", e)
+		}
+		if _, err := filepath.Match(e, ""); err != nil {
+			return nil, fmt.Errorf("bad exclude pattern %s: %s", e, err)
+		}
+		cleaned = append(cleaned, filepath.Clean(e))
+	}
+	return cleaned, nil
+}
+
+// isExcluded returns whether path matches one of the exclude patterns.
+func isExcluded(path string, excludes []string) bool {
+	if path == ManifestPath {
+		return true
+	}
+	for _, e := range excludes {
+		if path == e {
+			return true
+		}
+		if ok, _ := filepath.Match(e, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fileDigest returns the sha256 digest of the file at path.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Generate returns the manifest of the sandbox at root, excluding paths
+// matching excludes.
+func Generate(root string, excludes []string) (*Manifest, error) {
+	excludes, err := checkExcludes(excludes)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{
+		Version:  manifestVersion,
+		Excludes: excludes,
+		Entries:  make([]Entry, 0),
+	}
+
+	// WalkDir visits files in lexical order
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		path := "/" + rel
+
+		if isExcluded(path, excludes) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		e := Entry{
+			Path: path,
+			Mode: fi.Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky),
+		}
+
+		switch {
+		case fi.Mode().IsRegular():
+			e.Type = TypeFile
+			e.Size = fi.Size()
+			if e.Digest, err = fileDigest(p); err != nil {
+				return fmt.Errorf("while computing digest of %s: %w", path, err)
+			}
+		case fi.IsDir():
+			e.Type = TypeDir
+		case fi.Mode()&fs.ModeSymlink != 0:
+			e.Type = TypeSymlink
+			if e.Target, err = os.Readlink(p); err != nil {
+				return err
+			}
+		default:
+			e.Type = TypeOther
+		}
+
+		m.Entries = append(m.Entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("while generating manifest of %s: %w", root, err)
+	}
+
+	return m, nil
+}
+
+// Seal writes the manifest of the sandbox at root in the sandbox, paths
+// matching DefaultExcludes or excludes are excluded.
+func Seal(root string, excludes []string) (*Manifest, error) {
+	m, err := Generate(root, append(append([]string{}, DefaultExcludes...), excludes...))
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(root, ManifestPath)
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return nil, fmt.Errorf("while writing manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Load returns the manifest of the sandbox at root.
+func Load(root string) (*Manifest, error) {
+	path := filepath.Join(root, ManifestPath)
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no manifest found in %s, the sandbox is not sealed", root)
+	} else if err != nil {
+		return nil, fmt.Errorf("while reading manifest %s: %w", path, err)
+	}
+
+	m := new(Manifest)
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("while decoding manifest %s: %w", path, err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", m.Version)
+	}
+	return m, nil
+}
+
+// Verify compares the sandbox at root with its manifest and returns the
+// changes found, sorted by path. Paths matching the manifest exclude patterns
+// or excludes are ignored.
+func Verify(root string, excludes []string) ([]Change, error) {
+	m, err := Load(root)
+	if err != nil {
+		return nil, err
+	}
+	excludes, err = checkExcludes(excludes)
+	if err != nil {
+		return nil, err
+	}
+	excludes = append(excludes, m.Excludes...)
+
+	current, err := Generate(root, excludes)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := make(map[string]Entry, len(m.Entries))
+	for _, e := range m.Entries {
+		if !isExcluded(e.Path, excludes) && !hasExcludedParent(e.Path, excludes) {
+			sealed[e.Path] = e
+		}
+	}
+
+	changes := make([]Change, 0)
+	for _, e := range current.Entries {
+		old, ok := sealed[e.Path]
+		if !ok {
+			changes = append(changes, Change{Kind: Added, Path: e.Path})
+			continue
+		}
+		delete(sealed, e.Path)
+
+		if reason := compare(old, e); reason != "" {
+			changes = append(changes, Change{Kind: Modified, Path: e.Path, Reason: reason})
+		}
+	}
+	for _, e := range sealed {
+		changes = append(changes, Change{Kind: Removed, Path: e.Path})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// hasExcludedParent returns whether a parent directory of path matches
+// one of the exclude patterns.
+func hasExcludedParent(path string, excludes []string) bool {
+	for dir := filepath.Dir(path); dir != "/"; dir = filepath.Dir(dir) {
+		if isExcluded(dir, excludes) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare returns the reason why the entries old and cur differ, or an
+// empty string if they are identical.
+func compare(old, cur Entry) string {
+	switch {
+	case old.Type != cur.Type:
+		return fmt.Sprintf("type changed from %s to %s", old.Type, cur.Type)
+	case old.Mode != cur.Mode:
+		return fmt.Sprintf("mode changed from %s to %s", old.Mode, cur.Mode)
+	case old.Digest != cur.Digest:
+		return "content changed"
+	case old.Target != cur.Target:
+		return fmt.Sprintf("link target changed from %s to %s", old.Target, cur.Target)
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func makeSandbox(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for _, d := range []string{"/.singularity.d", "/bin", "/etc", "/tmp", "/var/log"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"/bin/tool":     "#!/bin/sh\n",
+		"/etc/config":   "key=value\n",
+		"/etc/other":    "other\n",
+		"/tmp/scratch":  "scratch\n",
+		"/var/log/last": "log\n",
+	}
+	for f, content := range files {
+		if err := os.WriteFile(filepath.Join(root, f), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("tool", filepath.Join(root, "/bin/link")); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestSealVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		excludes []string
+		modify   func(root string) error
+		verifyEx []string
+		changes  []Change
+	}{
+		{
+			name:    "Unchanged",
+			modify:  func(string) error { return nil },
+			changes: []Change{},
+		},
+		{
+			name: "VolatileChanged",
+			modify: func(root string) error {
+				if err := os.WriteFile(filepath.Join(root, "/tmp/new"), nil, 0o644); err != nil {
+					return err
+				}
+				return os.Remove(filepath.Join(root, "/var/log/last"))
+			},
+			changes: []Change{},
+		},
+		{
+			name: "ContentChanged",
+			modify: func(root string) error {
+				return os.WriteFile(filepath.Join(root, "/etc/config"), []byte("key=other\n"), 0o644)
+			},
+			changes: []Change{
+				{Kind: Modified, Path: "/etc/config", Reason: "content changed"},
+			},
+		},
+		{
+			name: "ModeChanged",
+			modify: func(root string) error {
+				return os.Chmod(filepath.Join(root, "/bin/tool"), 0o755)
+			},
+			changes: []Change{
+				{Kind: Modified, Path: "/bin/tool", Reason: "mode changed from -rw-r--r-- to -rwxr-xr-x"},
+			},
+		},
+		{
+			name: "AddedRemoved",
+			modify: func(root string) error {
+				if err := os.WriteFile(filepath.Join(root, "/etc/added"), nil, 0o644); err != nil {
+					return err
+				}
+				return os.Remove(filepath.Join(root, "/etc/other"))
+			},
+			changes: []Change{
+				{Kind: Added, Path: "/etc/added"},
+				{Kind: Removed, Path: "/etc/other"},
+			},
+		},
+		{
+			name: "LinkTargetChanged",
+			modify: func(root string) error {
+				link := filepath.Join(root, "/bin/link")
+				if err := os.Remove(link); err != nil {
+					return err
+				}
+				return os.Symlink("other", link)
+			},
+			changes: []Change{
+				{Kind: Modified, Path: "/bin/link", Reason: "link target changed from tool to other"},
+			},
+		},
+		{
+			name:     "SealExclude",
+			excludes: []string{"/etc/conf*"},
+			modify: func(root string) error {
+				return os.WriteFile(filepath.Join(root, "/etc/config"), []byte("key=other\n"), 0o644)
+			},
+			changes: []Change{},
+		},
+		{
+			name: "VerifyExclude",
+			modify: func(root string) error {
+				if err := os.Remove(filepath.Join(root, "/etc/other")); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(root, "/bin/tool"), nil, 0o644)
+			},
+			verifyEx: []string{"/etc"},
+			changes: []Change{
+				{Kind: Modified, Path: "/bin/tool", Reason: "content changed"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := makeSandbox(t)
+
+			if _, err := Seal(root, tt.excludes); err != nil {
+				t.Fatalf("unexpected seal error: %s", err)
+			}
+			if err := tt.modify(root); err != nil {
+				t.Fatal(err)
+			}
+			changes, err := Verify(root, tt.verifyEx)
+			if err != nil {
+				t.Fatalf("unexpected verify error: %s", err)
+			}
+			if !reflect.DeepEqual(changes, tt.changes) {
+				t.Errorf("got changes %v, expected %v", changes, tt.changes)
+			}
+		})
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	root := makeSandbox(t)
+
+	if _, err := Verify(root, nil); err == nil {
+		t.Errorf("unexpected success verifying a sandbox without manifest")
+	}
+	if _, err := Seal(root, []string{"relative"}); err == nil {
+		t.Errorf("unexpected success with a relative exclude path")
+	}
+	if _, err := Seal(root, nil); err != nil {
+		t.Fatalf("unexpected seal error: %s", err)
+	}
+	if _, err := Verify(root, []string{"/bad["}); err == nil {
+		t.Errorf("unexpected success with a bad exclude pattern")
+	}
+}
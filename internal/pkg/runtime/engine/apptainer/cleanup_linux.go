@@ -19,12 +19,15 @@ import (
 	"syscall"
 
 	"github.com/apptainer/apptainer/internal/pkg/instance"
+	"github.com/apptainer/apptainer/internal/pkg/plugin"
 	fakerootConfig "github.com/apptainer/apptainer/internal/pkg/runtime/engine/fakeroot/config"
 	"github.com/apptainer/apptainer/internal/pkg/util/bin"
 	"github.com/apptainer/apptainer/internal/pkg/util/crypt"
 	"github.com/apptainer/apptainer/internal/pkg/util/priv"
 	"github.com/apptainer/apptainer/internal/pkg/util/starter"
 	"github.com/apptainer/apptainer/pkg/build/types"
+	"github.com/apptainer/apptainer/pkg/image"
+	apptainercallback "github.com/apptainer/apptainer/pkg/plugin/callback/runtime/engine/apptainer"
 	"github.com/apptainer/apptainer/pkg/runtime/engine/config"
 	"github.com/apptainer/apptainer/pkg/sylog"
 	"github.com/apptainer/apptainer/pkg/util/capabilities"
@@ -43,8 +46,10 @@ import (
 // For better understanding of runtime flow in general refer to
 // https://github.com/opencontainers/runtime-spec/blob/master/runtime.md#lifecycle.
 // CleanupContainer is performing step 8/9 here.
-func (e *EngineOperations) CleanupContainer(ctx context.Context, _ error, _ syscall.WaitStatus) error {
+func (e *EngineOperations) CleanupContainer(ctx context.Context, _ error, status syscall.WaitStatus) error {
 	sylog.Debugf("Cleanup container")
+	e.runOnExitCallbacks(status)
+
 	if fd := e.EngineConfig.GetShareNSFd(); fd != -1 && e.EngineConfig.GetShareNSMode() {
 		br := lock.NewByteRange(fd, 0, 0)
 		// wait all other processes first
@@ -77,10 +82,13 @@ func (e *EngineOperations) CleanupContainer(ctx context.Context, _ error, _ sysc
 	// fakeroot workflow
 	e.stopFuseDrivers()
 
-	if imageDriver != nil {
-		if err := umount(); err != nil {
-			sylog.Infof("Cleanup error: %s", err)
-		}
+	// the setup state is held by package variables, already released
+	// resources are skipped if create called cleanup on error
+	c := &container{engine: e}
+	c.cleanup()
+
+	if warnFree := e.EngineConfig.GetOverlayWarnFree(); warnFree > 0 {
+		e.checkOverlayUsage(warnFree)
 	}
 
 	if tempDir := e.EngineConfig.GetDeleteTempDir(); tempDir != "" {
@@ -108,6 +116,13 @@ func (e *EngineOperations) CleanupContainer(ctx context.Context, _ error, _ sysc
 		}
 	}
 
+	if overlayDir := e.EngineConfig.GetDeleteTempOverlay(); overlayDir != "" {
+		sylog.Verbosef("Removing temporary writable overlay %s", overlayDir)
+		if err := os.RemoveAll(overlayDir); err != nil {
+			sylog.Errorf("failed to delete temporary writable overlay %s: %s", overlayDir, err)
+		}
+	}
+
 	if networkSetup != nil {
 		var dropPrivilege priv.DropPrivFunc
 
@@ -126,27 +141,87 @@ func (e *EngineOperations) CleanupContainer(ctx context.Context, _ error, _ sysc
 		}
 	}
 
+	if e.EngineConfig.GetInstance() {
+		file, err := instance.Get(e.CommonConfig.ContainerID, instance.AppSubDir)
+		if err != nil {
+			return err
+		}
+		return file.Delete()
+	}
+
+	return nil
+}
+
+// cleanup unwinds the container setup, it kills the container process if
+// it's still running, unmounts the mount points in reverse order, stops the
+// image driver, destroys the cgroup and detaches the crypt device. Each
+// resource is forgotten only once released, so cleanup is idempotent and a
+// failed step is retried by the next call: it's called by create on error
+// and again by CleanupContainer.
+func (c *container) cleanup() {
+	if c.containerPid > 0 {
+		killContainerProcess(c.containerPid)
+	}
+
+	if imageDriver != nil {
+		if err := umount(); err != nil {
+			sylog.Infof("Cleanup error: %s", err)
+		}
+	}
+
 	if cgroupsManager != nil {
 		if err := cgroupsManager.Destroy(); err != nil {
 			sylog.Warningf("failed to remove cgroup configuration: %v", err)
+		} else {
+			cgroupsManager = nil
 		}
 	}
 
 	if cryptDev != "" {
 		if err := cleanupCrypt(cryptDev); err != nil {
 			sylog.Errorf("could not cleanup crypt: %v", err)
+		} else {
+			cryptDev = ""
 		}
 	}
+}
 
-	if e.EngineConfig.GetInstance() {
-		file, err := instance.Get(e.CommonConfig.ContainerID, instance.AppSubDir)
-		if err != nil {
-			return err
+// killContainerProcess kills the container process and waits until it
+// exited, so the mount points, cgroup and crypt device it uses can be
+// released. The process is not reaped, its status is still collected by
+// MonitorContainer.
+func killContainerProcess(pid int) {
+	if err := syscall.Kill(pid, syscall.SIGKILL); err != nil {
+		if err != syscall.ESRCH {
+			sylog.Warningf("could not kill container process %d: %s", pid, err)
 		}
-		return file.Delete()
+		return
 	}
+	for {
+		var info unix.Siginfo
+		err := unix.Waitid(unix.P_PID, pid, &info, unix.WEXITED|unix.WNOWAIT, nil)
+		if err != syscall.EINTR {
+			// ECHILD means MonitorContainer already reaped it
+			return
+		}
+	}
+}
 
-	return nil
+// runOnExitCallbacks notifies plugins that the container process exited
+// with status, errors are only reported as warnings to not alter the
+// container exit code.
+func (e *EngineOperations) runOnExitCallbacks(status syscall.WaitStatus) {
+	callbackType := (apptainercallback.OnExit)(nil)
+	callbacks, err := plugin.LoadCallbacks(callbackType)
+	if err != nil {
+		sylog.Warningf("while loading plugins callbacks '%T': %s", callbackType, err)
+		return
+	}
+	for _, callback := range callbacks {
+		if err := callback.(apptainercallback.OnExit)(e.CommonConfig, status); err != nil {
+			sylog.Warningf("while executing exit plugin callback: %s", err)
+		}
+	}
 }
 
 func umount() (err error) {
@@ -170,14 +245,31 @@ func umount() (err error) {
 		}
 	}()
 
+	// give a chance to the driver to flush cached data while all mount
+	// points are still there, this happens after the container process
+	// exited and before the cgroup is destroyed, so driver processes
+	// placed in the container cgroup are still running
+	for i := len(umountPoints) - 1; i >= 0; i-- {
+		p := umountPoints[i].path
+		if err := image.FlushDriver(imageDriver, p); err != nil {
+			errs = append(errs, fmt.Sprintf("while flushing driver for %s: %s", p, err))
+		}
+	}
+
 	// empty target to signify to driver we are entering in the stop phase
-	imageDriver.Stop("")
+	if imageDriver != nil {
+		imageDriver.Stop("")
+	}
 
 	// gocryptfs related temp folders
 	var gocryptfsTmp []string
+	// mount points which failed to be released, kept in mount order
+	// to be retried by a later call
+	var failed []umountPoint
 	for i := len(umountPoints) - 1; i >= 0; i-- {
 		up := umountPoints[i]
 		p := up.path
+		nerrs := len(errs)
 		if up.writable {
 			// First do Syncfs before unmounting.
 			// We haven't seen a problem without this but
@@ -197,9 +289,6 @@ func umount() (err error) {
 			}
 		}
 		sylog.Debugf("Umount %s", p)
-		if strings.Contains(p, "gocryptfs-") {
-			gocryptfsTmp = append(gocryptfsTmp, p)
-		}
 		retries := 0
 	retry:
 		err = syscall.Unmount(p, 0)
@@ -222,15 +311,21 @@ func umount() (err error) {
 				errs = append(errs, fmt.Sprintf("while unmounting %s directory: %s", p, err))
 			}
 		}
-		if imageDriver == nil {
-			continue
+		if imageDriver != nil {
+			err = imageDriver.Stop(p)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("while stopping driver for %s: %s", p, err))
+			}
 		}
-		err = imageDriver.Stop(p)
-		if err != nil {
-			errs = append(errs, fmt.Sprintf("while stopping driver for %s: %s", p, err))
+		if len(errs) > nerrs {
+			failed = append([]umountPoint{up}, failed...)
+		} else if strings.Contains(p, "gocryptfs-") {
+			gocryptfsTmp = append(gocryptfsTmp, p)
 		}
 	}
 
+	umountPoints = failed
+
 	if len(gocryptfsTmp) > 0 {
 		dir := filepath.Dir(gocryptfsTmp[0])
 		defer os.RemoveAll(dir)
@@ -278,3 +373,36 @@ func fakerootCleanup(path string) error {
 		starter.UseSuid(true),
 	)
 }
+
+// checkOverlayUsage reports the usage of the writable ext3 overlay image
+// once the container exited, from the block counts of its superblock.
+func (e *EngineOperations) checkOverlayUsage(warnFree uint64) {
+	for _, img := range e.EngineConfig.GetImageList() {
+		if !img.Writable {
+			continue
+		}
+		overlays, err := img.GetOverlayPartitions()
+		if err != nil {
+			continue
+		}
+		for _, part := range overlays {
+			if part.Type != image.EXT3 {
+				continue
+			}
+			f, err := os.Open(img.Source)
+			if err != nil {
+				sylog.Debugf("Could not open overlay image %s: %s", img.Path, err)
+				return
+			}
+			img.File = f
+			usage, err := img.GetExt3Usage(part)
+			f.Close()
+			if err != nil {
+				sylog.Debugf("Could not get usage of overlay image %s: %s", img.Path, err)
+				return
+			}
+			reportOverlayUsage(img.Path, usage.FreeBytes(), usage.TotalBlocks*usage.BlockSize, warnFree)
+			return
+		}
+	}
+}
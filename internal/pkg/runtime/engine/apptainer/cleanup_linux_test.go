@@ -0,0 +1,113 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/test"
+	"github.com/apptainer/apptainer/pkg/image"
+)
+
+// stopDriver is an image driver recording the stopped mount targets.
+type stopDriver struct {
+	stopped []string
+}
+
+func (d *stopDriver) Mount(*image.MountParams, image.MountFunc) error { return nil }
+func (d *stopDriver) MountErr() error                                 { return nil }
+func (d *stopDriver) Start(*image.DriverParams, int, bool) error      { return nil }
+func (d *stopDriver) Features() image.DriverFeature                   { return 0 }
+
+func (d *stopDriver) Stop(target string) error {
+	if target != "" {
+		d.stopped = append(d.stopped, target)
+	}
+	return nil
+}
+
+func TestCleanupRetry(t *testing.T) {
+	test.EnsurePrivilege(t)
+
+	tmpDir := t.TempDir()
+	// not a mount point, unmount reports EINVAL which means released
+	released := filepath.Join(tmpDir, "released")
+	if err := os.Mkdir(released, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// doesn't exist yet, unmount fails with ENOENT
+	failing := filepath.Join(tmpDir, "failing")
+
+	driver := &stopDriver{}
+	imageDriver = driver
+	umountPoints = []umountPoint{{released, false}, {failing, false}}
+	// cryptsetup reports an error for an unknown device
+	cryptDev = "/dev/mapper/apptainer-cleanup-test"
+	t.Cleanup(func() {
+		imageDriver = nil
+		umountPoints = nil
+		cryptDev = ""
+	})
+
+	c := &container{}
+
+	// the second call retries the failed steps only
+	for i := 0; i < 2; i++ {
+		c.cleanup()
+
+		if len(umountPoints) != 1 || umountPoints[0].path != failing {
+			t.Fatalf("call %d: unexpected remaining mount points %v", i+1, umountPoints)
+		}
+		if cryptDev == "" {
+			t.Fatalf("call %d: crypt device forgotten while it wasn't released", i+1)
+		}
+	}
+	stopped := 0
+	for _, target := range driver.stopped {
+		if target == released {
+			stopped++
+		}
+	}
+	if stopped != 1 {
+		t.Errorf("released mount point stopped %d times, expected once", stopped)
+	}
+
+	if err := os.Mkdir(failing, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cryptDev = ""
+
+	c.cleanup()
+
+	if len(umountPoints) != 0 {
+		t.Errorf("unexpected remaining mount points %v", umountPoints)
+	}
+}
+
+func TestCleanupKillsContainerProcess(t *testing.T) {
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("could not start sleep: %s", err)
+	}
+
+	c := &container{containerPid: cmd.Process.Pid}
+	c.cleanup()
+	// the process status is still available to MonitorContainer
+	c.cleanup()
+
+	err := cmd.Wait()
+	status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if err == nil || !ok || !status.Signaled() || status.Signal() != syscall.SIGKILL {
+		t.Errorf("container process not killed: %v", err)
+	}
+}
@@ -11,20 +11,24 @@ package apptainer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	osuser "os/user"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/apptainer/apptainer/internal/pkg/buildcfg"
 	"github.com/apptainer/apptainer/internal/pkg/cgroups"
 	"github.com/apptainer/apptainer/internal/pkg/image/driver"
 	"github.com/apptainer/apptainer/internal/pkg/plugin"
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/apptainer/rpc/client"
+	"github.com/apptainer/apptainer/internal/pkg/util/env"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs/files"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs/layout"
@@ -42,8 +46,11 @@ import (
 	apptainer "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
 	"github.com/apptainer/apptainer/pkg/sylog"
 	"github.com/apptainer/apptainer/pkg/util/fs/proc"
+	"github.com/apptainer/apptainer/pkg/util/loop"
 	"github.com/apptainer/apptainer/pkg/util/namespaces"
 	"github.com/apptainer/apptainer/pkg/util/slice"
+	units "github.com/docker/go-units"
+	lccgroups "github.com/opencontainers/runc/libcontainer/cgroups"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
@@ -67,12 +74,30 @@ var (
 	cgroupsManager *cgroups.Manager
 )
 
+// sessionDir is the directory where the container session is mounted.
+var sessionDir = buildcfg.SESSIONDIR
+
+// errErofsNotSupported is returned when the kernel can't mount EROFS images.
+var errErofsNotSupported = fmt.Errorf("EROFS filesystem is not supported by your kernel, Linux 5.4 or later with CONFIG_EROFS_FS is required")
+
 // defaultCNIConfPath is the default directory to CNI network configuration files.
 var defaultCNIConfPath = filepath.Join(buildcfg.SYSCONFDIR, "apptainer", "network")
 
 // defaultCNIPluginPath is the default directory to CNI plugins executables.
 var defaultCNIPluginPath = filepath.Join(buildcfg.LIBEXECDIR, "apptainer", "cni")
 
+// cniRetryDelay is the delay before the first network setup retry,
+// it's doubled after each attempt.
+const cniRetryDelay = 500 * time.Millisecond
+
+// loop device allocation retries when all loop devices are in use, the
+// delay before the first retry is doubled after each attempt to give
+// concurrent launches the time to release their loop devices.
+const (
+	loopAttachRetries = 5
+	loopAttachDelay   = 250 * time.Millisecond
+)
+
 type lastMount struct {
 	dest  string
 	flags uintptr
@@ -89,78 +114,58 @@ type container struct {
 	utsNS         bool
 	netNS         bool
 	ipcNS         bool
+	cgroupNS      bool
 	mountInfoPath string
 	lastMount     lastMount
 	skippedMount  []string
+	// lazytimeMount lists the image mount points mounted with lazytime
+	lazytimeMount []string
 	suidFlag      uintptr
 	devSourcePath string
 	skipCwd       bool
+	// planOnly is set when only the mount plan is built, see MountPlan
+	planOnly bool
+	// profile records the startup timeline when requested
+	profile *startupProfile
+	// containerPid is the container process killed by cleanup
+	containerPid int
 }
 
 //nolint:maintidx
-func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, pid int) error {
-	var err error
-
+func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, pid int) (err error) {
 	if len(engine.EngineConfig.GetImageList()) == 0 {
 		return fmt.Errorf("no root filesystem image provided")
 	}
 
-	c := &container{
-		engine:        engine,
-		rpcOps:        rpcOps,
-		sessionFsType: engine.EngineConfig.File.MemoryFSType,
-		mountInfoPath: fmt.Sprintf("/proc/%d/mountinfo", pid),
-		skippedMount:  make([]string, 0),
-		suidFlag:      syscall.MS_NOSUID,
-	}
-
-	cwd := engine.EngineConfig.GetCwd()
-	if err := os.Chdir(cwd); err != nil {
-		return fmt.Errorf("can't change directory to %s: %s", cwd, err)
-	}
+	c := newContainer(engine, rpcOps, pid)
+	c.containerPid = pid
 
-	if engine.EngineConfig.OciConfig.Linux != nil {
-		if os.Getuid() == 0 && namespaces.IsUnprivileged() {
-			// This is any root-mapped unprivileged user namespace,
-			//  the real fakeroot or the "fake" fakeroot with just
-			//  a root-mapped unprivileged user namespace.
-			// This setting is already on the real fakeroot but it is
-			//  needed to be added on the fake fakeroot for starting
-			//  instances.
-			engine.EngineConfig.OciConfig.AddOrReplaceLinuxNamespace(specs.UserNamespace, "")
+	// unwind a partial setup
+	defer func() {
+		if err != nil {
+			c.cleanup()
 		}
+	}()
 
-		for _, namespace := range engine.EngineConfig.OciConfig.Linux.Namespaces {
-			switch namespace.Type {
-			case specs.UserNamespace:
-				c.userNS = true
-			case specs.PIDNamespace:
-				c.pidNS = true
-			case specs.UTSNamespace:
-				c.utsNS = true
-			case specs.NetworkNamespace:
-				c.netNS = true
-			case specs.IPCNamespace:
-				c.ipcNS = true
+	if path := engine.EngineConfig.GetProfile(); path != "" {
+		c.profile = newStartupProfile(rpcOps)
+		defer func() {
+			if err := c.profile.write(path); err != nil {
+				sylog.Warningf("Could not write container startup profile: %s", err)
 			}
-		}
-	}
-
-	if os.Geteuid() != 0 {
-		c.sessionSize = int(c.engine.EngineConfig.File.SessiondirMaxSize)
-	} else if engine.EngineConfig.GetAllowSUID() && !c.userNS {
-		c.suidFlag = 0
+		}()
 	}
 
-	// user namespace was not requested but we need to check
-	// if we are currently running in a user namespace and set
-	// value accordingly to avoid remount errors while running
-	// inside a user namespace
-	if !c.userNS {
-		c.userNS, _ = namespaces.IsInsideUserNamespace(os.Getpid())
+	cwd := engine.EngineConfig.GetCwd()
+	if err := os.Chdir(cwd); err != nil {
+		return fmt.Errorf("can't change directory to %s: %s", cwd, err)
 	}
 
 	// initialize internal image drivers
+	endDrivers := c.profile.begin("image drivers")
+	if c.engine.EngineConfig.GetLoopMode() == apptainer.LoopModeFuse {
+		driver.PreferFuseSquash()
+	}
 	driver.InitImageDrivers(true, c.userNS, c.engine.EngineConfig.File, 0)
 
 	// load image driver plugins
@@ -180,99 +185,40 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 	if driverName != "" && imageDriver == nil {
 		return fmt.Errorf("%q: no such image driver", driverName)
 	}
-
-	p := &mount.Points{}
-	system := &mount.System{Points: p, Mount: c.mount}
-
-	createCwdDirTag := mount.AuthorizedTag(mount.LayerTag)
-	if c.engine.EngineConfig.GetSessionLayer() == apptainer.UnderlayLayer {
-		createCwdDirTag = mount.PreLayerTag
-	}
-	if err := system.RunBeforeTag(createCwdDirTag, c.createCwdDir); err != nil {
-		return err
-	}
-
-	if err := c.setupSessionLayout(system); err != nil {
+	if err := checkImageDriver(driverName); err != nil {
 		return err
 	}
+	endDrivers()
 
-	if err := c.setupImageDriver(system, pid); err != nil {
-		return err
+	// with a cgroup namespace, the container process must be in its
+	// cgroup before the cgroup filesystem mount is set up
+	if c.cgroupNS {
+		if err := c.applyCgroups(); err != nil {
+			return err
+		}
 	}
 
-	umountPoints = append(umountPoints, umountPoint{c.session.RootFsPath(), false})
-
-	if c.session.FinalPath() != c.session.RootFsPath() {
-		umountPoints = append(umountPoints, umountPoint{c.session.FinalPath(), false})
-	}
+	p := &mount.Points{}
+	system := &mount.System{Points: p, Mount: c.mount}
 
-	if err := system.RunAfterTag(mount.SessionTag, c.addMountInfo); err != nil {
-		return err
-	}
-	if err := system.RunBeforeTag(mount.CwdTag, c.addCwdMount); err != nil {
-		return err
-	}
-	if err := system.RunAfterTag(mount.SharedTag, c.addIdentityMount); err != nil {
-		return err
-	}
-	// this call must occur just after all container layers are mounted
-	// to prevent user binds to screw up session final directory and
-	// consequently chroot
-	if err := system.RunAfterTag(mount.SharedTag, c.chdirFinal); err != nil {
+	endMounts := c.profile.begin("session and mount points setup")
+	usernsFd, err := c.addMounts(system, pid)
+	if err != nil {
 		return err
 	}
+	endMounts()
 
-	if err := c.addRootfsMount(system); err != nil {
-		return err
-	}
-	if err := c.addImageBindMount(system); err != nil {
-		return err
-	}
-	if err := c.addKernelMount(system); err != nil {
-		return err
-	}
-	if err := c.addDevMount(system); err != nil {
-		return err
-	}
-	if err := c.addHostMount(system); err != nil {
-		return err
-	}
-	if err := c.addBindsMount(system); err != nil {
-		return err
-	}
-	if err := c.addHomeMount(system); err != nil {
-		return err
-	}
-	if err := c.addUserbindsMount(system); err != nil {
-		return err
-	}
-	if err := c.addTmpMount(system); err != nil {
-		return err
-	}
-	if err := c.addScratchMount(system); err != nil {
-		return err
-	}
-	if err := c.addLibsMount(system); err != nil {
-		return err
-	}
-	if err := c.addFilesMount(system); err != nil {
-		return err
-	}
-	if err := c.addResolvConfMount(system); err != nil {
-		return err
-	}
-	if err := c.addHostnameMount(system); err != nil {
-		return err
-	}
-	usernsFd, err := c.addFuseMount(system)
+	endNetwork := c.profile.begin("network prepare")
+	networkSetup, err := c.prepareNetworkSetup(system, pid)
 	if err != nil {
 		return err
 	}
+	endNetwork()
 
-	networkSetup, err := c.prepareNetworkSetup(system, pid)
-	if err != nil {
+	if err := c.profile.addMountHooks(system); err != nil {
 		return err
 	}
+	endMountAll := c.profile.begin("mount all")
 
 	sylog.Debugf("Mount all")
 
@@ -280,7 +226,7 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 	driverMountErr := make(chan error)
 
 	go func() {
-		mountAllErr <- system.MountAll()
+		mountAllErr <- c.mountAll(system)
 	}()
 
 	go func() {
@@ -325,6 +271,18 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 
 	close(mountAllErr)
 	close(driverMountErr)
+	endMountAll()
+
+	postMountType := (apptainercallback.PostMountSetup)(nil)
+	postMountCallbacks, err := plugin.LoadCallbacks(postMountType)
+	if err != nil {
+		return fmt.Errorf("while loading plugins callbacks '%T': %s", postMountType, err)
+	}
+	for _, callback := range postMountCallbacks {
+		if err := callback.(apptainercallback.PostMountSetup)(c.engine.CommonConfig, c.session.FinalPath()); err != nil {
+			return fmt.Errorf("while executing post mount setup plugin callback: %s", err)
+		}
+	}
 
 	if engine.EngineConfig.GetSessionLayer() == apptainer.UnderlayLayer {
 		// Underlay bind points can interfere with unmounting
@@ -358,6 +316,7 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 	// chroot from RPC server current working directory since
 	// it's already in final directory after chdirFinal call
 	sylog.Debugf("Chroot into %s\n", c.session.FinalPath())
+	endChroot := c.profile.begin("chroot")
 	_, err = c.rpcOps.Chroot(".", "pivot")
 	if err != nil {
 		sylog.Debugf("Fallback to move/chroot")
@@ -366,29 +325,20 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 			return fmt.Errorf("chroot failed: %s", err)
 		}
 	}
+	endChroot()
 
 	if networkSetup != nil {
+		endNetwork := c.profile.begin("network setup")
 		if err := networkSetup(ctx); err != nil {
 			return err
 		}
+		endNetwork()
 	}
 
-	cgJSON := engine.EngineConfig.GetCgroupsJSON()
-	if cgJSON != "" {
-		// Rootless cgroups setup interacts with systemd over D-Bus.
-		// The session bus address and XDG runtime dir must be set in the environment.
-		if os.Getuid() != 0 {
-			sylog.Debugf("Setting rootless XDG_RUNTIME_DIR / DBUS_SESSION_ADDRESS for cgroup manager")
-			os.Setenv("XDG_RUNTIME_DIR", engine.EngineConfig.GetXdgRuntimeDir())
-			os.Setenv("DBUS_SESSION_BUS_ADDRESS", engine.EngineConfig.GetDbusSessionBusAddress())
-		}
-
-		cgroupsManager, err = cgroups.NewManagerWithJSON(cgJSON, pid, "", engine.EngineConfig.File.SystemdCgroups)
-		if err != nil {
-			return fmt.Errorf("while applying cgroups config: %v", err)
+	if !c.cgroupNS {
+		if err := c.applyCgroups(); err != nil {
+			return err
 		}
-		os.Unsetenv("XDG_RUNTIME_DIR")
-		os.Unsetenv("DBUS_SESSION_BUS_ADDRESS")
 	}
 
 	sylog.Debugf("Chdir into / to avoid errors\n")
@@ -397,13 +347,201 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 		return fmt.Errorf("change directory failed: %s", err)
 	}
 
+	endFuse := c.profile.begin("FUSE drivers")
 	if err := engine.runFuseDrivers(false, usernsFd); err != nil {
 		return fmt.Errorf("while running FUSE drivers: %s", err)
 	}
+	endFuse()
 
 	return nil
 }
 
+// applyCgroups places the container process in a new cgroup with the
+// cgroups configuration, if any.
+func (c *container) applyCgroups() (err error) {
+	cgJSON := c.engine.EngineConfig.GetCgroupsJSON()
+	if cgJSON == "" {
+		return nil
+	}
+
+	endCgroups := c.profile.begin("cgroups")
+	// Rootless cgroups setup interacts with systemd over D-Bus.
+	// The session bus address and XDG runtime dir must be set in the environment.
+	if os.Getuid() != 0 {
+		sylog.Debugf("Setting rootless XDG_RUNTIME_DIR / DBUS_SESSION_ADDRESS for cgroup manager")
+		os.Setenv("XDG_RUNTIME_DIR", c.engine.EngineConfig.GetXdgRuntimeDir())
+		os.Setenv("DBUS_SESSION_BUS_ADDRESS", c.engine.EngineConfig.GetDbusSessionBusAddress())
+	}
+
+	cgroupsManager, err = cgroups.NewManagerWithJSON(cgJSON, c.containerPid, "", c.engine.EngineConfig.File.SystemdCgroups)
+	if err != nil {
+		return fmt.Errorf("while applying cgroups config: %v", err)
+	}
+	os.Unsetenv("XDG_RUNTIME_DIR")
+	os.Unsetenv("DBUS_SESSION_BUS_ADDRESS")
+	endCgroups()
+	return nil
+}
+
+// newContainer returns the container for the engine configuration,
+// pid is the container process ID.
+func newContainer(engine *EngineOperations, rpcOps *client.RPC, pid int) *container {
+	c := &container{
+		engine:        engine,
+		rpcOps:        rpcOps,
+		sessionFsType: engine.EngineConfig.File.MemoryFSType,
+		mountInfoPath: fmt.Sprintf("/proc/%d/mountinfo", pid),
+		skippedMount:  make([]string, 0),
+		suidFlag:      syscall.MS_NOSUID,
+	}
+
+	if engine.EngineConfig.OciConfig.Linux != nil {
+		if os.Getuid() == 0 && namespaces.IsUnprivileged() {
+			// This is any root-mapped unprivileged user namespace,
+			//  the real fakeroot or the "fake" fakeroot with just
+			//  a root-mapped unprivileged user namespace.
+			// This setting is already on the real fakeroot but it is
+			//  needed to be added on the fake fakeroot for starting
+			//  instances.
+			engine.EngineConfig.OciConfig.AddOrReplaceLinuxNamespace(specs.UserNamespace, "")
+		}
+
+		for _, namespace := range engine.EngineConfig.OciConfig.Linux.Namespaces {
+			switch namespace.Type {
+			case specs.UserNamespace:
+				c.userNS = true
+			case specs.PIDNamespace:
+				c.pidNS = true
+			case specs.UTSNamespace:
+				c.utsNS = true
+			case specs.NetworkNamespace:
+				c.netNS = true
+			case specs.IPCNamespace:
+				c.ipcNS = true
+			case specs.CgroupNamespace:
+				c.cgroupNS = true
+			}
+		}
+	}
+
+	if os.Geteuid() != 0 {
+		c.sessionSize = int(c.engine.EngineConfig.File.SessiondirMaxSize)
+	} else if engine.EngineConfig.GetAllowSUID() && !c.userNS {
+		c.suidFlag = 0
+	}
+	if size := engine.EngineConfig.GetSessionSize(); size > 0 {
+		c.sessionSize = size
+	}
+
+	// user namespace was not requested but we need to check
+	// if we are currently running in a user namespace and set
+	// value accordingly to avoid remount errors while running
+	// inside a user namespace
+	if !c.userNS {
+		c.userNS, _ = namespaces.IsInsideUserNamespace(os.Getpid())
+	}
+
+	return c
+}
+
+// addMounts registers the container mount points and the hook functions
+// executed while mounting them, it returns the user namespace file
+// descriptor passed along with FUSE mount file descriptors or -1.
+func (c *container) addMounts(system *mount.System, pid int) (int, error) {
+	createCwdDirTag := mount.AuthorizedTag(mount.LayerTag)
+	if c.engine.EngineConfig.GetSessionLayer() == apptainer.UnderlayLayer {
+		createCwdDirTag = mount.PreLayerTag
+	}
+	if err := system.RunBeforeTag(createCwdDirTag, c.createCwdDir); err != nil {
+		return -1, err
+	}
+
+	if err := c.setupSessionLayout(system); err != nil {
+		return -1, err
+	}
+
+	if !c.planOnly {
+		if err := c.setupImageDriver(system, pid); err != nil {
+			return -1, err
+		}
+
+		umountPoints = append(umountPoints, umountPoint{c.session.RootFsPath(), false})
+
+		if c.session.FinalPath() != c.session.RootFsPath() {
+			umountPoints = append(umountPoints, umountPoint{c.session.FinalPath(), false})
+		}
+	}
+
+	if err := system.RunAfterTag(mount.SessionTag, c.addMountInfo); err != nil {
+		return -1, err
+	}
+	if err := system.RunBeforeTag(mount.CwdTag, c.addCwdMount); err != nil {
+		return -1, err
+	}
+	if err := system.RunAfterTag(mount.SharedTag, c.addIdentityMount); err != nil {
+		return -1, err
+	}
+	// this call must occur just after all container layers are mounted
+	// to prevent user binds to screw up session final directory and
+	// consequently chroot
+	if err := system.RunAfterTag(mount.SharedTag, c.chdirFinal); err != nil {
+		return -1, err
+	}
+
+	if err := c.addRootfsMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addImageBindMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addKernelMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addDevMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addHostMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addBindsMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addHomeMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addUserbindsMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addOverlayBindsMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addMountFromMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addTmpfsMounts(system); err != nil {
+		return -1, err
+	}
+	if err := c.addTmpMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addScratchMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addLibsMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addFilesMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addResolvConfMount(system); err != nil {
+		return -1, err
+	}
+	if err := c.addHostnameMount(system); err != nil {
+		return -1, err
+	}
+	return c.addFuseMount(system)
+}
+
 // setupSessionLayout will create the session layout according to the capabilities of Apptainer
 // on the system. It will first attempt to use "overlay", followed by "underlay", and if neither
 // are available it will not use either. If neither are used, we will not be able to bind mount
@@ -412,9 +550,9 @@ func (c *container) setupSessionLayout(system *mount.System) error {
 	var err error
 	var sessionPath string
 
-	sessionPath, err = filepath.EvalSymlinks(buildcfg.SESSIONDIR)
+	sessionPath, err = filepath.EvalSymlinks(sessionDir)
 	if err != nil {
-		return fmt.Errorf("failed to resolve session directory %s: %s", buildcfg.SESSIONDIR, err)
+		return fmt.Errorf("failed to resolve session directory %s: %s", sessionDir, err)
 	}
 
 	sessionLayer := c.engine.EngineConfig.GetSessionLayer()
@@ -468,6 +606,71 @@ func (c *container) isLayerEnabled() bool {
 	return c.engine.EngineConfig.GetSessionLayer() != apptainer.DefaultLayer
 }
 
+// mountAll mounts all the container mount points. When requested, the
+// container mount table is written before mounting, so it's available
+// even if a mount fails, and written again once the mount points were
+// processed to add the mount points added by hook functions and the
+// skip status.
+func (c *container) mountAll(system *mount.System) error {
+	path := c.engine.EngineConfig.GetDumpMounts()
+	if path == "" {
+		return system.MountAll()
+	}
+
+	if err := c.dumpMounts(path, system); err != nil {
+		sylog.Warningf("Could not write container mount table: %s", err)
+	}
+	err := system.MountAll()
+	if err := c.dumpMounts(path, system); err != nil {
+		sylog.Warningf("Could not update container mount table: %s", err)
+	}
+	return err
+}
+
+// mountTableEntry describes a container mount point written by dumpMounts.
+type mountTableEntry struct {
+	Tag         mount.AuthorizedTag `json:"tag"`
+	Source      string              `json:"source"`
+	Destination string              `json:"destination"`
+	Type        string              `json:"type"`
+	Flags       uintptr             `json:"flags"`
+	Options     []string            `json:"options"`
+	Skipped     bool                `json:"skipped"`
+}
+
+// dumpMounts writes the mount points of the container in mount order
+// to path as indented JSON along with their skip status.
+func (c *container) dumpMounts(path string, system *mount.System) error {
+	entries := make([]mountTableEntry, 0)
+
+	for _, tag := range mount.GetTagList() {
+		for _, point := range system.Points.GetByTag(tag) {
+			flags, opts := mount.ConvertOptions(point.Options)
+			entries = append(entries, mountTableEntry{
+				Tag:         tag,
+				Source:      point.Source,
+				Destination: point.Destination,
+				Type:        point.Type,
+				Flags:       flags,
+				Options:     opts,
+				Skipped:     slices.Contains(c.skippedMount, point.Destination),
+			})
+		}
+	}
+
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("while encoding mount table: %s", err)
+	}
+	b = append(b, '\n')
+
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("while writing mount table to %s: %s", path, err)
+	}
+	sylog.Verbosef("Container mount table written to %s", path)
+	return nil
+}
+
 func (c *container) mount(point *mount.Point, system *mount.System) error {
 	if _, err := mount.GetOffset(point.InternalOptions); err == nil {
 		if err := c.mountImage(point, system); err != nil {
@@ -513,6 +716,7 @@ func (c *container) setupImageDriver(system *mount.System, containerPid int) err
 
 	fakeroot := c.engine.EngineConfig.GetFakeroot()
 	fakerootHybrid := fakeroot && os.Geteuid() != 0
+	keepIDHybrid := c.engine.EngineConfig.GetKeepID() && os.Geteuid() != 0
 
 	if fuseDriver {
 		fuseFd, fuseRPCFd, err := c.openFuseFdFromRPC()
@@ -536,9 +740,10 @@ func (c *container) setupImageDriver(system *mount.System, containerPid int) err
 			if fakerootHybrid {
 				uid = 0
 				gid = 0
-
+			}
+			if fakerootHybrid || keepIDHybrid {
 				// with hybrid workflow this process is actually running as the
-				// user but outside of the fakeroot user namespace, it means that
+				// user but outside of the container user namespace, it means that
 				// the FUSE kernel code prevent us from accessing the mount point
 				// where images (rootfs, overlay ...) resides if we are not in the
 				// user namespace, so we redirect session VFS calls via RPC in order
@@ -572,6 +777,8 @@ func (c *container) setupImageDriver(system *mount.System, containerPid int) err
 			umountPoints = append(umountPoints, umountPoint{sp, true})
 
 			sylog.Debugf("Starting image driver %s", c.engine.EngineConfig.File.ImageDriver)
+			end := c.profile.begin("image driver start")
+			defer end()
 			if err := imageDriver.Start(params, containerPid, fakerootHybrid); err != nil {
 				return fmt.Errorf("failed to start driver: %s", err)
 			}
@@ -586,6 +793,8 @@ func (c *container) setupImageDriver(system *mount.System, containerPid int) err
 			defer unix.Close(params.UsernsFd)
 		}
 		sylog.Debugf("Starting image driver %s", c.engine.EngineConfig.File.ImageDriver)
+		end := c.profile.begin("image driver start")
+		defer end()
 		if err := imageDriver.Start(params, containerPid, fakerootHybrid); err != nil {
 			return fmt.Errorf("failed to start driver: %s", err)
 		}
@@ -734,10 +943,13 @@ func (c *container) mountGeneric(mnt *mount.Point, system *mount.System) (err er
 			c.lastMount.flags = 0
 		}
 	}
+	// lazytime only takes effect with the remount of a bind mount
+	if remount && !propagation {
+		flags |= c.lazytimeFlag(mnt)
+	}
 
 	if !strings.HasPrefix(mnt.Destination, sessionPath) {
-		dest = fs.EvalRelative(mnt.Destination, c.session.FinalPath())
-		dest = filepath.Join(c.session.FinalPath(), dest)
+		dest = c.session.ContainerPath(mnt.Destination)
 	} else {
 		dest = mnt.Destination
 	}
@@ -818,6 +1030,9 @@ mount:
 			mount.CwdTag,
 			mount.FilesTag,
 			mount.TmpTag:
+			if bindMount && c.engine.EngineConfig.GetStrictBinds() {
+				return fmt.Errorf("can't bind %s [%s] with --strict-binds: %s doesn't exist in container", source, tag, mnt.Destination)
+			}
 			c.skippedMount = append(c.skippedMount, mnt.Destination)
 			sylog.Warningf("Skipping mount %s [%s]: %s doesn't exist in container", source, tag, mnt.Destination)
 			return nil
@@ -834,8 +1049,24 @@ mount:
 			return fmt.Errorf("destination %s doesn't exist in container", mnt.Destination)
 		}
 	} else if err != nil {
+		if propagation && tag == mount.UserbindsTag {
+			if os.IsPermission(err) && c.userNS {
+				// like remount, mount propagation may not be
+				// allowed within a user namespace
+				sylog.Warningf("Could not set mount propagation of %s: %s", mnt.Destination, err)
+				return nil
+			}
+			return fmt.Errorf("could not set mount propagation of %s: %s", mnt.Destination, err)
+		}
 		if !bindMount && !remount {
-			xinolessOptsString := strings.Replace(optsString, ",xino=on", "", -1)
+			// optional overlay options are dropped one at a time
+			fallbackOptsString, fallbackOpt := optsString, ""
+			for _, o := range mount.OverlayOptionalOptions {
+				if stripped := strings.Replace(optsString, ","+o, "", -1); stripped != optsString {
+					fallbackOptsString, fallbackOpt = stripped, o
+					break
+				}
+			}
 			if mnt.Type == "devpts" {
 				sylog.Verbosef("Couldn't mount devpts filesystem, continuing with PTY allocation functionality disabled")
 				return nil
@@ -846,9 +1077,9 @@ mount:
 				sylog.Verbosef("Overlay mount failed with %s, mounting with index=off", err)
 				optsString = fmt.Sprintf("%s,index=off", optsString)
 				goto mount
-			} else if mnt.Type == "overlay" && err == syscall.EINVAL && xinolessOptsString != optsString {
-				sylog.Verbosef("Overlay mount failed with %s, trying mount without xino option", err)
-				optsString = xinolessOptsString
+			} else if mnt.Type == "overlay" && err == syscall.EINVAL && fallbackOpt != "" {
+				sylog.Verbosef("Overlay mount failed with %s, trying mount without %s option", err, fallbackOpt)
+				optsString = fallbackOptsString
 				goto mount
 			} else if mnt.Type == "overlay" && tag == mount.LayerTag {
 				if imageDriver != nil && imageDriver.Features()&image.OverlayFeature != 0 {
@@ -867,6 +1098,11 @@ mount:
 				//  a message about why it is not available
 				driver.InitImageDrivers(false, c.userNS, c.engine.EngineConfig.File, image.OverlayFeature)
 				// fall through to print the kernel mount error
+			} else if mnt.Type == "overlay" && tag == mount.UserbindsTag {
+				if c.userNS {
+					return fmt.Errorf("can't mount overlay bind to %s, unprivileged overlay mounts may not be supported by the kernel: %s", mnt.Destination, err)
+				}
+				return fmt.Errorf("can't mount overlay bind to %s, overlay filesystem may not be supported by the kernel: %s", mnt.Destination, err)
 			}
 			// mount error for other filesystems is considered fatal
 			return fmt.Errorf("can't mount %s filesystem to %s: %s", mnt.Type, mnt.Destination, err)
@@ -877,7 +1113,9 @@ mount:
 				// remount, then if we get a permission denied error, we continue
 				// execution by ignoring the error and warn user if the bind mount
 				// need to be mounted read-only
-				if flags&syscall.MS_RDONLY != 0 {
+				if flags&syscall.MS_RDONLY != 0 && tag == mount.CwdTag {
+					sylog.Warningf("Could not remount current working directory %s read-only, it remains writable: %s", mnt.Destination, err)
+				} else if flags&syscall.MS_RDONLY != 0 {
 					sylog.Warningf("Could not remount %s read-only: %s", mnt.Destination, err)
 				} else {
 					sylog.Verbosef("Could not remount %s: %s", mnt.Destination, err)
@@ -888,6 +1126,9 @@ mount:
 		}
 
 		if mount.SkipOnError(mnt.InternalOptions) {
+			if c.engine.EngineConfig.GetStrictBinds() {
+				return fmt.Errorf("could not mount %s with --strict-binds: %s", mnt.Source, err)
+			}
 			sylog.Warningf("could not mount %s: %s", mnt.Source, err)
 			c.skippedMount = append(c.skippedMount, mnt.Destination)
 			return nil
@@ -914,12 +1155,47 @@ mount:
 	return nil
 }
 
+// attachLoopDevice attaches the image to a loop device and returns the
+// loop device number. When all loop devices are in use, the allocation is
+// retried with a backoff before reporting the loop device pool exhaustion.
+func (c *container) attachLoopDevice(image string, mode int, info unix.LoopInfo64, maxDevices int, shared bool) (int, error) {
+	delay := loopAttachDelay
+
+	for i := 0; ; i++ {
+		number, err := c.rpcOps.LoopDevice(image, mode, info, maxDevices, shared)
+		if err == nil {
+			return number, nil
+		}
+		// RPC errors are returned as strings
+		if !strings.Contains(err.Error(), loop.ErrNoLoopDevice.Error()) {
+			return -1, fmt.Errorf("failed to find loop device: %s", err)
+		}
+		if i == loopAttachRetries {
+			break
+		}
+		sylog.Debugf("All loop devices are in use, retrying in %s", delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	hint := "set 'shared loop devices = yes' in apptainer.conf to share loop devices between containers using the same image"
+	if shared {
+		hint = "increase 'max loop devices' in apptainer.conf"
+	}
+	return -1, fmt.Errorf(
+		"failed to find loop device: all %d loop devices allowed by 'max loop devices' are in use, "+
+			"ask your administrator to %s, or use --userns to mount the image with squashfuse",
+		maxDevices, hint,
+	)
+}
+
 // mount image via loop
 func (c *container) mountImage(mnt *mount.Point, system *mount.System) error {
 	var key []byte
 
 	maxDevices := int(c.engine.EngineConfig.File.MaxLoopDevices)
 	flags, opts := mount.ConvertOptions(mnt.Options)
+	flags |= c.lazytimeFlag(mnt)
 	optsString := strings.Join(opts, ",")
 
 	offset, err := mount.GetOffset(mnt.InternalOptions)
@@ -934,7 +1210,22 @@ func (c *container) mountImage(mnt *mount.Point, system *mount.System) error {
 
 	mountType := mnt.Type
 
-	if mountType == "encryptfs" || mountType == "gocryptfs" {
+	if mountType == "squashfs" {
+		switch system.CurrentTag() {
+		case mount.RootfsTag, mount.ImageBindTag:
+			// the options allowed for the kernel mount done with
+			// elevated privileges are checked by PrepareConfig
+			fsOpts, err := mount.SquashfsMountOptions(c.engine.EngineConfig.GetSquashfsMountOpts(), false)
+			if err != nil {
+				return fmt.Errorf("while checking squashfs mount options: %s", err)
+			}
+			opts = append(opts, fsOpts...)
+			optsString = strings.Join(opts, ",")
+		}
+	}
+
+	encrypted := mountType == "encryptfs" || mountType == "gocryptfs"
+	if encrypted {
 		key, err = mount.GetKey(mnt.InternalOptions)
 		if err != nil {
 			return err
@@ -954,7 +1245,11 @@ func (c *container) mountImage(mnt *mount.Point, system *mount.System) error {
 
 	if imageDriver != nil {
 		features := imageDriver.Features()
-		if mountType == "gocryptfs" {
+		if encrypted && features&image.EncryptFeature != 0 {
+			// the driver handles the decryption, bypass the
+			// built-in cryptsetup and gocryptfs mounts
+			return c.mountImageDriver(params, system, c.rpcOps.Mount)
+		} else if mountType == "gocryptfs" {
 			if features&image.GocryptFeature != 0 {
 				return c.gocryptfsMount(params, system, c.rpcOps.Mount)
 			}
@@ -966,9 +1261,17 @@ func (c *container) mountImage(mnt *mount.Point, system *mount.System) error {
 			if features&image.Ext3Feature != 0 {
 				return c.mountImageDriver(params, system, c.rpcOps.Mount)
 			}
+		} else if mountType == "erofs" {
+			if features&image.ErofsFeature != 0 {
+				return c.mountImageDriver(params, system, c.rpcOps.Mount)
+			}
 		}
 	}
 
+	if mountType == "squashfs" && c.engine.EngineConfig.GetLoopMode() == apptainer.LoopModeFuse {
+		sylog.Warningf("--loop=fuse requested but no image driver is available to mount squashfs, falling back to a kernel loop device")
+	}
+
 	if mountType == "gocryptfs" {
 		// no non-image driver alternative for this one
 		return fmt.Errorf("gocryptfs image driver unavailable")
@@ -989,9 +1292,9 @@ func (c *container) mountImage(mnt *mount.Point, system *mount.System) error {
 	}
 
 	shared := c.engine.EngineConfig.File.SharedLoopDevices
-	number, err := c.rpcOps.LoopDevice(mnt.Source, attachFlag, *info, maxDevices, shared)
+	number, err := c.attachLoopDevice(mnt.Source, attachFlag, *info, maxDevices, shared)
 	if err != nil {
-		return fmt.Errorf("failed to find loop device: %s", err)
+		return err
 	}
 
 	path := fmt.Sprintf("/dev/loop%d", number)
@@ -1022,14 +1325,19 @@ func (c *container) mountImage(mnt *mount.Point, system *mount.System) error {
 	switch err {
 	case syscall.EINVAL:
 		if mountType == "squashfs" {
+			return c.squashfsMountError(mnt.Source, offset)
+		} else if mountType == "erofs" {
 			return fmt.Errorf(
 				"kernel reported a bad superblock for %s image partition, "+
 					"possible causes are that your kernel doesn't support "+
-					"the compression algorithm or the image is corrupted",
+					"the EROFS features used by the image or the image is corrupted",
 				mountType)
 		}
 		return fmt.Errorf("%s image partition contains a bad superblock (corrupted image ?)", mountType)
 	case syscall.ENODEV:
+		if mountType == "erofs" {
+			return errErofsNotSupported
+		}
 		return fmt.Errorf("%s filesystem seems not enabled and/or supported by your kernel", mountType)
 	default:
 		if err != nil {
@@ -1040,10 +1348,81 @@ func (c *container) mountImage(mnt *mount.Point, system *mount.System) error {
 	return nil
 }
 
+// squashfsMountError returns the error reported when the kernel rejects
+// the superblock of the squashfs partition found at offset in the image
+// source, the compression recorded for the partition tells whether the
+// kernel lacks support for it.
+func (c *container) squashfsMountError(source string, offset uint64) error {
+	comp := ""
+	for _, img := range c.engine.EngineConfig.GetImageList() {
+		if img.Source != source {
+			continue
+		}
+		for _, part := range img.Partitions {
+			if part.Offset == offset && part.Type == image.SQUASHFS {
+				comp = part.Compression
+			}
+		}
+	}
+
+	switch comp {
+	case "":
+		return fmt.Errorf(
+			"kernel reported a bad superblock for squashfs image partition, " +
+				"possible causes are that your kernel doesn't support " +
+				"the compression algorithm or the image is corrupted")
+	case "gzip":
+		// gzip is always supported by squashfs
+		return fmt.Errorf("kernel reported a bad superblock for squashfs image partition (corrupted image ?)")
+	}
+
+	release := "unknown version"
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err == nil {
+		release = unix.ByteSliceToString(uts.Release[:])
+	}
+	return fmt.Errorf("squashfs uses %s compression which this kernel (%s) does not support", comp, release)
+}
+
+// imageMountFlags returns the mount flags corresponding to the access
+// time options requested for ext3 and sandbox images.
+func (c *container) imageMountFlags() (uintptr, error) {
+	flags, err := mount.ImageMountFlags(c.engine.EngineConfig.GetImageMountOpts())
+	if err != nil {
+		return 0, fmt.Errorf("while checking image mount options: %s", err)
+	}
+	return flags, nil
+}
+
+// addImageFlags returns flags with the access time flags of the image
+// mount point dest. The lazytime flag isn't stored in the mount point
+// options, where it's ignored like for any other mount point, the mount
+// point is recorded instead and the flag is added by lazytimeFlag.
+func (c *container) addImageFlags(dest string, flags, imageFlags uintptr) uintptr {
+	if imageFlags&unix.MS_LAZYTIME != 0 && !slices.Contains(c.lazytimeMount, dest) {
+		c.lazytimeMount = append(c.lazytimeMount, dest)
+	}
+	return flags | imageFlags&^unix.MS_LAZYTIME
+}
+
+// lazytimeFlag returns MS_LAZYTIME if lazytime was requested for the
+// image mount point mnt.
+func (c *container) lazytimeFlag(mnt *mount.Point) uintptr {
+	if slices.Contains(c.lazytimeMount, mnt.Destination) {
+		return unix.MS_LAZYTIME
+	}
+	return 0
+}
+
 func (c *container) addRootfsMount(system *mount.System) error {
 	flags := uintptr(c.suidFlag | syscall.MS_NODEV)
 	rootfs := c.engine.EngineConfig.GetImage()
 
+	imageFlags, err := c.imageMountFlags()
+	if err != nil {
+		return err
+	}
+
 	imageObject := c.engine.EngineConfig.GetImageList()[0]
 	part, err := imageObject.GetRootFsPartition()
 	if err != nil {
@@ -1065,8 +1444,11 @@ func (c *container) addRootfsMount(system *mount.System) error {
 	switch part.Type {
 	case image.SQUASHFS:
 		mountType = "squashfs"
+	case image.EROFS:
+		mountType = "erofs"
 	case image.EXT3:
 		mountType = "ext3"
+		flags = c.addImageFlags(c.session.RootFsPath(), flags, imageFlags)
 	case image.ENCRYPTSQUASHFS:
 		mountType = "encryptfs"
 		key = c.engine.EngineConfig.GetEncryptionKey()
@@ -1075,7 +1457,9 @@ func (c *container) addRootfsMount(system *mount.System) error {
 		key = c.engine.EngineConfig.GetEncryptionKey()
 	case image.SANDBOX:
 		sylog.Debugf("Mounting directory rootfs: %v\n", rootfs)
-		flags |= syscall.MS_BIND
+		// access time flags are ignored by the bind mount and
+		// only take effect with the following remount
+		flags = c.addImageFlags(c.session.RootFsPath(), flags|syscall.MS_BIND, imageFlags)
 		if err := system.Points.AddBind(mount.RootfsTag, rootfs, c.session.RootFsPath(), flags); err != nil {
 			return err
 		}
@@ -1144,10 +1528,76 @@ func (c *container) overlayUpperWork(_ *mount.System) error {
 	return nil
 }
 
+// checkOverlayFreeSpace reports the free space of the writable overlay
+// image path once mounted, upper is the overlay upper directory located
+// in the mounted image.
+func (c *container) checkOverlayFreeSpace(path, upper string) {
+	free, total, err := fsoverlay.FreeSpace(upper)
+	if err != nil {
+		sylog.Debugf("Could not check free space of overlay image %s: %s", path, err)
+		return
+	}
+	reportOverlayUsage(path, free, total, c.engine.EngineConfig.GetOverlayWarnFree())
+}
+
+// reportOverlayUsage displays the usage of the writable overlay image path,
+// as a warning if the free space is below warnFree bytes.
+func reportOverlayUsage(path string, free, total, warnFree uint64) {
+	used := uint64(0)
+	if total > 0 && free <= total {
+		used = (total - free) * 100 / total
+	}
+	usage := fmt.Sprintf("%d%% used, %s free", used, units.BytesSize(float64(free)))
+	if free < warnFree {
+		sylog.Warningf("Writable overlay image %s is almost full (%s), writes will fail once it's full", path, usage)
+		return
+	}
+	sylog.Verbosef("Writable overlay image %s: %s", path, usage)
+}
+
 func (c *container) addOverlayMount(system *mount.System) error {
 	nb := 0
 	ov := c.session.Layer.(*overlay.Overlay)
 	hasUpper := false
+	maxLayers := c.engine.EngineConfig.File.MaxOverlayLayers
+	layers := uint(0)
+	ociLayers := c.engine.EngineConfig.GetOCILayers()
+	// host directories used as overlay layers
+	xinoDirs := make([]string, 0)
+
+	// AddLowerDir puts the last added directory on top, with the "first"
+	// precedence overlay lower layers are deferred and added in reverse
+	// order once all images are processed, OCI layers keep their order
+	firstWins := c.engine.EngineConfig.GetOverlayPrecedence() == apptainer.OverlayPrecedenceFirst
+	deferredLowers := make([]string, 0)
+	addLowerDir := func(dir string, ociLayer bool) {
+		if firstWins && !ociLayer {
+			deferredLowers = append(deferredLowers, dir)
+			return
+		}
+		ov.AddLowerDir(dir)
+	}
+
+	imageFlags, err := c.imageMountFlags()
+	if err != nil {
+		return err
+	}
+
+	overlayOpts := slices.Clone(c.engine.EngineConfig.GetOverlayOpts())
+	if c.engine.EngineConfig.File.OverlayMetacopy {
+		overlayOpts = append(overlayOpts, "metacopy")
+	}
+	if c.engine.EngineConfig.File.OverlayRedirectDir {
+		overlayOpts = append(overlayOpts, "redirect_dir")
+	}
+	options, err := mount.OverlayMountOptions(overlayOpts)
+	if err != nil {
+		return err
+	}
+	for _, o := range options {
+		sylog.Debugf("Adding %s overlay option", o)
+		ov.AddOption(o)
+	}
 
 	if c.engine.EngineConfig.GetWritableTmpfs() {
 		sylog.Debugf("Setup writable tmpfs overlay")
@@ -1185,6 +1635,9 @@ func (c *container) addOverlayMount(system *mount.System) error {
 	}
 
 	for _, img := range c.engine.EngineConfig.GetImageList() {
+		if img.Type == image.SANDBOX && img.Usage&image.RootFsUsage != 0 {
+			xinoDirs = append(xinoDirs, img.Path)
+		}
 		overlays, err := img.GetOverlayPartitions()
 		if err != nil {
 			return fmt.Errorf("while opening overlay image %s: %s", img.Path, err)
@@ -1192,12 +1645,27 @@ func (c *container) addOverlayMount(system *mount.System) error {
 		for _, overlay := range overlays {
 			sylog.Debugf("Using overlay partition in image %s", img.Path)
 
+			// only one writable image provides the upper directory,
+			// any other one is used as a read-only lower layer
+			writable := img.Writable && !hasUpper
+			if img.Writable && hasUpper {
+				sylog.Verbosef("Overlay image %s used as a read-only lower layer, an upper layer is already set", img.Path)
+			}
+
+			// check before attaching loop devices, only writable
+			// images provide the upper directory
+			if overlay.Type == image.SQUASHFS || overlay.Type == image.EROFS || !writable {
+				if layers++; layers > maxLayers {
+					return errOverlayLayers(maxLayers)
+				}
+			}
+
 			sessionDest := fmt.Sprintf("/overlay-images/%d", nb)
 			if err := c.session.AddDir(sessionDest); err != nil {
 				return fmt.Errorf("failed to create session directory for overlay: %s", err)
 			}
 			dst, _ := c.session.GetPath(sessionDest)
-			umountPoints = append(umountPoints, umountPoint{dst, img.Writable})
+			umountPoints = append(umountPoints, umountPoint{dst, writable})
 			nb++
 
 			src := img.Source
@@ -1206,24 +1674,42 @@ func (c *container) addOverlayMount(system *mount.System) error {
 
 			switch overlay.Type {
 			case image.EXT3:
-				flags := uintptr(c.suidFlag | syscall.MS_NODEV)
+				flags := c.addImageFlags(dst, uintptr(c.suidFlag|syscall.MS_NODEV), imageFlags)
 
-				if !img.Writable {
+				if !writable {
 					flags |= syscall.MS_RDONLY
-					ov.AddLowerDir(filepath.Join(dst, "upper"))
+					addLowerDir(filepath.Join(dst, "upper"), false)
 				}
 
 				err = system.Points.AddImage(mount.PreLayerTag, src, dst, "ext3", flags, offset, size, nil)
 				if err != nil {
 					return fmt.Errorf("while adding ext3 image: %s", err)
 				}
+
+				if writable && c.engine.EngineConfig.GetOverlayWarnFree() > 0 {
+					imgPath := img.Path
+					err = system.RunAfterTag(mount.PreLayerTag, func(*mount.System) error {
+						c.checkOverlayFreeSpace(imgPath, filepath.Join(dst, "upper"))
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+				}
 			case image.SQUASHFS:
 				flags := uintptr(c.suidFlag | syscall.MS_NODEV | syscall.MS_RDONLY)
 				err = system.Points.AddImage(mount.PreLayerTag, src, dst, "squashfs", flags, offset, size, nil)
 				if err != nil {
 					return err
 				}
-				ov.AddLowerDir(dst)
+				addLowerDir(dst, false)
+			case image.EROFS:
+				flags := uintptr(c.suidFlag | syscall.MS_NODEV | syscall.MS_RDONLY)
+				err = system.Points.AddImage(mount.PreLayerTag, src, dst, "erofs", flags, offset, size, nil)
+				if err != nil {
+					return err
+				}
+				addLowerDir(dst, false)
 			case image.SANDBOX:
 				overlayImageDriver := false
 				if imageDriver != nil && imageDriver.Features()&image.OverlayFeature != 0 {
@@ -1237,7 +1723,10 @@ func (c *container) addOverlayMount(system *mount.System) error {
 					// go ahead and try unprivileged kernel overlay
 				}
 
-				flags := uintptr(c.suidFlag | syscall.MS_NODEV)
+				xinoDirs = append(xinoDirs, img.Path)
+
+				// access time flags only take effect with the remount
+				flags := c.addImageFlags(dst, uintptr(c.suidFlag|syscall.MS_NODEV), imageFlags)
 				err = system.Points.AddBind(mount.PreLayerTag, img.Path, dst, flags)
 				if err != nil {
 					return fmt.Errorf("while adding sandbox image: %s", err)
@@ -1248,7 +1737,7 @@ func (c *container) addOverlayMount(system *mount.System) error {
 					// When no overlay image driver available,
 					//  make sure filesystems type are compatible
 					//  with kernel overlayfs
-					if !img.Writable {
+					if !writable {
 						// check if the sandbox directory is located on a compatible
 						// filesystem usable overlay lower directory
 						if err := fsoverlay.CheckLower(img.Path); err != nil {
@@ -1263,11 +1752,15 @@ func (c *container) addOverlayMount(system *mount.System) error {
 					}
 				}
 
-				if !img.Writable {
-					if fs.IsDir(filepath.Join(img.Path, "upper")) {
-						ov.AddLowerDir(filepath.Join(dst, "upper"))
+				if !writable {
+					// an OCI layer holds root filesystem content which
+					// may contain an unrelated upper directory
+					if slices.Contains(ociLayers, img.Path) {
+						addLowerDir(dst, true)
+					} else if fs.IsDir(filepath.Join(img.Path, "upper")) {
+						addLowerDir(filepath.Join(dst, "upper"), false)
 					} else {
-						ov.AddLowerDir(dst)
+						addLowerDir(dst, false)
 					}
 				}
 			default:
@@ -1279,7 +1772,7 @@ func (c *container) addOverlayMount(system *mount.System) error {
 				return err
 			}
 
-			if img.Writable && !hasUpper {
+			if writable {
 				upper := filepath.Join(dst, "upper")
 				work := filepath.Join(dst, "work")
 
@@ -1295,12 +1788,26 @@ func (c *container) addOverlayMount(system *mount.System) error {
 		}
 	}
 
+	for i := len(deferredLowers) - 1; i >= 0; i-- {
+		ov.AddLowerDir(deferredLowers[i])
+	}
+
 	if hasUpper {
 		if err := system.RunAfterTag(mount.PreLayerTag, c.overlayUpperWork); err != nil {
 			return err
 		}
 	}
 
+	// request the xino feature only when the host directories used as
+	// overlay layers support it, rather than retrying the mount without it
+	for _, dir := range xinoDirs {
+		if err := fsoverlay.CheckXino(dir); err != nil {
+			sylog.Debugf("Not requesting overlay xino feature: %s", err)
+			ov.DisableXino()
+			break
+		}
+	}
+
 	return system.Points.AddPropagation(mount.SharedTag, c.session.FinalPath(), syscall.MS_UNBINDABLE)
 }
 
@@ -1309,7 +1816,7 @@ func (c *container) addImageBindMount(system *mount.System) error {
 	imageList := c.engine.EngineConfig.GetImageList()
 
 	for _, bind := range c.engine.EngineConfig.GetBindPath() {
-		if bind.ImageSrc() == "" && bind.ID() == "" {
+		if !bind.IsImageBind() {
 			continue
 		} else if !c.engine.EngineConfig.File.UserBindControl {
 			sylog.Warningf("Ignoring image bind mount request: user bind control disabled by system administrator")
@@ -1345,16 +1852,25 @@ func (c *container) addImageBindMount(system *mount.System) error {
 
 			data := (*image.Section)(nil)
 
-			// id is only meaningful for SIF images
-			if img.Type == image.SIF && partID > 0 {
+			// id and name are only meaningful for SIF images
+			if name := bind.PartitionName(); name != "" {
+				if img.Type != image.SIF {
+					return fmt.Errorf("name bind option is only supported with SIF images, %s is not a SIF image", img.Path)
+				}
+				var err error
+				data, err = img.GetPartitionByName(name)
+				if err != nil {
+					return err
+				}
+			} else if img.Type == image.SIF && partID > 0 {
 				partitions, err := img.GetAllPartitions()
 				if err != nil {
 					return fmt.Errorf("while getting partitions for %s: %s", img.Path, err)
 				}
 				data = getPartitionByID(partitions, partID)
 			} else {
-				// take the first data partition found
-				partitions, err := img.GetDataPartitions()
+				// take the first data partition with a supported filesystem
+				partitions, err := img.GetDataPartitionsByType(image.EXT3, image.SQUASHFS, image.EROFS)
 				if err != nil {
 					return fmt.Errorf("while getting data partition for %s: %s", img.Path, err)
 				}
@@ -1385,6 +1901,9 @@ func (c *container) addImageBindMount(system *mount.System) error {
 			case image.SQUASHFS:
 				flags |= syscall.MS_RDONLY
 				fstype = "squashfs"
+			case image.EROFS:
+				flags |= syscall.MS_RDONLY
+				fstype = "erofs"
 			default:
 				return fmt.Errorf("could not use %s for image binding: not supported image format", img.Path)
 			}
@@ -1482,12 +2001,93 @@ func (c *container) addKernelMount(system *mount.System) error {
 			return fmt.Errorf("unable to add sys to mount list: %s", err)
 		}
 		sylog.Verbosef("Default mount: /sys:/sys")
+
+		if c.cgroupNS || c.engine.EngineConfig.GetBindCgroupfs() {
+			if err := c.addCgroupfsMount(system); err != nil {
+				return fmt.Errorf("unable to add cgroup filesystem to mount list: %s", err)
+			}
+		}
 	} else {
 		sylog.Verbosef("Skipping /sys mount")
 	}
+
+	return c.addKernelFsMounts(system)
+}
+
+// addKernelFsMounts adds the kernel filesystems requested with
+// --mount type=kernelfs. As they expose kernel internals, unprivileged
+// users can only mount those allowed by the 'allow kernel fs' directive.
+func (c *container) addKernelFsMounts(system *mount.System) error {
+	flags := uintptr(syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC)
+
+	for _, km := range c.engine.EngineConfig.GetKernelMounts() {
+		if _, ok := apptainer.KernelFilesystems[km.Name]; !ok {
+			return fmt.Errorf("unsupported kernel filesystem %s", km.Name)
+		}
+		privileged := os.Getuid() == 0 || c.engine.EngineConfig.GetFakeroot()
+		if !privileged && !slices.Contains(c.engine.EngineConfig.File.AllowKernelFs, km.Name) {
+			return fmt.Errorf("mounting %s requires root or --fakeroot, or must be allowed by the 'allow kernel fs' directive in apptainer.conf", km.Name)
+		}
+
+		sylog.Debugf("Adding %s to mount list at %s", km.Name, km.Destination)
+		if err := system.Points.AddFS(mount.KernelTag, km.Destination, km.Name, flags, ""); err == mount.ErrMountExists {
+			sylog.Warningf("While mounting %s: %s", km.Name, err)
+		} else if err != nil {
+			return fmt.Errorf("unable to add %s to mount list: %s", km.Name, err)
+		}
+	}
+
 	return nil
 }
 
+// addCgroupfsMount adds a read-only mount of /sys/fs/cgroup. With a cgroup
+// namespace and cgroups v2, the container cgroup directory is bound so it
+// matches the cgroup namespace root, otherwise the host /sys/fs/cgroup is
+// bound.
+func (c *container) addCgroupfsMount(system *mount.System) error {
+	const cgroupfs = "/sys/fs/cgroup"
+	flags := uintptr(syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC | syscall.MS_RDONLY)
+
+	source := cgroupfs
+
+	if c.cgroupNS {
+		if lccgroups.IsCgroup2UnifiedMode() {
+			// the cgroup namespace is created by the container process
+			// once the RPC server exits, its root is the cgroup where
+			// the process has been placed by applyCgroups
+			path, err := cgroupfsPath(c.containerPid)
+			if err != nil {
+				return err
+			}
+			source = filepath.Join(cgroupfs, path)
+		} else {
+			sylog.Warningf("A cgroup filesystem can only be mounted in a cgroup namespace with cgroups v2, binding host %s instead", cgroupfs)
+		}
+	}
+
+	sylog.Debugf("Adding %s to mount list", source)
+	bindFlags := flags | syscall.MS_BIND | syscall.MS_REC
+	if err := system.Points.AddBind(mount.KernelTag, source, cgroupfs, bindFlags); err != nil {
+		return err
+	}
+	return system.Points.AddRemount(mount.KernelTag, cgroupfs, bindFlags)
+}
+
+// cgroupfsPath returns the cgroups v2 path of process pid relative to
+// the cgroup filesystem root.
+func cgroupfsPath(pid int) (string, error) {
+	cgroupFile := fmt.Sprintf("/proc/%d/cgroup", pid)
+	paths, err := lccgroups.ParseCgroupFile(cgroupFile)
+	if err != nil {
+		return "", fmt.Errorf("while reading %s: %s", cgroupFile, err)
+	}
+	path, ok := paths[""]
+	if !ok {
+		return "", fmt.Errorf("no cgroups v2 path found in %s", cgroupFile)
+	}
+	return path, nil
+}
+
 func (c *container) addSessionDevAt(srcpath string, atpath string, system *mount.System) error {
 	fi, err := os.Lstat(srcpath)
 	if err != nil {
@@ -1613,7 +2213,11 @@ func (c *container) addDevMount(system *mount.System) error {
 
 		}
 		// add /dev/console mount pointing to original tty if there is one
-		for fd := 0; fd <= 2; fd++ {
+		noConsole := c.engine.EngineConfig.GetNoTTYConsole() || !c.engine.EngineConfig.File.MountTTYConsole
+		if noConsole {
+			sylog.Debugf("Skipping /dev/console tty bind")
+		}
+		for fd := 0; fd <= 2 && !noConsole; fd++ {
 			if !term.IsTerminal(fd) {
 				continue
 			}
@@ -1692,6 +2296,17 @@ func (c *container) addDevMount(system *mount.System) error {
 					return err
 				}
 			}
+		} else if c.engine.EngineConfig.GetDRI() {
+			// the whole /dev/dri directory is already added for rocm
+			devs, err := gpu.DRIDevices()
+			if err != nil {
+				return fmt.Errorf("failed to get dri devices: %v", err)
+			}
+			for _, dev := range devs {
+				if err := c.addSessionDev(dev, system); err != nil {
+					return err
+				}
+			}
 		}
 
 		if err := c.addSessionDev("/dev/fd", system); err != nil {
@@ -1731,7 +2346,8 @@ func (c *container) addHostMount(system *mount.System) error {
 
 	info, err := proc.GetMountPointMap("/proc/self/mountinfo")
 	if err != nil {
-		return err
+		sylog.Warningf("Not mounting host file systems, could not get host mount points: %s", err)
+		return nil
 	}
 	flags := uintptr(syscall.MS_BIND | c.suidFlag | syscall.MS_NODEV | syscall.MS_REC)
 	for _, child := range info["/"] {
@@ -1886,7 +2502,11 @@ func (c *container) getHomePaths() (source string, dest string, err error) {
 	} else {
 		source = c.engine.EngineConfig.JSON.UserInfo.Home
 		if source != "" {
-			if c.engine.EngineConfig.GetFakeroot() || os.Getuid() == 0 {
+			if c.engine.EngineConfig.GetFakeroot() && c.engine.EngineConfig.GetHomeKeepPath() {
+				// Keep the user home directory path as requested,
+				// the passwd entry uses the same path
+				dest = source
+			} else if c.engine.EngineConfig.GetFakeroot() || os.Getuid() == 0 {
 				// Mount user home directory onto /root for
 				//  any root-mapped namespace
 				dest = "/root"
@@ -1977,131 +2597,410 @@ func (c *container) addHomeNoLayer(system *mount.System, dest string) error {
 	return nil
 }
 
-// addHomeMount is responsible for adding the home directory mount using the proper method
-func (c *container) addHomeMount(system *mount.System) error {
-	if c.engine.EngineConfig.GetNoHome() {
-		sylog.Debugf("Skipping home directory mount by user request.")
-		return nil
-	}
-
-	if !c.engine.EngineConfig.GetCustomHome() && !c.engine.EngineConfig.File.MountHome {
-		sylog.Debugf("Skipping home dir mounting (per config)")
-		return nil
-	}
+// addHomeMount is responsible for adding the home directory mount using the proper method
+func (c *container) addHomeMount(system *mount.System) error {
+	if c.engine.EngineConfig.GetNoHome() {
+		sylog.Debugf("Skipping home directory mount by user request.")
+		return nil
+	}
+
+	if !c.engine.EngineConfig.GetCustomHome() && !c.engine.EngineConfig.File.MountHome {
+		sylog.Debugf("Skipping home dir mounting (per config)")
+		return nil
+	}
+
+	// check if user attempt to mount a custom home when not allowed to
+	if c.engine.EngineConfig.GetCustomHome() && !c.engine.EngineConfig.File.UserBindControl {
+		return fmt.Errorf("not mounting user requested home: user bind control is disallowed")
+	}
+
+	source, dest, err := c.getHomePaths()
+	if err != nil {
+		return fmt.Errorf("unable to get home source/destination: %v", err)
+	}
+
+	// issue #5228 - don't attempt to mount a '/' home dir like 'nobody' has
+	if dest == "/" {
+		sylog.Warningf("Skipping impossible home directory mount to '/'")
+		return nil
+	}
+
+	stagingDir, err := c.addHomeStagingDir(system, source, dest)
+	if err != nil {
+		return err
+	}
+
+	sessionLayer := c.engine.EngineConfig.GetSessionLayer()
+	sylog.Debugf("Adding home directory mount [%v:%v] to list using layer: %s\n", stagingDir, dest, sessionLayer)
+	if !c.isLayerEnabled() {
+		return c.addHomeNoLayer(system, dest)
+	}
+	return c.addHomeLayer(system, stagingDir, dest)
+}
+
+func (c *container) addUserbindsMount(system *mount.System) error {
+	const devPrefix = "/dev"
+	defaultFlags := uintptr(syscall.MS_BIND | c.suidFlag | syscall.MS_NODEV | syscall.MS_REC)
+
+	for _, b := range c.engine.EngineConfig.GetBindPath() {
+		if strings.HasPrefix(b.Destination, "/.singularity.d/libs") {
+			// Defer to library bind time because otherwise the
+			//  binds here will get hidden under a new directory
+			libraries := c.engine.EngineConfig.GetLibrariesPath()
+			if b.Source == b.Destination {
+				libraries = append(libraries, b.Source)
+			} else {
+				libraries = append(libraries, b.Source+":"+b.Destination)
+			}
+			c.engine.EngineConfig.SetLibrariesPath(libraries)
+			continue
+		}
+		// data image bind
+		if b.IsImageBind() {
+			continue
+		}
+
+		flags := defaultFlags
+		source := b.Source
+		dst := b.Destination
+
+		src, err := filepath.Abs(source)
+		if err != nil {
+			sylog.Warningf("Can't determine absolute path of %s bind point", source)
+			continue
+		}
+		if b.Readonly() {
+			flags |= syscall.MS_RDONLY
+		}
+
+		// special case for /dev mount to override default mount behavior
+		// with --contain option or 'mount dev = minimal'
+		if strings.HasPrefix(dst, devPrefix) && strings.HasPrefix(src, devPrefix) {
+			if dst != src {
+				if err := c.skipUserBind(src, fmt.Sprintf("source and destination must be identical when binding to %s", devPrefix)); err != nil {
+					return err
+				}
+				continue
+			}
+			if c.engine.EngineConfig.File.MountDev == "no" || c.engine.EngineConfig.GetNoDev() {
+				if err := c.skipUserBind(src, "disallowed by configuration"); err != nil {
+					return err
+				}
+				continue
+			} else if c.engine.EngineConfig.File.MountDev == "minimal" || c.engine.EngineConfig.GetContain() {
+				// "--bind /dev" bind case
+				if src == devPrefix {
+					system.Points.RemoveByTag(mount.DevTag)
+					c.devSourcePath = devPrefix
+					sylog.Debugf("Adding %[1]s host bind mount, resetting container mount list for %[1]s\n", devPrefix)
+					continue
+				}
+				_, err := c.session.GetPath(src)
+				if err == nil {
+					if err := c.skipUserBind(src, "already mounted"); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := c.addSessionDev(src, system); err != nil {
+					if err := c.skipUserBind(src, err.Error()); err != nil {
+						return err
+					}
+				}
+				sylog.Debugf("Adding device %s to mount list\n", src)
+				continue
+			}
+			// proceed with normal binds below if 'mount dev = yes'
+			// or '--contain' wasn't requested
+		}
+		if !c.engine.EngineConfig.File.UserBindControl {
+			if c.engine.EngineConfig.GetStrictBinds() {
+				return fmt.Errorf("can't bind %s with --strict-binds: user bind control disabled by system administrator", src)
+			}
+			sylog.Warningf("Ignoring %s bind mount: user bind control disabled by system administrator", src)
+			continue
+		}
+
+		if b.Mkdir() {
+			if err := c.createBindParentDir(system, dst); err != nil {
+				return err
+			}
+		}
+		if b.Mkfile() {
+			if err := c.createBindFile(system, src, dst); err != nil {
+				return err
+			}
+		}
+
+		var pflags uintptr
+		if p := b.Propagation(); p != "" {
+			pflags, err = bindPropagationFlags(p)
+			if err != nil {
+				return err
+			}
+		}
+
+		sylog.Debugf("Adding %s to mount list\n", src)
+
+		if err := system.Points.AddBind(mount.UserbindsTag, src, dst, flags); err == mount.ErrMountExists {
+			sylog.Warningf("While bind mounting '%s:%s': %s", src, dst, err)
+		} else if err != nil {
+			return fmt.Errorf("unable to add %s to mount list: %s", src, err)
+		} else {
+			fi, err := os.Stat(src)
+			if err == nil && fi.IsDir() {
+				c.session.OverrideDir(dst, src)
+			}
+			system.Points.AddRemount(mount.UserbindsTag, dst, flags)
+			if pflags != 0 {
+				if err := system.Points.AddPropagation(mount.UserbindsTag, dst, pflags); err != nil {
+					return fmt.Errorf("unable to set %s mount propagation: %s", dst, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// skipUserBind warns that the user bind of src is skipped for the given
+// reason, or returns an error if --strict-binds was requested.
+func (c *container) skipUserBind(src, reason string) error {
+	if c.engine.EngineConfig.GetStrictBinds() {
+		return fmt.Errorf("can't bind %s with --strict-binds: %s", src, reason)
+	}
+	sylog.Warningf("Skipping %s bind mount: %s", src, reason)
+	return nil
+}
+
+// createBindParentDir creates the parent directory of the bind destination
+// dst in the session layer, so a single file or socket can be bound into a
+// directory which doesn't exist in the container. It requires a layer as
+// the container image can't be modified. The parent directory is resolved
+// within the container root filesystem once mounted, so a symlinked parent
+// like /var/run -> /run isn't shadowed by a directory in the layer.
+func (c *container) createBindParentDir(system *mount.System, dst string) error {
+	if c.session.Layer == nil {
+		if c.engine.EngineConfig.GetWritableImage() {
+			return fmt.Errorf("by using --writable, Apptainer can't create %s destination parent directory without overlay or underlay", dst)
+		}
+		return fmt.Errorf("no layer in use (overlay or underlay), check your configuration, "+
+			"Apptainer can't create %s destination parent directory without overlay or underlay", dst)
+	}
+	// the underlay layer creates the parent directories of
+	// all the missing mount point destinations
+	if c.engine.EngineConfig.GetSessionLayer() == apptainer.UnderlayLayer {
+		return nil
+	}
+
+	return system.RunAfterTag(mount.RootfsTag, func(*mount.System) error {
+		parent, exists := c.layerPath(filepath.Dir(filepath.Clean(dst)))
+		if exists {
+			return nil
+		}
+		if _, err := c.session.GetPath(parent); err == nil {
+			return nil
+		}
+		sylog.Debugf("Creating %s bind destination parent directory", dst)
+		if err := c.session.AddDir(parent); err != nil {
+			return fmt.Errorf("while creating %s destination parent directory: %s", dst, err)
+		}
+		return nil
+	})
+}
+
+// layerPath returns the path in the session layer corresponding to the
+// container path p once its symlinks are resolved within the container
+// root filesystem, along with whether p already exists in the root
+// filesystem.
+func (c *container) layerPath(p string) (string, bool) {
+	rootfs := c.session.RootFsPath()
+	resolved := c.session.VFS.EvalRelative(p, rootfs)
+	_, err := c.session.VFS.Stat(filepath.Join(rootfs, resolved))
+	return filepath.Join(c.session.Layer.Dir(), resolved), err == nil
+}
+
+// createBindFile creates the bind destination dst as an empty file in the
+// session layer, so the file src can be bound to a file which doesn't exist
+// in the container. Like createBindParentDir it requires a layer and
+// resolves dst within the container root filesystem once mounted.
+func (c *container) createBindFile(system *mount.System, src, dst string) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("while getting stat for %s: %s", src, err)
+	} else if !fi.Mode().IsRegular() {
+		return fmt.Errorf("mkfile bind option requires a regular file source, %s is not", src)
+	}
+	if c.session.Layer == nil {
+		if c.engine.EngineConfig.GetWritableImage() {
+			return fmt.Errorf("by using --writable, Apptainer can't create %s destination file without overlay or underlay", dst)
+		}
+		return fmt.Errorf("no layer in use (overlay or underlay), check your configuration, "+
+			"Apptainer can't create %s destination file without overlay or underlay", dst)
+	}
+	// the underlay layer creates all the missing
+	// mount point destinations
+	if c.engine.EngineConfig.GetSessionLayer() == apptainer.UnderlayLayer {
+		return nil
+	}
+
+	return system.RunAfterTag(mount.RootfsTag, func(*mount.System) error {
+		path, exists := c.layerPath(filepath.Clean(dst))
+		if exists {
+			return nil
+		}
+		if _, err := c.session.GetPath(path); err == nil {
+			return nil
+		}
+		sylog.Debugf("Creating %s bind destination file", dst)
+		if err := c.session.AddFile(path, nil); err != nil {
+			return fmt.Errorf("while creating %s destination file: %s", dst, err)
+		}
+		return nil
+	})
+}
+
+// bindPropagationFlags returns the mount flags corresponding to the
+// propagation bind option.
+func bindPropagationFlags(propagation string) (uintptr, error) {
+	flags := uintptr(syscall.MS_REC)
+
+	switch propagation {
+	case "slave":
+		flags |= syscall.MS_SLAVE
+	case "private":
+		flags |= syscall.MS_PRIVATE
+	case "unbindable":
+		flags |= syscall.MS_UNBINDABLE
+	default:
+		return 0, fmt.Errorf("unknown mount propagation %s", propagation)
+	}
+	return flags, nil
+}
+
+// addMountFromMount mounts the paths of running instances requested with
+// --mount-from. Source paths are resolved within the instance root
+// filesystem through /proc/<pid>/root, the resulting bind mounts remain
+// in the container if the instance exits but a FUSE mount becomes
+// unusable once its FUSE process is gone.
+func (c *container) addMountFromMount(system *mount.System) error {
+	flags := uintptr(syscall.MS_BIND | c.suidFlag | syscall.MS_NODEV | syscall.MS_REC)
+
+	for _, mf := range c.engine.EngineConfig.GetMountFrom() {
+		if !c.engine.EngineConfig.File.UserBindControl {
+			sylog.Warningf("Ignoring %s mount from instance %s: user bind control disabled by system administrator", mf.Source, mf.Instance)
+			continue
+		}
+
+		root := filepath.Join("/proc", strconv.Itoa(mf.Pid), "root")
+		src := filepath.Join(root, fs.EvalRelative(mf.Source, root))
+		fi, err := os.Stat(src)
+		if err != nil {
+			return fmt.Errorf("while getting stat for %s in instance %s: %s", mf.Source, mf.Instance, err)
+		}
+
+		sylog.Debugf("Adding %s from instance %s to mount list\n", mf.Source, mf.Instance)
+
+		if err := system.Points.AddBind(mount.UserbindsTag, src, mf.Destination, flags); err == mount.ErrMountExists {
+			sylog.Warningf("While mounting '%s' from instance %s: %s", mf.Source, mf.Instance, err)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("unable to add %s from instance %s to mount list: %s", mf.Source, mf.Instance, err)
+		}
+		if fi.IsDir() {
+			c.session.OverrideDir(mf.Destination, src)
+		}
+		system.Points.AddRemount(mount.UserbindsTag, mf.Destination, flags)
+	}
+
+	return nil
+}
+
+// addTmpfsMounts mounts the tmpfs filesystems requested with
+// --mount type=tmpfs. Without privileges the tmpfs size is limited
+// by the sessiondir max size directive, like the session directory.
+func (c *container) addTmpfsMounts(system *mount.System) error {
+	flags := uintptr(syscall.MS_NOSUID | syscall.MS_NODEV)
 
-	// check if user attempt to mount a custom home when not allowed to
-	if c.engine.EngineConfig.GetCustomHome() && !c.engine.EngineConfig.File.UserBindControl {
-		return fmt.Errorf("not mounting user requested home: user bind control is disallowed")
-	}
+	for _, tm := range c.engine.EngineConfig.GetTmpfsMounts() {
+		if !c.engine.EngineConfig.File.UserBindControl {
+			sylog.Warningf("Ignoring tmpfs mount %s: user bind control disabled by system administrator", tm.Destination)
+			continue
+		}
 
-	source, dest, err := c.getHomePaths()
-	if err != nil {
-		return fmt.Errorf("unable to get home source/destination: %v", err)
-	}
+		if c.sessionSize > 0 {
+			maxSize := int64(c.sessionSize) * 1024 * 1024
+			if tm.Size > maxSize {
+				return fmt.Errorf("tmpfs mount %s size exceeds the 'sessiondir max size' of %d MiB set in apptainer.conf", tm.Destination, c.sessionSize)
+			} else if tm.Size == 0 {
+				tm.Size = maxSize
+			}
+		}
 
-	// issue #5228 - don't attempt to mount a '/' home dir like 'nobody' has
-	if dest == "/" {
-		sylog.Warningf("Skipping impossible home directory mount to '/'")
-		return nil
-	}
+		sylog.Debugf("Adding tmpfs %s to mount list with options %q\n", tm.Destination, tm.Options())
 
-	stagingDir, err := c.addHomeStagingDir(system, source, dest)
-	if err != nil {
-		return err
+		if err := system.Points.AddFS(mount.UserbindsTag, tm.Destination, "tmpfs", flags, tm.Options()); err == mount.ErrMountExists {
+			sylog.Warningf("While mounting tmpfs %s: %s", tm.Destination, err)
+		} else if err != nil {
+			return fmt.Errorf("unable to add tmpfs %s to mount list: %s", tm.Destination, err)
+		}
 	}
 
-	sessionLayer := c.engine.EngineConfig.GetSessionLayer()
-	sylog.Debugf("Adding home directory mount [%v:%v] to list using layer: %s\n", stagingDir, dest, sessionLayer)
-	if !c.isLayerEnabled() {
-		return c.addHomeNoLayer(system, dest)
-	}
-	return c.addHomeLayer(system, stagingDir, dest)
+	return nil
 }
 
-func (c *container) addUserbindsMount(system *mount.System) error {
-	const devPrefix = "/dev"
-	defaultFlags := uintptr(syscall.MS_BIND | c.suidFlag | syscall.MS_NODEV | syscall.MS_REC)
+// addOverlayBindsMount mounts the host directories requested with
+// --overlay-bind as the lower layer of an overlay, the upper and work
+// directories are created in the session directory so modifications
+// are discarded when the container exits.
+func (c *container) addOverlayBindsMount(system *mount.System) error {
+	flags := uintptr(c.suidFlag | syscall.MS_NODEV)
 
-	for _, b := range c.engine.EngineConfig.GetBindPath() {
-		if strings.HasPrefix(b.Destination, "/.singularity.d/libs") {
-			// Defer to library bind time because otherwise the
-			//  binds here will get hidden under a new directory
-			libraries := c.engine.EngineConfig.GetLibrariesPath()
-			if b.Source == b.Destination {
-				libraries = append(libraries, b.Source)
-			} else {
-				libraries = append(libraries, b.Source+":"+b.Destination)
-			}
-			c.engine.EngineConfig.SetLibrariesPath(libraries)
-			continue
-		}
-		// data image bind
-		if b.ID() != "" || b.ImageSrc() != "" {
-			continue
+	for i, b := range c.engine.EngineConfig.GetOverlayBind() {
+		fi, err := os.Stat(b.Lower)
+		if err != nil {
+			return fmt.Errorf("while getting stat for overlay bind %s: %s", b.Lower, err)
 		}
 
-		flags := defaultFlags
-		source := b.Source
-		dst := b.Destination
+		sessionDir := fmt.Sprintf("/overlay-binds/%d", i)
+		upperDir := filepath.Join(sessionDir, "upper")
+		workDir := filepath.Join(sessionDir, "work")
 
-		src, err := filepath.Abs(source)
-		if err != nil {
-			sylog.Warningf("Can't determine absolute path of %s bind point", source)
-			continue
+		if err := c.session.AddDir(upperDir); err != nil {
+			return err
 		}
-		if b.Readonly() {
-			flags |= syscall.MS_RDONLY
+		if err := c.session.AddDir(workDir); err != nil {
+			return err
 		}
-
-		// special case for /dev mount to override default mount behavior
-		// with --contain option or 'mount dev = minimal'
-		if strings.HasPrefix(dst, devPrefix) && strings.HasPrefix(src, devPrefix) {
-			if dst != src {
-				sylog.Warningf("Skipping %s bind mount: source and destination must be identical when binding to %s", src, devPrefix)
-				continue
-			}
-			if c.engine.EngineConfig.File.MountDev == "no" || c.engine.EngineConfig.GetNoDev() {
-				sylog.Warningf("Skipping %s bind mount: disallowed by configuration", src)
-				continue
-			} else if c.engine.EngineConfig.File.MountDev == "minimal" || c.engine.EngineConfig.GetContain() {
-				// "--bind /dev" bind case
-				if src == devPrefix {
-					system.Points.RemoveByTag(mount.DevTag)
-					c.devSourcePath = devPrefix
-					sylog.Debugf("Adding %[1]s host bind mount, resetting container mount list for %[1]s\n", devPrefix)
-					continue
-				}
-				_, err := c.session.GetPath(src)
-				if err == nil {
-					sylog.Warningf("Skipping %s bind mount: already mounted", src)
-					continue
-				}
-				if err := c.addSessionDev(src, system); err != nil {
-					sylog.Warningf("Skipping %s bind mount: %s", src, err)
-				}
-				sylog.Debugf("Adding device %s to mount list\n", src)
-				continue
-			}
-			// proceed with normal binds below if 'mount dev = yes'
-			// or '--contain' wasn't requested
+		// the overlay root directory inherits its attributes from the upper directory
+		if err := c.session.Chmod(upperDir, fi.Mode().Perm()); err != nil {
+			return err
 		}
-		if !c.engine.EngineConfig.File.UserBindControl {
-			sylog.Warningf("Ignoring %s bind mount: user bind control disabled by system administrator", src)
-			continue
+		if !c.userNS {
+			st := fi.Sys().(*syscall.Stat_t)
+			if err := c.session.Chown(upperDir, int(st.Uid), int(st.Gid)); err != nil {
+				return err
+			}
 		}
 
-		sylog.Debugf("Adding %s to mount list\n", src)
+		upper, _ := c.session.GetPath(upperDir)
+		work, _ := c.session.GetPath(workDir)
 
-		if err := system.Points.AddBind(mount.UserbindsTag, src, dst, flags); err == mount.ErrMountExists {
-			sylog.Warningf("While bind mounting '%s:%s': %s", src, dst, err)
+		sylog.Debugf("Adding overlay bind %s to mount list with upper %s", b.Lower, upper)
+
+		var options []string
+		if err := fsoverlay.CheckXino(b.Lower); err != nil {
+			sylog.Debugf("Not requesting overlay xino feature: %s", err)
+		} else {
+			options = append(options, mount.OverlayXinoOption)
+		}
+
+		if err := system.Points.AddOverlay(mount.UserbindsTag, b.Target, flags, b.Lower, upper, work, options...); err == mount.ErrMountExists {
+			sylog.Warningf("While overlay bind mounting '%s:%s': %s", b.Lower, b.Target, err)
 		} else if err != nil {
-			return fmt.Errorf("unable to add %s to mount list: %s", src, err)
+			return fmt.Errorf("unable to add overlay bind %s to mount list: %s", b.Lower, err)
 		} else {
-			fi, err := os.Stat(src)
-			if err == nil && fi.IsDir() {
-				c.session.OverrideDir(dst, src)
-			}
-			system.Points.AddRemount(mount.UserbindsTag, dst, flags)
+			c.session.OverrideDir(b.Target, b.Lower)
 		}
 	}
 
@@ -2235,7 +3134,22 @@ func (c *container) addScratchMount(system *mount.System) error {
 		}
 	}
 
-	for _, dir := range scratchDir {
+	for _, spec := range scratchDir {
+		sd, err := apptainer.ParseScratchDir(spec)
+		if err != nil {
+			return err
+		}
+		dir := sd.Path
+
+		if sd.Size > 0 && hasWorkdir {
+			sylog.Verbosef("Scratch directory %s is backed by the workdir, its size of %d bytes is not enforced", dir, sd.Size)
+		} else if sd.Size > 0 {
+			if err := c.addSizedScratchMount(system, sd); err != nil {
+				return err
+			}
+			continue
+		}
+
 		src := filepath.Join(scratchSessionDir, dir)
 		if err := c.session.AddDir(src); err != nil {
 			return fmt.Errorf("could not create scratch working directory %s: %s", src, err)
@@ -2258,6 +3172,27 @@ func (c *container) addScratchMount(system *mount.System) error {
 	return nil
 }
 
+// addSizedScratchMount mounts a dedicated tmpfs limited to the scratch
+// directory size, unprivileged users are limited by the sessiondir max
+// size directive like for the session directory.
+func (c *container) addSizedScratchMount(system *mount.System, sd apptainer.ScratchDir) error {
+	size := sd.Size
+	if os.Geteuid() != 0 {
+		maxSize := int64(c.engine.EngineConfig.File.SessiondirMaxSize) * 1024 * 1024
+		if size > maxSize {
+			sylog.Warningf("Scratch directory %s size limited to the 'sessiondir max size' of %d MiB set in apptainer.conf", sd.Path, c.engine.EngineConfig.File.SessiondirMaxSize)
+			size = maxSize
+		}
+	}
+
+	sylog.Debugf("Adding tmpfs scratch directory %s to mount list with size %d", sd.Path, size)
+	flags := uintptr(c.suidFlag | syscall.MS_NODEV)
+	if err := system.Points.AddFS(mount.ScratchTag, sd.Path, "tmpfs", flags, fmt.Sprintf("size=%d", size)); err != nil {
+		return fmt.Errorf("could not add scratch directory %s to mount list: %s", sd.Path, err)
+	}
+	return nil
+}
+
 func (c *container) isMounted(dest string) bool {
 	sylog.Debugf("Checking if %s is already mounted", dest)
 
@@ -2281,6 +3216,17 @@ func (c *container) isMounted(dest string) bool {
 	return false
 }
 
+// cwdMountFlags returns the flags used to bind the current working
+// directory, MS_RDONLY is also applied with the remount step which is
+// required to make the bind mount read-only.
+func (c *container) cwdMountFlags() uintptr {
+	flags := uintptr(syscall.MS_BIND | c.suidFlag | syscall.MS_NODEV | syscall.MS_REC)
+	if c.engine.EngineConfig.GetCwdReadOnly() || c.engine.EngineConfig.File.MountCwdReadOnly {
+		flags |= syscall.MS_RDONLY
+	}
+	return flags
+}
+
 func (c *container) addCwdMount(system *mount.System) error {
 	if c.skipCwd {
 		return nil
@@ -2299,8 +3245,7 @@ func (c *container) addCwdMount(system *mount.System) error {
 
 	cwdHostSymlink := cwdHost != cwdHostResolved
 
-	cwdContainerResolved := fs.EvalRelative(cwdHost, c.session.FinalPath())
-	cwdContainerResolved = filepath.Join(c.session.FinalPath(), cwdContainerResolved)
+	cwdContainerResolved := c.session.ContainerPath(cwdHost)
 	cwdContainerSymlink := cwdContainerResolved != cwdHost
 
 	fi, err := c.rpcOps.Stat(cwdContainerResolved)
@@ -2318,10 +3263,17 @@ func (c *container) addCwdMount(system *mount.System) error {
 		return err
 	}
 
+	flags := c.cwdMountFlags()
+
 	// same ino/dev, the current working directory is available within the container
 	if hst.Dev == cst.Dev && hst.Ino == cst.Ino {
 		sylog.Verbosef("%s found within container", cwdHost)
-		return nil
+		if flags&syscall.MS_RDONLY == 0 {
+			return nil
+		}
+		// it may be visible through the home or a user bind, bind
+		// it on itself to make it read-only with the remount below
+		sylog.Debugf("Binding %s on itself to make it read-only", cwdHost)
 	} else if c.isMounted(cwdContainerResolved) {
 		sylog.Verbosef("Not mounting CWD (already mounted in container): %s", cwdHost)
 		return nil
@@ -2331,7 +3283,6 @@ func (c *container) addCwdMount(system *mount.System) error {
 		return nil
 	}
 
-	flags := uintptr(syscall.MS_BIND | c.suidFlag | syscall.MS_NODEV | syscall.MS_REC)
 	if err := system.Points.AddBind(mount.CwdTag, cwdHost, cwdHost, flags); err != nil {
 		if errors.Is(err, mount.ErrMountExists) {
 			return nil
@@ -2427,7 +3378,7 @@ func (c *container) createCwdDir(system *mount.System) error {
 
 	if !existingPath && c.session.Layer != nil {
 		if c.engine.EngineConfig.GetSessionLayer() == apptainer.UnderlayLayer {
-			flags := uintptr(syscall.MS_BIND | c.suidFlag | syscall.MS_NODEV | syscall.MS_REC)
+			flags := c.cwdMountFlags()
 			if err := system.Points.AddBind(mount.CwdTag, cwdHost, cwdHost, flags); err != nil {
 				return fmt.Errorf("could not bind cwd directory %s into container: %s", cwdHost, err)
 			}
@@ -2552,17 +3503,50 @@ func (c *container) addIdentityMount(system *mount.System) error {
 		uid = 0
 	}
 
+	// staged even for writable containers as requested explicitly
+	if c.engine.EngineConfig.GetNsswitch() {
+		if err := c.addNsswitchMount(system); err != nil {
+			return err
+		}
+	}
+
+	var passwdEntry *files.PasswdEntry
+	var groupEntry *files.GroupEntry
+
+	if entry := c.engine.EngineConfig.GetPasswdEntry(); entry != "" {
+		pe, err := files.ParsePasswdEntry(entry)
+		if err != nil {
+			return err
+		}
+		if int(pe.UID) != uid {
+			return fmt.Errorf("passwd entry UID %d doesn't match the container user ID %d", pe.UID, uid)
+		}
+		passwdEntry = pe
+	}
+	if entry := c.engine.EngineConfig.GetGroupEntry(); entry != "" {
+		ge, err := files.ParseGroupEntry(entry)
+		if err != nil {
+			return err
+		}
+		groupEntry = ge
+	}
+
 	if (uid == 0) &&
 		(c.engine.EngineConfig.GetWritableImage() ||
 			c.engine.EngineConfig.GetWritableTmpfs() ||
 			c.engine.EngineConfig.GetWritableOverlay()) {
 		sylog.Verbosef("skipping bind-mount of /etc/passwd and /etc/group (container is writable running as root)")
+		if passwdEntry != nil || groupEntry != nil {
+			sylog.Warningf("Ignoring custom passwd and group entries, container is writable running as root")
+		}
 		return nil
 	}
 
 	rootfs := c.session.RootFsPath()
 	defer c.session.Update()
 
+	var passwdContent, groupContent []byte
+
 	if c.engine.EngineConfig.File.ConfigPasswd {
 		passwd := filepath.Join(rootfs, "/etc/passwd")
 		_, home, err := c.getHomePaths()
@@ -2573,21 +3557,17 @@ func (c *container) addIdentityMount(system *mount.System) error {
 			if err != nil {
 				sylog.Warningf("%s", err)
 			} else {
-				if err := c.session.AddFile("/etc/passwd", content); err != nil {
-					sylog.Warningf("failed to add passwd session file: %s", err)
-				}
-				passwd, _ = c.session.GetPath("/etc/passwd")
-
-				sylog.Debugf("Adding /etc/passwd to mount list\n")
-				err = system.Points.AddBind(mount.FilesTag, passwd, "/etc/passwd", syscall.MS_BIND)
-				if err != nil {
-					return fmt.Errorf("unable to add /etc/passwd to mount list: %s", err)
+				if passwdEntry != nil {
+					content = files.SetPasswdEntry(content, passwdEntry)
 				}
-				sylog.Verbosef("Default mount: /etc/passwd:/etc/passwd")
+				passwdContent = content
 			}
 		}
 	} else {
 		sylog.Verbosef("Skipping bind of the host's /etc/passwd")
+		if passwdEntry != nil {
+			sylog.Warningf("Ignoring custom passwd entry, 'config passwd' is disabled by configuration")
+		}
 	}
 
 	if c.engine.EngineConfig.File.ConfigGroup {
@@ -2596,22 +3576,92 @@ func (c *container) addIdentityMount(system *mount.System) error {
 		if err != nil {
 			sylog.Warningf("%s", err)
 		} else {
-			if err := c.session.AddFile("/etc/group", content); err != nil {
-				sylog.Warningf("failed to add group session file: %s", err)
-			}
-			group, _ = c.session.GetPath("/etc/group")
-
-			sylog.Debugf("Adding /etc/group to mount list\n")
-			err = system.Points.AddBind(mount.FilesTag, group, "/etc/group", syscall.MS_BIND)
-			if err != nil {
-				return fmt.Errorf("unable to add /etc/group to mount list: %s", err)
+			if groupEntry != nil {
+				name := ""
+				if passwdEntry != nil {
+					name = passwdEntry.Name
+				} else if pw, err := c.GetPwUID(uint32(uid)); err == nil {
+					name = pw.Name
+				}
+				content = files.SetGroupEntry(content, groupEntry, name)
 			}
-			sylog.Verbosef("Default mount: /etc/group:/etc/group")
+			groupContent = content
 		}
 	} else {
 		sylog.Verbosef("Skipping bind of the host's /etc/group")
+		if groupEntry != nil {
+			sylog.Warningf("Ignoring custom group entry, 'config group' is disabled by configuration")
+		}
+	}
+
+	if passwdContent == nil && groupContent == nil {
+		return nil
+	}
+
+	identityType := (apptainercallback.IdentityFiles)(nil)
+	identityCallbacks, err := plugin.LoadCallbacks(identityType)
+	if err != nil {
+		return fmt.Errorf("while loading plugins callbacks '%T': %s", identityType, err)
+	}
+	for _, callback := range identityCallbacks {
+		passwdContent, groupContent, err = callback.(apptainercallback.IdentityFiles)(
+			c.engine.CommonConfig, uid, c.engine.EngineConfig.JSON.UserInfo, passwdContent, groupContent,
+		)
+		if err != nil {
+			return fmt.Errorf("while executing identity files plugin callback: %s", err)
+		}
+	}
+
+	if passwdContent != nil {
+		if err := c.session.AddFile("/etc/passwd", passwdContent); err != nil {
+			sylog.Warningf("failed to add passwd session file: %s", err)
+		}
+		passwd, _ := c.session.GetPath("/etc/passwd")
+
+		sylog.Debugf("Adding /etc/passwd to mount list\n")
+		err = system.Points.AddBind(mount.FilesTag, passwd, "/etc/passwd", syscall.MS_BIND)
+		if err != nil {
+			return fmt.Errorf("unable to add /etc/passwd to mount list: %s", err)
+		}
+		sylog.Verbosef("Default mount: /etc/passwd:/etc/passwd")
+	}
+
+	if groupContent != nil {
+		if err := c.session.AddFile("/etc/group", groupContent); err != nil {
+			sylog.Warningf("failed to add group session file: %s", err)
+		}
+		group, _ := c.session.GetPath("/etc/group")
+
+		sylog.Debugf("Adding /etc/group to mount list\n")
+		err = system.Points.AddBind(mount.FilesTag, group, "/etc/group", syscall.MS_BIND)
+		if err != nil {
+			return fmt.Errorf("unable to add /etc/group to mount list: %s", err)
+		}
+		sylog.Verbosef("Default mount: /etc/group:/etc/group")
+	}
+
+	return nil
+}
+
+// addNsswitchMount stages a minimal /etc/nsswitch.conf, avoiding lookups
+// hanging on directory services unreachable from the container.
+func (c *container) addNsswitchMount(system *mount.System) error {
+	nsswitch := "/etc/nsswitch.conf"
+
+	if err := c.session.AddFile(nsswitch, files.Nsswitch()); err != nil {
+		sylog.Warningf("failed to add nsswitch.conf session file: %s", err)
+		return nil
+	}
+	if err := c.session.Update(); err != nil {
+		return fmt.Errorf("failed to update session directory: %s", err)
 	}
+	sessionFile, _ := c.session.GetPath(nsswitch)
 
+	sylog.Debugf("Adding %s to mount list\n", nsswitch)
+	if err := system.Points.AddBind(mount.FilesTag, sessionFile, nsswitch, syscall.MS_BIND); err != nil {
+		return fmt.Errorf("unable to add %s to mount list: %s", nsswitch, err)
+	}
+	sylog.Verbosef("Default mount: %s:%s", nsswitch, nsswitch)
 	return nil
 }
 
@@ -2623,6 +3673,8 @@ func (c *container) addResolvConfMount(system *mount.System) error {
 		var content []byte
 
 		dns := c.engine.EngineConfig.GetDNS()
+		search := c.engine.EngineConfig.GetDNSSearch()
+		options := c.engine.EngineConfig.GetDNSOptions()
 
 		if dns == "" {
 			r, err := os.Open(resolvConf)
@@ -2634,9 +3686,35 @@ func (c *container) addResolvConfMount(system *mount.System) error {
 			if err != nil {
 				return err
 			}
+			// the systemd-resolved stub listens on the host loopback
+			// interface, unreachable from a network namespace
+			if c.netNS && files.IsResolvedStub(content) && c.engine.EngineConfig.File.ResolvedStub == "upstream" {
+				upstream, err := os.ReadFile(files.ResolvedUpstreamConf)
+				if err != nil || files.IsResolvedStub(upstream) {
+					sylog.Warningf("Host %s points to the systemd-resolved stub resolver, DNS resolution may not work in the container network namespace", resolvConf)
+				} else {
+					sylog.Verbosef("Using systemd-resolved upstream nameservers from %s", files.ResolvedUpstreamConf)
+					content = upstream
+				}
+			}
+			// search domains or options requested, generate a resolv.conf
+			// with the host nameservers
+			if len(search) > 0 || len(options) > 0 {
+				hostDNS, hostSearch, hostOptions := files.ParseResolvConf(content)
+				if len(search) == 0 {
+					search = hostSearch
+				}
+				if len(options) == 0 {
+					options = hostOptions
+				}
+				content, err = files.ResolvConf(hostDNS, search, options)
+				if err != nil {
+					return fmt.Errorf("while generating %s from host nameservers: %s", resolvConf, err)
+				}
+			}
 		} else {
 			dns = strings.Replace(dns, " ", "", -1)
-			content, err = files.ResolvConf(strings.Split(dns, ","))
+			content, err = files.ResolvConf(strings.Split(dns, ","), search, options)
 			if err != nil {
 				return err
 			}
@@ -2681,6 +3759,9 @@ func (c *container) addHostnameMount(system *mount.System) error {
 				return fmt.Errorf("unable to add %s to mount list: %s", hostnameFile, err)
 			}
 			sylog.Verbosef("Default mount: /etc/hostname:/etc/hostname")
+			if c.planOnly {
+				return nil
+			}
 			if _, err := c.rpcOps.SetHostname(hostname); err != nil {
 				return fmt.Errorf("failed to set container hostname: %s", err)
 			}
@@ -2691,6 +3772,21 @@ func (c *container) addHostnameMount(system *mount.System) error {
 	return nil
 }
 
+// cniNetworkConfPaths returns the CNI configuration directory of the networks
+// from the <network>:<path> entries of the 'cni network configuration paths'
+// directive.
+func cniNetworkConfPaths(entries []string) (map[string]string, error) {
+	paths := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		name, dir, ok := strings.Cut(entry, ":")
+		if !ok || name == "" || !filepath.IsAbs(dir) {
+			return nil, fmt.Errorf("invalid 'cni network configuration paths' entry %q in apptainer.conf: must be <network>:<absolute path>", entry)
+		}
+		paths[name] = filepath.Clean(dir)
+	}
+	return paths, nil
+}
+
 func (c *container) prepareNetworkSetup(system *mount.System, pid int) (func(context.Context) error, error) {
 	const (
 		fakerootNet  = "fakeroot"
@@ -2781,6 +3877,11 @@ func (c *container) prepareNetworkSetup(system *mount.System, pid int) (func(con
 	if cniPath.Plugin == "" {
 		cniPath.Plugin = defaultCNIPluginPath
 	}
+	networkConf, err := cniNetworkConfPaths(c.engine.EngineConfig.File.CniNetworkConfPaths)
+	if err != nil {
+		return nil, err
+	}
+	cniPath.NetworkConf = networkConf
 
 	setup, err := network.NewSetup(networks, strconv.Itoa(pid), nspath, cniPath)
 	if err != nil {
@@ -2793,6 +3894,16 @@ func (c *container) prepareNetworkSetup(system *mount.System, pid int) (func(con
 		return nil, fmt.Errorf("error while setting network arguments: %s", err)
 	}
 
+	retries := c.engine.EngineConfig.File.NetworkRetries
+	if r := c.engine.EngineConfig.GetNetworkRetries(); r >= 0 {
+		retries = uint(r)
+	}
+	if maxRetries := c.engine.EngineConfig.File.MaxNetworkRetries; retries > maxRetries {
+		sylog.Warningf("Network setup retries limited to %d by configuration", maxRetries)
+		retries = maxRetries
+	}
+	networkSetup.SetRetry(retries, cniRetryDelay)
+
 	return func(ctx context.Context) error {
 		if fakeroot || allowedNetUnpriv {
 			// prevent port hijacking between user processes
@@ -2930,7 +4041,7 @@ func (c *container) addFuseMount(system *mount.System) (int, error) {
 		fds = append(fds, fuseMount.Fd)
 	}
 
-	if len(fds) > 0 {
+	if len(fds) > 0 && !c.planOnly {
 		newfds, err := c.getFuseFdFromRPC(fds)
 		if err != nil {
 			return usernsFd, err
@@ -2984,7 +4095,7 @@ func (c *container) addFuseMount(system *mount.System) (int, error) {
 
 		sylog.Debugf("Add FUSE mount for %s with options %s", fuseMounts[i].MountPoint, opts)
 		err := system.Points.AddFS(
-			mount.BindsTag,
+			mount.FuseTag,
 			fuseDir,
 			"fuse",
 			syscall.MS_NOSUID|syscall.MS_NODEV,
@@ -3012,7 +4123,13 @@ func (c *container) addFuseMount(system *mount.System) (int, error) {
 
 		// second, add a bind-mount the session directory into
 		// the destination mount point inside the container.
-		if err := system.Points.AddBind(mount.OtherTag, fuseDir, fuseMounts[i].MountPoint, 0); err != nil {
+		if err := system.Points.AddBind(mount.FuseTag, fuseDir, fuseMounts[i].MountPoint, 0); err != nil {
+			return usernsFd, err
+		}
+	}
+
+	if len(fuseMounts) > 0 {
+		if err := system.RunBeforeTag(mount.FuseTag, c.checkFuseMounts); err != nil {
 			return usernsFd, err
 		}
 	}
@@ -3020,6 +4137,66 @@ func (c *container) addFuseMount(system *mount.System) (int, error) {
 	return usernsFd, nil
 }
 
+// checkFuseMounts is called once all other mount points are mounted, it
+// checks that FUSE mount points exist within the container and that the
+// programs executed from the container by runFuseDrivers after chroot can
+// be resolved in the container root filesystem.
+func (c *container) checkFuseMounts(_ *mount.System) error {
+	finalPath := c.session.FinalPath()
+
+	for _, fuseMount := range c.engine.EngineConfig.GetFuseMount() {
+		dest := filepath.Join(finalPath, fs.EvalRelative(fuseMount.MountPoint, finalPath))
+		if _, err := c.rpcOps.Stat(dest); err != nil {
+			return fmt.Errorf("FUSE mount point %s doesn't exist in container: %s", fuseMount.MountPoint, err)
+		}
+		if !fuseMount.FromContainer {
+			continue
+		}
+		if err := c.lookContainerProgram(fuseMount.Program[0]); err != nil {
+			return fmt.Errorf("FUSE program for mount point %s: %s", fuseMount.MountPoint, err)
+		}
+	}
+
+	return nil
+}
+
+// lookContainerProgram checks that the program can be found in the
+// container root filesystem, either by its absolute path or within the
+// container PATH directories.
+func (c *container) lookContainerProgram(program string) error {
+	finalPath := c.session.FinalPath()
+
+	isExecutable := func(path string) bool {
+		path = filepath.Join(finalPath, fs.EvalRelative(path, finalPath))
+		fi, err := c.rpcOps.Stat(path)
+		return err == nil && fi.Mode().IsRegular() && fi.Mode().Perm()&0o111 != 0
+	}
+
+	if strings.Contains(program, "/") {
+		// relative paths are resolved from the container
+		// working directory, let the execution report errors
+		if filepath.IsAbs(program) && !isExecutable(program) {
+			return fmt.Errorf("%s not found or not executable in container", program)
+		}
+		return nil
+	}
+
+	path := env.DefaultPath
+	for _, keyval := range c.engine.EngineConfig.OciConfig.Process.Env {
+		if strings.HasPrefix(keyval, "PATH=") {
+			path = keyval[5:]
+			break
+		}
+	}
+	for _, dir := range filepath.SplitList(path) {
+		if filepath.IsAbs(dir) && isExecutable(filepath.Join(dir, program)) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s not found in container PATH %s", program, path)
+}
+
 func (c *container) getBindFlags(source string, defaultFlags uintptr) (uintptr, error) {
 	addFlags := uintptr(0)
 
@@ -3033,10 +4210,14 @@ func (c *container) getBindFlags(source string, defaultFlags uintptr) (uintptr,
 
 	// use statfs to retrieve mount options or fallback to /proc/self/mountinfo
 	// in case of failure
-	if err := unix.Statfs(source, &stfs); err != nil {
+	if statErr := unix.Statfs(source, &stfs); statErr != nil {
 		entries, err := proc.GetMountInfoEntry(c.mountInfoPath)
 		if err != nil {
-			return 0, fmt.Errorf("error while reading %s: %s", c.mountInfoPath, err)
+			// mountinfo may be unreadable or truncated with hidepid,
+			// don't make the mount less restrictive than the source
+			// mount point could be
+			sylog.Warningf("Could not get mount flags of %s (%s, %s), mounting it read-only", source, statErr, err)
+			return defaultFlags | syscall.MS_RDONLY | syscall.MS_NOSUID | syscall.MS_NODEV, nil
 		}
 
 		e, err := proc.FindParentMountEntry(source, entries)
@@ -3050,7 +4231,7 @@ func (c *container) getBindFlags(source string, defaultFlags uintptr) (uintptr,
 	}
 
 	if addFlags&syscall.MS_RDONLY != 0 && defaultFlags&syscall.MS_RDONLY == 0 {
-		if !strings.HasPrefix(source, buildcfg.SESSIONDIR) {
+		if !strings.HasPrefix(source, sessionDir) {
 			sylog.Verbosef("Could not mount %s as read-write: mounted read-only", source)
 		}
 	}
@@ -0,0 +1,300 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	args "github.com/apptainer/apptainer/internal/pkg/runtime/engine/apptainer/rpc"
+	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/apptainer/rpc/client"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs/layout"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs/layout/layer/overlay"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs/mount"
+	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+func readMountTable(t *testing.T, path string) []mountTableEntry {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read mount table: %s", err)
+	}
+	var entries []mountTableEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		t.Fatalf("failed to decode mount table: %s", err)
+	}
+	return entries
+}
+
+func TestMountAllDumpMounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mounts.json")
+
+	e := &EngineOperations{EngineConfig: apptainerConfig.NewConfig()}
+	e.EngineConfig.SetDumpMounts(path)
+	c := &container{engine: e}
+
+	points := &mount.Points{}
+	for _, dest := range []string{"/etc/hosts", "/etc/localtime", "/etc/resolv.conf"} {
+		if err := points.AddBind(mount.BindsTag, dest, dest, syscall.MS_BIND); err != nil {
+			t.Fatalf("failed to add bind %s: %s", dest, err)
+		}
+	}
+
+	var planned []mountTableEntry
+	system := &mount.System{
+		Points: points,
+		Mount: func(point *mount.Point, _ *mount.System) error {
+			switch point.Destination {
+			case "/etc/hosts":
+				// the mount table is written before mounting
+				planned = readMountTable(t, path)
+			case "/etc/localtime":
+				c.skippedMount = append(c.skippedMount, point.Destination)
+			case "/etc/resolv.conf":
+				return fmt.Errorf("mount failure")
+			}
+			return nil
+		},
+	}
+
+	if err := c.mountAll(system); err == nil {
+		t.Fatalf("unexpected success")
+	}
+
+	if len(planned) != 3 {
+		t.Fatalf("got %d mount points before mounting, want 3", len(planned))
+	}
+	for _, entry := range planned {
+		if entry.Skipped {
+			t.Errorf("mount point %s skipped before mounting", entry.Destination)
+		}
+	}
+
+	// the skip status is updated even if a mount failed
+	entries := readMountTable(t, path)
+	if len(entries) != 3 {
+		t.Fatalf("got %d mount points, want 3", len(entries))
+	}
+	for _, entry := range entries {
+		if skipped := entry.Destination == "/etc/localtime"; entry.Skipped != skipped {
+			t.Errorf("mount point %s: got skipped %v, want %v", entry.Destination, entry.Skipped, skipped)
+		}
+		if entry.Tag != mount.BindsTag {
+			t.Errorf("mount point %s: got tag %s, want %s", entry.Destination, entry.Tag, mount.BindsTag)
+		}
+	}
+}
+
+func TestImageLazytime(t *testing.T) {
+	c := &container{}
+
+	flags := c.addImageFlags("/rootfs", syscall.MS_NODEV, syscall.MS_NOATIME|unix.MS_LAZYTIME)
+	if flags != syscall.MS_NODEV|syscall.MS_NOATIME {
+		t.Errorf("got flags %#x, want %#x", flags, syscall.MS_NODEV|syscall.MS_NOATIME)
+	}
+	if flags := c.addImageFlags("/overlay", syscall.MS_NODEV, syscall.MS_NOATIME); flags != syscall.MS_NODEV|syscall.MS_NOATIME {
+		t.Errorf("got flags %#x, want %#x", flags, syscall.MS_NODEV|syscall.MS_NOATIME)
+	}
+
+	// lazytime only applies to the image mount point requesting it
+	tests := map[string]uintptr{
+		"/rootfs":  unix.MS_LAZYTIME,
+		"/overlay": 0,
+		"/etc":     0,
+	}
+	for dest, want := range tests {
+		if got := c.lazytimeFlag(&mount.Point{Mount: specs.Mount{Destination: dest}}); got != want {
+			t.Errorf("%s: got lazytime flag %#x, want %#x", dest, got, want)
+		}
+	}
+}
+
+func TestCreateBindParentDir(t *testing.T) {
+	session, err := layout.NewSession(t.TempDir(), "tmpfs", 0, &mount.System{Points: &mount.Points{}}, overlay.New())
+	if err != nil {
+		t.Fatalf("failed to create session: %s", err)
+	}
+	rootfs := session.RootFsPath()
+	if err := os.MkdirAll(filepath.Join(rootfs, "run"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(rootfs, "var"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../run", filepath.Join(rootfs, "var", "run")); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &EngineOperations{EngineConfig: apptainerConfig.NewConfig()}
+	c := &container{engine: e, session: session}
+
+	system := &mount.System{Points: &mount.Points{}}
+	for _, dst := range []string{"/var/run/app/app.sock", "/var/run/host.sock"} {
+		if err := c.createBindParentDir(system, dst); err != nil {
+			t.Fatalf("unexpected error for %s: %s", dst, err)
+		}
+	}
+	if err := system.MountAll(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the symlinked parent is resolved within the root filesystem
+	if _, err := session.GetPath(filepath.Join(session.Layer.Dir(), "run", "app")); err != nil {
+		t.Errorf("parent directory not created: %s", err)
+	}
+	if _, err := session.GetPath(filepath.Join(session.Layer.Dir(), "var", "run")); err == nil {
+		t.Errorf("symlinked parent directory shadowed in the layer")
+	}
+
+	// no layer to create the parent directory
+	session, err = layout.NewSession(t.TempDir(), "tmpfs", 0, &mount.System{Points: &mount.Points{}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %s", err)
+	}
+	c.session = session
+	if err := c.createBindParentDir(system, "/var/run/app/app.sock"); err == nil {
+		t.Errorf("unexpected success without layer")
+	}
+}
+
+func TestCreateBindFile(t *testing.T) {
+	session, err := layout.NewSession(t.TempDir(), "tmpfs", 0, &mount.System{Points: &mount.Points{}}, overlay.New())
+	if err != nil {
+		t.Fatalf("failed to create session: %s", err)
+	}
+	rootfs := session.RootFsPath()
+	if err := os.MkdirAll(filepath.Join(rootfs, "run"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(rootfs, "run", "existing.conf"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(rootfs, "var"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../run", filepath.Join(rootfs, "var", "run")); err != nil {
+		t.Fatal(err)
+	}
+
+	src := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(src, []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &EngineOperations{EngineConfig: apptainerConfig.NewConfig()}
+	c := &container{engine: e, session: session}
+
+	if err := c.createBindFile(&mount.System{Points: &mount.Points{}}, filepath.Dir(src), "/var/run/app.conf"); err == nil {
+		t.Errorf("unexpected success with a directory source")
+	}
+
+	system := &mount.System{Points: &mount.Points{}}
+	for _, dst := range []string{"/var/run/app.conf", "/var/run/existing.conf"} {
+		if err := c.createBindFile(system, src, dst); err != nil {
+			t.Fatalf("unexpected error for %s: %s", dst, err)
+		}
+	}
+	if err := system.MountAll(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the symlinked parent is resolved within the root filesystem
+	if _, err := session.GetPath(filepath.Join(session.Layer.Dir(), "run", "app.conf")); err != nil {
+		t.Errorf("destination file not created: %s", err)
+	}
+	if _, err := session.GetPath(filepath.Join(session.Layer.Dir(), "run", "existing.conf")); err == nil {
+		t.Errorf("existing destination file created in the layer")
+	}
+	if _, err := session.GetPath(filepath.Join(session.Layer.Dir(), "var", "run")); err == nil {
+		t.Errorf("symlinked parent directory shadowed in the layer")
+	}
+
+	// no layer to create the destination file
+	session, err = layout.NewSession(t.TempDir(), "tmpfs", 0, &mount.System{Points: &mount.Points{}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %s", err)
+	}
+	c.session = session
+	if err := c.createBindFile(system, src, "/var/run/app.conf"); err == nil {
+		t.Errorf("unexpected success without layer")
+	}
+}
+
+// statMethods implements the stat RPC method by returning the
+// information of path whatever the requested path is.
+type statMethods struct {
+	path string
+}
+
+func (m *statMethods) Stat(_ *args.StatArgs, reply *args.StatReply) error {
+	reply.Fi, reply.Err = os.Stat(m.path)
+	if reply.Fi != nil {
+		reply.Fi = args.FileInfo(reply.Fi)
+	}
+	return nil
+}
+
+func TestAddCwdMountReadOnly(t *testing.T) {
+	cwd := t.TempDir()
+
+	server := rpc.NewServer()
+	// the current working directory is found within the container
+	if err := server.RegisterName("stat", &statMethods{path: cwd}); err != nil {
+		t.Fatalf("failed to register RPC methods: %s", err)
+	}
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+	rpcOps := &client.RPC{Client: rpc.NewClient(clientConn), Name: "stat"}
+	defer rpcOps.Client.Close()
+
+	session, err := layout.NewSession(t.TempDir(), "tmpfs", 0, &mount.System{Points: &mount.Points{}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %s", err)
+	}
+
+	for _, readOnly := range []bool{false, true} {
+		e := &EngineOperations{EngineConfig: apptainerConfig.NewConfig()}
+		e.EngineConfig.SetCwd(cwd)
+		e.EngineConfig.SetCwdReadOnly(readOnly)
+		c := &container{engine: e, session: session, rpcOps: rpcOps}
+
+		system := &mount.System{Points: &mount.Points{}}
+		if err := c.addCwdMount(system); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		points := system.Points.GetByTag(mount.CwdTag)
+		if !readOnly {
+			if len(points) != 0 {
+				t.Errorf("unexpected mount of the current working directory found within container")
+			}
+			continue
+		}
+		if len(points) != 2 {
+			t.Fatalf("got %d mount points, want a bind and its remount", len(points))
+		}
+		for _, p := range points {
+			flags, _ := mount.ConvertOptions(p.Options)
+			if p.Destination != cwd || flags&syscall.MS_RDONLY == 0 {
+				t.Errorf("got mount point %s with flags %#x, want %s read-only", p.Destination, flags, cwd)
+			}
+		}
+		if flags, _ := mount.ConvertOptions(points[1].Options); flags&syscall.MS_REMOUNT == 0 {
+			t.Errorf("current working directory not remounted read-only")
+		}
+	}
+}
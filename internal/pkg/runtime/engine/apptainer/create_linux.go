@@ -14,9 +14,14 @@ import (
 	"fmt"
 	"net"
 	"net/rpc"
+	"os"
 
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/apptainer/rpc/client"
+	"github.com/apptainer/apptainer/internal/pkg/security/seccomp"
+	"github.com/apptainer/apptainer/internal/pkg/util/priv"
 	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	"golang.org/x/sys/unix"
 )
 
 // CreateContainer is called from master process to prepare container
@@ -36,6 +41,10 @@ func (e *EngineOperations) CreateContainer(ctx context.Context, pid int, rpcConn
 		return fmt.Errorf("engineName configuration doesn't match runtime name")
 	}
 
+	if path := e.EngineConfig.GetTraceSyscalls(); path != "" {
+		go traceSyscalls(pid, os.NewFile(uintptr(e.EngineConfig.GetTraceSyscallsFd()), path))
+	}
+
 	if e.EngineConfig.GetInstanceJoin() {
 		return nil
 	}
@@ -50,3 +59,25 @@ func (e *EngineOperations) CreateContainer(ctx context.Context, pid int, rpcConn
 
 	return create(ctx, e, rpcOps, pid)
 }
+
+// traceSyscalls writes to f the system calls of the container process
+// reported by the trace filter loaded in stage two. In the setuid flow
+// privileges are escalated, the container process being not dumpable,
+// its file descriptors are not accessible with the user credentials.
+func traceSyscalls(pid int, f *os.File) {
+	defer f.Close()
+
+	_, euid, suid := unix.Getresuid()
+	if euid != 0 && suid == 0 {
+		dropPrivilege, err := priv.Escalate()
+		if err != nil {
+			sylog.Warningf("Could not trace system calls: %s", err)
+			return
+		}
+		defer dropPrivilege()
+	}
+
+	if err := seccomp.Trace(pid, f); err != nil {
+		sylog.Warningf("System calls trace interrupted: %s", err)
+	}
+}
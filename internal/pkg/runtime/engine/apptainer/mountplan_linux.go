@@ -0,0 +1,97 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/apptainer/apptainer/internal/pkg/util/fs/mount"
+	"github.com/apptainer/apptainer/internal/pkg/util/mainthread"
+	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
+)
+
+// MountPlan returns the mount points the container engine would mount
+// for the engine configuration without mounting anything, it doesn't
+// require any privilege. The images are opened to determine the session
+// layer and the image partitions, image mount points have the image path
+// as source. Mount points added by hooks executed while mounting, like the
+// overlay layers of a sandbox image, the current working directory and the
+// identity files, are not part of the plan.
+//
+// The engine configuration is modified the same way it is by the engine
+// before mounting, it shouldn't be used to start a container afterward.
+func MountPlan(engineConfig *apptainerConfig.EngineConfig) (*mount.Points, error) {
+	e := &EngineOperations{EngineConfig: engineConfig}
+
+	if engineConfig.GetImage() == "" {
+		return nil, fmt.Errorf("no root filesystem image provided")
+	}
+	if engineConfig.GetCwd() == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			cwd = "/"
+		}
+		engineConfig.SetCwd(cwd)
+	}
+
+	// the image loading reads symlinks from the main thread
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case f := <-mainthread.FuncChannel:
+				f()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// the images are loaded like the engine does without passing
+	// their file descriptors to a container process
+	err := e.loadImages(nil, false, false)
+	images := engineConfig.GetImageList()
+	defer func() {
+		for _, img := range images {
+			img.File.Close()
+		}
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("while loading images: %s", err)
+	}
+
+	// report the image paths instead of the file descriptor
+	// paths which are closed once the plan is built
+	sources := make(map[string]string, len(images))
+	for i := range images {
+		sources[images[i].Source] = images[i].Path
+		images[i].Source = images[i].Path
+	}
+	binds := engineConfig.GetBindPath()
+	for i := range binds {
+		if path, ok := sources[binds[i].Source]; ok && binds[i].IsImageBind() {
+			binds[i].Source = path
+		}
+	}
+	engineConfig.SetImageList(images)
+	e.setUserInfo(false)
+
+	c := newContainer(e, nil, os.Getpid())
+	c.planOnly = true
+
+	p := &mount.Points{}
+	system := &mount.System{Points: p, Mount: c.mount}
+
+	if _, err := c.addMounts(system, os.Getpid()); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,115 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/util/fs/mount"
+	"github.com/apptainer/apptainer/pkg/image"
+	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
+	"github.com/apptainer/apptainer/pkg/util/apptainerconf"
+)
+
+// hasPoint returns whether a mount point with the given source and
+// destination is part of the tag points, any destination matches if
+// dest is empty.
+func hasPoint(points *mount.Points, tag mount.AuthorizedTag, source, dest string) bool {
+	for _, p := range points.GetByTag(tag) {
+		if p.Source == source && (dest == "" || p.Destination == dest) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMountPlan(t *testing.T) {
+	// the plan must not depend on the installed configuration
+	// and session directory
+	file := &apptainerconf.File{
+		AllowContainerDir: true,
+		EnableOverlay:     "try",
+		EnableUnderlay:    "yes",
+		MountProc:         true,
+		MountSys:          true,
+		MountDev:          "yes",
+		MountDevPts:       true,
+		MountTmp:          true,
+		MountHome:         true,
+		MountSlave:        true,
+		UserBindControl:   true,
+		MaxOverlayLayers:  128,
+		MemoryFSType:      "tmpfs",
+		SessiondirMaxSize: 64,
+	}
+	current := apptainerconf.GetCurrentConfig()
+	apptainerconf.SetCurrentConfig(file)
+	t.Cleanup(func() { apptainerconf.SetCurrentConfig(current) })
+
+	defaultSessionDir := sessionDir
+	sessionDir = t.TempDir()
+	t.Cleanup(func() { sessionDir = defaultSessionDir })
+
+	sandbox := t.TempDir()
+	for _, dir := range []string{"etc", "mnt", "proc", "sys", "dev", "tmp", "var/tmp"} {
+		if err := os.MkdirAll(filepath.Join(sandbox, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	overlayDir := t.TempDir()
+
+	engineConfig := apptainerConfig.NewConfig()
+	engineConfig.File = file
+	engineConfig.SetImage(sandbox)
+	engineConfig.SetOverlayImage([]string{overlayDir + ":ro"})
+	engineConfig.SetBindPath([]apptainerConfig.BindPath{
+		{Source: "/etc", Destination: "/mnt"},
+	})
+	engineConfig.SetNoProc(true)
+	engineConfig.SetNoHome(true)
+
+	points, err := MountPlan(engineConfig)
+	if err != nil {
+		t.Fatalf("failed to build mount plan: %s", err)
+	}
+
+	if !hasPoint(points, mount.RootfsTag, sandbox, "") {
+		t.Errorf("sandbox %s not mounted as root filesystem", sandbox)
+	}
+	if !hasPoint(points, mount.PreLayerTag, overlayDir, "") {
+		t.Errorf("overlay directory %s not mounted", overlayDir)
+	}
+	if !hasPoint(points, mount.UserbindsTag, "/etc", "/mnt") {
+		t.Errorf("user bind /etc:/mnt not mounted")
+	}
+
+	// mount points skipped on user request
+	for _, p := range points.GetByTag(mount.KernelTag) {
+		if p.Destination == "/proc" {
+			t.Errorf("unexpected /proc mount with --no-mount proc")
+		}
+	}
+	if len(points.GetByTag(mount.HomeTag)) != 0 {
+		t.Errorf("unexpected home mounts with --no-home")
+	}
+
+	// the image list reports image paths instead of file descriptors
+	images := engineConfig.GetImageList()
+	if len(images) != 2 {
+		t.Fatalf("got %d images, want 2", len(images))
+	}
+	if images[0].Source != sandbox || images[0].Usage != image.RootFsUsage {
+		t.Errorf("unexpected root filesystem image %s", images[0].Source)
+	}
+	if images[1].Source != overlayDir || images[1].Usage != image.OverlayUsage {
+		t.Errorf("unexpected overlay image %s", images[1].Source)
+	}
+}
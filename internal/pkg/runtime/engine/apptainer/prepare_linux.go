@@ -16,11 +16,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/apptainer/apptainer/internal/pkg/buildcfg"
@@ -31,16 +34,20 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/plugin"
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/starter"
 	"github.com/apptainer/apptainer/internal/pkg/security"
+	"github.com/apptainer/apptainer/internal/pkg/security/landlock"
 	"github.com/apptainer/apptainer/internal/pkg/security/seccomp"
 	"github.com/apptainer/apptainer/internal/pkg/syecl"
 	"github.com/apptainer/apptainer/internal/pkg/sypgp"
+	"github.com/apptainer/apptainer/internal/pkg/util/bin"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs/mount"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs/overlay"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs/squashfs"
 	"github.com/apptainer/apptainer/internal/pkg/util/hack"
 	"github.com/apptainer/apptainer/internal/pkg/util/mainthread"
 	"github.com/apptainer/apptainer/internal/pkg/util/user"
 	"github.com/apptainer/apptainer/pkg/image"
+	apptainercallback "github.com/apptainer/apptainer/pkg/plugin/callback/runtime/engine/apptainer"
 	fakerootcallback "github.com/apptainer/apptainer/pkg/plugin/callback/runtime/fakeroot"
 	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
 	"github.com/apptainer/apptainer/pkg/runtime/engine/config"
@@ -49,6 +56,8 @@ import (
 	"github.com/apptainer/apptainer/pkg/util/fs/proc"
 	"github.com/apptainer/apptainer/pkg/util/namespaces"
 	"github.com/apptainer/apptainer/pkg/util/slice"
+	"github.com/apptainer/sif/v2/pkg/integrity"
+	"github.com/apptainer/sif/v2/pkg/sif"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
 )
@@ -143,13 +152,33 @@ func (e *EngineOperations) PrepareConfig(starterConfig *starter.Config) error {
 		useTargetIDs = true
 	}
 
+	if err := e.prepareImageDriver(starterConfig.GetIsSUID()); err != nil {
+		return err
+	}
+	if err := e.prepareSessionDir(starterConfig.GetIsSUID(), os.Getuid()); err != nil {
+		return err
+	}
+
 	userNS, _ := namespaces.IsInsideUserNamespace(os.Getpid())
-	userNS = userNS || e.EngineConfig.GetFakeroot()
+	userNS = userNS || e.EngineConfig.GetFakeroot() || e.EngineConfig.GetKeepID()
+	if e.EngineConfig.GetLoopMode() == apptainerConfig.LoopModeFuse {
+		driver.PreferFuseSquash()
+	}
 	driver.InitImageDrivers(true, userNS, e.EngineConfig.File, 0)
 	imageDriver = image.GetDriver(e.EngineConfig.File.ImageDriver)
+	if err := checkImageDriver(e.EngineConfig.File.ImageDriver); err != nil {
+		return err
+	}
 
 	elevated := starterConfig.GetIsSUID() && !userNS
 
+	if err := e.checkOverlayOpts(elevated); err != nil {
+		return err
+	}
+	if err := e.checkSquashfsMountOpts(elevated); err != nil {
+		return err
+	}
+
 	if e.EngineConfig.GetInstanceJoin() {
 		if err := e.prepareInstanceJoinConfig(starterConfig); err != nil {
 			return err
@@ -163,6 +192,13 @@ func (e *EngineOperations) PrepareConfig(starterConfig *starter.Config) error {
 		if err := e.loadImages(starterConfig, userNS, elevated); err != nil {
 			return err
 		}
+		if err := e.prepareOverlayBinds(); err != nil {
+			return err
+		}
+	}
+
+	if err := e.runProcessSpecCallbacks(); err != nil {
+		return err
 	}
 
 	starterConfig.SetMasterPropagateMount(true)
@@ -179,6 +215,10 @@ func (e *EngineOperations) PrepareConfig(starterConfig *starter.Config) error {
 	// determine if engine need to propagate signals across processes
 	e.checkSignalPropagation()
 
+	if err := e.prepareTraceSyscalls(starterConfig); err != nil {
+		return err
+	}
+
 	// We must call this here because at this point we haven't
 	// spawned the master process nor the RPC server. The assumption
 	// is that this function runs in stage 1 and that even if it's a
@@ -220,9 +260,69 @@ func (e *EngineOperations) PrepareConfig(starterConfig *starter.Config) error {
 		starterConfig.SetNvCCLICaps(true)
 	}
 
+	if path := e.EngineConfig.GetDumpOciSpec(); path != "" {
+		if err := e.dumpOciSpec(path); err != nil {
+			return err
+		}
+	}
+	if e.EngineConfig.GetPrintCaps() {
+		if err := e.printCaps(); err != nil {
+			return err
+		}
+	}
+	starterConfig.SetDryRun(e.EngineConfig.GetDryRun())
+
+	return nil
+}
+
+// dumpOciSpec writes the OCI runtime spec assembled for the container
+// as indented JSON to path, or to the standard output if path is "-".
+func (e *EngineOperations) dumpOciSpec(path string) error {
+	b, err := json.MarshalIndent(e.EngineConfig.OciConfig.Spec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("while encoding OCI runtime spec: %s", err)
+	}
+	b = append(b, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(b)
+	} else {
+		err = os.WriteFile(path, b, 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("while writing OCI runtime spec to %s: %s", path, err)
+	}
+	sylog.Verbosef("OCI runtime spec written to %s", path)
 	return nil
 }
 
+// printCaps prints the capability sets resolved for the container process
+// to the standard output.
+func (e *EngineOperations) printCaps() error {
+	caps := e.EngineConfig.OciConfig.Process.Capabilities
+	sets := []struct {
+		name string
+		caps []string
+	}{
+		{"Permitted", caps.Permitted},
+		{"Effective", caps.Effective},
+		{"Inheritable", caps.Inheritable},
+		{"Bounding", caps.Bounding},
+		{"Ambient", caps.Ambient},
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, set := range sets {
+		list := strings.Join(set.caps, ",")
+		if list == "" {
+			list = "-"
+		}
+		fmt.Fprintf(tw, "%s:\t%s\n", set.name, list)
+	}
+	fmt.Fprintf(tw, "NoNewPrivileges:\t%t\n", e.EngineConfig.OciConfig.Process.NoNewPrivileges)
+	return tw.Flush()
+}
+
 // prepareUserCaps is responsible for checking that user's requested
 // capabilities are authorized.
 func (e *EngineOperations) prepareUserCaps(enforced bool) error {
@@ -432,6 +532,66 @@ func (e *EngineOperations) prepareRootCaps() error {
 	return nil
 }
 
+// usernsIneffectiveCaps lists the capabilities granting privileges over
+// resources owned by the initial user namespace only, they have no effect
+// when held within another user namespace.
+var usernsIneffectiveCaps = map[string]bool{
+	"CAP_AUDIT_CONTROL":   true,
+	"CAP_AUDIT_READ":      true,
+	"CAP_AUDIT_WRITE":     true,
+	"CAP_BLOCK_SUSPEND":   true,
+	"CAP_BPF":             true,
+	"CAP_LINUX_IMMUTABLE": true,
+	"CAP_MAC_ADMIN":       true,
+	"CAP_MAC_OVERRIDE":    true,
+	"CAP_MKNOD":           true,
+	"CAP_PERFMON":         true,
+	"CAP_SYS_BOOT":        true,
+	"CAP_SYS_MODULE":      true,
+	"CAP_SYS_PACCT":       true,
+	"CAP_SYS_RAWIO":       true,
+	"CAP_SYS_TIME":        true,
+	"CAP_SYSLOG":          true,
+	"CAP_WAKE_ALARM":      true,
+}
+
+// setFakerootCaps sets the capabilities of the container process to caps
+// in place of all capabilities. It's only applied to fakeroot containers,
+// or containers started by root within a user namespace like build
+// sections run with fakeroot.
+func (e *EngineOperations) setFakerootCaps(caps string) error {
+	insideUserNs, _ := namespaces.IsInsideUserNamespace(os.Getpid())
+	if !e.EngineConfig.GetFakeroot() && (os.Getuid() != 0 || !insideUserNs) {
+		sylog.Warningf("Ignoring --fakeroot-caps, it's only effective with --fakeroot")
+		return nil
+	}
+
+	fakerootCaps, unknownCaps := capabilities.Split(caps)
+	if len(unknownCaps) > 0 {
+		return fmt.Errorf("unknown fakeroot capabilities: %s", strings.Join(unknownCaps, ","))
+	}
+
+	ineffectiveCaps := make([]string, 0)
+	for _, c := range fakerootCaps {
+		if usernsIneffectiveCaps[c] {
+			ineffectiveCaps = append(ineffectiveCaps, c)
+		}
+	}
+	if len(ineffectiveCaps) > 0 {
+		sylog.Warningf("Capabilities %s can't be effective within a user namespace", strings.Join(ineffectiveCaps, ","))
+	}
+
+	sylog.Debugf("Fakeroot capabilities %s set", strings.Join(fakerootCaps, ","))
+
+	e.EngineConfig.OciConfig.Process.Capabilities.Permitted = fakerootCaps
+	e.EngineConfig.OciConfig.Process.Capabilities.Effective = fakerootCaps
+	e.EngineConfig.OciConfig.Process.Capabilities.Inheritable = fakerootCaps
+	e.EngineConfig.OciConfig.Process.Capabilities.Bounding = fakerootCaps
+	e.EngineConfig.OciConfig.Process.Capabilities.Ambient = fakerootCaps
+
+	return nil
+}
+
 func keepAutofsMount(source string, autoFsPoints []string) (int, error) {
 	resolved, err := filepath.EvalSymlinks(source)
 	if err != nil {
@@ -454,6 +614,60 @@ func keepAutofsMount(source string, autoFsPoints []string) (int, error) {
 	return -1, fmt.Errorf("no mount point")
 }
 
+// prepareImageDriver applies the image driver selected for this container
+// on top of the configuration file, so it's used in place of the configured
+// image driver. In setuid mode the image driver must be the configured one
+// or be part of the image drivers allowed by the administrator.
+func (e *EngineOperations) prepareImageDriver(suid bool) error {
+	name := e.EngineConfig.GetImageDriver()
+	if name == "" || name == e.EngineConfig.File.ImageDriver {
+		return nil
+	}
+	if suid && !slices.Contains(e.EngineConfig.File.AllowedImageDrivers, name) {
+		return fmt.Errorf("image driver %q is not allowed in setuid mode, check 'allowed image drivers' in apptainer.conf", name)
+	}
+	sylog.Debugf("Using image driver %s instead of the configured one", name)
+	e.EngineConfig.File.ImageDriver = name
+	return nil
+}
+
+// checkImageDriver returns an error if the image driver registered with
+// name reports it can't be used, so a missing dependency is reported
+// before mounting images with the driver.
+func checkImageDriver(name string) error {
+	if imageDriver == nil {
+		return nil
+	}
+	if err := image.DriverAvailable(imageDriver); err != nil {
+		return fmt.Errorf("image driver %s is not available: %s", name, err)
+	}
+	return nil
+}
+
+// prepareSessionDir applies the session directory filesystem type and size
+// requested for this run. In setuid mode ramfs, which has no size limit, is
+// only allowed if it's the configured type, and the size is capped at the
+// 'sessiondir max size' set in apptainer.conf for users other than root.
+func (e *EngineOperations) prepareSessionDir(suid bool, uid int) error {
+	if fsType := e.EngineConfig.GetSessionFsType(); fsType != "" && fsType != e.EngineConfig.File.MemoryFSType {
+		if fsType != "tmpfs" && fsType != "ramfs" {
+			return fmt.Errorf("session directory filesystem type must be tmpfs or ramfs, got %s", fsType)
+		}
+		if suid && fsType == "ramfs" {
+			return fmt.Errorf("ramfs session directory is not allowed in setuid mode, it doesn't enforce the 'sessiondir max size' limit")
+		}
+		sylog.Debugf("Using %s session directory instead of the configured %s", fsType, e.EngineConfig.File.MemoryFSType)
+		e.EngineConfig.File.MemoryFSType = fsType
+	}
+
+	maxSize := int(e.EngineConfig.File.SessiondirMaxSize)
+	if size := e.EngineConfig.GetSessionSize(); suid && uid != 0 && size > maxSize {
+		sylog.Warningf("Session directory size of %d MiB exceeds the 'sessiondir max size' set in apptainer.conf, using %d MiB", size, maxSize)
+		e.EngineConfig.SetSessionSize(maxSize)
+	}
+	return nil
+}
+
 func (e *EngineOperations) prepareAutofs(starterConfig *starter.Config) error {
 	const mountInfoPath = "/proc/self/mountinfo"
 
@@ -473,6 +687,14 @@ func (e *EngineOperations) prepareAutofs(starterConfig *starter.Config) error {
 		return nil
 	}
 
+	if !e.EngineConfig.File.AutofsWorkaround {
+		sylog.Debugf("Autofs workaround disabled by configuration, binds from autofs mounts may fail")
+		return nil
+	} else if e.EngineConfig.GetNoAutofsWorkaround() {
+		sylog.Warningf("Autofs workaround disabled by --no-autofs-workaround, binds from autofs mounts may fail")
+		return nil
+	}
+
 	fds := make([]int, 0)
 
 	if e.EngineConfig.File.UserBindControl {
@@ -537,6 +759,61 @@ func (e *EngineOperations) prepareAutofs(starterConfig *starter.Config) error {
 	return nil
 }
 
+// prepareTraceSyscalls opens the file receiving the system calls trace of
+// the container process, if requested. The file is opened with the user
+// credentials and kept open for the master process, which handles the
+// seccomp notifications of the trace filter loaded in stage two.
+func (e *EngineOperations) prepareTraceSyscalls(starterConfig *starter.Config) error {
+	path := e.EngineConfig.GetTraceSyscalls()
+	if path == "" {
+		return nil
+	}
+	if err := seccomp.TraceSupported(); err != nil {
+		return fmt.Errorf("can't trace system calls: %s", err)
+	}
+
+	sylog.Warningf("Tracing system calls to %s, the container process will run significantly slower", path)
+
+	fd, err := unix.Open(path, unix.O_WRONLY|unix.O_CREAT|unix.O_TRUNC|unix.O_CLOEXEC, 0o644)
+	if err != nil {
+		return fmt.Errorf("while opening system calls trace file %s: %s", path, err)
+	}
+	if err := starterConfig.KeepFileDescriptor(fd); err != nil {
+		return err
+	}
+	e.EngineConfig.SetTraceSyscallsFd(fd)
+
+	return nil
+}
+
+// prepareOverlayBinds checks that the host directories requested with
+// --overlay-bind can be used as overlay lower layers.
+func (e *EngineOperations) prepareOverlayBinds() error {
+	binds := e.EngineConfig.GetOverlayBind()
+	if len(binds) == 0 {
+		return nil
+	}
+
+	if e.EngineConfig.File.EnableOverlay == "no" {
+		return fmt.Errorf("--overlay-bind requires 'enable overlay', but set to 'no' by administrator")
+	}
+	if !e.EngineConfig.File.UserBindControl {
+		return fmt.Errorf("--overlay-bind requires 'user bind control', but set to 'no' by administrator")
+	}
+	for _, b := range binds {
+		fi, err := os.Stat(b.Lower)
+		if err != nil {
+			return fmt.Errorf("overlay bind lower directory: %s", err)
+		} else if !fi.IsDir() {
+			return fmt.Errorf("overlay bind lower %s is not a directory", b.Lower)
+		}
+		if err := overlay.CheckLower(b.Lower); err != nil {
+			return fmt.Errorf("overlay bind lower directory: %s", err)
+		}
+	}
+	return nil
+}
+
 // removeNamespace is used to remove a namespace from the slice of namespaces.
 // It is used mainly within prepareContainerConfig(...)
 func (e *EngineOperations) removeNamespace(namespaceType specs.LinuxNamespaceType) {
@@ -607,6 +884,111 @@ func (e *EngineOperations) joinNetns(starterConfig *starter.Config) error {
 	return starterConfig.SetNsPath(specs.NetworkNamespace, netnsPath)
 }
 
+// joinInstanceNamespaces validates the PID and IPC namespace paths
+// requested with --pid=container:NAME and --ipc=container:NAME. Only
+// /proc/<pid>/ns/<type> paths are accepted and non-root users can only
+// join namespaces of processes they own.
+func (e *EngineOperations) joinInstanceNamespaces(starterConfig *starter.Config) error {
+	for _, nsType := range []specs.LinuxNamespaceType{specs.PIDNamespace, specs.IPCNamespace} {
+		ok, nsPath := e.hasNamespace(nsType)
+		if !ok || nsPath == "" {
+			continue
+		}
+
+		elem := strings.Split(filepath.Clean(nsPath), "/")
+		if len(elem) != 5 || elem[0] != "" || elem[1] != "proc" || elem[3] != "ns" || elem[4] != nsProcName[nsType] {
+			return fmt.Errorf("%s namespace path %s is not allowed", nsType, nsPath)
+		}
+		pid, err := strconv.Atoi(elem[2])
+		if err != nil || pid <= 1 {
+			return fmt.Errorf("%s namespace path %s is not allowed", nsType, nsPath)
+		}
+
+		if uid := os.Getuid(); uid != 0 {
+			gid := os.Getgid()
+			// "/proc/pid/task" directory must be owned by user UID/GID
+			fi, err := os.Stat(filepath.Join("/proc", elem[2], "task"))
+			if err != nil {
+				return fmt.Errorf("while checking process %d: %s", pid, err)
+			}
+			st := fi.Sys().(*syscall.Stat_t)
+			if st.Uid != uint32(uid) || st.Gid != uint32(gid) {
+				return fmt.Errorf("process %d owned by %d:%d instead of %d:%d", pid, st.Uid, st.Gid, uid, gid)
+			}
+		}
+
+		if err := starterConfig.SetNsPath(nsType, nsPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkMountFrom validates the instance process IDs of the paths requested
+// with --mount-from, non-root users can only mount paths from instances
+// they own.
+func (e *EngineOperations) checkMountFrom() error {
+	uid := os.Getuid()
+	gid := os.Getgid()
+
+	for _, mf := range e.EngineConfig.GetMountFrom() {
+		if mf.Pid <= 1 {
+			return fmt.Errorf("bad process ID %d found for instance %s", mf.Pid, mf.Instance)
+		}
+		if uid == 0 {
+			continue
+		}
+		// "/proc/pid/task" directory must be owned by user UID/GID
+		fi, err := os.Stat(filepath.Join("/proc", strconv.Itoa(mf.Pid), "task"))
+		if err != nil {
+			return fmt.Errorf("while checking instance %s process %d: %s", mf.Instance, mf.Pid, err)
+		}
+		st := fi.Sys().(*syscall.Stat_t)
+		if st.Uid != uint32(uid) || st.Gid != uint32(gid) {
+			return fmt.Errorf("instance %s process %d owned by %d:%d instead of %d:%d", mf.Instance, mf.Pid, st.Uid, st.Gid, uid, gid)
+		}
+	}
+	return nil
+}
+
+// runProcessSpecCallbacks lets plugins alter the prepared container
+// process specification.
+func (e *EngineOperations) runProcessSpecCallbacks() error {
+	callbackType := (apptainercallback.ProcessSpec)(nil)
+	callbacks, err := plugin.LoadCallbacks(callbackType)
+	if err != nil {
+		return fmt.Errorf("while loading plugins callbacks '%T': %s", callbackType, err)
+	}
+	for _, callback := range callbacks {
+		if err := callback.(apptainercallback.ProcessSpec)(e.CommonConfig, e.EngineConfig.OciConfig.Process); err != nil {
+			return fmt.Errorf("while executing process spec plugin callback: %s", err)
+		}
+	}
+	if len(e.EngineConfig.OciConfig.Process.Args) == 0 {
+		return fmt.Errorf("container process arguments removed by plugin callback")
+	}
+	if e.EngineConfig.OciConfig.Process.Capabilities == nil {
+		e.EngineConfig.OciConfig.Process.Capabilities = &specs.LinuxCapabilities{}
+	}
+	return nil
+}
+
+// idRangeFunc returns the function used to determine subordinate ID
+// ranges, a plugin may override the default one.
+func idRangeFunc() (fakerootcallback.UserMapping, error) {
+	callbackType := (fakerootcallback.UserMapping)(nil)
+	callbacks, err := plugin.LoadCallbacks(callbackType)
+	if err != nil {
+		return nil, fmt.Errorf("while loading plugins callbacks '%T': %s", callbackType, err)
+	}
+	if len(callbacks) > 1 {
+		return nil, fmt.Errorf("multiple plugins have registered hook callback for fakeroot")
+	} else if len(callbacks) == 1 {
+		return callbacks[0].(fakerootcallback.UserMapping), nil
+	}
+	return fakerootutil.GetIDRange, nil
+}
+
 // prepareContainerConfig is responsible for getting and applying
 // user supplied configuration for container creation.
 func (e *EngineOperations) prepareContainerConfig(starterConfig *starter.Config) error {
@@ -644,6 +1026,17 @@ func (e *EngineOperations) prepareContainerConfig(starterConfig *starter.Config)
 		return err
 	}
 
+	// Validate and apply any request to share the PID or IPC namespace
+	// of a running instance.
+	if err := e.joinInstanceNamespaces(starterConfig); err != nil {
+		return err
+	}
+
+	// Validate any request to mount paths of a running instance.
+	if err := e.checkMountFrom(); err != nil {
+		return err
+	}
+
 	if os.Getuid() == 0 {
 		if err := e.prepareRootCaps(); err != nil {
 			return err
@@ -678,17 +1071,9 @@ func (e *EngineOperations) prepareContainerConfig(starterConfig *starter.Config)
 			}
 		}
 
-		getIDRange := fakerootutil.GetIDRange
-
-		callbackType := (fakerootcallback.UserMapping)(nil)
-		callbacks, err := plugin.LoadCallbacks(callbackType)
+		getIDRange, err := idRangeFunc()
 		if err != nil {
-			return fmt.Errorf("while loading plugins callbacks '%T': %s", callbackType, err)
-		}
-		if len(callbacks) > 1 {
-			return fmt.Errorf("multiple plugins have registered hook callback for fakeroot")
-		} else if len(callbacks) == 1 {
-			getIDRange = callbacks[0].(fakerootcallback.UserMapping)
+			return err
 		}
 
 		e.EngineConfig.OciConfig.AddLinuxUIDMapping(uid, 0, 1)
@@ -718,6 +1103,62 @@ func (e *EngineOperations) prepareContainerConfig(starterConfig *starter.Config)
 		starterConfig.SetTargetGID([]int{0})
 	}
 
+	if caps := e.EngineConfig.GetFakerootCaps(); caps != "" {
+		if err := e.setFakerootCaps(caps); err != nil {
+			return err
+		}
+	}
+
+	if e.EngineConfig.GetKeepID() {
+		uid := uint32(os.Getuid())
+		gid := uint32(os.Getgid())
+
+		if !starterConfig.GetIsSUID() {
+			sylog.Verbosef("Keep-id requested with unprivileged workflow, fallback to newuidmap/newgidmap")
+			sylog.Debugf("Search for newuidmap binary")
+			if err := starterConfig.SetNewUIDMapPath(); err != nil {
+				return err
+			}
+			sylog.Debugf("Search for newgidmap binary")
+			if err := starterConfig.SetNewGIDMapPath(); err != nil {
+				return err
+			}
+		}
+
+		getIDRange, err := idRangeFunc()
+		if err != nil {
+			return err
+		}
+
+		// Unlike fakeroot, the user keeps their own UID/GID in the container,
+		// subordinate ranges are only used to map the other container IDs
+		idRange, err := getIDRange(fakerootutil.SubUIDFile, uid)
+		if err != nil {
+			return fmt.Errorf("could not use keep-id: %s", err)
+		}
+		for _, m := range fakerootutil.GetKeepIDMappings(uid, idRange) {
+			e.EngineConfig.OciConfig.AddLinuxUIDMapping(m.HostID, m.ContainerID, m.Size)
+		}
+		starterConfig.AddUIDMappings(e.EngineConfig.OciConfig.Linux.UIDMappings)
+
+		idRange, err = getIDRange(fakerootutil.SubGIDFile, uid)
+		if err != nil {
+			return fmt.Errorf("could not use keep-id: %s", err)
+		}
+		for _, m := range fakerootutil.GetKeepIDMappings(gid, idRange) {
+			e.EngineConfig.OciConfig.AddLinuxGIDMapping(m.HostID, m.ContainerID, m.Size)
+		}
+		starterConfig.AddGIDMappings(e.EngineConfig.OciConfig.Linux.GIDMappings)
+
+		e.EngineConfig.OciConfig.AddOrReplaceLinuxNamespace(specs.UserNamespace, "")
+
+		starterConfig.SetHybridWorkflow(true)
+		starterConfig.SetAllowSetgroups(true)
+
+		starterConfig.SetTargetUID(int(uid))
+		starterConfig.SetTargetGID([]int{int(gid)})
+	}
+
 	starterConfig.SetBringLoopbackInterface(true)
 
 	// check whether container should run in sharens mode
@@ -758,6 +1199,12 @@ func (e *EngineOperations) prepareContainerConfig(starterConfig *starter.Config)
 			return err
 		}
 	}
+	if params := security.GetParams(e.EngineConfig.GetSecurity(), "landlock"); len(params) > 0 {
+		sylog.Debugf("Applying landlock rules %s", strings.Join(params, ","))
+		if _, err := landlock.ParseRules(params); err != nil {
+			return err
+		}
+	}
 
 	// open file descriptors (autofs bug path)
 	return e.prepareAutofs(starterConfig)
@@ -1028,6 +1475,18 @@ func (e *EngineOperations) prepareInstanceJoinConfig(starterConfig *starter.Conf
 		e.EngineConfig.OciConfig.Linux.Seccomp = instanceEngineConfig.OciConfig.Linux.Seccomp
 	}
 
+	// restore landlock rules or apply new ones if provided
+	if params := security.GetParams(e.EngineConfig.GetSecurity(), "landlock"); len(params) > 0 {
+		sylog.Debugf("Applying landlock rules %s", strings.Join(params, ","))
+		if _, err := landlock.ParseRules(params); err != nil {
+			return err
+		}
+	} else {
+		for _, param := range security.GetParams(instanceEngineConfig.GetSecurity(), "landlock") {
+			e.EngineConfig.SetSecurity(append(e.EngineConfig.GetSecurity(), "landlock:"+param))
+		}
+	}
+
 	// Note - in non-root flow without userns the CLI process joined the cgroup
 	// early in execStarter because we don't have permission to move a parent
 	// process into the cgroup here. In that case, this code is a no-op that
@@ -1073,6 +1532,18 @@ func openDevFuse(e *EngineOperations, starterConfig *starter.Config) (bool, erro
 		return false, fmt.Errorf("fusemount disabled by configuration 'enable fusemount = no'")
 	}
 
+	if allowed := e.EngineConfig.File.AllowedFusemountPrograms; len(allowed) > 0 {
+		for _, m := range mounts {
+			if len(m.Program) == 0 {
+				continue
+			}
+			prog := filepath.Base(m.Program[0])
+			if !slice.ContainsString(allowed, prog) {
+				return false, fmt.Errorf("fusemount program %s not allowed by configuration 'allowed fusemount programs'", prog)
+			}
+		}
+	}
+
 	for i := range mounts {
 		sylog.Debugf("Opening /dev/fuse for FUSE mount point %s\n", mounts[i].MountPoint)
 		fd, err := syscall.Open("/dev/fuse", syscall.O_RDWR, 0)
@@ -1083,7 +1554,7 @@ func openDevFuse(e *EngineOperations, starterConfig *starter.Config) (bool, erro
 		mounts[i].Fd = fd
 		starterConfig.KeepFileDescriptor(fd)
 
-		if (!starterConfig.GetIsSUID() || e.EngineConfig.GetFakeroot()) && !mounts[i].FromContainer {
+		if (!starterConfig.GetIsSUID() || e.EngineConfig.GetFakeroot() || e.EngineConfig.GetKeepID()) && !mounts[i].FromContainer {
 			sendFd = true
 		}
 	}
@@ -1122,6 +1593,7 @@ func (e *EngineOperations) setSessionLayer(img *image.Image) error {
 	writableTmpfs := e.EngineConfig.GetWritableTmpfs()
 	writableImage := e.EngineConfig.GetWritableImage()
 	hasOverlayImage := len(e.EngineConfig.GetOverlayImage()) > 0
+	hasOCILayers := len(e.EngineConfig.GetOCILayers()) > 0
 
 	if writableImage && hasOverlayImage {
 		return fmt.Errorf("cannot use --overlay in conjunction with --writable")
@@ -1150,12 +1622,15 @@ func (e *EngineOperations) setSessionLayer(img *image.Image) error {
 		if writableTmpfs {
 			return fmt.Errorf("--writable-tmpfs requires 'enable overlay', but set to 'no' by administrator")
 		}
+		if hasOCILayers {
+			return fmt.Errorf("OCI layers require 'enable overlay', but set to 'no' by administrator")
+		}
 		if hasSIFOverlay {
 			return fmt.Errorf("SIF overlay partition requires 'enable overlay', but set to 'no' by administrator")
 		}
 		sylog.Debugf("Can not use overlay, disabled by configuration ('enable overlay = no')")
 	} else {
-		if writableTmpfs || hasOverlayImage {
+		if writableTmpfs || hasOverlayImage || hasOCILayers {
 			sylog.Debugf("Overlay requested by user")
 			e.EngineConfig.SetSessionLayer(apptainerConfig.OverlayLayer)
 			return nil
@@ -1201,6 +1676,10 @@ func (e *EngineOperations) setSessionLayer(img *image.Image) error {
 	return nil
 }
 
+// loadImages loads the root filesystem image followed by the OCI layers,
+// the overlay images and the data bind images and stores them in the image
+// list. When starterConfig is nil the image file descriptors are not passed
+// to the container process, this is used by MountPlan.
 func (e *EngineOperations) loadImages(starterConfig *starter.Config, userNS bool, elevated bool) error {
 	images := make([]image.Image, 0)
 
@@ -1228,11 +1707,7 @@ func (e *EngineOperations) loadImages(starterConfig *starter.Config, userNS bool
 	images = append(images, *img)
 	writableOverlayPath := ""
 
-	if err := hack.UnsetFileFinalizer(img.File); err != nil {
-		return err
-	}
-
-	if err := starterConfig.KeepFileDescriptor(int(img.Fd)); err != nil {
+	if err := keepImage(starterConfig, img); err != nil {
 		return err
 	}
 
@@ -1243,11 +1718,17 @@ func (e *EngineOperations) loadImages(starterConfig *starter.Config, userNS bool
 		}
 
 		// C starter code will position current working directory
-		starterConfig.SetWorkingDirectoryFd(int(img.Fd))
+		if starterConfig != nil {
+			starterConfig.SetWorkingDirectoryFd(int(img.Fd))
+		}
 
 		if e.EngineConfig.GetSessionLayer() == apptainerConfig.OverlayLayer &&
 			(imageDriver == nil || imageDriver.Features()&image.OverlayFeature == 0) {
 			if err := overlay.CheckLower(img.Path); overlay.IsIncompatible(err) {
+				// the image layers can't be dropped like overlay images
+				if len(e.EngineConfig.GetOCILayers()) > 0 {
+					return fmt.Errorf("OCI layers can't be used: %s", err)
+				}
 				layer := apptainerConfig.UnderlayLayer
 				if e.EngineConfig.File.EnableUnderlay == "no" {
 					sylog.Warningf("Could not fallback to underlay, disabled by configuration ('enable underlay = no')")
@@ -1313,8 +1794,8 @@ func (e *EngineOperations) loadImages(starterConfig *starter.Config, userNS bool
 				if p.Type != image.EXT3 {
 					continue
 				}
-				if elevated && !e.EngineConfig.File.AllowSetuidMountExtfs && (imageDriver == nil || imageDriver.Features()&image.Ext3Feature == 0) {
-					return fmt.Errorf("configuration disallows users from mounting SIF extfs partition in setuid mode, try --userns")
+				if err := e.checkSetuidExtfs(elevated, "SIF extfs partition"); err != nil {
+					return err
 				}
 				if img.Writable {
 					writableOverlayPath = img.Path
@@ -1332,6 +1813,11 @@ func (e *EngineOperations) loadImages(starterConfig *starter.Config, userNS bool
 
 	switch e.EngineConfig.GetSessionLayer() {
 	case apptainerConfig.OverlayLayer:
+		layerImages, err := e.loadOCILayers(starterConfig, userNS, elevated)
+		if err != nil {
+			return fmt.Errorf("while loading OCI layers: %s", err)
+		}
+		images = append(images, layerImages...)
 		overlayImages, err := e.loadOverlayImages(starterConfig, writableOverlayPath, userNS, elevated)
 		if err != nil {
 			return fmt.Errorf("while loading overlay images: %s", err)
@@ -1355,24 +1841,181 @@ func (e *EngineOperations) loadImages(starterConfig *starter.Config, userNS bool
 	return nil
 }
 
-// loadOverlayImages loads overlay images.
+// keepImage keeps the image file descriptor open for the container
+// process, nothing is done if starterConfig is nil.
+func keepImage(starterConfig *starter.Config, img *image.Image) error {
+	if starterConfig == nil {
+		return nil
+	}
+	if err := hack.UnsetFileFinalizer(img.File); err != nil {
+		return err
+	}
+	return starterConfig.KeepFileDescriptor(int(img.Fd))
+}
+
+// checkOverlayOpts returns an error if an overlay feature requested with
+// --overlay-opts isn't enabled by the administrator in setuid mode, the
+// kernel documentation warns against metacopy and redirect_dir with
+// untrusted layers like user images whose trusted.* xattrs are controlled
+// by the user.
+func (e *EngineOperations) checkOverlayOpts(elevated bool) error {
+	if !elevated {
+		return nil
+	}
+	for _, opt := range e.EngineConfig.GetOverlayOpts() {
+		allowed, directive := false, ""
+		switch opt = strings.TrimSpace(opt); opt {
+		case "metacopy":
+			allowed, directive = e.EngineConfig.File.OverlayMetacopy, "overlay metacopy"
+		case "redirect_dir":
+			allowed, directive = e.EngineConfig.File.OverlayRedirectDir, "overlay redirect dir"
+		default:
+			continue
+		}
+		if !allowed {
+			return fmt.Errorf("--overlay-opts %s requires '%s = yes' in apptainer.conf in setuid mode, try --userns", opt, directive)
+		}
+	}
+	return nil
+}
+
+// checkSquashfsMountOpts returns an error if a squashfs mount option
+// passed with --image-mount-opt isn't allowed, only a list of safe options
+// is accepted for the kernel mount done with elevated privileges.
+func (e *EngineOperations) checkSquashfsMountOpts(elevated bool) error {
+	if _, err := mount.SquashfsMountOptions(e.EngineConfig.GetSquashfsMountOpts(), elevated); err != nil {
+		return fmt.Errorf("while checking squashfs mount options: %s", err)
+	}
+	return nil
+}
+
+// checkSetuidExtfs returns an error if an extfs image can't be mounted in
+// setuid mode. When 'allow setuid-mount extfs' is disabled, extfs images
+// are mounted read-write with fuse2fs by the image driver, the error tells
+// whether fuse2fs is missing or the image driver doesn't handle extfs.
+func (e *EngineOperations) checkSetuidExtfs(elevated bool, what string) error {
+	if !elevated || e.EngineConfig.File.AllowSetuidMountExtfs {
+		return nil
+	}
+	if imageDriver != nil && imageDriver.Features()&image.Ext3Feature != 0 {
+		sylog.Debugf("Mounting %s with the %s image driver", what, e.EngineConfig.File.ImageDriver)
+		return nil
+	}
+	if _, err := bin.FindBin("fuse2fs"); err != nil {
+		return fmt.Errorf(
+			"configuration disallows users from mounting %s in setuid mode and fuse2fs, "+
+				"required to mount it with FUSE instead, was not found: install fuse2fs or try --userns",
+			what,
+		)
+	}
+	return fmt.Errorf(
+		"configuration disallows users from mounting %s in setuid mode and the %s image driver "+
+			"doesn't support extfs mounts, try --userns",
+		what, e.EngineConfig.File.ImageDriver,
+	)
+}
+
+// errOverlayLayers returns the error reported when the number of read-only
+// overlay layers exceeds the maximum set in apptainer.conf.
+func errOverlayLayers(maxLayers uint) error {
+	return fmt.Errorf(
+		"too many overlay layers, the maximum allowed by 'max overlay layers' in apptainer.conf is %d "+
+			"(the kernel overlay filesystem supports at most 500 lower layers and, before Linux 6.8, "+
+			"limits the size of the mount options to a page)",
+		maxLayers,
+	)
+}
+
+// loadOCILayers loads the unpacked OCI layer directories stacked as
+// read-only lower layers between the root filesystem and the overlay
+// images.
+func (e *EngineOperations) loadOCILayers(starterConfig *starter.Config, userNS bool, elevated bool) ([]image.Image, error) {
+	layers := e.EngineConfig.GetOCILayers()
+	maxLayers := e.EngineConfig.File.MaxOverlayLayers
+	if uint(len(layers)) > maxLayers {
+		return nil, errOverlayLayers(maxLayers)
+	}
+
+	images := make([]image.Image, 0, len(layers))
+	paths := make([]string, 0, len(layers))
+
+	for _, path := range layers {
+		img, err := e.loadImage(path, false, userNS, elevated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open OCI layer %s: %s", path, err)
+		}
+		if img.Type != image.SANDBOX {
+			return nil, fmt.Errorf("OCI layer %s is not a directory", path)
+		}
+		img.Usage = image.OverlayUsage
+
+		if err := keepImage(starterConfig, img); err != nil {
+			return nil, err
+		}
+		images = append(images, *img)
+		paths = append(paths, img.Path)
+	}
+
+	// store resolved paths to identify the layers in the image list
+	e.EngineConfig.SetOCILayers(paths)
+
+	return images, nil
+}
+
+// loadOverlayImages loads overlay images. Only one overlay image can be
+// writable, by default the last writable capable image specified becomes
+// the writable upper layer and the previous ones are used as read-only
+// lower layers. An image explicitly requested writable with the :rw suffix
+// takes precedence, specifying two of them is an error.
 func (e *EngineOperations) loadOverlayImages(starterConfig *starter.Config, writableOverlayPath string, userNS bool, elevated bool) ([]image.Image, error) {
-	images := make([]image.Image, 0)
+	overlayImages := e.EngineConfig.GetOverlayImage()
+	images := make([]image.Image, len(overlayImages))
+	maxLayers := e.EngineConfig.File.MaxOverlayLayers
+	layers := uint(0)
 
-	for _, overlayImg := range e.EngineConfig.GetOverlayImage() {
-		writableOverlay := true
+	// a writable SIF overlay partition is explicitly requested with --writable
+	explicitWritable := writableOverlayPath != ""
+	for _, overlayImg := range overlayImages {
+		if strings.HasSuffix(overlayImg, ":rw") {
+			explicitWritable = true
+		}
+	}
+	overlayUpper := false
+	forcedLowers := make([]string, 0)
+
+	var kr openpgp.KeyRing
+	if e.EngineConfig.GetOverlayVerify() || e.EngineConfig.File.RequireSignedOverlay {
+		keyring := sypgp.NewHandle(buildcfg.APPTAINER_CONFDIR, sypgp.GlobalHandleOpt())
+		el, err := keyring.LoadPubKeyring()
+		if err != nil {
+			return nil, fmt.Errorf("while obtaining keyring for overlay verification: %s", err)
+		}
+		kr = el
+	}
 
-		splitted := strings.SplitN(overlayImg, ":", 2)
-		if len(splitted) == 2 {
-			if splitted[1] == "ro" {
-				writableOverlay = false
+	// images are loaded from the last one so the writable upper
+	// layer is the last writable capable image
+	for i := len(overlayImages) - 1; i >= 0; i-- {
+		path, mode, _ := strings.Cut(overlayImages[i], ":")
+		if kr != nil {
+			m, err := signedOverlayMode(path, mode)
+			if err != nil {
+				return nil, err
 			}
+			mode = m
+		}
+		writableOverlay := mode != "ro"
+		forcedReadOnly := false
+
+		if writableOverlay && mode != "rw" && (explicitWritable || writableOverlayPath != "") {
+			writableOverlay = false
+			forcedReadOnly = true
 		}
 
-		img, err := e.loadImage(splitted[0], writableOverlay, userNS, elevated)
+		img, err := e.loadImage(path, writableOverlay, userNS, elevated)
 		if err != nil {
 			if !image.IsReadOnlyFilesytem(err) {
-				return nil, fmt.Errorf("failed to open overlay image %s: %s", splitted[0], err)
+				return nil, fmt.Errorf("failed to open overlay image %s: %s", path, err)
 			}
 			// let's proceed with readonly filesystem and set
 			// writableOverlay to appropriate value
@@ -1380,6 +2023,29 @@ func (e *EngineOperations) loadOverlayImages(starterConfig *starter.Config, writ
 		}
 		img.Usage = image.OverlayUsage
 
+		// ext3 overlay partitions of SIF overlay images are mounted
+		// like the SIF root filesystem ext3 overlay partitions
+		if img.Type == image.SIF {
+			overlays, err := img.GetOverlayPartitions()
+			if err != nil {
+				return nil, fmt.Errorf("while getting overlay partitions in %s: %s", img.Path, err)
+			}
+			for _, p := range overlays {
+				if p.Type != image.EXT3 {
+					continue
+				}
+				if err := e.checkSetuidExtfs(elevated, "SIF extfs partition"); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if kr != nil {
+			if err := verifyOverlayImage(img, kr); err != nil {
+				return nil, fmt.Errorf("while verifying overlay image %s: %s", img.Path, err)
+			}
+		}
+
 		if writableOverlay && img.Writable {
 			if writableOverlayPath != "" {
 				return nil, fmt.Errorf(
@@ -1389,20 +2055,31 @@ func (e *EngineOperations) loadOverlayImages(starterConfig *starter.Config, writ
 				)
 			}
 			writableOverlayPath = img.Path
+			overlayUpper = true
+			sylog.Verbosef("Using overlay image %s as the writable upper layer", img.Path)
+		} else {
+			if layers++; layers > maxLayers {
+				return nil, errOverlayLayers(maxLayers)
+			}
+			if forcedReadOnly {
+				forcedLowers = append(forcedLowers, img.Path)
+			}
 		}
 
-		e.EngineConfig.SetWritableOverlay(writableOverlay)
-
-		if err := hack.UnsetFileFinalizer(img.File); err != nil {
+		if err := keepImage(starterConfig, img); err != nil {
 			return nil, err
 		}
+		images[i] = *img
+	}
 
-		if err := starterConfig.KeepFileDescriptor(int(img.Fd)); err != nil {
-			return nil, err
+	if writableOverlayPath != "" {
+		for _, path := range forcedLowers {
+			sylog.Verbosef("Using overlay image %s as a read-only lower layer, %s provides the writable upper layer", path, writableOverlayPath)
 		}
-		images = append(images, *img)
 	}
 
+	e.EngineConfig.SetWritableOverlay(overlayUpper)
+
 	if e.EngineConfig.GetWritableTmpfs() && writableOverlayPath != "" {
 		return nil, fmt.Errorf("you can't specify --writable-tmpfs with another writable overlay image (%s)", writableOverlayPath)
 	}
@@ -1410,6 +2087,104 @@ func (e *EngineOperations) loadOverlayImages(starterConfig *starter.Config, writ
 	return images, nil
 }
 
+// signedOverlayMode returns the mode used for the overlay image path
+// requested with mode when its signature is verified. A writable overlay
+// could be modified once verified, so signed overlays are always opened
+// read-only and requesting one writable is an error.
+func signedOverlayMode(path, mode string) (string, error) {
+	switch mode {
+	case "rw":
+		return "", fmt.Errorf("overlay image %s can't be writable when its signature is verified", path)
+	case "ro":
+	default:
+		sylog.Verbosef("Using overlay image %s read-only as its signature is verified", path)
+	}
+	return "ro", nil
+}
+
+// verifyOverlayImage checks that the overlay image carries a valid signature
+// from a key of the keyring. SIF images are verified with their embedded
+// signatures, other images with a detached signature file located next to
+// the image with the .sig extension, or .asc for an armored signature. The
+// image is read through its open file, the one kept for the mount, so the
+// verified image can't be substituted by another file.
+func verifyOverlayImage(img *image.Image, kr openpgp.KeyRing) error {
+	var signer *openpgp.Entity
+
+	switch img.Type {
+	case image.SANDBOX:
+		return fmt.Errorf("overlay directories can't be signed")
+	case image.SIF:
+		f, err := sif.LoadContainer(img.File,
+			sif.OptLoadWithFlag(os.O_RDONLY),
+			sif.OptLoadWithCloseOnUnload(false),
+		)
+		if err != nil {
+			return err
+		}
+		defer f.UnloadContainer()
+
+		cb := func(r integrity.VerifyResult) bool {
+			if r.Error() == nil && r.Entity() != nil {
+				signer = r.Entity()
+			}
+			return false
+		}
+		opts := []integrity.VerifierOpt{
+			integrity.OptVerifyWithContext(context.TODO()),
+			integrity.OptVerifyWithKeyRing(kr),
+			integrity.OptVerifyCallback(cb),
+		}
+		// don't rely on the default verification of object groups,
+		// each overlay partition must be covered by a valid signature
+		overlays, err := img.GetOverlayPartitions()
+		if err != nil {
+			return err
+		}
+		for _, p := range overlays {
+			opts = append(opts, integrity.OptVerifyObject(p.ID))
+		}
+		v, err := integrity.NewVerifier(f, opts...)
+		if err != nil {
+			return err
+		}
+		if err := v.Verify(); err != nil {
+			return err
+		}
+	default:
+		fi, err := img.File.Stat()
+		if err != nil {
+			return err
+		}
+		signed := io.NewSectionReader(img.File, 0, fi.Size())
+
+		if sig, err := os.Open(img.Path + ".sig"); err == nil {
+			defer sig.Close()
+			signer, err = openpgp.CheckDetachedSignature(kr, signed, sig, nil)
+			if err != nil {
+				return fmt.Errorf("signature verification failed: %s", err)
+			}
+		} else if sig, err := os.Open(img.Path + ".asc"); err == nil {
+			defer sig.Close()
+			signer, err = openpgp.CheckArmoredDetachedSignature(kr, signed, sig, nil)
+			if err != nil {
+				return fmt.Errorf("signature verification failed: %s", err)
+			}
+		} else {
+			return fmt.Errorf("no detached signature %s.sig or %s.asc found", img.Path, img.Path)
+		}
+	}
+
+	if signer != nil {
+		name := ""
+		if id := signer.PrimaryIdentity(); id != nil {
+			name = id.Name
+		}
+		sylog.Verbosef("Overlay image %s signed by %s (%X)", img.Path, name, signer.PrimaryKey.Fingerprint)
+	}
+	return nil
+}
+
 // loadBindImages load data bind images.
 func (e *EngineOperations) loadBindImages(starterConfig *starter.Config, userNS bool, elevated bool) ([]image.Image, error) {
 	images := make([]image.Image, 0)
@@ -1417,7 +2192,7 @@ func (e *EngineOperations) loadBindImages(starterConfig *starter.Config, userNS
 	binds := e.EngineConfig.GetBindPath()
 
 	for i := range binds {
-		if binds[i].ImageSrc() == "" && binds[i].ID() == "" {
+		if !binds[i].IsImageBind() {
 			continue
 		}
 
@@ -1431,11 +2206,7 @@ func (e *EngineOperations) loadBindImages(starterConfig *starter.Config, userNS
 		}
 		img.Usage = image.DataUsage
 
-		if err := hack.UnsetFileFinalizer(img.File); err != nil {
-			return nil, err
-		}
-
-		if err := starterConfig.KeepFileDescriptor(int(img.Fd)); err != nil {
+		if err := keepImage(starterConfig, img); err != nil {
 			return nil, err
 		}
 		images = append(images, *img)
@@ -1510,13 +2281,18 @@ func (e *EngineOperations) loadImage(path string, writable bool, userNS bool, el
 		if elevated && !squashfs.SetuidMountAllowed(e.EngineConfig.File) && !hasFeature(image.SquashFeature) {
 			return nil, fmt.Errorf("configuration disallows users from mounting squashFS in setuid mode, try --userns")
 		}
+	// Bare EROFS
+	case image.EROFS:
+		if elevated && !squashfs.SetuidMountAllowed(e.EngineConfig.File) && !hasFeature(image.ErofsFeature) {
+			return nil, fmt.Errorf("configuration disallows users from mounting EROFS in setuid mode, try --userns")
+		}
 	// Bare EXT3
 	case image.EXT3:
 		if !e.EngineConfig.File.AllowContainerExtfs {
 			return nil, fmt.Errorf("configuration disallows users from running extFS containers")
 		}
-		if elevated && !e.EngineConfig.File.AllowSetuidMountExtfs && !hasFeature(image.Ext3Feature) {
-			return nil, fmt.Errorf("configuration disallows users from mounting extfs in setuid mode, try --userns")
+		if err := e.checkSetuidExtfs(elevated, "extfs"); err != nil {
+			return nil, err
 		}
 	// Bare sandbox directory
 	case image.SANDBOX:
@@ -1525,7 +2301,11 @@ func (e *EngineOperations) loadImage(path string, writable bool, userNS bool, el
 		}
 	// SIF
 	case image.SIF:
-		if elevated && !squashfs.SetuidMountAllowed(e.EngineConfig.File) && !hasFeature(image.SquashFeature) {
+		if part, err := imgObject.GetRootFsPartition(); err == nil && part.Type == image.EROFS {
+			if elevated && !squashfs.SetuidMountAllowed(e.EngineConfig.File) && !hasFeature(image.ErofsFeature) {
+				return nil, fmt.Errorf("configuration disallows users from mounting SIF EROFS partition in setuid mode, try --userns")
+			}
+		} else if elevated && !squashfs.SetuidMountAllowed(e.EngineConfig.File) && !hasFeature(image.SquashFeature) {
 			return nil, fmt.Errorf("configuration disallows users from mounting SIF squashFS partition in setuid mode, try --userns")
 		}
 		// Check if SIF contains an encrypted rootfs partition.
@@ -1538,10 +2318,10 @@ func (e *EngineOperations) loadImage(path string, writable bool, userNS bool, el
 		if encryptedType != "" && !e.EngineConfig.File.AllowContainerEncrypted {
 			return nil, fmt.Errorf("configuration disallows users from running encrypted SIF containers")
 		}
-		if encryptedType == "encryptfs" && userNS {
+		if encryptedType == "encryptfs" && userNS && !hasFeature(image.EncryptFeature) {
 			return nil, fmt.Errorf("cannot mount device-mapper encrypted files without setuid mode or root")
 		}
-		if encryptedType == "encryptfs" && elevated && !e.EngineConfig.File.AllowSetuidMountEncrypted {
+		if encryptedType == "encryptfs" && elevated && !e.EngineConfig.File.AllowSetuidMountEncrypted && !hasFeature(image.EncryptFeature) {
 			return nil, fmt.Errorf("configuration disallows users from mounting device-mapper encrypted files")
 		}
 		// SIF without encryption - regardless of rootfs filesystem type
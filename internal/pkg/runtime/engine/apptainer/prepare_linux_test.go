@@ -0,0 +1,663 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/apptainer/apptainer/internal/pkg/util/mainthread"
+	"github.com/apptainer/apptainer/pkg/image"
+	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
+	"github.com/apptainer/apptainer/pkg/util/apptainerconf"
+	"github.com/apptainer/sif/v2/pkg/integrity"
+	"github.com/apptainer/sif/v2/pkg/sif"
+)
+
+const testSquash = "../../../../../pkg/image/testdata/squashfs.v4"
+
+// overlayPartition returns a squashfs overlay partition descriptor input.
+func overlayPartition(t *testing.T, opts ...sif.DescriptorInputOpt) sif.DescriptorInput {
+	b, err := os.ReadFile(testSquash)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", testSquash, err)
+	}
+	opts = append(opts, sif.OptPartitionMetadata(sif.FsSquash, sif.PartOverlay, runtime.GOARCH))
+	di, err := sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(b), opts...)
+	if err != nil {
+		t.Fatalf("failed to create descriptor input: %s", err)
+	}
+	return di
+}
+
+// createOverlaySIF creates a SIF image with the given partitions, the
+// object groups are signed with e if not nil.
+func createOverlaySIF(t *testing.T, e *openpgp.Entity, dis ...sif.DescriptorInput) string {
+	path := filepath.Join(t.TempDir(), "overlay.sif")
+
+	opts := make([]sif.CreateOpt, 0, len(dis))
+	for _, di := range dis {
+		opts = append(opts, sif.OptCreateWithDescriptors(di))
+	}
+	f, err := sif.CreateContainerAtPath(path, opts...)
+	if err != nil {
+		t.Fatalf("failed to create SIF: %s", err)
+	}
+	defer f.UnloadContainer()
+
+	if e != nil {
+		s, err := integrity.NewSigner(f, integrity.OptSignWithEntity(e))
+		if err != nil {
+			t.Fatalf("failed to create signer: %s", err)
+		}
+		if err := s.Sign(); err != nil {
+			t.Fatalf("failed to sign SIF: %s", err)
+		}
+	}
+	return path
+}
+
+// createExt3 creates an ext3 image and returns its path, an empty path is
+// returned if mkfs.ext3 is not available.
+func createExt3(t *testing.T) string {
+	mkfs, err := exec.LookPath("mkfs.ext3")
+	if err != nil {
+		return ""
+	}
+	ext3 := filepath.Join(t.TempDir(), "overlay.img")
+	if err := os.WriteFile(ext3, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(ext3, 4<<20); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command(mkfs, "-q", "-F", ext3).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create ext3 image: %s: %s", err, out)
+	}
+	return ext3
+}
+
+func TestVerifyOverlayImage(t *testing.T) {
+	e, err := openpgp.NewEntity("Apptainer Test", "", "test@apptainer.test", &packet.Config{
+		Algorithm: packet.PubKeyAlgoEdDSA,
+	})
+	if err != nil {
+		t.Fatalf("failed to create entity: %s", err)
+	}
+	kr := openpgp.EntityList{e}
+
+	signed := createOverlaySIF(t, e, overlayPartition(t, sif.OptGroupID(1)))
+
+	// the group is signed but not the ungrouped overlay partition
+	unsignedUngrouped := createOverlaySIF(t, e,
+		overlayPartition(t, sif.OptGroupID(1)),
+		overlayPartition(t, sif.OptNoGroup()),
+	)
+
+	tampered := createOverlaySIF(t, e, overlayPartition(t, sif.OptGroupID(1)))
+	f, err := sif.LoadContainerFromPath(tampered, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		t.Fatalf("failed to load %s: %s", tampered, err)
+	}
+	d, err := f.GetDescriptor(sif.WithPartitionType(sif.PartOverlay))
+	if err != nil {
+		t.Fatalf("failed to get overlay partition: %s", err)
+	}
+	offset := d.Offset() + d.Size() - 1
+	f.UnloadContainer()
+	tf, err := os.OpenFile(tampered, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tf.WriteAt([]byte{0xff}, offset); err != nil {
+		t.Fatal(err)
+	}
+	tf.Close()
+
+	sandbox := t.TempDir()
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{
+			name: "Signed",
+			path: signed,
+		},
+		{
+			name:    "UnsignedUngrouped",
+			path:    unsignedUngrouped,
+			wantErr: true,
+		},
+		{
+			name:    "Tampered",
+			path:    tampered,
+			wantErr: true,
+		},
+		{
+			name:    "Sandbox",
+			path:    sandbox,
+			wantErr: true,
+		},
+	}
+
+	if ext3 := createExt3(t); ext3 != "" {
+		// no detached signature next to the image
+		tests = append(tests, struct {
+			name    string
+			path    string
+			wantErr bool
+		}{name: "Ext3WithoutSignature", path: ext3, wantErr: true})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := image.Init(tt.path, false)
+			if err != nil {
+				t.Fatalf("failed to open image %s: %s", tt.path, err)
+			}
+			defer img.File.Close()
+
+			err = verifyOverlayImage(img, kr)
+			if tt.wantErr && err == nil {
+				t.Errorf("unexpected success")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestSignedOverlayMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    string
+		wantErr bool
+	}{
+		{mode: "", want: "ro"},
+		{mode: "ro", want: "ro"},
+		{mode: "rw", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		mode, err := signedOverlayMode("overlay.img", tt.mode)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("mode %q: unexpected success", tt.mode)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("mode %q: unexpected error: %s", tt.mode, err)
+		}
+		if mode != tt.want {
+			t.Errorf("mode %q: got %q, want %q", tt.mode, mode, tt.want)
+		}
+	}
+}
+
+func TestCheckOverlayOpts(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []string
+		elevated    bool
+		metacopy    bool
+		redirectDir bool
+		wantErr     bool
+	}{
+		{
+			name: "Unprivileged",
+			opts: []string{"metacopy", "redirect_dir"},
+		},
+		{
+			name:     "SetuidNoOpts",
+			elevated: true,
+		},
+		{
+			name:     "SetuidMetacopyDisallowed",
+			opts:     []string{"metacopy"},
+			elevated: true,
+			wantErr:  true,
+		},
+		{
+			name:     "SetuidRedirectDirDisallowed",
+			opts:     []string{"metacopy", " redirect_dir"},
+			elevated: true,
+			metacopy: true,
+			wantErr:  true,
+		},
+		{
+			name:        "SetuidAllowed",
+			opts:        []string{"metacopy", "redirect_dir"},
+			elevated:    true,
+			metacopy:    true,
+			redirectDir: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &EngineOperations{EngineConfig: apptainerConfig.NewConfig()}
+			e.EngineConfig.SetOverlayOpts(tt.opts)
+			e.EngineConfig.File.OverlayMetacopy = tt.metacopy
+			e.EngineConfig.File.OverlayRedirectDir = tt.redirectDir
+
+			err := e.checkOverlayOpts(tt.elevated)
+			if tt.wantErr && err == nil {
+				t.Errorf("unexpected success")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestCheckSquashfsMountOpts(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []string
+		elevated bool
+		wantErr  bool
+	}{
+		{
+			name: "UnprivilegedAnyOption",
+			opts: []string{"cache_size=64M"},
+		},
+		{
+			name:    "UnprivilegedBadFormat",
+			opts:    []string{"cache_size"},
+			wantErr: true,
+		},
+		{
+			name:     "SetuidAllowed",
+			opts:     []string{"errors=continue", "threads=4"},
+			elevated: true,
+		},
+		{
+			name:     "SetuidDisallowed",
+			opts:     []string{"cache_size=64M"},
+			elevated: true,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &EngineOperations{EngineConfig: apptainerConfig.NewConfig()}
+			e.EngineConfig.SetSquashfsMountOpts(tt.opts)
+
+			err := e.checkSquashfsMountOpts(tt.elevated)
+			if tt.wantErr && err == nil {
+				t.Errorf("unexpected success")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+// serveMainThread executes the functions sent to the main thread by the
+// image loading until the test ends.
+func serveMainThread(t *testing.T) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		for {
+			select {
+			case f := <-mainthread.FuncChannel:
+				f()
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+func TestLoadOverlayImages(t *testing.T) {
+	serveMainThread(t)
+
+	file, err := apptainerconf.GetConfig(nil)
+	if err != nil {
+		t.Fatalf("failed to get default configuration: %s", err)
+	}
+
+	lower := t.TempDir()
+	upper := t.TempDir()
+
+	b, err := os.ReadFile(testSquash)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", testSquash, err)
+	}
+	squash := filepath.Join(t.TempDir(), "overlay.sqfs")
+	if err := os.WriteFile(squash, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ext3 := createExt3(t)
+
+	tests := []struct {
+		name     string
+		overlays []string
+		needExt3 bool
+		// writable is the expected writable state of each image
+		writable []bool
+		wantErr  bool
+	}{
+		{
+			name:     "TwoWritable",
+			overlays: []string{lower, upper},
+			writable: []bool{false, true},
+		},
+		{
+			name:     "ExplicitAndImplicitWritable",
+			overlays: []string{upper + ":rw", lower},
+			writable: []bool{true, false},
+		},
+		{
+			name:     "TwoExplicitWritable",
+			overlays: []string{lower + ":rw", upper + ":rw"},
+			wantErr:  true,
+		},
+		{
+			name:     "SquashfsLast",
+			overlays: []string{ext3, squash},
+			needExt3: true,
+			writable: []bool{true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needExt3 && ext3 == "" {
+				t.Skip("mkfs.ext3 not found")
+			}
+			e := &EngineOperations{EngineConfig: apptainerConfig.NewConfig()}
+			e.EngineConfig.File = file
+			e.EngineConfig.SetOverlayImage(tt.overlays)
+
+			images, err := e.loadOverlayImages(nil, "", false, false)
+			defer func() {
+				for _, img := range images {
+					img.File.Close()
+				}
+			}()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("unexpected success")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(images) != len(tt.writable) {
+				t.Fatalf("got %d images, want %d", len(images), len(tt.writable))
+			}
+			for i, img := range images {
+				if img.Writable != tt.writable[i] {
+					t.Errorf("image %s: got writable %v, want %v", img.Path, img.Writable, tt.writable[i])
+				}
+			}
+			if !e.EngineConfig.GetWritableOverlay() {
+				t.Errorf("no writable upper layer")
+			}
+		})
+	}
+}
+
+// featureDriver is an image driver providing the given features.
+type featureDriver struct {
+	stopDriver
+	features image.DriverFeature
+}
+
+func (d *featureDriver) Features() image.DriverFeature { return d.features }
+
+// setBinaryPath makes dir the only directory searched for executables.
+func setBinaryPath(t *testing.T, dir string) {
+	t.Setenv("PATH", dir)
+
+	current := apptainerconf.GetCurrentConfig()
+	file, err := apptainerconf.GetConfig(nil)
+	if err != nil {
+		t.Fatalf("failed to get default configuration: %s", err)
+	}
+	file.BinaryPath = dir
+	file.SuidBinaryPath = dir
+	apptainerconf.SetCurrentConfig(file)
+	t.Cleanup(func() { apptainerconf.SetCurrentConfig(current) })
+}
+
+func TestCheckSetuidExtfs(t *testing.T) {
+	withFuse2fs := t.TempDir()
+	if err := os.WriteFile(filepath.Join(withFuse2fs, "fuse2fs"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	withoutFuse2fs := t.TempDir()
+
+	tests := []struct {
+		name       string
+		elevated   bool
+		allowExtfs bool
+		driver     image.Driver
+		binaryPath string
+		wantErr    string
+	}{
+		{
+			name:       "Unprivileged",
+			binaryPath: withoutFuse2fs,
+		},
+		{
+			name:       "SetuidExtfsAllowed",
+			elevated:   true,
+			allowExtfs: true,
+			binaryPath: withoutFuse2fs,
+		},
+		{
+			name:       "Ext3ImageDriver",
+			elevated:   true,
+			driver:     &featureDriver{features: image.Ext3Feature},
+			binaryPath: withoutFuse2fs,
+		},
+		{
+			name:       "Fuse2fsMissing",
+			elevated:   true,
+			binaryPath: withoutFuse2fs,
+			wantErr:    "fuse2fs, required to mount it with FUSE instead, was not found",
+		},
+		{
+			name:       "ImageDriverWithoutExt3",
+			elevated:   true,
+			driver:     &featureDriver{features: image.SquashFeature},
+			binaryPath: withFuse2fs,
+			wantErr:    "image driver doesn't support extfs mounts",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setBinaryPath(t, tt.binaryPath)
+			imageDriver = tt.driver
+			t.Cleanup(func() { imageDriver = nil })
+
+			e := &EngineOperations{EngineConfig: apptainerConfig.NewConfig()}
+			e.EngineConfig.File.AllowSetuidMountExtfs = tt.allowExtfs
+
+			err := e.checkSetuidExtfs(tt.elevated, "extfs")
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrepareImageDriver(t *testing.T) {
+	tests := []struct {
+		name       string
+		driver     string
+		suid       bool
+		wantDriver string
+		wantErr    bool
+	}{
+		{
+			name:       "NoDriver",
+			suid:       true,
+			wantDriver: "squashfuse",
+		},
+		{
+			name:       "ConfiguredDriver",
+			driver:     "squashfuse",
+			suid:       true,
+			wantDriver: "squashfuse",
+		},
+		{
+			name:       "Unprivileged",
+			driver:     "other",
+			wantDriver: "other",
+		},
+		{
+			name:       "SetuidAllowed",
+			driver:     "fuseapps",
+			suid:       true,
+			wantDriver: "fuseapps",
+		},
+		{
+			name:    "SetuidDisallowed",
+			driver:  "other",
+			suid:    true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &EngineOperations{EngineConfig: apptainerConfig.NewConfig()}
+			e.EngineConfig.File.ImageDriver = "squashfuse"
+			e.EngineConfig.File.AllowedImageDrivers = []string{"fuseapps"}
+			e.EngineConfig.SetImageDriver(tt.driver)
+
+			err := e.prepareImageDriver(tt.suid)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("unexpected success")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := e.EngineConfig.File.ImageDriver; got != tt.wantDriver {
+				t.Errorf("got image driver %q, want %q", got, tt.wantDriver)
+			}
+		})
+	}
+}
+
+func TestPrepareSessionDir(t *testing.T) {
+	tests := []struct {
+		name       string
+		fsType     string
+		size       int
+		suid       bool
+		uid        int
+		wantFsType string
+		wantSize   int
+		wantErr    bool
+	}{
+		{
+			name:       "Default",
+			suid:       true,
+			uid:        1000,
+			wantFsType: "tmpfs",
+		},
+		{
+			name:       "UnprivilegedRamfs",
+			fsType:     "ramfs",
+			uid:        1000,
+			wantFsType: "ramfs",
+		},
+		{
+			name:    "SetuidRamfs",
+			fsType:  "ramfs",
+			suid:    true,
+			uid:     1000,
+			wantErr: true,
+		},
+		{
+			name:    "InvalidType",
+			fsType:  "ext4",
+			uid:     1000,
+			wantErr: true,
+		},
+		{
+			name:       "SetuidSizeClamped",
+			size:       256,
+			suid:       true,
+			uid:        1000,
+			wantFsType: "tmpfs",
+			wantSize:   64,
+		},
+		{
+			name:       "SetuidSizeBelowMax",
+			size:       32,
+			suid:       true,
+			uid:        1000,
+			wantFsType: "tmpfs",
+			wantSize:   32,
+		},
+		{
+			name:       "SetuidRootSize",
+			size:       256,
+			suid:       true,
+			uid:        0,
+			wantFsType: "tmpfs",
+			wantSize:   256,
+		},
+		{
+			name:       "UnprivilegedSize",
+			size:       256,
+			uid:        1000,
+			wantFsType: "tmpfs",
+			wantSize:   256,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &EngineOperations{EngineConfig: apptainerConfig.NewConfig()}
+			e.EngineConfig.File.MemoryFSType = "tmpfs"
+			e.EngineConfig.File.SessiondirMaxSize = 64
+			e.EngineConfig.SetSessionFsType(tt.fsType)
+			e.EngineConfig.SetSessionSize(tt.size)
+
+			err := e.prepareSessionDir(tt.suid, tt.uid)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("unexpected success")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := e.EngineConfig.File.MemoryFSType; got != tt.wantFsType {
+				t.Errorf("got session filesystem %q, want %q", got, tt.wantFsType)
+			}
+			if got := e.EngineConfig.GetSessionSize(); got != tt.wantSize {
+				t.Errorf("got session size %d, want %d", got, tt.wantSize)
+			}
+		})
+	}
+}
@@ -35,9 +35,11 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/instance"
 	"github.com/apptainer/apptainer/internal/pkg/plugin"
 	"github.com/apptainer/apptainer/internal/pkg/security"
+	"github.com/apptainer/apptainer/internal/pkg/security/seccomp"
 	"github.com/apptainer/apptainer/internal/pkg/util/env"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs/files"
 	"github.com/apptainer/apptainer/internal/pkg/util/machine"
+	"github.com/apptainer/apptainer/internal/pkg/util/sched"
 	"github.com/apptainer/apptainer/internal/pkg/util/shell"
 	"github.com/apptainer/apptainer/internal/pkg/util/shell/interpreter"
 	"github.com/apptainer/apptainer/internal/pkg/util/user"
@@ -49,11 +51,48 @@ import (
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
 	"golang.org/x/term"
+	"mvdan.cc/sh/v3/expand"
 	"mvdan.cc/sh/v3/interp"
 )
 
 const defaultShell = "/bin/sh"
 
+// setTerminalSize applies the window size recorded by the launcher to
+// the controlling terminal of the container process, so interactive
+// applications render correctly even when the terminal was reset or
+// reports an empty window size. As the container process shares the host
+// terminal and its foreground process group, resizes are then notified to
+// it by the kernel with SIGWINCH.
+func setTerminalSize(size *specs.Box) {
+	if size == nil || size.Width == 0 || size.Height == 0 {
+		return
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		sylog.Debugf("Could not open controlling terminal: %s", err)
+		return
+	}
+	defer tty.Close()
+	if err := applyTerminalSize(int(tty.Fd()), size); err != nil {
+		sylog.Debugf("Could not set terminal window size: %s", err)
+	}
+}
+
+// applyTerminalSize sets the window size of the terminal fd to size when
+// it differs from the current one.
+func applyTerminalSize(fd int, size *specs.Box) error {
+	ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return err
+	}
+	if uint(ws.Row) == size.Height && uint(ws.Col) == size.Width {
+		return nil
+	}
+	ws.Row = uint16(size.Height)
+	ws.Col = uint16(size.Width)
+	return unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, ws)
+}
+
 // StartProcess is called during stage2 after RPC server finished
 // environment preparation. This is the container process itself.
 //
@@ -85,6 +124,10 @@ func (e *EngineOperations) StartProcess(masterConnFd int) error {
 		return err
 	}
 
+	if e.EngineConfig.OciConfig.Process.Terminal {
+		setTerminalSize(e.EngineConfig.OciConfig.Process.ConsoleSize)
+	}
+
 	_, customCwd := e.EngineConfig.OciConfig.Annotations["CustomCwd"]
 
 	if err := os.Chdir(e.EngineConfig.OciConfig.Process.Cwd); err != nil {
@@ -133,7 +176,8 @@ func (e *EngineOperations) StartProcess(masterConnFd int) error {
 		namespaces := e.EngineConfig.OciConfig.Linux.Namespaces
 		for _, ns := range namespaces {
 			if ns.Type == specs.PIDNamespace {
-				if !e.EngineConfig.GetNoInit() {
+				// no shim process when joining the PID namespace of an instance
+				if !e.EngineConfig.GetNoInit() && ns.Path == "" {
 					shimProcess = true
 				}
 				break
@@ -170,6 +214,38 @@ func (e *EngineOperations) StartProcess(masterConnFd int) error {
 		}
 	}
 
+	// the scheduling settings are applied with the container process
+	// credentials, the kernel denies those requiring privileges
+	if policy := e.EngineConfig.GetSchedPolicy(); policy != "" {
+		p, err := sched.ParsePolicy(policy)
+		if err != nil {
+			return err
+		}
+		if err := sched.SetPolicy(p); err != nil {
+			return err
+		}
+	}
+	if nice := e.EngineConfig.GetNice(); nice != nil {
+		if err := sched.SetNice(*nice); err != nil {
+			return err
+		}
+	}
+
+	if params := security.GetParams(e.EngineConfig.GetSecurity(), "landlock"); len(params) > 0 {
+		if err := security.ConfigureLandlock(params); err != nil {
+			return fmt.Errorf("failed to apply landlock rules: %s", err)
+		}
+	}
+
+	// load the trace filter before the security configuration as
+	// a seccomp profile could deny the seccomp system call
+	if e.EngineConfig.GetTraceSyscalls() != "" {
+		_ = syscall.Close(e.EngineConfig.GetTraceSyscallsFd())
+		if err := seccomp.LoadTraceFilter(); err != nil {
+			return fmt.Errorf("failed to load system calls trace filter: %s", err)
+		}
+	}
+
 	if err := security.Configure(&e.EngineConfig.OciConfig.Spec); err != nil {
 		return fmt.Errorf("failed to apply security configuration: %s", err)
 	}
@@ -751,7 +827,8 @@ func sylogBuiltin(_ context.Context, argv []string) error {
 	return nil
 }
 
-// getAllEnvBuiltin display all exported variables in the form KEY=VALUE.
+// getAllEnvBuiltin display all exported variables in the form KEY=$'VALUE',
+// where VALUE is escaped to be restored exactly by the action script.
 func getAllEnvBuiltin() interpreter.ShellBuiltin {
 	return func(ctx context.Context, _ []string) error {
 		hc := interp.HandlerCtx(ctx)
@@ -767,17 +844,12 @@ func getAllEnvBuiltin() interpreter.ShellBuiltin {
 				sylog.Debugf("Not exporting %q to container environment: invalid key", key)
 				continue
 			}
-			// Because we are using IFS=\n we need to escape newlines here and
-			// unescape them in the action script when we export the var again.
-			//
-			// This is imperfect - it is not possible to represent a string
-			// containing a literal '\u000A' (unicode escaped newline) in it.
-			//
-			// Full escaping / unescaping requires iterative parsing of the
-			// string in the action script. This is too awkward and slow in
-			// shell code. If we can use `printf -v VAR "%b" ...` from mvdan.cc/sh
-			// in future, we may be able to revisit this.
-			env := strings.Replace(env, "\n", "\\u000A", -1)
+			// Because we are using IFS=\n we need to escape newlines here.
+			// Values are ANSI-C quoted, so newlines, quotes and any other
+			// special characters are restored exactly when the action
+			// script evaluates the export again.
+			value := strings.SplitN(env, "=", 2)[1]
+			env = key + "=$'" + shell.EscapeANSIC(value) + "'"
 			fmt.Fprintf(hc.Stdout, "%s\n", env)
 		}
 		return nil
@@ -849,6 +921,9 @@ func getEnvVal(env []string, envname string) string {
 func runActionScript(engineConfig *apptainerConfig.EngineConfig) ([]string, []string, error) {
 	args := engineConfig.OciConfig.Process.Args
 	penv := append(engineConfig.OciConfig.Process.Env, "APPTAINER_COMMAND="+filepath.Base(args[0]))
+	if entrypoint := engineConfig.GetEntrypoint(); entrypoint != "" {
+		penv = append(penv, "APPTAINER_ENTRYPOINT="+entrypoint)
+	}
 	var execCtx context.Context
 	if timeoutVal := engineConfig.GetRunscriptTimeout(); timeoutVal != "" {
 		timeoutDur, err := time.ParseDuration(timeoutVal)
@@ -935,6 +1010,15 @@ func runActionScript(engineConfig *apptainerConfig.EngineConfig) ([]string, []st
 		}
 	}
 
+	if wrap := engineConfig.GetWrap(); len(wrap) > 0 && len(args) > 0 {
+		cmd, err := interp.LookPath(expand.ListEnviron(penv...), wrap[0])
+		if err != nil {
+			return nil, nil, fmt.Errorf("wrapper command %s not found in container: %s", wrap[0], err)
+		}
+		args = append(append([]string{cmd}, wrap[1:]...), args...)
+		sylog.Debugf("Wrapped container process %+q", args)
+	}
+
 	fakeargs := fakeroot.GetFakeArgs()
 	fakerootPath := fakeargs[0]
 	_, err = os.Stat(fakerootPath)
@@ -0,0 +1,55 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"testing"
+
+	"github.com/creack/pty"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+func TestApplyTerminalSize(t *testing.T) {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		t.Skipf("could not open pseudo terminal: %s", err)
+	}
+	defer ptmx.Close()
+	defer tty.Close()
+
+	fd := int(tty.Fd())
+	if err := unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, &unix.Winsize{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := applyTerminalSize(fd, &specs.Box{Width: 120, Height: 40}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ws.Col != 120 || ws.Row != 40 {
+		t.Errorf("got window size %dx%d, want 120x40", ws.Col, ws.Row)
+	}
+
+	// the size seen from the master side is the same
+	ws, err = unix.IoctlGetWinsize(int(ptmx.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ws.Col != 120 || ws.Row != 40 {
+		t.Errorf("got master window size %dx%d, want 120x40", ws.Col, ws.Row)
+	}
+
+	if err := applyTerminalSize(int(ptmx.Fd())+1000, &specs.Box{Width: 80, Height: 24}); err == nil {
+		t.Errorf("unexpected success with an invalid file descriptor")
+	}
+}
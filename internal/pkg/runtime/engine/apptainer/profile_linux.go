@@ -0,0 +1,168 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/apptainer/rpc/client"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs/mount"
+	"github.com/apptainer/apptainer/pkg/sylog"
+)
+
+// profilePhase is a container startup phase recorded by startupProfile.
+type profilePhase struct {
+	Name string `json:"name"`
+	// Start is the phase start in microseconds since the profile start.
+	Start int64 `json:"start_us"`
+	// Duration is the phase duration in microseconds.
+	Duration int64 `json:"duration_us"`
+	// RPCCalls is the number of RPC calls performed during the phase.
+	RPCCalls uint64 `json:"rpc_calls"`
+}
+
+// startupProfile records the timeline of the container startup phases
+// written as JSON to a file once the container is created. A nil profile
+// records nothing.
+type startupProfile struct {
+	start  time.Time
+	rpcOps *client.RPC
+	phases []profilePhase
+}
+
+// newStartupProfile returns a startup profile counting the RPC calls
+// performed with rpcOps.
+func newStartupProfile(rpcOps *client.RPC) *startupProfile {
+	return &startupProfile{
+		start:  time.Now(),
+		rpcOps: rpcOps,
+		phases: make([]profilePhase, 0),
+	}
+}
+
+// rpcCalls returns the total number of RPC calls performed so far.
+func (p *startupProfile) rpcCalls() uint64 {
+	total := uint64(0)
+	for _, n := range p.rpcOps.Calls() {
+		total += n
+	}
+	return total
+}
+
+// record appends the phase name started at start, calls is the number of
+// RPC calls performed before the phase start.
+func (p *startupProfile) record(name string, start time.Time, calls uint64) {
+	p.phases = append(p.phases, profilePhase{
+		Name:     name,
+		Start:    start.Sub(p.start).Microseconds(),
+		Duration: time.Since(start).Microseconds(),
+		RPCCalls: p.rpcCalls() - calls,
+	})
+}
+
+// begin starts the phase name and returns the function ending it.
+func (p *startupProfile) begin(name string) func() {
+	if p == nil {
+		return func() {}
+	}
+	start := time.Now()
+	calls := p.rpcCalls()
+
+	return func() {
+		p.record(name, start, calls)
+	}
+}
+
+// addMountHooks records a phase for each mount tag processed by MountAll.
+// A tag phase ends once the after hook functions of the tag are executed
+// and the next tag phase starts there, so it must be called once all the
+// other hook functions are registered, right before MountAll. Tags without
+// mount points and RPC calls are not recorded.
+func (p *startupProfile) addMountHooks(system *mount.System) error {
+	if p == nil {
+		return nil
+	}
+	start := time.Now()
+	calls := p.rpcCalls()
+
+	for _, tag := range mount.GetTagList() {
+		name := "mount " + string(tag)
+		after := func(system *mount.System) error {
+			if len(system.Points.GetByTag(tag)) > 0 || p.rpcCalls() != calls {
+				p.record(name, start, calls)
+			}
+			start = time.Now()
+			calls = p.rpcCalls()
+			return nil
+		}
+		if err := system.RunAfterTag(tag, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes the profile timeline to path along with the number of
+// calls performed for each RPC method.
+func (p *startupProfile) write(path string) error {
+	if p == nil {
+		return nil
+	}
+	timeline := struct {
+		Start    time.Time         `json:"start"`
+		Total    int64             `json:"total_us"`
+		Phases   []profilePhase    `json:"phases"`
+		RPCCalls map[string]uint64 `json:"rpc_calls"`
+	}{
+		Start:    p.start,
+		Total:    time.Since(p.start).Microseconds(),
+		Phases:   p.phases,
+		RPCCalls: p.rpcOps.Calls(),
+	}
+
+	b, err := json.MarshalIndent(timeline, "", "  ")
+	if err != nil {
+		return fmt.Errorf("while encoding startup profile: %s", err)
+	}
+	b = append(b, '\n')
+
+	if err := writeProfileFile(path, b); err != nil {
+		return fmt.Errorf("while writing startup profile to %s: %s", path, err)
+	}
+	sylog.Verbosef("Container startup profile written to %s", path)
+	return nil
+}
+
+// writeProfileFile writes b to a temporary file in the directory of path
+// which is then renamed to path, so a symlink planted at path, which may
+// be predictable, is replaced instead of being followed.
+func writeProfileFile(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,194 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"encoding/json"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+
+	args "github.com/apptainer/apptainer/internal/pkg/runtime/engine/apptainer/rpc"
+	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/apptainer/rpc/client"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs/mount"
+)
+
+// profileMethods implements the chdir RPC method only.
+type profileMethods int
+
+func (t *profileMethods) Chdir(*args.ChdirArgs, *int) error {
+	return nil
+}
+
+// newProfileRPC returns a RPC client connected to profileMethods.
+func newProfileRPC(t *testing.T) *client.RPC {
+	server := rpc.NewServer()
+	if err := server.RegisterName("profile", new(profileMethods)); err != nil {
+		t.Fatalf("failed to register RPC methods: %s", err)
+	}
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+
+	rpcOps := &client.RPC{Client: rpc.NewClient(clientConn), Name: "profile"}
+	t.Cleanup(func() { rpcOps.Client.Close() })
+	return rpcOps
+}
+
+// profileTimeline is the JSON profile written by startupProfile.
+type profileTimeline struct {
+	Total    int64             `json:"total_us"`
+	Phases   []profilePhase    `json:"phases"`
+	RPCCalls map[string]uint64 `json:"rpc_calls"`
+}
+
+func readProfile(t *testing.T, path string) profileTimeline {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read profile: %s", err)
+	}
+	var timeline profileTimeline
+	if err := json.Unmarshal(b, &timeline); err != nil {
+		t.Fatalf("failed to decode profile: %s", err)
+	}
+	return timeline
+}
+
+func TestStartupProfileNil(t *testing.T) {
+	var p *startupProfile
+
+	p.begin("phase")()
+	if err := p.addMountHooks(&mount.System{Points: &mount.Points{}}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "profile.json")
+	if err := p.write(path); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("profile written by a nil profile")
+	}
+}
+
+func TestStartupProfilePhases(t *testing.T) {
+	rpcOps := newProfileRPC(t)
+	p := newStartupProfile(rpcOps)
+
+	end := p.begin("first")
+	for i := 0; i < 2; i++ {
+		if _, err := rpcOps.Chdir("/"); err != nil {
+			t.Fatalf("chdir RPC failed: %s", err)
+		}
+	}
+	end()
+	p.begin("second")()
+
+	path := filepath.Join(t.TempDir(), "profile.json")
+	if err := p.write(path); err != nil {
+		t.Fatalf("failed to write profile: %s", err)
+	}
+	timeline := readProfile(t, path)
+
+	if len(timeline.Phases) != 2 {
+		t.Fatalf("got %d phases, want 2", len(timeline.Phases))
+	}
+	first, second := timeline.Phases[0], timeline.Phases[1]
+	if first.Name != "first" || first.RPCCalls != 2 {
+		t.Errorf("unexpected first phase %+v", first)
+	}
+	if second.Name != "second" || second.RPCCalls != 0 {
+		t.Errorf("unexpected second phase %+v", second)
+	}
+	if second.Start < first.Start+first.Duration {
+		t.Errorf("second phase starts before the end of the first one")
+	}
+	if timeline.Total < second.Start+second.Duration {
+		t.Errorf("total %d shorter than the phases", timeline.Total)
+	}
+	if want := map[string]uint64{"Chdir": 2}; !reflect.DeepEqual(timeline.RPCCalls, want) {
+		t.Errorf("got RPC calls %v, want %v", timeline.RPCCalls, want)
+	}
+}
+
+func TestStartupProfileMountHooks(t *testing.T) {
+	rpcOps := newProfileRPC(t)
+	p := newStartupProfile(rpcOps)
+
+	points := &mount.Points{}
+	if err := points.AddBind(mount.BindsTag, "/etc/hosts", "/etc/hosts", syscall.MS_BIND); err != nil {
+		t.Fatalf("failed to add bind: %s", err)
+	}
+	system := &mount.System{
+		Points: points,
+		Mount: func(*mount.Point, *mount.System) error {
+			_, err := rpcOps.Chdir("/")
+			return err
+		},
+	}
+	if err := p.addMountHooks(system); err != nil {
+		t.Fatalf("failed to add mount hooks: %s", err)
+	}
+	if err := system.MountAll(); err != nil {
+		t.Fatalf("failed to mount: %s", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "profile.json")
+	if err := p.write(path); err != nil {
+		t.Fatalf("failed to write profile: %s", err)
+	}
+	timeline := readProfile(t, path)
+
+	// tags without mount points and RPC calls are not recorded
+	if len(timeline.Phases) != 1 {
+		t.Fatalf("got phases %+v, want only the binds tag", timeline.Phases)
+	}
+	if phase := timeline.Phases[0]; phase.Name != "mount "+string(mount.BindsTag) || phase.RPCCalls != 1 {
+		t.Errorf("unexpected phase %+v", phase)
+	}
+}
+
+func TestStartupProfileSymlink(t *testing.T) {
+	p := newStartupProfile(newProfileRPC(t))
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := os.WriteFile(target, []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "profile.json")
+	if err := os.Symlink(target, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.write(path); err != nil {
+		t.Fatalf("failed to write profile: %s", err)
+	}
+	if b, err := os.ReadFile(target); err != nil || string(b) != "content" {
+		t.Errorf("symlink target overwritten: %q %v", b, err)
+	}
+	fi, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.Mode().IsRegular() || fi.Mode().Perm() != 0o644 {
+		t.Errorf("profile written as %v", fi.Mode())
+	}
+	readProfile(t, path)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("temporary profile file left in %s: %v", dir, entries)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"io/fs"
 	"net/rpc"
 	"os"
+	"sync"
 
 	args "github.com/apptainer/apptainer/internal/pkg/runtime/engine/apptainer/rpc"
 	"golang.org/x/sys/unix"
@@ -22,6 +23,33 @@ import (
 type RPC struct {
 	Client *rpc.Client
 	Name   string
+
+	callsMutex sync.Mutex
+	calls      map[string]uint64
+}
+
+// call calls the named method of the RPC server and counts the call.
+func (t *RPC) call(method string, arguments any, reply any) error {
+	t.callsMutex.Lock()
+	if t.calls == nil {
+		t.calls = make(map[string]uint64)
+	}
+	t.calls[method]++
+	t.callsMutex.Unlock()
+
+	return t.Client.Call(t.Name+"."+method, arguments, reply)
+}
+
+// Calls returns the number of calls performed for each RPC method.
+func (t *RPC) Calls() map[string]uint64 {
+	t.callsMutex.Lock()
+	defer t.callsMutex.Unlock()
+
+	calls := make(map[string]uint64, len(t.calls))
+	for method, n := range t.calls {
+		calls[method] = n
+	}
+	return calls
 }
 
 // Mount calls the mount RPC using the supplied arguments.
@@ -36,7 +64,7 @@ func (t *RPC) Mount(source string, target string, filesystem string, flags uintp
 
 	var mountErr error
 
-	err := t.Client.Call(t.Name+".Mount", arguments, &mountErr)
+	err := t.call("Mount", arguments, &mountErr)
 	// RPC communication will take precedence over mount error
 	if err == nil {
 		err = mountErr
@@ -54,7 +82,7 @@ func (t *RPC) Unmount(target string, flags int) error {
 
 	var unmountErr error
 
-	err := t.Client.Call(t.Name+".Unmount", arguments, &unmountErr)
+	err := t.call("Unmount", arguments, &unmountErr)
 	// RPC communication will take precedence over unmount error
 	if err == nil {
 		err = unmountErr
@@ -73,7 +101,7 @@ func (t *RPC) Decrypt(offset uint64, path string, key []byte, masterPid int) (st
 	}
 
 	var reply string
-	err := t.Client.Call(t.Name+".Decrypt", arguments, &reply)
+	err := t.call("Decrypt", arguments, &reply)
 
 	return reply, err
 }
@@ -84,7 +112,7 @@ func (t *RPC) Mkdir(path string, perm os.FileMode) error {
 		Path: path,
 		Perm: perm,
 	}
-	return t.Client.Call(t.Name+".Mkdir", arguments, nil)
+	return t.call("Mkdir", arguments, nil)
 }
 
 // Chroot calls the chroot RPC using the supplied arguments.
@@ -94,7 +122,7 @@ func (t *RPC) Chroot(root string, method string) (int, error) {
 		Method: method,
 	}
 	var reply int
-	err := t.Client.Call(t.Name+".Chroot", arguments, &reply)
+	err := t.call("Chroot", arguments, &reply)
 	return reply, err
 }
 
@@ -108,7 +136,7 @@ func (t *RPC) LoopDevice(image string, mode int, info unix.LoopInfo64, maxDevice
 		Shared:     shared,
 	}
 	var reply int
-	err := t.Client.Call(t.Name+".LoopDevice", arguments, &reply)
+	err := t.call("LoopDevice", arguments, &reply)
 	return reply, err
 }
 
@@ -118,7 +146,7 @@ func (t *RPC) SetHostname(hostname string) (int, error) {
 		Hostname: hostname,
 	}
 	var reply int
-	err := t.Client.Call(t.Name+".SetHostname", arguments, &reply)
+	err := t.call("SetHostname", arguments, &reply)
 	return reply, err
 }
 
@@ -128,7 +156,7 @@ func (t *RPC) Chdir(dir string) (int, error) {
 		Dir: dir,
 	}
 	var reply int
-	err := t.Client.Call(t.Name+".Chdir", arguments, &reply)
+	err := t.call("Chdir", arguments, &reply)
 	return reply, err
 }
 
@@ -138,7 +166,7 @@ func (t *RPC) Stat(path string) (os.FileInfo, error) {
 		Path: path,
 	}
 	var reply args.StatReply
-	err := t.Client.Call(t.Name+".Stat", arguments, &reply)
+	err := t.call("Stat", arguments, &reply)
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +179,7 @@ func (t *RPC) Lstat(path string) (os.FileInfo, error) {
 		Path: path,
 	}
 	var reply args.StatReply
-	err := t.Client.Call(t.Name+".Lstat", arguments, &reply)
+	err := t.call("Lstat", arguments, &reply)
 	if err != nil {
 		return nil, err
 	}
@@ -165,7 +193,7 @@ func (t *RPC) Access(path string, mode uint32) error {
 		Mode: mode,
 	}
 	var reply args.AccessReply
-	err := t.Client.Call(t.Name+".Access", arguments, &reply)
+	err := t.call("Access", arguments, &reply)
 	if err != nil {
 		return err
 	}
@@ -179,7 +207,7 @@ func (t *RPC) SendFuseFd(socket int, fds []int) error {
 		Fds:    fds,
 	}
 	var reply int
-	err := t.Client.Call(t.Name+".SendFuseFd", arguments, &reply)
+	err := t.call("SendFuseFd", arguments, &reply)
 	return err
 }
 
@@ -189,7 +217,7 @@ func (t *RPC) OpenSendFuseFd(socket int) (int, error) {
 		Socket: socket,
 	}
 	var reply int
-	err := t.Client.Call(t.Name+".OpenSendFuseFd", arguments, &reply)
+	err := t.call("OpenSendFuseFd", arguments, &reply)
 	return reply, err
 }
 
@@ -199,7 +227,7 @@ func (t *RPC) Symlink(old string, new string) error {
 		Old: old,
 		New: new,
 	}
-	return t.Client.Call(t.Name+".Symlink", arguments, nil)
+	return t.call("Symlink", arguments, nil)
 }
 
 // ReadDir calls the readdir RPC using the supplied arguments.
@@ -208,7 +236,7 @@ func (t *RPC) ReadDir(dir string) ([]fs.DirEntry, error) {
 		Dir: dir,
 	}
 	var reply args.ReadDirReply
-	err := t.Client.Call(t.Name+".ReadDir", arguments, &reply)
+	err := t.call("ReadDir", arguments, &reply)
 	return reply.Files, err
 }
 
@@ -219,7 +247,7 @@ func (t *RPC) Chown(name string, uid int, gid int) error {
 		UID:  uid,
 		GID:  gid,
 	}
-	return t.Client.Call(t.Name+".Chown", arguments, nil)
+	return t.call("Chown", arguments, nil)
 }
 
 // Lchown calls the lchown RPC using the supplied arguments.
@@ -229,7 +257,7 @@ func (t *RPC) Lchown(name string, uid int, gid int) error {
 		UID:  uid,
 		GID:  gid,
 	}
-	return t.Client.Call(t.Name+".Lchown", arguments, nil)
+	return t.call("Lchown", arguments, nil)
 }
 
 // EvalRelative calls the evalrelative RPC using the supplied arguments.
@@ -239,7 +267,7 @@ func (t *RPC) EvalRelative(name string, root string) string {
 		Root: root,
 	}
 	var reply string
-	t.Client.Call(t.Name+".EvalRelative", arguments, &reply)
+	t.call("EvalRelative", arguments, &reply)
 	return reply
 }
 
@@ -249,7 +277,7 @@ func (t *RPC) Readlink(name string) (string, error) {
 		Name: name,
 	}
 	var reply string
-	err := t.Client.Call(t.Name+".Readlink", arguments, &reply)
+	err := t.call("Readlink", arguments, &reply)
 	return reply, err
 }
 
@@ -259,7 +287,7 @@ func (t *RPC) Umask(mask int) int {
 		Mask: mask,
 	}
 	var reply int
-	t.Client.Call(t.Name+".Umask", arguments, &reply)
+	t.call("Umask", arguments, &reply)
 	return reply
 }
 
@@ -270,7 +298,7 @@ func (t *RPC) WriteFile(filename string, data []byte, perm os.FileMode) error {
 		Data:     data,
 		Perm:     perm,
 	}
-	return t.Client.Call(t.Name+".WriteFile", arguments, nil)
+	return t.call("WriteFile", arguments, nil)
 }
 
 // NvCCLI will call nvidia-container-cli to configure GPU(s) for the container.
@@ -280,5 +308,5 @@ func (t *RPC) NvCCLI(flags []string, rootFsPath string, userNS bool) error {
 		RootFsPath: rootFsPath,
 		UserNS:     userNS,
 	}
-	return t.Client.Call(t.Name+".NvCCLI", arguments, nil)
+	return t.call("NvCCLI", arguments, nil)
 }
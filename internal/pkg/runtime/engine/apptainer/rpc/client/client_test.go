@@ -0,0 +1,74 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package client
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"reflect"
+	"testing"
+
+	args "github.com/apptainer/apptainer/internal/pkg/runtime/engine/apptainer/rpc"
+)
+
+// testMethods implements a subset of the RPC server methods.
+type testMethods int
+
+func (t *testMethods) Chdir(arguments *args.ChdirArgs, _ *int) error {
+	if arguments.Dir == "/fail" {
+		return errors.New("chdir failure")
+	}
+	return nil
+}
+
+func (t *testMethods) Mkdir(_ *args.MkdirArgs, _ *int) error {
+	return nil
+}
+
+func TestCalls(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("test", new(testMethods)); err != nil {
+		t.Fatalf("failed to register RPC methods: %s", err)
+	}
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+
+	rpcOps := &RPC{Client: rpc.NewClient(clientConn), Name: "test"}
+	defer rpcOps.Client.Close()
+
+	if calls := rpcOps.Calls(); len(calls) != 0 {
+		t.Errorf("unexpected calls before any call: %v", calls)
+	}
+
+	for _, dir := range []string{"/", "/tmp", "/fail"} {
+		_, err := rpcOps.Chdir(dir)
+		if dir == "/fail" && err == nil {
+			t.Errorf("unexpected success for chdir %s", dir)
+		} else if dir != "/fail" && err != nil {
+			t.Errorf("unexpected error for chdir %s: %s", dir, err)
+		}
+	}
+	if err := rpcOps.Mkdir("/tmp/dir", 0o755); err != nil {
+		t.Errorf("unexpected error for mkdir: %s", err)
+	}
+
+	// failed calls are counted too
+	want := map[string]uint64{"Chdir": 3, "Mkdir": 1}
+	calls := rpcOps.Calls()
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+
+	// the returned map is a copy
+	calls["Chdir"] = 0
+	if n := rpcOps.Calls()["Chdir"]; n != 3 {
+		t.Errorf("calls modified through the returned map: %d", n)
+	}
+}
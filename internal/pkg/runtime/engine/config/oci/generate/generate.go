@@ -184,6 +184,15 @@ func (g *Generator) SetProcessTerminal(b bool) {
 	g.Config.Process.Terminal = b
 }
 
+// SetProcessConsoleSize sets container process terminal window size.
+func (g *Generator) SetProcessConsoleSize(width, height uint) {
+	g.initProcess()
+	g.Config.Process.ConsoleSize = &specs.Box{
+		Width:  width,
+		Height: height,
+	}
+}
+
 // SetRootPath sets container root filesystem path.
 func (g *Generator) SetRootPath(path string) {
 	g.initRoot()
@@ -53,6 +53,11 @@ func TestGenerate(t *testing.T) {
 		t.Fatalf("wrong OCI process terminal: %v instead of %v", config.Process.Terminal, terminal)
 	}
 
+	g.SetProcessConsoleSize(80, 24)
+	if config.Process.ConsoleSize == nil || config.Process.ConsoleSize.Width != 80 || config.Process.ConsoleSize.Height != 24 {
+		t.Fatalf("wrong OCI process console size: %v instead of 80x24", config.Process.ConsoleSize)
+	}
+
 	noNewPriv := true
 	g.SetProcessNoNewPrivileges(noNewPriv)
 	if config.Process.NoNewPrivileges != noNewPriv {
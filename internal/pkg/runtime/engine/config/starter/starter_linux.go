@@ -172,6 +172,16 @@ func (c *Config) SetNvCCLICaps(enabled bool) {
 	}
 }
 
+// SetDryRun sets the flag to tell starter to stop once stage 1
+// prepared the configuration, without starting the container.
+func (c *Config) SetDryRun(dryRun bool) {
+	if dryRun {
+		c.config.starter.dryRun = C.true
+	} else {
+		c.config.starter.dryRun = C.false
+	}
+}
+
 // SetHybridWorkflow sets the flag to tell starter container setup
 // will require a hybrid workflow. Typically used for fakeroot.
 // In a hybrid workflow, the master process lives in host user namespace
@@ -16,11 +16,14 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
+	apptainerapp "github.com/apptainer/apptainer/internal/app/apptainer"
 	"github.com/apptainer/apptainer/internal/pkg/buildcfg"
 	"github.com/apptainer/apptainer/internal/pkg/cgroups"
 	"github.com/apptainer/apptainer/internal/pkg/checkpoint/dmtcp"
@@ -29,12 +32,14 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/image/unpacker"
 	"github.com/apptainer/apptainer/internal/pkg/instance"
 	"github.com/apptainer/apptainer/internal/pkg/plugin"
+	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/apptainer"
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci"
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci/generate"
 	"github.com/apptainer/apptainer/internal/pkg/security"
 	"github.com/apptainer/apptainer/internal/pkg/util/bin"
 	"github.com/apptainer/apptainer/internal/pkg/util/env"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs/mount"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs/squashfs"
 	"github.com/apptainer/apptainer/internal/pkg/util/gpu"
 	"github.com/apptainer/apptainer/internal/pkg/util/starter"
@@ -53,12 +58,13 @@ import (
 	"github.com/apptainer/apptainer/pkg/util/fs/proc"
 	"github.com/apptainer/apptainer/pkg/util/namespaces"
 	"github.com/apptainer/apptainer/pkg/util/rlimit"
+	units "github.com/docker/go-units"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
 )
 
 func NewLauncher(opts ...Option) (*Launcher, error) {
-	lo := launchOptions{}
+	lo := launchOptions{NetworkRetries: -1}
 	for _, opt := range opts {
 		if err := opt(&lo); err != nil {
 			return nil, fmt.Errorf("%w", err)
@@ -96,6 +102,15 @@ func NewLauncher(opts ...Option) (*Launcher, error) {
 func (l *Launcher) Exec(ctx context.Context, image string, args []string, instanceName string) error {
 	var err error
 
+	if err := checkTmpDir(l.cfg.TmpDir); err != nil {
+		return err
+	}
+
+	// Fail early if the hostname can't be set.
+	if err := l.setHostname(); err != nil {
+		return err
+	}
+
 	var fakerootPath string
 	if l.cfg.Fakeroot {
 		if (l.uid == 0) && namespaces.IsUnprivileged() {
@@ -161,6 +176,20 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 		}
 	}
 
+	if l.cfg.KeepID {
+		if l.cfg.Fakeroot {
+			sylog.Fatalf("--keep-id and --fakeroot are mutually exclusive")
+		}
+		if l.uid == 0 {
+			sylog.Warningf("--keep-id has no effect when running as root, ignoring")
+			l.cfg.KeepID = false
+		} else if !fakeroot.IsUIDMapped(l.uid) || l.cfg.IgnoreSubuid {
+			sylog.Fatalf("--keep-id requires an entry for your user in %v", fakeroot.SubUIDFile)
+		} else if l.cfg.IgnoreUserns {
+			sylog.Fatalf("--keep-id requires a user namespace, but --ignore-userns is set")
+		}
+	}
+
 	// Set arguments to pass to contained process.
 	l.generator.SetProcessArgs(args)
 
@@ -195,7 +224,51 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 
 	// Overlay or writable image requested?
 	l.engineConfig.SetOverlayImage(l.cfg.OverlayPaths)
+	l.engineConfig.SetOverlayVerify(l.cfg.OverlayVerify)
 	l.engineConfig.SetWritableImage(l.cfg.Writable)
+	if _, err := l.tempOverlaySize(); err != nil {
+		return err
+	}
+	if l.cfg.OverlayWarnFree != "" {
+		size, err := units.RAMInBytes(l.cfg.OverlayWarnFree)
+		if err != nil || size < 0 {
+			return fmt.Errorf("invalid --overlay-warn-free %s: must be a size like 100M or 1G", l.cfg.OverlayWarnFree)
+		}
+		l.engineConfig.SetOverlayWarnFree(uint64(size))
+	}
+
+	// Session directory filesystem type or size requested?
+	if l.cfg.SessionFs != "" && l.cfg.SessionFs != "tmpfs" && l.cfg.SessionFs != "ramfs" {
+		return fmt.Errorf("invalid --session-fs %s: must be tmpfs or ramfs", l.cfg.SessionFs)
+	}
+	l.engineConfig.SetSessionFsType(l.cfg.SessionFs)
+	if l.cfg.SessionSize != "" {
+		size, err := units.RAMInBytes(l.cfg.SessionSize)
+		if err != nil || size < units.MiB {
+			return fmt.Errorf("invalid --session-size %s: must be a size of at least 1M like 256M or 1G", l.cfg.SessionSize)
+		}
+		l.engineConfig.SetSessionSize(int(size / units.MiB))
+	}
+
+	// Access time mount options for ext3 and sandbox images?
+	if _, err := mount.ImageMountFlags(l.cfg.ImageMountOpts); err != nil {
+		sylog.Fatalf("While checking --image-mount-opts: %s", err)
+	}
+	l.engineConfig.SetImageMountOpts(l.cfg.ImageMountOpts)
+
+	// Mount options for squashfs rootfs and data images?
+	privileged := !l.cfg.Namespaces.User && !insideUserNs
+	if _, err := mount.SquashfsMountOptions(l.cfg.SquashfsMountOpts, privileged); err != nil {
+		sylog.Fatalf("While checking --image-mount-opt: %s", err)
+	}
+	l.engineConfig.SetSquashfsMountOpts(l.cfg.SquashfsMountOpts)
+
+	// Kernel overlay features for the container overlay?
+	if _, err := mount.OverlayMountOptions(l.cfg.OverlayOpts); err != nil {
+		sylog.Fatalf("While checking --overlay-opts: %s", err)
+	}
+	l.engineConfig.SetOverlayOpts(l.cfg.OverlayOpts)
+	l.engineConfig.SetImageDriver(l.cfg.ImageDriver)
 
 	// Prefer underlay for bind
 	l.engineConfig.SetUnderlay(l.cfg.Underlay)
@@ -204,7 +277,10 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 	// If we are joining an instance, then any encrypted image is already mounted.
 	if !l.engineConfig.GetInstanceJoin() {
 		err = l.checkEncryptionKey()
-		if err != nil {
+		var keyErr *EncryptionKeyError
+		if errors.As(err, &keyErr) && keyErr.Status == NotEncrypted {
+			sylog.Verbosef("Ignoring encryption key material: %s", err)
+		} else if err != nil {
 			sylog.Fatalf("While checking container encryption: %s", err)
 		}
 	}
@@ -235,6 +311,39 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 	l.engineConfig.SetNoHome(l.cfg.NoHome)
 	// Allow user to disable binds via --no-mount.
 	l.setNoMountFlags()
+	l.engineConfig.SetCwdReadOnly(l.cfg.CwdReadOnly)
+	l.engineConfig.SetNoTTYConsole(l.cfg.NoTTYConsole)
+	l.engineConfig.SetBindCgroupfs(l.cfg.BindCgroupfs)
+	l.engineConfig.SetNoAutofsWorkaround(l.cfg.NoAutofsWorkaround)
+	l.engineConfig.SetDumpOciSpec(l.cfg.DumpOciSpec)
+	// Allow user to write the container mount table for debugging purpose.
+	// The variable holds the path of the JSON file, not a boolean, a relative
+	// path is resolved from the current working directory so
+	// APPTAINER_DUMP_MOUNTS=1 writes the mount table to a file named 1.
+	if path := os.Getenv("APPTAINER_DUMP_MOUNTS"); path != "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("while resolving mount table path %s: %s", path, err)
+		}
+		l.engineConfig.SetDumpMounts(abs)
+	}
+	// Allow user to record the container startup timeline.
+	if os.Getenv("APPTAINER_PROFILE") == "1" {
+		path := os.Getenv("APPTAINER_PROFILE_FILE")
+		if path == "" {
+			path = filepath.Join(l.tmpDir(), fmt.Sprintf("apptainer-profile-%d.json", os.Getpid()))
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("while resolving startup profile path %s: %s", path, err)
+		}
+		sylog.Infof("Container startup profile will be written to %s", abs)
+		l.engineConfig.SetProfile(abs)
+	}
+	l.engineConfig.SetDryRun(l.cfg.DryRun || l.cfg.PrintCaps)
+	l.engineConfig.SetPrintCaps(l.cfg.PrintCaps)
+	l.engineConfig.SetLoopMode(l.cfg.LoopMode)
+	l.engineConfig.SetOverlayPrecedence(l.cfg.OverlayPrecedence)
 
 	// GPU configuration may add library bind to /.singularity.d/libs.
 	// Note: --nvccli may implicitly add --writable-tmpfs, so handle that *after* GPUs.
@@ -258,6 +367,13 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 	l.engineConfig.AppendLibrariesPath(l.cfg.ContainLibs...)
 
 	// Additional directory overrides.
+	for _, sd := range l.cfg.ScratchDirs {
+		for _, spec := range strings.Split(sd, ",") {
+			if _, err := apptainerConfig.ParseScratchDir(spec); err != nil {
+				return fmt.Errorf("invalid --scratch %s: %w", spec, err)
+			}
+		}
+	}
 	l.engineConfig.SetScratchDir(l.cfg.ScratchDirs)
 	l.engineConfig.SetWorkdir(l.cfg.WorkDir)
 	l.engineConfig.SetConfigDir(syfs.ConfigDir())
@@ -265,17 +381,16 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 	// Container networking configuration.
 	l.engineConfig.SetNetwork(l.cfg.Network)
 	l.engineConfig.SetDNS(l.cfg.DNS)
+	l.engineConfig.SetDNSSearch(l.cfg.DNSSearch)
+	l.engineConfig.SetDNSOptions(l.cfg.DNSOptions)
+	l.engineConfig.SetNsswitch(l.cfg.Nsswitch)
 	l.engineConfig.SetNetworkArgs(l.cfg.NetworkArgs)
-
-	// If user wants to set a hostname, it requires the UTS namespace.
-	if l.cfg.Hostname != "" {
-		l.cfg.Namespaces.UTS = true
-		l.engineConfig.SetHostname(l.cfg.Hostname)
-	}
+	l.engineConfig.SetNetworkRetries(l.cfg.NetworkRetries)
 
 	// Set requested capabilities (effective for root, or if sysadmin has permitted to another user).
 	l.engineConfig.SetAddCaps(l.cfg.AddCaps)
 	l.engineConfig.SetDropCaps(l.cfg.DropCaps)
+	l.engineConfig.SetFakerootCaps(l.cfg.FakerootCaps)
 
 	// Custom --config file (only effective in non-setuid or as root).
 	l.engineConfig.SetConfigurationFile(l.cfg.ConfigFile)
@@ -306,6 +421,21 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 	// Set engine --security options (selinux, apparmor, seccomp functionality).
 	l.engineConfig.SetSecurity(l.cfg.SecurityOpts)
 
+	// Debugging trace of the container process system calls.
+	if l.cfg.TraceSyscalls != "" {
+		path, err := filepath.Abs(l.cfg.TraceSyscalls)
+		if err != nil {
+			sylog.Fatalf("Could not determine absolute path of %s: %s", l.cfg.TraceSyscalls, err)
+		}
+		l.engineConfig.SetTraceSyscalls(path)
+	}
+
+	// CPU scheduling of the container process.
+	l.engineConfig.SetSchedPolicy(l.cfg.SchedPolicy)
+	if l.cfg.Nice != nil {
+		l.engineConfig.SetNice(*l.cfg.Nice)
+	}
+
 	// User can override shell used when entering container.
 	l.engineConfig.SetShell(l.cfg.ShellPath)
 	if l.cfg.ShellPath != "" {
@@ -318,6 +448,16 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 		l.cfg.Namespaces.User = !l.cfg.IgnoreUserns
 	}
 
+	// Are we running with userns and subuid / subgid mapping that keeps our own UID/GID?
+	l.engineConfig.SetKeepID(l.cfg.KeepID)
+	if l.cfg.KeepID {
+		l.cfg.Namespaces.User = true
+	}
+
+	// Custom user and group entries in the container passwd and group files.
+	l.engineConfig.SetPasswdEntry(l.cfg.PasswdEntry)
+	l.engineConfig.SetGroupEntry(l.cfg.GroupEntry)
+
 	err = l.setCgroups(instanceName)
 	if err != nil {
 		sylog.Fatalf("Error while setting cgroups, err: %s", err)
@@ -353,8 +493,10 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 		l.engineConfig.SetInstance(true)
 		l.engineConfig.SetBootInstance(l.cfg.Boot)
 
-		if useSuid && !l.cfg.Namespaces.User && hidepidProc() {
-			return fmt.Errorf("hidepid option set on /proc mount, require 'hidepid=0' to start instance with setuid workflow")
+		if useSuid && !l.cfg.Namespaces.User {
+			if err := checkHidepidProc(); err != nil {
+				return fmt.Errorf("%s, required to start instance with setuid workflow", err)
+			}
 		}
 
 		_, err := instance.Get(instanceName, instance.AppSubDir)
@@ -382,6 +524,12 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 	// Set runscript timeout
 	l.engineConfig.SetRunscriptTimout(l.cfg.RunscriptTimeout)
 
+	// Set command overriding the runscript
+	l.engineConfig.SetEntrypoint(l.cfg.Entrypoint)
+
+	// Set wrapper command of the container process
+	l.engineConfig.SetWrap(l.cfg.Wrap)
+
 	// Set the required namespaces in the engine config.
 	l.setNamespaces()
 	// Set the container environment.
@@ -390,6 +538,8 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 	}
 	// Set the container process work directory.
 	l.setProcessCwd()
+	// Set the container process terminal.
+	l.setProcessTerminal()
 
 	l.generator.SetProcessEnvWithPrefixes(env.ApptainerPrefixes, "APPNAME", l.cfg.AppName)
 	// set an additional environment APPTAINER_SHARENS_MASTER = 1 inside container
@@ -402,6 +552,19 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 		return fmt.Errorf("while preparing image: %s", err)
 	}
 
+	// Create the temporary writable overlay image, if requested.
+	if err := l.createTempOverlay(); err != nil {
+		return fmt.Errorf("while creating temporary writable overlay: %s", err)
+	}
+	tempOverlay := l.engineConfig.GetDeleteTempOverlay()
+
+	if l.cfg.DryRunMounts {
+		if tempOverlay != "" {
+			defer os.RemoveAll(tempOverlay)
+		}
+		return l.printMountPlan()
+	}
+
 	loadOverlay := false
 	if !l.cfg.Namespaces.User && (buildcfg.APPTAINER_SUID_INSTALL == 1 || os.Getuid() == 0) {
 		has, err := proc.HasFilesystem("overlay")
@@ -437,11 +600,58 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 
 	// Execution is finished.
 	if err != nil {
+		if tempOverlay != "" {
+			os.RemoveAll(tempOverlay)
+		}
 		return fmt.Errorf("while executing starter: %s", err)
 	}
 	return nil
 }
 
+// printMountPlan prints the mount points of the container in mount order
+// without mounting them.
+func (l *Launcher) printMountPlan() error {
+	points, err := apptainer.MountPlan(l.engineConfig)
+	if err != nil {
+		return fmt.Errorf("while building mount plan: %s", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TAG\tSOURCE\tDESTINATION\tTYPE\tOPTIONS")
+	for _, tag := range mount.GetTagList() {
+		for _, point := range points.GetByTag(tag) {
+			source := point.Source
+			if source == "" {
+				source = "-"
+			}
+			fsType := point.Type
+			if fsType == "" {
+				fsType = "-"
+			}
+			options := strings.Join(point.Options, ",")
+			if options == "" {
+				options = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", tag, source, point.Destination, fsType, options)
+		}
+	}
+	return tw.Flush()
+}
+
+// setHostname sets the requested container hostname, it requires the UTS
+// namespace which must be allowed by configuration.
+func (l *Launcher) setHostname() error {
+	if l.cfg.Hostname == "" {
+		return nil
+	}
+	if !l.engineConfig.File.AllowUtsNs {
+		return fmt.Errorf("can't set container hostname, UTS namespace is disallowed by configuration ('allow uts ns = no')")
+	}
+	l.cfg.Namespaces.UTS = true
+	l.engineConfig.SetHostname(l.cfg.Hostname)
+	return nil
+}
+
 // setUmask saves the current umask, to be set for the process run in the container,
 // unless the --no-umask option was specified.
 // https://github.com/apptainer/singularity/issues/5214
@@ -575,39 +785,198 @@ func (l *Launcher) setImageOrInstance(image string, name string) error {
 	return nil
 }
 
-// checkEncryptionKey verifies key material is available if the image is encrypted.
-// Allows us to fail fast if required key material is not available / usable.
-func (l *Launcher) checkEncryptionKey() error {
-	sylog.Debugf("Checking for encrypted system partition")
-	img, err := imgutil.Init(l.engineConfig.GetImage(), false)
+// EncryptionKeyStatus describes the key material required by an image.
+type EncryptionKeyStatus int
+
+const (
+	// NotEncrypted indicates the image is not encrypted, no key is needed.
+	NotEncrypted EncryptionKeyStatus = iota
+	// PassphraseRequired indicates the image is encrypted with a passphrase.
+	PassphraseRequired
+	// PEMRequired indicates the image is encrypted with a PEM public key,
+	// the matching PEM private key is required.
+	PEMRequired
+)
+
+// EncryptionKeyError is returned by checkEncryptionKey when the key material
+// provided doesn't match the image encryption, or can't decrypt the image.
+type EncryptionKeyError struct {
+	// Image is the path of the image.
+	Image string
+	// Status is the key material required by the image.
+	Status EncryptionKeyStatus
+	// EncryptionType is the image encryption type, encryptfs or gocryptfs,
+	// it is empty when the image is not encrypted.
+	EncryptionType string
+	// Err is the decryption error, if any.
+	Err error
+}
+
+func (e *EncryptionKeyError) Error() string {
+	switch e.Status {
+	case NotEncrypted:
+		return fmt.Sprintf("image %s is not encrypted, no key material is needed", e.Image)
+	case PassphraseRequired:
+		msg := fmt.Sprintf("image %s is encrypted with %s and requires a passphrase", e.Image, e.EncryptionType)
+		if e.Err != nil {
+			return fmt.Sprintf("%s: %s", msg, e.Err)
+		}
+		return msg + ", provide it with --passphrase, --passphrase-fd or APPTAINER_ENCRYPTION_PASSPHRASE"
+	case PEMRequired:
+		msg := fmt.Sprintf("image %s is encrypted with %s and requires a PEM private key", e.Image, e.EncryptionType)
+		if e.Err != nil {
+			return fmt.Sprintf("%s: %s", msg, e.Err)
+		}
+		return msg + ", provide it with --pem-path or APPTAINER_ENCRYPTION_PEM_PATH"
+	}
+	return fmt.Sprintf("image %s: unknown encryption key status", e.Image)
+}
+
+func (e *EncryptionKeyError) Unwrap() error {
+	return e.Err
+}
+
+// checkTmpDir checks the temporary directory dir set with --tmpdir is
+// a writable directory, so we fail before creating any temporary file.
+func checkTmpDir(dir string) error {
+	// the system temporary directory is checked when used
+	if dir == "" || dir == os.TempDir() {
+		return nil
+	}
+	fi, err := os.Stat(dir)
 	if err != nil {
-		return fmt.Errorf("could not open image %s: %w", l.engineConfig.GetImage(), err)
+		return fmt.Errorf("temporary directory %s: %w", dir, err)
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("temporary directory %s is not a directory", dir)
+	}
+	if err := unix.Access(dir, unix.W_OK|unix.X_OK); err != nil {
+		return fmt.Errorf("temporary directory %s is not writable: %w", dir, err)
+	}
+	return nil
+}
+
+// tmpDir returns the directory where temporary files are created.
+func (l *Launcher) tmpDir() string {
+	if l.cfg.TmpDir != "" {
+		return l.cfg.TmpDir
 	}
+	return os.TempDir()
+}
 
-	part, err := img.GetRootFsPartition()
+// tempOverlaySize returns the size in MiB of the temporary writable overlay
+// image requested with --writable-overlay-size, or 0 if none is requested.
+func (l *Launcher) tempOverlaySize() (int, error) {
+	if l.cfg.WritableOverlaySize == "" {
+		return 0, nil
+	}
+	if l.engineConfig.File.EnableOverlay == "no" {
+		return 0, fmt.Errorf("--writable-overlay-size requires 'enable overlay', but set to 'no' by administrator")
+	}
+	if l.cfg.Writable {
+		return 0, fmt.Errorf("--writable-overlay-size can't be used with --writable")
+	}
+	if l.cfg.WritableTmpfs {
+		return 0, fmt.Errorf("--writable-overlay-size can't be used with --writable-tmpfs")
+	}
+	size, err := units.RAMInBytes(l.cfg.WritableOverlaySize)
 	if err != nil {
-		return fmt.Errorf("while getting root filesystem in %s: %w", l.engineConfig.GetImage(), err)
+		return 0, fmt.Errorf("invalid --writable-overlay-size %s: %s", l.cfg.WritableOverlaySize, err)
 	}
+	return int(size / (1024 * 1024)), nil
+}
 
-	if part.Type == imgutil.ENCRYPTSQUASHFS || part.Type == imgutil.GOCRYPTFSSQUASHFS {
-		sylog.Debugf("Encrypted container filesystem detected")
+// createTempOverlay creates the sparse ext3 image requested with
+// --writable-overlay-size in the temporary directory and stacks it as the
+// writable upper layer on top of the overlay images. The image directory
+// is deleted by the engine once the container exits.
+func (l *Launcher) createTempOverlay() error {
+	size, err := l.tempOverlaySize()
+	if err != nil || size == 0 {
+		return err
+	}
 
-		if l.cfg.KeyInfo == nil {
-			return fmt.Errorf("required option --passphrase or --pem-path missing")
-		}
+	dir, err := os.MkdirTemp(l.tmpDir(), "overlay-")
+	if err != nil {
+		return err
+	}
+	img := filepath.Join(dir, "overlay.img")
 
-		plaintextKey, err := cryptkey.PlaintextKey(*l.cfg.KeyInfo, l.engineConfig.GetImage())
-		if err != nil {
-			sylog.Errorf("Please check you are providing the correct key for decryption")
-			return fmt.Errorf("cannot decrypt %s: %w", l.engineConfig.GetImage(), err)
-		}
+	sylog.Verbosef("Creating temporary writable overlay image %s of %d MiB", img, size)
+	if err := apptainerapp.OverlayCreate(size, img, true, l.cfg.Fakeroot); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+
+	l.engineConfig.SetOverlayImage(append(l.engineConfig.GetOverlayImage(), img+":rw"))
+	l.engineConfig.SetDeleteTempOverlay(dir)
+	return nil
+}
 
-		l.engineConfig.SetEncryptionKey(plaintextKey)
+// checkEncryptionKey verifies key material is available if the image is encrypted.
+// Allows us to fail fast if required key material is not available / usable.
+// An *EncryptionKeyError is returned when the key material is missing, doesn't
+// match the image encryption or can't decrypt the image, and also with the
+// NotEncrypted status when key material is provided for an image which is not
+// encrypted, in which case the key material is ignored.
+func (l *Launcher) checkEncryptionKey() error {
+	sylog.Debugf("Checking for encrypted system partition")
+	image := l.engineConfig.GetImage()
+	img, err := imgutil.Init(image, false)
+	if err != nil {
+		return fmt.Errorf("could not open image %s: %w", image, err)
 	}
 	// don't defer this call as in all cases it won't be
 	// called before execing starter, so it would leak the
 	// image file descriptor to the container process
+	encryptionType, err := img.EncryptedRootFs()
 	img.File.Close()
+	if err != nil {
+		return fmt.Errorf("while getting root filesystem in %s: %w", image, err)
+	}
+
+	if encryptionType == "" {
+		if l.cfg.KeyInfo != nil {
+			return &EncryptionKeyError{Image: image, Status: NotEncrypted}
+		}
+		return nil
+	}
+	sylog.Debugf("Encrypted container filesystem detected (%s)", encryptionType)
+
+	keyErr := &EncryptionKeyError{
+		Image:          image,
+		Status:         PassphraseRequired,
+		EncryptionType: encryptionType,
+	}
+	format, err := cryptkey.ImageKeyFormat(image)
+	if err != nil {
+		return fmt.Errorf("while getting %s key format: %w", image, err)
+	}
+	if format == cryptkey.PEM {
+		keyErr.Status = PEMRequired
+	}
+
+	if l.cfg.KeyInfo == nil {
+		return keyErr
+	}
+	switch l.cfg.KeyInfo.Format {
+	case cryptkey.Passphrase:
+		if keyErr.Status != PassphraseRequired {
+			return keyErr
+		}
+	case cryptkey.PEM, cryptkey.ENV:
+		if keyErr.Status != PEMRequired {
+			return keyErr
+		}
+	}
+
+	plaintextKey, err := cryptkey.PlaintextKey(*l.cfg.KeyInfo, image)
+	if err != nil {
+		keyErr.Err = fmt.Errorf("cannot decrypt with the provided key: %w", err)
+		return keyErr
+	}
+
+	l.engineConfig.SetEncryptionKey(plaintextKey)
 	return nil
 }
 
@@ -655,20 +1024,73 @@ func (l *Launcher) useSuid(insideUserNs bool) (useSuid bool) {
 
 // setBinds sets engine configuration for requested bind mounts.
 func (l *Launcher) setBinds(fakerootPath string) error {
-	// First get binds from -B/--bind and env var
+	// First get binds from -B/--bind
 	binds, err := apptainerConfig.ParseBindPath(l.cfg.BindPaths)
 	if err != nil {
 		return fmt.Errorf("while parsing bind path: %w", err)
 	}
-	// Now add binds from one or more --mount and env var.
+	// Then binds from --bind-file, processed as command line binds
+	for _, f := range l.cfg.BindFiles {
+		fileBinds, err := apptainerConfig.ParseBindFile(f)
+		if err != nil {
+			return fmt.Errorf("while parsing bind file: %w", err)
+		}
+		binds = append(binds, fileBinds...)
+	}
+	// Now get binds from one or more --mount and env var.
 	// Note that these do not get exported for nested containers
+	var mountBinds []apptainerConfig.BindPath
+	var tmpfsMounts []apptainerConfig.TmpfsMount
+	var kernelMounts []apptainerConfig.KernelMount
 	for _, m := range l.cfg.Mounts {
-		bps, err := apptainerConfig.ParseMountString(m)
+		bps, tms, kms, err := apptainerConfig.ParseMounts(m)
 		if err != nil {
 			return fmt.Errorf("while parsing mount %q: %w", m, err)
 		}
-		binds = append(binds, bps...)
+		for _, bp := range bps {
+			if _, err := os.Stat(bp.Source); err != nil {
+				return fmt.Errorf("while checking mount %q source: %w", m, err)
+			}
+		}
+		// tmpfs mounts are charged to the user memory, so an unprivileged
+		// user is limited to the configured session directory size
+		maxSize := int64(l.engineConfig.File.SessiondirMaxSize) * 1024 * 1024
+		for _, tm := range tms {
+			if l.uid != 0 && tm.Size > maxSize {
+				return fmt.Errorf("tmpfs mount %s size exceeds the 'sessiondir max size' of %d MiB set in apptainer.conf", tm.Destination, l.engineConfig.File.SessiondirMaxSize)
+			}
+		}
+		mountBinds = append(mountBinds, bps...)
+		tmpfsMounts = append(tmpfsMounts, tms...)
+		kernelMounts = append(kernelMounts, kms...)
+	}
+	// Binds from APPTAINER_BIND/APPTAINER_BINDPATH are overridden by
+	// command line binds and mounts with the same destination.
+	envBinds, err := apptainerConfig.ParseBindPath(l.cfg.EnvBindPaths)
+	if err != nil {
+		return fmt.Errorf("while parsing bind path from environment: %w", err)
+	}
+	for _, eb := range envBinds {
+		if hasBindDestination(binds, eb.Destination) || hasBindDestination(mountBinds, eb.Destination) {
+			sylog.Verbosef("Bind %s:%s from environment overridden by command line", eb.Source, eb.Destination)
+			continue
+		}
+		binds = append(binds, eb)
+	}
+	// Binds recommended by the image are overridden by any other bind
+	// or mount with the same destination.
+	imageBinds, err := l.imageBinds()
+	if err != nil {
+		return err
 	}
+	for _, ib := range imageBinds {
+		if hasBindDestination(binds, ib.Destination) || hasBindDestination(mountBinds, ib.Destination) {
+			sylog.Verbosef("Bind %s:%s provided by image overridden", ib.Source, ib.Destination)
+			continue
+		}
+		binds = append(binds, ib)
+	}
+	binds = append(binds, mountBinds...)
 
 	if fakerootPath != "" {
 		l.engineConfig.SetFakerootPath(fakerootPath)
@@ -685,6 +1107,32 @@ func (l *Launcher) setBinds(fakerootPath string) error {
 	}
 
 	l.engineConfig.SetBindPath(binds)
+	l.engineConfig.SetTmpfsMounts(tmpfsMounts)
+	l.engineConfig.SetKernelMounts(kernelMounts)
+	l.engineConfig.SetStrictBinds(l.cfg.StrictBinds)
+
+	overlayBinds := make([]apptainerConfig.OverlayBind, 0, len(l.cfg.OverlayBinds))
+	for _, spec := range l.cfg.OverlayBinds {
+		ob, err := apptainerConfig.ParseOverlayBind(spec)
+		if err != nil {
+			return fmt.Errorf("while parsing overlay bind %q: %w", spec, err)
+		}
+		overlayBinds = append(overlayBinds, ob)
+	}
+	l.engineConfig.SetOverlayBind(overlayBinds)
+
+	mountFrom := make([]apptainerConfig.MountFrom, 0, len(l.cfg.MountFrom))
+	for _, spec := range l.cfg.MountFrom {
+		mf, err := apptainerConfig.ParseMountFrom(spec)
+		if err != nil {
+			return fmt.Errorf("while parsing mount from %q: %w", spec, err)
+		}
+		if mf.Pid, err = l.instancePid(mf.Instance); err != nil {
+			return fmt.Errorf("while mounting %s from instance %s: %w", mf.Source, mf.Instance, err)
+		}
+		mountFrom = append(mountFrom, mf)
+	}
+	l.engineConfig.SetMountFrom(mountFrom)
 
 	// Pass only the destinations to nested binds
 	bindPaths := make([]string, len(binds))
@@ -695,6 +1143,73 @@ func (l *Launcher) setBinds(fakerootPath string) error {
 	return nil
 }
 
+// imageBinds returns the binds recommended by the SIF image in its
+// apptainer.binds descriptor. They are only applied when allowed by the
+// 'allow image binds' directive, and are ignored with --no-mount
+// image-binds or when user binds are disabled by the administrator. Only
+// plain host path binds are accepted, and the ones whose source doesn't
+// exist on this host are skipped.
+func (l *Launcher) imageBinds() ([]apptainerConfig.BindPath, error) {
+	image := l.engineConfig.GetImage()
+	if slices.Contains(l.cfg.NoMount, "image-binds") || !fs.IsFile(image) {
+		return nil, nil
+	}
+
+	img, err := imgutil.Init(image, false)
+	if err != nil {
+		// the image is checked by the engine later
+		sylog.Debugf("Could not read binds provided by image %s: %s", image, err)
+		return nil, nil
+	}
+	defer img.File.Close()
+
+	if len(img.Binds) == 0 {
+		return nil, nil
+	}
+	if !l.engineConfig.File.AllowImageBinds {
+		sylog.Verbosef("Ignoring binds provided by image %s: 'allow image binds' disabled in apptainer.conf", image)
+		return nil, nil
+	}
+	if !l.engineConfig.File.UserBindControl {
+		sylog.Warningf("Ignoring binds provided by image %s: user bind control disabled by system administrator", image)
+		return nil, nil
+	}
+
+	binds, err := apptainerConfig.ParseBindPath(img.Binds)
+	if err != nil {
+		return nil, fmt.Errorf("while parsing binds provided by image %s: %w", image, err)
+	}
+
+	imageBinds := make([]apptainerConfig.BindPath, 0, len(binds))
+	for _, b := range binds {
+		if b.IsImageBind() || b.Propagation() != "" {
+			sylog.Warningf("Ignoring bind %s:%s provided by image: only host path binds without mount propagation are allowed", b.Source, b.Destination)
+			continue
+		}
+		if !filepath.IsAbs(b.Source) {
+			sylog.Warningf("Ignoring bind %s:%s provided by image: source must be an absolute path", b.Source, b.Destination)
+			continue
+		}
+		if _, err := os.Stat(b.Source); err != nil {
+			sylog.Verbosef("Skipping bind %s:%s provided by image: %s", b.Source, b.Destination, err)
+			continue
+		}
+		sylog.Infof("Adding bind %s:%s provided by image", b.Source, b.Destination)
+		imageBinds = append(imageBinds, b)
+	}
+	return imageBinds, nil
+}
+
+// hasBindDestination returns true if a bind in binds targets dest.
+func hasBindDestination(binds []apptainerConfig.BindPath, dest string) bool {
+	for _, b := range binds {
+		if filepath.Clean(b.Destination) == filepath.Clean(dest) {
+			return true
+		}
+	}
+	return false
+}
+
 // setFuseMounts sets engine configuration for requested FUSE mounts.
 func (l *Launcher) setFuseMounts() error {
 	if len(l.cfg.FuseMount) > 0 {
@@ -729,6 +1244,8 @@ func (l *Launcher) setNoMountFlags() {
 			l.engineConfig.SetNoHostfs(true)
 		case "cwd":
 			l.engineConfig.SetNoCwd(true)
+		// Binds provided by the image, handled by setBinds
+		case "image-binds":
 		// All bind path apptainer.conf entries
 		case "bind-paths":
 			skipBinds = append(skipBinds, "*")
@@ -748,6 +1265,10 @@ func (l *Launcher) setNoMountFlags() {
 // If it is not possible to mount a home directory then the mount will be disabled.
 func (l *Launcher) setHome() error {
 	l.engineConfig.SetCustomHome(l.cfg.CustomHome)
+	if l.cfg.HomeKeepPath && !l.cfg.Fakeroot {
+		sylog.Warningf("--home-keep-path has no effect without --fakeroot")
+	}
+	l.engineConfig.SetHomeKeepPath(l.cfg.HomeKeepPath)
 	// If we have fakeroot & the home flag has not been used then we have the standard
 	// /root location for the root user $HOME in the container.
 	// This doesn't count as a SetCustomHome(true), as we are mounting from the real
@@ -757,7 +1278,8 @@ func (l *Launcher) setHome() error {
 	// Note from dwd on 3/24/22: it's not clear to me that this has
 	// any effect because getHomePaths() appears to ignore the
 	// HomeDir settings if there is no CustomHome
-	if !l.cfg.CustomHome && l.cfg.Fakeroot {
+	// With --home-keep-path the home directory keeps its original path.
+	if !l.cfg.CustomHome && l.cfg.Fakeroot && !l.cfg.HomeKeepPath {
 		l.cfg.HomeDir = fmt.Sprintf("%s:/root", l.cfg.HomeDir)
 	}
 	// If we are running apptainer as root, but requesting a target UID in the container,
@@ -819,6 +1341,12 @@ func (l *Launcher) SetGPUConfig() error {
 		}
 	}
 
+	if l.cfg.DRI {
+		if err := l.setDRIConfig(); err != nil {
+			return err
+		}
+	}
+
 	if l.cfg.Nvidia {
 		// If nvccli was not enabled by flag or config, drop down to legacy binds immediately
 		if !l.engineConfig.File.UseNvCCLI && !l.cfg.NvCCLI {
@@ -873,7 +1401,7 @@ func (l *Launcher) setNvCCLIConfig() (err error) {
 		}
 	}
 
-	if !l.cfg.Writable && !l.cfg.WritableTmpfs && !overlayExist {
+	if !l.cfg.Writable && !l.cfg.WritableTmpfs && !overlayExist && l.cfg.WritableOverlaySize == "" {
 		sylog.Infof("Setting --writable-tmpfs (required by nvidia-container-cli)")
 		l.cfg.WritableTmpfs = true
 	}
@@ -912,6 +1440,27 @@ func (l *Launcher) setRocmConfig() error {
 	return nil
 }
 
+// setDRIConfig sets up EngineConfig entries for GPU usage through DRM card and render nodes
+// via direct binds of configured bins/libs.
+func (l *Launcher) setDRIConfig() error {
+	sylog.Debugf("Using DRI GPU setup")
+	devs, err := gpu.DRIDevices()
+	if err != nil {
+		return fmt.Errorf("while looking for DRI devices: %w", err)
+	}
+	if len(devs) == 0 {
+		sylog.Warningf("Could not find any DRM card or render node in /dev/dri on this host!")
+	}
+	l.engineConfig.SetDRI(true)
+	gpuConfFile := filepath.Join(buildcfg.APPTAINER_CONFDIR, "driliblist.conf")
+	libs, bins, err := gpu.DRIPaths(gpuConfFile)
+	if err != nil {
+		sylog.Warningf("While finding DRI bind points: %v", err)
+	}
+	l.addGPUBinds(libs, bins, []string{}, []string{}, "dri")
+	return nil
+}
+
 // addGPUBinds adds EngineConfig entries to bind the provided list of libs, bins, ipc files.
 func (l *Launcher) addGPUBinds(libs, bins, ipcs, regularFiles []string, gpuPlatform string) {
 	files := make([]string, len(bins)+len(ipcs)+len(regularFiles))
@@ -989,22 +1538,86 @@ func (l *Launcher) setNamespaces() {
 	if l.cfg.Namespaces.UTS {
 		l.generator.AddOrReplaceLinuxNamespace("uts", "")
 	}
-	if l.cfg.Namespaces.PID {
+	if l.cfg.Namespaces.Cgroup {
+		l.generator.AddOrReplaceLinuxNamespace("cgroup", "")
+	}
+	if l.cfg.Namespaces.PIDInstance != "" {
+		if l.engineConfig.GetInstance() {
+			sylog.Fatalf("--pid=container:%s can't be used when starting an instance", l.cfg.Namespaces.PIDInstance)
+		}
+		path, err := l.instanceNamespacePath(l.cfg.Namespaces.PIDInstance, "pid")
+		if err != nil {
+			sylog.Fatalf("While sharing PID namespace: %s", err)
+		}
+		l.generator.AddOrReplaceLinuxNamespace("pid", path)
+	} else if l.cfg.Namespaces.PID {
 		l.generator.AddOrReplaceLinuxNamespace("pid", "")
 		l.engineConfig.SetNoInit(l.cfg.NoInit)
 	}
-	if l.cfg.Namespaces.IPC {
+	if l.cfg.Namespaces.IPCInstance != "" {
+		path, err := l.instanceNamespacePath(l.cfg.Namespaces.IPCInstance, "ipc")
+		if err != nil {
+			sylog.Fatalf("While sharing IPC namespace: %s", err)
+		}
+		l.generator.AddOrReplaceLinuxNamespace("ipc", path)
+	} else if l.cfg.Namespaces.IPC {
 		l.generator.AddOrReplaceLinuxNamespace("ipc", "")
 	}
 	if l.cfg.Namespaces.User {
 		l.generator.AddOrReplaceLinuxNamespace("user", "")
-		if !l.cfg.Fakeroot {
+		if !l.cfg.Fakeroot && !l.cfg.KeepID {
 			l.generator.AddLinuxUIDMapping(uint32(os.Getuid()), l.uid, 1)
 			l.generator.AddLinuxGIDMapping(uint32(os.Getgid()), l.gid, 1)
 		}
 	}
 }
 
+// instanceNamespacePath returns the path of the nsType namespace of the
+// running instance name, to be joined by the container. Ownership of the
+// instance process is checked again by the engine.
+func (l *Launcher) instanceNamespacePath(name, nsType string) (string, error) {
+	if l.engineConfig.GetInstanceJoin() {
+		return "", fmt.Errorf("can't be combined with joining an instance")
+	}
+	if l.cfg.Namespaces.User {
+		return "", fmt.Errorf("not supported with a user namespace")
+	}
+	file, err := instance.Get(name, instance.AppSubDir)
+	if err != nil {
+		return "", err
+	}
+	if file.UserNs {
+		return "", fmt.Errorf("instance %s runs in a user namespace and its namespaces can't be shared", name)
+	}
+	if file.Pid <= 1 {
+		return "", fmt.Errorf("bad instance process ID found for %s", name)
+	}
+	if err := syscall.Kill(file.Pid, 0); err != nil {
+		return "", fmt.Errorf("instance %s is not running: %s", name, err)
+	}
+	return filepath.Join("/proc", strconv.Itoa(file.Pid), "ns", nsType), nil
+}
+
+// instancePid returns the process ID of the running instance name, to
+// mount paths from its root filesystem. Ownership of the instance process
+// is checked again by the engine.
+func (l *Launcher) instancePid(name string) (int, error) {
+	if l.engineConfig.GetInstanceJoin() {
+		return 0, fmt.Errorf("can't be combined with joining an instance")
+	}
+	file, err := instance.Get(name, instance.AppSubDir)
+	if err != nil {
+		return 0, err
+	}
+	if file.Pid <= 1 {
+		return 0, fmt.Errorf("bad instance process ID found for %s", name)
+	}
+	if err := syscall.Kill(file.Pid, 0); err != nil {
+		return 0, fmt.Errorf("instance %s is not running: %s", name, err)
+	}
+	return file.Pid, nil
+}
+
 // setEnvVars sets the environment for the container, from the host environment, glads, env-file.
 func (l *Launcher) setEnvVars(ctx context.Context, args []string) error {
 	if len(l.cfg.EnvFiles) > 0 {
@@ -1014,15 +1627,11 @@ func (l *Launcher) setEnvVars(ctx context.Context, args []string) error {
 		)
 
 		// Read all environment files and put the variables into envFilesMap,
-		// environment variables in later files will take precedence.
-		envFilesMap := map[string]string{}
-		for _, envFile := range l.cfg.EnvFiles {
-			tempEnvMap, err := env.FileMap(ctx, envFile, args, currentEnv)
-			if err != nil {
-				return fmt.Errorf("while processing %s: %w", envFile, err)
-			}
-			sylog.Debugf("Setting environment variables from file %s", envFile)
-			envFilesMap = env.MergeMap(envFilesMap, tempEnvMap)
+		// environment variables in later files will take precedence and
+		// can reference variables set in earlier files.
+		envFilesMap, err := env.FilesMap(ctx, l.cfg.EnvFiles, args, currentEnv)
+		if err != nil {
+			return fmt.Errorf("while processing environment files: %w", err)
 		}
 
 		// --env variables will take precedence over variables defined by the environment files
@@ -1050,7 +1659,7 @@ func (l *Launcher) setEnvVars(ctx context.Context, args []string) error {
 	// Copy and cache environment
 	environment := os.Environ()
 	// Clean environment
-	apptainerEnv := env.SetContainerEnv(l.generator, environment, l.cfg.CleanEnv, l.engineConfig.GetHomeDest())
+	apptainerEnv := env.SetContainerEnv(l.generator, environment, l.cfg.CleanEnv, l.cfg.KeepEnv, l.engineConfig.GetHomeDest())
 	l.engineConfig.SetApptainerEnv(apptainerEnv)
 	return nil
 }
@@ -1077,6 +1686,41 @@ func (l *Launcher) setProcessCwd() {
 	}
 }
 
+// setProcessTerminal records whether the container process is attached
+// to a terminal, found on stdin, stdout or stderr, its initial window size
+// and the host terminal type.
+func (l *Launcher) setProcessTerminal() {
+	for fd := 0; fd <= 2; fd++ {
+		if l.setTerminal(fd) {
+			return
+		}
+	}
+}
+
+// setTerminal records the terminal attached to fd, if any, for the
+// container process. TERM is set from the host unless the container
+// environment already defines it, as with APPTAINERENV_TERM.
+func (l *Launcher) setTerminal(fd int) bool {
+	ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return false
+	}
+	l.generator.SetProcessTerminal(true)
+	if ws.Row > 0 && ws.Col > 0 {
+		sylog.Debugf("Terminal window size is %dx%d", ws.Col, ws.Row)
+		l.generator.SetProcessConsoleSize(uint(ws.Col), uint(ws.Row))
+	}
+	if term := os.Getenv("TERM"); term != "" {
+		for _, e := range l.generator.Config.Process.Env {
+			if strings.HasPrefix(e, "TERM=") {
+				return true
+			}
+		}
+		l.generator.SetProcessEnv("TERM", term)
+	}
+	return true
+}
+
 // setCgroups sets cgroup related configuration
 func (l *Launcher) setCgroups(instanceName string) error {
 	// If we are not root, we need to pass in XDG / DBUS environment so we can communicate
@@ -1088,6 +1732,17 @@ func (l *Launcher) setCgroups(instanceName string) error {
 	}
 
 	if l.cfg.CGroupsJSON != "" {
+		// Check controllers delegation early for rootless cgroups, to report
+		// a clear error rather than a D-Bus failure at container creation.
+		if l.uid != 0 {
+			resources, err := cgroups.UnmarshalJSONResources(l.cfg.CGroupsJSON)
+			if err != nil {
+				return err
+			}
+			if err := cgroups.CheckDelegation(resources); err != nil {
+				return err
+			}
+		}
 		// Handle cgroups configuration (parsed from file or flags in CLI).
 		l.engineConfig.SetCgroupsJSON(l.cfg.CGroupsJSON)
 		return nil
@@ -1097,7 +1752,7 @@ func (l *Launcher) setCgroups(instanceName string) error {
 		return nil
 	}
 
-	hidePid := hidepidProc()
+	hidePid := checkHidepidProc() != nil
 	// If we are an instance, always use a cgroup if possible, to enable stats.
 	// root can always create a cgroup.
 	sylog.Debugf("During setting cgroups configuration, uid: %d, namespace.user: %t, fakeroot: %t, unprivileged: %t", l.uid, l.cfg.Namespaces.User, l.cfg.Fakeroot, namespaces.IsUnprivileged())
@@ -1142,8 +1797,24 @@ func (l *Launcher) prepareImage(_ context.Context, insideUserNs bool, image stri
 		desiredFeatures = imgutil.ImageFeature
 	}
 	fileconf := l.engineConfig.File
+	if name := l.engineConfig.GetImageDriver(); name != "" {
+		// the image driver selected for this container takes
+		// precedence over the configured one
+		conf := *fileconf
+		conf.ImageDriver = name
+		fileconf = &conf
+	}
 	driver.InitImageDrivers(true, l.cfg.Namespaces.User || insideUserNs, fileconf, desiredFeatures)
 
+	// OCI layers are stacked like sandbox overlays which can only be
+	// used by the root user in setuid mode
+	if len(l.cfg.OCILayers) > 0 {
+		if l.uid != 0 && !l.cfg.Namespaces.User && !insideUserNs {
+			return fmt.Errorf("OCI layers can only be used with a user namespace, use --userns")
+		}
+		l.engineConfig.SetOCILayers(l.cfg.OCILayers)
+	}
+
 	// convert image file to sandbox if either it was requested by
 	// `--unsquash` or we cannot mount the image directly and there's
 	// no image driver.
@@ -1172,10 +1843,16 @@ func (l *Launcher) prepareImage(_ context.Context, insideUserNs bool, image stri
 					// the image driver indicates support for squashfs so let's
 					// proceed with the image driver without conversion
 					convert = false
+				} else if driver != nil && driver.Features()&imgutil.ErofsFeature != 0 && isErofsImage(image) {
+					convert = false
 				}
 			}
 		}
 
+		if convert && isErofsImage(image) {
+			return fmt.Errorf("EROFS image %s can't be converted to a sandbox, erofsfuse is required to run it", image)
+		}
+
 		if convert {
 			unsquashfsPath, err := bin.FindBin("unsquashfs")
 			if err != nil {
@@ -1202,6 +1879,19 @@ func (l *Launcher) prepareImage(_ context.Context, insideUserNs bool, image stri
 	return nil
 }
 
+// isErofsImage returns whether the root filesystem of the image
+// file is EROFS, which can't be extracted with unsquashfs.
+func isErofsImage(path string) bool {
+	img, err := imgutil.Init(path, false)
+	if err != nil {
+		return false
+	}
+	defer img.File.Close()
+
+	part, err := img.GetRootFsPartition()
+	return err == nil && part.Type == imgutil.EROFS
+}
+
 // starterInteractive executes the starter binary to run an image interactively, given the supplied engineConfig
 func (l *Launcher) starterInteractive(loadOverlay bool, useSuid bool, cfg *config.Common, imageFilename string) error {
 	err := starter.Exec(
@@ -1294,27 +1984,93 @@ func withPrivilege(uid uint32, cond bool, desc string, fn func() error) error {
 	return fn()
 }
 
-// hidepidProc checks if hidepid is set on /proc mount point, when this
-// option is an instance started with setuid workflow could not even be
-// joined later or stopped correctly.
-func hidepidProc() bool {
+// procHidepid returns the hidepid mode set on /proc mount point, or an
+// empty string if none is set, and the group ID allowed to access all
+// processes with the gid= option, or -1 if none is set.
+func procHidepid() (mode string, gid int) {
+	gid = -1
+
 	entries, err := proc.GetMountInfoEntry("/proc/self/mountinfo")
 	if err != nil {
 		sylog.Warningf("while reading /proc/self/mountinfo: %s", err)
-		return false
+		return "", gid
 	}
 	for _, e := range entries {
-		if e.Point == "/proc" {
-			for _, o := range e.SuperOptions {
-				if strings.HasPrefix(o, "hidepid=") {
-					return true
+		if e.Point != "/proc" {
+			continue
+		}
+		// the last /proc mount point is the visible one
+		mode, gid = "", -1
+		for _, o := range e.SuperOptions {
+			if v, ok := strings.CutPrefix(o, "hidepid="); ok {
+				mode = v
+			} else if v, ok := strings.CutPrefix(o, "gid="); ok {
+				if id, err := strconv.Atoi(v); err == nil {
+					gid = id
 				}
 			}
 		}
 	}
+	if mode == "0" || mode == "off" {
+		mode = ""
+	}
+	return mode, gid
+}
+
+// inGroup returns whether the current process is a member of the group gid.
+func inGroup(gid int) bool {
+	if os.Getegid() == gid {
+		return true
+	}
+	groups, err := os.Getgroups()
+	if err != nil {
+		return false
+	}
+	for _, g := range groups {
+		if g == gid {
+			return true
+		}
+	}
 	return false
 }
 
+// checkHidepidProc checks if hidepid is set on /proc mount point and
+// prevents the current process from accessing processes of other users,
+// in which case an instance started with setuid workflow could not even
+// be joined later or stopped correctly. The returned error describes how
+// to grant the access.
+func checkHidepidProc() error {
+	mode, gid := procHidepid()
+	if mode == "" {
+		return nil
+	}
+	if gid >= 0 && inGroup(gid) {
+		sylog.Debugf("hidepid=%s set on /proc mount, access granted by gid=%d option", mode, gid)
+		return nil
+	}
+	// the init process of the PID namespace belongs to another user
+	// unless it runs in a container, check if its status is readable
+	// to detect capabilities or any other access rule bypassing hidepid
+	if fi, err := os.Stat("/proc/1"); err == nil {
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Geteuid() {
+			if f, err := os.Open("/proc/1/status"); err == nil {
+				f.Close()
+				sylog.Debugf("hidepid=%s set on /proc mount, access granted to processes of other users", mode)
+				return nil
+			}
+		}
+	}
+
+	if gid < 0 {
+		return fmt.Errorf("hidepid=%s option set on /proc mount prevents access to processes of other users: remount /proc with 'hidepid=0', or with a 'gid=' option granting access to a group of the user", mode)
+	}
+	group := strconv.Itoa(gid)
+	if gr, err := user.GetGrGID(uint32(gid)); err == nil {
+		group = fmt.Sprintf("%s (gid %d)", gr.Name, gid)
+	}
+	return fmt.Errorf("hidepid=%s option set on /proc mount prevents access to processes of other users: add the user to the group %s allowed by the /proc 'gid=' option, or remount /proc with 'hidepid=0'", mode, group)
+}
+
 // convertImage extracts the image found at filename to directory dir within a temporary directory
 // tempDir. If the unsquashfs binary is not located, the binary at unsquashfsPath is used. It is
 // the caller's responsibility to remove rootfsDir when no longer needed.
@@ -0,0 +1,239 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package launch
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	imgutil "github.com/apptainer/apptainer/pkg/image"
+	"github.com/apptainer/apptainer/pkg/util/apptainerconf"
+	"github.com/apptainer/sif/v2/pkg/sif"
+	"github.com/creack/pty"
+	"golang.org/x/sys/unix"
+)
+
+// newTestLauncher returns a launcher using the default configuration
+// with the UTS namespace allowed or not.
+func newTestLauncher(t *testing.T, allowUtsNs bool, opts ...Option) *Launcher {
+	file, err := apptainerconf.GetConfig(nil)
+	if err != nil {
+		t.Fatalf("failed to get default configuration: %s", err)
+	}
+	file.AllowUtsNs = allowUtsNs
+
+	current := apptainerconf.GetCurrentConfig()
+	apptainerconf.SetCurrentConfig(file)
+	t.Cleanup(func() { apptainerconf.SetCurrentConfig(current) })
+
+	l, err := NewLauncher(opts...)
+	if err != nil {
+		t.Fatalf("failed to create launcher: %s", err)
+	}
+	return l
+}
+
+func TestSetHostname(t *testing.T) {
+	tests := []struct {
+		name       string
+		hostname   string
+		allowUtsNs bool
+		wantUTS    bool
+		wantErr    bool
+	}{
+		{
+			name:       "NoHostname",
+			allowUtsNs: false,
+		},
+		{
+			name:       "Hostname",
+			hostname:   "test",
+			allowUtsNs: true,
+			wantUTS:    true,
+		},
+		{
+			name:       "UTSDisallowed",
+			hostname:   "test",
+			allowUtsNs: false,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLauncher(t, tt.allowUtsNs, OptHostname(tt.hostname))
+
+			err := l.setHostname()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if l.cfg.Namespaces.UTS != tt.wantUTS {
+				t.Errorf("got UTS namespace %v, want %v", l.cfg.Namespaces.UTS, tt.wantUTS)
+			}
+			if got := l.engineConfig.GetHostname(); got != tt.hostname {
+				t.Errorf("got hostname %q, want %q", got, tt.hostname)
+			}
+		})
+	}
+}
+
+func TestExecHostnameEarlyFailure(t *testing.T) {
+	l := newTestLauncher(t, false, OptHostname("test"))
+
+	// the image doesn't exist, the hostname check must fail first
+	err := l.Exec(context.Background(), "/nonexistent.sif", []string{"true"}, "")
+	if err == nil || !strings.Contains(err.Error(), "allow uts ns = no") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSetTerminal(t *testing.T) {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		t.Skipf("could not open pseudo terminal: %s", err)
+	}
+	defer ptmx.Close()
+	defer tty.Close()
+
+	ws := &unix.Winsize{Col: 132, Row: 43}
+	if err := unix.IoctlSetWinsize(int(tty.Fd()), unix.TIOCSWINSZ, ws); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TERM", "xterm-256color")
+
+	notty, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notty.Close()
+
+	l := newTestLauncher(t, true)
+	if l.setTerminal(int(notty.Fd())) {
+		t.Fatalf("unexpected terminal found for %s", os.DevNull)
+	}
+	if p := l.generator.Config.Process; p != nil && p.Terminal {
+		t.Fatalf("unexpected terminal set for %s", os.DevNull)
+	}
+
+	if !l.setTerminal(int(tty.Fd())) {
+		t.Fatalf("terminal not found")
+	}
+	process := l.generator.Config.Process
+	if !process.Terminal {
+		t.Errorf("process terminal not set")
+	}
+	if process.ConsoleSize == nil || process.ConsoleSize.Width != 132 || process.ConsoleSize.Height != 43 {
+		t.Errorf("got console size %v, want 132x43", process.ConsoleSize)
+	}
+	if !slices.Contains(process.Env, "TERM=xterm-256color") {
+		t.Errorf("TERM not set from the host: %v", process.Env)
+	}
+
+	// TERM already defined for the container is kept
+	l = newTestLauncher(t, true)
+	l.generator.SetProcessEnv("TERM", "vt100")
+	l.setTerminal(int(tty.Fd()))
+	if !slices.Contains(l.generator.Config.Process.Env, "TERM=vt100") {
+		t.Errorf("TERM overridden: %v", l.generator.Config.Process.Env)
+	}
+}
+
+// createBindsSIF creates a SIF image with an EROFS root filesystem header
+// and an apptainer.binds descriptor holding binds.
+func createBindsSIF(t *testing.T, binds string) string {
+	rootfs := make([]byte, 4096)
+	copy(rootfs[1024:], "\xe2\xe1\xf5\xe0")
+
+	part, err := sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(rootfs),
+		sif.OptPartitionMetadata(sif.FsRaw, sif.PartPrimSys, runtime.GOARCH),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc, err := sif.NewDescriptorInput(sif.DataGenericJSON, strings.NewReader(binds),
+		sif.OptObjectName(imgutil.SIFDescBindsJSON),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "binds.sif")
+	fp, err := sif.CreateContainerAtPath(path, sif.OptCreateWithDescriptors(part, desc))
+	if err != nil {
+		t.Fatalf("failed to create SIF: %s", err)
+	}
+	fp.UnloadContainer()
+	return path
+}
+
+func TestImageBinds(t *testing.T) {
+	source := t.TempDir()
+	image := createBindsSIF(t, `["`+source+`:/data", "/non/existent:/missing"]`)
+
+	tests := []struct {
+		name            string
+		allowImageBinds bool
+		userBindControl bool
+		noMount         []string
+		wantBinds       int
+	}{
+		{
+			name:            "NotAllowed",
+			userBindControl: true,
+		},
+		{
+			name:            "Allowed",
+			allowImageBinds: true,
+			userBindControl: true,
+			wantBinds:       1,
+		},
+		{
+			name:            "NoUserBindControl",
+			allowImageBinds: true,
+		},
+		{
+			name:            "NoMount",
+			allowImageBinds: true,
+			userBindControl: true,
+			noMount:         []string{"image-binds"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLauncher(t, true)
+			l.engineConfig.File.AllowImageBinds = tt.allowImageBinds
+			l.engineConfig.File.UserBindControl = tt.userBindControl
+			l.cfg.NoMount = tt.noMount
+			l.engineConfig.SetImage(image)
+
+			binds, err := l.imageBinds()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(binds) != tt.wantBinds {
+				t.Fatalf("got binds %v, want %d binds", binds, tt.wantBinds)
+			}
+			if tt.wantBinds > 0 && (binds[0].Source != source || binds[0].Destination != "/data") {
+				t.Errorf("unexpected bind %s:%s", binds[0].Source, binds[0].Destination)
+			}
+		})
+	}
+}
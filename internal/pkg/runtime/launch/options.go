@@ -20,7 +20,13 @@
 package launch
 
 import (
+	"fmt"
+	"path/filepath"
+
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci/generate"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs/files"
+	"github.com/apptainer/apptainer/internal/pkg/util/sched"
+	"github.com/apptainer/apptainer/internal/pkg/util/shell"
 	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
 	"github.com/apptainer/apptainer/pkg/util/cryptkey"
 )
@@ -35,6 +41,27 @@ type launchOptions struct {
 	WritableTmpfs bool
 	// OverlayPaths holds paths to image or directory overlays to be applied.
 	OverlayPaths []string
+	// WritableOverlaySize is the size of a temporary writable overlay image
+	// applied to the container and deleted once it exits.
+	WritableOverlaySize string
+	// OverlayVerify requires overlay images to carry a valid signature.
+	OverlayVerify bool
+	// OverlayWarnFree is the free space of the writable ext3 overlay below which a warning is displayed.
+	OverlayWarnFree string
+	// SessionFs is the memory filesystem type of the session directory.
+	SessionFs string
+	// SessionSize is the size of the session directory.
+	SessionSize string
+	// OCILayers holds unpacked OCI layer directories stacked on top of the container image.
+	OCILayers []string
+	// ImageMountOpts holds access time mount options applied to ext3 and sandbox rootfs / overlay images.
+	ImageMountOpts []string
+	// SquashfsMountOpts holds key=value mount options passed to squashfs rootfs / data image mounts.
+	SquashfsMountOpts []string
+	// OverlayOpts holds kernel overlay features enabled for the container overlay.
+	OverlayOpts []string
+	// ImageDriver is the image driver used instead of the one set in apptainer.conf.
+	ImageDriver string
 	// Scratchdir lists paths into the container to be mounted from a temporary location on the host.
 	ScratchDirs []string
 	// WorkDir is the parent path for scratch directories, and contained home/tmp on the host.
@@ -47,15 +74,36 @@ type launchOptions struct {
 	CustomHome bool
 	// NoHome disables automatic mounting of the home directory into the container.
 	NoHome bool
+	// HomeKeepPath mounts the home directory at its original path instead of /root with fakeroot.
+	HomeKeepPath bool
 
 	// BindPaths lists paths to bind from host to container, which may be <src>:<dest> pairs.
 	BindPaths []string
+	// EnvBindPaths lists paths to bind set by the APPTAINER_BIND/APPTAINER_BINDPATH
+	// environment variables, overridden by BindPaths and Mounts on destination conflict.
+	EnvBindPaths []string
+	// BindFiles lists files containing bind path specifications, one per line.
+	BindFiles []string
+	// StrictBinds makes a bind which can't be mounted a fatal error instead of being skipped.
+	StrictBinds bool
 	// FuseMount lists paths to be mounted into the container using a FUSE binary, and their options.
 	FuseMount []string
 	// Mounts lists paths to bind from host to container, from the docker compatible `--mount` flag (CSV format).
 	Mounts []string
+	// OverlayBinds lists host directories to mount with a writable overlay, in
+	// lower=<dir>[,upper=tmpfs][,target=<dest>] format.
+	OverlayBinds []string
+	// MountFrom lists paths of running instances to mount into the container,
+	// in instance://<name>:<src>[:<dest>] format.
+	MountFrom []string
 	// NoMount is a list of automatic / configured mounts to disable.
 	NoMount []string
+	// CwdReadOnly mounts the current working directory read-only into the container.
+	CwdReadOnly bool
+	// NoTTYConsole disables the bind of the terminal at /dev/console with a minimal /dev.
+	NoTTYConsole bool
+	// BindCgroupfs binds the host /sys/fs/cgroup read-only into the container.
+	BindCgroupfs bool
 
 	// Nvidia enables NVIDIA GPU support.
 	Nvidia bool
@@ -67,6 +115,8 @@ type launchOptions struct {
 	Rocm bool
 	// NoRocm disable Rocm GPU support when set default in apptainer.conf.
 	NoRocm bool
+	// DRI enables vendor neutral GPU support through DRM render nodes.
+	DRI bool
 
 	// ContainLibs lists paths of libraries to bind mount into the container .singularity.d/libs dir.
 	ContainLibs []string
@@ -77,6 +127,8 @@ type launchOptions struct {
 	EnvFiles []string
 	// CleanEnv starts the container with a clean environment, excluding host env vars.
 	CleanEnv bool
+	// KeepEnv lists host env vars kept in the container with a clean environment.
+	KeepEnv []string
 	// NoEval instructs Apptainer not to shell evaluate args and env vars.
 	NoEval bool
 
@@ -91,15 +143,27 @@ type launchOptions struct {
 	Network string
 	// NetworkArgs are argument to pass to the CNI plugin that will configure networking when Network is set.
 	NetworkArgs []string
+	// NetworkRetries is the number of times network setup is retried on
+	// transient CNI plugin errors, -1 uses the configuration value.
+	NetworkRetries int
 	// Hostname is the hostname to set in the container (infers/requires UTS namespace).
 	Hostname string
 	// DNS is the comma separated list of DNS servers to be set in the container's resolv.conf.
 	DNS string
+	// DNSSearch is the list of search domains to be set in the container's resolv.conf.
+	DNSSearch []string
+	// DNSOptions is the list of resolver options to be set in the container's resolv.conf.
+	DNSOptions []string
+	// Nsswitch stages a minimal /etc/nsswitch.conf resolving users, groups and hosts from local files.
+	Nsswitch bool
 
 	// AddCaps is the list of capabilities to Add to the container process.
 	AddCaps string
 	// DropCaps is the list of capabilities to drop from the container process.
 	DropCaps string
+	// FakerootCaps is the list of capabilities set within the fakeroot
+	// user namespace, in place of all capabilities.
+	FakerootCaps string
 	// AllowSUID permits setuid executables inside a container started by the root user.
 	AllowSUID bool
 	// KeepPrivs keeps all privileges inside a container started by the root user.
@@ -108,6 +172,12 @@ type launchOptions struct {
 	NoPrivs bool
 	// SecurityOpts is the list of security options (selinux, apparmor, seccomp) to apply.
 	SecurityOpts []string
+	// TraceSyscalls is the path of a file where the system calls of the container process are traced.
+	TraceSyscalls string
+	// SchedPolicy is the CPU scheduling policy of the container process, in <policy>[:<priority>] format.
+	SchedPolicy string
+	// Nice is the nice value of the container process, inherited if nil.
+	Nice *int
 	// NoUmask disables propagation of the host umask into the container, using a default 0022.
 	NoUmask bool
 
@@ -124,6 +194,13 @@ type launchOptions struct {
 
 	// Fakeroot enables the fake root mode, using user namespaces and subuid / subgid mapping.
 	Fakeroot bool
+	// KeepID runs the container in a user namespace where the user keeps
+	// their own UID/GID, with other IDs mapped from subuid / subgid ranges.
+	KeepID bool
+	// PasswdEntry overrides the user entry generated in the container passwd file, in name:uid:gid:gecos:home:shell format.
+	PasswdEntry string
+	// GroupEntry overrides the group entry generated in the container group file, in name:gid format.
+	GroupEntry string
 	// Boot enables execution of /sbin/init on startup of an instance container.
 	Boot bool
 	// NoInit disables shim process when PID namespace is used.
@@ -154,10 +231,38 @@ type launchOptions struct {
 	IgnoreUserns      bool
 	UseBuildConfig    bool
 	TmpDir            string
-	Underlay          bool   // whether prefer underlay over overlay
-	ShareNSMode       bool   // whether running in sharens mode
-	ShareNSFd         int    // fd opened in sharens mode
-	RunscriptTimeout  string // runscript timeout
+	Underlay          bool     // whether prefer underlay over overlay
+	ShareNSMode       bool     // whether running in sharens mode
+	ShareNSFd         int      // fd opened in sharens mode
+	RunscriptTimeout  string   // runscript timeout
+	Entrypoint        string   // command executed in place of the runscript
+	Wrap              []string // wrapper command prepended to the container process
+
+	// NoAutofsWorkaround disables the file descriptors kept open on bind
+	// sources located under autofs mount points.
+	NoAutofsWorkaround bool
+
+	// DumpOciSpec is the path where the OCI runtime spec assembled for
+	// the container is written, "-" for the standard output.
+	DumpOciSpec string
+	// DryRun prepares the container configuration without starting the
+	// container.
+	DryRun bool
+	// DryRunMounts prints the container mount plan without starting the
+	// container.
+	DryRunMounts bool
+	// PrintCaps prints the capability sets of the container process
+	// without starting the container.
+	PrintCaps bool
+
+	// LoopMode selects how squashfs image partitions are mounted, one
+	// of apptainerConfig.LoopModeKernel or apptainerConfig.LoopModeFuse.
+	LoopMode string
+
+	// OverlayPrecedence selects which read-only overlays take precedence,
+	// one of apptainerConfig.OverlayPrecedenceLast or
+	// apptainerConfig.OverlayPrecedenceFirst.
+	OverlayPrecedence string
 }
 
 type Launcher struct {
@@ -176,8 +281,14 @@ type Namespaces struct {
 	PID  bool
 	IPC  bool
 	Net  bool
+	// Cgroup creates a cgroup namespace, with a cgroup filesystem mounted on /sys/fs/cgroup.
+	Cgroup bool
 	// NoPID will force the PID namespace not to be used, even if set by default / other flags.
 	NoPID bool
+	// PIDInstance is the name of a running instance whose PID namespace will be joined.
+	PIDInstance string
+	// IPCInstance is the name of a running instance whose IPC namespace will be joined.
+	IPCInstance string
 }
 
 type Option func(co *launchOptions) error
@@ -198,6 +309,15 @@ func OptWritableTmpfs(b bool) Option {
 	}
 }
 
+// OptWritableOverlaySize sets the size of a temporary writable overlay image,
+// in a format like 512M or 2G, applied to the container and deleted on exit.
+func OptWritableOverlaySize(size string) Option {
+	return func(lo *launchOptions) error {
+		lo.WritableOverlaySize = size
+		return nil
+	}
+}
+
 // OptOverlayPaths sets overlay images and directories to apply to the container.
 func OptOverlayPaths(op []string) Option {
 	return func(lo *launchOptions) error {
@@ -206,6 +326,84 @@ func OptOverlayPaths(op []string) Option {
 	}
 }
 
+// OptOverlayVerify requires overlay images to carry a valid signature from
+// a key of the global keyring.
+func OptOverlayVerify(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.OverlayVerify = b
+		return nil
+	}
+}
+
+// OptOverlayWarnFree sets the free space, in a format like 100M or 1G, of
+// the writable ext3 overlay below which a warning is displayed.
+func OptOverlayWarnFree(size string) Option {
+	return func(lo *launchOptions) error {
+		lo.OverlayWarnFree = size
+		return nil
+	}
+}
+
+// OptSessionFs sets the memory filesystem type, tmpfs or ramfs, of the
+// session directory.
+func OptSessionFs(fsType string) Option {
+	return func(lo *launchOptions) error {
+		lo.SessionFs = fsType
+		return nil
+	}
+}
+
+// OptSessionSize sets the size, in a format like 256M or 1G, of the
+// session directory.
+func OptSessionSize(size string) Option {
+	return func(lo *launchOptions) error {
+		lo.SessionSize = size
+		return nil
+	}
+}
+
+// OptOCILayers sets unpacked OCI layer directories, ordered from the bottom
+// to the top layer, stacked as read-only overlay lower directories on top of
+// the container image.
+func OptOCILayers(layers []string) Option {
+	return func(lo *launchOptions) error {
+		lo.OCILayers = layers
+		return nil
+	}
+}
+
+// OptSquashfsMountOpts sets key=value mount options passed to squashfs rootfs / data image mounts.
+func OptSquashfsMountOpts(o []string) Option {
+	return func(lo *launchOptions) error {
+		lo.SquashfsMountOpts = o
+		return nil
+	}
+}
+
+// OptOverlayOpts sets kernel overlay features (metacopy, redirect_dir) enabled for the container overlay.
+func OptOverlayOpts(o []string) Option {
+	return func(lo *launchOptions) error {
+		lo.OverlayOpts = o
+		return nil
+	}
+}
+
+// OptImageDriver sets the image driver used instead of the one set in apptainer.conf.
+func OptImageDriver(name string) Option {
+	return func(lo *launchOptions) error {
+		lo.ImageDriver = name
+		return nil
+	}
+}
+
+// OptImageMountOpts sets access time mount options applied to ext3 and sandbox rootfs / overlay images.
+func OptImageMountOpts(o []string) Option {
+	return func(lo *launchOptions) error {
+		lo.ImageMountOpts = o
+		return nil
+	}
+}
+
 // OptScratchDirs sets temporary host directories to create and bind into the container.
 func OptScratchDirs(sd []string) Option {
 	return func(lo *launchOptions) error {
@@ -236,6 +434,15 @@ func OptHome(homeDir string, custom bool, disable bool) Option {
 	}
 }
 
+// OptHomeKeepPath mounts the home directory at its original path instead
+// of /root when running with fakeroot.
+func OptHomeKeepPath(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.HomeKeepPath = b
+		return nil
+	}
+}
+
 // OptMounts sets user-requested mounts to propagate into the container.
 //
 // binds lists bind mount specifications in Apptainer's <src>:<dst>[:<opts>] format.
@@ -250,6 +457,62 @@ func OptMounts(binds []string, mounts []string, fuseMounts []string) Option {
 	}
 }
 
+// OptEnvBindPaths sets bind mount specifications read from the
+// APPTAINER_BIND/APPTAINER_BINDPATH environment variables. They are
+// ignored when noEnvBinds is set.
+func OptEnvBindPaths(binds []string, noEnvBinds bool) Option {
+	return func(lo *launchOptions) error {
+		if !noEnvBinds {
+			lo.EnvBindPaths = binds
+		}
+		return nil
+	}
+}
+
+// OptBindFiles sets files containing bind path specifications, one per
+// line in the same <src>:<dst>[:<opts>] format as binds.
+func OptBindFiles(files []string) Option {
+	return func(lo *launchOptions) error {
+		lo.BindFiles = files
+		return nil
+	}
+}
+
+// OptStrictBinds makes a bind which can't be mounted, because its source or
+// destination is missing, a fatal error instead of being skipped.
+func OptStrictBinds(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.StrictBinds = b
+		return nil
+	}
+}
+
+// OptOverlayBinds sets host directories to mount into the container
+// as the lower layer of an overlay with an ephemeral tmpfs upper layer.
+func OptOverlayBinds(specs []string) Option {
+	return func(lo *launchOptions) error {
+		lo.OverlayBinds = specs
+		return nil
+	}
+}
+
+// OptMountFrom sets paths of running instances to mount into the
+// container, the instances must outlive the container.
+func OptMountFrom(specs []string) Option {
+	return func(lo *launchOptions) error {
+		lo.MountFrom = specs
+		return nil
+	}
+}
+
+// OptBindCgroupfs binds the host /sys/fs/cgroup read-only into the container.
+func OptBindCgroupfs(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.BindCgroupfs = b
+		return nil
+	}
+}
+
 // OptNoMount disables the specified bind mounts.
 func OptNoMount(nm []string) Option {
 	return func(lo *launchOptions) error {
@@ -258,6 +521,22 @@ func OptNoMount(nm []string) Option {
 	}
 }
 
+// OptCwdReadOnly mounts the current working directory read-only.
+func OptCwdReadOnly(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.CwdReadOnly = b
+		return nil
+	}
+}
+
+// OptNoTTYConsole disables the bind of the terminal at /dev/console.
+func OptNoTTYConsole(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.NoTTYConsole = b
+		return nil
+	}
+}
+
 // OptNvidia enables NVIDIA GPU support.
 //
 // nvccli sets whether to use the nvidia-container-runtime (true), or legacy bind mounts (false).
@@ -285,6 +564,14 @@ func OptRocm(b bool) Option {
 	}
 }
 
+// OptDRI enables GPU support through DRM card and render nodes.
+func OptDRI(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.DRI = b
+		return nil
+	}
+}
+
 // OptNoRocm disables Rocm GPU support, even if enabled via apptainer.conf.
 func OptNoRocm(b bool) Option {
 	return func(lo *launchOptions) error {
@@ -315,6 +602,15 @@ func OptEnv(env map[string]string, envFiles []string, clean bool) Option {
 	}
 }
 
+// OptKeepEnv sets host environment variables kept in the container
+// environment when it is cleaned.
+func OptKeepEnv(keep []string) Option {
+	return func(lo *launchOptions) error {
+		lo.KeepEnv = keep
+		return nil
+	}
+}
+
 // OptNoEval disables shell evaluation of args and env vars.
 func OptNoEval(b bool) Option {
 	return func(lo *launchOptions) error {
@@ -351,6 +647,19 @@ func OptNetwork(network string, args []string) Option {
 	}
 }
 
+// OptNetworkRetries sets the number of times network setup is retried on
+// transient CNI plugin errors, -1 uses the configuration value and 0
+// disables retries.
+func OptNetworkRetries(n int) Option {
+	return func(lo *launchOptions) error {
+		if n < -1 {
+			return fmt.Errorf("network retries must be a positive number, or -1 to use the configuration value")
+		}
+		lo.NetworkRetries = n
+		return nil
+	}
+}
+
 // OptHostname sets a hostname for the container (infers/requires UTS namespace).
 func OptHostname(h string) Option {
 	return func(lo *launchOptions) error {
@@ -367,6 +676,24 @@ func OptDNS(d string) Option {
 	}
 }
 
+// OptDNSSearch sets the search domains and resolver options for the
+// container resolv.conf.
+func OptDNSSearch(search []string, options []string) Option {
+	return func(lo *launchOptions) error {
+		lo.DNSSearch = search
+		lo.DNSOptions = options
+		return nil
+	}
+}
+
+// OptNsswitch stages a minimal /etc/nsswitch.conf resolving users, groups and hosts from local files.
+func OptNsswitch(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.Nsswitch = b
+		return nil
+	}
+}
+
 // OptCaps sets capabilities to add and drop.
 func OptCaps(add, drop string) Option {
 	return func(lo *launchOptions) error {
@@ -376,6 +703,15 @@ func OptCaps(add, drop string) Option {
 	}
 }
 
+// OptFakerootCaps sets the capabilities set within the fakeroot user
+// namespace, in place of all capabilities.
+func OptFakerootCaps(caps string) Option {
+	return func(lo *launchOptions) error {
+		lo.FakerootCaps = caps
+		return nil
+	}
+}
+
 // OptAllowSUID permits setuid executables inside a container started by the root user.
 func OptAllowSUID(b bool) Option {
 	return func(lo *launchOptions) error {
@@ -408,6 +744,35 @@ func OptSecurity(s []string) Option {
 	}
 }
 
+// OptTraceSyscalls traces the system calls of the container process to the file at path.
+func OptTraceSyscalls(path string) Option {
+	return func(lo *launchOptions) error {
+		lo.TraceSyscalls = path
+		return nil
+	}
+}
+
+// OptScheduling sets the CPU scheduling policy and the nice value of the
+// container process, inherited by its children. The nice value is only
+// applied when setNice is true.
+func OptScheduling(policy string, nice int, setNice bool) Option {
+	return func(lo *launchOptions) error {
+		if policy != "" {
+			if _, err := sched.ParsePolicy(policy); err != nil {
+				return err
+			}
+		}
+		lo.SchedPolicy = policy
+		if setNice {
+			if err := sched.CheckNice(nice); err != nil {
+				return err
+			}
+			lo.Nice = &nice
+		}
+		return nil
+	}
+}
+
 // OptNoUmask disables propagation of the host umask into the container, using a default 0022.
 func OptNoUmask(b bool) Option {
 	return func(lo *launchOptions) error {
@@ -456,6 +821,37 @@ func OptFakeroot(b bool) Option {
 	}
 }
 
+// OptKeepID runs the container in a user namespace where the user keeps
+// their own UID/GID, with other IDs mapped from subuid / subgid ranges.
+func OptKeepID(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.KeepID = b
+		return nil
+	}
+}
+
+// OptIdentityEntries overrides the user entry generated in the container
+// passwd file with passwd, in name:uid:gid:gecos:home:shell format, and the
+// group entry generated in the container group file with group, in name:gid
+// format.
+func OptIdentityEntries(passwd, group string) Option {
+	return func(lo *launchOptions) error {
+		if passwd != "" {
+			if _, err := files.ParsePasswdEntry(passwd); err != nil {
+				return err
+			}
+		}
+		if group != "" {
+			if _, err := files.ParseGroupEntry(group); err != nil {
+				return err
+			}
+		}
+		lo.PasswdEntry = passwd
+		lo.GroupEntry = group
+		return nil
+	}
+}
+
 // OptBoot enables execution of /sbin/init on startup of an instance container.
 func OptBoot(b bool) Option {
 	return func(lo *launchOptions) error {
@@ -568,7 +964,9 @@ func OptUseBuildConfig(b bool) Option {
 	}
 }
 
-// OptTmpDir
+// OptTmpDir sets the directory where temporary files, like the sandbox
+// extracted from an image, are created instead of the system temporary
+// directory.
 func OptTmpDir(a string) Option {
 	return func(lo *launchOptions) error {
 		lo.TmpDir = a
@@ -607,3 +1005,110 @@ func OptRunscriptTimeout(timeout string) Option {
 		return nil
 	}
 }
+
+// OptEntrypoint sets a command executed in place of the runscript
+// by the run action, after the container environment is sourced.
+func OptEntrypoint(entrypoint string) Option {
+	return func(lo *launchOptions) error {
+		lo.Entrypoint = entrypoint
+		return nil
+	}
+}
+
+// OptWrap sets a wrapper command, split following the shell quoting rules,
+// prepended to the container process arguments. The wrapper is resolved
+// within the container, after the container environment is sourced.
+func OptWrap(wrap string) Option {
+	return func(lo *launchOptions) error {
+		if wrap == "" {
+			lo.Wrap = nil
+			return nil
+		}
+		args, err := shell.Fields(wrap)
+		if err != nil {
+			return fmt.Errorf("invalid wrapper command: %s", err)
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("invalid wrapper command: %q is empty", wrap)
+		}
+		lo.Wrap = args
+		return nil
+	}
+}
+
+// OptNoAutofsWorkaround disables the file descriptors kept open on bind
+// sources located under autofs mount points to prevent them from being
+// unmounted before the container setup.
+func OptNoAutofsWorkaround(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.NoAutofsWorkaround = b
+		return nil
+	}
+}
+
+// OptLoopMode sets how squashfs image partitions are mounted, with kernel
+// loop devices (kernel) or with the FUSE image driver when available (fuse).
+func OptLoopMode(mode string) Option {
+	return func(lo *launchOptions) error {
+		switch mode {
+		case "", apptainerConfig.LoopModeKernel, apptainerConfig.LoopModeFuse:
+		default:
+			return fmt.Errorf("invalid --loop mode %q, must be %q or %q", mode, apptainerConfig.LoopModeKernel, apptainerConfig.LoopModeFuse)
+		}
+		lo.LoopMode = mode
+		return nil
+	}
+}
+
+// OptOverlayPrecedence sets which read-only overlays take precedence when
+// they provide the same file, the last ones (last) or the first ones (first).
+func OptOverlayPrecedence(precedence string) Option {
+	return func(lo *launchOptions) error {
+		switch precedence {
+		case "", apptainerConfig.OverlayPrecedenceLast, apptainerConfig.OverlayPrecedenceFirst:
+		default:
+			return fmt.Errorf("invalid --overlay-precedence %q, must be %q or %q", precedence, apptainerConfig.OverlayPrecedenceLast, apptainerConfig.OverlayPrecedenceFirst)
+		}
+		lo.OverlayPrecedence = precedence
+		return nil
+	}
+}
+
+// OptDumpOciSpec sets the path where the OCI runtime spec assembled for the
+// container is written, "-" for the standard output. With dryRun the
+// container is not started once the spec is written.
+func OptDumpOciSpec(path string, dryRun bool) Option {
+	return func(lo *launchOptions) error {
+		if dryRun && path == "" {
+			return fmt.Errorf("--dry-run requires --dump-oci-spec")
+		}
+		if path != "" && path != "-" {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("while resolving OCI spec path %s: %s", path, err)
+			}
+			path = abs
+		}
+		lo.DumpOciSpec = path
+		lo.DryRun = dryRun
+		return nil
+	}
+}
+
+// OptDryRunMounts prints the mount points of the container without
+// mounting them instead of starting the container.
+func OptDryRunMounts(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.DryRunMounts = b
+		return nil
+	}
+}
+
+// OptPrintCaps prints the capability sets resolved for the container
+// process instead of starting the container.
+func OptPrintCaps(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.PrintCaps = b
+		return nil
+	}
+}
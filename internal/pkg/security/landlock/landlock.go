@@ -0,0 +1,156 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package landlock
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Rule grants access to a path and everything beneath it.
+type Rule struct {
+	Path     string
+	Writable bool
+}
+
+const (
+	// read-only access rights
+	accessRead = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR
+
+	// access rights applying to regular files, any other
+	// right is only valid for directories
+	accessFile = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_TRUNCATE |
+		unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+)
+
+// handledAccess maps Landlock ABI versions to the filesystem access
+// rights they handle.
+var handledAccess = []uint64{
+	1: 0x1fff,
+	2: 0x1fff | unix.LANDLOCK_ACCESS_FS_REFER,
+	3: 0x1fff | unix.LANDLOCK_ACCESS_FS_REFER | unix.LANDLOCK_ACCESS_FS_TRUNCATE,
+	4: 0x1fff | unix.LANDLOCK_ACCESS_FS_REFER | unix.LANDLOCK_ACCESS_FS_TRUNCATE,
+	5: 0x1fff | unix.LANDLOCK_ACCESS_FS_REFER | unix.LANDLOCK_ACCESS_FS_TRUNCATE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV,
+}
+
+// ParseRules parses Landlock rules formatted as <ro|rw>:<path>.
+func ParseRules(params []string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(params))
+	for _, param := range params {
+		access, path, ok := strings.Cut(param, ":")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("bad format for landlock rule %q (format is <ro|rw>:<path>)", param)
+		}
+		if !filepath.IsAbs(path) {
+			return nil, fmt.Errorf("landlock rule path %s must be absolute", path)
+		}
+		rule := Rule{Path: filepath.Clean(path)}
+		switch access {
+		case "ro":
+		case "rw":
+			rule.Writable = true
+		default:
+			return nil, fmt.Errorf("unknown access %q for landlock rule %q, must be ro or rw", access, param)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// abiVersion returns the highest Landlock ABI version supported by the
+// kernel, or 0 if Landlock is not supported or not enabled.
+func abiVersion() int {
+	v, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return 0
+	}
+	return int(v)
+}
+
+// Enabled returns whether Landlock is supported and enabled by the kernel.
+func Enabled() bool {
+	return abiVersion() > 0
+}
+
+// Apply restricts the filesystem access of the current thread, and of
+// the processes it executes, to the paths allowed by rules. All rule
+// paths are resolved before the restriction is enforced, the no new
+// privileges flag is set as required by Landlock.
+func Apply(rules []Rule) error {
+	abi := abiVersion()
+	if abi <= 0 {
+		return fmt.Errorf("landlock is not supported or not enabled by the kernel")
+	}
+	if abi >= len(handledAccess) {
+		abi = len(handledAccess) - 1
+	}
+	handled := handledAccess[abi]
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	// only pass the filesystem access field, supported by all ABI versions
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr.Access_fs), 0)
+	if errno != 0 {
+		return fmt.Errorf("while creating landlock ruleset: %s", errno)
+	}
+	defer unix.Close(int(fd))
+
+	for _, rule := range rules {
+		if err := addRule(int(fd), rule, handled); err != nil {
+			return err
+		}
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("while setting no new privileges flag: %s", err)
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("while enforcing landlock ruleset: %s", errno)
+	}
+	return nil
+}
+
+func addRule(rulesetFd int, rule Rule, handled uint64) error {
+	pathFd, err := unix.Open(rule.Path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("while opening landlock rule path %s: %s", rule.Path, err)
+	}
+	defer unix.Close(pathFd)
+
+	var st unix.Stat_t
+	if err := unix.Fstat(pathFd, &st); err != nil {
+		return fmt.Errorf("while getting information for %s: %s", rule.Path, err)
+	}
+
+	access := uint64(accessRead)
+	if rule.Writable {
+		access = handled
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= accessFile
+	}
+
+	attr := unix.LandlockPathBeneathAttr{
+		Allowed_access: access & handled,
+		Parent_fd:      int32(pathFd),
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(rulesetFd), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&attr)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("while adding landlock rule for %s: %s", rule.Path, errno)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package landlock
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  []string
+		rules   []Rule
+		wantErr bool
+	}{
+		{
+			name:   "ReadOnlyAndWritable",
+			params: []string{"ro:/", "rw:/tmp/"},
+			rules:  []Rule{{Path: "/"}, {Path: "/tmp", Writable: true}},
+		},
+		{
+			name:    "MissingPath",
+			params:  []string{"ro:"},
+			wantErr: true,
+		},
+		{
+			name:    "MissingAccess",
+			params:  []string{"/tmp"},
+			wantErr: true,
+		},
+		{
+			name:    "UnknownAccess",
+			params:  []string{"rx:/tmp"},
+			wantErr: true,
+		},
+		{
+			name:    "RelativePath",
+			params:  []string{"rw:tmp"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseRules(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(rules, tt.rules) {
+				t.Errorf("unexpected rules: %v instead of %v", rules, tt.rules)
+			}
+		})
+	}
+}
+
+// landlockTestDir is set when the test binary is executed to apply
+// rules and check access in a separate process.
+const landlockTestDir = "LANDLOCK_TEST_DIR"
+
+func TestApply(t *testing.T) {
+	if dir := os.Getenv(landlockTestDir); dir != "" {
+		applyAndWrite(t, dir)
+		return
+	}
+	if !Enabled() {
+		t.Skip("landlock not supported or not enabled by the kernel")
+	}
+
+	dir := t.TempDir()
+	for _, d := range []string{"ro", "rw"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestApply$", "-test.v")
+	cmd.Env = append(os.Environ(), landlockTestDir+"="+dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("unexpected failure: %s\n%s", err, out)
+	}
+}
+
+// applyAndWrite restricts writes to the rw subdirectory of dir and checks
+// that writes are denied in the ro subdirectory.
+func applyAndWrite(t *testing.T, dir string) {
+	runtime.LockOSThread()
+
+	rules := []Rule{
+		{Path: "/"},
+		{Path: filepath.Join(dir, "rw"), Writable: true},
+	}
+	if err := Apply(rules); err != nil {
+		t.Fatalf("while applying rules: %s", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "rw", "file"), []byte("test"), 0o644); err != nil {
+		t.Errorf("unexpected write failure in writable directory: %s", err)
+	}
+	err := os.WriteFile(filepath.Join(dir, "ro", "file"), []byte("test"), 0o644)
+	if !errors.Is(err, os.ErrPermission) {
+		t.Errorf("unexpected write result in read-only directory: %v", err)
+	}
+	if _, err := os.ReadDir(filepath.Join(dir, "ro")); err != nil {
+		t.Errorf("unexpected read failure in read-only directory: %s", err)
+	}
+}
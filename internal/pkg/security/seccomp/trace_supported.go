@@ -0,0 +1,166 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+//go:build seccomp
+
+package seccomp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	lseccomp "github.com/seccomp/libseccomp-golang"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// highest system call number probed when building the trace filter
+	maxSyscallNr = 1024
+	// interval between checks for the trace filter listener
+	tracePollInterval = 10 * time.Millisecond
+	// link target of a seccomp notification listener in /proc/<pid>/fd
+	listenerLink = "anon_inode:seccomp notify"
+)
+
+// TraceSupported returns an error if system call tracing with seccomp
+// user notifications is not supported by libseccomp or the kernel.
+func TraceSupported() error {
+	api, err := lseccomp.GetAPI()
+	if err != nil {
+		return fmt.Errorf("could not determine seccomp API level: %s", err)
+	}
+	// API level 6 provides user notifications with the continue flag
+	if api < 6 {
+		return fmt.Errorf("seccomp user notifications are not supported (API level %d, requires 6)", api)
+	}
+	return nil
+}
+
+// LoadTraceFilter loads a seccomp filter reporting every system call
+// of the current thread, and of the processes it executes, to a user
+// notification listener which is expected to be handled by Trace in
+// another process. System calls are blocked until Trace responds.
+func LoadTraceFilter() error {
+	filter, err := lseccomp.NewFilter(lseccomp.ActAllow)
+	if err != nil {
+		return fmt.Errorf("error creating new filter: %s", err)
+	}
+	defer filter.Release()
+
+	// the notify action can't be the filter default action
+	for nr := 0; nr < maxSyscallNr; nr++ {
+		sc := lseccomp.ScmpSyscall(nr)
+		if _, err := sc.GetName(); err != nil {
+			continue
+		}
+		if err := filter.AddRule(sc, lseccomp.ActNotify); err != nil && !isUnrecognizedSyscall(err) {
+			return fmt.Errorf("failed adding trace rule for syscall %d: %s", nr, err)
+		}
+	}
+
+	if err := filter.Load(); err != nil {
+		return fmt.Errorf("failed loading seccomp trace filter: %s", err)
+	}
+	return nil
+}
+
+// Trace waits for the filter loaded with LoadTraceFilter by the process
+// pid and writes a line to w for each system call reported, until the
+// process and its children exit.
+func Trace(pid int, w io.Writer) error {
+	pidfd, err := unix.PidfdOpen(pid, 0)
+	if err != nil {
+		return fmt.Errorf("while opening process %d: %s", pid, err)
+	}
+	defer unix.Close(pidfd)
+
+	fd, err := findListener(pid)
+	if err != nil {
+		return err
+	} else if fd < 0 {
+		return nil
+	}
+
+	listener, err := unix.PidfdGetfd(pidfd, fd, 0)
+	if err != nil {
+		return fmt.Errorf("while getting seccomp listener of process %d: %s", pid, err)
+	}
+	defer unix.Close(listener)
+
+	notifFd := lseccomp.ScmpFd(listener)
+	pfd := []unix.PollFd{{Fd: int32(listener), Events: unix.POLLIN}}
+
+	for {
+		if _, err := unix.Poll(pfd, -1); err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return fmt.Errorf("while waiting seccomp notification: %s", err)
+		}
+		// all traced processes exited
+		if pfd[0].Revents&unix.POLLHUP != 0 {
+			return nil
+		}
+
+		req, err := lseccomp.NotifReceive(notifFd)
+		if errors.Is(err, unix.ENOENT) {
+			// the process was interrupted before the notification was received
+			continue
+		} else if err != nil {
+			return fmt.Errorf("while receiving seccomp notification: %s", err)
+		}
+
+		name, err := req.Data.Syscall.GetNameByArch(req.Data.Arch)
+		if err != nil {
+			name = strconv.Itoa(int(req.Data.Syscall))
+		}
+		args := make([]string, len(req.Data.Args))
+		for i, arg := range req.Data.Args {
+			args[i] = fmt.Sprintf("%#x", arg)
+		}
+		fmt.Fprintf(w, "%s %d %s(%s)\n", time.Now().Format("15:04:05.000000"), req.Pid, name, strings.Join(args, ", "))
+
+		resp := &lseccomp.ScmpNotifResp{
+			ID:    req.ID,
+			Flags: lseccomp.NotifRespFlagContinue,
+		}
+		if err := lseccomp.NotifRespond(notifFd, resp); err != nil && !errors.Is(err, unix.ENOENT) {
+			return fmt.Errorf("while responding to seccomp notification: %s", err)
+		}
+	}
+}
+
+// findListener returns the file descriptor number of the seccomp
+// notification listener opened by the process pid, it returns -1
+// if the process exited before the trace filter was loaded.
+func findListener(pid int) (int, error) {
+	fdDir := fmt.Sprintf("/proc/%d/fd", pid)
+
+	for {
+		entries, err := os.ReadDir(fdDir)
+		if errors.Is(err, os.ErrNotExist) {
+			return -1, nil
+		} else if err != nil {
+			return -1, fmt.Errorf("while reading %s: %s", fdDir, err)
+		}
+		for _, e := range entries {
+			target, err := os.Readlink(filepath.Join(fdDir, e.Name()))
+			if err != nil || target != listenerLink {
+				continue
+			}
+			return strconv.Atoi(e.Name())
+		}
+		time.Sleep(tracePollInterval)
+	}
+}
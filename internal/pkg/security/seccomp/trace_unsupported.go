@@ -0,0 +1,34 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+//go:build !seccomp
+
+package seccomp
+
+import (
+	"fmt"
+	"io"
+)
+
+// TraceSupported returns an error if system call tracing with seccomp
+// user notifications is not supported by libseccomp or the kernel.
+func TraceSupported() error {
+	return fmt.Errorf("seccomp support not enabled at compilation time")
+}
+
+// LoadTraceFilter loads a seccomp filter reporting every system call
+// of the current thread to a user notification listener.
+func LoadTraceFilter() error {
+	return fmt.Errorf("can't load seccomp filter: not enabled at compilation time")
+}
+
+// Trace writes the system calls reported by the filter loaded with
+// LoadTraceFilter by the process pid.
+func Trace(_ int, _ io.Writer) error {
+	return fmt.Errorf("can't trace system calls: seccomp not enabled at compilation time")
+}
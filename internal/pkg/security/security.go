@@ -14,6 +14,7 @@ import (
 	"strings"
 
 	"github.com/apptainer/apptainer/internal/pkg/security/apparmor"
+	"github.com/apptainer/apptainer/internal/pkg/security/landlock"
 	"github.com/apptainer/apptainer/internal/pkg/security/seccomp"
 	"github.com/apptainer/apptainer/internal/pkg/security/selinux"
 	"github.com/apptainer/apptainer/pkg/sylog"
@@ -56,6 +57,19 @@ func Configure(config *specs.Spec) error {
 	return nil
 }
 
+// ConfigureLandlock applies the landlock rules to current process
+func ConfigureLandlock(params []string) error {
+	rules, err := landlock.ParseRules(params)
+	if err != nil {
+		return err
+	}
+	if !landlock.Enabled() {
+		sylog.Warningf("landlock requested but not supported or not enabled by the kernel")
+		return nil
+	}
+	return landlock.Apply(rules)
+}
+
 // GetParam iterates over security argument and returns parameters
 // for the security feature
 func GetParam(security []string, feature string) string {
@@ -70,3 +84,20 @@ func GetParam(security []string, feature string) string {
 	}
 	return ""
 }
+
+// GetParams iterates over security argument and returns parameters
+// of all occurrences of the security feature
+func GetParams(security []string, feature string) []string {
+	var params []string
+	for _, param := range security {
+		splitted := strings.SplitN(param, ":", 2)
+		if splitted[0] == feature {
+			if len(splitted) != 2 {
+				sylog.Warningf("bad format for parameter %s (format is <security>:<arg>)", param)
+				continue
+			}
+			params = append(params, splitted[1])
+		}
+	}
+	return params
+}
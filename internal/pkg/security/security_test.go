@@ -11,6 +11,7 @@ package security
 
 import (
 	"os"
+	"reflect"
 	"runtime"
 	"testing"
 
@@ -51,6 +52,18 @@ func TestGetParam(t *testing.T) {
 	}
 }
 
+func TestGetParams(t *testing.T) {
+	security := []string{"landlock:ro:/", "seccomp:test", "landlock:rw:/tmp", "landlock"}
+
+	params := GetParams(security, "landlock")
+	if !reflect.DeepEqual(params, []string{"ro:/", "rw:/tmp"}) {
+		t.Errorf("unexpected landlock params returned: %v", params)
+	}
+	if params := GetParams(security, "apparmor"); len(params) != 0 {
+		t.Errorf("unexpected apparmor params returned: %v", params)
+	}
+}
+
 func TestConfigure(t *testing.T) {
 	test.EnsurePrivilege(t)
 
@@ -23,6 +23,7 @@ import (
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/buildcfg"
+	"github.com/apptainer/apptainer/internal/pkg/security/landlock"
 	"github.com/apptainer/apptainer/internal/pkg/security/seccomp"
 	"github.com/apptainer/apptainer/internal/pkg/util/rpm"
 	"github.com/apptainer/apptainer/pkg/network"
@@ -243,6 +244,14 @@ func Rocm(t *testing.T) {
 	}
 }
 
+// DRI checks that DRM render nodes are available
+func DRI(t *testing.T) {
+	nodes, err := filepath.Glob("/dev/dri/renderD*")
+	if err != nil || len(nodes) == 0 {
+		t.Skipf("no DRM render node found in /dev/dri")
+	}
+}
+
 // DMTCP checks that a DMTCP stack is available
 func DMTCP(t *testing.T) {
 	_, err := exec.LookPath("dmtcp_launch")
@@ -283,6 +292,23 @@ func Seccomp(t *testing.T) {
 	}
 }
 
+// SeccompNotify checks that seccomp user notifications are supported,
+// as required to trace system calls. If not, the test is skipped with
+// a message.
+func SeccompNotify(t *testing.T) {
+	if err := seccomp.TraceSupported(); err != nil {
+		t.Skipf("seccomp user notifications not supported: %s", err)
+	}
+}
+
+// Landlock checks that Landlock is supported and enabled by the kernel.
+// If not, the test is skipped with a message.
+func Landlock(t *testing.T) {
+	if !landlock.Enabled() {
+		t.Skipf("landlock not supported or not enabled by the kernel")
+	}
+}
+
 // Arch checks the test machine has the specified architecture.
 // If not, the test is skipped with a message.
 func Arch(t *testing.T, arch string) {
@@ -10,6 +10,7 @@
 package env
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci/generate"
@@ -125,7 +126,9 @@ func warnDeprecatedEnvUsage(hostEnvs []string) {
 }
 
 // SetContainerEnv cleans environment variables before running the container.
-func SetContainerEnv(g *generate.Generator, hostEnvs []string, cleanEnv bool, homeDest string) map[string]string {
+// The host environment variables named in keepEnv are forwarded even with a
+// clean environment, unless overridden by a prefixed variable.
+func SetContainerEnv(g *generate.Generator, hostEnvs []string, cleanEnv bool, keepEnv []string, homeDest string) map[string]string {
 	// allow override with APPTAINERENV_LANG
 	if cleanEnv {
 		g.SetProcessEnv("LANG", "C")
@@ -162,7 +165,7 @@ EnvKeys:
 		}
 
 		// non prefixed environment variables
-		if mustAddToHostEnv(e[0], cleanEnv) {
+		if mustAddToHostEnv(e[0], cleanEnv && !slices.Contains(keepEnv, e[0])) {
 			if value, ok := envKeys[e[0]]; ok {
 				if value != e[1] {
 					sylog.Debugf("Environment variable %s already has value [%s], will not forward new value [%s] from parent process environment", e[0], value, e[1])
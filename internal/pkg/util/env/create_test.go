@@ -32,6 +32,7 @@ func TestSetContainerEnv(t *testing.T) {
 	tt := []struct {
 		name            string
 		cleanEnv        bool
+		keepEnv         []string
 		homeDest        string
 		env             []string
 		processEnv      map[string]string
@@ -181,6 +182,37 @@ func TestSetContainerEnv(t *testing.T) {
 				"Not forwarding APPTAINER_NAME environment variable",
 			},
 		},
+		{
+			name:     "keep env",
+			cleanEnv: true,
+			keepEnv:  []string{"PS1", "LC_ALL", "PATH", "UNSET"},
+			homeDest: "/home/tester",
+			env: []string{
+				"HOME=/home/john",
+				"PS1=test",
+				"PATH=/usr/games:/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+				"LANG=en_US.UTF-8",
+				"LC_ALL=C",
+				"APPTAINERENV_LC_ALL=en_US.UTF-8",
+			},
+			resultEnv: []string{
+				"LANG=C",
+				"PS1=test",
+				"HOME=/home/tester",
+				"PATH=" + DefaultPath,
+			},
+			apptainerEnv: map[string]string{
+				"LC_ALL": "en_US.UTF-8",
+			},
+			outputNeeded: []string{
+				"Forwarding PS1 environment variable",
+				"Forwarding APPTAINERENV_LC_ALL as LC_ALL environment variable",
+			},
+			outputNotNeeded: []string{
+				"Forwarding LANG environment variable",
+				"Forwarding LC_ALL environment variable",
+			},
+		},
 		{
 			name:     "always pass keys",
 			cleanEnv: true,
@@ -744,7 +776,7 @@ func TestSetContainerEnv(t *testing.T) {
 					sylog.SetWriter(oldWriter)
 					sylog.SetLevel(oldLevel, true)
 				}()
-				senv = SetContainerEnv(generator, tc.env, tc.cleanEnv, tc.homeDest)
+				senv = SetContainerEnv(generator, tc.env, tc.cleanEnv, tc.keepEnv, tc.homeDest)
 			}()
 			for _, requiredOutput := range tc.outputNeeded {
 				if !strings.Contains(output.String(), requiredOutput) {
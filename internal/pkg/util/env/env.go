@@ -13,6 +13,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/apptainer/apptainer/internal/pkg/util/shell/interpreter"
@@ -129,6 +130,29 @@ func FileMap(ctx context.Context, f string, args []string, hostEnv []string) (ma
 	return envMap, nil
 }
 
+// FilesMap returns a map of KEY=VAL env vars from the environment files,
+// variables set in a file take precedence over those set in the previous
+// files. Each file is evaluated like FileMap, with the variables set by the
+// previous files appended to hostEnv so they can be referenced.
+func FilesMap(ctx context.Context, files []string, args []string, hostEnv []string) (map[string]string, error) {
+	envMap := map[string]string{}
+	env := slices.Clip(hostEnv)
+
+	for _, f := range files {
+		fileMap, err := FileMap(ctx, f, args, env)
+		if err != nil {
+			return envMap, err
+		}
+		sylog.Debugf("Setting environment variables from file %s", f)
+		for k, v := range fileMap {
+			env = append(env, k+"="+v)
+		}
+		envMap = MergeMap(envMap, fileMap)
+	}
+
+	return envMap, nil
+}
+
 // MergeMap merges two maps of environment variables, with values in b replacing
 // values also set in a.
 func MergeMap(a map[string]string, b map[string]string) map[string]string {
@@ -251,3 +251,33 @@ func TestEnvFileMap(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvFilesMap(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	base := filepath.Join(tmpDir, "base")
+	if err := os.WriteFile(base, []byte("# base\nPREFIX=/opt\nBIN=\"$PREFIX/bin\"\nNAME=base\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	project := filepath.Join(tmpDir, "project")
+	if err := os.WriteFile(project, []byte("\nNAME='my project'\nBIN=${BIN}:$HOME/bin\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := FilesMap(context.Background(), []string{base, project}, []string{}, []string{"HOME=/home/user"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := map[string]string{
+		"PREFIX": "/opt",
+		"BIN":    "/opt/bin:/home/user/bin",
+		"NAME":   "my project",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := FilesMap(context.Background(), []string{base, filepath.Join(tmpDir, "missing")}, []string{}, nil); err == nil {
+		t.Errorf("unexpected success with a missing environment file")
+	}
+}
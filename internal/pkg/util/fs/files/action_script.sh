@@ -10,6 +10,7 @@
 
 declare -r __exported_env__=$(getallenv)
 declare -r __apptainer_cmd__=${APPTAINER_COMMAND:-}
+declare -r __apptainer_entrypoint__=${APPTAINER_ENTRYPOINT:-}
 
 if test -n "${SINGULARITY_APPNAME:-}"; then
     readonly SINGULARITY_APPNAME
@@ -66,11 +67,12 @@ restore_env() {
 
     # restore environment variables which haven't been
     # defined by docker or virtual file above, empty
-    # variables are also unset
+    # variables are also unset. Values are ANSI-C quoted
+    # by getallenv and restored exactly by the eval below
     for e in ${__exported_env__}; do
         key=${e%%=*}
         if ! test -v "${key}"; then
-            export "${e//'\u000A'/$IFS}"
+            eval "export ${e}"
         elif test -z "${!key}"; then
             unset "${key}"
         fi
@@ -173,7 +175,10 @@ shell)
     sylog error "/bin/sh does not exist in container"
     exit 1 ;;
 run)
-    if test -n "${SINGULARITY_APPNAME:-}"; then
+    # --entrypoint replaces the runscript, the environment is sourced as usual
+    if test -n "${__apptainer_entrypoint__}"; then
+        exec "${__apptainer_entrypoint__}" "$@"
+    elif test -n "${SINGULARITY_APPNAME:-}"; then
         if test -x "/scif/apps/${SINGULARITY_APPNAME:-}/scif/runscript"; then
             exec "/scif/apps/${SINGULARITY_APPNAME:-}/scif/runscript" "$@"
 		elif test -x "/scif/apps/singularity/scif/test"; then
@@ -12,6 +12,7 @@ package files
 import (
 	"bytes"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/test"
@@ -72,19 +73,88 @@ func TestResolvConf(t *testing.T) {
 	test.DropPrivilege(t)
 	defer test.ResetPrivilege(t)
 
-	_, err := ResolvConf([]string{})
+	_, err := ResolvConf([]string{}, nil, nil)
 	if err == nil {
 		t.Errorf("should have failed with empty dns")
 	}
-	_, err = ResolvConf([]string{"test"})
+	_, err = ResolvConf([]string{"test"}, nil, nil)
 	if err == nil {
 		t.Errorf("should have failed with bad dns")
 	}
-	content, err := ResolvConf([]string{"8.8.8.8"})
+	content, err := ResolvConf([]string{"8.8.8.8"}, nil, nil)
 	if err != nil {
 		t.Errorf("should have passed with valid dns")
 	}
 	if !bytes.Equal(content, []byte("nameserver 8.8.8.8\n")) {
 		t.Errorf("ResolvConf returns a bad content")
 	}
+	_, err = ResolvConf([]string{"8.8.8.8"}, []string{"bad domain"}, nil)
+	if err == nil {
+		t.Errorf("should have failed with bad search domain")
+	}
+	content, err = ResolvConf([]string{"8.8.8.8"}, []string{"example.com", "example.org"}, []string{"ndots:2"})
+	if err != nil {
+		t.Errorf("should have passed with valid search domains and options")
+	}
+	if !bytes.Equal(content, []byte("nameserver 8.8.8.8\nsearch example.com example.org\noptions ndots:2\n")) {
+		t.Errorf("ResolvConf returns a bad content: %q", content)
+	}
+}
+
+func TestParseResolvConf(t *testing.T) {
+	content := "# comment\nnameserver 1.1.1.1\ndomain example.net\nsearch example.com\noptions ndots:5 timeout:1\nnameserver 8.8.8.8\n"
+
+	dns, search, options := ParseResolvConf([]byte(content))
+	if !reflect.DeepEqual(dns, []string{"1.1.1.1", "8.8.8.8"}) {
+		t.Errorf("unexpected nameservers %v", dns)
+	}
+	if !reflect.DeepEqual(search, []string{"example.com"}) {
+		t.Errorf("unexpected search domains %v", search)
+	}
+	if !reflect.DeepEqual(options, []string{"ndots:5", "timeout:1"}) {
+		t.Errorf("unexpected options %v", options)
+	}
+}
+
+func TestIsResolvedStub(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		stub    bool
+	}{
+		{"Empty", "", false},
+		{"Stub", "nameserver 127.0.0.53\noptions edns0 trust-ad\nsearch .\n", true},
+		{"Proxy", "# comment\nnameserver 127.0.0.54\n", true},
+		{"Upstream", "nameserver 192.168.1.1\nnameserver 8.8.8.8\n", false},
+		{"Mixed", "nameserver 127.0.0.53\nnameserver 8.8.8.8\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsResolvedStub([]byte(tt.content)); got != tt.stub {
+				t.Errorf("IsResolvedStub() = %v, want %v", got, tt.stub)
+			}
+		})
+	}
+}
+
+func TestGroupEntry(t *testing.T) {
+	for _, bad := range []string{"", "group", "group:", ":100", "group:100:user", "gr oup:100"} {
+		if _, err := ParseGroupEntry(bad); err == nil {
+			t.Errorf("unexpected success parsing group entry %q", bad)
+		}
+	}
+
+	ge, err := ParseGroupEntry("devs:100")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ge.Name != "devs" || ge.GID != 100 {
+		t.Errorf("unexpected group entry %+v", ge)
+	}
+
+	content := "root:x:0:\nusers:x:100:\nusers:x:100:host\n"
+	want := "root:x:0:\ndevs:x:100:user\n"
+	if got := string(SetGroupEntry([]byte(content), ge, "user")); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
 }
@@ -13,6 +13,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
 	"github.com/apptainer/apptainer/internal/pkg/util/user"
@@ -100,3 +102,38 @@ func Group(path string, uid int, gids []int, customLookup UserGroupLookup) (cont
 	}
 	return content, nil
 }
+
+// GroupEntry is a group entry overriding the one of the same GID generated in
+// the container group file.
+type GroupEntry struct {
+	Name string
+	GID  uint32
+}
+
+// ParseGroupEntry parses a group entry in name:gid format.
+func ParseGroupEntry(entry string) (*GroupEntry, error) {
+	fields := strings.Split(entry, ":")
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("bad group entry %q, format is name:gid", entry)
+	}
+
+	ge := &GroupEntry{Name: fields[0]}
+	var err error
+	if err = checkEntryName(ge.Name); err != nil {
+		return nil, fmt.Errorf("bad group entry %q: %s", entry, err)
+	}
+	if ge.GID, err = parseID("gid", fields[1]); err != nil {
+		return nil, fmt.Errorf("bad group entry %q: %s", entry, err)
+	}
+	return ge, nil
+}
+
+// SetGroupEntry returns the group content with the group entry for the gid
+// of ge replaced by ge with user as member, entries with the same name are
+// removed.
+func SetGroupEntry(content []byte, ge *GroupEntry, user string) []byte {
+	line := fmt.Sprintf("%s:x:%d:%s", ge.Name, ge.GID, user)
+	return setEntry(content, line, func(fields []string) bool {
+		return fields[0] == ge.Name || (len(fields) > 2 && fields[2] == strconv.FormatUint(uint64(ge.GID), 10))
+	})
+}
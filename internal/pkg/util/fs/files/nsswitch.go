@@ -0,0 +1,32 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package files
+
+// nsswitchConf resolves users, groups and hosts from local files only, hosts
+// are also resolved with the DNS servers from resolv.conf.
+const nsswitchConf = `# nsswitch.conf generated by Apptainer
+passwd:     files
+group:      files
+shadow:     files
+gshadow:    files
+hosts:      files dns
+networks:   files
+protocols:  files
+services:   files
+ethers:     files
+rpc:        files
+netgroup:   files
+`
+
+// Nsswitch returns the content of a minimal nsswitch.conf which doesn't
+// reference directory services (sss, ldap, ...) possibly unreachable from
+// the container.
+func Nsswitch() []byte {
+	return []byte(nsswitchConf)
+}
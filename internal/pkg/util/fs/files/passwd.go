@@ -13,6 +13,8 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	pwd "github.com/astromechza/etcpwdparse"
@@ -95,3 +97,106 @@ func Passwd(path string, home string, uid int, customLookup UserGroupLookup) (co
 func makePasswdLine(name string, uid uint32, gid uint32, gecos string, homedir string, shell string) string {
 	return fmt.Sprintf("%s:x:%d:%d:%s:%s:%s", name, uid, gid, gecos, homedir, shell)
 }
+
+// PasswdEntry is a user entry overriding the one generated in the
+// container passwd file.
+type PasswdEntry struct {
+	Name  string
+	UID   uint32
+	GID   uint32
+	Gecos string
+	Home  string
+	Shell string
+}
+
+// checkEntryName returns an error if name is not a valid user or group name.
+func checkEntryName(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty name")
+	}
+	if strings.ContainsAny(name, ": \t\n") {
+		return fmt.Errorf("name %q contains invalid characters", name)
+	}
+	return nil
+}
+
+// parseID parses a user or group ID.
+func parseID(kind, id string) (uint32, error) {
+	v, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", kind, id)
+	}
+	return uint32(v), nil
+}
+
+// ParsePasswdEntry parses a passwd entry in name:uid:gid:gecos:home:shell format.
+func ParsePasswdEntry(entry string) (*PasswdEntry, error) {
+	fields := strings.Split(entry, ":")
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("bad passwd entry %q, format is name:uid:gid:gecos:home:shell", entry)
+	}
+	if strings.Contains(entry, "\n") {
+		return nil, fmt.Errorf("bad passwd entry %q: contains a newline", entry)
+	}
+
+	pe := &PasswdEntry{
+		Name:  fields[0],
+		Gecos: fields[3],
+		Home:  fields[4],
+		Shell: fields[5],
+	}
+	var err error
+	if err = checkEntryName(pe.Name); err != nil {
+		return nil, fmt.Errorf("bad passwd entry %q: %s", entry, err)
+	}
+	if pe.UID, err = parseID("uid", fields[1]); err != nil {
+		return nil, fmt.Errorf("bad passwd entry %q: %s", entry, err)
+	}
+	if pe.GID, err = parseID("gid", fields[2]); err != nil {
+		return nil, fmt.Errorf("bad passwd entry %q: %s", entry, err)
+	}
+	if !filepath.IsAbs(pe.Home) {
+		return nil, fmt.Errorf("bad passwd entry %q: home directory %q must be an absolute path", entry, pe.Home)
+	}
+	if !filepath.IsAbs(pe.Shell) {
+		return nil, fmt.Errorf("bad passwd entry %q: shell %q must be an absolute path", entry, pe.Shell)
+	}
+	return pe, nil
+}
+
+// SetPasswdEntry returns the passwd content with the user entry for the uid
+// of pe replaced by pe, entries with the same name are removed.
+func SetPasswdEntry(content []byte, pe *PasswdEntry) []byte {
+	line := makePasswdLine(pe.Name, pe.UID, pe.GID, pe.Gecos, pe.Home, pe.Shell)
+	return setEntry(content, line, func(fields []string) bool {
+		return fields[0] == pe.Name || (len(fields) > 2 && fields[2] == strconv.FormatUint(uint64(pe.UID), 10))
+	})
+}
+
+// setEntry returns content with the lines matching match removed and line
+// added in place of the first one, or at the end if none matches.
+func setEntry(content []byte, line string, match func(fields []string) bool) []byte {
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	out := make([]string, 0, len(lines)+2)
+	added := false
+
+	for _, l := range lines {
+		if l == "" {
+			continue
+		}
+		if !match(strings.Split(l, ":")) {
+			out = append(out, l)
+		} else if !added {
+			out = append(out, line)
+			added = true
+		}
+	}
+	if !added {
+		out = append(out, line)
+	}
+
+	// Add this so that the following strings.Join call will result in text that ends in a newline
+	out = append(out, "")
+
+	return []byte(strings.Join(out, "\n"))
+}
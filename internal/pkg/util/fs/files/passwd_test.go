@@ -55,3 +55,81 @@ func TestPasswd(t *testing.T) {
 
 	golden.Assert(t, string(bytes), testGoldenFile, "mismatch in Passwd() invocation (uid: %d; requested homeDir: %#v)", testUID, testHomeDir)
 }
+
+func TestParsePasswdEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		want    *PasswdEntry
+		wantErr bool
+	}{
+		{
+			name:  "Valid",
+			entry: "user:1000:100:Some User:/home/user:/bin/zsh",
+			want:  &PasswdEntry{"user", 1000, 100, "Some User", "/home/user", "/bin/zsh"},
+		},
+		{
+			name:  "EmptyGecos",
+			entry: "user:1000:100::/home/user:/bin/bash",
+			want:  &PasswdEntry{"user", 1000, 100, "", "/home/user", "/bin/bash"},
+		},
+		{"MissingField", "user:1000:100:/home/user:/bin/zsh", nil, true},
+		{"EmptyName", ":1000:100::/home/user:/bin/zsh", nil, true},
+		{"BadName", "us er:1000:100::/home/user:/bin/zsh", nil, true},
+		{"BadUID", "user:-1:100::/home/user:/bin/zsh", nil, true},
+		{"BadGID", "user:1000:group::/home/user:/bin/zsh", nil, true},
+		{"RelativeHome", "user:1000:100::home/user:/bin/zsh", nil, true},
+		{"RelativeShell", "user:1000:100::/home/user:zsh", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePasswdEntry(tt.entry)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("unexpected success for %q", tt.entry)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if *got != *tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPasswdEntry(t *testing.T) {
+	pe := &PasswdEntry{"user", 1000, 100, "", "/home/user", "/bin/zsh"}
+	want := "user:x:1000:100::/home/user:/bin/zsh"
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "Append",
+			content: "root:x:0:0:root:/root:/bin/sh\n",
+			want:    "root:x:0:0:root:/root:/bin/sh\n" + want + "\n",
+		},
+		{
+			name:    "ReplaceUID",
+			content: "root:x:0:0:root:/root:/bin/sh\nhost:x:1000:1000:Host:/home/host:/bin/bash\nother:x:1001:1001::/:/bin/sh\n",
+			want:    "root:x:0:0:root:/root:/bin/sh\n" + want + "\nother:x:1001:1001::/:/bin/sh\n",
+		},
+		{
+			name:    "ReplaceName",
+			content: "user:x:1002:1002::/:/bin/sh\nhost:x:1000:1000:Host:/home/host:/bin/bash\n",
+			want:    want + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(SetPasswdEntry([]byte(tt.content), pe)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
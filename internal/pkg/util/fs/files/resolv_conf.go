@@ -10,14 +10,18 @@
 package files
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"net"
+	"strings"
 
 	"github.com/apptainer/apptainer/pkg/sylog"
 )
 
-// ResolvConf creates a resolv.conf content with provided dns list and returns it
-func ResolvConf(dns []string) (content []byte, err error) {
+// ResolvConf creates a resolv.conf content with provided dns list, search
+// domains and resolver options and returns it
+func ResolvConf(dns []string, search []string, options []string) (content []byte, err error) {
 	sylog.Verbosef("Creating resolv.conf content\n")
 	if len(dns) == 0 {
 		return content, fmt.Errorf("no dns ip provided")
@@ -29,5 +33,72 @@ func ResolvConf(dns []string) (content []byte, err error) {
 		line := fmt.Sprintf("nameserver %s\n", ip)
 		content = append(content, line...)
 	}
+	if len(search) > 0 {
+		for _, domain := range search {
+			if domain == "" || strings.ContainsAny(domain, " \t\n") {
+				return content, fmt.Errorf("dns search domain %q is not valid", domain)
+			}
+		}
+		content = append(content, fmt.Sprintf("search %s\n", strings.Join(search, " "))...)
+	}
+	if len(options) > 0 {
+		for _, option := range options {
+			if option == "" || strings.ContainsAny(option, " \t\n") {
+				return content, fmt.Errorf("dns option %q is not valid", option)
+			}
+		}
+		content = append(content, fmt.Sprintf("options %s\n", strings.Join(options, " "))...)
+	}
 	return content, nil
 }
+
+// ParseResolvConf returns the nameservers, search domains and resolver
+// options found in the resolv.conf content. As with the resolver, the
+// last search or domain line takes precedence.
+func ParseResolvConf(content []byte) (dns []string, search []string, options []string) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "nameserver":
+			dns = append(dns, fields[1])
+		case "search", "domain":
+			search = fields[1:]
+		case "options":
+			options = append(options, fields[1:]...)
+		}
+	}
+	return dns, search, options
+}
+
+// ResolvedUpstreamConf is the resolv.conf file maintained by systemd-resolved
+// which lists the upstream nameservers instead of the local stub resolver.
+const ResolvedUpstreamConf = "/run/systemd/resolve/resolv.conf"
+
+// resolvedStubAddresses are the local addresses of the systemd-resolved stub
+// resolver and of its DNS proxy.
+var resolvedStubAddresses = map[string]bool{
+	"127.0.0.53": true,
+	"127.0.0.54": true,
+}
+
+// IsResolvedStub returns whether the resolv.conf content only points to
+// the systemd-resolved stub resolver.
+func IsResolvedStub(content []byte) bool {
+	found := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		if !resolvedStubAddresses[fields[1]] {
+			return false
+		}
+		found = true
+	}
+	return found
+}
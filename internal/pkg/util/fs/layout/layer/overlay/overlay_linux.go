@@ -30,6 +30,8 @@ type Overlay struct {
 	lowerDirs []string
 	upperDir  string
 	workDir   string
+	options   []string
+	noXino    bool
 }
 
 // New creates and returns an overlay layer manager
@@ -37,6 +39,12 @@ func New() *Overlay {
 	return &Overlay{}
 }
 
+// DisableXino doesn't request the xino feature for the overlay mount,
+// it's requested by default.
+func (o *Overlay) DisableXino() {
+	o.noXino = true
+}
+
 // Add adds required directory in session layout
 func (o *Overlay) Add(session *layout.Session, system *mount.System) error {
 	o.session = session
@@ -62,7 +70,11 @@ func (o *Overlay) createOverlay(system *mount.System) error {
 	o.lowerDirs = append(o.lowerDirs, o.session.RootFsPath())
 
 	lowerdir := strings.Join(o.lowerDirs, ":")
-	err := system.Points.AddOverlay(mount.LayerTag, o.session.FinalPath(), flags, lowerdir, o.upperDir, o.workDir)
+	options := o.options
+	if !o.noXino {
+		options = append([]string{mount.OverlayXinoOption}, options...)
+	}
+	err := system.Points.AddOverlay(mount.LayerTag, o.session.FinalPath(), flags, lowerdir, o.upperDir, o.workDir, options...)
 	if err != nil {
 		return err
 	}
@@ -74,12 +86,20 @@ func (o *Overlay) createOverlay(system *mount.System) error {
 	return o.createLayer(points[0].Destination, system)
 }
 
-// AddLowerDir adds a lower directory to overlay mount
+// AddLowerDir adds a lower directory to overlay mount, the last added
+// directory is the leftmost one of the lowerdir option and takes precedence
+// over the previously added ones
 func (o *Overlay) AddLowerDir(path string) error {
 	o.lowerDirs = append([]string{path}, o.lowerDirs...)
 	return nil
 }
 
+// AddOption adds an option to overlay mount, applied only when an upper
+// directory is set
+func (o *Overlay) AddOption(option string) {
+	o.options = append(o.options, option)
+}
+
 // SetUpperDir sets upper directory to overlay mount
 func (o *Overlay) SetUpperDir(path string) error {
 	if o.upperDir != "" {
@@ -11,7 +11,9 @@ package layout
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 
 	"github.com/apptainer/apptainer/internal/pkg/util/fs/mount"
@@ -94,6 +96,46 @@ func (s *Session) OverrideDir(path string, realpath string) {
 	s.overrideDir(p, realpath)
 }
 
+// ContainerPath returns the full path of the container path p within the
+// session final directory, symlinks are evaluated relative to the final
+// directory so the returned path never points outside of it.
+func (s *Session) ContainerPath(p string) string {
+	finalPath := s.FinalPath()
+	return filepath.Join(finalPath, s.VFS.EvalRelative(p, finalPath))
+}
+
+// ResolveContainerPath returns the host path where the absolute container
+// path p lives along with whether it exists. Symlinks are evaluated within
+// the container layers, and paths located in an overridden directory are
+// resolved to the directory substituted to it.
+func (s *Session) ResolveContainerPath(p string) (string, bool, error) {
+	if !filepath.IsAbs(p) {
+		return "", false, fmt.Errorf("container path %s is not an absolute path", p)
+	}
+
+	hostPath := s.ContainerPath(p)
+	dest := strings.TrimPrefix(hostPath, s.FinalPath())
+
+	// search for the nearest overridden parent directory
+	for dir := dest; dir != "/" && dir != ""; dir = filepath.Dir(dir) {
+		ovDir := dir
+		if s.Layer != nil {
+			ovDir = filepath.Join(s.Layer.Dir(), dir)
+		}
+		if realpath, err := s.GetOverridePath(ovDir); err == nil {
+			hostPath = filepath.Join(realpath, strings.TrimPrefix(dest, dir))
+			break
+		}
+	}
+
+	if _, err := s.VFS.Stat(hostPath); os.IsNotExist(err) {
+		return hostPath, false, nil
+	} else if err != nil {
+		return hostPath, false, fmt.Errorf("while checking %s: %s", hostPath, err)
+	}
+	return hostPath, true, nil
+}
+
 // RootFsPath returns the full path to session rootfs directory
 func (s *Session) RootFsPath() string {
 	path, _ := s.GetPath(rootFsDir)
@@ -0,0 +1,83 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveContainerPath(t *testing.T) {
+	session := &Session{Manager: &Manager{VFS: DefaultVFS}}
+
+	if err := session.SetRootPath(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.AddDir(rootFsDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.AddDir(rootFsDir + "/etc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.AddFile(rootFsDir+"/etc/hosts", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.AddSymlink(rootFsDir+"/etc/link", "/etc/hosts"); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.AddSymlink(rootFsDir+"/escape", "../../../../"); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.AddDir(rootFsDir + "/data"); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	dataDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataDir, "file"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	session.OverrideDir("/data", dataDir)
+
+	rootfs := session.RootFsPath()
+
+	tests := []struct {
+		name     string
+		path     string
+		hostPath string
+		exists   bool
+		wantErr  bool
+	}{
+		{name: "File", path: "/etc/hosts", hostPath: rootfs + "/etc/hosts", exists: true},
+		{name: "Symlink", path: "/etc/link", hostPath: rootfs + "/etc/hosts", exists: true},
+		{name: "EscapingSymlink", path: "/escape/etc", hostPath: rootfs + "/etc", exists: true},
+		{name: "NonExistent", path: "/etc/nonexistent", hostPath: rootfs + "/etc/nonexistent"},
+		{name: "OverrideDir", path: "/data/file", hostPath: dataDir + "/file", exists: true},
+		{name: "OverrideDirNonExistent", path: "/data/nonexistent", hostPath: dataDir + "/nonexistent"},
+		{name: "RelativePath", path: "etc/hosts", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostPath, exists, err := session.ResolveContainerPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hostPath != tt.hostPath {
+				t.Errorf("got host path %s, expected %s", hostPath, tt.hostPath)
+			}
+			if exists != tt.exists {
+				t.Errorf("got exists %v, expected %v", exists, tt.exists)
+			}
+		})
+	}
+}
@@ -14,13 +14,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 
 	"github.com/apptainer/apptainer/pkg/util/fs/proc"
 
 	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
 )
 
 type mountError string
@@ -122,6 +125,9 @@ const (
 	UserbindsTag = "userbinds"
 	// OtherTag defines tag for other mount points that can't be classified
 	OtherTag = "other"
+	// FuseTag defines tag for FUSE mount points, mounted once all other
+	// mount points are mounted
+	FuseTag = "fuse"
 	// FinalTag defines tag for mount points to mount/remount at the end of mount process
 	FinalTag = "final"
 )
@@ -148,7 +154,8 @@ var authorizedTags = map[AuthorizedTag]struct {
 	UserbindsTag: {true, true, 14},
 	CwdTag:       {false, false, 15},
 	OtherTag:     {true, false, 16},
-	FinalTag:     {true, false, 17},
+	FuseTag:      {true, false, 17},
+	FinalTag:     {true, false, 18},
 }
 
 var authorizedImage = map[string]fsContext{
@@ -156,6 +163,7 @@ var authorizedImage = map[string]fsContext{
 	"ext3":      {true},
 	"squashfs":  {true},
 	"gocryptfs": {true},
+	"erofs":     {true},
 }
 
 var authorizedFS = map[string]fsContext{
@@ -168,6 +176,8 @@ var authorizedFS = map[string]fsContext{
 	"mqueue":  {false},
 	"cgroup":  {false},
 	"cgroup2": {false},
+	"debugfs": {false},
+	"tracefs": {false},
 	"fuse":    {false},
 }
 
@@ -238,6 +248,99 @@ func ConvertOptions(options []string) (uintptr, []string) {
 	return flags, finalOpt
 }
 
+// imageMountOptions maps the options allowed by ImageMountFlags to their
+// mount flags. lazytime is only converted for image mount points, it's
+// ignored like other filesystem specific options elsewhere.
+var imageMountOptions = map[string]uintptr{
+	"lazytime":    unix.MS_LAZYTIME,
+	"noatime":     syscall.MS_NOATIME,
+	"nodiratime":  syscall.MS_NODIRATIME,
+	"relatime":    syscall.MS_RELATIME,
+	"strictatime": syscall.MS_STRICTATIME,
+}
+
+// squashfsMountOptions lists the squashfs mount options, with their
+// accepted values, allowed by SquashfsMountOptions for privileged mounts.
+var squashfsMountOptions = map[string][]string{
+	"errors":  {"continue"},
+	"threads": {"single", "multi", "percpu"},
+}
+
+// overlayMountOptions maps the options allowed by OverlayMountOptions to
+// the corresponding overlay mount options.
+var overlayMountOptions = map[string]string{
+	"metacopy":     "metacopy=on",
+	"redirect_dir": "redirect_dir=on",
+}
+
+// OverlayXinoOption is the overlay mount option enabling the xino feature.
+const OverlayXinoOption = "xino=on"
+
+// OverlayOptionalOptions lists the overlay mount options which may be
+// dropped when the kernel doesn't support them, in the order they are
+// dropped.
+var OverlayOptionalOptions = []string{"metacopy=on", "redirect_dir=on", OverlayXinoOption}
+
+// OverlayMountOptions validates the overlay features requested for the
+// container overlay and converts them into overlay mount options, only
+// metacopy and redirect_dir are accepted.
+func OverlayMountOptions(options []string) ([]string, error) {
+	mountOptions := make([]string, 0, len(options))
+	for _, option := range options {
+		o, ok := overlayMountOptions[strings.TrimSpace(option)]
+		if !ok {
+			return nil, fmt.Errorf("overlay mount option %q is not supported", option)
+		}
+		if !slices.Contains(mountOptions, o) {
+			mountOptions = append(mountOptions, o)
+		}
+	}
+	return mountOptions, nil
+}
+
+// ImageMountFlags validates the options applied to writable image mount
+// points and converts them into mount flags, only options controlling
+// access time updates are accepted.
+func ImageMountFlags(options []string) (uintptr, error) {
+	flags := uintptr(0)
+	for _, option := range options {
+		flag, ok := imageMountOptions[strings.TrimSpace(option)]
+		if !ok {
+			return 0, fmt.Errorf("image mount option %q is not supported", option)
+		}
+		flags |= flag
+	}
+	return flags, nil
+}
+
+// SquashfsMountOptions validates the key=value mount options passed to
+// the squashfs root filesystem and data image mounts. For privileged
+// mounts, done by the kernel in setuid mode, only errors=continue and the
+// threads option are accepted, otherwise the options are passed as is to
+// the unprivileged mount.
+func SquashfsMountOptions(options []string, privileged bool) ([]string, error) {
+	mountOptions := make([]string, 0, len(options))
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		key, value, ok := strings.Cut(option, "=")
+		if !ok || key == "" || value == "" || strings.Contains(option, ",") {
+			return nil, fmt.Errorf("squashfs mount option %q is not in key=value format", option)
+		}
+		if privileged {
+			values, ok := squashfsMountOptions[key]
+			if !ok {
+				return nil, fmt.Errorf("squashfs mount option %q is not allowed in setuid mode", option)
+			}
+			_, err := strconv.ParseUint(value, 10, 32)
+			if !slices.Contains(values, value) && (key != "threads" || err != nil) {
+				return nil, fmt.Errorf("squashfs mount option %q value is not allowed in setuid mode", option)
+			}
+		}
+		mountOptions = append(mountOptions, option)
+	}
+	return mountOptions, nil
+}
+
 // ConvertSpec converts an OCI Mount spec into an importable mount points list
 func ConvertSpec(mounts []specs.Mount) (map[AuthorizedTag]PointList, error) {
 	points := make(map[AuthorizedTag]PointList)
@@ -612,6 +715,7 @@ func (p *Points) Import(points map[AuthorizedTag]PointList) error {
 				lowerdir := ""
 				upperdir := ""
 				workdir := ""
+				xino := []string{}
 				for _, option := range options {
 					if strings.HasPrefix(option, "lowerdir=") {
 						fmt.Sscanf(option, "lowerdir=%s", &lowerdir)
@@ -619,9 +723,11 @@ func (p *Points) Import(points map[AuthorizedTag]PointList) error {
 						fmt.Sscanf(option, "upperdir=%s", &upperdir)
 					} else if strings.HasPrefix(option, "workdir=") {
 						fmt.Sscanf(option, "workdir=%s", &workdir)
+					} else if option == OverlayXinoOption {
+						xino = append(xino, option)
 					}
 				}
-				if err = p.AddOverlay(tag, point.Destination, flags, lowerdir, upperdir, workdir); err == nil {
+				if err = p.AddOverlay(tag, point.Destination, flags, lowerdir, upperdir, workdir, xino...); err == nil {
 					continue
 				}
 			}
@@ -712,8 +818,9 @@ func (p *Points) GetAllBinds() PointList {
 	return binds
 }
 
-// AddOverlay adds an overlay mount point
-func (p *Points) AddOverlay(tag AuthorizedTag, dest string, flags uintptr, lowerdir string, upperdir string, workdir string) error {
+// AddOverlay adds an overlay mount point, the additional options, like
+// OverlayXinoOption, are applied only when an upper directory is set.
+func (p *Points) AddOverlay(tag AuthorizedTag, dest string, flags uintptr, lowerdir string, upperdir string, workdir string, options ...string) error {
 	if flags&(syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_REC) != 0 {
 		return fmt.Errorf("ms_bind, ms_rec or ms_remount are not valid flags for overlay mount points")
 	}
@@ -723,7 +830,7 @@ func (p *Points) AddOverlay(tag AuthorizedTag, dest string, flags uintptr, lower
 	if !strings.HasPrefix(lowerdir, "/") {
 		return fmt.Errorf("lowerdir may contain only an absolute paths")
 	}
-	options := ""
+	opts := ""
 	if upperdir != "" {
 		if !strings.HasPrefix(upperdir, "/") {
 			return fmt.Errorf("upperdir must be an absolute path")
@@ -734,11 +841,14 @@ func (p *Points) AddOverlay(tag AuthorizedTag, dest string, flags uintptr, lower
 		if !strings.HasPrefix(workdir, "/") {
 			return fmt.Errorf("workdir must be an absolute path")
 		}
-		options = fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s,xino=on", lowerdir, upperdir, workdir)
+		opts = fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", lowerdir, upperdir, workdir)
+		for _, o := range options {
+			opts += "," + o
+		}
 	} else {
-		options = fmt.Sprintf("lowerdir=%s", lowerdir)
+		opts = fmt.Sprintf("lowerdir=%s", lowerdir)
 	}
-	return p.add(tag, "overlay", dest, "overlay", flags, options)
+	return p.add(tag, "overlay", dest, "overlay", flags, opts)
 }
 
 // GetAllOverlays returns a list of all registered overlay mount points
@@ -11,12 +11,14 @@ package mount
 
 import (
 	"fmt"
+	"slices"
 	"syscall"
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/test"
 	"github.com/apptainer/apptainer/internal/pkg/test/tool/require"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
 )
 
 func TestImage(t *testing.T) {
@@ -105,6 +107,186 @@ func TestImage(t *testing.T) {
 	}
 }
 
+func TestImageMountFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		flags   uintptr
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			options: nil,
+			flags:   0,
+		},
+		{
+			name:    "noatime lazytime",
+			options: []string{"noatime", "lazytime"},
+			flags:   syscall.MS_NOATIME | unix.MS_LAZYTIME,
+		},
+		{
+			name:    "relatime nodiratime",
+			options: []string{" relatime", "nodiratime "},
+			flags:   syscall.MS_RELATIME | syscall.MS_NODIRATIME,
+		},
+		{
+			name:    "unsupported flag",
+			options: []string{"noatime", "suid"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported option",
+			options: []string{"commit=60"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, err := ImageMountFlags(tt.options)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error for options %v", tt.options)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error for options %v: %s", tt.options, err)
+			}
+			if flags != tt.flags {
+				t.Errorf("unexpected flags for options %v: got %#x, expected %#x", tt.options, flags, tt.flags)
+			}
+		})
+	}
+
+	// lazytime is only converted for image mount points
+	if flags, _ := ConvertOptions([]string{"lazytime"}); flags != 0 {
+		t.Errorf("unexpected flags for lazytime mount option: got %#x, expected 0", flags)
+	}
+}
+
+func TestSquashfsMountOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    []string
+		privileged bool
+		want       []string
+		wantErr    bool
+	}{
+		{
+			name:       "empty",
+			options:    nil,
+			privileged: true,
+			want:       []string{},
+		},
+		{
+			name:       "privileged allowed",
+			options:    []string{"errors=continue", " threads=multi"},
+			privileged: true,
+			want:       []string{"errors=continue", "threads=multi"},
+		},
+		{
+			name:       "privileged threads number",
+			options:    []string{"threads=4"},
+			privileged: true,
+			want:       []string{"threads=4"},
+		},
+		{
+			name:       "privileged disallowed value",
+			options:    []string{"errors=panic"},
+			privileged: true,
+			wantErr:    true,
+		},
+		{
+			name:       "privileged disallowed option",
+			options:    []string{"offset=10"},
+			privileged: true,
+			wantErr:    true,
+		},
+		{
+			name:    "unprivileged",
+			options: []string{"errors=panic", "timeout=10"},
+			want:    []string{"errors=panic", "timeout=10"},
+		},
+		{
+			name:    "flag",
+			options: []string{"nosuid"},
+			wantErr: true,
+		},
+		{
+			name:    "multiple options",
+			options: []string{"errors=continue,suid=1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SquashfsMountOptions(tt.options, tt.privileged)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error for options %v", tt.options)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error for options %v: %s", tt.options, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("unexpected mount options for options %v: got %v, expected %v", tt.options, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverlayMountOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			options: nil,
+			want:    []string{},
+		},
+		{
+			name:    "metacopy redirect_dir",
+			options: []string{"metacopy", " redirect_dir"},
+			want:    []string{"metacopy=on", "redirect_dir=on"},
+		},
+		{
+			name:    "duplicated",
+			options: []string{"metacopy", "metacopy"},
+			want:    []string{"metacopy=on"},
+		},
+		{
+			name:    "unsupported option",
+			options: []string{"index=off"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := OverlayMountOptions(tt.options)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error for options %v", tt.options)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error for options %v: %s", tt.options, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("unexpected mount options for options %v: got %v, expected %v", tt.options, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestOverlay(t *testing.T) {
 	test.DropPrivilege(t)
 	defer test.ResetPrivilege(t)
@@ -152,6 +334,22 @@ func TestOverlay(t *testing.T) {
 	}
 	points.RemoveAll()
 
+	if err := points.AddOverlay(LayerTag, "/fake", 0, "/lower", "/upper", "/work", "metacopy=on"); err != nil {
+		t.Errorf("%s", err)
+	}
+	if overlay := points.GetByDest("/fake"); len(overlay) != 1 || !slices.Contains(overlay[0].Options, "metacopy=on") {
+		t.Errorf("option metacopy=on not applied for /fake")
+	}
+	points.RemoveAll()
+
+	if err := points.AddOverlay(LayerTag, "/fake", 0, "/lower", "", "", "metacopy=on"); err != nil {
+		t.Errorf("%s", err)
+	}
+	if overlay := points.GetByDest("/fake"); len(overlay) != 1 || slices.Contains(overlay[0].Options, "metacopy=on") {
+		t.Errorf("option metacopy=on applied for /fake without upper directory")
+	}
+	points.RemoveAll()
+
 	if err := points.AddOverlay(LayerTag, "/mnt", syscall.MS_NOSUID, "/lower", "/upper", "/work"); err != nil {
 		t.Fatalf("%s", err)
 	}
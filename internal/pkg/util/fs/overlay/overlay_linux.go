@@ -26,6 +26,7 @@ const (
 	lowerDir
 	upperDir
 	fuseDir
+	xinoDir
 )
 
 type fs struct {
@@ -46,12 +47,12 @@ var incompatibleFs = map[int64]fs{
 	// NFS filesystem
 	Nfs: {
 		name:       "NFS",
-		overlayDir: upperDir,
+		overlayDir: upperDir | xinoDir,
 	},
 	// FUSE filesystem
 	Fuse: {
 		name:       "FUSE",
-		overlayDir: upperDir | fuseDir,
+		overlayDir: upperDir | fuseDir | xinoDir,
 	},
 	// ECRYPT filesystem
 	Ecrypt: {
@@ -62,17 +63,17 @@ var incompatibleFs = map[int64]fs{
 	//nolint:misspell
 	Lustre: {
 		name:       "LUSTRE",
-		overlayDir: lowerDir | upperDir,
+		overlayDir: lowerDir | upperDir | xinoDir,
 	},
 	// GPFS filesystem
 	Gpfs: {
 		name:       "GPFS",
-		overlayDir: lowerDir | upperDir,
+		overlayDir: lowerDir | upperDir | xinoDir,
 	},
 	// PANFS filesystem
 	Panfs: {
 		name:       "PANFS",
-		overlayDir: lowerDir | upperDir,
+		overlayDir: lowerDir | upperDir | xinoDir,
 	},
 }
 
@@ -113,6 +114,25 @@ func CheckFuse(path string) error {
 	return check(path, fuseDir)
 }
 
+// CheckXino checks if the underlying filesystem of the provided
+// path, used as an overlay directory, supports the overlay xino
+// feature.
+func CheckXino(path string) error {
+	return check(path, xinoDir)
+}
+
+// FreeSpace returns the space in bytes available to unprivileged users and
+// the total size in bytes of the filesystem where the provided path, like
+// an overlay upper directory, is located.
+func FreeSpace(path string) (free uint64, total uint64, err error) {
+	stfs := &unix.Statfs_t{}
+
+	if err := statfs(path, stfs); err != nil {
+		return 0, 0, fmt.Errorf("could not retrieve underlying filesystem information for %s: %s", path, err)
+	}
+	return stfs.Bavail * uint64(stfs.Bsize), stfs.Blocks * uint64(stfs.Bsize), nil
+}
+
 type errIncompatibleFs struct {
 	path string
 	name string
@@ -124,6 +144,11 @@ func (e *errIncompatibleFs) Error() string {
 	overlayDir := "lower"
 	if e.dir == upperDir {
 		overlayDir = "upper"
+	} else if e.dir == xinoDir {
+		return fmt.Sprintf(
+			"%s is located on a %s filesystem which doesn't support the overlay xino feature",
+			e.path, e.name,
+		)
 	}
 	return fmt.Sprintf(
 		"%s is located on a %s filesystem incompatible as overlay %s directory",
@@ -167,6 +167,41 @@ func TestCheckLowerUpper(t *testing.T) {
 			expectedSuccess:       false,
 			expectIncompatibleErr: true,
 		},
+		{
+			name:                  "Root filesystem xino",
+			path:                  "/",
+			fsName:                "none",
+			dir:                   xinoDir,
+			expectedSuccess:       true,
+			expectIncompatibleErr: false,
+		},
+		{
+			name:                  "NFS mock xino",
+			path:                  "/",
+			fsName:                "NFS",
+			dir:                   xinoDir,
+			fsType:                Nfs,
+			expectedSuccess:       false,
+			expectIncompatibleErr: true,
+		},
+		{
+			name:                  "FUSE mock xino",
+			path:                  "/",
+			fsName:                "FUSE",
+			dir:                   xinoDir,
+			fsType:                Fuse,
+			expectedSuccess:       false,
+			expectIncompatibleErr: true,
+		},
+		{
+			name:                  "ECRYPT mock xino",
+			path:                  "/",
+			fsName:                "ECRYPT",
+			dir:                   xinoDir,
+			fsType:                Ecrypt,
+			expectedSuccess:       true,
+			expectIncompatibleErr: false,
+		},
 	}
 
 	if IsIncompatible(nil) {
@@ -191,6 +226,8 @@ func TestCheckLowerUpper(t *testing.T) {
 			err = CheckLower(tt.path)
 		case upperDir:
 			err = CheckUpper(tt.path)
+		case xinoDir:
+			err = CheckXino(tt.path)
 		}
 
 		if err != nil && tt.expectedSuccess {
@@ -215,3 +252,34 @@ func TestCheckLowerUpper(t *testing.T) {
 		}
 	}
 }
+
+func TestFreeSpace(t *testing.T) {
+	statfs = func(path string, st *unix.Statfs_t) error {
+		if path != "/upper" {
+			return unix.ENOENT
+		}
+		st.Bsize = 4096
+		st.Blocks = 1000
+		st.Bfree = 300
+		st.Bavail = 250
+		return nil
+	}
+	defer func() {
+		statfs = unix.Statfs
+	}()
+
+	free, total, err := FreeSpace("/upper")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if free != 250*4096 {
+		t.Errorf("got %d free bytes instead of %d", free, 250*4096)
+	}
+	if total != 1000*4096 {
+		t.Errorf("got %d total bytes instead of %d", total, 1000*4096)
+	}
+
+	if _, _, err := FreeSpace("/non/existent/path"); err == nil {
+		t.Errorf("unexpected success with a non existent path")
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package gpu
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/apptainer/apptainer/internal/pkg/util/paths"
+)
+
+const driDir = "/dev/dri"
+
+// DRIPaths returns a list of libraries/binaries that should be
+// mounted into the container in order to use DRM render nodes
+func DRIPaths(configFilePath string) ([]string, []string, error) {
+	driFiles, err := gpuliblist(configFilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read %s: %v", filepath.Base(configFilePath), err)
+	}
+	// return nil slices to signal that the input was empty
+	if len(driFiles) == 0 {
+		return nil, nil, nil
+	}
+
+	libs, bins, _, err := paths.Resolve(driFiles)
+	return libs, bins, err
+}
+
+// DRIDevices returns the list of DRM card and render nodes
+// found in /dev/dri.
+func DRIDevices() ([]string, error) {
+	return driDevices(driDir)
+}
+
+func driDevices(dir string) ([]string, error) {
+	devs := []string{}
+	for _, pattern := range []string{"card*", "renderD*"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		devs = append(devs, matches...)
+	}
+	sort.Strings(devs)
+	return devs, nil
+}
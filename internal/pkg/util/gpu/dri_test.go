@@ -0,0 +1,50 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package gpu
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_driDevices(t *testing.T) {
+	dir := t.TempDir()
+
+	devs, err := driDevices(dir)
+	if err != nil {
+		t.Fatalf("driDevices() error = %v", err)
+	}
+	if len(devs) != 0 {
+		t.Errorf("driDevices() gave unexpected results for empty directory: %v", devs)
+	}
+
+	for _, f := range []string{"card1", "card0", "renderD128", "controlD64"} {
+		if err := os.WriteFile(filepath.Join(dir, f), nil, 0o644); err != nil {
+			t.Fatalf("could not create %s: %v", f, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "by-path"), 0o755); err != nil {
+		t.Fatalf("could not create by-path directory: %v", err)
+	}
+
+	devs, err = driDevices(dir)
+	if err != nil {
+		t.Fatalf("driDevices() error = %v", err)
+	}
+	expected := []string{
+		filepath.Join(dir, "card0"),
+		filepath.Join(dir, "card1"),
+		filepath.Join(dir, "renderD128"),
+	}
+	if !reflect.DeepEqual(devs, expected) {
+		t.Errorf("driDevices() gave unexpected results, got: %v expected: %v", devs, expected)
+	}
+}
@@ -157,6 +157,28 @@ func AskQuestionNoEcho(format string, a ...interface{}) (string, error) {
 	return string(response), nil
 }
 
+// ReadPassphraseFd reads a passphrase from the file descriptor fd until
+// EOF, the trailing newline is removed. The file descriptor is closed once
+// read so the passphrase can't be read twice nor leak to child processes.
+func ReadPassphraseFd(fd int) (string, error) {
+	if fd < 0 {
+		return "", fmt.Errorf("invalid passphrase file descriptor %d", fd)
+	}
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("passphrase-fd-%d", fd))
+	if f == nil {
+		return "", fmt.Errorf("invalid passphrase file descriptor %d", fd)
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return "", fmt.Errorf("while reading passphrase from file descriptor %d: %w", fd, err)
+	}
+	passphrase := strings.TrimSuffix(string(b), "\n")
+	passphrase = strings.TrimSuffix(passphrase, "\r")
+	return passphrase, nil
+}
+
 // GetPassphrase will ask the user for a password with int number of
 // retries.
 func GetPassphrase(message string, retries int) (string, error) {
@@ -13,6 +13,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"syscall"
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/test"
@@ -339,3 +340,46 @@ func TestGetPassphrase(t *testing.T) {
 		})
 	}
 }
+
+func TestReadPassphraseFd(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		passphrase string
+	}{
+		{name: "Newline", input: "secret\n", passphrase: "secret"},
+		{name: "CarriageReturn", input: "secret\r\n", passphrase: "secret"},
+		{name: "NoNewline", input: "secret", passphrase: "secret"},
+		{name: "Multiline", input: "secret\nphrase\n", passphrase: "secret\nphrase"},
+		{name: "Empty", input: "", passphrase: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fds := make([]int, 2)
+			if err := syscall.Pipe(fds); err != nil {
+				t.Fatalf("failed to create pipe: %s", err)
+			}
+			if _, err := syscall.Write(fds[1], []byte(tt.input)); err != nil {
+				t.Fatalf("failed to write to pipe: %s", err)
+			}
+			syscall.Close(fds[1])
+
+			passphrase, err := ReadPassphraseFd(fds[0])
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if passphrase != tt.passphrase {
+				t.Errorf("got passphrase %q, expected %q", passphrase, tt.passphrase)
+			}
+			// the file descriptor is closed once read
+			if _, err := ReadPassphraseFd(fds[0]); err == nil {
+				t.Errorf("unexpected success while reading the passphrase twice")
+			}
+		})
+	}
+
+	if _, err := ReadPassphraseFd(-1); err == nil {
+		t.Errorf("unexpected success with an invalid file descriptor")
+	}
+}
@@ -193,6 +193,16 @@ func getCredentialsFromFile(reqAuthFile string) (*configfile.ConfigFile, error)
 	return cf, nil
 }
 
+// Authenticator returns the authenticator for target, built from ociAuth if
+// set or from the credentials stored in the auth file otherwise.
+func Authenticator(ociAuth *authn.AuthConfig, reqAuthFile string, target authn.Resource) (authn.Authenticator, error) {
+	if ociAuth != nil {
+		return authn.FromConfig(*ociAuth), nil
+	}
+	kc := &apptainerKeychain{reqAuthFile: reqAuthFile}
+	return kc.Resolve(target)
+}
+
 func AuthOptn(ociAuth *authn.AuthConfig, reqAuthFile string) remote.Option {
 	if ociAuth != nil {
 		return remote.WithAuth(authn.FromConfig(*ociAuth))
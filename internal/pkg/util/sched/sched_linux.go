@@ -0,0 +1,142 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sched
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// MinNice is the highest scheduling priority nice value
+	MinNice = -20
+	// MaxNice is the lowest scheduling priority nice value
+	MaxNice = 19
+	// MinRealtimePriority is the lowest static priority of real-time policies
+	MinRealtimePriority = 1
+	// MaxRealtimePriority is the highest static priority of real-time policies
+	MaxRealtimePriority = 99
+)
+
+var policies = map[string]uint32{
+	"other": unix.SCHED_NORMAL,
+	"batch": unix.SCHED_BATCH,
+	"idle":  unix.SCHED_IDLE,
+	"fifo":  unix.SCHED_FIFO,
+	"rr":    unix.SCHED_RR,
+}
+
+// Policy holds a CPU scheduling policy and its static priority.
+type Policy struct {
+	Name     string
+	Priority int
+}
+
+// Realtime returns whether the policy is a real-time policy.
+func (p Policy) Realtime() bool {
+	return p.Name == "fifo" || p.Name == "rr"
+}
+
+// ParsePolicy parses a scheduling policy in <policy>[:<priority>] format
+// where policy is one of other, batch, idle, fifo or rr. A priority between
+// 1 and 99 is required by the fifo and rr real-time policies only.
+func ParsePolicy(s string) (Policy, error) {
+	name, prio, hasPrio := strings.Cut(s, ":")
+	p := Policy{Name: name}
+
+	if _, ok := policies[name]; !ok {
+		return p, fmt.Errorf("unknown scheduling policy %q, must be one of other, batch, idle, fifo or rr", name)
+	}
+	if !p.Realtime() {
+		if hasPrio {
+			return p, fmt.Errorf("scheduling policy %s doesn't take a priority", name)
+		}
+		return p, nil
+	}
+	if !hasPrio {
+		return p, fmt.Errorf("scheduling policy %s requires a priority (format is %s:<priority>)", name, name)
+	}
+
+	n, err := strconv.Atoi(prio)
+	if err != nil {
+		return p, fmt.Errorf("bad priority %q for scheduling policy %s: %s", prio, name, err)
+	}
+	if n < MinRealtimePriority || n > MaxRealtimePriority {
+		return p, fmt.Errorf("priority %d for scheduling policy %s is out of range [%d, %d]", n, name, MinRealtimePriority, MaxRealtimePriority)
+	}
+	p.Priority = n
+
+	return p, nil
+}
+
+// CheckNice returns an error if nice is not a valid nice value.
+func CheckNice(nice int) error {
+	if nice < MinNice || nice > MaxNice {
+		return fmt.Errorf("nice value %d is out of range [%d, %d]", nice, MinNice, MaxNice)
+	}
+	return nil
+}
+
+// SetPolicy sets the scheduling policy of the calling thread, inherited
+// by the processes it executes and their children.
+func SetPolicy(p Policy) error {
+	policy, ok := policies[p.Name]
+	if !ok {
+		return fmt.Errorf("unknown scheduling policy %q", p.Name)
+	}
+
+	attr := &unix.SchedAttr{
+		Size:     uint32(unsafe.Sizeof(unix.SchedAttr{})),
+		Policy:   policy,
+		Priority: uint32(p.Priority),
+	}
+	if !p.Realtime() {
+		// preserve the current nice value
+		nice, err := getNice()
+		if err != nil {
+			return err
+		}
+		attr.Nice = int32(nice)
+	}
+
+	err := unix.SchedSetAttr(0, attr, 0)
+	if errors.Is(err, unix.EPERM) && p.Realtime() {
+		return fmt.Errorf("real-time scheduling policy %s with priority %d requires CAP_SYS_NICE or a RLIMIT_RTPRIO limit of at least %d", p.Name, p.Priority, p.Priority)
+	} else if err != nil {
+		return fmt.Errorf("while setting scheduling policy %s: %s", p.Name, err)
+	}
+	return nil
+}
+
+// SetNice sets the nice value of the calling thread, inherited by the
+// processes it executes and their children.
+func SetNice(nice int) error {
+	err := unix.Setpriority(unix.PRIO_PROCESS, 0, nice)
+	if (errors.Is(err, unix.EACCES) || errors.Is(err, unix.EPERM)) && nice < 0 {
+		return fmt.Errorf("negative nice value %d requires CAP_SYS_NICE or a sufficient RLIMIT_NICE limit", nice)
+	} else if err != nil {
+		return fmt.Errorf("while setting nice value %d: %s", nice, err)
+	}
+	return nil
+}
+
+// getNice returns the nice value of the calling thread.
+func getNice() (int, error) {
+	// the system call returns 20 - nice to avoid negative values
+	prio, err := unix.Getpriority(unix.PRIO_PROCESS, 0)
+	if err != nil {
+		return 0, fmt.Errorf("while getting nice value: %s", err)
+	}
+	return 20 - prio, nil
+}
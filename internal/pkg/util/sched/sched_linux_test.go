@@ -0,0 +1,57 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sched
+
+import (
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Policy
+		wantErr bool
+	}{
+		{spec: "other", want: Policy{Name: "other"}},
+		{spec: "batch", want: Policy{Name: "batch"}},
+		{spec: "idle", want: Policy{Name: "idle"}},
+		{spec: "fifo:10", want: Policy{Name: "fifo", Priority: 10}},
+		{spec: "rr:99", want: Policy{Name: "rr", Priority: 99}},
+		{spec: "deadline", wantErr: true},
+		{spec: "batch:1", wantErr: true},
+		{spec: "fifo", wantErr: true},
+		{spec: "fifo:0", wantErr: true},
+		{spec: "rr:100", wantErr: true},
+		{spec: "rr:high", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParsePolicy(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePolicy(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParsePolicy(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckNice(t *testing.T) {
+	for _, n := range []int{-20, 0, 19} {
+		if err := CheckNice(n); err != nil {
+			t.Errorf("unexpected error for nice value %d: %s", n, err)
+		}
+	}
+	for _, n := range []int{-21, 20} {
+		if err := CheckNice(n); err == nil {
+			t.Errorf("unexpected success for nice value %d", n)
+		}
+	}
+}
@@ -9,7 +9,10 @@
 
 package shell
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // ArgsQuoted concatenates a slice of string shell args, quoting each item
 func ArgsQuoted(a []string) (quoted string) {
@@ -39,3 +42,29 @@ func EscapeDoubleQuotes(s string) string {
 func EscapeSingleQuotes(s string) string {
 	return strings.Replace(s, `'`, `'"'"'`, -1)
 }
+
+// EscapeANSIC performs escaping of a string so it can be safely enclosed
+// within a bash ANSI-C quoted string ($'...'). Backslashes, single quotes
+// and control characters, including newlines, are escaped so the original
+// value is restored exactly when the quoted string is expanded by a shell.
+func EscapeANSIC(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(&b, `\x%02x`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String()
+}
@@ -9,7 +9,12 @@
 
 package shell
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/util/shell/interpreter"
+)
 
 func TestArgsQuoted(t *testing.T) {
 	quoteTests := []struct {
@@ -93,3 +98,60 @@ func TestEscapeSingleQuotes(t *testing.T) {
 		})
 	}
 }
+
+func TestEscapeANSIC(t *testing.T) {
+	escapeANSICTests := []struct {
+		input    string
+		expected string
+	}{
+		{`Hello`, `Hello`},
+		{`Hell'o`, `Hell\'o`},
+		{`Hello \n me`, `Hello \\n me`},
+		{"Hello\nme", `Hello\nme`},
+		{"Hello\tme\r", `Hello\x09me\x0d`},
+		{`"$HOME" ` + "`id`", `"$HOME" ` + "`id`"},
+	}
+
+	for _, test := range escapeANSICTests {
+		t.Run(test.input, func(t *testing.T) {
+			escaped := EscapeANSIC(test.input)
+			if escaped != test.expected {
+				t.Errorf("got %s, expected %s", escaped, test.expected)
+			}
+		})
+	}
+}
+
+func TestEscapeANSICRoundTrip(t *testing.T) {
+	values := []string{
+		"",
+		"simple",
+		"line1\nline2\n",
+		"-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+		`literal \u000A escape`,
+		`single ' and double " quotes`,
+		"$HOME $(id -u) `id -u` ${PATH}",
+		"back\\slash\\n and \\'",
+		"control \x01\x1b[0m\x7f chars",
+		"\x01A hex followed by hex digit",
+		"unicode é ✓",
+	}
+
+	for _, value := range values {
+		script := []byte("VALUE=$'" + EscapeANSIC(value) + "'")
+		env, err := interpreter.EvaluateEnv(context.Background(), script, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error while evaluating %q: %s", value, err)
+		}
+		found := false
+		for _, e := range env {
+			if e == "VALUE="+value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("value %q was not restored, got environment %q", value, env)
+		}
+	}
+}
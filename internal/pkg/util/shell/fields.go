@@ -0,0 +1,43 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package shell
+
+import (
+	"fmt"
+	"strings"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Fields splits the simple command s into words following the shell quoting
+// rules. Variable expansions and command substitutions are not allowed.
+func Fields(s string) ([]string, error) {
+	f, err := syntax.NewParser().Parse(strings.NewReader(s), "")
+	if err != nil {
+		return nil, fmt.Errorf("while parsing %q: %s", s, err)
+	}
+	if len(f.Stmts) == 0 {
+		return []string{}, nil
+	}
+
+	stmt := f.Stmts[0]
+	call, ok := stmt.Cmd.(*syntax.CallExpr)
+	if len(f.Stmts) > 1 || !ok || len(call.Assigns) > 0 || len(stmt.Redirs) > 0 || stmt.Background || stmt.Negated {
+		return nil, fmt.Errorf("%q is not a simple command", s)
+	}
+
+	// unset variables raise an error as the environment is empty
+	cfg := &expand.Config{Env: expand.ListEnviron(), NoUnset: true}
+	fields, err := expand.Fields(cfg, call.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("while splitting %q: %s", s, err)
+	}
+	return fields, nil
+}
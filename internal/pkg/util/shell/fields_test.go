@@ -0,0 +1,52 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		fields  []string
+		wantErr bool
+	}{
+		{"Empty", "", []string{}, false},
+		{"Single", "numactl", []string{"numactl"}, false},
+		{"Args", "numactl  --cpunodebind=0 --membind=0", []string{"numactl", "--cpunodebind=0", "--membind=0"}, false},
+		{"Quoted", `perf record -o "out file" 'a b' c\ d`, []string{"perf", "record", "-o", "out file", "a b", "c d"}, false},
+		{"Variable", "taskset $CPUS", nil, true},
+		{"CommandSubstitution", "taskset $(nproc)", nil, true},
+		{"List", "numactl; true", nil, true},
+		{"Pipe", "numactl | cat", nil, true},
+		{"Redirect", "numactl > out", nil, true},
+		{"Assign", "A=1 numactl", nil, true},
+		{"Unterminated", `numactl "a`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := Fields(tt.s)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("unexpected success for %q: %q", tt.s, fields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("got %q, want %q", fields, tt.fields)
+			}
+		})
+	}
+}
@@ -136,6 +136,18 @@ $(rocm_liblist_INSTALL): $(rocm_liblist)
 INSTALLFILES += $(rocm_liblist_INSTALL)
 
 
+# dri liblist config file
+dri_liblist := $(SOURCEDIR)/etc/driliblist.conf
+
+dri_liblist_INSTALL := $(DESTDIR)$(SYSCONFDIR)/apptainer/driliblist.conf
+$(dri_liblist_INSTALL): $(dri_liblist)
+	@echo " INSTALL" $@
+	$(V)umask 0022 && mkdir -p $(@D)
+	$(V)install -m 0644 $< $@
+
+INSTALLFILES += $(dri_liblist_INSTALL)
+
+
 # cgroups config file
 cgroups_config := $(SOURCEDIR)/internal/pkg/cgroups/example/cgroups.toml
 
@@ -29,6 +29,7 @@ package types
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -70,6 +71,9 @@ type Options struct {
 	LibraryAuthToken string `json:"libraryAuthToken"`
 	// Path to fakeroot command will be empty if not needed or not available
 	FakerootPath string `json:"fakerootPath"`
+	// FakerootCaps is the list of capabilities set during the post
+	// section when building with fakeroot, in place of all capabilities.
+	FakerootCaps string `json:"fakerootCaps"`
 	// KeyServerOpts contains options for keyserver used for SIF fingerprint verification in builds.
 	KeyServerOpts []keyClient.Option
 	// If non-nil, provides credentials to be used when authenticating to OCI registries.
@@ -98,10 +102,22 @@ type Options struct {
 	NoCleanUp bool `json:"noCleanUp"`
 	// NoCache when true, will not use any cache, or make cache.
 	NoCache bool
+	// NoSectionCache when true, will execute all sections when updating a
+	// sandbox, even if they are unchanged since the last build.
+	NoSectionCache bool
 	// FixPerms controls if we will ensure owner rwX on container content
 	// to preserve <=3.4 behavior.
 	// TODO: Deprecate in 3.6, remove in 3.8
 	FixPerms bool
+	// StripDocs removes documentation from the root filesystem once
+	// all sections ran.
+	StripDocs bool
+	// StripLocales removes locales from the root filesystem once
+	// all sections ran.
+	StripLocales bool
+	// StripPaths lists additional paths, which can contain shell
+	// patterns, removed from the root filesystem once all sections ran.
+	StripPaths []string
 	// To warn when the above is needed, we need to know if the target of this
 	// bundle will be a sandbox
 	SandboxTarget bool
@@ -115,6 +131,9 @@ type Options struct {
 	ReqAuthFile string
 	// Which Platform to use when retrieving images for the build
 	Platform ggcrv1.Platform
+	// JSONProgress, if non-nil, receives the build progress events as
+	// newline-delimited JSON objects.
+	JSONProgress io.Writer `json:"-"`
 }
 
 // NewEncryptedBundle creates an Encrypted Bundle environment.
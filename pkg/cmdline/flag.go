@@ -88,6 +88,11 @@ func (m *flagManager) registerFlagForCmd(flag *Flag, cmds ...*cobra.Command) err
 	if flag.EnvHandler == nil {
 		flag.EnvHandler = EnvSetValue
 	}
+	if v, ok := flag.Value.(pflag.Value); ok {
+		m.registerValueVar(flag, v, cmds)
+		m.flags[flag.ID] = flag
+		return nil
+	}
 	switch flag.DefaultValue.(type) {
 	case string:
 		m.registerStringVar(flag, cmds)
@@ -195,6 +200,21 @@ func (m *flagManager) registerUint32Var(flag *Flag, cmds []*cobra.Command) error
 	return nil
 }
 
+// registerValueVar registers a flag holding a custom pflag.Value, the
+// default value is the value held by the variable at registration time.
+// Values implementing IsBoolFlag() returning true can be passed without
+// argument like boolean flags.
+func (m *flagManager) registerValueVar(flag *Flag, value pflag.Value, cmds []*cobra.Command) error {
+	for _, c := range cmds {
+		f := c.Flags().VarPF(value, flag.Name, flag.ShortHand, flag.Usage)
+		if bv, ok := value.(interface{ IsBoolFlag() bool }); ok && bv.IsBoolFlag() {
+			f.NoOptDefVal = "true"
+		}
+		m.setFlagOptions(flag, c)
+	}
+	return nil
+}
+
 func (m *flagManager) updateCmdFlagFromEnv(cmd *cobra.Command, precedence int, foundKeys map[string]string) error {
 	var errs []error
 	var prefix string
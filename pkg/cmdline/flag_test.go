@@ -24,8 +24,35 @@ var (
 	testInt         int
 	testUint32      uint32
 	testStringMap   map[string]string
+	testValue       = &boolStringValue{}
 )
 
+// boolStringValue is a custom flag value accepting a boolean
+// or an arbitrary string.
+type boolStringValue struct {
+	value string
+}
+
+func (v *boolStringValue) Set(s string) error {
+	v.value = s
+	return nil
+}
+
+func (v *boolStringValue) String() string {
+	if v.value == "" {
+		return "false"
+	}
+	return v.value
+}
+
+func (v *boolStringValue) Type() string {
+	return "boolString"
+}
+
+func (v *boolStringValue) IsBoolFlag() bool {
+	return true
+}
+
 var ttData = []struct {
 	desc       string
 	flag       *Flag
@@ -120,6 +147,19 @@ var ttData = []struct {
 		envValue:   "1",
 		matchValue: "true",
 	},
+	{
+		desc: "custom value flag",
+		flag: &Flag{
+			ID:      "testValueFlag",
+			Value:   testValue,
+			Name:    "value",
+			Usage:   "a custom value flag",
+			EnvKeys: []string{"VALUE"},
+		},
+		cmd:        parentCmd,
+		envValue:   "container:test",
+		matchValue: "container:test",
+	},
 	{
 		desc: "boolean flag (short)",
 		flag: &Flag{
@@ -29,6 +29,12 @@ const (
 	OverlayFeature
 	// FuseFeature means the driver uses FUSE as its base.
 	FuseFeature
+	// ErofsFeature means the driver handles EROFS image mounts.
+	ErofsFeature
+	// EncryptFeature means the driver handles encrypted image mounts,
+	// both encryptfs and gocryptfs, with the decryption key passed in
+	// MountParams.Key. It takes precedence over GocryptFeature.
+	EncryptFeature
 )
 
 // ImageFeature means the driver handles any of the image mount types
@@ -75,6 +81,41 @@ type Driver interface {
 	Features() DriverFeature
 }
 
+// Flusher is an optional interface implemented by image drivers caching
+// data of mount targets which must be flushed before they are unmounted.
+type Flusher interface {
+	// Flush is called for each mount target during container cleanup,
+	// once the container process exited and before the driver is
+	// stopped and the target unmounted.
+	Flush(string) error
+}
+
+// FlushDriver flushes the given mount target if the driver implements
+// the Flusher interface, it does nothing otherwise.
+func FlushDriver(driver Driver, target string) error {
+	if f, ok := driver.(Flusher); ok {
+		return f.Flush(target)
+	}
+	return nil
+}
+
+// Checker is an optional interface implemented by image drivers which
+// may be registered while some of their dependencies are not usable.
+type Checker interface {
+	// Available returns an error naming the missing or unusable
+	// dependency when the driver can't be used.
+	Available() error
+}
+
+// DriverAvailable returns the error reported by the driver if it implements
+// the Checker interface, a driver not implementing it is always available.
+func DriverAvailable(driver Driver) error {
+	if c, ok := driver.(Checker); ok {
+		return c.Available()
+	}
+	return nil
+}
+
 // drivers holds all registered image drivers
 var drivers = make(map[string]Driver)
 
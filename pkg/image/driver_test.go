@@ -0,0 +1,73 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package image
+
+import (
+	"fmt"
+	"testing"
+)
+
+type testDriver struct{}
+
+func (d *testDriver) Mount(*MountParams, MountFunc) error  { return nil }
+func (d *testDriver) MountErr() error                      { return nil }
+func (d *testDriver) Start(*DriverParams, int, bool) error { return nil }
+func (d *testDriver) Stop(string) error                    { return nil }
+func (d *testDriver) Features() DriverFeature              { return 0 }
+
+type testFlushDriver struct {
+	testDriver
+	flushed []string
+}
+
+func (d *testFlushDriver) Flush(target string) error {
+	if target == "" {
+		return fmt.Errorf("empty target")
+	}
+	d.flushed = append(d.flushed, target)
+	return nil
+}
+
+func TestFlushDriver(t *testing.T) {
+	if err := FlushDriver(&testDriver{}, "/mnt"); err != nil {
+		t.Errorf("unexpected error for a driver without Flush: %s", err)
+	}
+
+	d := &testFlushDriver{}
+	if err := FlushDriver(d, "/mnt"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if len(d.flushed) != 1 || d.flushed[0] != "/mnt" {
+		t.Errorf("unexpected flushed targets %v", d.flushed)
+	}
+	if err := FlushDriver(d, ""); err == nil {
+		t.Errorf("unexpected success with an empty target")
+	}
+}
+
+type testCheckDriver struct {
+	testDriver
+	err error
+}
+
+func (d *testCheckDriver) Available() error {
+	return d.err
+}
+
+func TestDriverAvailable(t *testing.T) {
+	if err := DriverAvailable(&testDriver{}); err != nil {
+		t.Errorf("unexpected error for a driver without Available: %s", err)
+	}
+	if err := DriverAvailable(&testCheckDriver{}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := DriverAvailable(&testCheckDriver{err: fmt.Errorf("squashfuse not found")}); err == nil {
+		t.Errorf("unexpected success with an unavailable driver")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+const (
+	// the EROFS super block starts 1024 bytes after the beginning
+	// of the filesystem with the little-endian magic 0xE0F5E1E2
+	erofsMagic       = "\xe2\xe1\xf5\xe0"
+	erofsMagicOffset = 1024
+)
+
+// CheckErofsHeader checks if byte content contains a valid EROFS header.
+func CheckErofsHeader(b []byte) error {
+	if len(b) < erofsMagicOffset+len(erofsMagic) {
+		return fmt.Errorf("can't find EROFS super block")
+	}
+	if !bytes.Equal(b[erofsMagicOffset:erofsMagicOffset+len(erofsMagic)], []byte(erofsMagic)) {
+		return fmt.Errorf("not a valid EROFS image")
+	}
+	return nil
+}
+
+type erofsFormat struct{}
+
+func (f *erofsFormat) detect(img *Image, fileinfo os.FileInfo) error {
+	if fileinfo.IsDir() {
+		return debugError("not an EROFS image")
+	}
+	b, err := readHeader(img)
+	if err != nil {
+		return err
+	}
+	if err := CheckErofsHeader(b); err != nil {
+		return debugErrorf("while checking EROFS super block: %v", err)
+	}
+	img.Type = EROFS
+	return nil
+}
+
+func (f *erofsFormat) initializer(img *Image, fileinfo os.FileInfo) error {
+	img.Partitions = []Section{
+		{
+			Offset:       0,
+			Size:         uint64(fileinfo.Size()),
+			ID:           1,
+			Type:         EROFS,
+			Name:         RootFs,
+			AllowedUsage: RootFsUsage | OverlayUsage | DataUsage,
+		},
+	}
+
+	if img.Writable {
+		// we set Writable to appropriate value to match the
+		// image open mode as some code may want to ignore this
+		// error by using IsReadOnlyFilesytem check
+		img.Writable = false
+
+		return &readOnlyFilesystemError{
+			"could not set " + img.Path + " image writable: EROFS is a read-only filesystem",
+		}
+	}
+
+	return nil
+}
+
+func (f *erofsFormat) openMode(_ bool) int {
+	return os.O_RDONLY
+}
+
+func (f *erofsFormat) lock(_ *Image) error {
+	return nil
+}
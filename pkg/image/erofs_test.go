@@ -0,0 +1,74 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package image
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// erofsHeader returns a buffer with an EROFS super block magic, it's
+// enough to identify the format but not to mount it.
+func erofsHeader() []byte {
+	b := make([]byte, 4096)
+	copy(b[erofsMagicOffset:], erofsMagic)
+	return b
+}
+
+func TestCheckErofsHeader(t *testing.T) {
+	if err := CheckErofsHeader(erofsHeader()); err != nil {
+		t.Errorf("unexpected error for a valid EROFS header: %s", err)
+	}
+	if err := CheckErofsHeader(make([]byte, bufferSize)); err == nil {
+		t.Errorf("unexpected success for an invalid EROFS header")
+	}
+	if err := CheckErofsHeader(make([]byte, 16)); err == nil {
+		t.Errorf("unexpected success for a truncated EROFS header")
+	}
+}
+
+func TestErofsInit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image.erofs")
+	if err := os.WriteFile(path, erofsHeader(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := Init(path, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer img.File.Close()
+
+	if img.Type != EROFS {
+		t.Errorf("unexpected image type %d", img.Type)
+	}
+	part, err := img.GetRootFsPartition()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if part.Type != EROFS || part.FSType() != "erofs" || part.Size != 4096 {
+		t.Errorf("unexpected root filesystem partition: %+v", part)
+	}
+
+	if _, err := Init(path, true); !IsReadOnlyFilesytem(err) {
+		t.Errorf("unexpected error for a writable EROFS image: %v", err)
+	}
+}
+
+func TestErofsOpenMode(t *testing.T) {
+	var erofsfmt erofsFormat
+
+	if erofsfmt.openMode(true) != os.O_RDONLY {
+		t.Fatal("openMode(true) returned the wrong value")
+	}
+	if erofsfmt.openMode(false) != os.O_RDONLY {
+		t.Fatal("openMode(false) returned the wrong value")
+	}
+}
@@ -29,6 +29,11 @@ const (
 	rocompatBtreeDir    = 0x4
 )
 
+const (
+	extSuperblockOffset = 1024
+	extSuperblockSize   = 1024
+)
+
 const notValidExt3ImageMessage = "file is not a valid ext3 image"
 
 type extFSInfo struct {
@@ -40,6 +45,68 @@ type extFSInfo struct {
 	Rocompat uint32
 }
 
+// extSuperblock holds the beginning of an ext2/3/4 superblock.
+type extSuperblock struct {
+	InodesCount     uint32
+	BlocksCount     uint32
+	RBlocksCount    uint32
+	FreeBlocksCount uint32
+	FreeInodesCount uint32
+	FirstDataBlock  uint32
+	LogBlockSize    uint32
+	Dummy           [7]uint32
+	Magic           [2]byte
+}
+
+// Ext3Usage holds the block usage of an EXT3 partition as
+// reported by its superblock.
+type Ext3Usage struct {
+	BlockSize   uint64 `json:"blockSize"`
+	TotalBlocks uint64 `json:"totalBlocks"`
+	FreeBlocks  uint64 `json:"freeBlocks"`
+}
+
+// UsedBlocks returns the number of blocks in use.
+func (u *Ext3Usage) UsedBlocks() uint64 {
+	return u.TotalBlocks - u.FreeBlocks
+}
+
+// FreeBytes returns the free space in bytes, including
+// blocks reserved for the root user.
+func (u *Ext3Usage) FreeBytes() uint64 {
+	return u.FreeBlocks * u.BlockSize
+}
+
+// GetExt3Usage reads the superblock of the EXT3 partition part and
+// returns its block usage, without mounting the partition.
+func (i *Image) GetExt3Usage(part Section) (*Ext3Usage, error) {
+	if part.Type != EXT3 {
+		return nil, fmt.Errorf("partition %s is not an ext3 partition", part.Name)
+	}
+	if i.File == nil {
+		return nil, fmt.Errorf("image %s is not opened", i.Path)
+	}
+
+	b := make([]byte, extSuperblockSize)
+	if _, err := i.File.ReadAt(b, int64(part.Offset)+extSuperblockOffset); err != nil {
+		return nil, fmt.Errorf("while reading ext3 superblock of partition %s: %s", part.Name, err)
+	}
+
+	sb := &extSuperblock{}
+	if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, sb); err != nil {
+		return nil, fmt.Errorf("while decoding ext3 superblock of partition %s: %s", part.Name, err)
+	}
+	if !bytes.Equal(sb.Magic[:], []byte(extMagic)) {
+		return nil, fmt.Errorf("partition %s: %s", part.Name, notValidExt3ImageMessage)
+	}
+
+	return &Ext3Usage{
+		BlockSize:   1024 << sb.LogBlockSize,
+		TotalBlocks: uint64(sb.BlocksCount),
+		FreeBlocks:  uint64(sb.FreeBlocksCount),
+	}, nil
+}
+
 type ext3Format struct{}
 
 // CheckExt3Header checks if byte content contains a valid ext3 header
@@ -77,7 +144,7 @@ func CheckExt3Header(b []byte) (uint64, error) {
 	return offset, nil
 }
 
-func (f *ext3Format) initializer(img *Image, fileinfo os.FileInfo) error {
+func (f *ext3Format) detect(img *Image, fileinfo os.FileInfo) error {
 	if fileinfo.IsDir() {
 		return debugError("not an ext3 image")
 	}
@@ -85,11 +152,22 @@ func (f *ext3Format) initializer(img *Image, fileinfo os.FileInfo) error {
 	if n, err := img.File.Read(b); err != nil || n != bufferSize {
 		return debugErrorf("can't read first %d bytes: %v", bufferSize, err)
 	}
+	if _, err := CheckExt3Header(b); err != nil {
+		return err
+	}
+	img.Type = EXT3
+	return nil
+}
+
+func (f *ext3Format) initializer(img *Image, fileinfo os.FileInfo) error {
+	b, err := readHeader(img)
+	if err != nil {
+		return err
+	}
 	offset, err := CheckExt3Header(b)
 	if err != nil {
 		return err
 	}
-	img.Type = EXT3
 	img.Partitions = []Section{
 		{
 			Offset:       offset,
@@ -98,6 +176,7 @@ func (f *ext3Format) initializer(img *Image, fileinfo os.FileInfo) error {
 			Type:         EXT3,
 			Name:         RootFs,
 			AllowedUsage: RootFsUsage | OverlayUsage | DataUsage,
+			Writable:     true,
 		},
 	}
 
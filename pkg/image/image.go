@@ -14,6 +14,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -38,6 +40,8 @@ const (
 	RAW
 	// GOCRYPTFS constant for encrypted gocryptfs format
 	GOCRYPTFSSQUASHFS
+	// EROFS constant for EROFS format
+	EROFS
 )
 
 type Usage uint8
@@ -107,15 +111,31 @@ var registeredFormats = []struct {
 	{"sif", &sifFormat{}},
 	{"squashfs", &squashfsFormat{}},
 	{"ext3", &ext3Format{}},
+	{"erofs", &erofsFormat{}},
 }
 
 // format describes the interface that an image format type must implement.
 type format interface {
 	openMode(bool) int
+	// detect checks the image header to identify the format and sets
+	// the image type, it returns a debugError on format mismatch.
+	detect(*Image, os.FileInfo) error
+	// initializer parses the image partitions and sections once the
+	// format has been detected.
 	initializer(*Image, os.FileInfo) error
 	lock(*Image) error
 }
 
+// readHeader returns the first bytes of the image file used
+// to identify the image format.
+func readHeader(img *Image) ([]byte, error) {
+	b := make([]byte, bufferSize)
+	if n, err := img.File.ReadAt(b, 0); err != nil || n != bufferSize {
+		return nil, debugErrorf("can't read first %d bytes: %v", bufferSize, err)
+	}
+	return b, nil
+}
+
 // Section identifies and locates a data section in image object.
 type Section struct {
 	Name         string `json:"name"`
@@ -124,6 +144,32 @@ type Section struct {
 	ID           uint32 `json:"id"`
 	Type         uint32 `json:"type"`
 	AllowedUsage Usage  `json:"allowed_usage"`
+	// Writable is set for partitions with a filesystem that can
+	// be mounted read-write (EXT3).
+	Writable bool `json:"writable"`
+	// Compression is the compression algorithm of squashfs
+	// partitions as reported by GetSquashfsComp.
+	Compression string `json:"compression,omitempty"`
+}
+
+// FSType returns the name of the filesystem format of a partition,
+// or an empty string if the format is unknown.
+func (s Section) FSType() string {
+	switch s.Type {
+	case SQUASHFS:
+		return "squashfs"
+	case EXT3:
+		return "ext3"
+	case ENCRYPTSQUASHFS:
+		return "encryptfs"
+	case GOCRYPTFSSQUASHFS:
+		return "gocryptfs"
+	case RAW:
+		return "raw"
+	case EROFS:
+		return "erofs"
+	}
+	return ""
 }
 
 // Image describes an image object, an image is composed of one
@@ -141,6 +187,9 @@ type Image struct {
 	Fd         uintptr   `json:"fd"`
 	Writable   bool      `json:"writable"`
 	Usage      Usage     `json:"usage"`
+	// Binds lists the bind path specifications recommended by the
+	// image, read from the SIF descriptor SIFDescBindsJSON.
+	Binds []string `json:"binds,omitempty"`
 }
 
 // ReInit fills in the File object if needed.  This function should be
@@ -259,6 +308,62 @@ func (i *Image) GetDataPartitions() ([]Section, error) {
 	return i.getPartitions(DataUsage)
 }
 
+// GetDataPartitionsByType returns data partitions found in the image
+// with a filesystem type matching one of types (eg: SQUASHFS, EXT3).
+func (i *Image) GetDataPartitionsByType(types ...uint32) ([]Section, error) {
+	partitions, err := i.GetDataPartitions()
+	if err != nil {
+		return nil, err
+	}
+
+	sections := make([]Section, 0, len(partitions))
+	for _, p := range partitions {
+		if slices.Contains(types, p.Type) {
+			sections = append(sections, p)
+		}
+	}
+
+	return sections, nil
+}
+
+// GetPartitionByName returns the partition named name found in the image,
+// an error is returned if there is no such partition or if several
+// partitions share the same name.
+func (i *Image) GetPartitionByName(name string) (*Section, error) {
+	partitions, err := i.GetAllPartitions()
+	if err != nil {
+		return nil, err
+	}
+
+	var found *Section
+	ids := make([]string, 0)
+	names := make([]string, 0)
+	for idx, p := range partitions {
+		if p.Name != name {
+			if p.Name != "" && !slices.Contains(names, p.Name) {
+				names = append(names, p.Name)
+			}
+			continue
+		}
+		if found == nil {
+			found = &partitions[idx]
+		}
+		ids = append(ids, strconv.FormatUint(uint64(p.ID), 10))
+	}
+
+	switch len(ids) {
+	case 0:
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no partition named %q found in %s, no partition has a name", name, i.Path)
+		}
+		return nil, fmt.Errorf("no partition named %q found in %s, available partition names: %s", name, i.Path, strings.Join(names, ", "))
+	case 1:
+		return found, nil
+	default:
+		return nil, fmt.Errorf("partition name %q is ambiguous in %s, matching partition IDs: %s", name, i.Path, strings.Join(ids, ", "))
+	}
+}
+
 // EncryptedRootFs returns "encryptfs" if the image contains a device-mapper
 // encrypted root partition, "gocryptfs" if it contains a gocryptfs
 // encrypted root partition, or an empty string if there is no encryption
@@ -369,10 +474,64 @@ func ResolvePath(path string) (string, error) {
 	return resolvedPath, nil
 }
 
+// InitOptions configures the image initialization done by
+// InitWithOptions.
+type InitOptions struct {
+	// Writable opens the image for writing when the image format and
+	// the file permissions allow it.
+	Writable bool
+	// DetectOnly stops the image initialization once the image format
+	// is detected, see OptDetectOnly.
+	DetectOnly bool
+	// HeaderOnly limits the image initialization to the image header
+	// and partition table, see OptHeaderOnly.
+	HeaderOnly bool
+}
+
+// InitOption configures the image initialization done by Init.
+type InitOption func(*InitOptions)
+
+// OptDetectOnly stops the image initialization once the image format
+// is detected, for callers only interested in the image type. Image
+// partitions and sections are not parsed, the image is not locked and
+// the returned image doesn't hold an open file descriptor (File is nil).
+func OptDetectOnly() InitOption {
+	return func(o *InitOptions) {
+		o.DetectOnly = true
+	}
+}
+
+// OptHeaderOnly limits the image initialization to the image header and
+// partition table, for callers only interested in the image type and
+// partition metadata, like when listing many images. The image is opened
+// read-only and closed once parsed, it's not locked, Source is empty and
+// the returned image doesn't hold an open file descriptor (File is nil),
+// so it can't be used to mount the image.
+func OptHeaderOnly() InitOption {
+	return func(o *InitOptions) {
+		o.HeaderOnly = true
+	}
+}
+
 // Init initializes an image object based on given path.
-func Init(path string, writable bool) (*Image, error) {
+func Init(path string, writable bool, opts ...InitOption) (*Image, error) {
+	o := InitOptions{Writable: writable}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return InitWithOptions(path, o)
+}
+
+// InitWithOptions initializes an image object based on given path and
+// initialization options.
+func InitWithOptions(path string, o InitOptions) (*Image, error) {
 	sylog.Debugf("Image format detection")
 
+	writable := o.Writable
+	if o.HeaderOnly {
+		writable = false
+	}
+
 	resolvedPath, err := ResolvePath(path)
 	if err != nil {
 		return nil, err
@@ -414,6 +573,23 @@ func Init(path string, writable bool) (*Image, error) {
 			return nil, err
 		}
 
+		detectErr := rf.format.detect(img, fileinfo)
+		if _, ok := detectErr.(debugError); ok {
+			sylog.Debugf("%s format detection returned: %v", rf.name, detectErr)
+			_ = img.File.Close()
+			continue
+		} else if detectErr != nil {
+			_ = img.File.Close()
+			return nil, detectErr
+		}
+
+		if o.DetectOnly {
+			sylog.Debugf("%s image format detected", rf.name)
+			_ = img.File.Close()
+			img.File = nil
+			return img, nil
+		}
+
 		// readOnlyFilesystemError is allowed here and passed back
 		// to the caller because there is basically no error with
 		// the image format just a mismatch with writable parameter,
@@ -430,6 +606,12 @@ func Init(path string, writable bool) (*Image, error) {
 
 		sylog.Debugf("%s image format detected", rf.name)
 
+		if o.HeaderOnly {
+			_ = img.File.Close()
+			img.File = nil
+			return img, nil
+		}
+
 		if _, _, err := syscall.Syscall(syscall.SYS_FCNTL, img.File.Fd(), syscall.F_SETFD, syscall.O_CLOEXEC); err != 0 {
 			sylog.Warningf("failed to set O_CLOEXEC flags on image")
 		}
@@ -16,6 +16,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
@@ -92,6 +93,170 @@ func checkSection(_ *testing.T, reader io.Reader) error {
 	return nil
 }
 
+// initFormat runs the format detection followed by the format
+// initializer, as done by Init.
+func initFormat(f format, img *Image, fi os.FileInfo) error {
+	if err := f.detect(img, fi); err != nil {
+		return err
+	}
+	return f.initializer(img, fi)
+}
+
+func TestInitDetectOnly(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"SIF", busyboxSIF},
+		{"Sandbox", t.TempDir()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Init(tt.path, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			img.File.Close()
+
+			detected, err := Init(tt.path, false, OptDetectOnly())
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if detected.Type != img.Type {
+				t.Errorf("unexpected image format: %v instead of %v", detected.Type, img.Type)
+			}
+			if detected.File != nil || detected.Fd != emptyFd {
+				t.Errorf("unexpected open file for detected image")
+			}
+			if len(detected.Partitions) != 0 || len(detected.Sections) != 0 {
+				t.Errorf("unexpected partitions or sections for detected image")
+			}
+		})
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("cannot create temporary file: %s", err)
+	}
+	f.Close()
+	if _, err := Init(f.Name(), false, OptDetectOnly()); err != ErrUnknownFormat {
+		t.Errorf("unexpected error for unknown format: %v", err)
+	}
+}
+
+func TestInitHeaderOnly(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"SIF", busyboxSIF},
+		{"Sandbox", t.TempDir()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Init(tt.path, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			img.File.Close()
+
+			header, err := Init(tt.path, true, OptHeaderOnly())
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if header.Type != img.Type {
+				t.Errorf("unexpected image format: %v instead of %v", header.Type, img.Type)
+			}
+			if header.File != nil || header.Fd != emptyFd || header.Source != "" {
+				t.Errorf("unexpected open file for image header")
+			}
+			if header.Writable {
+				t.Errorf("unexpected writable image header")
+			}
+			if !reflect.DeepEqual(header.Partitions, img.Partitions) || !reflect.DeepEqual(header.Sections, img.Sections) {
+				t.Errorf("got partitions %v and sections %v, expected %v and %v", header.Partitions, header.Sections, img.Partitions, img.Sections)
+			}
+		})
+	}
+}
+
+func TestInitWithOptions(t *testing.T) {
+	img, err := Init(busyboxSIF, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	img.File.Close()
+
+	header, err := InitWithOptions(busyboxSIF, InitOptions{Writable: true, HeaderOnly: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if header.File != nil || header.Writable {
+		t.Errorf("unexpected open or writable image header")
+	}
+	if !reflect.DeepEqual(header.Partitions, img.Partitions) {
+		t.Errorf("got partitions %v, expected %v", header.Partitions, img.Partitions)
+	}
+
+	detected, err := InitWithOptions(busyboxSIF, InitOptions{DetectOnly: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if detected.Type != img.Type || detected.File != nil || len(detected.Partitions) != 0 {
+		t.Errorf("unexpected detected image %+v", detected)
+	}
+
+	full, err := InitWithOptions(busyboxSIF, InitOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer full.File.Close()
+	if full.File == nil || full.Writable {
+		t.Errorf("unexpected image file %v or writable image", full.File)
+	}
+}
+
+func TestGetDataPartitionsByType(t *testing.T) {
+	img := &Image{
+		Usage: DataUsage,
+		Partitions: []Section{
+			{ID: 1, Type: EXT3, AllowedUsage: DataUsage},
+			{ID: 2, Type: SQUASHFS, AllowedUsage: RootFsUsage},
+			{ID: 3, Type: SQUASHFS, AllowedUsage: DataUsage},
+			{ID: 4, Type: EROFS, AllowedUsage: DataUsage},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		types []uint32
+		ids   []uint32
+	}{
+		{name: "Squashfs", types: []uint32{SQUASHFS}, ids: []uint32{3}},
+		{name: "Multiple", types: []uint32{EROFS, EXT3}, ids: []uint32{1, 4}},
+		{name: "NoMatch", types: []uint32{SANDBOX}},
+		{name: "NoType"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			partitions, err := img.GetDataPartitionsByType(tt.types...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			ids := make([]uint32, 0)
+			for _, p := range partitions {
+				ids = append(ids, p.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+				t.Errorf("got partitions %v, expected %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
 func TestReader(t *testing.T) {
 	if testing.Short() {
 		t.SkipNow()
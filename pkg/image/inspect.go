@@ -0,0 +1,106 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package image
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/apptainer/sif/v2/pkg/sif"
+)
+
+// Partition types reported by InspectPartitions.
+const (
+	PartitionRootFs  = "rootfs"
+	PartitionSystem  = "system"
+	PartitionData    = "data"
+	PartitionOverlay = "overlay"
+)
+
+// PartitionInfo describes a SIF partition as reported by InspectPartitions.
+type PartitionInfo struct {
+	ID   uint32 `json:"id"`
+	Name string `json:"name"`
+	// Type is one of the Partition* constants.
+	Type string `json:"type"`
+	// FSType is the filesystem format identified from the partition
+	// header as returned by Section.FSType, or empty if the header
+	// doesn't match the filesystem recorded in the SIF descriptor.
+	FSType string `json:"fstype"`
+	// Compression is the compression algorithm of squashfs partitions.
+	Compression string `json:"compression,omitempty"`
+	Offset      uint64 `json:"offset"`
+	Size        uint64 `json:"size"`
+	GroupID     uint32 `json:"group_id"`
+	Arch        string `json:"arch"`
+}
+
+// InspectPartitions returns the partitions of the SIF image at path in
+// the descriptor table order. The image is only opened read-only to read
+// the descriptor table and partition headers, nothing is mounted so it
+// doesn't require any privilege.
+func InspectPartitions(path string) ([]PartitionInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("while opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	fimg, err := sif.LoadContainer(f,
+		sif.OptLoadWithFlag(os.O_RDONLY),
+		sif.OptLoadWithCloseOnUnload(false),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("while loading SIF %s: %w", path, err)
+	}
+	defer fimg.UnloadContainer()
+
+	// checkPartitionType only reads partition headers from the file
+	img := &Image{Path: path, File: f}
+
+	partitions := make([]PartitionInfo, 0)
+	fimg.WithDescriptors(func(desc sif.Descriptor) bool {
+		fstype, ptype, arch, err := desc.PartitionMetadata()
+		if err != nil {
+			return false
+		}
+
+		info := PartitionInfo{
+			ID:      desc.ID(),
+			Name:    desc.Name(),
+			Offset:  uint64(desc.Offset()),
+			Size:    uint64(desc.Size()),
+			GroupID: desc.GroupID(),
+			Arch:    arch,
+		}
+
+		switch ptype {
+		case sif.PartPrimSys:
+			info.Type = PartitionRootFs
+		case sif.PartSystem:
+			info.Type = PartitionSystem
+		case sif.PartOverlay:
+			info.Type = PartitionOverlay
+		default:
+			info.Type = PartitionData
+		}
+
+		if htype, err := checkPartitionType(img, fstype, desc.Offset()); err == nil {
+			info.FSType = Section{Type: htype}.FSType()
+			if htype == SQUASHFS {
+				info.Compression = squashfsCompression(img, desc.Offset())
+			}
+		}
+
+		partitions = append(partitions, info)
+		return false
+	})
+
+	return partitions, nil
+}
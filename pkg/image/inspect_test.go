@@ -0,0 +1,73 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package image
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/apptainer/sif/v2/pkg/sif"
+)
+
+func TestInspectPartitions(t *testing.T) {
+	squash, err := os.ReadFile(testSquash)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", testSquash, err)
+	}
+
+	part := func(name string, fstype sif.FSType, ptype sif.PartType, data []byte) func() (sif.DescriptorInput, error) {
+		return func() (sif.DescriptorInput, error) {
+			return sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(data),
+				sif.OptObjectName(name),
+				sif.OptGroupID(1),
+				sif.OptPartitionMetadata(fstype, ptype, runtime.GOARCH),
+			)
+		}
+	}
+
+	path := createSIF(t, false,
+		part("root", sif.FsSquash, sif.PartPrimSys, squash),
+		part("data", sif.FsRaw, sif.PartData, erofsHeader()),
+		// the header doesn't match the squashfs filesystem type
+		part("overlay", sif.FsSquash, sif.PartOverlay, make([]byte, 4096)),
+	)
+	defer os.Remove(path)
+
+	partitions, err := InspectPartitions(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []PartitionInfo{
+		{ID: 1, Name: "root", Type: PartitionRootFs, FSType: "squashfs", Compression: "gzip", Size: uint64(len(squash))},
+		{ID: 2, Name: "data", Type: PartitionData, FSType: "erofs", Size: 4096},
+		{ID: 3, Name: "overlay", Type: PartitionOverlay, FSType: "", Size: 4096},
+	}
+	if len(partitions) != len(expected) {
+		t.Fatalf("got %d partitions, expected %d", len(partitions), len(expected))
+	}
+	for i, p := range partitions {
+		if p.Offset == 0 || p.GroupID != 1 || p.Arch != runtime.GOARCH {
+			t.Errorf("unexpected partition %+v", p)
+		}
+		p.Offset = 0
+		p.GroupID = 0
+		p.Arch = ""
+		if !reflect.DeepEqual(p, expected[i]) {
+			t.Errorf("got partition %+v, expected %+v", p, expected[i])
+		}
+	}
+
+	if _, err := InspectPartitions(testSquash); err == nil {
+		t.Errorf("unexpected success with a non SIF image")
+	}
+}
@@ -15,12 +15,15 @@ import (
 
 type sandboxFormat struct{}
 
-func (f *sandboxFormat) initializer(img *Image, fi os.FileInfo) error {
-	if fi.IsDir() {
-		img.Type = SANDBOX
-	} else {
+func (f *sandboxFormat) detect(img *Image, fi os.FileInfo) error {
+	if !fi.IsDir() {
 		return debugError("not a directory image")
 	}
+	img.Type = SANDBOX
+	return nil
+}
+
+func (f *sandboxFormat) initializer(img *Image, _ os.FileInfo) error {
 	img.Partitions = []Section{
 		{
 			Type:         SANDBOX,
@@ -11,11 +11,13 @@ package image
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"runtime"
 
 	"github.com/apptainer/apptainer/internal/pkg/util/machine"
+	"github.com/apptainer/apptainer/pkg/sylog"
 	"github.com/apptainer/sif/v2/pkg/sif"
 )
 
@@ -24,8 +26,31 @@ const (
 	SIFDescOCIConfigJSON = "oci-config.json"
 	// SIFDescInspectMetadataJSON is the name of the SIF descriptor holding the container metadata.
 	SIFDescInspectMetadataJSON = "inspect-metadata.json"
+	// SIFDescBindsJSON is the name of the SIF descriptor holding the
+	// JSON array of bind path specifications recommended by the image.
+	SIFDescBindsJSON = "apptainer.binds"
 )
 
+// maxBindsSize is the maximum size of the SIF binds descriptor.
+const maxBindsSize = 1 << 20
+
+// readBinds decodes the bind path specifications stored in the SIF
+// binds descriptor, in the same src[:dst[:options]] format as --bind.
+func readBinds(desc sif.Descriptor) ([]string, error) {
+	if desc.Size() > maxBindsSize {
+		return nil, fmt.Errorf("descriptor size exceeds %d bytes", maxBindsSize)
+	}
+	b, err := desc.GetData()
+	if err != nil {
+		return nil, err
+	}
+	var binds []string
+	if err := json.Unmarshal(b, &binds); err != nil {
+		return nil, err
+	}
+	return binds, nil
+}
+
 type sifFormat struct{}
 
 func checkPartitionType(img *Image, fstype sif.FSType, offset int64) (uint32, error) {
@@ -49,6 +74,11 @@ func checkPartitionType(img *Image, fstype sif.FSType, offset int64) (uint32, er
 	case sif.FsEncryptedSquashfs:
 		return ENCRYPTSQUASHFS, nil
 	case sif.FsRaw:
+		// SIF has no dedicated EROFS filesystem type, EROFS
+		// partitions are identified by their super block
+		if CheckErofsHeader(header) == nil {
+			return EROFS, nil
+		}
 		return RAW, nil
 	case sif.FsGocryptfsSquashfs:
 		return GOCRYPTFSSQUASHFS, nil
@@ -57,7 +87,7 @@ func checkPartitionType(img *Image, fstype sif.FSType, offset int64) (uint32, er
 	return 0, fmt.Errorf("unknown filesystem type %v", fstype)
 }
 
-func (f *sifFormat) initializer(img *Image, fi os.FileInfo) error {
+func (f *sifFormat) detect(img *Image, fi os.FileInfo) error {
 	if fi.IsDir() {
 		return debugError("not a sif file image")
 	}
@@ -68,7 +98,11 @@ func (f *sifFormat) initializer(img *Image, fi os.FileInfo) error {
 	if !bytes.Contains(b, []byte("SIF_MAGIC")) {
 		return debugError("SIF magic not found")
 	}
+	img.Type = SIF
+	return nil
+}
 
+func (f *sifFormat) initializer(img *Image, _ os.FileInfo) error {
 	flag := os.O_RDONLY
 	if img.Writable {
 		flag = os.O_RDWR
@@ -119,6 +153,9 @@ func (f *sifFormat) initializer(img *Image, fi os.FileInfo) error {
 				AllowedUsage: RootFsUsage,
 			},
 		}
+		if htype == SQUASHFS {
+			img.Partitions[0].Compression = squashfsCompression(img, desc.Offset())
+		}
 	}
 
 	fimg.WithDescriptors(func(desc sif.Descriptor) bool {
@@ -152,6 +189,10 @@ func (f *sifFormat) initializer(img *Image, fi os.FileInfo) error {
 				Name:         desc.Name(),
 				Type:         htype,
 				AllowedUsage: usage,
+				Writable:     htype == EXT3,
+			}
+			if htype == SQUASHFS {
+				partition.Compression = squashfsCompression(img, desc.Offset())
 			}
 			img.Partitions = append(img.Partitions, partition)
 			img.Usage |= usage
@@ -165,12 +206,19 @@ func (f *sifFormat) initializer(img *Image, fi os.FileInfo) error {
 				AllowedUsage: DataUsage,
 			}
 			img.Sections = append(img.Sections, data)
+
+			if desc.DataType() == sif.DataGenericJSON && desc.Name() == SIFDescBindsJSON {
+				binds, err := readBinds(desc)
+				if err != nil {
+					sylog.Warningf("Ignoring %s descriptor of %s: %s", SIFDescBindsJSON, img.Path, err)
+				} else {
+					img.Binds = binds
+				}
+			}
 		}
 		return false
 	})
 
-	img.Type = SIF
-
 	return nil
 }
 
@@ -12,9 +12,14 @@ package image
 import (
 	"bytes"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/apptainer/apptainer/internal/pkg/test/tool/require"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
 	"github.com/apptainer/apptainer/internal/pkg/util/machine"
 	"github.com/apptainer/sif/v2/pkg/sif"
@@ -201,7 +206,7 @@ func TestSIFInitializer(t *testing.T) {
 				t.Fatalf("cannot stat the image file: %s\n", err)
 			}
 
-			err = sifFmt.initializer(img, fileinfo)
+			err = initFormat(sifFmt, img, fileinfo)
 			os.Remove(tt.path)
 
 			if (err == nil) != tt.expectedSuccess {
@@ -215,6 +220,228 @@ func TestSIFInitializer(t *testing.T) {
 	}
 }
 
+func TestSIFOverlayPartitionUsage(t *testing.T) {
+	require.MkfsExt3(t)
+
+	const (
+		imageSize = 16 * 1024 * 1024
+		dataSize  = 4 * 1024 * 1024
+	)
+
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "data")
+	if err := os.Mkdir(dataDir, 0o755); err != nil {
+		t.Fatalf("failed to create %s: %s", dataDir, err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "file"), bytes.Repeat([]byte{'a'}, dataSize), 0o644); err != nil {
+		t.Fatalf("failed to create data file: %s", err)
+	}
+
+	ext3Path := filepath.Join(tmpDir, "overlay.img")
+	if err := os.WriteFile(ext3Path, nil, 0o644); err != nil {
+		t.Fatalf("failed to create %s: %s", ext3Path, err)
+	}
+	if err := os.Truncate(ext3Path, imageSize); err != nil {
+		t.Fatalf("failed to resize %s: %s", ext3Path, err)
+	}
+	if out, err := exec.Command("mkfs.ext3", "-q", "-F", "-d", dataDir, ext3Path).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create ext3 image: %s: %s", err, out)
+	}
+
+	ext3, err := os.ReadFile(ext3Path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", ext3Path, err)
+	}
+	squash, err := os.ReadFile(testSquash)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", testSquash, err)
+	}
+
+	primPart := func() (sif.DescriptorInput, error) {
+		return sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(squash),
+			sif.OptPartitionMetadata(sif.FsSquash, sif.PartPrimSys, runtime.GOARCH),
+		)
+	}
+	ext3OverlayPart := func() (sif.DescriptorInput, error) {
+		return sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(ext3),
+			sif.OptPartitionMetadata(sif.FsExt3, sif.PartOverlay, runtime.GOARCH),
+		)
+	}
+	squashOverlayPart := func() (sif.DescriptorInput, error) {
+		return sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(squash),
+			sif.OptPartitionMetadata(sif.FsSquash, sif.PartOverlay, runtime.GOARCH),
+		)
+	}
+
+	path := createSIF(t, false, primPart, ext3OverlayPart, squashOverlayPart)
+	defer os.Remove(path)
+
+	img := &Image{
+		Path: path,
+		Name: path,
+	}
+	img.File, err = os.Open(path)
+	if err != nil {
+		t.Fatalf("cannot open image's file: %s", err)
+	}
+	defer img.File.Close()
+
+	fileinfo, err := img.File.Stat()
+	if err != nil {
+		t.Fatalf("cannot stat the image file: %s", err)
+	}
+	if err := initFormat(new(sifFormat), img, fileinfo); err != nil {
+		t.Fatalf("unexpected error while initializing image: %s", err)
+	}
+
+	overlays, err := img.GetOverlayPartitions()
+	if err != nil {
+		t.Fatalf("unexpected error while getting overlay partitions: %s", err)
+	} else if len(overlays) != 2 {
+		t.Fatalf("unexpected overlay partitions number: %d instead of 2", len(overlays))
+	}
+
+	if fstype := overlays[0].FSType(); fstype != "ext3" {
+		t.Errorf("unexpected filesystem type %q for ext3 overlay", fstype)
+	}
+	if !overlays[0].Writable {
+		t.Errorf("ext3 overlay partition should be writable")
+	}
+	if fstype := overlays[1].FSType(); fstype != "squashfs" {
+		t.Errorf("unexpected filesystem type %q for squashfs overlay", fstype)
+	}
+	if overlays[1].Writable {
+		t.Errorf("squashfs overlay partition shouldn't be writable")
+	}
+
+	usage, err := img.GetExt3Usage(overlays[0])
+	if err != nil {
+		t.Fatalf("unexpected error while getting ext3 usage: %s", err)
+	}
+	if total := usage.TotalBlocks * usage.BlockSize; total != imageSize {
+		t.Errorf("unexpected ext3 size: %d instead of %d", total, imageSize)
+	}
+	if used := usage.UsedBlocks() * usage.BlockSize; used < dataSize {
+		t.Errorf("unexpected ext3 used space: %d, expected at least %d", used, dataSize)
+	}
+	if usage.FreeBytes() == 0 || usage.FreeBytes() > imageSize-dataSize {
+		t.Errorf("unexpected ext3 free space: %d", usage.FreeBytes())
+	}
+
+	if _, err := img.GetExt3Usage(overlays[1]); err == nil {
+		t.Errorf("unexpected success while getting ext3 usage of squashfs partition")
+	}
+}
+
+func TestSIFPartitionByName(t *testing.T) {
+	squash, err := os.ReadFile(testSquash)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", testSquash, err)
+	}
+
+	namedPart := func(name string) func() (sif.DescriptorInput, error) {
+		return func() (sif.DescriptorInput, error) {
+			return sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(squash),
+				sif.OptObjectName(name),
+				sif.OptPartitionMetadata(sif.FsSquash, sif.PartData, runtime.GOARCH),
+			)
+		}
+	}
+
+	path := createSIF(t, false, namedPart("datasets"), namedPart("models"), namedPart("models"))
+	defer os.Remove(path)
+
+	img := &Image{
+		Path: path,
+		Name: path,
+	}
+	img.File, err = os.Open(path)
+	if err != nil {
+		t.Fatalf("cannot open image's file: %s", err)
+	}
+	defer img.File.Close()
+
+	fileinfo, err := img.File.Stat()
+	if err != nil {
+		t.Fatalf("cannot stat the image file: %s", err)
+	}
+	if err := initFormat(new(sifFormat), img, fileinfo); err != nil {
+		t.Fatalf("unexpected error while initializing image: %s", err)
+	}
+
+	part, err := img.GetPartitionByName("datasets")
+	if err != nil {
+		t.Fatalf("unexpected error while getting partition by name: %s", err)
+	}
+	if part.Name != "datasets" || part.ID != 1 {
+		t.Errorf("unexpected partition %q with ID %d returned", part.Name, part.ID)
+	}
+
+	if _, err := img.GetPartitionByName("models"); err == nil {
+		t.Errorf("unexpected success with ambiguous partition name")
+	} else if !strings.Contains(err.Error(), "2, 3") {
+		t.Errorf("ambiguous partition error doesn't report matching IDs: %s", err)
+	}
+	if _, err := img.GetPartitionByName("absent"); err == nil {
+		t.Errorf("unexpected success with absent partition name")
+	} else if !strings.Contains(err.Error(), "available partition names: datasets, models") {
+		t.Errorf("absent partition error doesn't report available names: %s", err)
+	}
+}
+
+func TestSIFErofsPartition(t *testing.T) {
+	erofsPart := func() (sif.DescriptorInput, error) {
+		return sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(erofsHeader()),
+			sif.OptPartitionMetadata(sif.FsRaw, sif.PartPrimSys, runtime.GOARCH),
+		)
+	}
+
+	path := createSIF(t, false, erofsPart)
+	defer os.Remove(path)
+
+	img, err := Init(path, false)
+	if err != nil {
+		t.Fatalf("unexpected error while initializing image: %s", err)
+	}
+	defer img.File.Close()
+
+	part, err := img.GetRootFsPartition()
+	if err != nil {
+		t.Fatalf("unexpected error while getting root filesystem partition: %s", err)
+	}
+	if part.Type != EROFS {
+		t.Errorf("unexpected root filesystem partition type %d", part.Type)
+	}
+}
+
+func TestSIFSquashfsCompression(t *testing.T) {
+	lzo, err := os.ReadFile("./testdata/squashfs.lzo")
+	if err != nil {
+		t.Fatalf("failed to read squashfs image: %s", err)
+	}
+
+	path := createSIF(t, false, func() (sif.DescriptorInput, error) {
+		return sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(lzo),
+			sif.OptPartitionMetadata(sif.FsSquash, sif.PartPrimSys, runtime.GOARCH),
+		)
+	})
+	defer os.Remove(path)
+
+	img, err := Init(path, false)
+	if err != nil {
+		t.Fatalf("unexpected error while initializing image: %s", err)
+	}
+	defer img.File.Close()
+
+	part, err := img.GetRootFsPartition()
+	if err != nil {
+		t.Fatalf("unexpected error while getting root filesystem partition: %s", err)
+	}
+	if part.Compression != "lzo" {
+		t.Errorf("unexpected compression %q instead of lzo", part.Compression)
+	}
+}
+
 func TestSIFOpenMode(t *testing.T) {
 	var sifFmt sifFormat
 
@@ -225,3 +452,49 @@ func TestSIFOpenMode(t *testing.T) {
 		t.Fatal("openMode(false) returned the wrong value")
 	}
 }
+
+func TestSIFBinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{
+			name:     "binds",
+			data:     `["/run/license.sock", "/opt/data:/data:ro"]`,
+			expected: []string{"/run/license.sock", "/opt/data:/data:ro"},
+		},
+		{
+			name: "invalid",
+			data: `{"binds": "/run/license.sock"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := createSIF(t, false,
+				func() (sif.DescriptorInput, error) {
+					return sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(erofsHeader()),
+						sif.OptPartitionMetadata(sif.FsRaw, sif.PartPrimSys, runtime.GOARCH),
+					)
+				},
+				func() (sif.DescriptorInput, error) {
+					return sif.NewDescriptorInput(sif.DataGenericJSON, strings.NewReader(tt.data),
+						sif.OptObjectName(SIFDescBindsJSON),
+					)
+				},
+			)
+			defer os.Remove(path)
+
+			img, err := Init(path, false)
+			if err != nil {
+				t.Fatalf("unexpected error while initializing image: %s", err)
+			}
+			defer img.File.Close()
+
+			if !reflect.DeepEqual(img.Binds, tt.expected) {
+				t.Errorf("got binds %v, expected %v", img.Binds, tt.expected)
+			}
+		})
+	}
+}
@@ -141,7 +141,22 @@ func GetSquashfsComp(b []byte) (string, error) {
 	return "", fmt.Errorf("not a valid squashfs image")
 }
 
-func (f *squashfsFormat) initializer(img *Image, fileinfo os.FileInfo) error {
+// squashfsCompression returns the compression algorithm of the squashfs
+// filesystem found at offset in the image file, or an empty string if it
+// can't be determined.
+func squashfsCompression(img *Image, offset int64) string {
+	b := make([]byte, bufferSize)
+	if _, err := img.File.ReadAt(b, offset); err != nil {
+		return ""
+	}
+	comp, err := GetSquashfsComp(b)
+	if err != nil {
+		return ""
+	}
+	return comp
+}
+
+func (f *squashfsFormat) detect(img *Image, fileinfo os.FileInfo) error {
 	if fileinfo.IsDir() {
 		return debugError("not a squashfs image")
 	}
@@ -149,11 +164,26 @@ func (f *squashfsFormat) initializer(img *Image, fileinfo os.FileInfo) error {
 	if n, err := img.File.Read(b); err != nil || n != bufferSize {
 		return debugErrorf("can't read first %d bytes: %v", bufferSize, err)
 	}
+	if _, err := CheckSquashfsHeader(b); err != nil {
+		return err
+	}
+	img.Type = SQUASHFS
+	return nil
+}
+
+func (f *squashfsFormat) initializer(img *Image, fileinfo os.FileInfo) error {
+	b, err := readHeader(img)
+	if err != nil {
+		return err
+	}
 	offset, err := CheckSquashfsHeader(b)
 	if err != nil {
 		return err
 	}
-	img.Type = SQUASHFS
+	comp, err := GetSquashfsComp(b)
+	if err != nil {
+		sylog.Debugf("Could not determine squashfs compression of %s: %s", img.Path, err)
+	}
 	img.Partitions = []Section{
 		{
 			Offset:       offset,
@@ -162,6 +192,7 @@ func (f *squashfsFormat) initializer(img *Image, fileinfo os.FileInfo) error {
 			Type:         SQUASHFS,
 			Name:         RootFs,
 			AllowedUsage: RootFsUsage | OverlayUsage | DataUsage,
+			Compression:  comp,
 		},
 	}
 
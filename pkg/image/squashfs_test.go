@@ -91,7 +91,7 @@ func TestSquashfsInitializer(t *testing.T) {
 	}
 
 	// initializer must fail if writable is true
-	err = squashfsfmt.initializer(img, fileinfo)
+	err = initFormat(&squashfsfmt, img, fileinfo)
 	if err == nil {
 		t.Fatalf("unexpected success for squashfs initializer\n")
 	}
@@ -99,7 +99,7 @@ func TestSquashfsInitializer(t *testing.T) {
 	img.File.Seek(0, io.SeekStart)
 	// initialized must succeed if writable is false
 	img.Writable = false
-	err = squashfsfmt.initializer(img, fileinfo)
+	err = initFormat(&squashfsfmt, img, fileinfo)
 	if err != nil {
 		t.Fatalf("unexpected error for squashfs initializer: %s\n", err)
 	}
@@ -117,7 +117,7 @@ func TestSquashfsInitializer(t *testing.T) {
 		t.Fatalf("cannot stat file pointer: %s\n", err)
 	}
 
-	err = squashfsfmt.initializer(img, fileinfo)
+	err = initFormat(&squashfsfmt, img, fileinfo)
 	if err == nil {
 		t.Fatal("squashfs succeeded with a directory while expected to fail")
 	}
@@ -0,0 +1,269 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apptainer/sif/v2/pkg/sif"
+)
+
+const (
+	// offset of the bytes_used field in a v4 squashfs super block
+	squashfsBytesUsedOffset = 40
+	// offsets of the blkszbits and blocks fields in an EROFS super block
+	erofsBlkszBitsOffset = erofsMagicOffset + 12
+	erofsBlocksOffset    = erofsMagicOffset + 36
+)
+
+// VerifyCheck is the result of a single check performed by Verify.
+type VerifyCheck struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// VerifyReport holds the results of the checks performed by Verify.
+type VerifyReport struct {
+	Path string `json:"path"`
+	// Format is the detected image format, empty if not recognized.
+	Format string        `json:"format"`
+	Size   int64         `json:"size"`
+	Checks []VerifyCheck `json:"checks"`
+}
+
+// Passed returns true if all checks passed.
+func (r *VerifyReport) Passed() bool {
+	for _, c := range r.Checks {
+		if !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// add records the result of a check, err being nil for a passed check.
+func (r *VerifyReport) add(name string, err error) bool {
+	c := VerifyCheck{Name: name, Passed: err == nil}
+	if err != nil {
+		c.Message = err.Error()
+	}
+	r.Checks = append(r.Checks, c)
+	return c.Passed
+}
+
+// Verify checks that the file at path is a well-formed SIF, squashfs,
+// ext3 or EROFS image without mounting it. It checks the format magic,
+// the SIF descriptors consistency, that partitions and filesystems fit
+// within the file and the filesystem super blocks. A failed check is
+// reported in the returned report, an error is returned only if the file
+// can't be read.
+func Verify(path string) (*VerifyReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("while opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("while getting %s information: %w", path, err)
+	}
+	if fi.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	r := &VerifyReport{Path: path, Size: fi.Size()}
+
+	header := make([]byte, bufferSize)
+	n, err := f.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("while reading %s: %w", path, err)
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.Contains(header, []byte("SIF_MAGIC")):
+		r.Format = "sif"
+		r.add("magic", nil)
+		verifySIF(r, f)
+	case checkSquashfsMagic(header) == nil:
+		r.Format = "squashfs"
+		r.add("magic", nil)
+		_, offset, _ := parseSquashfsHeader(header)
+		r.add("squashfs superblock", verifyFilesystem(f, SQUASHFS, int64(offset), fi.Size()-int64(offset)))
+	case checkExt3Magic(header) == nil:
+		r.Format = "ext3"
+		r.add("magic", nil)
+		offset, err := CheckExt3Header(header)
+		if err == nil {
+			err = verifyFilesystem(f, EXT3, int64(offset), fi.Size()-int64(offset))
+		}
+		r.add("ext3 superblock", err)
+	case CheckErofsHeader(header) == nil:
+		r.Format = "erofs"
+		r.add("magic", nil)
+		r.add("erofs superblock", verifyFilesystem(f, EROFS, 0, fi.Size()))
+	default:
+		r.add("magic", ErrUnknownFormat)
+	}
+
+	return r, nil
+}
+
+// checkSquashfsMagic checks the squashfs magic only, CheckSquashfsHeader
+// also checks the compression algorithm.
+func checkSquashfsMagic(b []byte) error {
+	_, _, err := parseSquashfsHeader(b)
+	return err
+}
+
+// checkExt3Magic checks the ext magic only, CheckExt3Header also checks
+// the filesystem features.
+func checkExt3Magic(b []byte) error {
+	offset := uint64(extMagicOffset)
+	if o := bytes.Index(b, []byte(launchString)); o > 0 {
+		offset += uint64(o + len(launchString) + 1)
+	}
+	if offset+uint64(len(extMagic)) > uint64(len(b)) || !bytes.Equal(b[offset:offset+uint64(len(extMagic))], []byte(extMagic)) {
+		return fmt.Errorf(notValidExt3ImageMessage)
+	}
+	return nil
+}
+
+// verifySIF checks the SIF header and descriptors, and the filesystem of
+// each partition.
+func verifySIF(r *VerifyReport, f *os.File) {
+	fimg, err := sif.LoadContainer(f,
+		sif.OptLoadWithFlag(os.O_RDONLY),
+		sif.OptLoadWithCloseOnUnload(false),
+	)
+	if !r.add("sif header", err) {
+		return
+	}
+	defer fimg.UnloadContainer()
+
+	dataEnd := fimg.DataOffset() + fimg.DataSize()
+	if dataEnd > r.Size {
+		r.add("sif data bounds", fmt.Errorf("data section ends at %d beyond file size %d, the image may be truncated", dataEnd, r.Size))
+	} else {
+		r.add("sif data bounds", nil)
+	}
+
+	hasRootFs := false
+
+	fimg.WithDescriptors(func(d sif.Descriptor) bool {
+		name := fmt.Sprintf("descriptor %d bounds", d.ID())
+		if d.Offset() < fimg.DataOffset() || d.Size() < 0 || d.Offset()+d.Size() > r.Size {
+			r.add(name, fmt.Errorf("object at offset %d with size %d is outside of the file data section", d.Offset(), d.Size()))
+			return false
+		}
+		r.add(name, nil)
+
+		if d.DataType() != sif.DataPartition {
+			return false
+		}
+
+		name = fmt.Sprintf("partition %d (%s) filesystem", d.ID(), d.Name())
+		fstype, ptype, _, err := d.PartitionMetadata()
+		if err != nil {
+			r.add(name, fmt.Errorf("while reading partition metadata: %s", err))
+			return false
+		}
+		if ptype == sif.PartPrimSys {
+			hasRootFs = true
+		}
+
+		img := &Image{Path: r.Path, File: f}
+		htype, err := checkPartitionType(img, fstype, d.Offset())
+		if err == nil {
+			err = verifyFilesystem(f, htype, d.Offset(), d.Size())
+		}
+		r.add(name, err)
+		return false
+	})
+
+	if !hasRootFs {
+		r.add("sif root filesystem", fmt.Errorf("no primary system partition found"))
+	} else {
+		r.add("sif root filesystem", nil)
+	}
+}
+
+// verifyFilesystem checks the super block of the filesystem of type
+// fstype located at offset and reports if the filesystem is larger than
+// size. Filesystems without super block information are not checked.
+func verifyFilesystem(f *os.File, fstype uint32, offset, size int64) error {
+	switch fstype {
+	case SQUASHFS:
+		b := make([]byte, squashfsBytesUsedOffset+8)
+		if _, err := f.ReadAt(b, offset); err != nil {
+			return fmt.Errorf("while reading squashfs super block: %s", err)
+		}
+		sinfo := &squashfsInfo{}
+		if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, sinfo); err != nil {
+			return fmt.Errorf("while decoding squashfs super block: %s", err)
+		}
+		if sinfo.Major != 4 {
+			return fmt.Errorf("unsupported squashfs version %d.%d", sinfo.Major, sinfo.Minor)
+		}
+		if sinfo.BlockSize < 4096 || sinfo.BlockSize > 1<<20 || sinfo.BlockSize != 1<<sinfo.BlockLog {
+			return fmt.Errorf("corrupted squashfs super block: invalid block size %d", sinfo.BlockSize)
+		}
+		if comp, err := GetSquashfsComp(b); err != nil {
+			return err
+		} else if comp == "" {
+			return fmt.Errorf("corrupted squashfs super block: unknown compression algorithm value %d", sinfo.Compression)
+		}
+		bytesUsed := int64(binary.LittleEndian.Uint64(b[squashfsBytesUsedOffset:]))
+		if bytesUsed > size {
+			return fmt.Errorf("squashfs filesystem size %d exceeds available size %d, the image may be truncated", bytesUsed, size)
+		}
+	case EXT3:
+		b := make([]byte, extSuperblockSize)
+		if _, err := f.ReadAt(b, offset+extSuperblockOffset); err != nil {
+			return fmt.Errorf("while reading ext3 super block: %s", err)
+		}
+		sb := &extSuperblock{}
+		if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, sb); err != nil {
+			return fmt.Errorf("while decoding ext3 super block: %s", err)
+		}
+		if !bytes.Equal(sb.Magic[:], []byte(extMagic)) {
+			return fmt.Errorf(notValidExt3ImageMessage)
+		}
+		if sb.LogBlockSize > 6 {
+			return fmt.Errorf("corrupted ext3 super block: invalid block size")
+		}
+		fsSize := int64(sb.BlocksCount) * (1024 << sb.LogBlockSize)
+		if fsSize > size {
+			return fmt.Errorf("ext3 filesystem size %d exceeds available size %d, the image may be truncated", fsSize, size)
+		}
+	case EROFS:
+		b := make([]byte, erofsBlocksOffset+4)
+		if _, err := f.ReadAt(b, offset); err != nil {
+			return fmt.Errorf("while reading EROFS super block: %s", err)
+		}
+		if err := CheckErofsHeader(b); err != nil {
+			return err
+		}
+		blkszbits := b[erofsBlkszBitsOffset]
+		if blkszbits < 9 || blkszbits > 16 {
+			return fmt.Errorf("corrupted EROFS super block: invalid block size")
+		}
+		fsSize := int64(binary.LittleEndian.Uint32(b[erofsBlocksOffset:])) << blkszbits
+		if fsSize > size {
+			return fmt.Errorf("EROFS filesystem size %d exceeds available size %d, the image may be truncated", fsSize, size)
+		}
+	}
+	return nil
+}
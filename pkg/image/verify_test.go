@@ -0,0 +1,77 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package image
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/apptainer/sif/v2/pkg/sif"
+)
+
+func TestVerify(t *testing.T) {
+	squash, err := os.ReadFile(testSquash)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", testSquash, err)
+	}
+
+	dir := t.TempDir()
+	truncated := filepath.Join(dir, "truncated.squashfs")
+	if err := os.WriteFile(truncated, squash[:200], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unknown := filepath.Join(dir, "unknown.img")
+	if err := os.WriteFile(unknown, make([]byte, 4096), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rootfs := func() (sif.DescriptorInput, error) {
+		return sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(squash),
+			sif.OptPartitionMetadata(sif.FsSquash, sif.PartPrimSys, runtime.GOARCH),
+		)
+	}
+	validSIF := createSIF(t, false, rootfs)
+	defer os.Remove(validSIF)
+	corruptedSIF := createSIF(t, true, rootfs)
+	defer os.Remove(corruptedSIF)
+
+	tests := []struct {
+		name   string
+		path   string
+		format string
+		passed bool
+	}{
+		{"Squashfs", testSquash, "squashfs", true},
+		{"TruncatedSquashfs", truncated, "squashfs", false},
+		{"SIF", validSIF, "sif", true},
+		{"TruncatedSIF", corruptedSIF, "sif", false},
+		{"Unknown", unknown, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Verify(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if r.Format != tt.format {
+				t.Errorf("got format %q, expected %q", r.Format, tt.format)
+			}
+			if r.Passed() != tt.passed {
+				t.Errorf("got passed %v, expected %v: %+v", r.Passed(), tt.passed, r.Checks)
+			}
+		})
+	}
+
+	if _, err := Verify(dir); err == nil {
+		t.Errorf("unexpected success with a directory")
+	}
+}
@@ -11,6 +11,7 @@ package network
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -22,6 +23,7 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/apptainer/apptainer/internal/pkg/util/env"
+	"github.com/apptainer/apptainer/pkg/sylog"
 	"github.com/containernetworking/cni/libcni"
 	"github.com/containernetworking/cni/pkg/types"
 	cnitypes "github.com/containernetworking/cni/pkg/types/100"
@@ -44,6 +46,18 @@ const (
 type CNIPath struct {
 	Conf   string
 	Plugin string
+	// NetworkConf maps network names to the CNI configuration directory
+	// holding their configuration, used in place of Conf.
+	NetworkConf map[string]string
+}
+
+// ConfDir returns the CNI configuration directory holding the configuration
+// of the named network.
+func (p *CNIPath) ConfDir(network string) string {
+	if dir, ok := p.NetworkConf[network]; ok {
+		return dir
+	}
+	return p.Conf
 }
 
 // Setup contains network installation setup
@@ -56,6 +70,8 @@ type Setup struct {
 	containerID     string
 	netNS           string
 	envPath         string
+	retries         uint
+	retryDelay      time.Duration
 }
 
 // PortMapEntry describes a port mapping between host and container
@@ -124,7 +140,7 @@ func NewSetup(networks []string, containerID string, netNS string, cniPath *CNIP
 	for i, network := range networks {
 		var err error
 
-		networkConfList[i], err = libcni.LoadConfList(cniPath.Conf, network)
+		networkConfList[i], err = libcni.LoadConfList(cniPath.ConfDir(network), network)
 		if err != nil {
 			return nil, err
 		}
@@ -443,9 +459,70 @@ func (m *Setup) SetEnvPath(envPath string) {
 	m.envPath = envPath
 }
 
+// maxRetryDelay caps the delay between two network setup attempts.
+const maxRetryDelay = 10 * time.Second
+
+// SetRetry sets the number of times network setup is retried when
+// a CNI plugin reports a transient error, delay is doubled after each
+// attempt up to 10 seconds.
+func (m *Setup) SetRetry(retries uint, delay time.Duration) {
+	m.retries = retries
+	m.retryDelay = delay
+}
+
 // AddNetworks brings up networks interface in container
 func (m *Setup) AddNetworks(ctx context.Context) error {
-	return m.command(ctx, "ADD")
+	delay := m.retryDelay
+
+	for attempt := uint(0); ; attempt++ {
+		err := m.command(ctx, "ADD")
+		if err == nil || attempt == m.retries || !isTransientError(err) || ctx.Err() != nil {
+			return err
+		}
+		sylog.Verbosef("Network setup failed: %s, retrying in %s", err, delay)
+
+		// remove interfaces partially created by the failed attempt,
+		// CNI plugins ignore deletion of non existent resources
+		if err := m.command(ctx, "DEL"); err != nil {
+			sylog.Debugf("While cleaning up networks: %s", err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay = nextRetryDelay(delay)
+	}
+}
+
+// nextRetryDelay returns the delay following delay in the exponential
+// backoff between network setup attempts.
+func nextRetryDelay(delay time.Duration) time.Duration {
+	if delay *= 2; delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// isTransientError returns if a network setup error may be fixed by
+// retrying the operation, only plugin timeouts and errors reported by
+// CNI plugins as temporary or I/O failures are retried.
+func isTransientError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var cniErr *types.Error
+	if !errors.As(err, &cniErr) {
+		return false
+	}
+	switch cniErr.Code {
+	case types.ErrTryAgainLater, types.ErrIOFailure:
+		return true
+	}
+	return false
 }
 
 // DelNetworks tears down networks interface in container
@@ -23,10 +23,12 @@ import (
 	"strings"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/apptainer/apptainer/internal/pkg/buildcfg"
 	"github.com/apptainer/apptainer/internal/pkg/test"
 	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/pkg/types"
 )
 
 var confFiles = []struct {
@@ -289,6 +291,19 @@ func testSetArgs(setup *Setup, t *testing.T) {
 	}
 }
 
+func TestCNIPathConfDir(t *testing.T) {
+	cniPath := &CNIPath{
+		Conf:        "/etc/cni/net.d",
+		NetworkConf: map[string]string{"tenant": "/etc/cni/tenant.d"},
+	}
+	if dir := cniPath.ConfDir("bridge"); dir != "/etc/cni/net.d" {
+		t.Errorf("unexpected configuration directory %s for bridge network", dir)
+	}
+	if dir := cniPath.ConfDir("tenant"); dir != "/etc/cni/tenant.d" {
+		t.Errorf("unexpected configuration directory %s for tenant network", dir)
+	}
+}
+
 func TestNewSetup(t *testing.T) {
 	test.EnsurePrivilege(t)
 
@@ -597,6 +612,41 @@ func TestAddDelNetworks(t *testing.T) {
 	}
 }
 
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{"TryAgainLater", types.NewError(types.ErrTryAgainLater, "busy", ""), true},
+		{"IOFailure", types.NewError(types.ErrIOFailure, "failed", ""), true},
+		{"Internal", types.NewError(types.ErrInternal, "failed", ""), false},
+		{"Unknown", types.NewError(types.ErrUnknown, "failed", ""), false},
+		{"Timeout", fmt.Errorf("plugin: %w", context.DeadlineExceeded), true},
+		{"InvalidConfig", types.NewError(types.ErrInvalidNetworkConfig, "bad config", ""), false},
+		{"IncompatibleVersion", types.NewError(types.ErrIncompatibleCNIVersion, "bad version", ""), false},
+		{"Other", fmt.Errorf("failed to find plugin"), false},
+	}
+	for _, tt := range tests {
+		if transient := isTransientError(tt.err); transient != tt.transient {
+			t.Errorf("%s: got transient %v, expected %v", tt.name, transient, tt.transient)
+		}
+	}
+}
+
+func TestNextRetryDelay(t *testing.T) {
+	delay := 500 * time.Millisecond
+	for i := 0; i < 10; i++ {
+		delay = nextRetryDelay(delay)
+		if delay > maxRetryDelay {
+			t.Fatalf("got delay %s, expected at most %s", delay, maxRetryDelay)
+		}
+	}
+	if delay != maxRetryDelay {
+		t.Errorf("got delay %s, expected %s", delay, maxRetryDelay)
+	}
+}
+
 func TestMain(m *testing.M) {
 	var err error
 
@@ -13,7 +13,9 @@ import (
 	"os"
 	"syscall"
 
+	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
 	"github.com/apptainer/apptainer/pkg/runtime/engine/config"
+	"github.com/opencontainers/runtime-spec/specs-go"
 )
 
 // MonitorContainer callback allows to monitor container process.
@@ -36,3 +38,58 @@ type PostStartProcess func(config *config.Common, pid int) error
 // This callback is called in:
 // - internal/pkg/runtime/engine/apptainer/container_linux.go
 type RegisterImageDriver func(unprivileged bool) error
+
+// PostMountSetup callback is called once all the container mount points,
+// including overlays and binds, are in place and before the container
+// process chroot into the session final directory. The finalPath
+// parameter is the host path of the container root filesystem, a good
+// place to write generated files into the container.
+// This callback runs in the master process without access to the RPC
+// server driving the container setup, the filesystem changes are done
+// with the master process credentials. They may not be allowed to write
+// into the container root filesystem, in hybrid fakeroot mode in
+// particular where the root filesystem is owned by the fakeroot user.
+// This callback is called in:
+// - internal/pkg/runtime/engine/apptainer/container_linux.go
+type PostMountSetup func(config *config.Common, finalPath string) error
+
+// IdentityFiles callback is called once the container /etc/passwd and
+// /etc/group files content is generated and before it's written to the
+// session directory. The uid parameter is the container user ID and
+// userInfo the resolved user information, passwd and group hold the
+// generated content, nil when the file isn't generated. The callback
+// returns the content to use instead, if more than one plugin uses this
+// callback, each one receives the content returned by the previous one.
+// This callback runs in the master process with the privileges used to
+// generate the files, it doesn't have to access the container filesystem
+// as the returned content is written to the session directory by the
+// runtime.
+// This callback is called in:
+// - internal/pkg/runtime/engine/apptainer/container_linux.go
+type IdentityFiles func(config *config.Common, uid int, userInfo apptainerConfig.UserInfo, passwd, group []byte) ([]byte, []byte, error)
+
+// ProcessSpec callback is called once the container process specification
+// is fully prepared, after the container capability set is resolved from
+// the configuration and the user requests, and before the capabilities,
+// the no new privileges flag and the process are applied. It allows to
+// alter the process arguments, environment, resource limits or
+// capabilities, if more than one plugin uses this callback they are
+// called in the plugin loading order, each one seeing the changes done
+// by the previous one.
+// This callback runs in the unprivileged stage 1 of the container
+// creation, for both container start and instance join.
+// This callback is called in:
+// - internal/pkg/runtime/engine/apptainer/prepare_linux.go
+type ProcessSpec func(config *config.Common, process *specs.Process) error
+
+// OnExit callback is called once the container process terminated, before
+// the container resources are cleaned up. The status parameter is the
+// container process wait status, which may report a termination by a
+// signal when the container process crashed. It's called even if the
+// container setup failed, in which case the status may be empty. An error
+// returned by the callback is reported as a warning and doesn't change
+// the container exit code.
+// This callback runs in the master process.
+// This callback is called in:
+// - internal/pkg/runtime/engine/apptainer/cleanup_linux.go
+type OnExit func(config *config.Common, status syscall.WaitStatus) error
@@ -29,8 +29,24 @@ var bindOptions = map[string]bool{
 	"rw":        flagOption,
 	"image-src": valueOption,
 	"id":        valueOption,
+	"name":      valueOption,
+	// mount propagation options
+	"shared":     flagOption,
+	"slave":      flagOption,
+	"private":    flagOption,
+	"unbindable": flagOption,
+	// create the destination parent directory
+	"mkdir": flagOption,
+	// create the destination file
+	"mkfile": flagOption,
 }
 
+// propagationOptions lists the bind options setting the mount propagation.
+// The shared option is recognized only to be rejected with a clear error,
+// as mounts done within the container mount namespace, which is a slave
+// of the host one, can't propagate back to the host.
+var propagationOptions = []string{"slave", "private", "unbindable"}
+
 // BindPath stores a parsed bind path specification. Source and Destination
 // paths are required.
 type BindPath struct {
@@ -61,11 +77,47 @@ func (b *BindPath) ID() string {
 	return ""
 }
 
+// PartitionName returns the value of the option name for a BindPath, or an
+// empty string if the option wasn't set.
+func (b *BindPath) PartitionName() string {
+	if b.Options != nil && b.Options["name"] != nil {
+		return b.Options["name"].Value
+	}
+	return ""
+}
+
+// IsImageBind returns true if the BindPath mounts a partition, or a
+// directory within a partition, of an image file.
+func (b *BindPath) IsImageBind() bool {
+	return b.ImageSrc() != "" || b.ID() != "" || b.PartitionName() != ""
+}
+
 // Readonly returns true if the ro option was set for a BindPath.
 func (b *BindPath) Readonly() bool {
 	return b.Options != nil && b.Options["ro"] != nil
 }
 
+// Propagation returns the mount propagation option set for a BindPath, or
+// an empty string if none was set.
+func (b *BindPath) Propagation() string {
+	for _, p := range propagationOptions {
+		if b.Options != nil && b.Options[p] != nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// Mkdir returns true if the mkdir option was set for a BindPath.
+func (b *BindPath) Mkdir() bool {
+	return b.Options != nil && b.Options["mkdir"] != nil
+}
+
+// Mkfile returns true if the mkfile option was set for a BindPath.
+func (b *BindPath) Mkfile() bool {
+	return b.Options != nil && b.Options["mkfile"] != nil
+}
+
 // ParseBindPath parses a an array of strings each specifying one or
 // more (comma separated) bind paths in src[:dst[:options]] format, and
 // returns all encountered bind paths as a slice. Options may be simple
@@ -267,6 +319,30 @@ func newBindPath(bind string) (BindPath, error) {
 				return bp, fmt.Errorf("%s is not a valid bind option", value)
 			}
 		}
+		if bp.ID() != "" && bp.PartitionName() != "" {
+			return bp, fmt.Errorf("id and name bind options are mutually exclusive")
+		}
+		if bp.Options["shared"] != nil {
+			return bp, fmt.Errorf("shared bind option is not supported: the container mount namespace is a slave of the host one, mounts done within the container can't propagate back to the host")
+		}
+		propagation := make([]string, 0, 1)
+		for _, p := range propagationOptions {
+			if bp.Options[p] != nil {
+				propagation = append(propagation, p)
+			}
+		}
+		if len(propagation) > 1 {
+			return bp, fmt.Errorf("%s bind options are mutually exclusive", strings.Join(propagation, " and "))
+		}
+		if len(propagation) > 0 && bp.IsImageBind() {
+			return bp, fmt.Errorf("%s bind option can't be used with image binds", propagation[0])
+		}
+		if bp.Mkdir() && bp.IsImageBind() {
+			return bp, fmt.Errorf("mkdir bind option can't be used with image binds")
+		}
+		if bp.Mkfile() && bp.IsImageBind() {
+			return bp, fmt.Errorf("mkfile bind option can't be used with image binds")
+		}
 	}
 
 	return bp, nil
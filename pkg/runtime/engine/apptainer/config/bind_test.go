@@ -174,6 +174,100 @@ func TestParseBindPath(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:      "srcDstName",
+			bindpaths: []string{"test.sif:/other:image-src=/opt,name=models"},
+			want: []BindPath{
+				{
+					Source:      "test.sif",
+					Destination: "/other",
+					Options: map[string]*BindOption{
+						"image-src": {"/opt"},
+						"name":      {"models"},
+					},
+				},
+			},
+		},
+		{
+			// id and name can't be used together
+			name:      "srcDstIdName",
+			bindpaths: []string{"test.sif:/other:id=2,name=models"},
+			want:      []BindPath{},
+			wantErr:   true,
+		},
+		{
+			name:      "srcDstSlave",
+			bindpaths: []string{"/opt:/other:ro,slave"},
+			want: []BindPath{
+				{
+					Source:      "/opt",
+					Destination: "/other",
+					Options: map[string]*BindOption{
+						"ro":    {},
+						"slave": {},
+					},
+				},
+			},
+		},
+		{
+			// mounts can't propagate back to the host
+			name:      "srcDstShared",
+			bindpaths: []string{"/opt:/other:ro,shared"},
+			want:      []BindPath{},
+			wantErr:   true,
+		},
+		{
+			// only one propagation option can be set
+			name:      "srcDstSlavePrivate",
+			bindpaths: []string{"/opt:/other:slave,private"},
+			want:      []BindPath{},
+			wantErr:   true,
+		},
+		{
+			name:      "srcDstImagePropagation",
+			bindpaths: []string{"test.sif:/other:id=2,slave"},
+			want:      []BindPath{},
+			wantErr:   true,
+		},
+		{
+			name:      "srcDstMkdir",
+			bindpaths: []string{"/run/app/socket:/run/app/socket:mkdir"},
+			want: []BindPath{
+				{
+					Source:      "/run/app/socket",
+					Destination: "/run/app/socket",
+					Options: map[string]*BindOption{
+						"mkdir": {},
+					},
+				},
+			},
+		},
+		{
+			name:      "srcDstImageMkdir",
+			bindpaths: []string{"test.sif:/other:id=2,mkdir"},
+			want:      []BindPath{},
+			wantErr:   true,
+		},
+		{
+			name:      "srcDstMkfile",
+			bindpaths: []string{"/etc/app.conf:/opt/app/app.conf:ro,mkfile"},
+			want: []BindPath{
+				{
+					Source:      "/etc/app.conf",
+					Destination: "/opt/app/app.conf",
+					Options: map[string]*BindOption{
+						"ro":     {},
+						"mkfile": {},
+					},
+				},
+			},
+		},
+		{
+			name:      "srcDstImageMkfile",
+			bindpaths: []string{"test.sif:/other:id=2,mkfile"},
+			want:      []BindPath{},
+			wantErr:   true,
+		},
 		{
 			name:      "invalidOption",
 			bindpaths: []string{"/opt:/other:invalid"},
@@ -0,0 +1,53 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ParseBindFile reads the bind path specifications of a --bind-file, one
+// per line in the same src[:dst[:options]] format as --bind. Blank lines
+// and lines starting with # are ignored. Errors report the line number of
+// the offending specification.
+func ParseBindFile(path string) ([]BindPath, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("while opening bind file: %w", err)
+	}
+	defer f.Close()
+
+	return parseBindFile(path, f)
+}
+
+func parseBindFile(path string, r io.Reader) ([]BindPath, error) {
+	var binds []BindPath
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		bps, err := ParseBindPath([]string{line})
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		binds = append(binds, bps...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("while reading bind file %s: %w", path, err)
+	}
+
+	return binds, nil
+}
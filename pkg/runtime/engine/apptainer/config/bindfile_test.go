@@ -0,0 +1,62 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBindFile(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "binds")
+	content := `# generated binds
+
+/opt:/opt:ro
+  /data:/mnt/data  
+/tmp/image.sif:/images:image-src=/,id=2
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParseBindFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want, err := ParseBindPath([]string{
+		"/opt:/opt:ro",
+		"/data:/mnt/data",
+		"/tmp/image.sif:/images:image-src=/,id=2",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseBindFile() = %v, want %v", got, want)
+	}
+
+	bad := filepath.Join(dir, "bad")
+	if err := os.WriteFile(bad, []byte("/opt\n\n/data:/data:badopt\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseBindFile(bad); err == nil {
+		t.Errorf("unexpected success with an invalid bind option")
+	} else if !strings.HasPrefix(err.Error(), bad+":3:") {
+		t.Errorf("error %q doesn't report the line number", err)
+	}
+
+	if _, err := ParseBindFile(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("unexpected success with a missing bind file")
+	}
+}
@@ -31,6 +31,20 @@ const (
 	UnderlayLayer = "underlay"
 )
 
+const (
+	// LoopModeKernel mounts squashfs image partitions with kernel loop devices.
+	LoopModeKernel = "kernel"
+	// LoopModeFuse mounts squashfs image partitions with the image driver.
+	LoopModeFuse = "fuse"
+)
+
+const (
+	// OverlayPrecedenceLast gives precedence to the overlays added last.
+	OverlayPrecedenceLast = "last"
+	// OverlayPrecedenceFirst gives precedence to the overlays added first.
+	OverlayPrecedenceFirst = "first"
+)
+
 // EngineConfig stores the JSONConfig, the OciConfig and the File configuration.
 type EngineConfig struct {
 	JSON      *JSONConfig         `json:"jsonConfig"`
@@ -83,13 +97,25 @@ type UserInfo struct {
 type JSONConfig struct {
 	ScratchDir            []string          `json:"scratchdir,omitempty"`
 	OverlayImage          []string          `json:"overlayImage,omitempty"`
+	OverlayVerify         bool              `json:"overlayVerify,omitempty"`
+	OverlayWarnFree       uint64            `json:"overlayWarnFree,omitempty"`
+	OCILayers             []string          `json:"ociLayers,omitempty"`
+	ImageMountOpts        []string          `json:"imageMountOpts,omitempty"`
+	SquashfsMountOpts     []string          `json:"squashfsMountOpts,omitempty"`
+	OverlayOpts           []string          `json:"overlayOpts,omitempty"`
+	ImageDriver           string            `json:"imageDriver,omitempty"`
 	NetworkArgs           []string          `json:"networkArgs,omitempty"`
+	NetworkRetries        *uint             `json:"networkRetries,omitempty"`
 	Security              []string          `json:"security,omitempty"`
 	FilesPath             []string          `json:"filesPath,omitempty"`
 	LibrariesPath         []string          `json:"librariesPath,omitempty"`
 	FuseMount             []FuseMount       `json:"fuseMount,omitempty"`
 	ImageList             []image.Image     `json:"imageList,omitempty"`
 	BindPath              []BindPath        `json:"bindpath,omitempty"`
+	OverlayBind           []OverlayBind     `json:"overlayBind,omitempty"`
+	MountFrom             []MountFrom       `json:"mountFrom,omitempty"`
+	TmpfsMounts           []TmpfsMount      `json:"tmpfsMounts,omitempty"`
+	KernelMounts          []KernelMount     `json:"kernelMounts,omitempty"`
 	ApptainerEnv          map[string]string `json:"apptainerEnv,omitempty"`
 	UnixSocketPair        [2]int            `json:"unixSocketPair,omitempty"`
 	OpenFd                []int             `json:"openFd,omitempty"`
@@ -101,15 +127,20 @@ type JSONConfig struct {
 	CgroupsJSON           string            `json:"cgroupsJSON,omitempty"`
 	HomeSource            string            `json:"homedir,omitempty"`
 	HomeDest              string            `json:"homeDest,omitempty"`
+	HomeKeepPath          bool              `json:"homeKeepPath,omitempty"`
 	Command               string            `json:"command,omitempty"`
 	Shell                 string            `json:"shell,omitempty"`
 	FakerootPath          string            `json:"fakerootPath,omitempty"`
 	TmpDir                string            `json:"tmpdir,omitempty"`
 	AddCaps               string            `json:"addCaps,omitempty"`
 	DropCaps              string            `json:"dropCaps,omitempty"`
+	FakerootCaps          string            `json:"fakerootCaps,omitempty"`
 	Hostname              string            `json:"hostname,omitempty"`
 	Network               string            `json:"network,omitempty"`
 	DNS                   string            `json:"dns,omitempty"`
+	DNSSearch             []string          `json:"dnsSearch,omitempty"`
+	DNSOptions            []string          `json:"dnsOptions,omitempty"`
+	Nsswitch              bool              `json:"nsswitch,omitempty"`
 	Cwd                   string            `json:"cwd,omitempty"`
 	SessionLayer          string            `json:"sessionLayer,omitempty"`
 	ConfigurationFile     string            `json:"configurationFile,omitempty"`
@@ -123,6 +154,7 @@ type JSONConfig struct {
 	NvCCLI                bool              `json:"nvCCLI,omitempty"`
 	NvCCLIEnv             []string          `json:"NvCCLIEnv,omitempty"`
 	Rocm                  bool              `json:"rocm,omitempty"`
+	DRI                   bool              `json:"dri,omitempty"`
 	CustomHome            bool              `json:"customHome,omitempty"`
 	Instance              bool              `json:"instance,omitempty"`
 	InstanceJoin          bool              `json:"instanceJoin,omitempty"`
@@ -133,18 +165,32 @@ type JSONConfig struct {
 	NoPrivs               bool              `json:"noPrivs,omitempty"`
 	NoProc                bool              `json:"noProc,omitempty"`
 	NoSys                 bool              `json:"noSys,omitempty"`
+	BindCgroupfs          bool              `json:"bindCgroupfs,omitempty"`
 	NoDev                 bool              `json:"noDev,omitempty"`
 	NoDevPts              bool              `json:"noDevPts,omitempty"`
+	NoTTYConsole          bool              `json:"noTTYConsole,omitempty"`
 	NoHome                bool              `json:"noHome,omitempty"`
 	NoTmp                 bool              `json:"noTmp,omitempty"`
 	NoHostfs              bool              `json:"noHostfs,omitempty"`
 	NoCwd                 bool              `json:"noCwd,omitempty"`
+	CwdReadOnly           bool              `json:"cwdReadOnly,omitempty"`
 	SkipBinds             []string          `json:"skipBinds,omitempty"`
 	NoInit                bool              `json:"noInit,omitempty"`
 	Fakeroot              bool              `json:"fakeroot,omitempty"`
+	KeepID                bool              `json:"keepID,omitempty"`
+	PasswdEntry           string            `json:"passwdEntry,omitempty"`
+	GroupEntry            string            `json:"groupEntry,omitempty"`
 	SignalPropagation     bool              `json:"signalPropagation,omitempty"`
+	TraceSyscalls         string            `json:"traceSyscalls,omitempty"`
+	TraceSyscallsFd       int               `json:"traceSyscallsFd,omitempty"`
+	SchedPolicy           string            `json:"schedPolicy,omitempty"`
+	Nice                  *int              `json:"nice,omitempty"`
 	RestoreUmask          bool              `json:"restoreUmask,omitempty"`
 	DeleteTempDir         string            `json:"deleteTempDir,omitempty"`
+	DeleteTempOverlay     string            `json:"deleteTempOverlay,omitempty"`
+	SessionFsType         string            `json:"sessionFsType,omitempty"`
+	SessionSize           int               `json:"sessionSize,omitempty"`
+	StrictBinds           bool              `json:"strictBinds,omitempty"`
 	Umask                 int               `json:"umask,omitempty"`
 	DMTCPConfig           DMTCPConfig       `json:"dmtcpConfig,omitempty"`
 	XdgRuntimeDir         string            `json:"xdgRuntimeDir,omitempty"`
@@ -157,6 +203,16 @@ type JSONConfig struct {
 	ShareNSMode           bool              `json:"sharensMode,omitempty"`
 	ShareNSFd             int               `json:"sharensFd,omitempty"`
 	RunscriptTimeout      string            `json:"runscriptTimeout,omitempty"`
+	Entrypoint            string            `json:"entrypoint,omitempty"`
+	Wrap                  []string          `json:"wrap,omitempty"`
+	NoAutofsWorkaround    bool              `json:"noAutofsWorkaround,omitempty"`
+	DumpOciSpec           string            `json:"dumpOciSpec,omitempty"`
+	DumpMounts            string            `json:"dumpMounts,omitempty"`
+	Profile               string            `json:"profile,omitempty"`
+	DryRun                bool              `json:"dryRun,omitempty"`
+	PrintCaps             bool              `json:"printCaps,omitempty"`
+	LoopMode              string            `json:"loopMode,omitempty"`
+	OverlayPrecedence     string            `json:"overlayPrecedence,omitempty"`
 }
 
 // SetImage sets the container image path to be used by EngineConfig.JSON.
@@ -209,6 +265,88 @@ func (e *EngineConfig) GetOverlayImage() []string {
 	return e.JSON.OverlayImage
 }
 
+// SetOverlayVerify sets if overlay images must carry a valid signature.
+func (e *EngineConfig) SetOverlayVerify(val bool) {
+	e.JSON.OverlayVerify = val
+}
+
+// GetOverlayVerify returns if overlay images must carry a valid signature.
+func (e *EngineConfig) GetOverlayVerify() bool {
+	return e.JSON.OverlayVerify
+}
+
+// SetOverlayWarnFree sets the free space in bytes of the writable ext3
+// overlay image below which a warning is displayed.
+func (e *EngineConfig) SetOverlayWarnFree(size uint64) {
+	e.JSON.OverlayWarnFree = size
+}
+
+// GetOverlayWarnFree returns the free space in bytes of the writable ext3
+// overlay image below which a warning is displayed.
+func (e *EngineConfig) GetOverlayWarnFree() uint64 {
+	return e.JSON.OverlayWarnFree
+}
+
+// SetOCILayers sets the unpacked OCI layer directories stacked as read-only
+// overlay lower directories on top of the container image, ordered from the
+// bottom to the top layer.
+func (e *EngineConfig) SetOCILayers(paths []string) {
+	e.JSON.OCILayers = paths
+}
+
+// GetOCILayers retrieves the unpacked OCI layer directories.
+func (e *EngineConfig) GetOCILayers() []string {
+	return e.JSON.OCILayers
+}
+
+// SetImageMountOpts sets the access time mount options applied to
+// ext3 and sandbox root filesystem and overlay images.
+func (e *EngineConfig) SetImageMountOpts(opts []string) {
+	e.JSON.ImageMountOpts = opts
+}
+
+// GetImageMountOpts retrieves the access time mount options applied to
+// ext3 and sandbox root filesystem and overlay images.
+func (e *EngineConfig) GetImageMountOpts() []string {
+	return e.JSON.ImageMountOpts
+}
+
+// SetSquashfsMountOpts sets the key=value mount options passed to the
+// squashfs root filesystem and data image mounts.
+func (e *EngineConfig) SetSquashfsMountOpts(opts []string) {
+	e.JSON.SquashfsMountOpts = opts
+}
+
+// GetSquashfsMountOpts retrieves the key=value mount options passed to
+// the squashfs root filesystem and data image mounts.
+func (e *EngineConfig) GetSquashfsMountOpts() []string {
+	return e.JSON.SquashfsMountOpts
+}
+
+// SetOverlayOpts sets the overlay features (metacopy, redirect_dir)
+// enabled for the container overlay.
+func (e *EngineConfig) SetOverlayOpts(opts []string) {
+	e.JSON.OverlayOpts = opts
+}
+
+// GetOverlayOpts retrieves the overlay features enabled for the container
+// overlay.
+func (e *EngineConfig) GetOverlayOpts() []string {
+	return e.JSON.OverlayOpts
+}
+
+// SetImageDriver sets the image driver used for this container instead
+// of the image driver set in the configuration file.
+func (e *EngineConfig) SetImageDriver(name string) {
+	e.JSON.ImageDriver = name
+}
+
+// GetImageDriver returns the image driver used for this container
+// instead of the image driver set in the configuration file.
+func (e *EngineConfig) GetImageDriver() string {
+	return e.JSON.ImageDriver
+}
+
 // SetContain sets contain flag.
 func (e *EngineConfig) SetContain(contain bool) {
 	e.JSON.Contain = contain
@@ -259,6 +397,16 @@ func (e *EngineConfig) GetRocm() bool {
 	return e.JSON.Rocm
 }
 
+// SetDRI sets dri flag to add DRM card and render nodes into container.
+func (e *EngineConfig) SetDRI(dri bool) {
+	e.JSON.DRI = dri
+}
+
+// GetDRI returns if dri flag is set or not.
+func (e *EngineConfig) GetDRI() bool {
+	return e.JSON.DRI
+}
+
 // SetWorkdir sets a work directory path.
 func (e *EngineConfig) SetWorkdir(name string) {
 	e.JSON.Workdir = name
@@ -310,6 +458,18 @@ func (e *EngineConfig) GetHomeDest() string {
 	return e.JSON.HomeDest
 }
 
+// SetHomeKeepPath sets if the home directory is mounted at its
+// original path instead of /root with fakeroot.
+func (e *EngineConfig) SetHomeKeepPath(keep bool) {
+	e.JSON.HomeKeepPath = keep
+}
+
+// GetHomeKeepPath returns if the home directory is mounted at its
+// original path instead of /root with fakeroot.
+func (e *EngineConfig) GetHomeKeepPath() bool {
+	return e.JSON.HomeKeepPath
+}
+
 // SetCustomHome sets if home path is a custom path or not.
 func (e *EngineConfig) SetCustomHome(custom bool) {
 	e.JSON.CustomHome = custom
@@ -330,6 +490,48 @@ func (e *EngineConfig) GetBindPath() []BindPath {
 	return e.JSON.BindPath
 }
 
+// SetOverlayBind sets the host directories to mount with an overlay
+// into container.
+func (e *EngineConfig) SetOverlayBind(binds []OverlayBind) {
+	e.JSON.OverlayBind = binds
+}
+
+// GetOverlayBind retrieves the overlay binds.
+func (e *EngineConfig) GetOverlayBind() []OverlayBind {
+	return e.JSON.OverlayBind
+}
+
+// SetMountFrom sets the paths of running instances to mount
+// into container.
+func (e *EngineConfig) SetMountFrom(mounts []MountFrom) {
+	e.JSON.MountFrom = mounts
+}
+
+// GetMountFrom retrieves the paths of running instances to mount.
+func (e *EngineConfig) GetMountFrom() []MountFrom {
+	return e.JSON.MountFrom
+}
+
+// SetTmpfsMounts sets the tmpfs filesystems to mount in the container.
+func (e *EngineConfig) SetTmpfsMounts(mounts []TmpfsMount) {
+	e.JSON.TmpfsMounts = mounts
+}
+
+// GetTmpfsMounts retrieves the tmpfs filesystems to mount in the container.
+func (e *EngineConfig) GetTmpfsMounts() []TmpfsMount {
+	return e.JSON.TmpfsMounts
+}
+
+// SetKernelMounts sets the kernel filesystems to mount in the container.
+func (e *EngineConfig) SetKernelMounts(mounts []KernelMount) {
+	e.JSON.KernelMounts = mounts
+}
+
+// GetKernelMounts retrieves the kernel filesystems to mount in the container.
+func (e *EngineConfig) GetKernelMounts() []KernelMount {
+	return e.JSON.KernelMounts
+}
+
 // SetCommand sets action command to execute.
 func (e *EngineConfig) SetCommand(command string) {
 	e.JSON.Command = command
@@ -420,6 +622,16 @@ func (e *EngineConfig) GetDropCaps() string {
 	return e.JSON.DropCaps
 }
 
+// SetFakerootCaps sets the capabilities set within the fakeroot user namespace.
+func (e *EngineConfig) SetFakerootCaps(caps string) {
+	e.JSON.FakerootCaps = caps
+}
+
+// GetFakerootCaps retrieves the capabilities set within the fakeroot user namespace.
+func (e *EngineConfig) GetFakerootCaps() string {
+	return e.JSON.FakerootCaps
+}
+
 // SetHostname sets hostname to use in containee.JSON.
 func (e *EngineConfig) SetHostname(hostname string) {
 	e.JSON.Hostname = hostname
@@ -480,6 +692,16 @@ func (e *EngineConfig) GetNoSys() bool {
 	return e.JSON.NoSys
 }
 
+// SetBindCgroupfs sets flag to bind the host /sys/fs/cgroup read-only.
+func (e *EngineConfig) SetBindCgroupfs(val bool) {
+	e.JSON.BindCgroupfs = val
+}
+
+// GetBindCgroupfs returns if the host /sys/fs/cgroup is bound read-only.
+func (e *EngineConfig) GetBindCgroupfs() bool {
+	return e.JSON.BindCgroupfs
+}
+
 // SetNoDev set flag to not mount dev directory.
 func (e *EngineConfig) SetNoDev(val bool) {
 	e.JSON.NoDev = val
@@ -540,6 +762,26 @@ func (e *EngineConfig) GetNoCwd() bool {
 	return e.JSON.NoCwd
 }
 
+// SetNoTTYConsole sets flag to not bind the terminal at /dev/console.
+func (e *EngineConfig) SetNoTTYConsole(val bool) {
+	e.JSON.NoTTYConsole = val
+}
+
+// GetNoTTYConsole returns if no-tty-console flag is set or not.
+func (e *EngineConfig) GetNoTTYConsole() bool {
+	return e.JSON.NoTTYConsole
+}
+
+// SetCwdReadOnly sets flag to mount CWD read-only.
+func (e *EngineConfig) SetCwdReadOnly(val bool) {
+	e.JSON.CwdReadOnly = val
+}
+
+// GetCwdReadOnly returns if CWD is mounted read-only or not.
+func (e *EngineConfig) GetCwdReadOnly() bool {
+	return e.JSON.CwdReadOnly
+}
+
 // SetSkipBinds sets bind paths to skip
 func (e *EngineConfig) SetSkipBinds(val []string) {
 	e.JSON.SkipBinds = val
@@ -580,6 +822,27 @@ func (e *EngineConfig) GetNetworkArgs() []string {
 	return e.JSON.NetworkArgs
 }
 
+// SetNetworkRetries sets the number of times network setup is retried
+// on transient CNI plugin errors, a negative value selects the value
+// from the configuration.
+func (e *EngineConfig) SetNetworkRetries(retries int) {
+	if retries < 0 {
+		e.JSON.NetworkRetries = nil
+		return
+	}
+	r := uint(retries)
+	e.JSON.NetworkRetries = &r
+}
+
+// GetNetworkRetries returns the number of times network setup is retried
+// on transient CNI plugin errors, or -1 if not set.
+func (e *EngineConfig) GetNetworkRetries() int {
+	if e.JSON.NetworkRetries == nil {
+		return -1
+	}
+	return int(*e.JSON.NetworkRetries)
+}
+
 // SetDNS sets a commas separated list of DNS servers to add in resolv.conf.
 func (e *EngineConfig) SetDNS(dns string) {
 	e.JSON.DNS = dns
@@ -590,6 +853,38 @@ func (e *EngineConfig) GetDNS() string {
 	return e.JSON.DNS
 }
 
+// SetDNSSearch sets the search domains to add in resolv.conf.
+func (e *EngineConfig) SetDNSSearch(search []string) {
+	e.JSON.DNSSearch = search
+}
+
+// GetDNSSearch retrieves the search domains to add in resolv.conf.
+func (e *EngineConfig) GetDNSSearch() []string {
+	return e.JSON.DNSSearch
+}
+
+// SetDNSOptions sets the resolver options to add in resolv.conf.
+func (e *EngineConfig) SetDNSOptions(options []string) {
+	e.JSON.DNSOptions = options
+}
+
+// GetDNSOptions retrieves the resolver options to add in resolv.conf.
+func (e *EngineConfig) GetDNSOptions() []string {
+	return e.JSON.DNSOptions
+}
+
+// SetNsswitch sets if a minimal nsswitch.conf resolving from local
+// files is staged in the container.
+func (e *EngineConfig) SetNsswitch(nsswitch bool) {
+	e.JSON.Nsswitch = nsswitch
+}
+
+// GetNsswitch returns if a minimal nsswitch.conf resolving from local
+// files is staged in the container.
+func (e *EngineConfig) GetNsswitch() bool {
+	return e.JSON.Nsswitch
+}
+
 // SetImageList sets image list containing opened images.
 func (e *EngineConfig) SetImageList(list []image.Image) {
 	e.JSON.ImageList = list
@@ -734,6 +1029,40 @@ func (e *EngineConfig) GetFakeroot() bool {
 	return e.JSON.Fakeroot
 }
 
+// SetKeepID sets keep-id flag.
+func (e *EngineConfig) SetKeepID(keepID bool) {
+	e.JSON.KeepID = keepID
+}
+
+// GetKeepID returns if keep-id is set or not.
+func (e *EngineConfig) GetKeepID() bool {
+	return e.JSON.KeepID
+}
+
+// SetPasswdEntry sets the user entry, in name:uid:gid:gecos:home:shell
+// format, overriding the one generated in the container passwd file.
+func (e *EngineConfig) SetPasswdEntry(entry string) {
+	e.JSON.PasswdEntry = entry
+}
+
+// GetPasswdEntry returns the user entry overriding the one generated in
+// the container passwd file.
+func (e *EngineConfig) GetPasswdEntry() string {
+	return e.JSON.PasswdEntry
+}
+
+// SetGroupEntry sets the group entry, in name:gid format, overriding the
+// one generated in the container group file.
+func (e *EngineConfig) SetGroupEntry(entry string) {
+	e.JSON.GroupEntry = entry
+}
+
+// GetGroupEntry returns the group entry overriding the one generated in
+// the container group file.
+func (e *EngineConfig) GetGroupEntry() string {
+	return e.JSON.GroupEntry
+}
+
 // GetDeleteTempDir returns the path of the temporary directory containing the root filesystem
 // which must be deleted after use. If no deletion is required, the empty string is returned.
 func (e *EngineConfig) GetDeleteTempDir() string {
@@ -746,6 +1075,52 @@ func (e *EngineConfig) SetDeleteTempDir(dir string) {
 	e.JSON.DeleteTempDir = dir
 }
 
+// GetDeleteTempOverlay returns the path of the temporary directory containing
+// the writable overlay image which must be deleted once the container exits.
+func (e *EngineConfig) GetDeleteTempOverlay() string {
+	return e.JSON.DeleteTempOverlay
+}
+
+// SetDeleteTempOverlay sets dir as the path of the temporary directory containing
+// the writable overlay image, which must be deleted once the container exits.
+func (e *EngineConfig) SetDeleteTempOverlay(dir string) {
+	e.JSON.DeleteTempOverlay = dir
+}
+
+// SetSessionFsType sets the memory filesystem type of the session
+// directory used instead of the 'memory fs type' set in apptainer.conf.
+func (e *EngineConfig) SetSessionFsType(fsType string) {
+	e.JSON.SessionFsType = fsType
+}
+
+// GetSessionFsType returns the memory filesystem type of the session
+// directory used instead of the 'memory fs type' set in apptainer.conf.
+func (e *EngineConfig) GetSessionFsType() string {
+	return e.JSON.SessionFsType
+}
+
+// SetSessionSize sets the size in MiB of the session directory.
+func (e *EngineConfig) SetSessionSize(size int) {
+	e.JSON.SessionSize = size
+}
+
+// GetSessionSize returns the size in MiB of the session directory.
+func (e *EngineConfig) GetSessionSize() int {
+	return e.JSON.SessionSize
+}
+
+// SetStrictBinds sets if a bind which can't be mounted is a fatal
+// error instead of being skipped with a warning.
+func (e *EngineConfig) SetStrictBinds(strict bool) {
+	e.JSON.StrictBinds = strict
+}
+
+// GetStrictBinds returns if a bind which can't be mounted is a fatal
+// error instead of being skipped with a warning.
+func (e *EngineConfig) GetStrictBinds() bool {
+	return e.JSON.StrictBinds
+}
+
 // SetSignalPropagation sets if engine must propagate signals from
 // master process -> container process when PID namespace is disabled
 // or from master process -> appinit process -> container
@@ -760,6 +1135,52 @@ func (e *EngineConfig) GetSignalPropagation() bool {
 	return e.JSON.SignalPropagation
 }
 
+// SetTraceSyscalls sets the path of the file where the system calls
+// of the container process are traced.
+func (e *EngineConfig) SetTraceSyscalls(path string) {
+	e.JSON.TraceSyscalls = path
+}
+
+// GetTraceSyscalls returns the path of the file where the system calls
+// of the container process are traced.
+func (e *EngineConfig) GetTraceSyscalls() string {
+	return e.JSON.TraceSyscalls
+}
+
+// SetTraceSyscallsFd sets the file descriptor of the system calls trace
+// file opened in stage one by the engine.
+func (e *EngineConfig) SetTraceSyscallsFd(fd int) {
+	e.JSON.TraceSyscallsFd = fd
+}
+
+// SetSchedPolicy sets the CPU scheduling policy of the container process
+// in <policy>[:<priority>] format.
+func (e *EngineConfig) SetSchedPolicy(policy string) {
+	e.JSON.SchedPolicy = policy
+}
+
+// GetSchedPolicy returns the CPU scheduling policy of the container process.
+func (e *EngineConfig) GetSchedPolicy() string {
+	return e.JSON.SchedPolicy
+}
+
+// SetNice sets the nice value of the container process.
+func (e *EngineConfig) SetNice(nice int) {
+	e.JSON.Nice = &nice
+}
+
+// GetNice returns the nice value of the container process, or nil if
+// the nice value is inherited.
+func (e *EngineConfig) GetNice() *int {
+	return e.JSON.Nice
+}
+
+// GetTraceSyscallsFd returns the file descriptor of the system calls
+// trace file previously set in stage one by the engine.
+func (e *EngineConfig) GetTraceSyscallsFd() int {
+	return e.JSON.TraceSyscallsFd
+}
+
 // GetSessionLayer returns the session layer used to setup the
 // container mount points.
 func (e *EngineConfig) GetSessionLayer() string {
@@ -987,3 +1408,118 @@ func (e *EngineConfig) SetRunscriptTimout(timeout string) {
 func (e *EngineConfig) GetRunscriptTimeout() string {
 	return e.JSON.RunscriptTimeout
 }
+
+// SetEntrypoint sets the command executed in place of the runscript.
+func (e *EngineConfig) SetEntrypoint(entrypoint string) {
+	e.JSON.Entrypoint = entrypoint
+}
+
+// GetEntrypoint gets the command executed in place of the runscript.
+func (e *EngineConfig) GetEntrypoint() string {
+	return e.JSON.Entrypoint
+}
+
+// SetWrap sets the wrapper command prepended to the container process arguments.
+func (e *EngineConfig) SetWrap(wrap []string) {
+	e.JSON.Wrap = wrap
+}
+
+// GetWrap gets the wrapper command prepended to the container process arguments.
+func (e *EngineConfig) GetWrap() []string {
+	return e.JSON.Wrap
+}
+
+// SetNoAutofsWorkaround sets whether file descriptors are kept open on
+// bind sources located under autofs mount points.
+func (e *EngineConfig) SetNoAutofsWorkaround(val bool) {
+	e.JSON.NoAutofsWorkaround = val
+}
+
+// GetNoAutofsWorkaround returns if the autofs workaround is disabled or not.
+func (e *EngineConfig) GetNoAutofsWorkaround() bool {
+	return e.JSON.NoAutofsWorkaround
+}
+
+// SetDumpOciSpec sets the path where the OCI runtime spec assembled for
+// the container is written, "-" for the standard output.
+func (e *EngineConfig) SetDumpOciSpec(path string) {
+	e.JSON.DumpOciSpec = path
+}
+
+// GetDumpOciSpec returns the path where the OCI runtime spec assembled
+// for the container is written.
+func (e *EngineConfig) GetDumpOciSpec() string {
+	return e.JSON.DumpOciSpec
+}
+
+// SetDumpMounts sets the absolute path where the container mount table
+// is written as JSON before mounting and updated once all mount points
+// were processed.
+func (e *EngineConfig) SetDumpMounts(path string) {
+	e.JSON.DumpMounts = path
+}
+
+// GetDumpMounts returns the path where the container mount table is
+// written.
+func (e *EngineConfig) GetDumpMounts() string {
+	return e.JSON.DumpMounts
+}
+
+// SetProfile sets the path where the container startup timeline is
+// written as JSON once the container is created.
+func (e *EngineConfig) SetProfile(path string) {
+	e.JSON.Profile = path
+}
+
+// GetProfile returns the path where the container startup timeline is
+// written.
+func (e *EngineConfig) GetProfile() string {
+	return e.JSON.Profile
+}
+
+// SetDryRun sets whether the container configuration is prepared without
+// starting the container.
+func (e *EngineConfig) SetDryRun(val bool) {
+	e.JSON.DryRun = val
+}
+
+// GetDryRun returns if the container configuration is prepared without
+// starting the container.
+func (e *EngineConfig) GetDryRun() bool {
+	return e.JSON.DryRun
+}
+
+// SetPrintCaps sets whether the capability sets resolved for the
+// container process are printed once the configuration is prepared.
+func (e *EngineConfig) SetPrintCaps(val bool) {
+	e.JSON.PrintCaps = val
+}
+
+// GetPrintCaps returns if the capability sets resolved for the
+// container process are printed once the configuration is prepared.
+func (e *EngineConfig) GetPrintCaps() bool {
+	return e.JSON.PrintCaps
+}
+
+// SetLoopMode sets how squashfs image partitions are mounted, one of
+// LoopModeKernel or LoopModeFuse.
+func (e *EngineConfig) SetLoopMode(mode string) {
+	e.JSON.LoopMode = mode
+}
+
+// GetLoopMode returns how squashfs image partitions are mounted.
+func (e *EngineConfig) GetLoopMode() string {
+	return e.JSON.LoopMode
+}
+
+// SetOverlayPrecedence sets which overlay lower layers take precedence when
+// several overlays provide the same file, one of OverlayPrecedenceLast or
+// OverlayPrecedenceFirst.
+func (e *EngineConfig) SetOverlayPrecedence(precedence string) {
+	e.JSON.OverlayPrecedence = precedence
+}
+
+// GetOverlayPrecedence returns which overlay lower layers take precedence.
+func (e *EngineConfig) GetOverlayPrecedence() string {
+	return e.JSON.OverlayPrecedence
+}
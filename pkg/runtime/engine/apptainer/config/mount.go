@@ -8,14 +8,57 @@ package apptainer
 import (
 	"encoding/csv"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+
+	"github.com/docker/go-units"
 )
 
+// TmpfsMount stores a parsed --mount type=tmpfs specification.
+type TmpfsMount struct {
+	Destination string `json:"destination"`
+	// Size is the tmpfs size limit in bytes, the kernel
+	// default is used when zero.
+	Size int64 `json:"size,omitempty"`
+	// Mode is the permission of the tmpfs root directory, the
+	// kernel default is used when zero.
+	Mode uint32 `json:"mode,omitempty"`
+}
+
+// KernelMount stores a parsed --mount type=kernelfs specification.
+type KernelMount struct {
+	// Name is the kernel filesystem type, one of KernelFilesystems.
+	Name        string `json:"name"`
+	Destination string `json:"destination"`
+}
+
+// KernelFilesystems maps the kernel filesystems which can be requested
+// with --mount type=kernelfs to their default destination.
+var KernelFilesystems = map[string]string{
+	"debugfs": "/sys/kernel/debug",
+	"tracefs": "/sys/kernel/tracing",
+	"cgroup2": "/sys/fs/cgroup",
+}
+
+// Options returns the tmpfs mount options.
+func (t TmpfsMount) Options() string {
+	opts := make([]string, 0, 2)
+	if t.Size > 0 {
+		opts = append(opts, "size="+strconv.FormatInt(t.Size, 10))
+	}
+	if t.Mode != 0 {
+		opts = append(opts, "mode="+strconv.FormatUint(uint64(t.Mode), 8))
+	}
+	return strings.Join(opts, ",")
+}
+
 // ParseMountString converts a --mount string into one or more BindPath structs.
 //
 // Our intention is to support common docker --mount strings, but have
-// additional fields for apptainer specific concepts (image-src, id when
-// binding out of an image file).
+// additional fields for apptainer specific concepts (image-src, id or name
+// when binding out of an image file).
 //
 // We use a CSV reader to parse the fields in a mount string according to CSV
 // escaping rules. This is the approach docker uses to allow special characters
@@ -27,71 +70,245 @@ import (
 //
 //	type=bind,source=/opt,destination=/other,rw
 //
-// We only support type=bind at present, so assume this if type is missing and
-// error for other types.
+// Only type=bind is supported by ParseMountString, so assume this if type is
+// missing and error for other types. Use ParseMounts to also accept tmpfs
+// and kernelfs mounts.
+//
+// Relative sources are resolved against the current working directory, and
+// sources starting with ~/ against the home directory of the calling user.
 func ParseMountString(mount string) (bindPaths []BindPath, err error) {
+	bindPaths, tmpfsMounts, kernelMounts, err := ParseMounts(mount)
+	if err != nil {
+		return []BindPath{}, err
+	}
+	if len(tmpfsMounts) > 0 {
+		return []BindPath{}, fmt.Errorf("unsupported mount type \"tmpfs\", only 'bind' is supported")
+	}
+	if len(kernelMounts) > 0 {
+		return []BindPath{}, fmt.Errorf("unsupported mount type \"kernelfs\", only 'bind' is supported")
+	}
+	return bindPaths, nil
+}
+
+// ParseMounts converts a --mount string into bind paths, tmpfs mounts and
+// kernel filesystem mounts. It accepts the same bind mount strings as
+// ParseMountString, tmpfs mounts in the format:
+//
+//	type=tmpfs,destination=/cache,size=256m,mode=1777
+//
+// The size accepts the docker tmpfs-size units, the mode is an octal
+// permission. The docker tmpfs-size and tmpfs-mode keys are also accepted.
+// Kernel filesystem mounts are in the format:
+//
+//	type=kernelfs,name=tracefs[,destination=/sys/kernel/tracing]
+//
+// The name is one of KernelFilesystems, the destination defaults to the
+// usual filesystem location.
+func ParseMounts(mount string) (bindPaths []BindPath, tmpfsMounts []TmpfsMount, kernelMounts []KernelMount, err error) {
 	r := strings.NewReader(mount)
 	c := csv.NewReader(r)
 	records, err := c.ReadAll()
 	if err != nil {
-		return []BindPath{}, fmt.Errorf("error parsing mount: %v", err)
+		return []BindPath{}, nil, nil, fmt.Errorf("error parsing mount: %v", err)
 	}
 
 	for _, r := range records {
-		bp := BindPath{
-			Options: map[string]*BindOption{},
+		switch {
+		case isRecordType(r, "tmpfs"):
+			tm, err := parseTmpfsRecord(r)
+			if err != nil {
+				return []BindPath{}, nil, nil, err
+			}
+			tmpfsMounts = append(tmpfsMounts, tm)
+		case isRecordType(r, "kernelfs"):
+			km, err := parseKernelRecord(r)
+			if err != nil {
+				return []BindPath{}, nil, nil, err
+			}
+			kernelMounts = append(kernelMounts, km)
+		default:
+			bp, err := parseBindRecord(r)
+			if err != nil {
+				return []BindPath{}, nil, nil, err
+			}
+			bindPaths = append(bindPaths, bp)
 		}
+	}
+
+	return bindPaths, tmpfsMounts, kernelMounts, nil
+}
 
-		for _, f := range r {
-			kv := strings.SplitN(f, "=", 2)
-			key := kv[0]
-			val := ""
-			if len(kv) > 1 {
-				val = kv[1]
-			}
-
-			switch key {
-			// TODO - Eventually support volume and tmpfs? Requires structural changes to engine mount functionality.
-			case "type":
-				if val != "bind" {
-					return []BindPath{}, fmt.Errorf("unsupported mount type %q, only 'bind' is supported", val)
-				}
-			case "source", "src":
-				if val == "" {
-					return []BindPath{}, fmt.Errorf("mount source cannot be empty")
-				}
-				bp.Source = val
-			case "destination", "dst", "target":
-				if val == "" {
-					return []BindPath{}, fmt.Errorf("mount destination cannot be empty")
-				}
-				bp.Destination = val
-			case "ro", "readonly":
-				bp.Options["ro"] = &BindOption{}
-			// Apptainer only - directory inside an image file source to mount from
-			case "image-src":
-				if val == "" {
-					return []BindPath{}, fmt.Errorf("img-src cannot be empty")
-				}
-				bp.Options["image-src"] = &BindOption{Value: val}
-			// Apptainer only - id of the descriptor in a SIF image source to mount from
-			case "id":
-				if val == "" {
-					return []BindPath{}, fmt.Errorf("id cannot be empty")
-				}
-				bp.Options["id"] = &BindOption{Value: val}
-			case "bind-propagation":
-				return []BindPath{}, fmt.Errorf("bind-propagation not supported for individual mounts, check apptainer.conf for global setting")
-			default:
-				return []BindPath{}, fmt.Errorf("invalid key %q in mount specification", key)
+// isRecordType returns whether the mount record has the type mountType.
+func isRecordType(r []string, mountType string) bool {
+	for _, f := range r {
+		if f == "type="+mountType {
+			return true
+		}
+	}
+	return false
+}
+
+// parseKernelRecord parses the fields of a kernel filesystem mount.
+func parseKernelRecord(r []string) (KernelMount, error) {
+	var km KernelMount
+
+	for _, f := range r {
+		key, val, _ := strings.Cut(f, "=")
+
+		switch key {
+		case "type":
+		case "name":
+			if _, ok := KernelFilesystems[val]; !ok {
+				return km, fmt.Errorf("unsupported kernel filesystem %q, must be one of debugfs, tracefs or cgroup2", val)
+			}
+			km.Name = val
+		case "destination", "dst", "target":
+			if val == "" {
+				return km, fmt.Errorf("mount destination cannot be empty")
 			}
+			km.Destination = val
+		default:
+			return km, fmt.Errorf("invalid key %q in kernelfs mount specification", key)
 		}
+	}
 
-		if bp.Source == "" || bp.Destination == "" {
-			return []BindPath{}, fmt.Errorf("mounts must specify a source and a destination")
+	if km.Name == "" {
+		return km, fmt.Errorf("kernelfs mounts must specify a filesystem name")
+	}
+	if km.Destination == "" {
+		km.Destination = KernelFilesystems[km.Name]
+	}
+	if !strings.HasPrefix(km.Destination, "/") {
+		return km, fmt.Errorf("kernelfs mount destination %s must be an absolute path", km.Destination)
+	}
+	return km, nil
+}
+
+// parseTmpfsRecord parses the fields of a tmpfs mount.
+func parseTmpfsRecord(r []string) (TmpfsMount, error) {
+	var tm TmpfsMount
+
+	for _, f := range r {
+		key, val, _ := strings.Cut(f, "=")
+
+		switch key {
+		case "type":
+		case "destination", "dst", "target":
+			if val == "" {
+				return tm, fmt.Errorf("mount destination cannot be empty")
+			}
+			tm.Destination = val
+		case "size", "tmpfs-size":
+			size, err := units.RAMInBytes(val)
+			if err != nil {
+				return tm, fmt.Errorf("invalid tmpfs size %q: %v", val, err)
+			}
+			if size <= 0 {
+				return tm, fmt.Errorf("invalid tmpfs size %q: must be greater than zero", val)
+			}
+			tm.Size = size
+		case "mode", "tmpfs-mode":
+			mode, err := strconv.ParseUint(val, 8, 32)
+			if err != nil || mode > 0o7777 {
+				return tm, fmt.Errorf("invalid tmpfs mode %q: must be an octal permission", val)
+			}
+			tm.Mode = uint32(mode)
+		default:
+			return tm, fmt.Errorf("invalid key %q in tmpfs mount specification", key)
 		}
-		bindPaths = append(bindPaths, bp)
 	}
 
-	return bindPaths, nil
+	if tm.Destination == "" {
+		return tm, fmt.Errorf("tmpfs mounts must specify a destination")
+	}
+	if !strings.HasPrefix(tm.Destination, "/") {
+		return tm, fmt.Errorf("tmpfs mount destination %s must be an absolute path", tm.Destination)
+	}
+	return tm, nil
+}
+
+// parseBindRecord parses the fields of a bind mount.
+func parseBindRecord(r []string) (BindPath, error) {
+	bp := BindPath{
+		Options: map[string]*BindOption{},
+	}
+
+	for _, f := range r {
+		kv := strings.SplitN(f, "=", 2)
+		key := kv[0]
+		val := ""
+		if len(kv) > 1 {
+			val = kv[1]
+		}
+
+		switch key {
+		// TODO - Eventually support volume? Requires structural changes to engine mount functionality.
+		case "type":
+			if val != "bind" {
+				return BindPath{}, fmt.Errorf("unsupported mount type %q, only 'bind' is supported", val)
+			}
+		case "source", "src":
+			if val == "" {
+				return BindPath{}, fmt.Errorf("mount source cannot be empty")
+			}
+			src, err := resolveMountSource(val)
+			if err != nil {
+				return BindPath{}, err
+			}
+			bp.Source = src
+		case "destination", "dst", "target":
+			if val == "" {
+				return BindPath{}, fmt.Errorf("mount destination cannot be empty")
+			}
+			bp.Destination = val
+		case "ro", "readonly":
+			bp.Options["ro"] = &BindOption{}
+		// Apptainer only - directory inside an image file source to mount from
+		case "image-src":
+			if val == "" {
+				return BindPath{}, fmt.Errorf("img-src cannot be empty")
+			}
+			bp.Options["image-src"] = &BindOption{Value: val}
+		// Apptainer only - id of the descriptor in a SIF image source to mount from
+		case "id":
+			if val == "" {
+				return BindPath{}, fmt.Errorf("id cannot be empty")
+			}
+			bp.Options["id"] = &BindOption{Value: val}
+		// Apptainer only - name of the descriptor in a SIF image source to mount from
+		case "name":
+			if val == "" {
+				return BindPath{}, fmt.Errorf("name cannot be empty")
+			}
+			bp.Options["name"] = &BindOption{Value: val}
+		case "bind-propagation":
+			return BindPath{}, fmt.Errorf("bind-propagation not supported for individual mounts, check apptainer.conf for global setting")
+		default:
+			return BindPath{}, fmt.Errorf("invalid key %q in mount specification", key)
+		}
+	}
+
+	if bp.ID() != "" && bp.PartitionName() != "" {
+		return BindPath{}, fmt.Errorf("id and name are mutually exclusive in mount specification")
+	}
+	if bp.Source == "" || bp.Destination == "" {
+		return BindPath{}, fmt.Errorf("mounts must specify a source and a destination")
+	}
+	return bp, nil
+}
+
+// resolveMountSource returns the absolute path of a mount source.
+func resolveMountSource(src string) (string, error) {
+	if src == "~" || strings.HasPrefix(src, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("while resolving mount source %s: %v", src, err)
+		}
+		src = filepath.Join(home, src[1:])
+	}
+	abs, err := filepath.Abs(src)
+	if err != nil {
+		return "", fmt.Errorf("while resolving mount source %s: %v", src, err)
+	}
+	return abs, nil
 }
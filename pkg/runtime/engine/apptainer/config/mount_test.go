@@ -6,11 +6,20 @@
 package apptainer
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
 
 func TestParseMountString(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("could not get current working directory: %s", err)
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
 	tests := []struct {
 		name        string
 		mountString string
@@ -65,6 +74,54 @@ func TestParseMountString(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:        "relative",
+			mountString: "type=bind,source=./data,destination=/data",
+			want: []BindPath{
+				{
+					Source:      filepath.Join(cwd, "data"),
+					Destination: "/data",
+					Options:     map[string]*BindOption{},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:        "relativeParent",
+			mountString: "type=bind,source=../data,destination=/data",
+			want: []BindPath{
+				{
+					Source:      filepath.Join(filepath.Dir(cwd), "data"),
+					Destination: "/data",
+					Options:     map[string]*BindOption{},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:        "home",
+			mountString: "type=bind,source=~/data,destination=/data",
+			want: []BindPath{
+				{
+					Source:      filepath.Join(home, "data"),
+					Destination: "/data",
+					Options:     map[string]*BindOption{},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:        "homeOnly",
+			mountString: "type=bind,source=~,destination=/data",
+			want: []BindPath{
+				{
+					Source:      home,
+					Destination: "/data",
+					Options:     map[string]*BindOption{},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name:        "simpleSrc",
 			mountString: "type=bind,src=/opt,destination=/opt",
@@ -146,7 +203,7 @@ func TestParseMountString(t *testing.T) {
 			mountString: "type=bind,source=test.sif,destination=/opt,image-src=/opt",
 			want: []BindPath{
 				{
-					Source:      "test.sif",
+					Source:      filepath.Join(cwd, "test.sif"),
 					Destination: "/opt",
 					Options: map[string]*BindOption{
 						"image-src": {Value: "/opt"},
@@ -172,7 +229,7 @@ func TestParseMountString(t *testing.T) {
 			mountString: "type=bind,source=test.sif,destination=/opt,image-src=/opt,id=2",
 			want: []BindPath{
 				{
-					Source:      "test.sif",
+					Source:      filepath.Join(cwd, "test.sif"),
 					Destination: "/opt",
 					Options: map[string]*BindOption{
 						"image-src": {Value: "/opt"},
@@ -194,6 +251,33 @@ func TestParseMountString(t *testing.T) {
 			want:        []BindPath{},
 			wantErr:     true,
 		},
+		{
+			name:        "name",
+			mountString: "type=bind,source=test.sif,destination=/opt,image-src=/opt,name=models",
+			want: []BindPath{
+				{
+					Source:      filepath.Join(cwd, "test.sif"),
+					Destination: "/opt",
+					Options: map[string]*BindOption{
+						"image-src": {Value: "/opt"},
+						"name":      {Value: "models"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:        "nameEmpty",
+			mountString: "type=bind,source=test.sif,destination=/opt,image-src=/opt,name=",
+			want:        []BindPath{},
+			wantErr:     true,
+		},
+		{
+			name:        "idAndName",
+			mountString: "type=bind,source=test.sif,destination=/opt,id=2,name=models",
+			want:        []BindPath{},
+			wantErr:     true,
+		},
 		{
 			name:        "bindpropagation",
 			mountString: "type=bind,source=/opt,destination=/opt,bind-propagation=shared",
@@ -229,6 +313,11 @@ func TestParseMountString(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:        "tmpfs",
+			mountString: "type=tmpfs,destination=/cache",
+			wantErr:     true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -243,3 +332,134 @@ func TestParseMountString(t *testing.T) {
 		})
 	}
 }
+
+func TestParseMounts(t *testing.T) {
+	tests := []struct {
+		name        string
+		mountString string
+		wantBinds   []BindPath
+		wantTmpfs   []TmpfsMount
+		wantKernel  []KernelMount
+		wantErr     bool
+	}{
+		{
+			name:        "tmpfs",
+			mountString: "type=tmpfs,destination=/cache",
+			wantTmpfs:   []TmpfsMount{{Destination: "/cache"}},
+		},
+		{
+			name:        "tmpfsSizeMode",
+			mountString: "type=tmpfs,destination=/cache,size=256m,mode=1777",
+			wantTmpfs:   []TmpfsMount{{Destination: "/cache", Size: 256 * 1024 * 1024, Mode: 0o1777}},
+		},
+		{
+			name:        "tmpfsDockerKeys",
+			mountString: "type=tmpfs,target=/cache,tmpfs-size=1024,tmpfs-mode=700",
+			wantTmpfs:   []TmpfsMount{{Destination: "/cache", Size: 1024, Mode: 0o700}},
+		},
+		{
+			name:        "tmpfsNoDestination",
+			mountString: "type=tmpfs,size=1g",
+			wantErr:     true,
+		},
+		{
+			name:        "tmpfsRelativeDestination",
+			mountString: "type=tmpfs,destination=cache",
+			wantErr:     true,
+		},
+		{
+			name:        "tmpfsInvalidSize",
+			mountString: "type=tmpfs,destination=/cache,size=lots",
+			wantErr:     true,
+		},
+		{
+			name:        "tmpfsZeroSize",
+			mountString: "type=tmpfs,destination=/cache,size=0",
+			wantErr:     true,
+		},
+		{
+			name:        "tmpfsInvalidMode",
+			mountString: "type=tmpfs,destination=/cache,mode=999",
+			wantErr:     true,
+		},
+		{
+			name:        "tmpfsSource",
+			mountString: "type=tmpfs,source=/opt,destination=/cache",
+			wantErr:     true,
+		},
+		{
+			name:        "bindAndTmpfs",
+			mountString: "type=bind,source=/opt,destination=/opt\ntype=tmpfs,destination=/cache,size=1k",
+			wantBinds: []BindPath{
+				{
+					Source:      "/opt",
+					Destination: "/opt",
+					Options:     map[string]*BindOption{},
+				},
+			},
+			wantTmpfs: []TmpfsMount{{Destination: "/cache", Size: 1024}},
+		},
+		{
+			name:        "kernelfs",
+			mountString: "type=kernelfs,name=tracefs",
+			wantKernel:  []KernelMount{{Name: "tracefs", Destination: "/sys/kernel/tracing"}},
+		},
+		{
+			name:        "kernelfsDestination",
+			mountString: "type=kernelfs,name=debugfs,destination=/debug",
+			wantKernel:  []KernelMount{{Name: "debugfs", Destination: "/debug"}},
+		},
+		{
+			name:        "kernelfsNoName",
+			mountString: "type=kernelfs,destination=/debug",
+			wantErr:     true,
+		},
+		{
+			name:        "kernelfsUnsupported",
+			mountString: "type=kernelfs,name=securityfs",
+			wantErr:     true,
+		},
+		{
+			name:        "kernelfsRelativeDestination",
+			mountString: "type=kernelfs,name=cgroup2,destination=cgroup",
+			wantErr:     true,
+		},
+		{
+			name:        "kernelfsSource",
+			mountString: "type=kernelfs,name=debugfs,source=/sys/kernel/debug",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binds, tmpfs, kernel, err := ParseMounts(tt.mountString)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseMounts() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(binds, tt.wantBinds) {
+				t.Errorf("ParseMounts() binds = %v, want %v", binds, tt.wantBinds)
+			}
+			if !reflect.DeepEqual(tmpfs, tt.wantTmpfs) {
+				t.Errorf("ParseMounts() tmpfs = %v, want %v", tmpfs, tt.wantTmpfs)
+			}
+			if !reflect.DeepEqual(kernel, tt.wantKernel) {
+				t.Errorf("ParseMounts() kernel = %v, want %v", kernel, tt.wantKernel)
+			}
+		})
+	}
+}
+
+func TestTmpfsMountOptions(t *testing.T) {
+	tm := TmpfsMount{Destination: "/cache"}
+	if opts := tm.Options(); opts != "" {
+		t.Errorf("unexpected options %q", opts)
+	}
+	tm = TmpfsMount{Destination: "/cache", Size: 268435456, Mode: 0o1777}
+	if opts := tm.Options(); opts != "size=268435456,mode=1777" {
+		t.Errorf("unexpected options %q", opts)
+	}
+}
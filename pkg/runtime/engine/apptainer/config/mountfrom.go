@@ -0,0 +1,65 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MountFromPrefix is the prefix of --mount-from specifications.
+const MountFromPrefix = "instance://"
+
+// MountFrom stores a parsed --mount-from specification, the path Source
+// within the root filesystem of the running instance Instance is mounted
+// at the container path Destination. Pid is the instance process ID,
+// resolved by the launcher.
+type MountFrom struct {
+	Instance    string `json:"instance"`
+	Pid         int    `json:"pid"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// ParseMountFrom converts a --mount-from string into a MountFrom, e.g.:
+//
+//	instance://NAME:/src[:/dest]
+//
+// The destination defaults to the source path.
+func ParseMountFrom(spec string) (MountFrom, error) {
+	var mf MountFrom
+
+	s, ok := strings.CutPrefix(spec, MountFromPrefix)
+	if !ok {
+		return mf, fmt.Errorf("mount from specification must start with %s", MountFromPrefix)
+	}
+
+	fields := strings.Split(s, ":")
+	if len(fields) < 2 || len(fields) > 3 {
+		return mf, fmt.Errorf("mount from specification must be in the form %sNAME:/src[:/dest]", MountFromPrefix)
+	}
+	mf.Instance = fields[0]
+	mf.Source = fields[1]
+	mf.Destination = fields[1]
+	if len(fields) == 3 {
+		mf.Destination = fields[2]
+	}
+
+	if mf.Instance == "" {
+		return mf, fmt.Errorf("mount from specification must specify an instance name")
+	}
+	if !strings.HasPrefix(mf.Source, "/") {
+		return mf, fmt.Errorf("mount from source %s must be an absolute path", mf.Source)
+	}
+	if !strings.HasPrefix(mf.Destination, "/") {
+		return mf, fmt.Errorf("mount from destination %s must be an absolute path", mf.Destination)
+	}
+
+	return mf, nil
+}
@@ -0,0 +1,76 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMountFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    MountFrom
+		wantErr bool
+	}{
+		{
+			name: "sourceDest",
+			spec: "instance://fuse:/mnt/data:/data",
+			want: MountFrom{Instance: "fuse", Source: "/mnt/data", Destination: "/data"},
+		},
+		{
+			name: "defaultDest",
+			spec: "instance://fuse:/mnt/data",
+			want: MountFrom{Instance: "fuse", Source: "/mnt/data", Destination: "/mnt/data"},
+		},
+		{
+			name:    "noPrefix",
+			spec:    "fuse:/mnt/data:/data",
+			wantErr: true,
+		},
+		{
+			name:    "noSource",
+			spec:    "instance://fuse",
+			wantErr: true,
+		},
+		{
+			name:    "noInstance",
+			spec:    "instance://:/mnt/data",
+			wantErr: true,
+		},
+		{
+			name:    "relativeSource",
+			spec:    "instance://fuse:mnt/data:/data",
+			wantErr: true,
+		},
+		{
+			name:    "relativeDest",
+			spec:    "instance://fuse:/mnt/data:data",
+			wantErr: true,
+		},
+		{
+			name:    "tooManyFields",
+			spec:    "instance://fuse:/mnt/data:/data:ro",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMountFrom(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMountFrom() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMountFrom() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// OverlayBindTmpfs is the only upper layer supported by overlay binds,
+// modifications are stored in the session directory and discarded when
+// the container exits.
+const OverlayBindTmpfs = "tmpfs"
+
+// OverlayBind stores a parsed --overlay-bind specification, the host
+// directory Lower is mounted read-only as the lower layer of an overlay
+// combined with a writable Upper layer at the container path Target.
+type OverlayBind struct {
+	Lower  string `json:"lower"`
+	Upper  string `json:"upper"`
+	Target string `json:"target"`
+}
+
+// ParseOverlayBind converts an --overlay-bind string in key=value CSV
+// format into an OverlayBind, e.g.:
+//
+//	lower=/host/ro,upper=tmpfs,target=/app
+//
+// The upper field is optional and defaults to tmpfs, a relative lower
+// directory is resolved from the current working directory and the target
+// field defaults to the lower directory absolute path.
+func ParseOverlayBind(spec string) (OverlayBind, error) {
+	ob := OverlayBind{Upper: OverlayBindTmpfs}
+
+	fields, err := csv.NewReader(strings.NewReader(spec)).Read()
+	if err != nil {
+		return ob, fmt.Errorf("error parsing overlay bind: %v", err)
+	}
+
+	for _, f := range fields {
+		key, val, _ := strings.Cut(f, "=")
+
+		switch key {
+		case "lower", "source", "src":
+			ob.Lower = val
+		case "upper":
+			if val != OverlayBindTmpfs {
+				return ob, fmt.Errorf("unsupported overlay bind upper %q, only '%s' is supported", val, OverlayBindTmpfs)
+			}
+		case "target", "destination", "dst":
+			ob.Target = val
+		default:
+			return ob, fmt.Errorf("invalid key %q in overlay bind specification", key)
+		}
+	}
+
+	if ob.Lower == "" {
+		return ob, fmt.Errorf("overlay binds must specify a lower directory")
+	}
+	// resolved before defaulting the target to the lower directory
+	if ob.Lower, err = filepath.Abs(ob.Lower); err != nil {
+		return ob, fmt.Errorf("while getting absolute path of %s: %v", ob.Lower, err)
+	}
+	if ob.Target == "" {
+		ob.Target = ob.Lower
+	}
+	if !strings.HasPrefix(ob.Target, "/") {
+		return ob, fmt.Errorf("overlay bind target %s must be an absolute path", ob.Target)
+	}
+
+	return ob, nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseOverlayBind(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    OverlayBind
+		wantErr bool
+	}{
+		{
+			name: "full",
+			spec: "lower=/host/ro,upper=tmpfs,target=/app",
+			want: OverlayBind{Lower: "/host/ro", Upper: "tmpfs", Target: "/app"},
+		},
+		{
+			name: "defaultUpper",
+			spec: "lower=/host/ro,target=/app",
+			want: OverlayBind{Lower: "/host/ro", Upper: "tmpfs", Target: "/app"},
+		},
+		{
+			name: "defaultTarget",
+			spec: "lower=/opt",
+			want: OverlayBind{Lower: "/opt", Upper: "tmpfs", Target: "/opt"},
+		},
+		{
+			name: "relativeLowerDefaultTarget",
+			spec: "lower=relative/dir",
+			want: OverlayBind{Lower: filepath.Join(cwd, "relative/dir"), Upper: "tmpfs", Target: filepath.Join(cwd, "relative/dir")},
+		},
+		{
+			name: "quoted",
+			spec: `"lower=/host/a,b",target=/app`,
+			want: OverlayBind{Lower: "/host/a,b", Upper: "tmpfs", Target: "/app"},
+		},
+		{
+			name:    "noLower",
+			spec:    "upper=tmpfs,target=/app",
+			wantErr: true,
+		},
+		{
+			name:    "badUpper",
+			spec:    "lower=/opt,upper=/host/rw,target=/app",
+			wantErr: true,
+		},
+		{
+			name:    "relativeTarget",
+			spec:    "lower=/opt,target=app",
+			wantErr: true,
+		},
+		{
+			name:    "invalidKey",
+			spec:    "lower=/opt,color=turquoise",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOverlayBind(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOverlayBind() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseOverlayBind() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
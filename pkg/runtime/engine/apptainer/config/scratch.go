@@ -0,0 +1,58 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/go-units"
+)
+
+// ScratchDir stores a parsed --scratch entry.
+type ScratchDir struct {
+	Path string
+	// Size is the scratch directory size limit in bytes, no limit
+	// is applied when zero.
+	Size int64
+}
+
+// ParseScratchDir converts a --scratch entry in the <path>[:size=<size>]
+// format into a ScratchDir, e.g.:
+//
+//	/work:size=512m
+//
+// The size accepts the docker tmpfs-size units.
+func ParseScratchDir(spec string) (ScratchDir, error) {
+	path, opts, _ := strings.Cut(spec, ":")
+	sd := ScratchDir{Path: filepath.Clean(path)}
+
+	if path == "" {
+		return sd, fmt.Errorf("scratch directory path cannot be empty")
+	}
+	if opts == "" {
+		return sd, nil
+	}
+
+	key, val, _ := strings.Cut(opts, "=")
+	if key != "size" {
+		return sd, fmt.Errorf("invalid option %q for scratch directory %s, only size is supported", opts, path)
+	}
+	size, err := units.RAMInBytes(val)
+	if err != nil {
+		return sd, fmt.Errorf("invalid size %q for scratch directory %s: %v", val, path, err)
+	}
+	if size <= 0 {
+		return sd, fmt.Errorf("invalid size %q for scratch directory %s: must be greater than zero", val, path)
+	}
+	sd.Size = size
+
+	return sd, nil
+}
@@ -0,0 +1,43 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"testing"
+)
+
+func TestParseScratchDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    ScratchDir
+		wantErr bool
+	}{
+		{name: "Path", spec: "/work", want: ScratchDir{Path: "/work"}},
+		{name: "CleanPath", spec: "/work/", want: ScratchDir{Path: "/work"}},
+		{name: "Size", spec: "/work:size=512m", want: ScratchDir{Path: "/work", Size: 512 * 1024 * 1024}},
+		{name: "SizeBytes", spec: "/work:size=4096", want: ScratchDir{Path: "/work", Size: 4096}},
+		{name: "EmptyPath", spec: ":size=1g", wantErr: true},
+		{name: "InvalidOption", spec: "/work:mode=700", wantErr: true},
+		{name: "InvalidSize", spec: "/work:size=lots", wantErr: true},
+		{name: "ZeroSize", spec: "/work:size=0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseScratchDir(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got %+v, expected %+v", got, tt.want)
+			}
+		})
+	}
+}
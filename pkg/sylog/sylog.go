@@ -12,12 +12,15 @@
 package sylog
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var messageColors = map[messageLevel]string{
@@ -39,6 +42,7 @@ func init() {
 	if err == nil {
 		loggerLevel = messageLevel(l)
 	}
+	logFormatter = formatterFromEnv()
 }
 
 func prefix(logLevel, msgLevel messageLevel) string {
@@ -72,6 +76,36 @@ func prefix(logLevel, msgLevel messageLevel) string {
 	return fmt.Sprintf("%s%-8s%s%-19s%-30s", messageColor, msgLevel, colorReset, uidStr, funcName)
 }
 
+// jsonMessage is a log message written by the JSON formatter.
+type jsonMessage struct {
+	Level  string `json:"level"`
+	Msg    string `json:"msg"`
+	Time   string `json:"time"`
+	Pid    int    `json:"pid"`
+	Caller string `json:"caller"`
+}
+
+// jsonLine returns the JSON encoded message, the caller is reported
+// as the file, prefixed by its directory, and line calling the log
+// function.
+func jsonLine(msgLevel messageLevel, message string) []byte {
+	caller := "???"
+	if _, file, line, ok := runtime.Caller(3); ok {
+		file = filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file))
+		caller = fmt.Sprintf("%s:%d", file, line)
+	}
+
+	// marshaling strings and integers can't fail
+	b, _ := json.Marshal(jsonMessage{
+		Level:  strings.ToLower(msgLevel.String()),
+		Msg:    message,
+		Time:   time.Now().UTC().Format(time.RFC3339Nano),
+		Pid:    os.Getpid(),
+		Caller: caller,
+	})
+	return b
+}
+
 func writef(msgLevel messageLevel, format string, a ...interface{}) {
 	logLevel := getLoggerLevel()
 	if logLevel < msgLevel {
@@ -81,6 +115,10 @@ func writef(msgLevel messageLevel, format string, a ...interface{}) {
 	message := fmt.Sprintf(format, a...)
 	message = strings.TrimRight(message, "\n")
 
+	if logFormatter == JSONFormatter {
+		fmt.Fprintf(logWriter, "%s\n", jsonLine(msgLevel, message))
+		return
+	}
 	fmt.Fprintf(logWriter, "%s%s\n", prefix(logLevel, msgLevel), message)
 }
 
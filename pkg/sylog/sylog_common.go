@@ -9,6 +9,11 @@
 
 package sylog
 
+import (
+	"os"
+	"strings"
+)
+
 type messageLevel int
 
 // Log levels.
@@ -46,3 +51,46 @@ var messageLabels = map[messageLevel]string{
 	Verbose3Level: "VERBOSE",
 	DebugLevel:    "DEBUG",
 }
+
+// Formatter defines the output format of log messages.
+type Formatter int
+
+const (
+	// TextFormatter writes messages as text prefixed by their level,
+	// colored when the output is a terminal.
+	TextFormatter Formatter = iota
+	// JSONFormatter writes each message as a JSON object on a single
+	// line with the level, msg, time, pid and caller fields.
+	JSONFormatter
+)
+
+// logFormatEnv is the environment variable selecting the JSON formatter
+// when set to json.
+const logFormatEnv = "APPTAINER_LOG_FORMAT"
+
+var logFormatter = TextFormatter
+
+// SetFormatter sets the output format of subsequent log messages and
+// returns the previous one so that it may be restored by the caller.
+func SetFormatter(f Formatter) Formatter {
+	old := logFormatter
+	logFormatter = f
+	return old
+}
+
+// GetFormatEnvVar returns a formatted environment variable string which
+// can later be interpreted by init() in a child proc to select the same
+// output format.
+func GetFormatEnvVar() string {
+	if logFormatter == JSONFormatter {
+		return logFormatEnv + "=json"
+	}
+	return logFormatEnv + "=text"
+}
+
+func formatterFromEnv() Formatter {
+	if strings.EqualFold(os.Getenv(logFormatEnv), "json") {
+		return JSONFormatter
+	}
+	return TextFormatter
+}
@@ -27,6 +27,7 @@ func init() {
 	if err == nil {
 		loggerLevel = messageLevel(l)
 	}
+	logFormatter = formatterFromEnv()
 }
 
 func getLoggerLevel() messageLevel {
@@ -13,12 +13,14 @@ package sylog
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/apptainer/apptainer/internal/pkg/test"
 )
@@ -217,6 +219,42 @@ func TestWritef(t *testing.T) {
 	}
 }
 
+func TestJSONFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	logWriter = &buf
+
+	old := SetFormatter(JSONFormatter)
+	defer func() {
+		logWriter = defaultWriter
+		SetFormatter(old)
+	}()
+
+	SetLevel(int(InfoLevel), true)
+	Warningf("a \"quoted\" %s\n", "message")
+	Debugf("filtered out")
+
+	var msg jsonMessage
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines instead of 1: %q", len(lines), buf.String())
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &msg); err != nil {
+		t.Fatalf("failed to decode %q: %s", lines[0], err)
+	}
+	if msg.Level != "warning" || msg.Msg != `a "quoted" message` || msg.Pid != os.Getpid() {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	if !strings.HasPrefix(msg.Caller, "sylog/sylog_test.go:") {
+		t.Errorf("unexpected caller %s", msg.Caller)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, msg.Time); err != nil {
+		t.Errorf("unexpected time %s: %s", msg.Time, err)
+	}
+	if env := GetFormatEnvVar(); env != "APPTAINER_LOG_FORMAT=json" {
+		t.Errorf("unexpected format environment variable %s", env)
+	}
+}
+
 func TestGetLevel(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -84,16 +84,23 @@ type File struct {
 	ConfigPasswd              bool     `default:"yes" authorized:"yes,no" directive:"config passwd"`
 	ConfigGroup               bool     `default:"yes" authorized:"yes,no" directive:"config group"`
 	ConfigResolvConf          bool     `default:"yes" authorized:"yes,no" directive:"config resolv_conf"`
+	ResolvedStub              string   `default:"upstream" authorized:"upstream,keep" directive:"systemd resolved stub"`
 	MountProc                 bool     `default:"yes" authorized:"yes,no" directive:"mount proc"`
 	MountSys                  bool     `default:"yes" authorized:"yes,no" directive:"mount sys"`
+	AllowKernelFs             []string `directive:"allow kernel fs"`
 	MountDevPts               bool     `default:"yes" authorized:"yes,no" directive:"mount devpts"`
+	MountTTYConsole           bool     `default:"yes" authorized:"yes,no" directive:"mount tty console"`
 	MountHome                 bool     `default:"yes" authorized:"yes,no" directive:"mount home"`
 	MountTmp                  bool     `default:"yes" authorized:"yes,no" directive:"mount tmp"`
 	MountHostfs               bool     `default:"no" authorized:"yes,no" directive:"mount hostfs"`
+	MountCwdReadOnly          bool     `default:"no" authorized:"yes,no" directive:"mount cwd read only"`
 	UserBindControl           bool     `default:"yes" authorized:"yes,no" directive:"user bind control"`
+	AllowImageBinds           bool     `default:"no" authorized:"yes,no" directive:"allow image binds"`
 	EnableFusemount           bool     `default:"yes" authorized:"yes,no" directive:"enable fusemount"`
+	AllowedFusemountPrograms  []string `directive:"allowed fusemount programs"`
 	EnableUnderlay            string   `default:"yes" authorized:"yes,no,preferred" directive:"enable underlay"`
 	MountSlave                bool     `default:"yes" authorized:"yes,no" directive:"mount slave"`
+	AutofsWorkaround          bool     `default:"yes" authorized:"yes,no" directive:"autofs workaround"`
 	AllowContainerSIF         bool     `default:"yes" authorized:"yes,no" directive:"allow container sif"`
 	AllowContainerEncrypted   bool     `default:"yes" authorized:"yes,no" directive:"allow container encrypted"`
 	AllowContainerSquashfs    bool     `default:"yes" authorized:"yes,no" directive:"allow container squashfs"`
@@ -110,6 +117,10 @@ type File struct {
 	SessiondirMaxSize         uint     `default:"64" directive:"sessiondir max size"`
 	MountDev                  string   `default:"yes" authorized:"yes,no,minimal" directive:"mount dev"`
 	EnableOverlay             string   `default:"yes" authorized:"yes,no,try,driver" directive:"enable overlay"`
+	MaxOverlayLayers          uint     `default:"128" directive:"max overlay layers"`
+	RequireSignedOverlay      bool     `default:"no" authorized:"yes,no" directive:"require signed overlay"`
+	OverlayMetacopy           bool     `default:"no" authorized:"yes,no" directive:"overlay metacopy"`
+	OverlayRedirectDir        bool     `default:"no" authorized:"yes,no" directive:"overlay redirect dir"`
 	BindPath                  []string `default:"/etc/localtime,/etc/hosts" directive:"bind path"`
 	LimitContainerOwners      []string `directive:"limit container owners"`
 	LimitContainerGroups      []string `directive:"limit container groups"`
@@ -122,16 +133,20 @@ type File struct {
 	MemoryFSType              string   `default:"tmpfs" authorized:"tmpfs,ramfs" directive:"memory fs type"`
 	CniConfPath               string   `directive:"cni configuration path"`
 	CniPluginPath             string   `directive:"cni plugin path"`
+	CniNetworkConfPaths       []string `directive:"cni network configuration paths"`
+	NetworkRetries            uint     `default:"0" directive:"network retries"`
+	MaxNetworkRetries         uint     `default:"10" directive:"max network retries"`
 	BinaryPath                string   `default:"$PATH:/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin" directive:"binary path"`
 	// SuidBinaryPath is hidden; it is not referenced below, and overwritten
-	SuidBinaryPath      string `directive:"suidbinary path"`
-	MksquashfsProcs     uint   `default:"0" directive:"mksquashfs procs"`
-	MksquashfsMem       string `directive:"mksquashfs mem"`
-	ImageDriver         string `directive:"image driver"`
-	DownloadConcurrency uint   `default:"3" directive:"download concurrency"`
-	DownloadPartSize    uint   `default:"5242880" directive:"download part size"`
-	DownloadBufferSize  uint   `default:"32768" directive:"download buffer size"`
-	SystemdCgroups      bool   `default:"yes" authorized:"yes,no" directive:"systemd cgroups"`
+	SuidBinaryPath      string   `directive:"suidbinary path"`
+	MksquashfsProcs     uint     `default:"0" directive:"mksquashfs procs"`
+	MksquashfsMem       string   `directive:"mksquashfs mem"`
+	ImageDriver         string   `directive:"image driver"`
+	AllowedImageDrivers []string `directive:"allowed image drivers"`
+	DownloadConcurrency uint     `default:"3" directive:"download concurrency"`
+	DownloadPartSize    uint     `default:"5242880" directive:"download part size"`
+	DownloadBufferSize  uint     `default:"32768" directive:"download buffer size"`
+	SystemdCgroups      bool     `default:"yes" authorized:"yes,no" directive:"systemd cgroups"`
 	// apptheus unix socket
 	ApptheusSocketPath string `default:"/run/apptheus/gateway.sock" directive:"apptheus communication socket path"`
 	// Allow monitoring by apptheus, default is `no` because it requires an additional tool, i.e. apptheus
@@ -214,6 +229,15 @@ config group = {{ if eq .ConfigGroup true }}yes{{ else }}no{{ end }}
 # /etc/resolv.conf.
 config resolv_conf = {{ if eq .ConfigResolvConf true }}yes{{ else }}no{{ end }}
 
+# SYSTEMD RESOLVED STUB: [upstream/keep]
+# DEFAULT: upstream
+# When the host /etc/resolv.conf only points to the systemd-resolved local
+# stub resolver (127.0.0.53), the stub is unreachable from containers running
+# in their own network namespace. With "upstream", the container resolv.conf
+# is generated from the upstream nameservers listed by systemd-resolved in
+# /run/systemd/resolve/resolv.conf. With "keep", the host content is used as is.
+systemd resolved stub = {{ .ResolvedStub }}
+
 # MOUNT PROC: [BOOL]
 # DEFAULT: yes
 # Should we automatically bind mount /proc within the container?
@@ -224,6 +248,17 @@ mount proc = {{ if eq .MountProc true }}yes{{ else }}no{{ end }}
 # Should we automatically bind mount /sys within the container?
 mount sys = {{ if eq .MountSys true }}yes{{ else }}no{{ end }}
 
+# ALLOW KERNEL FS: [STRING]
+# DEFAULT: NULL
+# Comma separated list of the kernel filesystems, among debugfs, tracefs and
+# cgroup2, that unprivileged users are allowed to mount in the container with
+# --mount type=kernelfs,name=<filesystem>. Root and fakeroot users can always
+# request them. Only allow debugfs with care, it exposes kernel internals.
+#allow kernel fs = tracefs
+{{ range $index, $fs := .AllowKernelFs }}
+{{- if eq $index 0 }}allow kernel fs = {{ else }}, {{ end }}{{$fs}}
+{{- end }}
+
 # MOUNT DEV: [yes/no/minimal]
 # DEFAULT: yes
 # Should we automatically bind mount /dev within the container? If 'minimal'
@@ -239,6 +274,15 @@ mount dev = {{ .MountDev }}
 # running kernel 4.7 or newer.
 mount devpts = {{ if eq .MountDevPts true }}yes{{ else }}no{{ end }}
 
+# MOUNT TTY CONSOLE: [BOOL]
+# DEFAULT: yes
+# Should we bind the terminal found on the standard input, output or error
+# at /dev/console if there is a 'minimal' /dev, or -C is passed? Disabling it
+# is mostly useful for non-interactive jobs where the terminal may disappear
+# while the container is running. Users can also disable it with the
+# --no-tty-console command line option.
+mount tty console = {{ if eq .MountTTYConsole true }}yes{{ else }}no{{ end }}
+
 # MOUNT HOME: [BOOL]
 # DEFAULT: yes
 # Should we automatically determine the calling user's home directory and
@@ -262,6 +306,12 @@ mount tmp = {{ if eq .MountTmp true }}yes{{ else }}no{{ end }}
 # those into the container?
 mount hostfs = {{ if eq .MountHostfs true }}yes{{ else }}no{{ end }}
 
+# MOUNT CWD READ ONLY: [BOOL]
+# DEFAULT: no
+# Should the current working directory be bind mounted read-only into the
+# container? Users can also request it with the --cwd-ro command line option.
+mount cwd read only = {{ if eq .MountCwdReadOnly true }}yes{{ else }}no{{ end }}
+
 # BIND PATH: [STRING]
 # DEFAULT: Undefined
 # Define a list of files/directories that should be made available from within
@@ -286,12 +336,31 @@ bind path = {{$path}}
 # control is only allowed if the host also supports PR_SET_NO_NEW_PRIVS)
 user bind control = {{ if eq .UserBindControl true }}yes{{ else }}no{{ end }}
 
+# ALLOW IMAGE BINDS: [BOOL]
+# DEFAULT: no
+# Apply the binds recommended by SIF images in their apptainer.binds data
+# object? Images can then bind host paths into the container without being
+# requested by the user, each applied bind is reported. They are always
+# ignored when user bind control is disabled.
+allow image binds = {{ if eq .AllowImageBinds true }}yes{{ else }}no{{ end }}
+
 # ENABLE FUSEMOUNT: [BOOL]
 # DEFAULT: yes
 # Allow users to mount fuse filesystems inside containers with the --fusemount
 # command line option.
 enable fusemount = {{ if eq .EnableFusemount true }}yes{{ else }}no{{ end }}
 
+# ALLOWED FUSEMOUNT PROGRAMS: [STRING]
+# DEFAULT: NULL
+# Comma separated list of FUSE program names (without their directory) that
+# can be used with the --fusemount command line option, for example
+# "sshfs, s3fs". Any other program is rejected. When empty, any program is
+# allowed.
+#allowed fusemount programs = sshfs, s3fs
+{{ range $index, $prog := .AllowedFusemountPrograms }}
+{{- if eq $index 0 }}allowed fusemount programs = {{ else }}, {{ end }}{{$prog}}
+{{- end }}
+
 # ENABLE OVERLAY: [yes/no/driver/try]
 # DEFAULT: yes
 # Enabling this option will make it possible to specify bind paths to locations
@@ -305,6 +374,46 @@ enable fusemount = {{ if eq .EnableFusemount true }}yes{{ else }}no{{ end }}
 # creating bind paths.
 enable overlay = {{ .EnableOverlay }}
 
+# MAX OVERLAY LAYERS: [INT]
+# DEFAULT: 128
+# Set the maximum number of read-only overlay layers, coming from overlay
+# images given with --overlay and overlay partitions embedded in images,
+# that can be stacked on top of the container root filesystem.  The kernel
+# overlay filesystem supports at most 500 lower layers and, before Linux
+# 6.8, limits the size of the mount options to a page (usually 4096 bytes),
+# exceeding these limits results in mount failures.
+max overlay layers = {{ .MaxOverlayLayers }}
+
+# REQUIRE SIGNED OVERLAY: [BOOL]
+# DEFAULT: no
+# Require overlay images given with --overlay to carry a valid signature
+# from a key of the global keyring, like users can request with the
+# --overlay-verify option.  SIF overlay images are verified with their
+# embedded signatures, other overlay images with a detached signature file
+# next to the image with the .sig extension, or .asc for an armored
+# signature.  Overlay directories are rejected and signed overlay images
+# are always mounted read-only.
+require signed overlay = {{ if eq .RequireSignedOverlay true }}yes{{ else }}no{{ end }}
+
+# OVERLAY METACOPY: [BOOL]
+# OVERLAY REDIRECT DIR: [BOOL]
+# DEFAULT: no
+# Enable the metacopy and redirect_dir kernel overlay features for the
+# container overlay with a writable upper layer, like users can request
+# with the --overlay-opts option.  With metacopy, changing file metadata
+# (e.g. chmod, chown) copies up only the metadata instead of the whole
+# file, with redirect_dir renaming a directory of a lower layer doesn't
+# copy up its content.  The options are dropped when not supported by the
+# kernel, and are ignored by fuse-overlayfs.  Note that with metacopy the
+# inode numbers of the underlying lower layer files, located on the host,
+# may be exposed in the container, and that overlay images modified with
+# these features can't be used with older kernels.  In setuid mode users
+# can only request a feature with --overlay-opts when it's enabled here,
+# the kernel documentation warns against these features with untrusted
+# layers, like user provided overlay images.
+overlay metacopy = {{ if eq .OverlayMetacopy true }}yes{{ else }}no{{ end }}
+overlay redirect dir = {{ if eq .OverlayRedirectDir true }}yes{{ else }}no{{ end }}
+
 # ENABLE UNDERLAY: [yes/no/preferred]
 # DEFAULT: yes
 # Enabling this option will make it possible to specify bind paths to locations
@@ -325,6 +434,16 @@ enable underlay = {{ .EnableUnderlay }}
 # show up in the container.
 mount slave = {{ if eq .MountSlave true }}yes{{ else }}no{{ end }}
 
+# AUTOFS WORKAROUND: [BOOL]
+# DEFAULT: yes
+# Should we keep file descriptors open on bind sources located under autofs
+# mount points? This prevents a kernel bug where autofs mounts are expired
+# before the container bind mounts are done. Disabling it keeps fewer file
+# descriptors open, which may help with a large number of binds, but binds
+# from autofs mounts may then fail. Users can also disable it with the
+# '--no-autofs-workaround' option.
+autofs workaround = {{ if eq .AutofsWorkaround true }}yes{{ else }}no{{ end }}
+
 # SESSIONDIR MAXSIZE: [STRING]
 # DEFAULT: 64
 # This specifies how large the default sessiondir should be (in MB). It will
@@ -551,6 +670,31 @@ memory fs type = {{ .MemoryFSType }}
 # Defines path where CNI executable plugins are stored
 #cni plugin path =
 {{ if ne .CniPluginPath "" }}cni plugin path = {{ .CniPluginPath }}{{ end }}
+# CNI NETWORK CONFIGURATION PATHS: [STRING]
+# DEFAULT: Undefined
+# Comma separated list of <network>:<path> entries defining the directory
+# where the CNI configuration of a network is stored, in place of the cni
+# configuration path. This allows to keep the configurations of different
+# tenants in separate directories, only the administrator can select them,
+# unprivileged users still being restricted to the allow net networks.
+#cni network configuration paths = tenant1:/etc/apptainer/network/tenant1
+{{ range $index, $path := .CniNetworkConfPaths }}
+{{- if eq $index 0 }}cni network configuration paths = {{ else }}, {{ end }}{{$path}}
+{{- end }}
+
+# NETWORK RETRIES: [INT]
+# DEFAULT: 0
+# Number of times the network setup is retried when a CNI plugin reports a
+# transient error, with an exponential backoff between attempts starting at
+# 500 milliseconds and capped at 10 seconds. Users can override it with the
+# --net-retry option, 0 disabling retries.
+network retries = {{ .NetworkRetries }}
+
+# MAX NETWORK RETRIES: [INT]
+# DEFAULT: 10
+# Maximum number of network setup retries, applied to the network retries
+# directive and to the --net-retry option.
+max network retries = {{ .MaxNetworkRetries }}
 
 # BINARY PATH: [STRING]
 # DEFAULT: $PATH:/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin
@@ -598,6 +742,16 @@ shared loop devices = {{ if eq .SharedLoopDevices true }}yes{{ else }}no{{ end }
 # the run-time will abort.
 image driver = {{ .ImageDriver }}
 
+# ALLOWED IMAGE DRIVERS: [STRING]
+# DEFAULT: NULL
+# Comma separated list of image drivers users are allowed to select with the
+# --image-driver option in setuid mode, the image driver set above is always
+# allowed. Without setuid the option accepts any registered image driver.
+#allowed image drivers = fuseapps
+{{ range $index, $driver := .AllowedImageDrivers }}
+{{- if eq $index 0 }}allowed image drivers = {{ else }}, {{ end }}{{$driver}}
+{{- end }}
+
 # DOWNLOAD CONCURRENCY: [UINT]
 # DEFAULT: 3
 # This option specifies how many concurrent streams when downloading (pulling)
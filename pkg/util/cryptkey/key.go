@@ -312,6 +312,19 @@ func savePEMMessage(w io.Writer, msg []byte) error {
 	return pem.Encode(w, b)
 }
 
+// ImageKeyFormat returns the format of the key material required to decrypt
+// the encrypted SIF image fn: PEM if the encryption key is stored in the
+// image as a PEM encrypted message, Passphrase otherwise.
+func ImageKeyFormat(fn string) (int, error) {
+	_, err := getEncryptionKeyFromImage(fn)
+	if errors.Is(err, ErrEncryptedKeyNotFound) {
+		return Passphrase, nil
+	} else if err != nil {
+		return Unknown, err
+	}
+	return PEM, nil
+}
+
 func getEncryptionKeyFromImage(fn string) ([]byte, error) {
 	img, err := sif.LoadContainerFromPath(fn, sif.OptLoadWithFlag(os.O_RDONLY))
 	if err != nil {
@@ -354,5 +367,5 @@ func getEncryptionKeyFromImage(fn string) ([]byte, error) {
 		return key, nil
 	}
 
-	return nil, fmt.Errorf("could not read LUKS key from %s: %v", fn, ErrEncryptedKeyNotFound)
+	return nil, fmt.Errorf("could not read LUKS key from %s: %w", fn, ErrEncryptedKeyNotFound)
 }
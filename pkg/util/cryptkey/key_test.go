@@ -10,10 +10,13 @@
 package cryptkey
 
 import (
+	"bytes"
 	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/test"
+	"github.com/apptainer/sif/v2/pkg/sif"
 	"github.com/pkg/errors"
 )
 
@@ -174,3 +177,75 @@ func TestPlaintextKey(t *testing.T) {
 		})
 	}
 }
+
+func TestImageKeyFormat(t *testing.T) {
+	dir := t.TempDir()
+
+	createImage := func(name string, pemMessage bool) string {
+		path := filepath.Join(dir, name)
+
+		part, err := sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(make([]byte, 4096)),
+			sif.OptPartitionMetadata(sif.FsEncryptedSquashfs, sif.PartPrimSys, "amd64"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dis := []sif.DescriptorInput{part}
+
+		if pemMessage {
+			msg, err := sif.NewDescriptorInput(sif.DataCryptoMessage, bytes.NewReader([]byte("message")),
+				sif.OptLinkedID(1),
+				sif.OptCryptoMessageMetadata(sif.FormatPEM, sif.MessageRSAOAEP),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dis = append(dis, msg)
+		}
+
+		f, err := sif.CreateContainerAtPath(path, sif.OptCreateWithDescriptors(dis...))
+		if err != nil {
+			t.Fatalf("while creating %s: %s", path, err)
+		}
+		if err := f.UnloadContainer(); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		format      int
+		expectError bool
+	}{
+		{
+			name:   "passphrase",
+			path:   createImage("passphrase.sif", false),
+			format: Passphrase,
+		},
+		{
+			name:   "pem",
+			path:   createImage("pem.sif", true),
+			format: PEM,
+		},
+		{
+			name:        "no image",
+			path:        filepath.Join(dir, "missing.sif"),
+			format:      Unknown,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := ImageKeyFormat(tt.path)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if format != tt.format {
+				t.Errorf("got format %d, expected %d", format, tt.format)
+			}
+		})
+	}
+}
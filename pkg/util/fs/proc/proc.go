@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
@@ -53,9 +54,15 @@ func GetMountPointMap(path string) (map[string][]string, error) {
 
 	scanner := bufio.NewScanner(p)
 	for scanner.Scan() {
-		entry := parseMountInfoLine(scanner.Text())
+		entry, err := parseMountInfoLine(scanner.Text())
+		if err != nil {
+			return mp, fmt.Errorf("while parsing %s: %s", path, err)
+		}
 		entries[entry.ID] = entry
 	}
+	if err := scanner.Err(); err != nil {
+		return mp, fmt.Errorf("while reading %s: %s", path, err)
+	}
 
 	for e := range entries {
 		parentID := entries[e].ParentID
@@ -85,11 +92,18 @@ type MountInfoEntry struct {
 
 // parseMountInfoLine parses a mountinfo line and returns
 // a MountInfoEntry containing parsed fields associated
-// to the line.
-func parseMountInfoLine(line string) MountInfoEntry {
+// to the line, or an error if the line is malformed.
+func parseMountInfoLine(line string) (MountInfoEntry, error) {
 	fields := strings.Split(line, " ")
 	entry := MountInfoEntry{}
 
+	// the optional fields are terminated by a single hyphen
+	// followed by at least 3 fields
+	sep := slices.Index(fields, "-")
+	if sep < 6 || len(fields) < sep+4 {
+		return entry, fmt.Errorf("malformed mountinfo line %q", line)
+	}
+
 	// ID field
 	entry.ID = fields[0]
 	// convert Parent ID field
@@ -104,7 +118,7 @@ func parseMountInfoLine(line string) MountInfoEntry {
 	entry.Options = strings.Split(fields[5], ",")
 	// optional fields field
 	index := 6
-	for ; fields[index] != "-"; index++ {
+	for ; index < sep; index++ {
 		entry.Fields += " " + fields[index]
 	}
 	entry.Fields = strings.TrimSpace(entry.Fields)
@@ -131,7 +145,7 @@ func parseMountInfoLine(line string) MountInfoEntry {
 		}
 	}
 
-	return entry
+	return entry, nil
 }
 
 // GetMountInfoEntry parses a mountinfo file and returns all
@@ -146,9 +160,15 @@ func GetMountInfoEntry(path string) ([]MountInfoEntry, error) {
 	entries := make([]MountInfoEntry, 0)
 	scanner := bufio.NewScanner(p)
 	for scanner.Scan() {
-		entry := parseMountInfoLine(scanner.Text())
+		entry, err := parseMountInfoLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("while parsing %s: %s", path, err)
+		}
 		entries = append(entries, entry)
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("while reading %s: %s", path, err)
+	}
 
 	return entries, nil
 }
@@ -12,6 +12,7 @@ package proc
 import (
 	"os"
 	"os/exec"
+	"path/filepath"
 	"syscall"
 	"testing"
 
@@ -517,3 +518,42 @@ func TestGetppid(t *testing.T) {
 		}
 	}
 }
+
+func TestUnparseableMountInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "TruncatedLine",
+			data: "22 28 0:21 / /sys rw,nosuid\n",
+		},
+		{
+			name: "MissingSeparator",
+			data: "22 28 0:21 / /sys rw,nosuid,nodev shared:7 sysfs sysfs rw\n",
+		},
+		{
+			name: "MissingSuperOptions",
+			data: "22 28 0:21 / /sys rw,nosuid,nodev shared:7 - sysfs sysfs\n",
+		},
+		{
+			name: "Garbage",
+			data: "not a mountinfo file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mountinfo")
+			if err := os.WriteFile(path, []byte(mountInfoData+"\n"+tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := GetMountInfoEntry(path); err == nil {
+				t.Errorf("unexpected success while parsing %s with GetMountInfoEntry", tt.name)
+			}
+			if _, err := GetMountPointMap(path); err == nil {
+				t.Errorf("unexpected success while parsing %s with GetMountPointMap", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package loop
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/apptainer/apptainer/pkg/util/fs/proc"
+	"golang.org/x/sys/unix"
+)
+
+// sysBlockPath is where the kernel exposes the loop devices attributes.
+var sysBlockPath = "/sys/block"
+
+// deletedSuffix is appended by the kernel to the backing file of a loop
+// device when the file was deleted.
+const deletedSuffix = " (deleted)"
+
+// LoopInfo describes a loop device attached to a backing file.
+type LoopInfo struct {
+	// Device is the loop device number, N for /dev/loopN.
+	Device int
+	// Dev is the loop device major:minor number.
+	Dev string
+	// BackingFile is the path of the backing file, as seen by the
+	// process which attached the loop device.
+	BackingFile string
+	// BackingFileExists is false when the backing file was deleted.
+	BackingFileExists bool
+	Offset            uint64
+	// AutoClear is true when the loop device is detached on last
+	// close, Apptainer always attaches loop devices with autoclear.
+	AutoClear bool
+}
+
+// ListAttached returns the loop devices attached to a backing file,
+// ordered by device number.
+func ListAttached() ([]LoopInfo, error) {
+	return listAttached(sysBlockPath)
+}
+
+func listAttached(sysBlock string) ([]LoopInfo, error) {
+	entries, err := os.ReadDir(sysBlock)
+	if err != nil {
+		return nil, fmt.Errorf("while reading %s: %s", sysBlock, err)
+	}
+
+	loops := make([]LoopInfo, 0)
+	for _, e := range entries {
+		num, ok := strings.CutPrefix(e.Name(), "loop")
+		if !ok {
+			continue
+		}
+		device, err := strconv.Atoi(num)
+		if err != nil {
+			continue
+		}
+
+		dir := filepath.Join(sysBlock, e.Name())
+		// the loop directory only exists for attached devices
+		backingFile, err := readSysAttr(dir, "loop/backing_file")
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		info := LoopInfo{
+			Device:            device,
+			BackingFile:       strings.TrimSuffix(backingFile, deletedSuffix),
+			BackingFileExists: !strings.HasSuffix(backingFile, deletedSuffix),
+		}
+		if info.Dev, err = readSysAttr(dir, "dev"); err != nil {
+			return nil, err
+		}
+		offset, err := readSysAttr(dir, "loop/offset")
+		if err != nil {
+			return nil, err
+		}
+		if info.Offset, err = strconv.ParseUint(offset, 10, 64); err != nil {
+			return nil, fmt.Errorf("while parsing %s offset: %s", getLoopPath(device), err)
+		}
+		autoClear, err := readSysAttr(dir, "loop/autoclear")
+		if err != nil {
+			return nil, err
+		}
+		info.AutoClear = autoClear == "1"
+
+		loops = append(loops, info)
+	}
+
+	sort.Slice(loops, func(i, j int) bool {
+		return loops[i].Device < loops[j].Device
+	})
+
+	return loops, nil
+}
+
+// DetachStale detaches the autoclear loop devices whose backing file was
+// deleted, as left over by crashed containers. Devices still mounted in any
+// mount namespace or used by another block device, like a device-mapper
+// target, are left untouched. It returns the detached loop devices.
+func DetachStale() ([]LoopInfo, error) {
+	loops, err := ListAttached()
+	if err != nil {
+		return nil, err
+	}
+
+	mounted, err := mountedDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	detached := make([]LoopInfo, 0)
+	for _, l := range loops {
+		if !l.AutoClear || l.BackingFileExists {
+			continue
+		}
+		path := getLoopPath(l.Device)
+		if mounted[l.Dev] {
+			sylog.Debugf("Skipping %s: device is mounted", path)
+			continue
+		}
+		holders, err := os.ReadDir(filepath.Join(sysBlockPath, fmt.Sprintf("loop%d", l.Device), "holders"))
+		if err == nil && len(holders) > 0 {
+			sylog.Debugf("Skipping %s: device is used by %s", path, holders[0].Name())
+			continue
+		}
+
+		if err := detach(path); err != nil {
+			return detached, err
+		}
+		detached = append(detached, l)
+	}
+
+	return detached, nil
+}
+
+// detach clears the backing file of the loop device path.
+func detach(path string) error {
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", path, err)
+	}
+	defer syscall.Close(fd)
+
+	if err := unix.IoctlSetInt(fd, unix.LOOP_CLR_FD, 0); err != nil {
+		return fmt.Errorf("could not detach %s: %w", path, err)
+	}
+	return nil
+}
+
+// mountedDevices returns the major:minor numbers of the devices mounted in
+// any mount namespace of the host.
+func mountedDevices() (map[string]bool, error) {
+	mounted := make(map[string]bool)
+	namespaces := make(map[string]bool)
+
+	pids, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+	for _, pid := range append([]string{"/proc/self"}, pids...) {
+		ns, err := os.Readlink(filepath.Join(pid, "ns", "mnt"))
+		if err != nil || namespaces[ns] {
+			// process exited, or mount namespace already seen
+			continue
+		}
+		entries, err := proc.GetMountInfoEntry(filepath.Join(pid, "mountinfo"))
+		if err != nil {
+			continue
+		}
+		namespaces[ns] = true
+		for _, e := range entries {
+			mounted[e.Dev] = true
+		}
+	}
+
+	if len(namespaces) == 0 {
+		return nil, fmt.Errorf("could not read mount information from /proc")
+	}
+	return mounted, nil
+}
+
+func readSysAttr(dir, attr string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(dir, attr))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package loop
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestListAttached(t *testing.T) {
+	sysBlock := t.TempDir()
+
+	writeAttr := func(dev, attr, value string) {
+		path := filepath.Join(sysBlock, dev, attr)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(value+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeAttr("loop10", "dev", "7:10")
+	writeAttr("loop10", "loop/backing_file", "/tmp/image.sif (deleted)")
+	writeAttr("loop10", "loop/offset", "4096")
+	writeAttr("loop10", "loop/autoclear", "1")
+	writeAttr("loop2", "dev", "7:2")
+	writeAttr("loop2", "loop/backing_file", "/tmp/overlay.img")
+	writeAttr("loop2", "loop/offset", "0")
+	writeAttr("loop2", "loop/autoclear", "0")
+	// detached loop device
+	writeAttr("loop3", "dev", "7:3")
+	// not a loop device
+	writeAttr("sda", "dev", "8:0")
+
+	loops, err := listAttached(sysBlock)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []LoopInfo{
+		{Device: 2, Dev: "7:2", BackingFile: "/tmp/overlay.img", BackingFileExists: true},
+		{Device: 10, Dev: "7:10", BackingFile: "/tmp/image.sif", Offset: 4096, AutoClear: true},
+	}
+	if !reflect.DeepEqual(loops, expected) {
+		t.Errorf("got %+v, expected %+v", loops, expected)
+	}
+
+	writeAttr("loop4", "dev", "7:4")
+	writeAttr("loop4", "loop/backing_file", "/tmp/image.sif")
+	writeAttr("loop4", "loop/offset", "bad")
+	if _, err := listAttached(sysBlock); err == nil {
+		t.Errorf("unexpected success with an invalid offset")
+	}
+}
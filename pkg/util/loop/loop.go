@@ -11,6 +11,7 @@
 package loop
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"syscall"
@@ -30,6 +31,10 @@ type Device struct {
 	fd             *int
 }
 
+// ErrNoLoopDevice is returned when all the loop devices up to
+// MaxLoopDevices are in use.
+var ErrNoLoopDevice = errors.New("no loop devices available")
+
 // Loop control device IOCTL commands
 const (
 	CmdCtlAdd     = 0x4C80
@@ -81,7 +86,7 @@ func (loop *Device) AttachFromFile(image *os.File, mode int, number *int) error
 	}
 
 	if err := loop.attachLoop(image.Fd(), imageInfo, mode, number); err != nil {
-		return fmt.Errorf("failed to attach loop device: %s", err)
+		return fmt.Errorf("failed to attach loop device: %w", err)
 	}
 
 	return nil
@@ -175,7 +180,7 @@ func (loop *Device) attachLoop(imageFd uintptr, imageInfo *syscall.Stat_t, mode
 		return nil
 	}
 
-	return fmt.Errorf("no loop devices available")
+	return ErrNoLoopDevice
 }
 
 // openLoopDev will attempt to open the specified loop device number, with specified mode.